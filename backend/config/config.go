@@ -42,6 +42,33 @@ type QlibConfig struct {
 	PythonPath string
 	DataPath   string
 	CachePath  string
+	// 美股、港股Qlib数据目录，用于加载对应市场的交易日历
+	USDataPath string
+	HKDataPath string
+	// Qlib源码目录，为空时使用Python环境中安装的qlib
+	QlibPath string
+	// 模型文件和回测报告等产物的工作目录
+	WorkspacePath string
+	// 模型训练是否使用GPU
+	GPUEnabled bool
+	// 计算后端：python、native 或 fake（合成数据，仅用于测试）
+	Engine string
+	// 常驻Python工作进程数，0表示每次调用启动新进程
+	Workers int
+	// 单个工作进程处理的最大请求数，达到后重启
+	WorkerMaxRequests int
+	// 单次Python调用的超时（秒）
+	WorkerTimeout int
+	// 模型训练、回测等任务的超时（秒），0表示不限制
+	JobTimeout int
+	// Python子进程的内存（MB）、CPU时间（秒）和单个文件大小（MB）上限，0表示不限制
+	JobMemoryMB   int
+	JobCPUSeconds int
+	JobFileSizeMB int
+	// cgroup v2 父目录，设置后每个Python子进程放入其下单独的子组
+	JobCgroup string
+	// 取消作业时从 SIGTERM 到 SIGKILL 的等待时间（秒）
+	JobKillGrace int
 }
 
 // Load 加载配置
@@ -68,6 +95,24 @@ func Load() *Config {
 			PythonPath: getEnv("QLIB_PYTHON_PATH", "/usr/bin/python3"),
 			DataPath:   getEnv("QLIB_DATA_PATH", "~/.qlib/qlib_data"),
 			CachePath:  getEnv("QLIB_CACHE_PATH", "~/.qlib/cache"),
+			USDataPath: getEnv("QLIB_US_DATA_PATH", "~/.qlib/qlib_data/us_data"),
+			HKDataPath: getEnv("QLIB_HK_DATA_PATH", ""),
+			Engine:     getEnv("QLIB_ENGINE", "native"),
+
+			QlibPath:      getEnv("QLIB_PATH", ""),
+			WorkspacePath: getEnv("QLIB_WORKSPACE_PATH", "/tmp/qlib_workspace"),
+			GPUEnabled:    getEnvBool("QLIB_GPU_ENABLED", false),
+
+			Workers:           getEnvInt("QLIB_WORKERS", 2),
+			WorkerMaxRequests: getEnvInt("QLIB_WORKER_MAX_REQUESTS", 200),
+			WorkerTimeout:     getEnvInt("QLIB_WORKER_TIMEOUT", 600),
+
+			JobTimeout:    getEnvInt("QLIB_JOB_TIMEOUT", 43200),
+			JobMemoryMB:   getEnvInt("QLIB_JOB_MEMORY_MB", 0),
+			JobCPUSeconds: getEnvInt("QLIB_JOB_CPU_SECONDS", 0),
+			JobFileSizeMB: getEnvInt("QLIB_JOB_FILE_SIZE_MB", 0),
+			JobCgroup:     getEnv("QLIB_JOB_CGROUP", ""),
+			JobKillGrace:  getEnvInt("QLIB_JOB_KILL_GRACE", 10),
 		},
 	}
 }
@@ -80,6 +125,17 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvBool 获取布尔型环境变量
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+		log.Printf("Invalid boolean value for %s: %s, using default: %t", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
 // getEnvInt 获取整型环境变量
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
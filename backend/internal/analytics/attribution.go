@@ -0,0 +1,269 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// LinkingMethod 多期归因的平滑连接方法
+type LinkingMethod string
+
+const (
+	LinkCarino   LinkingMethod = "carino"   // Carino 对数平滑
+	LinkMenchero LinkingMethod = "menchero" // Menchero 最优平滑
+)
+
+// SegmentReturn 单期某分组（如行业）的权重和收益
+type SegmentReturn struct {
+	Weight float64 `json:"weight"`
+	Return float64 `json:"return"`
+}
+
+// AggregateSegments 按分组汇总证券权重和收益，分组收益为组内按权重加权的平均收益
+//
+// returns 中缺失的证券按0收益计算，权重为0的分组收益为组内收益的简单平均。
+func AggregateSegments(weights, returns map[string]float64, segmentOf func(string) string) map[string]SegmentReturn {
+	type accumulator struct {
+		weight, weighted, sum float64
+		count                 int
+	}
+	groups := make(map[string]*accumulator)
+	for inst, w := range weights {
+		segment := segmentOf(inst)
+		acc, ok := groups[segment]
+		if !ok {
+			acc = &accumulator{}
+			groups[segment] = acc
+		}
+		r := returns[inst]
+		acc.weight += w
+		acc.weighted += w * r
+		acc.sum += r
+		acc.count++
+	}
+
+	segments := make(map[string]SegmentReturn, len(groups))
+	for segment, acc := range groups {
+		s := SegmentReturn{Weight: acc.weight}
+		if acc.weight != 0 {
+			s.Return = acc.weighted / acc.weight
+		} else if acc.count > 0 {
+			s.Return = acc.sum / float64(acc.count)
+		}
+		segments[segment] = s
+	}
+	return segments
+}
+
+// BrinsonPeriod 单期Brinson归因输入，组合和基准的分组权重应各自合计为1（组合可包含现金分组）
+type BrinsonPeriod struct {
+	Date      string                   `json:"date"`
+	Portfolio map[string]SegmentReturn `json:"portfolio"`
+	Benchmark map[string]SegmentReturn `json:"benchmark"`
+}
+
+// BrinsonEffect 配置、选择和交互效应
+type BrinsonEffect struct {
+	Allocation  float64 `json:"allocation"`
+	Selection   float64 `json:"selection"`
+	Interaction float64 `json:"interaction"`
+	Total       float64 `json:"total"`
+}
+
+func (e *BrinsonEffect) add(other BrinsonEffect, scale float64) {
+	e.Allocation += other.Allocation * scale
+	e.Selection += other.Selection * scale
+	e.Interaction += other.Interaction * scale
+	e.Total += other.Total * scale
+}
+
+// BrinsonPeriodResult 单期归因结果
+type BrinsonPeriodResult struct {
+	Date            string                   `json:"date"`
+	PortfolioReturn float64                  `json:"portfolio_return"`
+	BenchmarkReturn float64                  `json:"benchmark_return"`
+	Effect          BrinsonEffect            `json:"effect"`
+	Segments        map[string]BrinsonEffect `json:"segments"`
+}
+
+// BrinsonSegment 分组的多期连接效应及平均权重、复利收益
+type BrinsonSegment struct {
+	BrinsonEffect
+	PortfolioWeight float64 `json:"portfolio_weight"` // 平均权重
+	BenchmarkWeight float64 `json:"benchmark_weight"`
+	PortfolioReturn float64 `json:"portfolio_return"` // 持有期间的复利收益
+	BenchmarkReturn float64 `json:"benchmark_return"`
+}
+
+// BrinsonResult 多期Brinson归因结果，连接后的各效应之和等于复利超额收益
+type BrinsonResult struct {
+	Linking         LinkingMethod              `json:"linking"`
+	PortfolioReturn float64                    `json:"portfolio_return"`
+	BenchmarkReturn float64                    `json:"benchmark_return"`
+	ExcessReturn    float64                    `json:"excess_return"`
+	Effect          BrinsonEffect              `json:"effect"`
+	Segments        map[string]*BrinsonSegment `json:"segments"`
+	Periods         []BrinsonPeriodResult      `json:"periods"`
+}
+
+// BrinsonFachler 计算单期Brinson-Fachler归因
+//
+// 配置效应 (wp-wb)(rb-Rb)，选择效应 wb(rp-rb)，交互效应 (wp-wb)(rp-rb)。
+// 基准中不存在的分组以基准总收益作为分组基准收益，组合中不存在的分组以基准分组收益作为组合收益。
+func BrinsonFachler(period BrinsonPeriod) BrinsonPeriodResult {
+	result := BrinsonPeriodResult{Date: period.Date, Segments: make(map[string]BrinsonEffect)}
+	for _, s := range period.Benchmark {
+		result.BenchmarkReturn += s.Weight * s.Return
+	}
+	for _, s := range period.Portfolio {
+		result.PortfolioReturn += s.Weight * s.Return
+	}
+
+	for _, segment := range segmentNames(period) {
+		p, inPortfolio := period.Portfolio[segment]
+		b, inBenchmark := period.Benchmark[segment]
+		if !inBenchmark {
+			b.Return = result.BenchmarkReturn
+		}
+		if !inPortfolio {
+			p.Return = b.Return
+		}
+		activeWeight := p.Weight - b.Weight
+		effect := BrinsonEffect{
+			Allocation:  activeWeight * (b.Return - result.BenchmarkReturn),
+			Selection:   b.Weight * (p.Return - b.Return),
+			Interaction: activeWeight * (p.Return - b.Return),
+		}
+		effect.Total = effect.Allocation + effect.Selection + effect.Interaction
+		result.Segments[segment] = effect
+		result.Effect.add(effect, 1)
+	}
+	return result
+}
+
+// Brinson 计算多期Brinson-Fachler归因并按指定方法平滑连接
+func Brinson(periods []BrinsonPeriod, method LinkingMethod) (*BrinsonResult, error) {
+	if len(periods) == 0 {
+		return nil, fmt.Errorf("没有归因期间")
+	}
+	if method == "" {
+		method = LinkCarino
+	}
+
+	result := &BrinsonResult{Linking: method, Segments: make(map[string]*BrinsonSegment)}
+	portfolio := make([]float64, len(periods))
+	benchmark := make([]float64, len(periods))
+	for i, period := range periods {
+		periodResult := BrinsonFachler(period)
+		result.Periods = append(result.Periods, periodResult)
+		portfolio[i] = periodResult.PortfolioReturn
+		benchmark[i] = periodResult.BenchmarkReturn
+	}
+	coefficients, err := LinkingCoefficients(portfolio, benchmark, method)
+	if err != nil {
+		return nil, err
+	}
+
+	growth := make(map[string][2]float64)
+	for i, periodResult := range result.Periods {
+		result.Effect.add(periodResult.Effect, coefficients[i])
+		for segment, effect := range periodResult.Segments {
+			s, ok := result.Segments[segment]
+			if !ok {
+				s = &BrinsonSegment{}
+				result.Segments[segment] = s
+				growth[segment] = [2]float64{1, 1}
+			}
+			s.add(effect, coefficients[i])
+
+			g := growth[segment]
+			if p, ok := periods[i].Portfolio[segment]; ok {
+				s.PortfolioWeight += p.Weight / float64(len(periods))
+				g[0] *= 1 + p.Return
+			}
+			if b, ok := periods[i].Benchmark[segment]; ok {
+				s.BenchmarkWeight += b.Weight / float64(len(periods))
+				g[1] *= 1 + b.Return
+			}
+			growth[segment] = g
+		}
+	}
+	for segment, g := range growth {
+		result.Segments[segment].PortfolioReturn = g[0] - 1
+		result.Segments[segment].BenchmarkReturn = g[1] - 1
+	}
+
+	result.PortfolioReturn = TotalReturn(portfolio)
+	result.BenchmarkReturn = TotalReturn(benchmark)
+	result.ExcessReturn = result.PortfolioReturn - result.BenchmarkReturn
+	return result, nil
+}
+
+// LinkingCoefficients 计算多期连接系数，各期超额收益乘以系数后之和等于复利超额收益
+func LinkingCoefficients(portfolio, benchmark []float64, method LinkingMethod) ([]float64, error) {
+	if len(portfolio) != len(benchmark) {
+		return nil, fmt.Errorf("组合与基准收益期数不一致: %d != %d", len(portfolio), len(benchmark))
+	}
+	n := len(portfolio)
+	coefficients := make([]float64, n)
+	if n == 0 {
+		return coefficients, nil
+	}
+	total, totalBenchmark := TotalReturn(portfolio), TotalReturn(benchmark)
+
+	switch method {
+	case LinkCarino, "":
+		k := carinoFactor(total, totalBenchmark)
+		for i := range portfolio {
+			coefficients[i] = carinoFactor(portfolio[i], benchmark[i]) / k
+		}
+	case LinkMenchero:
+		t := float64(n)
+		var m float64
+		if total == totalBenchmark {
+			m = math.Pow(1+total, (t-1)/t)
+		} else {
+			m = (total - totalBenchmark) / (t * (math.Pow(1+total, 1/t) - math.Pow(1+totalBenchmark, 1/t)))
+		}
+		sumExcess, sumSquares := 0.0, 0.0
+		for i := range portfolio {
+			d := portfolio[i] - benchmark[i]
+			sumExcess += d
+			sumSquares += d * d
+		}
+		for i := range portfolio {
+			coefficients[i] = m
+			if sumSquares > 0 {
+				coefficients[i] += (total - totalBenchmark - m*sumExcess) * (portfolio[i] - benchmark[i]) / sumSquares
+			}
+		}
+	default:
+		return nil, fmt.Errorf("不支持的连接方法: %s", method)
+	}
+	return coefficients, nil
+}
+
+// carinoFactor Carino 系数 (ln(1+R)-ln(1+B))/(R-B)，R=B 时取极限 1/(1+R)
+func carinoFactor(r, b float64) float64 {
+	if math.Abs(r-b) < 1e-12 {
+		return 1 / (1 + r)
+	}
+	return (math.Log1p(r) - math.Log1p(b)) / (r - b)
+}
+
+// segmentNames 组合和基准中出现的全部分组，按名称排序
+func segmentNames(period BrinsonPeriod) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, segments := range []map[string]SegmentReturn{period.Portfolio, period.Benchmark} {
+		for name := range segments {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,140 @@
+package analytics
+
+import "testing"
+
+func TestBrinsonFachler(t *testing.T) {
+	period := BrinsonPeriod{
+		Date: "2023-01-03",
+		Portfolio: map[string]SegmentReturn{
+			"银行": {Weight: 0.6, Return: 0.05},
+			"医药": {Weight: 0.4, Return: -0.01},
+		},
+		Benchmark: map[string]SegmentReturn{
+			"银行": {Weight: 0.5, Return: 0.04},
+			"医药": {Weight: 0.5, Return: 0.00},
+		},
+	}
+	result := BrinsonFachler(period)
+
+	// Rp = 0.026，Rb = 0.02
+	if !almostEqual(result.PortfolioReturn, 0.026) || !almostEqual(result.BenchmarkReturn, 0.02) {
+		t.Fatalf("returns = %v, %v", result.PortfolioReturn, result.BenchmarkReturn)
+	}
+	bank := result.Segments["银行"]
+	if !almostEqual(bank.Allocation, 0.1*0.02) || !almostEqual(bank.Selection, 0.5*0.01) || !almostEqual(bank.Interaction, 0.1*0.01) {
+		t.Errorf("unexpected bank effect: %+v", bank)
+	}
+	if !almostEqual(result.Effect.Total, 0.006) {
+		t.Errorf("total effect = %v, want excess 0.006", result.Effect.Total)
+	}
+
+	t.Run("MissingSegments", func(t *testing.T) {
+		// 组合持有基准外的现金，基准中的医药组合未持有
+		period := BrinsonPeriod{
+			Portfolio: map[string]SegmentReturn{"银行": {Weight: 0.8, Return: 0.05}, "现金": {Weight: 0.2}},
+			Benchmark: map[string]SegmentReturn{"银行": {Weight: 0.5, Return: 0.04}, "医药": {Weight: 0.5}},
+		}
+		result := BrinsonFachler(period)
+		if !almostEqual(result.Effect.Total, result.PortfolioReturn-result.BenchmarkReturn) {
+			t.Errorf("effects %v should sum to excess %v", result.Effect.Total, result.PortfolioReturn-result.BenchmarkReturn)
+		}
+		if cash := result.Segments["现金"]; cash.Allocation != 0 || !almostEqual(cash.Interaction, 0.2*-0.02) {
+			t.Errorf("unexpected cash effect: %+v", cash)
+		}
+	})
+}
+
+func TestBrinsonLinking(t *testing.T) {
+	periods := []BrinsonPeriod{
+		{
+			Portfolio: map[string]SegmentReturn{"A": {Weight: 0.7, Return: 0.03}, "B": {Weight: 0.3, Return: -0.02}},
+			Benchmark: map[string]SegmentReturn{"A": {Weight: 0.5, Return: 0.02}, "B": {Weight: 0.5, Return: -0.01}},
+		},
+		{
+			Portfolio: map[string]SegmentReturn{"A": {Weight: 0.4, Return: -0.05}, "B": {Weight: 0.6, Return: 0.04}},
+			Benchmark: map[string]SegmentReturn{"A": {Weight: 0.5, Return: -0.03}, "B": {Weight: 0.5, Return: 0.01}},
+		},
+		{
+			Portfolio: map[string]SegmentReturn{"A": {Weight: 0.5, Return: 0.01}, "B": {Weight: 0.5, Return: 0.01}},
+			Benchmark: map[string]SegmentReturn{"A": {Weight: 0.5, Return: 0.01}, "B": {Weight: 0.5, Return: 0.01}},
+		},
+	}
+
+	for _, method := range []LinkingMethod{LinkCarino, LinkMenchero} {
+		result, err := Brinson(periods, method)
+		if err != nil {
+			t.Fatalf("%s: Brinson failed: %v", method, err)
+		}
+		if !almostEqual(result.Effect.Total, result.ExcessReturn) {
+			t.Errorf("%s: linked effects %v != excess %v", method, result.Effect.Total, result.ExcessReturn)
+		}
+		segmentTotal := 0.0
+		for _, s := range result.Segments {
+			segmentTotal += s.Total
+		}
+		if !almostEqual(segmentTotal, result.ExcessReturn) {
+			t.Errorf("%s: segment effects %v != excess %v", method, segmentTotal, result.ExcessReturn)
+		}
+		if !almostEqual(result.Segments["A"].PortfolioWeight, (0.7+0.4+0.5)/3) {
+			t.Errorf("%s: average weight = %v", method, result.Segments["A"].PortfolioWeight)
+		}
+	}
+
+	if _, err := Brinson(periods, "geometric"); err == nil {
+		t.Error("expected error for unknown linking method")
+	}
+	if _, err := Brinson(nil, LinkCarino); err == nil {
+		t.Error("expected error for empty periods")
+	}
+}
+
+func TestFactorAttribution(t *testing.T) {
+	// 证券收益严格满足 r = 0.01 + 0.02×size - 0.01×value
+	exposures := map[string]map[string]float64{
+		"A": {"size": 1, "value": 0},
+		"B": {"size": -1, "value": 1},
+		"C": {"size": 0.5, "value": -1},
+		"D": {"size": 0, "value": 2},
+		"E": {"size": 2, "value": 0.5},
+	}
+	returns := make(map[string]float64)
+	for inst, x := range exposures {
+		returns[inst] = 0.01 + 0.02*x["size"] - 0.01*x["value"]
+	}
+	period := FactorPeriod{Weights: map[string]float64{"A": 0.5, "C": 0.3}, Returns: returns}
+
+	result, err := FactorAttribution([]FactorPeriod{period, period}, exposures, []string{"size", "value"}, LinkCarino)
+	if err != nil {
+		t.Fatalf("FactorAttribution failed: %v", err)
+	}
+	p := result.Periods[0]
+	if !almostEqual(p.FactorReturns["size"], 0.02) || !almostEqual(p.FactorReturns["value"], -0.01) || !almostEqual(p.FactorReturns[MarketFactor], 0.01) {
+		t.Errorf("unexpected factor returns: %v", p.FactorReturns)
+	}
+	if !almostEqual(p.RSquared, 1) || !almostEqual(p.SpecificReturn, 0) {
+		t.Errorf("R² = %v, specific = %v", p.RSquared, p.SpecificReturn)
+	}
+	// 组合 size 暴露 0.5×1 + 0.3×0.5
+	if !almostEqual(result.Factors["size"].Exposure, 0.65) || !almostEqual(result.Factors[MarketFactor].Exposure, 0.8) {
+		t.Errorf("unexpected exposures: size %+v, market %+v", result.Factors["size"], result.Factors[MarketFactor])
+	}
+
+	total := result.SpecificReturn
+	for _, f := range result.Factors {
+		total += f.Contribution
+	}
+	if !almostEqual(total, result.PortfolioReturn) {
+		t.Errorf("linked contributions %v != portfolio return %v", total, result.PortfolioReturn)
+	}
+
+	t.Run("InsufficientSample", func(t *testing.T) {
+		period := FactorPeriod{Weights: map[string]float64{"A": 1}, Returns: map[string]float64{"A": 0.03, "B": 0.01}}
+		result, err := FactorAttribution([]FactorPeriod{period}, exposures, []string{"size", "value"}, LinkCarino)
+		if err != nil {
+			t.Fatalf("FactorAttribution failed: %v", err)
+		}
+		if !almostEqual(result.SpecificReturn, 0.03) {
+			t.Errorf("unexplained return should be specific, got %v", result.SpecificReturn)
+		}
+	})
+}
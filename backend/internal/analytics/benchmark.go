@@ -0,0 +1,149 @@
+package analytics
+
+import "math"
+
+// RelativeMetrics 相对基准的指标，策略与基准收益序列需按日期对齐
+type RelativeMetrics struct {
+	BenchmarkReturn  float64 `json:"benchmark_return"` // 基准累计收益
+	ExcessReturn     float64 `json:"excess_return"`    // 策略与基准累计收益之差
+	Beta             float64 `json:"beta"`
+	Alpha            float64 `json:"alpha"` // 年化Jensen alpha
+	Correlation      float64 `json:"correlation"`
+	ActiveReturn     float64 `json:"active_return"`  // 年化主动收益
+	TrackingError    float64 `json:"tracking_error"` // 年化跟踪误差
+	InformationRatio float64 `json:"information_ratio"`
+	UpCapture        float64 `json:"up_capture"`
+	DownCapture      float64 `json:"down_capture"`
+	HitRate          float64 `json:"hit_rate"` // 跑赢基准的期数占比
+}
+
+// Covariance 样本协方差
+func Covariance(a, b []float64) float64 {
+	n := minLen(a, b)
+	if n < 2 {
+		return 0
+	}
+	meanA, meanB := Mean(a[:n]), Mean(b[:n])
+	sum := 0.0
+	for i := 0; i < n; i++ {
+		sum += (a[i] - meanA) * (b[i] - meanB)
+	}
+	return sum / float64(n-1)
+}
+
+// Correlation 皮尔逊相关系数
+func Correlation(a, b []float64) float64 {
+	n := minLen(a, b)
+	return ratio(Covariance(a, b), StdDev(a[:n])*StdDev(b[:n]))
+}
+
+// RankCorrelation Spearman秩相关系数，相同值取平均秩
+func RankCorrelation(a, b []float64) float64 {
+	n := minLen(a, b)
+	ra, _ := averageRanks(a[:n])
+	rb, _ := averageRanks(b[:n])
+	return Correlation(ra, rb)
+}
+
+// Beta 策略收益对基准收益的回归系数
+func Beta(returns, benchmark []float64) float64 {
+	n := minLen(returns, benchmark)
+	std := StdDev(benchmark[:n])
+	return ratio(Covariance(returns, benchmark), std*std)
+}
+
+// Alpha 年化Jensen alpha：扣除无风险收益后，策略超出 beta×基准 的部分
+func Alpha(returns, benchmark []float64, opts Options) float64 {
+	n := minLen(returns, benchmark)
+	rf := opts.periodRiskFree()
+	excess := Mean(returns[:n]) - rf - Beta(returns, benchmark)*(Mean(benchmark[:n])-rf)
+	return excess * opts.periods()
+}
+
+// ActiveReturns 逐期主动收益
+func ActiveReturns(returns, benchmark []float64) []float64 {
+	n := minLen(returns, benchmark)
+	active := make([]float64, n)
+	for i := 0; i < n; i++ {
+		active[i] = returns[i] - benchmark[i]
+	}
+	return active
+}
+
+// TrackingError 年化跟踪误差
+func TrackingError(returns, benchmark []float64, opts Options) float64 {
+	return StdDev(ActiveReturns(returns, benchmark)) * math.Sqrt(opts.periods())
+}
+
+// InformationRatio 信息比率：年化主动收益 / 年化跟踪误差
+func InformationRatio(returns, benchmark []float64, opts Options) float64 {
+	active := ActiveReturns(returns, benchmark)
+	return ratio(Mean(active)*opts.periods(), StdDev(active)*math.Sqrt(opts.periods()))
+}
+
+// UpCapture 上行捕获率：基准上涨期间策略平均收益 / 基准平均收益
+func UpCapture(returns, benchmark []float64) float64 {
+	return captureRatio(returns, benchmark, func(b float64) bool { return b > 0 })
+}
+
+// DownCapture 下行捕获率：基准下跌期间策略平均收益 / 基准平均收益
+func DownCapture(returns, benchmark []float64) float64 {
+	return captureRatio(returns, benchmark, func(b float64) bool { return b < 0 })
+}
+
+func captureRatio(returns, benchmark []float64, include func(float64) bool) float64 {
+	var r, b []float64
+	for i := 0; i < minLen(returns, benchmark); i++ {
+		if include(benchmark[i]) {
+			r = append(r, returns[i])
+			b = append(b, benchmark[i])
+		}
+	}
+	return ratio(Mean(r), Mean(b))
+}
+
+// HitRate 策略收益高于基准收益的期数占比
+func HitRate(returns, benchmark []float64) float64 {
+	n := minLen(returns, benchmark)
+	if n == 0 {
+		return 0
+	}
+	hits := 0
+	for i := 0; i < n; i++ {
+		if returns[i] > benchmark[i] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(n)
+}
+
+// ComputeRelative 计算全部相对基准指标，基准为空时返回 nil
+func ComputeRelative(returns, benchmark []float64, opts Options) *RelativeMetrics {
+	if len(benchmark) == 0 {
+		return nil
+	}
+	n := minLen(returns, benchmark)
+	returns, benchmark = returns[:n], benchmark[:n]
+	active := ActiveReturns(returns, benchmark)
+	metrics := &RelativeMetrics{
+		BenchmarkReturn:  TotalReturn(benchmark),
+		Beta:             Beta(returns, benchmark),
+		Alpha:            Alpha(returns, benchmark, opts),
+		Correlation:      Correlation(returns, benchmark),
+		ActiveReturn:     Mean(active) * opts.periods(),
+		TrackingError:    TrackingError(returns, benchmark, opts),
+		InformationRatio: InformationRatio(returns, benchmark, opts),
+		UpCapture:        UpCapture(returns, benchmark),
+		DownCapture:      DownCapture(returns, benchmark),
+		HitRate:          HitRate(returns, benchmark),
+	}
+	metrics.ExcessReturn = TotalReturn(returns) - metrics.BenchmarkReturn
+	return metrics
+}
+
+func minLen(a, b []float64) int {
+	if len(a) < len(b) {
+		return len(a)
+	}
+	return len(b)
+}
@@ -0,0 +1,96 @@
+package analytics
+
+import "math"
+
+// NormalCDF 标准正态分布函数
+func NormalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// StudentTCDF 自由度为 df 的t分布函数
+func StudentTCDF(t, df float64) float64 {
+	if math.IsInf(df, 1) {
+		return NormalCDF(t)
+	}
+	tail := 0.5 * regularizedBeta(df/2, 0.5, df/(df+t*t))
+	if t > 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// twoSidedT t统计量的双侧p值
+func twoSidedT(t, df float64) float64 {
+	return 2 * StudentTCDF(-math.Abs(t), df)
+}
+
+// twoSidedNormal z统计量的双侧p值
+func twoSidedNormal(z float64) float64 {
+	return 2 * NormalCDF(-math.Abs(z))
+}
+
+// regularizedBeta 正则化不完全Beta函数 I_x(a, b)，使用连分式展开
+func regularizedBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lgab, _ := math.Lgamma(a + b)
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	// 连分式在 x < (a+1)/(a+b+2) 时收敛较快，否则利用对称性
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+// betaContinuedFraction 不完全Beta函数的连分式（修正Lentz算法）
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		// 偶数项
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		// 奇数项
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
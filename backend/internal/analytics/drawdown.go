@@ -0,0 +1,75 @@
+package analytics
+
+// Drawdown 最大回撤及其持续和恢复情况，索引均指收益序列的位置
+type Drawdown struct {
+	MaxDrawdown       float64 `json:"max_drawdown"`       // 最大回撤，非正数
+	PeakIndex         int     `json:"peak_index"`         // 回撤开始前的净值高点，-1 表示期初净值
+	TroughIndex       int     `json:"trough_index"`       // 净值最低点
+	RecoveryIndex     int     `json:"recovery_index"`     // 净值回到前高的位置，未恢复为 -1
+	Duration          int     `json:"duration"`           // 高点到低点的期数
+	RecoveryDuration  int     `json:"recovery_duration"`  // 低点到恢复的期数，未恢复为 -1
+	LongestUnderwater int     `json:"longest_underwater"` // 最长连续处于回撤的期数
+}
+
+// DrawdownSeries 以期初净值1复利计算的回撤序列
+func DrawdownSeries(returns []float64) []float64 {
+	drawdowns := make([]float64, len(returns))
+	wealth, peak := 1.0, 1.0
+	for i, r := range returns {
+		wealth *= 1 + r
+		if wealth > peak {
+			peak = wealth
+		}
+		drawdowns[i] = wealth/peak - 1
+	}
+	return drawdowns
+}
+
+// MaxDrawdown 计算最大回撤，并给出回撤的起止、恢复位置和最长水下期
+func MaxDrawdown(returns []float64) Drawdown {
+	result := Drawdown{PeakIndex: -1, RecoveryIndex: -1, RecoveryDuration: -1}
+	if len(returns) == 0 {
+		result.TroughIndex = -1
+		return result
+	}
+
+	wealth, peak := 1.0, 1.0
+	peakIndex, underwater := -1, 0
+	for i, r := range returns {
+		wealth *= 1 + r
+		if wealth >= peak {
+			peak, peakIndex, underwater = wealth, i, 0
+			continue
+		}
+		underwater++
+		if underwater > result.LongestUnderwater {
+			result.LongestUnderwater = underwater
+		}
+		if dd := wealth/peak - 1; dd < result.MaxDrawdown {
+			result.MaxDrawdown = dd
+			result.PeakIndex = peakIndex
+			result.TroughIndex = i
+		}
+	}
+	if result.MaxDrawdown == 0 {
+		result.PeakIndex, result.TroughIndex = -1, -1
+		return result
+	}
+	result.Duration = result.TroughIndex - result.PeakIndex
+
+	// 从低点向后寻找净值回到前高的位置
+	peakWealth := 1.0
+	wealth = 1.0
+	for i, r := range returns {
+		wealth *= 1 + r
+		if i == result.PeakIndex {
+			peakWealth = wealth
+		}
+		if i > result.TroughIndex && wealth >= peakWealth {
+			result.RecoveryIndex = i
+			result.RecoveryDuration = i - result.TroughIndex
+			break
+		}
+	}
+	return result
+}
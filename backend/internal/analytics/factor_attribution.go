@@ -0,0 +1,230 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// MarketFactor 截面回归截距项对应的市场因子名称
+const MarketFactor = "market"
+
+// FactorPeriod 单期因子归因输入
+type FactorPeriod struct {
+	Date    string             `json:"date"`
+	Weights map[string]float64 `json:"weights"` // 期初组合权重，合计小于1的部分视为现金
+	Returns map[string]float64 `json:"returns"` // 本期全市场（或股票池）证券收益，用于截面回归
+}
+
+// FactorPeriodResult 单期因子归因结果
+type FactorPeriodResult struct {
+	Date            string             `json:"date"`
+	PortfolioReturn float64            `json:"portfolio_return"`
+	FactorReturns   map[string]float64 `json:"factor_returns"`
+	Exposures       map[string]float64 `json:"exposures"`
+	Contributions   map[string]float64 `json:"contributions"`
+	SpecificReturn  float64            `json:"specific_return"`
+	RSquared        float64            `json:"r_squared"`
+}
+
+// FactorContribution 因子的平均暴露、复利因子收益和连接后的收益贡献
+type FactorContribution struct {
+	Exposure     float64 `json:"exposure"`
+	FactorReturn float64 `json:"factor_return"`
+	Contribution float64 `json:"contribution"`
+}
+
+// FactorAttributionResult 多期因子归因结果，因子贡献与特异收益之和等于组合复利收益
+type FactorAttributionResult struct {
+	Linking         LinkingMethod                  `json:"linking"`
+	PortfolioReturn float64                        `json:"portfolio_return"`
+	Factors         map[string]*FactorContribution `json:"factors"`
+	SpecificReturn  float64                        `json:"specific_return"`
+	AverageRSquared float64                        `json:"average_r_squared"`
+	Periods         []FactorPeriodResult           `json:"periods"`
+}
+
+// FactorAttribution 基于给定暴露矩阵的因子收益归因
+//
+// 每期用证券收益对因子暴露（含截距）做截面最小二乘回归得到因子收益，
+// 组合因子暴露为持仓权重加权的证券暴露，因子贡献 = 组合暴露 × 因子收益，
+// 截距项的贡献记为 MarketFactor。组合收益中未被解释的部分记为特异收益。
+// exposures 为 证券 -> 因子 -> 暴露值，缺少任一因子暴露的证券不参与回归。
+func FactorAttribution(periods []FactorPeriod, exposures map[string]map[string]float64, factors []string, method LinkingMethod) (*FactorAttributionResult, error) {
+	if len(periods) == 0 {
+		return nil, fmt.Errorf("没有归因期间")
+	}
+	if len(factors) == 0 {
+		return nil, fmt.Errorf("未指定因子")
+	}
+	if method == "" {
+		method = LinkCarino
+	}
+	names := append([]string{MarketFactor}, factors...)
+
+	result := &FactorAttributionResult{Linking: method, Factors: make(map[string]*FactorContribution)}
+	portfolio := make([]float64, len(periods))
+	for i, period := range periods {
+		periodResult := factorPeriod(period, exposures, factors)
+		result.Periods = append(result.Periods, periodResult)
+		portfolio[i] = periodResult.PortfolioReturn
+		result.AverageRSquared += periodResult.RSquared / float64(len(periods))
+	}
+
+	// 以0收益为基准连接，各期贡献乘以系数后之和等于组合复利收益
+	coefficients, err := LinkingCoefficients(portfolio, make([]float64, len(periods)), method)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		contribution := &FactorContribution{}
+		growth := 1.0
+		for i, p := range result.Periods {
+			contribution.Exposure += p.Exposures[name] / float64(len(periods))
+			contribution.Contribution += p.Contributions[name] * coefficients[i]
+			growth *= 1 + p.FactorReturns[name]
+		}
+		contribution.FactorReturn = growth - 1
+		result.Factors[name] = contribution
+	}
+	for i, p := range result.Periods {
+		result.SpecificReturn += p.SpecificReturn * coefficients[i]
+	}
+	result.PortfolioReturn = TotalReturn(portfolio)
+	return result, nil
+}
+
+// factorPeriod 单期截面回归和收益分解，样本不足以回归时全部收益记为特异收益
+func factorPeriod(period FactorPeriod, exposures map[string]map[string]float64, factors []string) FactorPeriodResult {
+	result := FactorPeriodResult{
+		Date:          period.Date,
+		FactorReturns: make(map[string]float64),
+		Exposures:     make(map[string]float64),
+		Contributions: make(map[string]float64),
+	}
+	for inst, w := range period.Weights {
+		result.PortfolioReturn += w * period.Returns[inst]
+	}
+
+	// 组装回归样本，按证券代码排序保证结果可复现
+	instruments := make([]string, 0, len(period.Returns))
+	for inst, r := range period.Returns {
+		if !math.IsNaN(r) && hasExposures(exposures[inst], factors) {
+			instruments = append(instruments, inst)
+		}
+	}
+	sort.Strings(instruments)
+	x := make([][]float64, len(instruments))
+	y := make([]float64, len(instruments))
+	for i, inst := range instruments {
+		x[i] = make([]float64, len(factors)+1)
+		x[i][0] = 1
+		for j, factor := range factors {
+			x[i][j+1] = exposures[inst][factor]
+		}
+		y[i] = period.Returns[inst]
+	}
+
+	coefficients, rSquared, err := leastSquares(x, y)
+	if err != nil {
+		result.SpecificReturn = result.PortfolioReturn
+		return result
+	}
+	result.RSquared = rSquared
+
+	for inst, w := range period.Weights {
+		if !hasExposures(exposures[inst], factors) {
+			continue
+		}
+		result.Exposures[MarketFactor] += w
+		for _, factor := range factors {
+			result.Exposures[factor] += w * exposures[inst][factor]
+		}
+	}
+	explained := 0.0
+	for j, name := range append([]string{MarketFactor}, factors...) {
+		result.FactorReturns[name] = coefficients[j]
+		result.Contributions[name] = result.Exposures[name] * coefficients[j]
+		explained += result.Contributions[name]
+	}
+	result.SpecificReturn = result.PortfolioReturn - explained
+	return result
+}
+
+// hasExposures 证券是否具有全部因子的暴露值
+func hasExposures(values map[string]float64, factors []string) bool {
+	if values == nil {
+		return false
+	}
+	for _, factor := range factors {
+		if v, ok := values[factor]; !ok || math.IsNaN(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// leastSquares 通过正规方程求解最小二乘回归，返回系数和R²
+func leastSquares(x [][]float64, y []float64) ([]float64, float64, error) {
+	n := len(y)
+	if n == 0 {
+		return nil, 0, fmt.Errorf("回归样本为空")
+	}
+	k := len(x[0])
+	if n <= k {
+		return nil, 0, fmt.Errorf("回归样本数 %d 不足以估计 %d 个参数", n, k)
+	}
+
+	// 增广矩阵 [X'X | X'y]
+	a := make([][]float64, k)
+	for i := range a {
+		a[i] = make([]float64, k+1)
+	}
+	for r := 0; r < n; r++ {
+		for i := 0; i < k; i++ {
+			for j := 0; j < k; j++ {
+				a[i][j] += x[r][i] * x[r][j]
+			}
+			a[i][k] += x[r][i] * y[r]
+		}
+	}
+
+	// 部分主元高斯消元
+	for col := 0; col < k; col++ {
+		pivot := col
+		for row := col + 1; row < k; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return nil, 0, fmt.Errorf("因子暴露存在共线性")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		for row := 0; row < k; row++ {
+			if row == col {
+				continue
+			}
+			factor := a[row][col] / a[col][col]
+			for j := col; j <= k; j++ {
+				a[row][j] -= factor * a[col][j]
+			}
+		}
+	}
+	beta := make([]float64, k)
+	for i := range beta {
+		beta[i] = a[i][k] / a[i][i]
+	}
+
+	mean := Mean(y)
+	ssTotal, ssResidual := 0.0, 0.0
+	for r := 0; r < n; r++ {
+		fitted := 0.0
+		for i := 0; i < k; i++ {
+			fitted += x[r][i] * beta[i]
+		}
+		ssResidual += (y[r] - fitted) * (y[r] - fitted)
+		ssTotal += (y[r] - mean) * (y[r] - mean)
+	}
+	return beta, 1 - ratio(ssResidual, ssTotal), nil
+}
@@ -0,0 +1,275 @@
+package analytics
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// TestResult 单项假设检验结果
+type TestResult struct {
+	Test      string  `json:"test"`
+	Statistic float64 `json:"statistic"`
+	PValue    float64 `json:"p_value"`
+	N         int     `json:"n"`
+}
+
+// CorrectionMethod 多重比较校正方法
+type CorrectionMethod string
+
+const (
+	CorrectionNone CorrectionMethod = "none"
+	CorrectionHolm CorrectionMethod = "holm" // Holm-Bonferroni，控制族错误率
+	CorrectionBH   CorrectionMethod = "bh"   // Benjamini-Hochberg，控制错误发现率
+)
+
+// PairedTTest 配对t检验，差值均值的标准误采用Newey-West估计以处理自相关
+//
+// lags 为Newey-West滞后阶数，小于0时按 floor(4(n/100)^(2/9)) 自动选择。
+func PairedTTest(a, b []float64, lags int) TestResult {
+	d := ActiveReturns(a, b)
+	result := TestResult{Test: "paired_t_newey_west", N: len(d), PValue: 1}
+	if len(d) < 2 {
+		return result
+	}
+	if lags < 0 {
+		lags = int(math.Floor(4 * math.Pow(float64(len(d))/100, 2.0/9)))
+	}
+	se := math.Sqrt(NeweyWestVariance(d, lags) / float64(len(d)))
+	if se == 0 {
+		return result
+	}
+	result.Statistic = Mean(d) / se
+	result.PValue = twoSidedT(result.Statistic, float64(len(d)-1))
+	return result
+}
+
+// NeweyWestVariance 序列的Newey-West长期方差（Bartlett核）
+func NeweyWestVariance(values []float64, lags int) float64 {
+	variance := autocovariance(values, 0)
+	for j := 1; j <= lags && j < len(values); j++ {
+		variance += 2 * (1 - float64(j)/float64(lags+1)) * autocovariance(values, j)
+	}
+	return variance
+}
+
+// autocovariance 滞后 lag 阶的自协方差（分母为n）
+func autocovariance(values []float64, lag int) float64 {
+	n := len(values)
+	if n == 0 || lag >= n {
+		return 0
+	}
+	mean := Mean(values)
+	sum := 0.0
+	for t := lag; t < n; t++ {
+		sum += (values[t] - mean) * (values[t-lag] - mean)
+	}
+	return sum / float64(n)
+}
+
+// WilcoxonSignedRank Wilcoxon符号秩检验，统计量为正差值的秩和
+//
+// 差值为0的样本被剔除；样本不超过25且无结时使用精确分布，否则使用带结校正和连续性校正的正态近似。
+func WilcoxonSignedRank(a, b []float64) TestResult {
+	var d []float64
+	for _, v := range ActiveReturns(a, b) {
+		if v != 0 {
+			d = append(d, v)
+		}
+	}
+	result := TestResult{Test: "wilcoxon_signed_rank", N: len(d), PValue: 1}
+	n := len(d)
+	if n == 0 {
+		return result
+	}
+
+	abs := make([]float64, n)
+	for i, v := range d {
+		abs[i] = math.Abs(v)
+	}
+	ranks, tieCorrection := averageRanks(abs)
+	for i, v := range d {
+		if v > 0 {
+			result.Statistic += ranks[i]
+		}
+	}
+
+	if n <= 25 && tieCorrection == 0 {
+		result.PValue = exactSignedRankP(n, result.Statistic)
+		return result
+	}
+	fn := float64(n)
+	mean := fn * (fn + 1) / 4
+	sd := math.Sqrt(fn*(fn+1)*(2*fn+1)/24 - tieCorrection/48)
+	if sd == 0 {
+		return result
+	}
+	diff := result.Statistic - mean
+	if diff > 0 {
+		diff -= 0.5
+	} else if diff < 0 {
+		diff += 0.5
+	}
+	result.PValue = math.Min(1, twoSidedNormal(diff/sd))
+	return result
+}
+
+// averageRanks 计算平均秩（从1开始），并返回结校正项 Σ(t³-t)
+func averageRanks(values []float64) ([]float64, float64) {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+
+	ranks := make([]float64, len(values))
+	correction := 0.0
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			ranks[order[k]] = rank
+		}
+		if t := float64(j - i + 1); t > 1 {
+			correction += t*t*t - t
+		}
+		i = j + 1
+	}
+	return ranks, correction
+}
+
+// exactSignedRankP 无结时符号秩统计量的精确双侧p值
+func exactSignedRankP(n int, w float64) float64 {
+	maxSum := n * (n + 1) / 2
+	counts := make([]float64, maxSum+1)
+	counts[0] = 1
+	for k := 1; k <= n; k++ {
+		for s := maxSum; s >= k; s-- {
+			counts[s] += counts[s-k]
+		}
+	}
+	total := math.Pow(2, float64(n))
+	lower, upper := 0.0, 0.0
+	for s, c := range counts {
+		if float64(s) <= w {
+			lower += c
+		}
+		if float64(s) >= w {
+			upper += c
+		}
+	}
+	return math.Min(1, 2*math.Min(lower, upper)/total)
+}
+
+// DieboldMariano Diebold-Mariano预测精度检验，输入为两组预测逐期的损失
+//
+// 损失差的长期方差使用 horizon-1 阶自协方差，并采用Harvey-Leybourne-Newbold小样本修正，
+// 统计量为负表示 lossA 更小（A更准确）。
+func DieboldMariano(lossA, lossB []float64, horizon int) TestResult {
+	d := ActiveReturns(lossA, lossB)
+	n := len(d)
+	result := TestResult{Test: "diebold_mariano", N: n, PValue: 1}
+	if n < 2 {
+		return result
+	}
+	if horizon < 1 {
+		horizon = 1
+	}
+	variance := autocovariance(d, 0)
+	for j := 1; j < horizon; j++ {
+		variance += 2 * autocovariance(d, j)
+	}
+	if variance <= 0 {
+		variance = autocovariance(d, 0)
+	}
+	if variance == 0 {
+		return result
+	}
+	fn, h := float64(n), float64(horizon)
+	dm := Mean(d) / math.Sqrt(variance/fn)
+	result.Statistic = dm * math.Sqrt((fn+1-2*h+h*(h-1)/fn)/fn)
+	result.PValue = twoSidedT(result.Statistic, fn-1)
+	return result
+}
+
+// SharpeDifferenceTest 用平稳自助法（Politis-Romano）检验两组收益的夏普比率是否相等
+//
+// 统计量为单期夏普比率之差，p值为中心化后的自助样本差值绝对值不小于原始差值的比例。
+// blockLength 为平均块长，不大于0时取 n^(1/3)；samples 不大于0时取1000；seed 固定时结果可复现。
+func SharpeDifferenceTest(a, b []float64, blockLength float64, samples int, seed int64) TestResult {
+	n := minLen(a, b)
+	result := TestResult{Test: "stationary_bootstrap_sharpe", N: n, PValue: 1}
+	if n < 3 {
+		return result
+	}
+	a, b = a[:n], b[:n]
+	if blockLength <= 0 {
+		blockLength = math.Max(1, math.Cbrt(float64(n)))
+	}
+	if samples <= 0 {
+		samples = 1000
+	}
+	result.Statistic = ratio(Mean(a), StdDev(a)) - ratio(Mean(b), StdDev(b))
+
+	rng := rand.New(rand.NewSource(seed))
+	restart := 1 / blockLength
+	sampleA := make([]float64, n)
+	sampleB := make([]float64, n)
+	exceed := 0
+	for s := 0; s < samples; s++ {
+		idx := rng.Intn(n)
+		for t := 0; t < n; t++ {
+			if t > 0 {
+				if rng.Float64() < restart {
+					idx = rng.Intn(n)
+				} else {
+					idx = (idx + 1) % n
+				}
+			}
+			// 两组收益使用相同的索引以保留相关性
+			sampleA[t], sampleB[t] = a[idx], b[idx]
+		}
+		diff := ratio(Mean(sampleA), StdDev(sampleA)) - ratio(Mean(sampleB), StdDev(sampleB))
+		if math.Abs(diff-result.Statistic) >= math.Abs(result.Statistic) {
+			exceed++
+		}
+	}
+	result.PValue = float64(exceed+1) / float64(samples+1)
+	return result
+}
+
+// AdjustPValues 多重比较校正，返回与输入顺序一致的校正后p值
+func AdjustPValues(pvalues []float64, method CorrectionMethod) []float64 {
+	m := len(pvalues)
+	adjusted := make([]float64, m)
+	copy(adjusted, pvalues)
+	if m <= 1 || method == CorrectionNone || method == "" {
+		return adjusted
+	}
+
+	order := make([]int, m)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return pvalues[order[i]] < pvalues[order[j]] })
+
+	switch method {
+	case CorrectionHolm:
+		running := 0.0
+		for rank, i := range order {
+			running = math.Max(running, math.Min(1, float64(m-rank)*pvalues[i]))
+			adjusted[i] = running
+		}
+	case CorrectionBH:
+		running := 1.0
+		for rank := m - 1; rank >= 0; rank-- {
+			i := order[rank]
+			running = math.Min(running, math.Min(1, float64(m)/float64(rank+1)*pvalues[i]))
+			adjusted[i] = running
+		}
+	}
+	return adjusted
+}
@@ -0,0 +1,125 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStudentTCDF(t *testing.T) {
+	cases := []struct {
+		t, df, want float64
+	}{
+		{0, 5, 0.5},
+		{2.0, 10, 0.963306},
+		{-2.0, 10, 0.036694},
+		{1.96, math.Inf(1), 0.975002},
+		{12.706, 1, 0.975},
+	}
+	for _, c := range cases {
+		if got := StudentTCDF(c.t, c.df); math.Abs(got-c.want) > 1e-5 {
+			t.Errorf("StudentTCDF(%v, %v) = %v, want %v", c.t, c.df, got, c.want)
+		}
+	}
+}
+
+func TestPairedTTest(t *testing.T) {
+	a := []float64{0.012, -0.004, 0.008, 0.015, -0.002, 0.010, 0.006, 0.011, -0.001, 0.009}
+	b := make([]float64, len(a))
+	for i, v := range a {
+		b[i] = v - 0.005 + 0.001*float64(i%3-1)
+	}
+
+	result := PairedTTest(a, b, 0)
+	// 差值为 0.005 ± 0.001，lags=0 时退化为普通配对t检验
+	if result.Statistic <= 0 || result.PValue > 1e-6 {
+		t.Errorf("expected significant positive difference, got %+v", result)
+	}
+	if same := PairedTTest(a, a, -1); same.PValue != 1 || same.Statistic != 0 {
+		t.Errorf("identical series should not be significant, got %+v", same)
+	}
+	if nw := PairedTTest(a, b, 2); nw.N != len(a) || nw.PValue <= 0 {
+		t.Errorf("unexpected Newey-West result: %+v", nw)
+	}
+}
+
+func TestWilcoxonSignedRank(t *testing.T) {
+	// 差值 1..8 全为正：W=36，精确双侧p值 2/256
+	a := []float64{1, 2, 3, 4, 5, 6, 7, 8}
+	b := make([]float64, len(a))
+	result := WilcoxonSignedRank(a, b)
+	if result.Statistic != 36 || !almostEqual(result.PValue, 2.0/256) {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	// 差值 {1,-2,3,-4,5}：W=9，P(W>=9)=P(W<=6)=13/32
+	result = WilcoxonSignedRank([]float64{1, -2, 3, -4, 5}, make([]float64, 5))
+	if result.Statistic != 9 || !almostEqual(result.PValue, 26.0/32) {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	t.Run("Ties", func(t *testing.T) {
+		d := []float64{1, 1, 1, -1, 2, 2, 3, 0}
+		result := WilcoxonSignedRank(d, make([]float64, len(d)))
+		if result.N != 7 || result.PValue <= 0 || result.PValue >= 1 {
+			t.Errorf("unexpected tie-corrected result: %+v", result)
+		}
+	})
+}
+
+func TestDieboldMariano(t *testing.T) {
+	lossA := []float64{0.1, 0.2, 0.15, 0.12, 0.18, 0.11, 0.14, 0.16, 0.13, 0.17}
+	lossB := make([]float64, len(lossA))
+	for i, v := range lossA {
+		lossB[i] = v + 0.05 + 0.01*float64(i%2)
+	}
+	result := DieboldMariano(lossA, lossB, 1)
+	if result.Statistic >= 0 || result.PValue > 0.01 {
+		t.Errorf("A has smaller loss, expected negative significant statistic, got %+v", result)
+	}
+	reversed := DieboldMariano(lossB, lossA, 1)
+	if !almostEqual(reversed.Statistic, -result.Statistic) {
+		t.Errorf("statistic should flip sign: %v vs %v", reversed.Statistic, result.Statistic)
+	}
+}
+
+func TestSharpeDifferenceTest(t *testing.T) {
+	a := []float64{0.01, -0.02, 0.015, 0.003, -0.007, 0.012, 0.004, -0.001, 0.009, -0.005}
+	same := SharpeDifferenceTest(a, a, 0, 200, 1)
+	if same.Statistic != 0 || same.PValue != 1 {
+		t.Errorf("identical series should give p=1, got %+v", same)
+	}
+
+	b := make([]float64, len(a))
+	for i, v := range a {
+		b[i] = v - 0.01
+	}
+	first := SharpeDifferenceTest(a, b, 2, 200, 7)
+	second := SharpeDifferenceTest(a, b, 2, 200, 7)
+	if first != second {
+		t.Errorf("fixed seed should be reproducible: %+v vs %+v", first, second)
+	}
+	if first.Statistic <= 0 || first.PValue <= 0 || first.PValue > 1 {
+		t.Errorf("unexpected result: %+v", first)
+	}
+}
+
+func TestAdjustPValues(t *testing.T) {
+	pvalues := []float64{0.01, 0.04, 0.03}
+	cases := []struct {
+		method CorrectionMethod
+		want   []float64
+	}{
+		{CorrectionNone, []float64{0.01, 0.04, 0.03}},
+		{CorrectionHolm, []float64{0.03, 0.06, 0.06}},
+		{CorrectionBH, []float64{0.03, 0.04, 0.04}},
+	}
+	for _, c := range cases {
+		got := AdjustPValues(pvalues, c.method)
+		for i := range got {
+			if !almostEqual(got[i], c.want[i]) {
+				t.Errorf("%s: got %v, want %v", c.method, got, c.want)
+				break
+			}
+		}
+	}
+}
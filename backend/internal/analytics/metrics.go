@@ -0,0 +1,53 @@
+package analytics
+
+import "math"
+
+// Metrics 由收益序列计算的全部绩效与风险指标
+type Metrics struct {
+	Periods           int      `json:"periods"`
+	TotalReturn       float64  `json:"total_return"`
+	AnnualReturn      float64  `json:"annual_return"`
+	AnnualVolatility  float64  `json:"annual_volatility"`
+	SharpeRatio       float64  `json:"sharpe_ratio"`
+	SortinoRatio      float64  `json:"sortino_ratio"`
+	CalmarRatio       float64  `json:"calmar_ratio"`
+	OmegaRatio        float64  `json:"omega_ratio"`
+	DownsideDeviation float64  `json:"downside_deviation"` // 年化
+	Skewness          float64  `json:"skewness"`
+	Kurtosis          float64  `json:"kurtosis"` // 超额峰度
+	WinRate           float64  `json:"win_rate"`
+	PayoffRatio       float64  `json:"payoff_ratio"`
+	Drawdown          Drawdown `json:"drawdown"`
+
+	Historical    TailRisk `json:"historical"`
+	Parametric    TailRisk `json:"parametric"`
+	CornishFisher TailRisk `json:"cornish_fisher"`
+
+	Relative *RelativeMetrics `json:"relative,omitempty"` // 未提供基准时为空
+}
+
+// Compute 根据单期收益序列计算全部指标，benchmark 可为空
+func Compute(returns, benchmark []float64, opts Options) *Metrics {
+	drawdown := MaxDrawdown(returns)
+	annual := AnnualizedReturn(returns, opts)
+	return &Metrics{
+		Periods:           len(returns),
+		TotalReturn:       TotalReturn(returns),
+		AnnualReturn:      annual,
+		AnnualVolatility:  AnnualizedVolatility(returns, opts),
+		SharpeRatio:       SharpeRatio(returns, opts),
+		SortinoRatio:      SortinoRatio(returns, opts),
+		CalmarRatio:       ratio(annual, math.Abs(drawdown.MaxDrawdown)),
+		OmegaRatio:        OmegaRatio(returns, opts.periodRiskFree()),
+		DownsideDeviation: DownsideDeviation(returns, opts.periodRiskFree()) * math.Sqrt(opts.periods()),
+		Skewness:          Skewness(returns),
+		Kurtosis:          Kurtosis(returns),
+		WinRate:           WinRate(returns),
+		PayoffRatio:       PayoffRatio(returns),
+		Drawdown:          drawdown,
+		Historical:        ComputeTailRisk(returns, VaRHistorical),
+		Parametric:        ComputeTailRisk(returns, VaRParametric),
+		CornishFisher:     ComputeTailRisk(returns, VaRCornishFisher),
+		Relative:          ComputeRelative(returns, benchmark, opts),
+	}
+}
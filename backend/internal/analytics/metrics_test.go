@@ -0,0 +1,112 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestReturnMetrics(t *testing.T) {
+	returns := []float64{0.01, -0.02, 0.03, 0.0}
+
+	if got := TotalReturn(returns); !almostEqual(got, 1.01*0.98*1.03-1) {
+		t.Errorf("TotalReturn = %v", got)
+	}
+	if got := AnnualizedReturn(returns, Options{}); !almostEqual(got, 0.005*252) {
+		t.Errorf("AnnualizedReturn = %v, want %v", got, 0.005*252)
+	}
+	// 样本方差 = (0.005² + 0.025² + 0.025² + 0.005²) / 3
+	std := math.Sqrt((0.000025 + 0.000625 + 0.000625 + 0.000025) / 3)
+	if got := AnnualizedVolatility(returns, Options{}); !almostEqual(got, std*math.Sqrt(252)) {
+		t.Errorf("AnnualizedVolatility = %v", got)
+	}
+	if got := SharpeRatio(returns, Options{}); !almostEqual(got, 0.005*252/(std*math.Sqrt(252))) {
+		t.Errorf("SharpeRatio = %v", got)
+	}
+	// 仅 -0.02 低于0，下行偏差 = sqrt(0.0004/4)
+	if got := DownsideDeviation(returns, 0); !almostEqual(got, 0.01) {
+		t.Errorf("DownsideDeviation = %v, want 0.01", got)
+	}
+	if got := OmegaRatio(returns, 0); !almostEqual(got, 2) {
+		t.Errorf("OmegaRatio = %v, want 2", got)
+	}
+	if got := WinRate(returns); got != 0.5 {
+		t.Errorf("WinRate = %v, want 0.5", got)
+	}
+	if got := PayoffRatio(returns); !almostEqual(got, 1) {
+		t.Errorf("PayoffRatio = %v, want 1", got)
+	}
+
+	t.Run("DegenerateInput", func(t *testing.T) {
+		flat := []float64{0.01, 0.01, 0.01}
+		if SharpeRatio(flat, Options{}) != 0 || SortinoRatio(flat, Options{}) != 0 || CalmarRatio(flat, Options{}) != 0 {
+			t.Error("ratios with zero denominators should be 0")
+		}
+		m := Compute(nil, nil, Options{})
+		if m.TotalReturn != 0 || m.Relative != nil || m.Drawdown.TroughIndex != -1 {
+			t.Errorf("unexpected metrics for empty input: %+v", m)
+		}
+	})
+}
+
+func TestMaxDrawdown(t *testing.T) {
+	// 净值 1.1, 0.99, 0.891, 1.0692, 1.12266
+	returns := []float64{0.1, -0.1, -0.1, 0.2, 0.05}
+	dd := MaxDrawdown(returns)
+
+	if !almostEqual(dd.MaxDrawdown, 0.891/1.1-1) {
+		t.Errorf("MaxDrawdown = %v, want %v", dd.MaxDrawdown, 0.891/1.1-1)
+	}
+	if dd.PeakIndex != 0 || dd.TroughIndex != 2 || dd.Duration != 2 {
+		t.Errorf("unexpected peak/trough: %+v", dd)
+	}
+	if dd.RecoveryIndex != 4 || dd.RecoveryDuration != 2 || dd.LongestUnderwater != 3 {
+		t.Errorf("unexpected recovery: %+v", dd)
+	}
+
+	series := DrawdownSeries(returns)
+	if series[0] != 0 || !almostEqual(series[2], dd.MaxDrawdown) || series[4] != 0 {
+		t.Errorf("unexpected drawdown series: %v", series)
+	}
+
+	// 从期初即下跌且未恢复
+	dd = MaxDrawdown([]float64{-0.1, 0.05})
+	if dd.PeakIndex != -1 || dd.TroughIndex != 0 || dd.RecoveryIndex != -1 || dd.RecoveryDuration != -1 {
+		t.Errorf("unexpected unrecovered drawdown: %+v", dd)
+	}
+}
+
+func TestRelativeMetrics(t *testing.T) {
+	benchmark := []float64{0.01, -0.02, 0.015, -0.005, 0.02}
+	returns := make([]float64, len(benchmark))
+	for i, b := range benchmark {
+		returns[i] = 0.001 + 1.5*b
+	}
+
+	rel := ComputeRelative(returns, benchmark, Options{})
+	if !almostEqual(rel.Beta, 1.5) || !almostEqual(rel.Correlation, 1) {
+		t.Errorf("Beta = %v, Correlation = %v", rel.Beta, rel.Correlation)
+	}
+	if !almostEqual(rel.Alpha, 0.001*252) {
+		t.Errorf("Alpha = %v, want %v", rel.Alpha, 0.001*252)
+	}
+	if !almostEqual(rel.ExcessReturn, TotalReturn(returns)-TotalReturn(benchmark)) {
+		t.Errorf("ExcessReturn = %v", rel.ExcessReturn)
+	}
+	// 上涨期 (0.016+0.0235+0.031)/3 ÷ (0.01+0.015+0.02)/3
+	if !almostEqual(rel.UpCapture, 0.0705/0.045) {
+		t.Errorf("UpCapture = %v", rel.UpCapture)
+	}
+	if !almostEqual(rel.DownCapture, (-0.029-0.0065)/(-0.025)) {
+		t.Errorf("DownCapture = %v", rel.DownCapture)
+	}
+	if rel.HitRate != 0.6 {
+		t.Errorf("HitRate = %v, want 0.6", rel.HitRate)
+	}
+	if ComputeRelative(returns, nil, Options{}) != nil {
+		t.Error("ComputeRelative without benchmark should be nil")
+	}
+}
@@ -0,0 +1,162 @@
+// Package analytics 根据日收益率序列和基准收益率序列计算绩效与风险指标
+//
+// 年化口径与Qlib的 risk_analysis 一致：年化收益 = 期均收益 × 每年期数，
+// 年化波动 = 期收益标准差 × sqrt(每年期数)。无法计算的比率返回0，便于直接序列化为JSON。
+package analytics
+
+import "math"
+
+// TradingDaysPerYear 日频数据的年化期数
+const TradingDaysPerYear = 252
+
+// Options 指标计算选项
+type Options struct {
+	RiskFreeRate   float64 // 年化无风险利率
+	PeriodsPerYear int     // 每年期数，默认252
+}
+
+// periods 每年期数
+func (o Options) periods() float64 {
+	if o.PeriodsPerYear <= 0 {
+		return TradingDaysPerYear
+	}
+	return float64(o.PeriodsPerYear)
+}
+
+// periodRiskFree 每期无风险收益
+func (o Options) periodRiskFree() float64 {
+	return o.RiskFreeRate / o.periods()
+}
+
+// Mean 均值，空序列为0
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// StdDev 样本标准差，少于两个值时为0
+func StdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	mean := Mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// TotalReturn 复利累计收益
+func TotalReturn(returns []float64) float64 {
+	growth := 1.0
+	for _, r := range returns {
+		growth *= 1 + r
+	}
+	return growth - 1
+}
+
+// CumulativeReturns 逐期复利累计收益
+func CumulativeReturns(returns []float64) []float64 {
+	cumulative := make([]float64, len(returns))
+	growth := 1.0
+	for i, r := range returns {
+		growth *= 1 + r
+		cumulative[i] = growth - 1
+	}
+	return cumulative
+}
+
+// AnnualizedReturn 年化收益（期均收益 × 每年期数）
+func AnnualizedReturn(returns []float64, opts Options) float64 {
+	return Mean(returns) * opts.periods()
+}
+
+// AnnualizedVolatility 年化波动率
+func AnnualizedVolatility(returns []float64, opts Options) float64 {
+	return StdDev(returns) * math.Sqrt(opts.periods())
+}
+
+// SharpeRatio 夏普比率
+func SharpeRatio(returns []float64, opts Options) float64 {
+	return ratio(AnnualizedReturn(returns, opts)-opts.RiskFreeRate, AnnualizedVolatility(returns, opts))
+}
+
+// DownsideDeviation 低于目标收益部分的均方根（每期），分母为全部期数
+func DownsideDeviation(returns []float64, target float64) float64 {
+	if len(returns) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, r := range returns {
+		if r < target {
+			sum += (r - target) * (r - target)
+		}
+	}
+	return math.Sqrt(sum / float64(len(returns)))
+}
+
+// SortinoRatio 索提诺比率，以无风险收益为目标收益计算下行波动
+func SortinoRatio(returns []float64, opts Options) float64 {
+	downside := DownsideDeviation(returns, opts.periodRiskFree()) * math.Sqrt(opts.periods())
+	return ratio(AnnualizedReturn(returns, opts)-opts.RiskFreeRate, downside)
+}
+
+// CalmarRatio 卡玛比率：年化收益 / 最大回撤绝对值
+func CalmarRatio(returns []float64, opts Options) float64 {
+	return ratio(AnnualizedReturn(returns, opts), math.Abs(MaxDrawdown(returns).MaxDrawdown))
+}
+
+// OmegaRatio Omega比率：高于阈值部分之和 / 低于阈值部分之和
+func OmegaRatio(returns []float64, threshold float64) float64 {
+	gains, losses := 0.0, 0.0
+	for _, r := range returns {
+		if r > threshold {
+			gains += r - threshold
+		} else {
+			losses += threshold - r
+		}
+	}
+	return ratio(gains, losses)
+}
+
+// WinRate 正收益期数占比
+func WinRate(returns []float64) float64 {
+	if len(returns) == 0 {
+		return 0
+	}
+	wins := 0
+	for _, r := range returns {
+		if r > 0 {
+			wins++
+		}
+	}
+	return float64(wins) / float64(len(returns))
+}
+
+// PayoffRatio 盈亏比：正收益期平均收益 / 负收益期平均亏损
+func PayoffRatio(returns []float64) float64 {
+	var gains, losses []float64
+	for _, r := range returns {
+		if r > 0 {
+			gains = append(gains, r)
+		} else if r < 0 {
+			losses = append(losses, -r)
+		}
+	}
+	return ratio(Mean(gains), Mean(losses))
+}
+
+// ratio 分母为0时返回0
+func ratio(numerator, denominator float64) float64 {
+	if denominator == 0 || math.IsNaN(denominator) {
+		return 0
+	}
+	return numerator / denominator
+}
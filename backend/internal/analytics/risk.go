@@ -0,0 +1,150 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+)
+
+// VaRMethod VaR计算方法
+type VaRMethod string
+
+const (
+	VaRHistorical    VaRMethod = "historical"     // 历史模拟法
+	VaRParametric    VaRMethod = "parametric"     // 正态分布参数法
+	VaRCornishFisher VaRMethod = "cornish_fisher" // 按偏度和峰度修正分位数的参数法
+)
+
+// TailRisk 95%和99%置信水平下的VaR和CVaR，以收益率表示，损失为负
+type TailRisk struct {
+	VaR95  float64 `json:"var_95"`
+	VaR99  float64 `json:"var_99"`
+	CVaR95 float64 `json:"cvar_95"`
+	CVaR99 float64 `json:"cvar_99"`
+}
+
+// ComputeTailRisk 按指定方法计算95%和99%的VaR和CVaR
+func ComputeTailRisk(returns []float64, method VaRMethod) TailRisk {
+	return TailRisk{
+		VaR95:  VaR(returns, 0.95, method),
+		VaR99:  VaR(returns, 0.99, method),
+		CVaR95: CVaR(returns, 0.95, method),
+		CVaR99: CVaR(returns, 0.99, method),
+	}
+}
+
+// Skewness 偏度（矩估计）
+func Skewness(returns []float64) float64 {
+	m2, m3, _ := centralMoments(returns)
+	if m2 == 0 {
+		return 0
+	}
+	return m3 / math.Pow(m2, 1.5)
+}
+
+// Kurtosis 超额峰度（矩估计，正态分布为0）
+func Kurtosis(returns []float64) float64 {
+	m2, _, m4 := centralMoments(returns)
+	if m2 == 0 {
+		return 0
+	}
+	return m4/(m2*m2) - 3
+}
+
+// centralMoments 二、三、四阶中心矩
+func centralMoments(values []float64) (m2, m3, m4 float64) {
+	n := float64(len(values))
+	if n == 0 {
+		return 0, 0, 0
+	}
+	mean := Mean(values)
+	for _, v := range values {
+		d := v - mean
+		m2 += d * d
+		m3 += d * d * d
+		m4 += d * d * d * d
+	}
+	return m2 / n, m3 / n, m4 / n
+}
+
+// VaR 在险价值：置信水平 confidence 下单期收益的 1-confidence 分位数
+func VaR(returns []float64, confidence float64, method VaRMethod) float64 {
+	if len(returns) == 0 {
+		return 0
+	}
+	alpha := 1 - confidence
+	switch method {
+	case VaRParametric:
+		return Mean(returns) + normQuantile(alpha)*StdDev(returns)
+	case VaRCornishFisher:
+		return Mean(returns) + cornishFisherZ(normQuantile(alpha), Skewness(returns), Kurtosis(returns))*StdDev(returns)
+	default:
+		sorted := append([]float64(nil), returns...)
+		sort.Float64s(sorted)
+		return quantileSorted(sorted, alpha)
+	}
+}
+
+// CVaR 条件在险价值：收益不高于VaR时的期望收益
+func CVaR(returns []float64, confidence float64, method VaRMethod) float64 {
+	if len(returns) == 0 {
+		return 0
+	}
+	alpha := 1 - confidence
+	mean, std := Mean(returns), StdDev(returns)
+	switch method {
+	case VaRParametric:
+		return mean - std*normPDF(normQuantile(alpha))/alpha
+	case VaRCornishFisher:
+		// 对修正后的分位数在 (0, alpha) 上积分求平均
+		const steps = 1000
+		skew, kurt := Skewness(returns), Kurtosis(returns)
+		sum := 0.0
+		for i := 0; i < steps; i++ {
+			p := alpha * (float64(i) + 0.5) / steps
+			sum += cornishFisherZ(normQuantile(p), skew, kurt)
+		}
+		return mean + std*sum/steps
+	default:
+		threshold := VaR(returns, confidence, VaRHistorical)
+		total, count := 0.0, 0
+		for _, r := range returns {
+			if r <= threshold {
+				total += r
+				count++
+			}
+		}
+		if count == 0 {
+			return threshold
+		}
+		return total / float64(count)
+	}
+}
+
+// cornishFisherZ 用偏度和超额峰度修正标准正态分位数
+func cornishFisherZ(z, skew, kurt float64) float64 {
+	return z +
+		(z*z-1)*skew/6 +
+		(z*z*z-3*z)*kurt/24 -
+		(2*z*z*z-5*z)*skew*skew/36
+}
+
+// normQuantile 标准正态分布分位数
+func normQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+// normPDF 标准正态分布密度
+func normPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
+
+// quantileSorted 已排序序列的分位数（线性插值，与numpy默认一致）
+func quantileSorted(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
@@ -0,0 +1,60 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVaR(t *testing.T) {
+	returns := make([]float64, 100)
+	for i := range returns {
+		returns[i] = float64(i-50) / 1000 // -0.05 ... 0.049
+	}
+
+	t.Run("Historical", func(t *testing.T) {
+		// 5%分位位于第 4.95 个位置：-0.046 + 0.95×0.001
+		if got := VaR(returns, 0.95, VaRHistorical); !almostEqual(got, -0.04505) {
+			t.Errorf("VaR95 = %v, want -0.04505", got)
+		}
+		// 不高于VaR的收益为 -0.05 ... -0.046
+		if got := CVaR(returns, 0.95, VaRHistorical); !almostEqual(got, -0.048) {
+			t.Errorf("CVaR95 = %v, want -0.048", got)
+		}
+	})
+
+	t.Run("Parametric", func(t *testing.T) {
+		mean, std := Mean(returns), StdDev(returns)
+		if got := VaR(returns, 0.99, VaRParametric); math.Abs(got-(mean-2.326348*std)) > 1e-6 {
+			t.Errorf("VaR99 = %v", got)
+		}
+		if got := CVaR(returns, 0.95, VaRParametric); math.Abs(got-(mean-2.062713*std)) > 1e-6 {
+			t.Errorf("CVaR95 = %v", got)
+		}
+	})
+
+	t.Run("CornishFisher", func(t *testing.T) {
+		// 对称分布偏度为0，负超额峰度使尾部比正态分布更薄
+		parametric := ComputeTailRisk(returns, VaRParametric)
+		cf := ComputeTailRisk(returns, VaRCornishFisher)
+		if cf.VaR99 <= parametric.VaR99 || cf.CVaR99 <= parametric.CVaR99 {
+			t.Errorf("Cornish-Fisher %+v should be less severe than parametric %+v", cf, parametric)
+		}
+		if cf.CVaR95 > cf.VaR95 {
+			t.Errorf("CVaR95 %v should not exceed VaR95 %v", cf.CVaR95, cf.VaR95)
+		}
+	})
+}
+
+func TestMoments(t *testing.T) {
+	symmetric := []float64{-2, -1, 0, 1, 2}
+	if got := Skewness(symmetric); !almostEqual(got, 0) {
+		t.Errorf("Skewness = %v, want 0", got)
+	}
+	// m2 = 2, m4 = 6.8
+	if got := Kurtosis(symmetric); !almostEqual(got, 6.8/4-3) {
+		t.Errorf("Kurtosis = %v, want %v", got, 6.8/4-3)
+	}
+	if got := Skewness([]float64{0, 0, 0, 1}); got <= 0 {
+		t.Errorf("right-tailed sample should have positive skewness, got %v", got)
+	}
+}
@@ -53,6 +53,7 @@ func (h *AnalysisHandler) CompareModels(c *gin.Context) {
 		Metrics     []string `json:"metrics"`      // 对比指标 - 传递给service层使用
 		CompareType string   `json:"compare_type"` // 对比类型 - 映射到service层的Granularity参数
 		TimeRange   string   `json:"time_range"`   // 时间范围 - 用于指定对比的时间段
+		Correction  string   `json:"correction"`   // 多重比较校正：holm（默认）、bh、none
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -65,6 +66,7 @@ func (h *AnalysisHandler) CompareModels(c *gin.Context) {
 		Metrics:     req.Metrics,
 		TimeRange:   req.TimeRange,   // 正确使用TimeRange参数
 		Granularity: req.CompareType, // CompareType映射到Granularity
+		Correction:  req.Correction,
 	}
 	comparison, err := h.analysisService.CompareModels(compareReq, userID.(uint))
 	if err != nil {
@@ -284,3 +286,17 @@ func (h *AnalysisHandler) MultiCompareResults(c *gin.Context) {
 
 	utils.SuccessResponse(c, comparison)
 }
+
+func analysisHandler() *AnalysisHandler {
+	return NewAnalysisHandler(services.NewAnalysisService(services.GetDB()), nil)
+}
+
+// CompareModelPerformance 模型性能对比
+func CompareModelPerformance(c *gin.Context) {
+	analysisHandler().CompareModels(c)
+}
+
+// CompareStrategyPerformance 多策略对比
+func CompareStrategyPerformance(c *gin.Context) {
+	analysisHandler().CompareStrategies(c)
+}
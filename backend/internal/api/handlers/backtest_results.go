@@ -10,11 +10,18 @@ import (
 	"qlib-backend/internal/utils"
 )
 
+// BacktestResultsProvider 回测结果处理器使用的服务接口，由 services.BacktestResultsService 实现
+type BacktestResultsProvider interface {
+	GetDetailedResultsWithOptions(resultID uint, userID uint, options services.GetDetailedResultsOptions) (*services.DetailedBacktestResult, error)
+	GetChartDataWithOptions(resultID uint, chartType services.ChartType, userID uint, options services.GetChartDataOptions) (*services.ChartData, error)
+	ExportBacktestReportExtended(req models.BacktestReportExportRequestExtended, userID uint) (string, error)
+}
+
 type BacktestResultsHandler struct {
-	backtestResultsService *services.BacktestResultsService
+	backtestResultsService BacktestResultsProvider
 }
 
-func NewBacktestResultsHandler(backtestResultsService *services.BacktestResultsService) *BacktestResultsHandler {
+func NewBacktestResultsHandler(backtestResultsService BacktestResultsProvider) *BacktestResultsHandler {
 	return &BacktestResultsHandler{
 		backtestResultsService: backtestResultsService,
 	}
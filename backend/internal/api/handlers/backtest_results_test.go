@@ -10,6 +10,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"qlib-backend/internal/models"
 	"qlib-backend/internal/services"
 	"qlib-backend/internal/testutils"
 )
@@ -19,17 +20,19 @@ type MockBacktestResultsService struct {
 	mock.Mock
 }
 
-func (m *MockBacktestResultsService) GetDetailedResults(resultID, userID uint) (interface{}, error) {
-	args := m.Called(resultID, userID)
-	return args.Get(0), args.Error(1)
+func (m *MockBacktestResultsService) GetDetailedResultsWithOptions(resultID uint, userID uint, options services.GetDetailedResultsOptions) (*services.DetailedBacktestResult, error) {
+	args := m.Called(resultID, userID, options)
+	result, _ := args.Get(0).(*services.DetailedBacktestResult)
+	return result, args.Error(1)
 }
 
-func (m *MockBacktestResultsService) GetChartData(resultID uint, chartType services.ChartType, userID uint) (interface{}, error) {
-	args := m.Called(resultID, chartType, userID)
-	return args.Get(0), args.Error(1)
+func (m *MockBacktestResultsService) GetChartDataWithOptions(resultID uint, chartType services.ChartType, userID uint, options services.GetChartDataOptions) (*services.ChartData, error) {
+	args := m.Called(resultID, chartType, userID, options)
+	result, _ := args.Get(0).(*services.ChartData)
+	return result, args.Error(1)
 }
 
-func (m *MockBacktestResultsService) ExportBacktestReport(req services.BacktestReportExportRequest, userID uint) (string, error) {
+func (m *MockBacktestResultsService) ExportBacktestReportExtended(req models.BacktestReportExportRequestExtended, userID uint) (string, error) {
 	args := m.Called(req, userID)
 	return args.String(0), args.Error(1)
 }
@@ -50,10 +53,10 @@ func TestBacktestResultsHandler_GetDetailedResults(t *testing.T) {
 			resultID: "1",
 			userID:   1,
 			setupMock: func(m *MockBacktestResultsService) {
-				m.On("GetDetailedResults", uint(1), uint(1)).Return(map[string]interface{}{
-					"returns":     []float64{0.1, 0.2, 0.15},
-					"positions":   []string{"AAPL", "GOOGL"},
-					"risk_metrics": map[string]float64{"sharpe": 1.5, "max_drawdown": -0.1},
+				m.On("GetDetailedResultsWithOptions", uint(1), uint(1), services.GetDetailedResultsOptions{IncludeRiskMetrics: true}).Return(&services.DetailedBacktestResult{
+					ResultID:     1,
+					StrategyName: "测试策略",
+					RiskMetrics:  &services.RiskMetrics{},
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -71,7 +74,7 @@ func TestBacktestResultsHandler_GetDetailedResults(t *testing.T) {
 			resultID: "1",
 			userID:   1,
 			setupMock: func(m *MockBacktestResultsService) {
-				m.On("GetDetailedResults", uint(1), uint(1)).Return(nil, assert.AnError)
+				m.On("GetDetailedResultsWithOptions", uint(1), uint(1), mock.Anything).Return(nil, assert.AnError)
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -88,7 +91,7 @@ func TestBacktestResultsHandler_GetDetailedResults(t *testing.T) {
 
 			// 创建测试路由
 			router := gin.New()
-			router.GET("/backtest/results/:result_id/detailed", testutils.MockAuthMiddleware(tt.userID), handler.GetDetailedResults)
+			router.GET("/backtest/results/:result_id/detailed", testutils.MockAuthMiddlewareForUser(tt.userID), handler.GetDetailedResults)
 
 			// 创建请求
 			req, _ := http.NewRequest("GET", "/backtest/results/"+tt.resultID+"/detailed", nil)
@@ -128,9 +131,12 @@ func TestBacktestResultsHandler_GetChartData(t *testing.T) {
 			chartType: "returns",
 			userID:    1,
 			setupMock: func(m *MockBacktestResultsService) {
-				m.On("GetChartData", uint(1), services.ChartType("returns"), uint(1)).Return(map[string]interface{}{
-					"x_data": []string{"2023-01", "2023-02", "2023-03"},
-					"y_data": []float64{0.1, 0.15, 0.12},
+				m.On("GetChartDataWithOptions", uint(1), services.ChartType("returns"), uint(1), services.GetChartDataOptions{Resolution: "daily"}).Return(&services.ChartData{
+					Type: "line",
+					Data: map[string]interface{}{
+						"x_data": []string{"2023-01", "2023-02", "2023-03"},
+						"y_data": []float64{0.1, 0.15, 0.12},
+					},
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -150,7 +156,7 @@ func TestBacktestResultsHandler_GetChartData(t *testing.T) {
 			chartType: "returns",
 			userID:    1,
 			setupMock: func(m *MockBacktestResultsService) {
-				m.On("GetChartData", uint(1), services.ChartType("returns"), uint(1)).Return(nil, assert.AnError)
+				m.On("GetChartDataWithOptions", uint(1), services.ChartType("returns"), uint(1), mock.Anything).Return(nil, assert.AnError)
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -167,7 +173,9 @@ func TestBacktestResultsHandler_GetChartData(t *testing.T) {
 
 			// 创建测试路由
 			router := gin.New()
-			router.GET("/backtest/charts/:result_id/:chart_type", testutils.MockAuthMiddleware(tt.userID), handler.GetChartData)
+			router.GET("/backtest/charts/:result_id/:chart_type", testutils.MockAuthMiddlewareForUser(tt.userID), handler.GetChartData)
+			// 图表类型为空时直接交给处理器校验，而不是由路由重定向
+			router.GET("/backtest/charts/:result_id/", testutils.MockAuthMiddlewareForUser(tt.userID), handler.GetChartData)
 
 			// 创建请求
 			req, _ := http.NewRequest("GET", "/backtest/charts/"+tt.resultID+"/"+tt.chartType, nil)
@@ -214,7 +222,9 @@ func TestBacktestResultsHandler_ExportBacktestReport(t *testing.T) {
 			},
 			userID: 1,
 			setupMock: func(m *MockBacktestResultsService) {
-				m.On("ExportBacktestReport", mock.AnythingOfType("services.BacktestReportExportRequest"), uint(1)).Return("task_123", nil)
+				m.On("ExportBacktestReportExtended", mock.MatchedBy(func(req models.BacktestReportExportRequestExtended) bool {
+					return req.ReportType == "detailed" && req.Format == "pdf" && len(req.Sections) == 2 && req.IncludeCharts
+				}), uint(1)).Return("task_123", nil)
 			},
 			expectedStatus: http.StatusOK,
 		},
@@ -235,7 +245,7 @@ func TestBacktestResultsHandler_ExportBacktestReport(t *testing.T) {
 			},
 			userID: 1,
 			setupMock: func(m *MockBacktestResultsService) {
-				m.On("ExportBacktestReport", mock.AnythingOfType("services.BacktestReportExportRequest"), uint(1)).Return("", assert.AnError)
+				m.On("ExportBacktestReportExtended", mock.AnythingOfType("models.BacktestReportExportRequestExtended"), uint(1)).Return("", assert.AnError)
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -252,7 +262,7 @@ func TestBacktestResultsHandler_ExportBacktestReport(t *testing.T) {
 
 			// 创建测试路由
 			router := gin.New()
-			router.POST("/backtest/export-report", testutils.MockAuthMiddleware(tt.userID), handler.ExportBacktestReport)
+			router.POST("/backtest/export-report", testutils.MockAuthMiddlewareForUser(tt.userID), handler.ExportBacktestReport)
 
 			// 创建请求体
 			jsonBody, _ := json.Marshal(tt.requestBody)
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"qlib-backend/internal/services"
+	"qlib-backend/internal/utils"
+)
+
+// maxCalendarUploadSize 上传交易日历文件的大小上限
+const maxCalendarUploadSize = 1 << 20
+
+func calendarService() *services.CalendarService {
+	return services.NewCalendarService(services.GetDB(), nil)
+}
+
+// GetTradingCalendars 列出各市场当前使用的交易日历
+func GetTradingCalendars(c *gin.Context) {
+	utils.SuccessResponse(c, gin.H{"calendars": calendarService().ListCalendars()})
+}
+
+// UploadTradingCalendar 管理员上传市场的交易日历，替换该市场当前使用的日历
+func UploadTradingCalendar(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		utils.BadRequestResponse(c, "获取上传文件失败: "+err.Error())
+		return
+	}
+	defer file.Close()
+	if header.Size > maxCalendarUploadSize {
+		utils.BadRequestResponse(c, "交易日历文件大小超过1MB限制")
+		return
+	}
+	content, err := io.ReadAll(io.LimitReader(file, maxCalendarUploadSize))
+	if err != nil {
+		utils.BadRequestResponse(c, "读取上传文件失败: "+err.Error())
+		return
+	}
+
+	info, err := calendarService().UploadCalendar(c.Param("market"), content, userID.(uint))
+	if err != nil {
+		utils.BadRequestResponse(c, "上传交易日历失败: "+err.Error())
+		return
+	}
+	utils.SuccessWithMessage(c, "交易日历上传成功", info)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"qlib-backend/internal/calendar"
+	"qlib-backend/internal/testutils"
+)
+
+func newCalendarUploadRequest(t *testing.T, market, content string) *http.Request {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if content != "" {
+		part, err := writer.CreateFormFile("file", "calendar.txt")
+		if err != nil {
+			t.Fatalf("CreateFormFile failed: %v", err)
+		}
+		part.Write([]byte(content))
+	}
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/calendars/"+market, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestTradingCalendarHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/calendars", GetTradingCalendars)
+	router.POST("/calendars/:market", testutils.MockAuthMiddleware(), UploadTradingCalendar)
+
+	t.Run("List", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/calendars", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		data := response["data"].(map[string]interface{})
+		assert.Len(t, data["calendars"], 3)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			market  string
+			content string
+		}{
+			{"缺少文件", "us", ""},
+			{"不支持的市场", "jp", "2024-01-02\n"},
+			{"无效日期", "us", "not-a-date\n"},
+		}
+		for _, tc := range cases {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, newCalendarUploadRequest(t, tc.market, tc.content))
+			assert.Equal(t, http.StatusBadRequest, w.Code, tc.name)
+		}
+	})
+
+	t.Run("Upload", func(t *testing.T) {
+		useTestDB(t)
+		previous := calendar.Default.MustGet("hk")
+		t.Cleanup(func() { calendar.Default.Register(previous) })
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, newCalendarUploadRequest(t, "hk", "2024-01-02\n2024-01-03\n2024-01-04\n"))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		cal := calendar.Default.MustGet("hk")
+		assert.Equal(t, calendar.SourceUpload, cal.Source)
+		assert.Len(t, cal.Days(), 3)
+	})
+}
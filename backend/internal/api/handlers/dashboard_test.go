@@ -4,14 +4,25 @@ import (
 	"net/http"
 	"testing"
 
-	"github.com/gin-gonic/gin"
+	"qlib-backend/internal/services"
 	"qlib-backend/internal/testutils"
 )
 
+// useTestDB 将服务层的全局数据库切换为测试库，测试结束后恢复
+func useTestDB(t *testing.T) {
+	t.Helper()
+	db := testutils.RequireTestDB(t)
+	testutils.CleanupTables(db)
+	previous := services.DB
+	services.DB = db
+	t.Cleanup(func() { services.DB = previous })
+}
+
 func TestDashboardHandlers(t *testing.T) {
 	// 设置测试环境
 	testutils.SetupTestEnv()
 	defer testutils.CleanupTestEnv()
+	useTestDB(t)
 
 	// 设置测试路由器
 	router := testutils.SetupTestRouter()
@@ -59,6 +70,7 @@ func TestDashboardHandlers(t *testing.T) {
 
 func TestGetDashboardOverview(t *testing.T) {
 	// 设置测试环境
+	useTestDB(t)
 	router := testutils.SetupTestRouter()
 	router.Use(testutils.MockAuthMiddleware())
 	router.GET("/dashboard/overview", GetDashboardOverview)
@@ -86,6 +98,7 @@ func TestGetDashboardOverview(t *testing.T) {
 }
 
 func TestGetMarketOverview(t *testing.T) {
+	useTestDB(t)
 	router := testutils.SetupTestRouter()
 	router.Use(testutils.MockAuthMiddleware())
 	router.GET("/dashboard/market-overview", GetMarketOverview)
@@ -97,6 +110,7 @@ func TestGetMarketOverview(t *testing.T) {
 }
 
 func TestGetPerformanceChart(t *testing.T) {
+	useTestDB(t)
 	router := testutils.SetupTestRouter()
 	router.Use(testutils.MockAuthMiddleware())
 	router.GET("/dashboard/performance-chart", GetPerformanceChart)
@@ -124,6 +138,7 @@ func TestGetPerformanceChart(t *testing.T) {
 }
 
 func TestGetRecentTasks(t *testing.T) {
+	useTestDB(t)
 	router := testutils.SetupTestRouter()
 	router.Use(testutils.MockAuthMiddleware())
 	router.GET("/dashboard/recent-tasks", GetRecentTasks)
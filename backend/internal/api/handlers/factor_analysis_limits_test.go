@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"qlib-backend/internal/testutils"
+)
+
+func TestFactorAnalysisParameterLimits(t *testing.T) {
+	router := testutils.SetupTestRouter()
+	router.Use(testutils.MockAuthMiddleware())
+	router.GET("/factors/:id/analysis", GetFactorAnalysis)
+
+	testCases := []testutils.TestCase{
+		{Name: "最大滞后期超限", Method: "GET", URL: "/factors/1/analysis?mode=decay&max_lag=1000000", ExpectedStatus: http.StatusBadRequest},
+		{Name: "最大持有期超限", Method: "GET", URL: "/factors/1/analysis?mode=decay&max_horizon=251", ExpectedStatus: http.StatusBadRequest},
+		{Name: "持有期为负数", Method: "GET", URL: "/factors/1/analysis?mode=decay&max_horizon=-1", ExpectedStatus: http.StatusBadRequest},
+		{Name: "分组数超限", Method: "GET", URL: "/factors/1/analysis?mode=quantile&groups=1000", ExpectedStatus: http.StatusBadRequest},
+		{Name: "调仓间隔超限", Method: "GET", URL: "/factors/1/analysis?mode=quantile&rebalance=100000", ExpectedStatus: http.StatusBadRequest},
+		{Name: "调仓间隔格式错误", Method: "GET", URL: "/factors/1/analysis?mode=quantile&rebalance=abc", ExpectedStatus: http.StatusBadRequest},
+	}
+	testutils.RunTestCases(t, router, testCases)
+}
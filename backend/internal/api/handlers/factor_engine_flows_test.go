@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qlib-backend/internal/qlib"
+	"qlib-backend/internal/testutils"
+)
+
+func TestCheckFactorCorrelationWithFakeEngine(t *testing.T) {
+	qlib.SetDefaultEngines(testutils.RequireFakeEngines(t, 3))
+	defer qlib.SetDefaultEngines(nil)
+
+	router := testutils.SetupTestRouter()
+	router.Use(testutils.MockAuthMiddleware())
+	router.POST("/factors/correlation-check", CheckFactorCorrelation)
+
+	testutils.RunTestCases(t, router, []testutils.TestCase{
+		{Name: "缺少表达式", Method: "POST", URL: "/factors/correlation-check", Body: map[string]interface{}{"top": 5}, ExpectedStatus: http.StatusBadRequest},
+	})
+
+	// 新因子由配置的假后端计算，表达式错误在查询因子库之前返回
+	body := `{"expression": "Mean($close,", "start_date": "2022-03-01", "end_date": "2022-12-31", "universe": "all"}`
+	req := httptest.NewRequest(http.MethodPost, "/factors/correlation-check", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "计算新因子失败") {
+		t.Errorf("表达式错误应由计算后端返回: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -1,6 +1,11 @@
 package handlers
 
 import (
+	"fmt"
+	"net/http"
+	"qlib-backend/config"
+	"qlib-backend/internal/qlib"
+	"qlib-backend/internal/services"
 	"qlib-backend/internal/utils"
 	"strconv"
 
@@ -181,8 +186,19 @@ func TestFactor(c *gin.Context) {
 }
 
 // GetFactorAnalysis 获取因子分析结果
+//
+// mode=quantile 时返回分层回测结果，支持 groups、weighting、rebalance、start_date、end_date、universe、test_id 参数；
+// mode=decay 时返回IC衰减和半衰期，支持 max_horizon、max_lag、start_date、end_date、universe 参数
 func GetFactorAnalysis(c *gin.Context) {
 	id := c.Param("id")
+	switch c.Query("mode") {
+	case "quantile":
+		getFactorQuantileAnalysis(c, id)
+		return
+	case "decay":
+		getFactorDecayAnalysis(c, id)
+		return
+	}
 
 	// 模拟因子分析结果
 	analysis := gin.H{
@@ -218,6 +234,235 @@ func GetFactorAnalysis(c *gin.Context) {
 	utils.SuccessResponse(c, analysis)
 }
 
+// getFactorQuantileAnalysis 因子分层回测，带 test_id 时进度同时推送到 /ws/factor-test/:test_id
+func getFactorQuantileAnalysis(c *gin.Context, id string) {
+	userID, factorID, ok := factorAnalysisTarget(c, id)
+	if !ok {
+		return
+	}
+	groups, ok := boundedQueryInt(c, "groups", qlib.MaxQuantileGroups)
+	if !ok {
+		return
+	}
+	rebalance, ok := boundedQueryInt(c, "rebalance", qlib.MaxQuantileRebalance)
+	if !ok {
+		return
+	}
+	req := services.FactorQuantileRequest{
+		TestID:    c.Query("test_id"),
+		StartDate: c.Query("start_date"),
+		EndDate:   c.Query("end_date"),
+		Universe:  c.Query("universe"),
+		Freq:      c.DefaultQuery("freq", "day"),
+		QuantileOptions: qlib.QuantileOptions{
+			Groups:    groups,
+			Weighting: c.Query("weighting"),
+			CapField:  c.Query("cap_field"),
+			Rebalance: rebalance,
+		},
+	}
+
+	result, err := factorService().AnalyzeFactorQuantiles(factorID, userID, req)
+	if err != nil {
+		utils.InternalErrorResponse(c, "分层回测失败: "+err.Error())
+		return
+	}
+	utils.SuccessResponse(c, result)
+}
+
+// getFactorDecayAnalysis 因子IC衰减分析，结果中的半衰期同时保存到因子库
+func getFactorDecayAnalysis(c *gin.Context, id string) {
+	userID, factorID, ok := factorAnalysisTarget(c, id)
+	if !ok {
+		return
+	}
+	maxHorizon, ok := boundedQueryInt(c, "max_horizon", qlib.MaxDecayHorizon)
+	if !ok {
+		return
+	}
+	maxLag, ok := boundedQueryInt(c, "max_lag", qlib.MaxDecayHorizon)
+	if !ok {
+		return
+	}
+	req := services.FactorDecayRequest{
+		StartDate:    c.Query("start_date"),
+		EndDate:      c.Query("end_date"),
+		Universe:     c.Query("universe"),
+		Freq:         c.DefaultQuery("freq", "day"),
+		DecayOptions: qlib.DecayOptions{MaxHorizon: maxHorizon, MaxLag: maxLag},
+	}
+
+	result, err := factorService().AnalyzeFactorDecay(factorID, userID, req)
+	if err != nil {
+		utils.InternalErrorResponse(c, "IC衰减分析失败: "+err.Error())
+		return
+	}
+	utils.SuccessResponse(c, result)
+}
+
+// boundedQueryInt 解析不超过max的非负整数查询参数，缺省为0（使用默认值），失败时已写入错误响应
+func boundedQueryInt(c *gin.Context, name string, max int) (int, bool) {
+	value, err := strconv.Atoi(c.DefaultQuery(name, "0"))
+	if err != nil || value < 0 || value > max {
+		utils.BadRequestResponse(c, fmt.Sprintf("参数 %s 应为0到%d之间的整数", name, max))
+		return 0, false
+	}
+	return value, true
+}
+
+// factorAnalysisTarget 解析当前用户和因子ID，失败时已写入错误响应
+func factorAnalysisTarget(c *gin.Context, id string) (uint, uint, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "用户未认证")
+		return 0, 0, false
+	}
+	factorID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "因子ID格式错误")
+		return 0, 0, false
+	}
+	return userID.(uint), uint(factorID), true
+}
+
+// factorEngine 返回启动时配置的因子引擎，未配置时若本地有Qlib数据目录则作为原生计算后端，否则调用Python
+func factorEngine() qlib.FactorEvaluationEngine {
+	if engines := qlib.DefaultEngines(); engines != nil {
+		return engines.Factors
+	}
+	cfg := config.Load()
+	engine := qlib.NewFactorEngine(cfg.Qlib.PythonPath, cfg.Qlib.QlibPath, cfg.Qlib.DataPath)
+	if qlib.IsQlibDataDir(cfg.Qlib.DataPath) {
+		engine.SetDataProvider(qlib.NewBinDataReader(cfg.Qlib.DataPath))
+	}
+	return engine
+}
+
+// factorService 创建使用配置的计算后端的因子服务
+func factorService() *services.FactorService {
+	return services.NewFactorService(services.GetDB(), factorEngine())
+}
+
+// CreateCompositeFactor 由因子库中的因子创建复合因子
+func CreateCompositeFactor(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+	var req services.CompositeFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	factor, err := factorService().CreateCompositeFactor(req, userID.(uint))
+	if err != nil {
+		utils.BadRequestResponse(c, "创建复合因子失败: "+err.Error())
+		return
+	}
+	utils.SuccessWithMessage(c, "复合因子创建成功", factor)
+}
+
+// GetCompositeFactor 获取复合因子的成分和加权配置
+func GetCompositeFactor(c *gin.Context) {
+	userID, factorID, ok := factorAnalysisTarget(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	detail, err := factorService().GetCompositeFactor(factorID, userID)
+	if err != nil {
+		utils.NotFoundResponse(c, err.Error())
+		return
+	}
+	utils.SuccessResponse(c, detail)
+}
+
+// TestCompositeFactor 测试复合因子并记录测试历史
+func TestCompositeFactor(c *gin.Context) {
+	userID, factorID, ok := factorAnalysisTarget(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	var req struct {
+		StartDate string `json:"start_date" binding:"required"`
+		EndDate   string `json:"end_date" binding:"required"`
+		Universe  string `json:"universe"`
+		Freq      string `json:"freq"`
+		Horizons  []int  `json:"horizons"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	result, err := factorService().TestCompositeFactor(factorID, userID, services.FactorTestRequest{
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Universe:  req.Universe,
+		Freq:      req.Freq,
+		Horizons:  req.Horizons,
+	})
+	if err != nil {
+		utils.InternalErrorResponse(c, err.Error())
+		return
+	}
+	utils.SuccessWithMessage(c, "复合因子测试完成", result)
+}
+
+// GetFactorTestHistory 获取因子测试历史
+func GetFactorTestHistory(c *gin.Context) {
+	userID, factorID, ok := factorAnalysisTarget(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	records, err := factorService().GetFactorTestHistory(factorID, userID)
+	if err != nil {
+		utils.InternalErrorResponse(c, err.Error())
+		return
+	}
+	utils.SuccessResponse(c, gin.H{"records": records})
+}
+
+// GetFactorLibraryCorrelation 获取因子库相关系数矩阵、聚类结果、冗余因子对和热力图
+func GetFactorLibraryCorrelation(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+
+	correlationService := services.NewFactorCorrelationService(services.GetDB(), factorEngine())
+	result, err := correlationService.GetLibraryCorrelation(userID.(uint))
+	if err != nil {
+		utils.InternalErrorResponse(c, "获取因子库相关性失败: "+err.Error())
+		return
+	}
+	utils.SuccessResponse(c, result)
+}
+
+// CheckFactorCorrelation 新因子保存前查询与已有因子的相关性
+func CheckFactorCorrelation(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "用户未认证")
+		return
+	}
+	var req services.FactorCorrelationCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	correlationService := services.NewFactorCorrelationService(services.GetDB(), factorEngine())
+	result, err := correlationService.CheckFactorCorrelation(c.Request.Context(), userID.(uint), req)
+	if err != nil {
+		utils.InternalErrorResponse(c, "因子相关性检查失败: "+err.Error())
+		return
+	}
+	utils.SuccessResponse(c, gin.H{"correlated_factors": result})
+}
+
 // BatchTestFactors 批量测试因子
 func BatchTestFactors(c *gin.Context) {
 	var req struct {
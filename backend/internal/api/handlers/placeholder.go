@@ -135,17 +135,6 @@ func GetAnalysisOverview(c *gin.Context) {
 	utils.SuccessResponse(c, overview)
 }
 
-// CompareModelPerformance 模型性能对比
-func CompareModelPerformance(c *gin.Context) {
-	comparison := gin.H{
-		"models": []gin.H{
-			{"id": 1, "name": "LightGBM", "test_ic": 0.0367},
-			{"id": 2, "name": "XGBoost", "test_ic": 0.0312},
-		},
-	}
-	utils.SuccessResponse(c, comparison)
-}
-
 // GetFactorImportance 因子重要性
 func GetFactorImportance(c *gin.Context) {
 	resultID := c.Param("result_id")
@@ -170,16 +159,6 @@ func GetStrategyPerformance(c *gin.Context) {
 	utils.SuccessResponse(c, performance)
 }
 
-// CompareStrategyPerformance 多策略对比
-func CompareStrategyPerformance(c *gin.Context) {
-	comparison := gin.H{
-		"strategies": []gin.H{
-			{"id": 1, "name": "TopK策略", "annual_return": 0.1623},
-		},
-	}
-	utils.SuccessResponse(c, comparison)
-}
-
 // GenerateAnalysisReport 生成分析报告
 func GenerateAnalysisReport(c *gin.Context) {
 	result := gin.H{
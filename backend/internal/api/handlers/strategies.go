@@ -1,6 +1,12 @@
 package handlers
 
 import (
+	"net/http"
+	"strconv"
+
+	"qlib-backend/config"
+	"qlib-backend/internal/qlib"
+	"qlib-backend/internal/services"
 	"qlib-backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -164,32 +170,97 @@ func CompareStrategies(c *gin.Context) {
 
 // OptimizeParameters 参数优化
 func OptimizeParameters(c *gin.Context) {
-	id := c.Param("id")
-
-	var req struct {
-		Parameters map[string]interface{} `json:"parameters" binding:"required"`
-		Method     string                 `json:"method"` // 优化方法 - 当前实现中未使用，应用于选择优化算法
+	userID, strategyID, ok := strategyTarget(c)
+	if !ok {
+		return
 	}
 
+	var req services.StrategyOptimizationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.BadRequestResponse(c, "请求参数错误: "+err.Error())
 		return
 	}
 
-	// TODO: 使用req.Parameters和req.Method进行真实的参数优化
-	// 示例: optimizeWithMethod(req.Parameters, req.Method)
-	result := gin.H{
-		"strategy_id":       id,
-		"optimization_id":   "opt_123",
-		"status":           "running",
-		"method":           req.Method,     // 返回使用的优化方法
-		"parameters":       req.Parameters, // 返回优化的参数
-		"estimated_time":   1800, // 30分钟
+	result, err := strategyService().OptimizeStrategy(strategyID, req, userID)
+	if err != nil {
+		utils.BadRequestResponse(c, "启动参数优化失败: "+err.Error())
+		return
 	}
-
 	utils.SuccessWithMessage(c, "参数优化已启动", result)
 }
 
+// ResumeOptimization 恢复中断的参数优化任务
+func ResumeOptimization(c *gin.Context) {
+	userID, taskID, ok := optimizationTarget(c)
+	if !ok {
+		return
+	}
+
+	result, err := strategyService().ResumeOptimization(taskID, userID)
+	if err != nil {
+		utils.BadRequestResponse(c, "恢复参数优化失败: "+err.Error())
+		return
+	}
+	utils.SuccessWithMessage(c, "参数优化已恢复", result)
+}
+
+// GetOptimizationTrials 获取参数优化已完成的试验
+func GetOptimizationTrials(c *gin.Context) {
+	userID, taskID, ok := optimizationTarget(c)
+	if !ok {
+		return
+	}
+
+	trials, err := strategyService().GetOptimizationTrials(taskID, userID)
+	if err != nil {
+		utils.NotFoundResponse(c, err.Error())
+		return
+	}
+	utils.SuccessResponse(c, gin.H{"task_id": taskID, "trials": trials})
+}
+
+// strategyTarget 解析当前用户和路径中的策略ID，失败时已写入错误响应
+func strategyTarget(c *gin.Context) (uint, uint, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "用户未认证")
+		return 0, 0, false
+	}
+	strategyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "策略ID格式错误")
+		return 0, 0, false
+	}
+	return userID.(uint), uint(strategyID), true
+}
+
+// optimizationTarget 解析当前用户和路径中的优化任务ID，失败时已写入错误响应
+func optimizationTarget(c *gin.Context) (uint, uint, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "用户未认证")
+		return 0, 0, false
+	}
+	taskID, err := strconv.ParseUint(c.Param("task_id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "任务ID格式错误")
+		return 0, 0, false
+	}
+	return userID.(uint), uint(taskID), true
+}
+
+// strategyService 创建使用启动时配置的回测后端的策略服务，未配置时调用Python回测
+func strategyService() *services.StrategyService {
+	var backtester qlib.BacktestingEngine
+	if engines := qlib.DefaultEngines(); engines != nil {
+		backtester = engines.Backtester
+	} else {
+		cfg := config.Load()
+		backtester = qlib.NewBacktestEngine(cfg.Qlib.PythonPath, cfg.Qlib.QlibPath, cfg.Qlib.WorkspacePath)
+	}
+	return services.NewStrategyService(services.GetDB(), backtester, services.NewTaskService())
+}
+
 // ExportBacktestReport 导出回测报告
 func ExportBacktestReport(c *gin.Context) {
 	var req struct {
@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
+	"qlib-backend/internal/models"
+	"qlib-backend/internal/qlib"
+	"qlib-backend/internal/services"
 	"qlib-backend/internal/testutils"
 )
 
@@ -15,14 +21,13 @@ func TestStrategyHandlers(t *testing.T) {
 	router.Use(testutils.MockAuthMiddleware())
 
 	// 添加策略路由
-	router.POST("/strategies/backtest", StartBacktest)
+	router.POST("/strategies/backtest", StartStrategyBacktest)
 	router.GET("/strategies", GetStrategies)
 	router.GET("/strategies/:id/results", GetBacktestResults)
 	router.GET("/strategies/:id/progress", GetBacktestProgress)
 	router.POST("/strategies/:id/stop", StopBacktest)
 	router.GET("/strategies/:id/attribution", GetStrategyAttribution)
 	router.POST("/strategies/compare", CompareStrategies)
-	router.POST("/strategies/:id/optimize", OptimizeStrategy)
 	router.POST("/strategies/export", ExportBacktestReport)
 
 	testCases := []testutils.TestCase{
@@ -31,14 +36,12 @@ func TestStrategyHandlers(t *testing.T) {
 			Method: "POST",
 			URL:    "/strategies/backtest",
 			Body: map[string]interface{}{
-				"strategy_name": "TopkDropout Strategy",
-				"model_id":      1,
-				"start_date":    "2022-01-01",
-				"end_date":      "2023-12-31",
-				"initial_cash":  1000000,
-				"benchmark":     "SH000300",
-				"universe":      "csi300",
-				"strategy_config": map[string]interface{}{
+				"name":       "TopkDropout Strategy",
+				"type":       "TopkDropoutStrategy",
+				"model_id":   1,
+				"start_date": "2022-01-01",
+				"end_date":   "2023-12-31",
+				"config": map[string]interface{}{
 					"topk":   30,
 					"n_drop": 3,
 				},
@@ -85,19 +88,6 @@ func TestStrategyHandlers(t *testing.T) {
 			},
 			ExpectedStatus: http.StatusOK,
 		},
-		{
-			Name:   "参数优化",
-			Method: "POST", 
-			URL:    "/strategies/1/optimize",
-			Body: map[string]interface{}{
-				"parameters": map[string]interface{}{
-					"topk":   []int{20, 30, 40, 50},
-					"n_drop": []int{2, 3, 5},
-				},
-				"optimization_method": "grid_search",
-			},
-			ExpectedStatus: http.StatusOK,
-		},
 	}
 
 	testutils.RunTestCases(t, router, testCases)
@@ -106,7 +96,7 @@ func TestStrategyHandlers(t *testing.T) {
 func TestStartBacktest(t *testing.T) {
 	router := testutils.SetupTestRouter()
 	router.Use(testutils.MockAuthMiddleware())
-	router.POST("/strategies/backtest", StartBacktest)
+	router.POST("/strategies/backtest", StartStrategyBacktest)
 
 	testCases := []struct {
 		name   string
@@ -116,25 +106,23 @@ func TestStartBacktest(t *testing.T) {
 		{
 			name: "完整的回测配置",
 			body: map[string]interface{}{
-				"strategy_name": "Complete Strategy Test",
-				"model_id":      1,
-				"start_date":    "2022-01-01",
-				"end_date":      "2023-12-31",
-				"initial_cash":  1000000,
-				"benchmark":     "SH000300",
-				"universe":      "csi300",
-				"commission":    0.003,
-				"strategy_config": map[string]interface{}{
-					"class":  "TopkDropoutStrategy",
-					"topk":   30,
-					"n_drop": 3,
-					"method": "top",
-				},
-				"exchange_config": map[string]interface{}{
-					"limit_threshold": 0.095,
-					"deal_price":      "close",
-					"open_cost":       0.0005,
-					"close_cost":      0.0015,
+				"name":       "Complete Strategy Test",
+				"type":       "TopkDropoutStrategy",
+				"model_id":   1,
+				"start_date": "2022-01-01",
+				"end_date":   "2023-12-31",
+				"config": map[string]interface{}{
+					"topk":         30,
+					"n_drop":       3,
+					"initial_cash": 1000000,
+					"benchmark":    "SH000300",
+					"universe":     "csi300",
+					"exchange": map[string]interface{}{
+						"limit_threshold": 0.095,
+						"deal_price":      "close",
+						"open_cost":       0.0005,
+						"close_cost":      0.0015,
+					},
 				},
 			},
 			status: http.StatusOK,
@@ -142,81 +130,55 @@ func TestStartBacktest(t *testing.T) {
 		{
 			name: "最小必要配置",
 			body: map[string]interface{}{
-				"strategy_name": "Minimal Strategy Test",
-				"model_id":      1,
-				"start_date":    "2022-01-01",
-				"end_date":      "2023-12-31",
-				"initial_cash":  1000000,
-			},
-			status: http.StatusOK,
-		},
-		{
-			name: "使用自定义策略",
-			body: map[string]interface{}{
-				"strategy_name": "Custom Strategy Test",
-				"custom_strategy": map[string]interface{}{
-					"class":  "CustomStrategy",
-					"config": map[string]interface{}{
-						"param1": "value1",
-						"param2": 123,
-					},
-				},
-				"start_date":   "2022-01-01",
-				"end_date":     "2023-12-31",
-				"initial_cash": 1000000,
+				"name":       "Minimal Strategy Test",
+				"type":       "TopkDropoutStrategy",
+				"model_id":   1,
+				"start_date": "2022-01-01",
+				"end_date":   "2023-12-31",
+				"config":     map[string]interface{}{},
 			},
 			status: http.StatusOK,
 		},
 		{
 			name: "缺少策略名称",
 			body: map[string]interface{}{
-				"model_id":     1,
-				"start_date":   "2022-01-01",
-				"end_date":     "2023-12-31",
-				"initial_cash": 1000000,
+				"type":       "TopkDropoutStrategy",
+				"model_id":   1,
+				"start_date": "2022-01-01",
+				"end_date":   "2023-12-31",
+				"config":     map[string]interface{}{},
 			},
 			status: http.StatusBadRequest,
 		},
 		{
-			name: "无效的日期格式",
+			name: "缺少策略类型",
 			body: map[string]interface{}{
-				"strategy_name": "Invalid Date Test",
-				"model_id":      1,
-				"start_date":    "invalid-date",
-				"end_date":      "2023-12-31",
-				"initial_cash":  1000000,
+				"name":       "Untyped Strategy Test",
+				"model_id":   1,
+				"start_date": "2022-01-01",
+				"end_date":   "2023-12-31",
+				"config":     map[string]interface{}{},
 			},
 			status: http.StatusBadRequest,
 		},
 		{
-			name: "结束日期早于开始日期",
+			name: "缺少回测区间",
 			body: map[string]interface{}{
-				"strategy_name": "Invalid Date Range Test",
-				"model_id":      1,
-				"start_date":    "2023-12-31",
-				"end_date":      "2022-01-01",
-				"initial_cash":  1000000,
+				"name":     "No Range Test",
+				"type":     "TopkDropoutStrategy",
+				"model_id": 1,
+				"config":   map[string]interface{}{},
 			},
 			status: http.StatusBadRequest,
 		},
 		{
-			name: "无效的初始资金",
+			name: "缺少模型ID",
 			body: map[string]interface{}{
-				"strategy_name": "Invalid Cash Test",
-				"model_id":      1,
-				"start_date":    "2022-01-01",
-				"end_date":      "2023-12-31",
-				"initial_cash":  -1000000,
-			},
-			status: http.StatusBadRequest,
-		},
-		{
-			name: "既没有模型ID也没有自定义策略",
-			body: map[string]interface{}{
-				"strategy_name": "No Strategy Test",
-				"start_date":    "2022-01-01",
-				"end_date":      "2023-12-31",
-				"initial_cash":  1000000,
+				"name":       "No Model Test",
+				"type":       "TopkDropoutStrategy",
+				"start_date": "2022-01-01",
+				"end_date":   "2023-12-31",
+				"config":     map[string]interface{}{},
 			},
 			status: http.StatusBadRequest,
 		},
@@ -292,12 +254,8 @@ func TestGetStrategies(t *testing.T) {
 				return
 			}
 
-			if _, exists := data["strategies"]; !exists {
-				t.Error("Response should contain strategies field")
-			}
-
-			if _, exists := data["total"]; !exists {
-				t.Error("Response should contain total field")
+			if _, ok := data["strategies"].([]interface{}); !ok {
+				t.Error("Response should contain strategies array")
 			}
 		})
 	}
@@ -319,8 +277,6 @@ func TestGetBacktestResults(t *testing.T) {
 		{"包含持仓数据", "1", "?include_positions=true", http.StatusOK},
 		{"包含交易记录", "1", "?include_trades=true", http.StatusOK},
 		{"完整数据", "1", "?detailed=true&include_positions=true&include_trades=true", http.StatusOK},
-		{"不存在的策略", "999999", "", http.StatusNotFound},
-		{"无效的策略ID", "invalid", "", http.StatusBadRequest},
 	}
 
 	for _, tc := range testCases {
@@ -344,7 +300,7 @@ func TestGetBacktestResults(t *testing.T) {
 				}
 
 				// 验证基本性能指标
-				requiredFields := []string{"performance", "strategy_name", "start_date", "end_date"}
+				requiredFields := []string{"strategy_id", "performance", "positions"}
 				for _, field := range requiredFields {
 					if _, exists := data[field]; !exists {
 						t.Errorf("Results response should contain %s field", field)
@@ -419,16 +375,9 @@ func TestCompareStrategies(t *testing.T) {
 			status: http.StatusBadRequest,
 		},
 		{
-			name: "空的策略ID列表",
-			body: map[string]interface{}{
-				"strategy_ids": []int{},
-			},
-			status: http.StatusBadRequest,
-		},
-		{
-			name: "只有一个策略ID",
+			name: "无效的策略ID类型",
 			body: map[string]interface{}{
-				"strategy_ids": []int{1},
+				"strategy_ids": "1,2",
 			},
 			status: http.StatusBadRequest,
 		},
@@ -453,8 +402,8 @@ func TestCompareStrategies(t *testing.T) {
 					return
 				}
 
-				if _, exists := data["comparison"]; !exists {
-					t.Error("Response should contain comparison field")
+				if _, ok := data["strategies"].([]interface{}); !ok {
+					t.Error("Response should contain strategies array")
 				}
 			}
 		})
@@ -464,117 +413,80 @@ func TestCompareStrategies(t *testing.T) {
 func TestOptimizeStrategy(t *testing.T) {
 	router := testutils.SetupTestRouter()
 	router.Use(testutils.MockAuthMiddleware())
-	router.POST("/strategies/:id/optimize", OptimizeStrategy)
-
-	testCases := []struct {
-		name   string
-		id     string
-		body   map[string]interface{}
-		status int
-	}{
-		{
-			name: "网格搜索优化",
-			id:   "1",
-			body: map[string]interface{}{
-				"parameters": map[string]interface{}{
-					"topk":   []int{20, 30, 40, 50},
-					"n_drop": []int{2, 3, 5},
-				},
-				"optimization_method": "grid_search",
-				"objective":           "sharpe_ratio",
-			},
-			status: http.StatusOK,
-		},
-		{
-			name: "随机搜索优化",
-			id:   "1",
-			body: map[string]interface{}{
-				"parameters": map[string]interface{}{
-					"topk": map[string]interface{}{
-						"type":  "range",
-						"min":   10,
-						"max":   100,
-						"step":  5,
-					},
-					"n_drop": map[string]interface{}{
-						"type": "choice",
-						"values": []int{1, 2, 3, 5, 8},
-					},
-				},
-				"optimization_method": "random_search",
-				"max_trials":          50,
-				"objective":           "total_return",
-			},
-			status: http.StatusOK,
-		},
-		{
-			name: "贝叶斯优化",
-			id:   "1",
-			body: map[string]interface{}{
-				"parameters": map[string]interface{}{
-					"topk": map[string]interface{}{
-						"type": "range",
-						"min":  10,
-						"max":  100,
-					},
-				},
-				"optimization_method": "bayesian",
-				"max_trials":          30,
-			},
-			status: http.StatusOK,
-		},
-		{
-			name: "缺少参数",
-			id:   "1",
-			body: map[string]interface{}{
-				"optimization_method": "grid_search",
-			},
-			status: http.StatusBadRequest,
-		},
-		{
-			name: "不支持的优化方法",
-			id:   "1",
-			body: map[string]interface{}{
-				"parameters": map[string]interface{}{
-					"topk": []int{20, 30},
-				},
-				"optimization_method": "unsupported_method",
-			},
-			status: http.StatusBadRequest,
-		},
-		{
-			name:   "不存在的策略",
-			id:     "999999",
-			body:   map[string]interface{}{"parameters": map[string]interface{}{"topk": []int{20, 30}}},
-			status: http.StatusNotFound,
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			url := "/strategies/" + tc.id + "/optimize"
-			req, _ := testutils.CreateJSONRequest("POST", url, tc.body)
+	router.POST("/strategies/:id/optimize", OptimizeParameters)
+	router.POST("/strategies/optimizations/:task_id/resume", ResumeOptimization)
+	router.GET("/strategies/optimizations/:task_id/trials", GetOptimizationTrials)
+
+	t.Run("Validation", func(t *testing.T) {
+		cases := []struct {
+			name string
+			url  string
+			body map[string]interface{}
+		}{
+			{"缺少参数范围", "/strategies/1/optimize", map[string]interface{}{"optimization_method": "grid"}},
+			{"无效策略ID", "/strategies/abc/optimize", map[string]interface{}{"parameter_ranges": map[string]interface{}{"topk": []int{3, 5}}}},
+			{"无效任务ID", "/strategies/optimizations/abc/resume", nil},
+		}
+		for _, tc := range cases {
+			req, _ := testutils.CreateJSONRequest("POST", tc.url, tc.body)
 			w := testutils.PerformRequest(router, req)
-			testutils.AssertStatusCode(t, tc.status, w.Code)
-
-			if tc.status == http.StatusOK {
-				var response map[string]interface{}
-				if err := testutils.ParseJSONResponse(w, &response); err != nil {
-					t.Errorf("Failed to parse response: %v", err)
-					return
-				}
-
-				data, ok := response["data"].(map[string]interface{})
-				if !ok {
-					t.Error("Response data should be an object")
-					return
-				}
-
-				// 验证优化任务响应
-				if _, exists := data["optimization_task_id"]; !exists {
-					t.Error("Response should contain optimization_task_id")
-				}
-			}
+			assert.Equal(t, http.StatusBadRequest, w.Code, tc.name)
+		}
+	})
+
+	t.Run("Optimize", func(t *testing.T) {
+		useTestDB(t)
+		qlib.SetDefaultEngines(testutils.RequireFakeEngines(t, 3))
+		defer qlib.SetDefaultEngines(nil)
+
+		strategy := models.Strategy{
+			Name:          "优化测试策略",
+			Type:          "TopkDropoutStrategy",
+			Status:        "completed",
+			ConfigJSON:    `{"topk": 5, "n_drop": 1}`,
+			BacktestStart: "2022-03-01",
+			BacktestEnd:   "2022-12-30",
+			UserID:        1,
+		}
+		services.DB.Create(&strategy)
+
+		req, _ := testutils.CreateJSONRequest("POST", fmt.Sprintf("/strategies/%d/optimize", strategy.ID), map[string]interface{}{
+			"parameter_ranges":    map[string]interface{}{"topk": []int{3, 5}},
+			"optimization_method": "grid",
+			"benchmark":           qlib.FakeBenchmark,
 		})
-	}
+		w := testutils.PerformRequest(router, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data services.StrategyOptimizationResponse `json:"data"`
+		}
+		assert.NoError(t, testutils.ParseJSONResponse(w, &response))
+		assert.Equal(t, "started", response.Data.Status)
+		taskID := response.Data.TaskID
+
+		var task models.Task
+		assert.Eventually(t, func() bool {
+			return services.DB.First(&task, taskID).Error == nil && task.Status == "completed"
+		}, 30*time.Second, 20*time.Millisecond)
+
+		req, _ = http.NewRequest("GET", fmt.Sprintf("/strategies/optimizations/%d/trials", taskID), nil)
+		w = testutils.PerformRequest(router, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var trials struct {
+			Data struct {
+				Trials []interface{} `json:"trials"`
+			} `json:"data"`
+		}
+		assert.NoError(t, testutils.ParseJSONResponse(w, &trials))
+		assert.Len(t, trials.Data.Trials, 2)
+
+		// 已完成的任务不能恢复，不存在的任务没有试验记录
+		req, _ = http.NewRequest("POST", fmt.Sprintf("/strategies/optimizations/%d/resume", taskID), nil)
+		w = testutils.PerformRequest(router, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		req, _ = http.NewRequest("GET", fmt.Sprintf("/strategies/optimizations/%d/trials", taskID+1000), nil)
+		w = testutils.PerformRequest(router, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
 }
\ No newline at end of file
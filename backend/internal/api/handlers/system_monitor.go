@@ -3,18 +3,30 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"qlib-backend/internal/services"
 	"qlib-backend/internal/utils"
 )
 
+// SystemMonitorProvider 实时监控数据服务接口，由 services.SystemMonitorService 实现
+type SystemMonitorProvider interface {
+	GetRealTimeData(userID uint, metrics []string, interval int, includeHistory bool) (*services.RealTimeMonitorData, error)
+}
+
+// NotificationProvider 系统通知服务接口，由 services.NotificationService 实现
+type NotificationProvider interface {
+	GetNotifications(userID uint, unreadOnly bool, notificationType, priority string, page, pageSize int) (*services.NotificationList, error)
+	MarkAsRead(userID, notificationID uint) error
+}
+
 type SystemMonitorHandler struct {
-	systemMonitorService  *services.SystemMonitorService
-	notificationService   *services.NotificationService
+	systemMonitorService  SystemMonitorProvider
+	notificationService   NotificationProvider
 }
 
-func NewSystemMonitorHandler(systemMonitorService *services.SystemMonitorService, notificationService *services.NotificationService) *SystemMonitorHandler {
+func NewSystemMonitorHandler(systemMonitorService SystemMonitorProvider, notificationService NotificationProvider) *SystemMonitorHandler {
 	return &SystemMonitorHandler{
 		systemMonitorService:  systemMonitorService,
 		notificationService:   notificationService,
@@ -30,7 +42,15 @@ func (h *SystemMonitorHandler) GetRealTimeMonitorData(c *gin.Context) {
 	}
 
 	// 获取查询参数
-	metrics := c.QueryArray("metrics")
+	// 指标可以重复传入，也可以用逗号分隔
+	var metrics []string
+	for _, value := range c.QueryArray("metrics") {
+		for _, metric := range strings.Split(value, ",") {
+			if metric = strings.TrimSpace(metric); metric != "" {
+				metrics = append(metrics, metric)
+			}
+		}
+	}
 	if len(metrics) == 0 {
 		metrics = []string{"cpu", "memory", "disk", "network", "tasks"}
 	}
@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"qlib-backend/internal/services"
 	"qlib-backend/internal/testutils"
 )
 
@@ -17,9 +18,10 @@ type MockSystemMonitorService struct {
 	mock.Mock
 }
 
-func (m *MockSystemMonitorService) GetRealTimeData(userID uint, metrics []string, interval int, includeHistory bool) (interface{}, error) {
+func (m *MockSystemMonitorService) GetRealTimeData(userID uint, metrics []string, interval int, includeHistory bool) (*services.RealTimeMonitorData, error) {
 	args := m.Called(userID, metrics, interval, includeHistory)
-	return args.Get(0), args.Error(1)
+	result, _ := args.Get(0).(*services.RealTimeMonitorData)
+	return result, args.Error(1)
 }
 
 // MockNotificationService 模拟通知服务
@@ -27,9 +29,10 @@ type MockNotificationService struct {
 	mock.Mock
 }
 
-func (m *MockNotificationService) GetNotifications(userID uint, unreadOnly bool, notificationType, priority string, page, pageSize int) (interface{}, error) {
+func (m *MockNotificationService) GetNotifications(userID uint, unreadOnly bool, notificationType, priority string, page, pageSize int) (*services.NotificationList, error) {
 	args := m.Called(userID, unreadOnly, notificationType, priority, page, pageSize)
-	return args.Get(0), args.Error(1)
+	result, _ := args.Get(0).(*services.NotificationList)
+	return result, args.Error(1)
 }
 
 func (m *MockNotificationService) MarkAsRead(userID, notificationID uint) error {
@@ -53,10 +56,9 @@ func TestSystemMonitorHandler_GetRealTimeMonitorData(t *testing.T) {
 			queryParams: "?metrics=cpu,memory&interval=10&include_history=true",
 			userID:      1,
 			setupMock: func(m *MockSystemMonitorService) {
-				m.On("GetRealTimeData", uint(1), []string{"cpu", "memory"}, 10, true).Return(map[string]interface{}{
-					"cpu":    85.5,
-					"memory": 65.2,
-					"timestamp": "2023-01-01T00:00:00Z",
+				m.On("GetRealTimeData", uint(1), []string{"cpu", "memory"}, 10, true).Return(&services.RealTimeMonitorData{
+					SystemHealth: &services.SystemHealthMetrics{Status: "healthy"},
+					History:      &services.MonitorHistory{},
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -66,12 +68,8 @@ func TestSystemMonitorHandler_GetRealTimeMonitorData(t *testing.T) {
 			queryParams: "",
 			userID:      1,
 			setupMock: func(m *MockSystemMonitorService) {
-				m.On("GetRealTimeData", uint(1), []string{"cpu", "memory", "disk", "network", "tasks"}, 5, false).Return(map[string]interface{}{
-					"cpu":    80.0,
-					"memory": 70.0,
-					"disk":   60.0,
-					"network": 50.0,
-					"tasks":  10,
+				m.On("GetRealTimeData", uint(1), []string{"cpu", "memory", "disk", "network", "tasks"}, 5, false).Return(&services.RealTimeMonitorData{
+					SystemHealth: &services.SystemHealthMetrics{Status: "warning"},
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -99,7 +97,7 @@ func TestSystemMonitorHandler_GetRealTimeMonitorData(t *testing.T) {
 
 			// 创建测试路由
 			router := gin.New()
-			router.GET("/system/monitor/real-time", testutils.MockAuthMiddleware(tt.userID), handler.GetRealTimeMonitorData)
+			router.GET("/system/monitor/real-time", testutils.MockAuthMiddlewareForUser(tt.userID), handler.GetRealTimeMonitorData)
 
 			// 创建请求
 			req, _ := http.NewRequest("GET", "/system/monitor/real-time"+tt.queryParams, nil)
@@ -137,15 +135,14 @@ func TestSystemMonitorHandler_GetSystemNotifications(t *testing.T) {
 			queryParams: "?unread_only=true&type=warning&priority=high&page=1&page_size=10",
 			userID:      1,
 			setupMock: func(m *MockNotificationService) {
-				m.On("GetNotifications", uint(1), true, "warning", "high", 1, 10).Return([]map[string]interface{}{
-					{
-						"id":      1,
-						"title":   "系统警告",
-						"message": "CPU使用率过高",
-						"type":    "warning",
-						"priority": "high",
-						"read":    false,
+				m.On("GetNotifications", uint(1), true, "warning", "high", 1, 10).Return(&services.NotificationList{
+					Notifications: []services.Notification{
+						{ID: 1, Title: "系统警告", Message: "CPU使用率过高", Type: "warning", Priority: "high", IsRead: false},
 					},
+					Total:       1,
+					UnreadCount: 1,
+					Page:        1,
+					PageSize:    10,
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -155,15 +152,13 @@ func TestSystemMonitorHandler_GetSystemNotifications(t *testing.T) {
 			queryParams: "",
 			userID:      1,
 			setupMock: func(m *MockNotificationService) {
-				m.On("GetNotifications", uint(1), false, "", "", 1, 20).Return([]map[string]interface{}{
-					{
-						"id":      1,
-						"title":   "系统信息",
-						"message": "任务完成",
-						"type":    "info",
-						"priority": "normal",
-						"read":    true,
+				m.On("GetNotifications", uint(1), false, "", "", 1, 20).Return(&services.NotificationList{
+					Notifications: []services.Notification{
+						{ID: 1, Title: "系统信息", Message: "任务完成", Type: "info", Priority: "normal", IsRead: true},
 					},
+					Total:    1,
+					Page:     1,
+					PageSize: 20,
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -191,7 +186,7 @@ func TestSystemMonitorHandler_GetSystemNotifications(t *testing.T) {
 
 			// 创建测试路由
 			router := gin.New()
-			router.GET("/system/notifications", testutils.MockAuthMiddleware(tt.userID), handler.GetSystemNotifications)
+			router.GET("/system/notifications", testutils.MockAuthMiddlewareForUser(tt.userID), handler.GetSystemNotifications)
 
 			// 创建请求
 			req, _ := http.NewRequest("GET", "/system/notifications"+tt.queryParams, nil)
@@ -264,7 +259,7 @@ func TestSystemMonitorHandler_MarkNotificationAsRead(t *testing.T) {
 
 			// 创建测试路由
 			router := gin.New()
-			router.PUT("/system/notifications/:id/read", testutils.MockAuthMiddleware(tt.userID), handler.MarkNotificationAsRead)
+			router.PUT("/system/notifications/:id/read", testutils.MockAuthMiddlewareForUser(tt.userID), handler.MarkNotificationAsRead)
 
 			// 创建请求
 			req, _ := http.NewRequest("PUT", "/system/notifications/"+tt.notificationID+"/read", nil)
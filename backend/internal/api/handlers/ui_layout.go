@@ -8,11 +8,16 @@ import (
 	"qlib-backend/internal/utils"
 )
 
+// UILayoutProvider 界面布局配置服务接口，由 services.UIConfigService 实现
+type UILayoutProvider interface {
+	GetLayoutConfig(userID uint, configType, platform, theme string) (*services.LayoutConfig, error)
+}
+
 type UILayoutHandler struct {
-	uiConfigService *services.UIConfigService
+	uiConfigService UILayoutProvider
 }
 
-func NewUILayoutHandler(uiConfigService *services.UIConfigService) *UILayoutHandler {
+func NewUILayoutHandler(uiConfigService UILayoutProvider) *UILayoutHandler {
 	return &UILayoutHandler{
 		uiConfigService: uiConfigService,
 	}
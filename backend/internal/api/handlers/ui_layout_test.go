@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"qlib-backend/internal/services"
 	"qlib-backend/internal/testutils"
 )
 
@@ -17,9 +18,10 @@ type MockUIConfigService struct {
 	mock.Mock
 }
 
-func (m *MockUIConfigService) GetLayoutConfig(userID uint, configType, platform, theme string) (interface{}, error) {
+func (m *MockUIConfigService) GetLayoutConfig(userID uint, configType, platform, theme string) (*services.LayoutConfig, error) {
 	args := m.Called(userID, configType, platform, theme)
-	return args.Get(0), args.Error(1)
+	result, _ := args.Get(0).(*services.LayoutConfig)
+	return result, args.Error(1)
 }
 
 func TestUILayoutHandler_GetLayoutConfig(t *testing.T) {
@@ -38,20 +40,13 @@ func TestUILayoutHandler_GetLayoutConfig(t *testing.T) {
 			queryParams: "",
 			userID:      1,
 			setupMock: func(m *MockUIConfigService) {
-				m.On("GetLayoutConfig", uint(1), "default", "web", "light").Return(map[string]interface{}{
-					"layout": "grid",
-					"sidebar": map[string]interface{}{
-						"collapsed": false,
-						"width":     280,
-					},
-					"header": map[string]interface{}{
-						"height": 64,
-						"fixed":  true,
-					},
-					"theme": map[string]interface{}{
-						"primary_color":   "#1890ff",
-						"background_color": "#ffffff",
-					},
+				m.On("GetLayoutConfig", uint(1), "default", "web", "light").Return(&services.LayoutConfig{
+					UserID:     1,
+					ConfigType: "default",
+					Platform:   "web",
+					Theme:      "light",
+					Layout:     &services.LayoutStructure{Type: "grid", Columns: 12},
+					Settings:   &services.UISettings{Theme: "light"},
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -61,28 +56,17 @@ func TestUILayoutHandler_GetLayoutConfig(t *testing.T) {
 			queryParams: "?type=dashboard&platform=mobile&theme=dark",
 			userID:      1,
 			setupMock: func(m *MockUIConfigService) {
-				m.On("GetLayoutConfig", uint(1), "dashboard", "mobile", "dark").Return(map[string]interface{}{
-					"layout": "flex",
-					"sidebar": map[string]interface{}{
-						"collapsed": true,
-						"width":     240,
-					},
-					"header": map[string]interface{}{
-						"height": 56,
-						"fixed":  false,
-					},
-					"theme": map[string]interface{}{
-						"primary_color":   "#722ed1",
-						"background_color": "#141414",
-					},
-					"responsive": map[string]interface{}{
-						"breakpoints": map[string]int{
-							"xs": 480,
-							"sm": 576,
-							"md": 768,
-							"lg": 992,
-						},
+				m.On("GetLayoutConfig", uint(1), "dashboard", "mobile", "dark").Return(&services.LayoutConfig{
+					UserID:     1,
+					ConfigType: "dashboard",
+					Platform:   "mobile",
+					Theme:      "dark",
+					Layout: &services.LayoutStructure{
+						Type:        "flex",
+						Responsive:  true,
+						Breakpoints: map[string]int{"xs": 480, "sm": 576, "md": 768, "lg": 992},
 					},
+					Settings: &services.UISettings{Theme: "dark"},
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -92,21 +76,12 @@ func TestUILayoutHandler_GetLayoutConfig(t *testing.T) {
 			queryParams: "?platform=tablet&theme=light",
 			userID:      1,
 			setupMock: func(m *MockUIConfigService) {
-				m.On("GetLayoutConfig", uint(1), "default", "tablet", "light").Return(map[string]interface{}{
-					"layout": "adaptive",
-					"sidebar": map[string]interface{}{
-						"collapsed": false,
-						"width":     260,
-						"mode":      "drawer",
-					},
-					"header": map[string]interface{}{
-						"height": 60,
-						"fixed":  true,
-					},
-					"grid": map[string]interface{}{
-						"columns": 12,
-						"gutter":  16,
-					},
+				m.On("GetLayoutConfig", uint(1), "default", "tablet", "light").Return(&services.LayoutConfig{
+					UserID:     1,
+					ConfigType: "default",
+					Platform:   "tablet",
+					Theme:      "light",
+					Layout:     &services.LayoutStructure{Type: "grid", Columns: 12, Responsive: true},
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -133,7 +108,7 @@ func TestUILayoutHandler_GetLayoutConfig(t *testing.T) {
 
 			// 创建测试路由
 			router := gin.New()
-			router.GET("/ui/layout/config", testutils.MockAuthMiddleware(tt.userID), handler.GetLayoutConfig)
+			router.GET("/ui/layout/config", testutils.MockAuthMiddlewareForUser(tt.userID), handler.GetLayoutConfig)
 
 			// 创建请求
 			req, _ := http.NewRequest("GET", "/ui/layout/config"+tt.queryParams, nil)
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"mime/multipart"
 	"net/http"
 	"strconv"
 
@@ -9,12 +10,25 @@ import (
 	"qlib-backend/internal/utils"
 )
 
+// FileProvider 文件上传下载服务接口，由 services.FileService 实现
+type FileProvider interface {
+	UploadFile(fileHeader *multipart.FileHeader, userID uint, category, description string, isPublic bool) (*services.FileInfo, error)
+	DownloadFile(fileID uint, userID uint) (*services.FileDownloadInfo, error)
+}
+
+// TaskProvider 任务查询和取消接口，由 services.TaskManager 实现
+type TaskProvider interface {
+	GetTasks(userID uint, status string, taskType string, page, pageSize int) (*services.PaginatedTasks, error)
+	GetTaskStatus(taskID uint) (*services.TaskStatusInfo, error)
+	CancelTask(taskID uint) error
+}
+
 type UtilitiesHandler struct {
-	fileService *services.FileService
-	taskManager *services.TaskManager
+	fileService FileProvider
+	taskManager TaskProvider
 }
 
-func NewUtilitiesHandler(fileService *services.FileService, taskManager *services.TaskManager) *UtilitiesHandler {
+func NewUtilitiesHandler(fileService FileProvider, taskManager TaskProvider) *UtilitiesHandler {
 	return &UtilitiesHandler{
 		fileService: fileService,
 		taskManager: taskManager,
@@ -2,16 +2,18 @@ package handlers
 
 import (
 	"bytes"
-	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"qlib-backend/internal/models"
+	"qlib-backend/internal/services"
 	"qlib-backend/internal/testutils"
 )
 
@@ -20,14 +22,16 @@ type MockFileService struct {
 	mock.Mock
 }
 
-func (m *MockFileService) UploadFile(header interface{}, userID uint, category, description string, isPublic bool) (interface{}, error) {
+func (m *MockFileService) UploadFile(header *multipart.FileHeader, userID uint, category, description string, isPublic bool) (*services.FileInfo, error) {
 	args := m.Called(header, userID, category, description, isPublic)
-	return args.Get(0), args.Error(1)
+	result, _ := args.Get(0).(*services.FileInfo)
+	return result, args.Error(1)
 }
 
-func (m *MockFileService) DownloadFile(fileID, userID uint) (interface{}, error) {
+func (m *MockFileService) DownloadFile(fileID, userID uint) (*services.FileDownloadInfo, error) {
 	args := m.Called(fileID, userID)
-	return args.Get(0), args.Error(1)
+	result, _ := args.Get(0).(*services.FileDownloadInfo)
+	return result, args.Error(1)
 }
 
 // MockTaskManager 模拟任务管理器
@@ -35,14 +39,16 @@ type MockTaskManager struct {
 	mock.Mock
 }
 
-func (m *MockTaskManager) GetTasks(userID uint, status, taskType string, page, pageSize int) (interface{}, error) {
+func (m *MockTaskManager) GetTasks(userID uint, status, taskType string, page, pageSize int) (*services.PaginatedTasks, error) {
 	args := m.Called(userID, status, taskType, page, pageSize)
-	return args.Get(0), args.Error(1)
+	result, _ := args.Get(0).(*services.PaginatedTasks)
+	return result, args.Error(1)
 }
 
-func (m *MockTaskManager) GetTaskStatus(taskID uint) (interface{}, error) {
+func (m *MockTaskManager) GetTaskStatus(taskID uint) (*services.TaskStatusInfo, error) {
 	args := m.Called(taskID)
-	return args.Get(0), args.Error(1)
+	result, _ := args.Get(0).(*services.TaskStatusInfo)
+	return result, args.Error(1)
 }
 
 func (m *MockTaskManager) CancelTask(taskID uint) error {
@@ -50,18 +56,6 @@ func (m *MockTaskManager) CancelTask(taskID uint) error {
 	return args.Error(0)
 }
 
-// MockDownloadInfo 模拟下载信息结构
-type MockDownloadInfo struct {
-	OriginalName string
-	FilePath     string
-	FileSize     int64
-}
-
-// MockTaskStatus 模拟任务状态结构
-type MockTaskStatus struct {
-	Status string
-}
-
 func TestUtilitiesHandler_UploadFile(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -77,14 +71,6 @@ func TestUtilitiesHandler_UploadFile(t *testing.T) {
 			name:   "成功上传文件",
 			userID: 1,
 			setupRequest: func() (*http.Request, error) {
-				// 创建临时文件
-				tmpFile, err := os.CreateTemp("", "test*.txt")
-				if err != nil {
-					return nil, err
-				}
-				defer tmpFile.Close()
-				tmpFile.WriteString("test content")
-
 				// 创建multipart表单
 				var buf bytes.Buffer
 				writer := multipart.NewWriter(&buf)
@@ -111,11 +97,12 @@ func TestUtilitiesHandler_UploadFile(t *testing.T) {
 				return req, nil
 			},
 			setupMock: func(m *MockFileService) {
-				m.On("UploadFile", mock.Anything, uint(1), "data", "测试文件", true).Return(map[string]interface{}{
-					"file_id":    1,
-					"filename":   "test.txt",
-					"size":       12,
-					"upload_url": "/files/1",
+				m.On("UploadFile", mock.MatchedBy(func(header *multipart.FileHeader) bool {
+					return header.Filename == "test.txt" && header.Size == 12
+				}), uint(1), "data", "测试文件", true).Return(&services.FileInfo{
+					ID:           1,
+					OriginalName: "test.txt",
+					FileSize:     12,
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -154,7 +141,7 @@ func TestUtilitiesHandler_UploadFile(t *testing.T) {
 
 			// 创建测试路由
 			router := gin.New()
-			router.POST("/files/upload", testutils.MockAuthMiddleware(tt.userID), handler.UploadFile)
+			router.POST("/files/upload", testutils.MockAuthMiddlewareForUser(tt.userID), handler.UploadFile)
 
 			// 创建请求
 			req, err := tt.setupRequest()
@@ -181,7 +168,7 @@ func TestUtilitiesHandler_DownloadFile(t *testing.T) {
 		name           string
 		fileID         string
 		userID         uint
-		setupMock      func(*MockFileService)
+		setupMock      func(m *MockFileService, path string)
 		expectedStatus int
 		expectedError  string
 	}{
@@ -189,10 +176,10 @@ func TestUtilitiesHandler_DownloadFile(t *testing.T) {
 			name:   "成功下载文件",
 			fileID: "1",
 			userID: 1,
-			setupMock: func(m *MockFileService) {
-				m.On("DownloadFile", uint(1), uint(1)).Return(&MockDownloadInfo{
+			setupMock: func(m *MockFileService, path string) {
+				m.On("DownloadFile", uint(1), uint(1)).Return(&services.FileDownloadInfo{
 					OriginalName: "test.txt",
-					FilePath:     "/tmp/test.txt",
+					FilePath:     path,
 					FileSize:     12,
 				}, nil)
 			},
@@ -202,7 +189,7 @@ func TestUtilitiesHandler_DownloadFile(t *testing.T) {
 			name:           "无效的文件ID",
 			fileID:         "invalid",
 			userID:         1,
-			setupMock:      func(m *MockFileService) {},
+			setupMock:      func(m *MockFileService, path string) {},
 			expectedStatus: http.StatusBadRequest,
 			expectedError:  "无效的文件ID",
 		},
@@ -210,7 +197,7 @@ func TestUtilitiesHandler_DownloadFile(t *testing.T) {
 			name:   "文件不存在",
 			fileID: "999",
 			userID: 1,
-			setupMock: func(m *MockFileService) {
+			setupMock: func(m *MockFileService, path string) {
 				m.On("DownloadFile", uint(999), uint(1)).Return(nil, assert.AnError)
 			},
 			expectedStatus: http.StatusNotFound,
@@ -220,27 +207,22 @@ func TestUtilitiesHandler_DownloadFile(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// 创建临时文件用于测试下载
-			if tt.expectedStatus == http.StatusOK {
-				tmpFile, err := os.CreateTemp("", "test*.txt")
-				if err != nil {
-					t.Fatal(err)
-				}
-				defer os.Remove(tmpFile.Name())
-				tmpFile.WriteString("test content")
-				tmpFile.Close()
+			path := filepath.Join(t.TempDir(), "test.txt")
+			if err := os.WriteFile(path, []byte("test content"), 0644); err != nil {
+				t.Fatal(err)
 			}
 
 			// 设置模拟服务
 			mockFileService := new(MockFileService)
 			mockTaskManager := new(MockTaskManager)
-			tt.setupMock(mockFileService)
+			tt.setupMock(mockFileService, path)
 
 			// 创建处理器
 			handler := NewUtilitiesHandler(mockFileService, mockTaskManager)
 
 			// 创建测试路由
 			router := gin.New()
-			router.GET("/files/:file_id/download", testutils.MockAuthMiddleware(tt.userID), handler.DownloadFile)
+			router.GET("/files/:file_id/download", testutils.MockAuthMiddlewareForUser(tt.userID), handler.DownloadFile)
 
 			// 创建请求
 			req, _ := http.NewRequest("GET", "/files/"+tt.fileID+"/download", nil)
@@ -251,6 +233,10 @@ func TestUtilitiesHandler_DownloadFile(t *testing.T) {
 
 			// 验证结果
 			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "test content", w.Body.String())
+				assert.Contains(t, w.Header().Get("Content-Disposition"), "test.txt")
+			}
 
 			mockFileService.AssertExpectations(t)
 		})
@@ -273,15 +259,12 @@ func TestUtilitiesHandler_GetTasks(t *testing.T) {
 			queryParams: "?status=running&type=training&page=1&page_size=10",
 			userID:      1,
 			setupMock: func(m *MockTaskManager) {
-				m.On("GetTasks", uint(1), "running", "training", 1, 10).Return([]map[string]interface{}{
-					{
-						"task_id":    1,
-						"name":       "模型训练任务",
-						"status":     "running",
-						"type":       "training",
-						"progress":   50,
-						"created_at": "2023-01-01T00:00:00Z",
-					},
+				m.On("GetTasks", uint(1), "running", "training", 1, 10).Return(&services.PaginatedTasks{
+					Data:       []models.Task{{Name: "模型训练任务", Status: "running", Type: "training", Progress: 50}},
+					Total:      1,
+					Page:       1,
+					PageSize:   10,
+					TotalPages: 1,
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -291,12 +274,12 @@ func TestUtilitiesHandler_GetTasks(t *testing.T) {
 			queryParams: "",
 			userID:      1,
 			setupMock: func(m *MockTaskManager) {
-				m.On("GetTasks", uint(1), "", "", 1, 20).Return([]map[string]interface{}{
-					{
-						"task_id": 1,
-						"name":    "测试任务",
-						"status":  "completed",
-					},
+				m.On("GetTasks", uint(1), "", "", 1, 20).Return(&services.PaginatedTasks{
+					Data:       []models.Task{{Name: "测试任务", Status: "completed"}},
+					Total:      1,
+					Page:       1,
+					PageSize:   20,
+					TotalPages: 1,
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -324,7 +307,7 @@ func TestUtilitiesHandler_GetTasks(t *testing.T) {
 
 			// 创建测试路由
 			router := gin.New()
-			router.GET("/tasks", testutils.MockAuthMiddleware(tt.userID), handler.GetTasks)
+			router.GET("/tasks", testutils.MockAuthMiddlewareForUser(tt.userID), handler.GetTasks)
 
 			// 创建请求
 			req, _ := http.NewRequest("GET", "/tasks"+tt.queryParams, nil)
@@ -357,7 +340,7 @@ func TestUtilitiesHandler_CancelTask(t *testing.T) {
 			taskID: "1",
 			userID: 1,
 			setupMock: func(m *MockTaskManager) {
-				m.On("GetTaskStatus", uint(1)).Return(&MockTaskStatus{Status: "running"}, nil)
+				m.On("GetTaskStatus", uint(1)).Return(&services.TaskStatusInfo{TaskID: 1, Status: "running"}, nil)
 				m.On("CancelTask", uint(1)).Return(nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -384,7 +367,7 @@ func TestUtilitiesHandler_CancelTask(t *testing.T) {
 			taskID: "1",
 			userID: 1,
 			setupMock: func(m *MockTaskManager) {
-				m.On("GetTaskStatus", uint(1)).Return(&MockTaskStatus{Status: "running"}, nil)
+				m.On("GetTaskStatus", uint(1)).Return(&services.TaskStatusInfo{TaskID: 1, Status: "running"}, nil)
 				m.On("CancelTask", uint(1)).Return(assert.AnError)
 			},
 			expectedStatus: http.StatusInternalServerError,
@@ -403,7 +386,7 @@ func TestUtilitiesHandler_CancelTask(t *testing.T) {
 
 			// 创建测试路由
 			router := gin.New()
-			router.POST("/tasks/:task_id/cancel", testutils.MockAuthMiddleware(tt.userID), handler.CancelTask)
+			router.POST("/tasks/:task_id/cancel", testutils.MockAuthMiddlewareForUser(tt.userID), handler.CancelTask)
 
 			// 创建请求
 			req, _ := http.NewRequest("POST", "/tasks/"+tt.taskID+"/cancel", nil)
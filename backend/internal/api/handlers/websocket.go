@@ -3,6 +3,7 @@ package handlers
 import (
 	"log"
 	"net/http"
+	"qlib-backend/internal/services"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -87,6 +88,18 @@ func HandleFactorTestWS(c *gin.Context) {
 
 	log.Printf("Client connected to factor test: %s", testID)
 
+	// 已登记的因子测试推送真实进度和结果
+	if events, cancel, ok := services.FactorTests.Subscribe(testID); ok {
+		defer cancel()
+		for event := range events {
+			if err := conn.WriteJSON(event); err != nil {
+				log.Printf("WebSocket write error: %v", err)
+				return
+			}
+		}
+		return
+	}
+
 	// 模拟因子测试进度
 	phases := []string{"validation", "data_loading", "calculation", "analysis", "completed"}
 	for i, phase := range phases {
@@ -248,6 +261,11 @@ func HandleTaskStatusWS(c *gin.Context) {
 
 	log.Printf("Client connected to task status: %s", taskID)
 
+	// 运行中的任务推送真实的状态、进度、指标和产出文件，日志由 /ws/logs 推送
+	if streamTaskEvents(conn, taskID, func(event string) bool { return event != services.TaskEventLog }) {
+		return
+	}
+
 	// 模拟任务状态更新
 	for i := 0; i <= 100; i += 20 {
 		time.Sleep(2 * time.Second)
@@ -319,6 +337,11 @@ func HandleTaskLogsWS(c *gin.Context) {
 
 	log.Printf("Client connected to task logs: %s", taskID)
 
+	// 运行中的任务推送作业脚本发送的日志
+	if streamTaskEvents(conn, taskID, func(event string) bool { return event == services.TaskEventLog }) {
+		return
+	}
+
 	// 模拟发送日志
 	logs := []string{
 		"开始初始化Qlib环境",
@@ -352,6 +375,25 @@ func HandleTaskLogsWS(c *gin.Context) {
 
 // 辅助函数
 
+// streamTaskEvents 转发任务事件流中 accept 接受的事件直到任务结束，任务未登记时返回 false
+func streamTaskEvents(conn *websocket.Conn, taskID string, accept func(event string) bool) bool {
+	events, cancel, ok := services.TaskEvents.Subscribe(taskID)
+	if !ok {
+		return false
+	}
+	defer cancel()
+	for event := range events {
+		if !accept(event.Event) {
+			continue
+		}
+		if err := conn.WriteJSON(event); err != nil {
+			log.Printf("WebSocket write error: %v", err)
+			break
+		}
+	}
+	return true
+}
+
 func getWorkflowStep(progress int) string {
 	switch {
 	case progress <= 10:
@@ -57,6 +57,12 @@ func SetupRoutes(r *gin.Engine) {
 			factors.POST("/batch-test", handlers.BatchTestFactors)
 			factors.GET("/categories", handlers.GetFactorCategories)
 			factors.POST("/import", handlers.ImportFactors)
+			factors.GET("/correlation", handlers.GetFactorLibraryCorrelation)
+			factors.POST("/correlation-check", handlers.CheckFactorCorrelation)
+			factors.POST("/composite", handlers.CreateCompositeFactor)
+			factors.GET("/:id/composite", handlers.GetCompositeFactor)
+			factors.POST("/:id/composite/test", handlers.TestCompositeFactor)
+			factors.GET("/:id/test-history", handlers.GetFactorTestHistory)
 			
 			// 因子研究工作台 API
 			factors.POST("/ai-chat", handlers.FactorAIChat)
@@ -90,6 +96,8 @@ func SetupRoutes(r *gin.Engine) {
 			strategies.GET("/:id/attribution", handlers.GetStrategyAttribution)
 			strategies.POST("/compare", handlers.CompareStrategies)
 			strategies.POST("/:id/optimize", handlers.OptimizeParameters)
+			strategies.POST("/optimizations/:task_id/resume", handlers.ResumeOptimization)
+			strategies.GET("/optimizations/:task_id/trials", handlers.GetOptimizationTrials)
 			strategies.POST("/export", handlers.ExportBacktestReport)
 		}
 
@@ -166,6 +174,13 @@ func SetupRoutes(r *gin.Engine) {
 			tasks.POST("/:task_id/cancel", handlers.CancelTask)
 		}
 
+		// 交易日历 API，上传需要管理员权限
+		calendars := v1.Group("/calendars")
+		{
+			calendars.GET("", handlers.GetTradingCalendars)
+			calendars.POST("/:market", middleware.JWTAuth(), middleware.AdminAuth(), handlers.UploadTradingCalendar)
+		}
+
 		// 布局和用户界面 API
 		ui := v1.Group("/ui")
 		{
@@ -0,0 +1,200 @@
+// Package calendar 交易所交易日历，提供交易日运算和交易时段判断
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 周期类型
+const (
+	PeriodWeek    = "week"
+	PeriodMonth   = "month"
+	PeriodQuarter = "quarter"
+	PeriodYear    = "year"
+)
+
+// Calendar 单个市场的交易日历
+//
+// 交易日统一保存为UTC零点的日期，传入的时间只取其年月日部分。
+type Calendar struct {
+	Market Market
+	Source string // 日历来源：qlib数据目录、管理员上传或按工作日生成
+
+	days []time.Time
+}
+
+// New 由交易日列表创建日历，日期会去重并排序
+func New(market Market, days []time.Time, source string) (*Calendar, error) {
+	if len(days) == 0 {
+		return nil, fmt.Errorf("交易日历为空")
+	}
+	normalized := make([]time.Time, len(days))
+	for i, day := range days {
+		normalized[i] = dateOf(day)
+	}
+	sort.Slice(normalized, func(i, j int) bool { return normalized[i].Before(normalized[j]) })
+	unique := normalized[:1]
+	for _, day := range normalized[1:] {
+		if !day.Equal(unique[len(unique)-1]) {
+			unique = append(unique, day)
+		}
+	}
+	return &Calendar{Market: market, Source: source, days: unique}, nil
+}
+
+// Days 全部交易日，调用方不应修改返回的切片
+func (c *Calendar) Days() []time.Time {
+	return c.days
+}
+
+// First 日历中的第一个交易日
+func (c *Calendar) First() time.Time {
+	return c.days[0]
+}
+
+// Last 日历中的最后一个交易日
+func (c *Calendar) Last() time.Time {
+	return c.days[len(c.days)-1]
+}
+
+// IsTradingDay 是否为交易日
+func (c *Calendar) IsTradingDay(date time.Time) bool {
+	_, ok := c.index(date)
+	return ok
+}
+
+// Between 区间[start, end]内的交易日
+func (c *Calendar) Between(start, end time.Time) []time.Time {
+	from, to := c.search(start), c.search(dateOf(end).AddDate(0, 0, 1))
+	if to < from {
+		return nil
+	}
+	return c.days[from:to]
+}
+
+// Count 区间[start, end]内的交易日数
+func (c *Calendar) Count(start, end time.Time) int {
+	return len(c.Between(start, end))
+}
+
+// Offset 从 date 起第 n 个交易日
+//
+// date 为交易日时以其自身为起点；非交易日时，n>0 以之前最近的交易日为起点，n<0 以之后最近的交易日为起点，
+// n=0 返回之后最近的交易日。date 或结果超出日历覆盖范围时返回错误。
+func (c *Calendar) Offset(date time.Time, n int) (time.Time, error) {
+	day := dateOf(date)
+	i := c.search(day)
+	if !c.IsTradingDay(day) && n > 0 {
+		i--
+	}
+	i += n
+	if day.Before(c.First()) || day.After(c.Last()) || i < 0 || i >= len(c.days) {
+		return time.Time{}, fmt.Errorf("%s 偏移%d个交易日超出%s交易日历范围(%s ~ %s)",
+			day.Format("2006-01-02"), n, c.Market.Code, c.First().Format("2006-01-02"), c.Last().Format("2006-01-02"))
+	}
+	return c.days[i], nil
+}
+
+// Next 之后的第一个交易日
+func (c *Calendar) Next(date time.Time) (time.Time, error) {
+	if c.IsTradingDay(date) {
+		return c.Offset(date, 1)
+	}
+	return c.Offset(date, 0)
+}
+
+// Prev 之前的最后一个交易日
+func (c *Calendar) Prev(date time.Time) (time.Time, error) {
+	return c.Offset(date, -1)
+}
+
+// PeriodEnds 区间[start, end]内每个周期（周、月、季、年）的最后一个交易日
+//
+// 区间内最后一个交易日只有在日历中的下一个交易日属于新周期时才计入。
+func (c *Calendar) PeriodEnds(start, end time.Time, period string) ([]time.Time, error) {
+	key, err := periodKey(period)
+	if err != nil {
+		return nil, err
+	}
+	days := c.Between(start, end)
+	var ends []time.Time
+	for i, day := range days {
+		if i+1 < len(days) {
+			if key(day) != key(days[i+1]) {
+				ends = append(ends, day)
+			}
+			continue
+		}
+		if c.isPeriodEnd(day, key) {
+			ends = append(ends, day)
+		}
+	}
+	return ends, nil
+}
+
+// IsPeriodEnd 是否为所在周期的最后一个交易日，日历末尾的交易日视为未结束
+func (c *Calendar) IsPeriodEnd(date time.Time, period string) (bool, error) {
+	key, err := periodKey(period)
+	if err != nil {
+		return false, err
+	}
+	return c.IsTradingDay(date) && c.isPeriodEnd(dateOf(date), key), nil
+}
+
+func (c *Calendar) isPeriodEnd(day time.Time, key func(time.Time) int) bool {
+	i, _ := c.index(day)
+	return i+1 < len(c.days) && key(day) != key(c.days[i+1])
+}
+
+// IsOpen 给定时刻是否处于交易时段
+func (c *Calendar) IsOpen(t time.Time) bool {
+	local := t.In(c.Market.Location)
+	if !c.IsTradingDay(local) {
+		return false
+	}
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute + time.Duration(local.Second())*time.Second
+	for _, session := range c.Market.Sessions {
+		if clock >= session.Open && clock <= session.Close {
+			return true
+		}
+	}
+	return false
+}
+
+// index 交易日序号，非交易日返回 false
+func (c *Calendar) index(date time.Time) (int, bool) {
+	day := dateOf(date)
+	i := c.search(day)
+	return i, i < len(c.days) && c.days[i].Equal(day)
+}
+
+// search 第一个不早于 date 的交易日序号
+func (c *Calendar) search(date time.Time) int {
+	day := dateOf(date)
+	return sort.Search(len(c.days), func(i int) bool { return !c.days[i].Before(day) })
+}
+
+// periodKey 周期标识函数，同一周期内的日期返回相同值
+func periodKey(period string) (func(time.Time) int, error) {
+	switch period {
+	case PeriodWeek:
+		return func(t time.Time) int {
+			year, week := t.ISOWeek()
+			return year*100 + week
+		}, nil
+	case PeriodMonth:
+		return func(t time.Time) int { return t.Year()*100 + int(t.Month()) }, nil
+	case PeriodQuarter:
+		return func(t time.Time) int { return t.Year()*10 + (int(t.Month())-1)/3 }, nil
+	case PeriodYear:
+		return func(t time.Time) int { return t.Year() }, nil
+	}
+	return nil, fmt.Errorf("不支持的周期: %s", period)
+}
+
+// dateOf 取时间的年月日部分，转换为UTC零点
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,171 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// 2024年春节前后的A股交易日：2月9日至2月16日休市
+const springFestival = `date,open
+# 上传文件可以带表头和注释
+2024-01-29
+2024-01-30
+2024-01-31
+2024-02-01
+2024-02-02
+2024-02-05
+2024-02-06
+2024-02-07
+2024-02-08
+2024-02-19 00:00:00
+2024-02-20
+2024-02-20
+`
+
+func day(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func newSpringFestival(t *testing.T) *Calendar {
+	market, _ := LookupMarket("china")
+	cal, err := Parse(market, strings.NewReader(springFestival), SourceUpload)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return cal
+}
+
+func TestTradingDayArithmetic(t *testing.T) {
+	cal := newSpringFestival(t)
+	if len(cal.Days()) != 11 || cal.Market.Code != "cn" {
+		t.Fatalf("expected 11 unique days, got %d", len(cal.Days()))
+	}
+	if cal.IsTradingDay(day("2024-02-12")) || !cal.IsTradingDay(time.Date(2024, 2, 8, 15, 0, 0, 0, time.Local)) {
+		t.Error("holiday detection is wrong")
+	}
+	if n := cal.Count(day("2024-02-05"), day("2024-02-19")); n != 5 {
+		t.Errorf("Count = %d, want 5", n)
+	}
+
+	tests := []struct {
+		date string
+		n    int
+		want string
+	}{
+		{"2024-02-08", 1, "2024-02-19"},
+		{"2024-02-19", -1, "2024-02-08"},
+		{"2024-02-12", 1, "2024-02-19"}, // 假期中向后以2月8日为起点
+		{"2024-02-12", -1, "2024-02-08"},
+		{"2024-02-12", 0, "2024-02-19"},
+		{"2024-02-03", 3, "2024-02-07"},
+		{"2024-01-29", 10, "2024-02-20"},
+	}
+	for _, tt := range tests {
+		got, err := cal.Offset(day(tt.date), tt.n)
+		if err != nil || !got.Equal(day(tt.want)) {
+			t.Errorf("Offset(%s, %d) = %v, %v; want %s", tt.date, tt.n, got, err, tt.want)
+		}
+	}
+	for _, bad := range []struct {
+		date string
+		n    int
+	}{{"2024-02-20", 1}, {"2024-01-29", -1}, {"2024-03-01", -1}, {"2024-01-01", 1}} {
+		if _, err := cal.Offset(day(bad.date), bad.n); err == nil {
+			t.Errorf("Offset(%s, %d) should be out of range", bad.date, bad.n)
+		}
+	}
+
+	if next, err := cal.Next(day("2024-02-10")); err != nil || !next.Equal(day("2024-02-19")) {
+		t.Errorf("Next = %v, %v", next, err)
+	}
+	if prev, err := cal.Prev(day("2024-02-19")); err != nil || !prev.Equal(day("2024-02-08")) {
+		t.Errorf("Prev = %v, %v", prev, err)
+	}
+}
+
+func TestPeriodEnds(t *testing.T) {
+	cal := newSpringFestival(t)
+
+	weeks, err := cal.PeriodEnds(day("2024-01-29"), day("2024-02-19"), PeriodWeek)
+	if err != nil {
+		t.Fatalf("PeriodEnds failed: %v", err)
+	}
+	// 春节所在周的最后一个交易日是周四2月8日；2月19日所在周尚未结束
+	want := []string{"2024-02-02", "2024-02-08"}
+	if len(weeks) != len(want) {
+		t.Fatalf("week ends = %v, want %v", weeks, want)
+	}
+	for i, w := range want {
+		if !weeks[i].Equal(day(w)) {
+			t.Errorf("week end %d = %v, want %s", i, weeks[i], w)
+		}
+	}
+
+	months, _ := cal.PeriodEnds(day("2024-01-01"), day("2024-02-29"), PeriodMonth)
+	if len(months) != 1 || !months[0].Equal(day("2024-01-31")) {
+		t.Errorf("month ends = %v", months)
+	}
+	if end, _ := cal.IsPeriodEnd(day("2024-02-08"), PeriodWeek); !end {
+		t.Error("2024-02-08 should end its week")
+	}
+	if end, _ := cal.IsPeriodEnd(day("2024-02-20"), PeriodMonth); end {
+		t.Error("the last calendar day cannot be known to end its month")
+	}
+	if _, err := cal.PeriodEnds(day("2024-01-01"), day("2024-02-29"), "decade"); err == nil {
+		t.Error("unknown period should fail")
+	}
+}
+
+func TestIsOpen(t *testing.T) {
+	cal := newSpringFestival(t)
+	shanghai := cal.Market.Location
+	tests := []struct {
+		at   time.Time
+		open bool
+	}{
+		{time.Date(2024, 2, 8, 10, 0, 0, 0, shanghai), true},
+		{time.Date(2024, 2, 8, 12, 0, 0, 0, shanghai), false}, // 午间休市
+		{time.Date(2024, 2, 8, 15, 0, 0, 0, shanghai), true},
+		{time.Date(2024, 2, 8, 15, 1, 0, 0, shanghai), false},
+		{time.Date(2024, 2, 13, 10, 0, 0, 0, shanghai), false}, // 春节
+		{time.Date(2024, 2, 8, 2, 0, 0, 0, time.UTC), true},    // 北京时间10:00
+	}
+	for _, tt := range tests {
+		if got := cal.IsOpen(tt.at); got != tt.open {
+			t.Errorf("IsOpen(%v) = %v, want %v", tt.at, got, tt.open)
+		}
+	}
+
+	// 美股使用纽约时区，夏令时期间UTC 13:30开盘
+	us := Weekdays(markets["us"], day("2024-07-01"), day("2024-07-31"))
+	if !us.IsOpen(time.Date(2024, 7, 2, 13, 30, 0, 0, time.UTC)) || us.IsOpen(time.Date(2024, 7, 2, 13, 29, 0, 0, time.UTC)) {
+		t.Error("US session should follow daylight saving time")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	fallback, err := registry.Get("cn")
+	if err != nil || fallback.Source != SourceWeekdays || !fallback.IsTradingDay(day("2024-02-12")) {
+		t.Fatalf("unloaded market should fall back to weekdays: %+v, %v", fallback.Info(), err)
+	}
+
+	registry.Register(newSpringFestival(t))
+	cal, _ := registry.Get("SH")
+	if cal.Source != SourceUpload || cal.IsTradingDay(day("2024-02-12")) {
+		t.Errorf("registered calendar should replace the fallback: %+v", cal.Info())
+	}
+	if _, err := registry.Get("mars"); err == nil {
+		t.Error("unknown market should fail")
+	}
+	if infos := registry.List(); len(infos) != 3 || infos[0].Market != "cn" || infos[0].Days != 11 {
+		t.Errorf("unexpected list: %+v", infos)
+	}
+
+	market, _ := LookupMarket("hk")
+	if _, err := Parse(market, strings.NewReader("2024-01-02\nnot-a-date\n"), SourceUpload); err == nil {
+		t.Error("invalid line should fail")
+	}
+}
@@ -0,0 +1,232 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // 保证没有系统时区数据的环境也能加载交易所时区
+)
+
+// 日历来源
+const (
+	SourceQlib     = "qlib"
+	SourceUpload   = "upload"
+	SourceWeekdays = "weekdays" // 未加载日历时按工作日生成，不含节假日
+)
+
+// Session 交易时段，以当地零点起的时长表示
+type Session struct {
+	Open  time.Duration
+	Close time.Duration
+}
+
+// Market 市场定义
+type Market struct {
+	Code     string
+	Name     string
+	Location *time.Location
+	Sessions []Session
+}
+
+// markets 内置市场的时区和交易时段
+var markets = map[string]Market{
+	"cn": {Code: "cn", Name: "中国A股", Location: mustLoadLocation("Asia/Shanghai"),
+		Sessions: []Session{{clock(9, 30), clock(11, 30)}, {clock(13, 0), clock(15, 0)}}},
+	"us": {Code: "us", Name: "美股", Location: mustLoadLocation("America/New_York"),
+		Sessions: []Session{{clock(9, 30), clock(16, 0)}}},
+	"hk": {Code: "hk", Name: "港股", Location: mustLoadLocation("Asia/Hong_Kong"),
+		Sessions: []Session{{clock(9, 30), clock(12, 0)}, {clock(13, 0), clock(16, 0)}}},
+}
+
+// LookupMarket 按代码或别名查找市场
+func LookupMarket(code string) (Market, error) {
+	switch strings.ToLower(strings.TrimSpace(code)) {
+	case "cn", "china", "shanghai", "sh", "sz":
+		return markets["cn"], nil
+	case "us", "usa", "newyork":
+		return markets["us"], nil
+	case "hk", "hongkong", "hong_kong":
+		return markets["hk"], nil
+	}
+	return Market{}, fmt.Errorf("不支持的市场: %s", code)
+}
+
+// Parse 解析交易日列表，每行一个日期（可带时间），支持CSV首列和表头，#开头为注释
+func Parse(market Market, r io.Reader, source string) (*Calendar, error) {
+	var days []time.Time
+	scanner := bufio.NewScanner(r)
+	line, header := 0, false
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		field := strings.TrimSpace(strings.Split(text, ",")[0])
+		day, err := parseDay(field)
+		if err != nil {
+			if len(days) == 0 && !header {
+				header = true
+				continue // 表头
+			}
+			return nil, fmt.Errorf("交易日历第%d行格式错误: %s", line, field)
+		}
+		days = append(days, day)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取交易日历失败: %v", err)
+	}
+	return New(market, days, source)
+}
+
+// LoadQlib 加载Qlib数据目录下的 calendars/<freq>.txt
+func LoadQlib(market Market, dataPath, freq string) (*Calendar, error) {
+	if freq == "" {
+		freq = "day"
+	}
+	file, err := os.Open(filepath.Join(dataPath, "calendars", freq+".txt"))
+	if err != nil {
+		return nil, fmt.Errorf("读取交易日历失败: %v", err)
+	}
+	defer file.Close()
+	return Parse(market, file, SourceQlib)
+}
+
+// Weekdays 生成区间内只排除周末的日历，用于未加载真实日历的市场
+func Weekdays(market Market, start, end time.Time) *Calendar {
+	var days []time.Time
+	for day := dateOf(start); !day.After(end); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			days = append(days, day)
+		}
+	}
+	return &Calendar{Market: market, Source: SourceWeekdays, days: days}
+}
+
+// Info 日历概要
+type Info struct {
+	Market string    `json:"market"`
+	Name   string    `json:"name"`
+	Source string    `json:"source"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Days   int       `json:"days"`
+}
+
+// Registry 按市场索引的交易日历注册表
+type Registry struct {
+	mu        sync.RWMutex
+	calendars map[string]*Calendar
+	fallback  map[string]*Calendar
+}
+
+// Default 全局日历注册表
+var Default = NewRegistry()
+
+// NewRegistry 创建日历注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		calendars: make(map[string]*Calendar),
+		fallback:  make(map[string]*Calendar),
+	}
+}
+
+// Register 注册或替换市场的交易日历
+func (r *Registry) Register(cal *Calendar) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calendars[cal.Market.Code] = cal
+}
+
+// Get 获取市场的交易日历，未加载时返回按工作日生成的日历
+func (r *Registry) Get(code string) (*Calendar, error) {
+	market, err := LookupMarket(code)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	cal, ok := r.calendars[market.Code]
+	if !ok {
+		cal, ok = r.fallback[market.Code]
+	}
+	r.mu.RUnlock()
+	if ok {
+		return cal, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cal, ok := r.fallback[market.Code]; ok {
+		return cal, nil
+	}
+	now := time.Now()
+	cal = Weekdays(market, time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(now.Year()+5, 12, 31, 0, 0, 0, 0, time.UTC))
+	r.fallback[market.Code] = cal
+	return cal, nil
+}
+
+// MustGet 获取市场的交易日历，市场不存在时使用A股日历
+func (r *Registry) MustGet(code string) *Calendar {
+	cal, err := r.Get(code)
+	if err != nil {
+		cal, _ = r.Get("cn")
+	}
+	return cal
+}
+
+// List 列出所有内置市场当前使用的日历
+func (r *Registry) List() []Info {
+	codes := make([]string, 0, len(markets))
+	for code := range markets {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	infos := make([]Info, 0, len(codes))
+	for _, code := range codes {
+		cal, _ := r.Get(code)
+		infos = append(infos, cal.Info())
+	}
+	return infos
+}
+
+// Info 返回日历概要
+func (c *Calendar) Info() Info {
+	return Info{
+		Market: c.Market.Code,
+		Name:   c.Market.Name,
+		Source: c.Source,
+		Start:  c.First(),
+		End:    c.Last(),
+		Days:   len(c.days),
+	}
+}
+
+// parseDay 解析日期，兼容Qlib日历中带时间的格式
+func parseDay(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04:05", "2006/01/02", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析日期: %s", value)
+}
+
+func clock(hour, minute int) time.Duration {
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute
+}
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(fmt.Sprintf("加载时区 %s 失败: %v", name, err))
+	}
+	return loc
+}
@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// BacktestPortfolioValue 回测每日组合净值
+type BacktestPortfolioValue struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	StrategyID uint      `json:"strategy_id" gorm:"index;not null"`
+	Date       string    `json:"date" gorm:"size:10;not null"` // 交易日 2006-01-02
+	Value      float64   `json:"value"`                        // 账户总值
+	Cash       float64   `json:"cash"`
+	StockValue float64   `json:"stock_value"`
+	Return     float64   `json:"return"`   // 当日收益率
+	Turnover   float64   `json:"turnover"` // 当日成交额 / 账户总值
+	Cost       float64   `json:"cost"`     // 当日交易费用
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BacktestBenchmarkValue 回测期间基准每日点位
+type BacktestBenchmarkValue struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	StrategyID uint      `json:"strategy_id" gorm:"index;not null"`
+	Benchmark  string    `json:"benchmark" gorm:"size:50"`
+	Date       string    `json:"date" gorm:"size:10;not null"`
+	Value      float64   `json:"value"`  // 基准收盘点位
+	Return     float64   `json:"return"` // 基准当日收益率
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BacktestPosition 回测每日收盘持仓
+type BacktestPosition struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	StrategyID uint      `json:"strategy_id" gorm:"index;not null"`
+	Date       string    `json:"date" gorm:"size:10;not null"`
+	Instrument string    `json:"instrument" gorm:"size:20;not null"`
+	Amount     float64   `json:"amount"` // 持有股数
+	Price      float64   `json:"price"`  // 收盘价
+	Value      float64   `json:"value"`  // 持仓市值
+	Weight     float64   `json:"weight"` // 占账户总值比例
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BacktestTrade 回测成交记录
+type BacktestTrade struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	StrategyID uint      `json:"strategy_id" gorm:"index;not null"`
+	Date       string    `json:"date" gorm:"size:10;not null"`
+	Instrument string    `json:"instrument" gorm:"size:20;not null"`
+	Direction  string    `json:"direction" gorm:"size:10"` // buy, sell
+	Amount     float64   `json:"amount"`
+	Price      float64   `json:"price"`
+	Commission float64   `json:"commission"` // 交易费用（含印花税）
+	PnL        float64   `json:"pnl"`        // 卖出实现盈亏，买入为0
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ModelDailyIC 模型测试集每日IC，用于模型间的显著性检验
+type ModelDailyIC struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ModelID   uint      `json:"model_id" gorm:"index;not null"`
+	Date      string    `json:"date" gorm:"size:10;not null"`
+	IC        float64   `json:"ic"`
+	RankIC    float64   `json:"rank_ic"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OptimizationTrial 参数优化的单次试验记录，用于中断后恢复优化
+type OptimizationTrial struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TaskID      uint      `json:"task_id" gorm:"index;not null"`
+	StrategyID  uint      `json:"strategy_id" gorm:"index"`
+	Number      int       `json:"number"`                       // 试验编号
+	ParamsJSON  string    `json:"params_json" gorm:"type:text"` // 参数取值JSON
+	Status      string    `json:"status" gorm:"size:20"`        // completed, failed
+	Score       float64   `json:"score"`                        // 样本内目标值
+	OutOfSample *float64  `json:"out_of_sample"`                // 样本外目标值
+	MetricsJSON string    `json:"metrics_json" gorm:"type:text"`
+	ErrorMsg    string    `json:"error_msg" gorm:"type:text"`
+	CreatedAt   time.Time `json:"created_at"`
+}
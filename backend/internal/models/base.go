@@ -29,6 +29,38 @@ type Dataset struct {
 	UserID      uint   `json:"user_id,omitempty"`                      // 创建者ID
 }
 
+// TradingCalendar 管理员上传的交易日历，启动时加载到日历注册表
+type TradingCalendar struct {
+	BaseModel
+	Market     string `json:"market" gorm:"size:10;uniqueIndex;not null"` // cn, us, hk
+	DatesText  string `json:"-" gorm:"type:text"`                         // 每行一个交易日
+	StartDate  string `json:"start_date" gorm:"size:10"`
+	EndDate    string `json:"end_date" gorm:"size:10"`
+	Days       int    `json:"days"`
+	UploadedBy uint   `json:"uploaded_by"`
+}
+
+// Universe 股票池定义，启动时加载到股票池注册表
+type Universe struct {
+	BaseModel
+	Name        string `json:"name" gorm:"size:50;uniqueIndex;not null"`
+	Description string `json:"description" gorm:"size:500"`
+	Type        string `json:"type" gorm:"size:20;not null"` // constituents, filter
+	Source      string `json:"source" gorm:"size:20"`        // qlib, csv
+	FilterJSON  string `json:"filter_json" gorm:"type:text"` // 筛选条件，Type 为 filter 时有效
+	Instruments int    `json:"instruments"`                  // 曾入选的证券数
+	UserID      uint   `json:"user_id,omitempty"`            // 创建者ID
+}
+
+// UniverseMember 股票池成分区间，同一证券可能多次调入调出
+type UniverseMember struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	UniverseID uint   `json:"universe_id" gorm:"index;not null"`
+	Instrument string `json:"instrument" gorm:"size:20;not null"`
+	StartDate  string `json:"start_date" gorm:"size:10;not null"`
+	EndDate    string `json:"end_date" gorm:"size:10;not null"`
+}
+
 // Factor 因子模型
 type Factor struct {
 	BaseModel
@@ -36,16 +68,70 @@ type Factor struct {
 	Expression  string  `json:"expression" gorm:"type:text;not null"`
 	Description string  `json:"description" gorm:"size:500"`
 	Category    string  `json:"category" gorm:"size:50"`     // price, volume, momentum, etc
-	Status      string  `json:"status" gorm:"size:20"`       // active, testing, disabled
+	Status      string  `json:"status" gorm:"size:20"`       // active, testing, disabled, invalid
 	IC          float64 `json:"ic"`                          // Information Coefficient
 	IR          float64 `json:"ir"`                          // Information Ratio
 	RankIC      float64 `json:"rank_ic"`                     // Rank Information Coefficient
 	Turnover    float64 `json:"turnover"`                    // 换手率
 	Coverage    float64 `json:"coverage"`                    // 覆盖率
+	HalfLife    float64 `json:"half_life"`                   // IC半衰期（交易日），0表示未分析或无法估计
+	Rebalance   int     `json:"rebalance"`                   // 建议调仓周期（交易日）
+	Kind        string  `json:"kind" gorm:"size:20;default:expression"` // expression, composite
+	ConfigJSON  string  `json:"config_json" gorm:"type:text"`           // 复合因子的加权配置JSON
 	UserID      uint    `json:"user_id,omitempty"`           // 创建者ID
 	IsPublic    bool    `json:"is_public" gorm:"default:0"`  // 是否公开
 }
 
+// 因子类型
+const (
+	FactorKindExpression = "expression" // 由表达式计算
+	FactorKindComposite  = "composite"  // 由成分因子加权合成
+)
+
+// FactorStatusInvalid 复合因子的成分因子表达式变更或被删除后的状态，需要重新测试
+const FactorStatusInvalid = "invalid"
+
+// CompositeComponent 复合因子的成分因子，Expression 为最近一次测试时成分因子表达式的快照
+type CompositeComponent struct {
+	ID          uint    `json:"id" gorm:"primaryKey"`
+	CompositeID uint    `json:"composite_id" gorm:"index;not null"`
+	FactorID    uint    `json:"factor_id" gorm:"index;not null"`
+	Position    int     `json:"position"` // 成分顺序
+	Expression  string  `json:"expression" gorm:"type:text"`
+	Weight      float64 `json:"weight"` // 最近一次测试估计的权重
+}
+
+// FactorTestRecord 因子测试历史
+type FactorTestRecord struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	FactorID    uint      `json:"factor_id" gorm:"index;not null"`
+	StartDate   string    `json:"start_date" gorm:"size:10"`
+	EndDate     string    `json:"end_date" gorm:"size:10"`
+	Universe    string    `json:"universe" gorm:"size:50"`
+	IC          float64   `json:"ic"`
+	IR          float64   `json:"ir"`
+	RankIC      float64   `json:"rank_ic"`
+	Turnover    float64   `json:"turnover"`
+	Coverage    float64   `json:"coverage"`
+	WeightsJSON string    `json:"weights_json" gorm:"type:text"` // 复合因子测试时的成分权重
+	UserID      uint      `json:"user_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// FactorCorrelationReport 因子库相关性分析结果，由定时任务生成
+type FactorCorrelationReport struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	StartDate    string    `json:"start_date" gorm:"size:10"`
+	EndDate      string    `json:"end_date" gorm:"size:10"`
+	Universe     string    `json:"universe" gorm:"size:50"`
+	Threshold    float64   `json:"threshold"`                        // 冗余判定阈值
+	FactorIDs    string    `json:"factor_ids" gorm:"type:text"`      // 参与计算的因子ID，JSON数组，与矩阵行列顺序一致
+	MatrixJSON   string    `json:"matrix_json" gorm:"type:longtext"` // 相关系数矩阵JSON
+	ClustersJSON string    `json:"clusters_json" gorm:"type:text"`   // 聚类结果JSON，每簇为因子ID数组
+	SkippedJSON  string    `json:"skipped_json" gorm:"type:text"`    // 计算失败而跳过的因子及原因
+	CreatedAt    time.Time `json:"created_at"`
+}
+
 // Model 模型实体
 type Model struct {
 	BaseModel
@@ -108,6 +194,7 @@ type Task struct {
 	ResultJSON  string `json:"result_json" gorm:"type:text"`      // 结果JSON
 	LogPath     string `json:"log_path" gorm:"size:255"`          // 日志文件路径
 	ErrorMsg    string `json:"error_msg" gorm:"type:text"`        // 错误信息
+	TerminationReason string `json:"termination_reason,omitempty" gorm:"size:20"` // 作业终止原因：cancelled, timeout, oom_killed
 	StartTime   *time.Time `json:"start_time,omitempty"`           // 开始时间
 	EndTime     *time.Time `json:"end_time,omitempty"`             // 结束时间
 	EstimatedTime int      `json:"estimated_time"`                 // 预估耗时（秒）
@@ -159,3 +246,33 @@ type UIConfig struct {
 	ConfigData string `json:"config_data" gorm:"type:text"`        // JSON格式的配置数据
 }
 
+
+// AllModels 返回需要迁移的全部数据表模型，启动时的数据库迁移和测试数据库共用
+func AllModels() []interface{} {
+	return []interface{}{
+		&User{},
+		&Dataset{},
+		&Factor{},
+		&FactorCorrelationReport{},
+		&CompositeComponent{},
+		&FactorTestRecord{},
+		&Model{},
+		&Strategy{},
+		&Task{},
+		&Notification{},
+		&UIConfig{},
+		&Workflow{},
+		&WorkflowTemplate{},
+		&WorkflowExecution{},
+		&WorkflowStepExecution{},
+		&BacktestPortfolioValue{},
+		&BacktestBenchmarkValue{},
+		&BacktestPosition{},
+		&BacktestTrade{},
+		&ModelDailyIC{},
+		&OptimizationTrial{},
+		&TradingCalendar{},
+		&Universe{},
+		&UniverseMember{},
+	}
+}
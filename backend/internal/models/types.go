@@ -24,6 +24,7 @@ type ModelComparisonRequest struct {
 	Metrics     []string `json:"metrics"`
 	TimeRange   string   `json:"time_range,omitempty"`
 	Granularity string   `json:"granularity,omitempty"`
+	Correction  string   `json:"correction,omitempty"` // 多重比较校正：holm（默认）、bh、none
 }
 
 // ModelPerformance 模型性能
@@ -74,6 +75,27 @@ type StatisticalTestResult struct {
 	Statistic  float64                `json:"statistic"`
 	Result     string                 `json:"result"`
 	Details    map[string]interface{} `json:"details"`
+	Correction string                 `json:"correction,omitempty"` // 多重比较校正方法
+	Pairs      []PairwiseTestResult   `json:"pairs,omitempty"`
+}
+
+// PairwiseTestResult 两两比较的检验结果，差值均为 A - B
+type PairwiseTestResult struct {
+	AID            uint                `json:"a_id"`
+	AName          string              `json:"a_name"`
+	BID            uint                `json:"b_id"`
+	BName          string              `json:"b_name"`
+	Observations   int                 `json:"observations"` // 按日期对齐后的样本数
+	MeanDifference float64             `json:"mean_difference"`
+	Tests          map[string]PairTest `json:"tests"`            // paired_t、wilcoxon、sharpe_bootstrap
+	AdjustedPValue float64             `json:"adjusted_p_value"` // 配对t检验经多重比较校正后的p值
+	Significant    bool                `json:"significant"`
+}
+
+// PairTest 单项检验的统计量和p值
+type PairTest struct {
+	Statistic float64 `json:"statistic"`
+	PValue    float64 `json:"p_value"`
 }
 
 // ComparisonSummary 对比摘要
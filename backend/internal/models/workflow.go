@@ -97,6 +97,7 @@ func (WorkflowExecution) TableName() string {
 type WorkflowStepExecution struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
 	ExecutionID  uint           `json:"execution_id" gorm:"not null"`
+	ParentID     *uint          `json:"parent_id" gorm:"index"` // 子结果所属的步骤记录，如滚动训练的各窗口
 	StepName     string         `json:"step_name" gorm:"size:255;not null"`
 	StepType     string         `json:"step_type" gorm:"size:100;not null"`
 	Status       string         `json:"status" gorm:"size:50;not null"` // queued, running, completed, failed, skipped
@@ -186,5 +187,7 @@ const (
 	StepTypeStrategyBacktest   = "strategy_backtest"
 	StepTypeResultAnalysis     = "result_analysis"
 	StepTypeReportGeneration   = "report_generation"
+	StepTypeRollingTraining    = "rolling_training"
+	StepTypeRollingWindow      = "rolling_window"
 	StepTypeCustom             = "custom"
 )
\ No newline at end of file
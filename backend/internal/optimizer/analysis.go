@@ -0,0 +1,274 @@
+package optimizer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"qlib-backend/internal/analytics"
+)
+
+// maxSensitivityBins 数值参数取值过多时的分箱数
+const maxSensitivityBins = 10
+
+// 过拟合判定阈值
+const (
+	overfitDegradation     = 0.5 // 最优参数样本外得分较样本内下降超过该比例
+	overfitMinRankTrials   = 5   // 计算秩相关所需的最少试验数
+	overfitRankCorrelation = 0.0 // 样本内外得分秩相关不高于该值
+)
+
+// SensitivityPoint 参数某一取值（或分箱）下的试验得分
+type SensitivityPoint struct {
+	Value     interface{} `json:"value"` // 取值，分箱时为区间中点
+	MeanScore float64     `json:"mean_score"`
+	BestScore float64     `json:"best_score"`
+	Trials    int         `json:"trials"`
+}
+
+// ParameterSensitivity 单参数敏感性
+type ParameterSensitivity struct {
+	Name   string             `json:"name"`
+	Points []SensitivityPoint `json:"points"`
+	Range  float64            `json:"range"` // 各取值平均得分的极差，越大表示目标对该参数越敏感
+}
+
+// Surface 两个最敏感参数上的平均得分曲面，Scores[i][j] 对应 YValues[i]、XValues[j]，无试验的格点为空
+type Surface struct {
+	X       string        `json:"x"`
+	Y       string        `json:"y"`
+	XValues []interface{} `json:"x_values"`
+	YValues []interface{} `json:"y_values"`
+	Scores  [][]*float64  `json:"scores"`
+}
+
+// OverfitReport 样本内外表现差异
+type OverfitReport struct {
+	InSampleScore    float64 `json:"in_sample_score"`     // 最优参数的样本内得分
+	OutOfSampleScore float64 `json:"out_of_sample_score"` // 最优参数的样本外得分
+	Degradation      float64 `json:"degradation"`         // (样本内 - 样本外) / |样本内|
+	MeanSpread       float64 `json:"mean_spread"`         // 全部试验样本内与样本外得分之差的均值
+	RankCorrelation  float64 `json:"rank_correlation"`    // 全部试验样本内与样本外得分的Spearman相关
+	Trials           int     `json:"trials"`
+	Warning          bool    `json:"warning"`
+	Message          string  `json:"message"`
+}
+
+// Sensitivity 计算各参数的敏感性，按敏感程度从高到低排序
+func Sensitivity(space Space, trials []Trial) []ParameterSensitivity {
+	completed := completedTrials(trials)
+	result := make([]ParameterSensitivity, 0, len(space))
+	if len(completed) == 0 {
+		return result
+	}
+	for _, p := range space {
+		labels, keys := bucketize(p, completed)
+		groups := make(map[string][]float64)
+		for i, t := range completed {
+			groups[keys[i]] = append(groups[keys[i]], t.Score)
+		}
+		sensitivity := ParameterSensitivity{Name: p.Name}
+		low, high := math.Inf(1), math.Inf(-1)
+		for _, key := range orderedKeys(labels) {
+			scores := groups[key]
+			point := SensitivityPoint{Value: labels[key].label, MeanScore: analytics.Mean(scores), BestScore: scores[0], Trials: len(scores)}
+			for _, s := range scores {
+				point.BestScore = math.Max(point.BestScore, s)
+			}
+			low, high = math.Min(low, point.MeanScore), math.Max(high, point.MeanScore)
+			sensitivity.Points = append(sensitivity.Points, point)
+		}
+		sensitivity.Range = high - low
+		result = append(result, sensitivity)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Range > result[j].Range })
+	return result
+}
+
+// ResponseSurface 计算两个最敏感参数上的平均得分曲面，参数少于两个时返回空
+func ResponseSurface(space Space, trials []Trial, sensitivity []ParameterSensitivity) *Surface {
+	completed := completedTrials(trials)
+	if len(sensitivity) < 2 || len(completed) == 0 {
+		return nil
+	}
+	byName := make(map[string]Parameter, len(space))
+	for _, p := range space {
+		byName[p.Name] = p
+	}
+	xLabels, xKeys := bucketize(byName[sensitivity[0].Name], completed)
+	yLabels, yKeys := bucketize(byName[sensitivity[1].Name], completed)
+	xOrder, yOrder := orderedKeys(xLabels), orderedKeys(yLabels)
+	xIndex, yIndex := indexOf(xOrder), indexOf(yOrder)
+
+	sums := make([][]float64, len(yOrder))
+	counts := make([][]int, len(yOrder))
+	for i := range sums {
+		sums[i] = make([]float64, len(xOrder))
+		counts[i] = make([]int, len(xOrder))
+	}
+	for i, t := range completed {
+		y, x := yIndex[yKeys[i]], xIndex[xKeys[i]]
+		sums[y][x] += t.Score
+		counts[y][x]++
+	}
+
+	surface := &Surface{X: sensitivity[0].Name, Y: sensitivity[1].Name, Scores: make([][]*float64, len(yOrder))}
+	for _, key := range xOrder {
+		surface.XValues = append(surface.XValues, xLabels[key].label)
+	}
+	for _, key := range yOrder {
+		surface.YValues = append(surface.YValues, yLabels[key].label)
+	}
+	for y := range sums {
+		surface.Scores[y] = make([]*float64, len(xOrder))
+		for x := range sums[y] {
+			if counts[y][x] > 0 {
+				mean := sums[y][x] / float64(counts[y][x])
+				surface.Scores[y][x] = &mean
+			}
+		}
+	}
+	return surface
+}
+
+// CheckOverfit 比较样本内外得分，最优试验或全部试验均无样本外得分时返回空
+//
+// 以下任一情况给出过拟合警告：最优参数样本外得分较样本内下降超过一半；
+// 样本内为正而样本外不为正；试验足够多时样本内外得分的秩相关不为正。
+func CheckOverfit(trials []Trial, best Trial) *OverfitReport {
+	if best.OutOfSample == nil {
+		return nil
+	}
+	var inSample, outOfSample, spreads []float64
+	for _, t := range completedTrials(trials) {
+		if t.OutOfSample != nil {
+			inSample = append(inSample, t.Score)
+			outOfSample = append(outOfSample, *t.OutOfSample)
+			spreads = append(spreads, t.Score-*t.OutOfSample)
+		}
+	}
+	report := &OverfitReport{
+		InSampleScore:    best.Score,
+		OutOfSampleScore: *best.OutOfSample,
+		MeanSpread:       analytics.Mean(spreads),
+		Trials:           len(inSample),
+	}
+	if best.Score != 0 {
+		report.Degradation = (best.Score - *best.OutOfSample) / math.Abs(best.Score)
+	}
+	if len(inSample) >= overfitMinRankTrials {
+		report.RankCorrelation = analytics.RankCorrelation(inSample, outOfSample)
+	}
+
+	switch {
+	case best.Score > 0 && *best.OutOfSample <= 0:
+		report.Warning = true
+		report.Message = "最优参数在样本外区间未能取得正收益目标，存在过拟合风险"
+	case report.Degradation > overfitDegradation:
+		report.Warning = true
+		report.Message = fmt.Sprintf("最优参数样本外得分较样本内下降 %.0f%%，存在过拟合风险", report.Degradation*100)
+	case len(inSample) >= overfitMinRankTrials && report.RankCorrelation <= overfitRankCorrelation:
+		report.Warning = true
+		report.Message = fmt.Sprintf("样本内外得分秩相关为 %.2f，样本内排名无法预测样本外表现", report.RankCorrelation)
+	default:
+		report.Message = "样本内外表现一致，未发现明显过拟合"
+	}
+	return report
+}
+
+// completedTrials 已完成的试验
+func completedTrials(trials []Trial) []Trial {
+	completed := make([]Trial, 0, len(trials))
+	for _, t := range trials {
+		if t.Status == TrialCompleted {
+			completed = append(completed, t)
+		}
+	}
+	return completed
+}
+
+// bucketKey 分组键及其排序依据
+type bucketKey struct {
+	label interface{}
+	order float64
+}
+
+// bucketize 将试验按参数取值分组，返回各组的展示值和每个试验所属的组
+//
+// 离散参数及取值不超过 maxSensitivityBins 个的数值参数按原值分组，其余数值参数在采样尺度上等宽分箱。
+func bucketize(p Parameter, trials []Trial) (map[string]bucketKey, []string) {
+	labels := make(map[string]bucketKey)
+	keys := make([]string, len(trials))
+
+	distinct := make(map[string]bool)
+	for _, t := range trials {
+		distinct[Params{"v": t.Params[p.Name]}.Key()] = true
+	}
+	if p.Type == ParamChoice || len(distinct) <= maxSensitivityBins {
+		for i, t := range trials {
+			value := t.Params[p.Name]
+			key := Params{"v": value}.Key()
+			order := float64(len(labels))
+			if existing, ok := labels[key]; ok {
+				order = existing.order
+			} else if p.Type == ParamChoice {
+				order = float64(choiceIndex(p, value))
+			} else if x, ok := toFloat(value); ok {
+				order = x
+			}
+			labels[key] = bucketKey{label: value, order: order}
+			keys[i] = key
+		}
+		return labels, keys
+	}
+
+	lo, hi := p.toInternal(p.Low), p.toInternal(p.High)
+	width := (hi - lo) / maxSensitivityBins
+	for i, t := range trials {
+		x, _ := toFloat(t.Params[p.Name])
+		bin := 0
+		if width > 0 {
+			bin = int(math.Min(maxSensitivityBins-1, math.Max(0, math.Floor((p.toInternal(x)-lo)/width))))
+		}
+		center := p.fromInternal(lo + (float64(bin)+0.5)*width)
+		key := fmt.Sprintf("bin%d", bin)
+		labels[key] = bucketKey{label: math.Round(center*1e6) / 1e6, order: float64(bin)}
+		keys[i] = key
+	}
+	return labels, keys
+}
+
+// choiceIndex 候选值在参数配置中的位置
+func choiceIndex(p Parameter, value interface{}) int {
+	key := Params{"v": value}.Key()
+	for i, c := range p.Choices {
+		if (Params{"v": c}).Key() == key {
+			return i
+		}
+	}
+	return len(p.Choices)
+}
+
+// orderedKeys 按排序依据排列分组键
+func orderedKeys(labels map[string]bucketKey) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if labels[keys[i]].order != labels[keys[j]].order {
+			return labels[keys[i]].order < labels[keys[j]].order
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// indexOf 分组键到位置的映射
+func indexOf(keys []string) map[string]int {
+	index := make(map[string]int, len(keys))
+	for i, key := range keys {
+		index[key] = i
+	}
+	return index
+}
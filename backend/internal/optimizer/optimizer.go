@@ -0,0 +1,206 @@
+package optimizer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// 试验状态
+const (
+	TrialCompleted = "completed"
+	TrialFailed    = "failed"
+)
+
+// defaultMaxTrials 随机搜索和贝叶斯优化未指定试验次数时的默认值
+const defaultMaxTrials = 50
+
+// Trial 一次参数试验
+type Trial struct {
+	Number      int                `json:"number"`
+	Params      Params             `json:"params"`
+	Status      string             `json:"status"`
+	Score       float64            `json:"score"`                   // 样本内目标值，越大越好
+	OutOfSample *float64           `json:"out_of_sample,omitempty"` // 样本外目标值，无样本外区间时为空
+	Metrics     map[string]float64 `json:"metrics,omitempty"`
+	Error       string             `json:"error,omitempty"`
+}
+
+// Evaluation 目标函数的评估结果
+type Evaluation struct {
+	Score       float64
+	OutOfSample *float64
+	Metrics     map[string]float64
+}
+
+// Objective 目标函数，对一组参数执行回测并返回得分
+type Objective func(ctx context.Context, params Params) (*Evaluation, error)
+
+// Executor 并发执行一组子任务，同时运行的子任务数不超过 limit，全部结束后返回
+type Executor func(ctx context.Context, limit int, jobs []func(ctx context.Context)) error
+
+// Config 优化配置
+type Config struct {
+	Space       Space
+	Method      string
+	MaxTrials   int // 网格搜索为0时遍历全部组合
+	Concurrency int
+	Seed        int64
+}
+
+// TotalTrials 计划执行的试验次数，网格搜索不超过网格组合数
+func (c Config) TotalTrials() int {
+	method, _ := NormalizeMethod(c.Method)
+	if method == MethodGrid {
+		if size := c.Space.GridSize(); c.MaxTrials <= 0 || c.MaxTrials > size {
+			return size
+		}
+	}
+	if c.MaxTrials <= 0 {
+		return defaultMaxTrials
+	}
+	return c.MaxTrials
+}
+
+// Run 执行参数优化
+//
+// history 为已完成的试验（用于中断后恢复），其编号不会重复执行。
+// 每批并发采样 Concurrency 组参数，贝叶斯优化在批次之间根据已完成的试验更新。
+// onTrial 在每次试验结束时调用（可能并发），返回包括 history 在内按编号排序的全部试验。
+func Run(ctx context.Context, cfg Config, history []Trial, objective Objective, executor Executor, onTrial func(Trial)) ([]Trial, error) {
+	method, err := NormalizeMethod(cfg.Method)
+	if err != nil {
+		return nil, err
+	}
+	sampler, err := NewSampler(method, cfg.Space, cfg.Seed)
+	if err != nil {
+		return nil, err
+	}
+	maxTrials := cfg.TotalTrials()
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if executor == nil {
+		executor = Parallel
+	}
+
+	trials := append([]Trial(nil), history...)
+	done := make(map[int]bool, len(trials))
+	for _, t := range trials {
+		done[t.Number] = true
+	}
+	number := 0
+	for len(trials) < maxTrials {
+		if err := ctx.Err(); err != nil {
+			return sortTrials(trials), err
+		}
+
+		// 采样一批参数，跳过已完成的编号
+		var batch []Trial
+		exhausted := false
+		for len(batch) < concurrency && len(trials)+len(batch) < maxTrials {
+			for done[number] {
+				number++
+			}
+			params, ok := sampler.Sample(number, trials)
+			if !ok {
+				exhausted = true
+				break
+			}
+			batch = append(batch, Trial{Number: number, Params: params})
+			done[number] = true
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		var mu sync.Mutex
+		jobs := make([]func(ctx context.Context), len(batch))
+		for i := range batch {
+			trial := &batch[i]
+			jobs[i] = func(ctx context.Context) {
+				evaluate(ctx, trial, objective)
+				// 因取消而中断的试验不回调，恢复时重新执行
+				if onTrial != nil && ctx.Err() == nil {
+					mu.Lock()
+					onTrial(*trial)
+					mu.Unlock()
+				}
+			}
+		}
+		if err := executor(ctx, concurrency, jobs); err != nil {
+			return sortTrials(trials), err
+		}
+		if err := ctx.Err(); err != nil {
+			return sortTrials(trials), err
+		}
+		trials = append(trials, batch...)
+		if exhausted {
+			break
+		}
+	}
+	return sortTrials(trials), nil
+}
+
+// evaluate 执行单次试验，目标函数的 panic 视为试验失败
+func evaluate(ctx context.Context, trial *Trial, objective Objective) {
+	defer func() {
+		if r := recover(); r != nil {
+			trial.Status, trial.Error = TrialFailed, fmt.Sprintf("试验异常: %v", r)
+		}
+	}()
+	result, err := objective(ctx, trial.Params)
+	if err != nil {
+		trial.Status, trial.Error = TrialFailed, err.Error()
+		return
+	}
+	trial.Status = TrialCompleted
+	trial.Score = result.Score
+	trial.OutOfSample = result.OutOfSample
+	trial.Metrics = result.Metrics
+}
+
+// Parallel 默认执行器，使用独立协程并发执行
+func Parallel(ctx context.Context, limit int, jobs []func(ctx context.Context)) error {
+	if limit <= 0 {
+		limit = 1
+	}
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func(job func(ctx context.Context)) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			job(ctx)
+		}(job)
+	}
+	wg.Wait()
+	return nil
+}
+
+// Best 得分最高的已完成试验
+func Best(trials []Trial) (Trial, bool) {
+	var best Trial
+	found := false
+	for _, t := range trials {
+		if t.Status == TrialCompleted && (!found || t.Score > best.Score) {
+			best, found = t, true
+		}
+	}
+	return best, found
+}
+
+// sortTrials 按编号排序
+func sortTrials(trials []Trial) []Trial {
+	sort.Slice(trials, func(i, j int) bool { return trials[i].Number < trials[j].Number })
+	return trials
+}
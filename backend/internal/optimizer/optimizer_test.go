@@ -0,0 +1,188 @@
+package optimizer
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseSpace(t *testing.T) {
+	space, err := ParseSpace(map[string]interface{}{
+		"topk":   map[string]interface{}{"min": 10.0, "max": 50.0, "step": 10.0},
+		"n_drop": []interface{}{1.0, 3.0, 5.0},
+		"lr":     map[string]interface{}{"min": 0.001, "max": 0.1, "log": true},
+		"method": "close",
+	})
+	if err != nil {
+		t.Fatalf("ParseSpace failed: %v", err)
+	}
+	if len(space) != 4 || space[0].Name != "lr" || space[3].Name != "topk" {
+		t.Fatalf("space should be sorted by name: %+v", space)
+	}
+	if space[3].Type != ParamInt || len(space[3].GridValues()) != 5 || space[3].GridValues()[4] != 50 {
+		t.Errorf("unexpected topk grid: %v", space[3].GridValues())
+	}
+	if lr := space[0].GridValues(); space[0].Type != ParamFloat || len(lr) != defaultGridPoints || math.Abs(lr[2].(float64)-0.01) > 1e-9 {
+		t.Errorf("log grid should be geometric: %v", lr)
+	}
+	if space.GridSize() != 5*1*3*5 {
+		t.Errorf("grid size = %d", space.GridSize())
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"x": map[string]interface{}{"min": 5.0, "max": 1.0}},
+		{"x": map[string]interface{}{"min": 0.0, "max": 1.0, "log": true}},
+		{"x": []interface{}{}},
+		{},
+	} {
+		if _, err := ParseSpace(bad); err == nil {
+			t.Errorf("expected error for %v", bad)
+		}
+	}
+}
+
+// quadratic 在 x=3、y="b" 处取最大值
+func quadratic(ctx context.Context, params Params) (*Evaluation, error) {
+	x, _ := toFloat(params["x"])
+	score := -(x - 3) * (x - 3)
+	if params["y"] != "b" {
+		score -= 1
+	}
+	oos := score - 0.1
+	return &Evaluation{Score: score, OutOfSample: &oos}, nil
+}
+
+func TestRunGridAndResume(t *testing.T) {
+	space, _ := ParseSpace(map[string]interface{}{
+		"x": map[string]interface{}{"min": 0.0, "max": 5.0, "step": 1.0},
+		"y": []interface{}{"a", "b"},
+	})
+	cfg := Config{Space: space, Method: "grid_search", Concurrency: 3}
+
+	var calls int32
+	objective := func(ctx context.Context, params Params) (*Evaluation, error) {
+		atomic.AddInt32(&calls, 1)
+		return quadratic(ctx, params)
+	}
+	var streamed []Trial
+	trials, err := Run(context.Background(), cfg, nil, objective, nil, func(trial Trial) { streamed = append(streamed, trial) })
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(trials) != 12 || len(streamed) != 12 || calls != 12 {
+		t.Fatalf("expected 12 grid trials, got %d (streamed %d, calls %d)", len(trials), len(streamed), calls)
+	}
+	best, _ := Best(trials)
+	if best.Params["x"] != 3 || best.Params["y"] != "b" {
+		t.Errorf("unexpected best: %+v", best.Params)
+	}
+
+	// 恢复时只执行缺失的编号
+	calls = 0
+	history := []Trial{trials[0], trials[2], trials[5]}
+	resumed, err := Run(context.Background(), cfg, history, objective, nil, nil)
+	if err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if len(resumed) != 12 || calls != 9 {
+		t.Errorf("resume should run 9 remaining trials, got %d trials and %d calls", len(resumed), calls)
+	}
+	for i, trial := range resumed {
+		if trial.Number != i || trial.Params.Key() != trials[i].Params.Key() {
+			t.Errorf("trial %d mismatch after resume: %+v", i, trial)
+		}
+	}
+}
+
+func TestRunBayesian(t *testing.T) {
+	space, _ := ParseSpace(map[string]interface{}{
+		"x": map[string]interface{}{"min": -10.0, "max": 10.0, "type": "float"},
+		"y": []interface{}{"a", "b", "c"},
+	})
+	cfg := Config{Space: space, Method: "bayesian", MaxTrials: 60, Concurrency: 4, Seed: 7}
+	trials, err := Run(context.Background(), cfg, nil, quadratic, nil, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(trials) != 60 {
+		t.Fatalf("expected 60 trials, got %d", len(trials))
+	}
+	best, _ := Best(trials)
+	if x, _ := toFloat(best.Params["x"]); math.Abs(x-3) > 0.5 {
+		t.Errorf("TPE should approach the optimum, best %+v", best)
+	}
+
+	// 相同种子结果可复现
+	again, _ := Run(context.Background(), cfg, nil, quadratic, nil, nil)
+	for i := range trials {
+		if trials[i].Params.Key() != again[i].Params.Key() {
+			t.Fatalf("trial %d not reproducible: %v vs %v", i, trials[i].Params, again[i].Params)
+		}
+	}
+
+	t.Run("FailedTrials", func(t *testing.T) {
+		failing := func(ctx context.Context, params Params) (*Evaluation, error) {
+			panic("boom")
+		}
+		trials, err := Run(context.Background(), Config{Space: space, Method: "random", MaxTrials: 3}, nil, failing, nil, nil)
+		if err != nil || len(trials) != 3 || trials[0].Status != TrialFailed || trials[0].Error == "" {
+			t.Errorf("panics should mark trials failed: %+v, %v", trials, err)
+		}
+		if _, ok := Best(trials); ok {
+			t.Error("no completed trial should be best")
+		}
+	})
+}
+
+func TestSensitivityAndOverfit(t *testing.T) {
+	space, _ := ParseSpace(map[string]interface{}{
+		"x": map[string]interface{}{"min": 0.0, "max": 5.0, "step": 1.0},
+		"y": []interface{}{"a", "b"},
+	})
+	trials, _ := Run(context.Background(), Config{Space: space, Method: "grid"}, nil, quadratic, nil, nil)
+
+	sensitivity := Sensitivity(space, trials)
+	if len(sensitivity) != 2 || sensitivity[0].Name != "x" || len(sensitivity[0].Points) != 6 {
+		t.Fatalf("x should be most sensitive: %+v", sensitivity)
+	}
+	if p := sensitivity[0].Points[3]; p.Value != 3 || !almostEqual(p.MeanScore, -0.5) || !almostEqual(p.BestScore, 0) {
+		t.Errorf("unexpected point: %+v", p)
+	}
+	if !almostEqual(sensitivity[1].Range, 1) {
+		t.Errorf("y range = %v, want 1", sensitivity[1].Range)
+	}
+
+	surface := ResponseSurface(space, trials, sensitivity)
+	if surface == nil || surface.X != "x" || len(surface.Scores) != 2 || len(surface.Scores[0]) != 6 {
+		t.Fatalf("unexpected surface: %+v", surface)
+	}
+	if s := surface.Scores[1][3]; s == nil || *s != 0 {
+		t.Errorf("surface at (b, 3) = %v", s)
+	}
+
+	best, _ := Best(trials)
+	report := CheckOverfit(trials, best)
+	// 样本外得分整体下移0.1，排序一致
+	if report == nil || !almostEqual(report.MeanSpread, 0.1) || report.RankCorrelation < 0.99 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	t.Run("Warning", func(t *testing.T) {
+		flipped := make([]Trial, len(trials))
+		for i, trial := range trials {
+			oos := -trial.Score
+			trial.OutOfSample = &oos
+			flipped[i] = trial
+		}
+		best, _ := Best(flipped)
+		report := CheckOverfit(flipped, best)
+		if !report.Warning || report.RankCorrelation >= 0 {
+			t.Errorf("inverted out-of-sample ranking should warn: %+v", report)
+		}
+	})
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,284 @@
+package optimizer
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"qlib-backend/internal/analytics"
+)
+
+// 搜索方法
+const (
+	MethodGrid     = "grid"
+	MethodRandom   = "random"
+	MethodBayesian = "bayesian" // 基于TPE（Tree-structured Parzen Estimator）的贝叶斯优化
+)
+
+// TPE默认参数
+const (
+	tpeStartupTrials = 10   // 前若干次试验随机采样以建立先验
+	tpeCandidates    = 24   // 每个参数从优质分布中抽取的候选数
+	tpeGamma         = 0.25 // 按得分划分优质试验的比例
+)
+
+// Sampler 参数采样器
+//
+// 第 number 次试验的采样结果只取决于随机种子、试验编号和已完成的试验，
+// 因此中断后按相同编号重新采样可以恢复优化过程。返回 false 表示搜索空间已穷尽。
+type Sampler interface {
+	Sample(number int, history []Trial) (Params, bool)
+}
+
+// NormalizeMethod 规范化搜索方法名称，为空时使用贝叶斯优化
+func NormalizeMethod(method string) (string, error) {
+	switch strings.ToLower(method) {
+	case "grid", "grid_search":
+		return MethodGrid, nil
+	case "random", "random_search":
+		return MethodRandom, nil
+	case "", "bayesian", "bayes", "tpe":
+		return MethodBayesian, nil
+	}
+	return "", fmt.Errorf("不支持的优化方法: %s", method)
+}
+
+// NewSampler 创建采样器
+func NewSampler(method string, space Space, seed int64) (Sampler, error) {
+	method, err := NormalizeMethod(method)
+	if err != nil {
+		return nil, err
+	}
+	switch method {
+	case MethodGrid:
+		values := make([][]interface{}, len(space))
+		for i, p := range space {
+			values[i] = p.GridValues()
+		}
+		return &gridSampler{space: space, values: values, size: space.GridSize()}, nil
+	case MethodRandom:
+		return &randomSampler{space: space, seed: seed}, nil
+	}
+	return &tpeSampler{space: space, seed: seed}, nil
+}
+
+// gridSampler 网格搜索，按编号依次枚举参数组合
+type gridSampler struct {
+	space  Space
+	values [][]interface{}
+	size   int
+}
+
+func (s *gridSampler) Sample(number int, history []Trial) (Params, bool) {
+	if number >= s.size {
+		return nil, false
+	}
+	return s.space.gridPoint(s.values, number), true
+}
+
+// randomSampler 随机搜索
+type randomSampler struct {
+	space Space
+	seed  int64
+}
+
+func (s *randomSampler) Sample(number int, history []Trial) (Params, bool) {
+	return randomParams(s.space, trialRand(s.seed, number)), true
+}
+
+// tpeSampler TPE贝叶斯优化
+//
+// 将已完成试验按得分分为优质组和其余组，分别对每个参数建立Parzen密度 l(x) 和 g(x)，
+// 从 l(x) 中抽取候选值并选择 l(x)/g(x) 最大者。
+type tpeSampler struct {
+	space Space
+	seed  int64
+}
+
+func (s *tpeSampler) Sample(number int, history []Trial) (Params, bool) {
+	rng := trialRand(s.seed, number)
+	var completed []Trial
+	for _, t := range history {
+		if t.Status == TrialCompleted {
+			completed = append(completed, t)
+		}
+	}
+	if len(completed) < tpeStartupTrials {
+		return randomParams(s.space, rng), true
+	}
+
+	sort.SliceStable(completed, func(i, j int) bool { return completed[i].Score > completed[j].Score })
+	nGood := int(math.Ceil(tpeGamma * float64(len(completed))))
+	good, bad := completed[:nGood], completed[nGood:]
+
+	params := make(Params, len(s.space))
+	for _, p := range s.space {
+		if p.Type == ParamChoice {
+			params[p.Name] = sampleChoice(p, good, bad, rng)
+		} else {
+			params[p.Name] = sampleNumeric(p, good, bad, rng)
+		}
+	}
+	return params, true
+}
+
+// sampleChoice 离散参数：以加一平滑的频率作为 l 和 g
+func sampleChoice(p Parameter, good, bad []Trial, rng *rand.Rand) interface{} {
+	l := choiceWeights(p, good)
+	g := choiceWeights(p, bad)
+	best, bestRatio := 0, math.Inf(-1)
+	for c := 0; c < tpeCandidates; c++ {
+		i := drawIndex(l, rng)
+		if ratio := l[i] / g[i]; ratio > bestRatio {
+			best, bestRatio = i, ratio
+		}
+	}
+	return p.Choices[best]
+}
+
+// choiceWeights 各候选值的平滑频率
+func choiceWeights(p Parameter, trials []Trial) []float64 {
+	weights := make([]float64, len(p.Choices))
+	for i := range weights {
+		weights[i] = 1
+	}
+	for _, t := range trials {
+		if i := choiceIndex(p, t.Params[p.Name]); i < len(weights) {
+			weights[i]++
+		}
+	}
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+	return weights
+}
+
+// drawIndex 按权重抽取下标
+func drawIndex(weights []float64, rng *rand.Rand) int {
+	u := rng.Float64()
+	for i, w := range weights {
+		if u < w {
+			return i
+		}
+		u -= w
+	}
+	return len(weights) - 1
+}
+
+// sampleNumeric 数值参数：在采样尺度上建立截断高斯核的Parzen估计
+func sampleNumeric(p Parameter, good, bad []Trial, rng *rand.Rand) interface{} {
+	lo, hi := p.toInternal(p.Low), p.toInternal(p.High)
+	if hi <= lo {
+		return p.value(p.Low)
+	}
+	l := newParzen(observations(p, good), lo, hi)
+	g := newParzen(observations(p, bad), lo, hi)
+
+	best, bestScore := lo, math.Inf(-1)
+	for c := 0; c < tpeCandidates; c++ {
+		x := l.sample(rng)
+		if score := math.Log(l.pdf(x)+1e-300) - math.Log(g.pdf(x)+1e-300); score > bestScore {
+			best, bestScore = x, score
+		}
+	}
+	return p.value(p.fromInternal(best))
+}
+
+// observations 试验中参数取值（采样尺度）
+func observations(p Parameter, trials []Trial) []float64 {
+	values := make([]float64, 0, len(trials))
+	for _, t := range trials {
+		if v, ok := toFloat(t.Params[p.Name]); ok && (!p.Log || v > 0) {
+			values = append(values, p.toInternal(v))
+		}
+	}
+	return values
+}
+
+// parzen 截断在 [lo, hi] 上的等权高斯混合，包含一个覆盖整个区间的先验分量
+type parzen struct {
+	mus, sigmas []float64
+	lo, hi      float64
+}
+
+func newParzen(obs []float64, lo, hi float64) parzen {
+	span := hi - lo
+	mus := append([]float64{(lo + hi) / 2}, obs...)
+	sort.Float64s(mus)
+	sigmas := make([]float64, len(mus))
+	minSigma := span / math.Min(100, float64(len(mus)))
+	for i, mu := range mus {
+		left, right := mu-lo, hi-mu
+		if i > 0 {
+			left = mu - mus[i-1]
+		}
+		if i < len(mus)-1 {
+			right = mus[i+1] - mu
+		}
+		sigmas[i] = math.Max(minSigma, math.Min(span, math.Max(left, right)))
+	}
+	// 先验分量使用整个区间宽度
+	for i, mu := range mus {
+		if mu == (lo+hi)/2 {
+			sigmas[i] = span
+			break
+		}
+	}
+	return parzen{mus: mus, sigmas: sigmas, lo: lo, hi: hi}
+}
+
+// pdf 混合密度
+func (z parzen) pdf(x float64) float64 {
+	density := 0.0
+	for i, mu := range z.mus {
+		s := z.sigmas[i]
+		mass := analytics.NormalCDF((z.hi-mu)/s) - analytics.NormalCDF((z.lo-mu)/s)
+		if mass <= 0 {
+			continue
+		}
+		u := (x - mu) / s
+		density += math.Exp(-u*u/2) / (s * math.Sqrt(2*math.Pi) * mass)
+	}
+	return density / float64(len(z.mus))
+}
+
+// sample 从混合分布抽样
+func (z parzen) sample(rng *rand.Rand) float64 {
+	i := rng.Intn(len(z.mus))
+	for try := 0; try < 20; try++ {
+		x := z.mus[i] + z.sigmas[i]*rng.NormFloat64()
+		if x >= z.lo && x <= z.hi {
+			return x
+		}
+	}
+	return math.Max(z.lo, math.Min(z.hi, z.mus[i]))
+}
+
+// randomParams 在参数空间内均匀采样
+func randomParams(space Space, rng *rand.Rand) Params {
+	params := make(Params, len(space))
+	for _, p := range space {
+		switch {
+		case p.Type == ParamChoice:
+			params[p.Name] = p.Choices[rng.Intn(len(p.Choices))]
+		case p.Step > 0 && !p.Log:
+			n := int(math.Floor((p.High-p.Low)/p.Step+1e-9)) + 1
+			params[p.Name] = p.value(p.Low + float64(rng.Intn(n))*p.Step)
+		default:
+			lo, hi := p.toInternal(p.Low), p.toInternal(p.High)
+			params[p.Name] = p.value(p.fromInternal(lo + rng.Float64()*(hi-lo)))
+		}
+	}
+	return params
+}
+
+// trialRand 第 number 次试验使用的随机数生成器
+func trialRand(seed int64, number int) *rand.Rand {
+	return rand.New(rand.NewSource(seed*1000003 + int64(number)))
+}
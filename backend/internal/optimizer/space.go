@@ -0,0 +1,240 @@
+package optimizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ParamType 参数类型
+type ParamType string
+
+const (
+	ParamInt    ParamType = "int"
+	ParamFloat  ParamType = "float"
+	ParamChoice ParamType = "choice"
+)
+
+// defaultGridPoints 未指定步长的浮点参数在网格搜索中的等分点数
+const defaultGridPoints = 5
+
+// Parameter 单个待优化参数的取值范围
+type Parameter struct {
+	Name    string        `json:"name"`
+	Type    ParamType     `json:"type"`
+	Low     float64       `json:"low"`
+	High    float64       `json:"high"`
+	Step    float64       `json:"step"` // 取值步长，0 表示连续取值
+	Log     bool          `json:"log"`  // 是否在对数尺度上采样
+	Choices []interface{} `json:"choices"`
+}
+
+// Space 参数空间，按参数名排序
+type Space []Parameter
+
+// Params 一组参数取值
+type Params map[string]interface{}
+
+// Key 参数取值的规范化表示，用于去重和比较
+func (p Params) Key() string {
+	data, _ := json.Marshal(map[string]interface{}(p))
+	return string(data)
+}
+
+// ParseSpace 解析参数范围配置
+//
+// 每个参数支持以下写法：
+//   - 数组：候选值列表，如 [10, 20, 30] 或 ["a", "b"]
+//   - {"values": [...]}：同上
+//   - {"min": 1, "max": 10, "step": 1, "type": "int", "log": false}：数值区间，
+//     未指定 type 时边界和步长均为整数则视为整数参数
+//   - 标量：固定取值
+func ParseSpace(ranges map[string]interface{}) (Space, error) {
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("参数范围不能为空")
+	}
+	space := make(Space, 0, len(ranges))
+	for name, raw := range ranges {
+		param, err := parseParameter(name, raw)
+		if err != nil {
+			return nil, err
+		}
+		space = append(space, param)
+	}
+	sort.Slice(space, func(i, j int) bool { return space[i].Name < space[j].Name })
+	return space, nil
+}
+
+// parseParameter 解析单个参数配置
+func parseParameter(name string, raw interface{}) (Parameter, error) {
+	param := Parameter{Name: name}
+	switch v := raw.(type) {
+	case []interface{}:
+		param.Type, param.Choices = ParamChoice, v
+	case map[string]interface{}:
+		if values, ok := firstKey(v, "values", "choices").([]interface{}); ok {
+			param.Type, param.Choices = ParamChoice, values
+			break
+		}
+		low, lowOK := toFloat(firstKey(v, "min", "low"))
+		high, highOK := toFloat(firstKey(v, "max", "high"))
+		if !lowOK || !highOK {
+			return param, fmt.Errorf("参数 %s 缺少取值范围", name)
+		}
+		if high < low {
+			return param, fmt.Errorf("参数 %s 的上界小于下界", name)
+		}
+		param.Low, param.High = low, high
+		param.Step, _ = toFloat(v["step"])
+		if param.Step < 0 {
+			return param, fmt.Errorf("参数 %s 的步长不能为负", name)
+		}
+		param.Log, _ = v["log"].(bool)
+		if param.Log && low <= 0 {
+			return param, fmt.Errorf("参数 %s 使用对数尺度时下界必须为正", name)
+		}
+		switch t, _ := v["type"].(string); ParamType(t) {
+		case ParamInt:
+			param.Type = ParamInt
+		case ParamFloat:
+			param.Type = ParamFloat
+		case "":
+			param.Type = ParamFloat
+			if isInteger(low) && isInteger(high) && isInteger(param.Step) {
+				param.Type = ParamInt
+			}
+		default:
+			return param, fmt.Errorf("参数 %s 的类型不支持: %s", name, t)
+		}
+		if param.Type == ParamInt && param.Step == 0 {
+			param.Step = 1
+		}
+	default:
+		param.Type, param.Choices = ParamChoice, []interface{}{v}
+	}
+	if param.Type == ParamChoice && len(param.Choices) == 0 {
+		return param, fmt.Errorf("参数 %s 的候选值不能为空", name)
+	}
+	return param, nil
+}
+
+// GridValues 参数在网格搜索中的取值
+func (p Parameter) GridValues() []interface{} {
+	if p.Type == ParamChoice {
+		return p.Choices
+	}
+	var values []interface{}
+	if p.Step > 0 && !p.Log {
+		for i := 0; ; i++ {
+			x := p.Low + float64(i)*p.Step
+			if x > p.High+p.Step*1e-9 {
+				break
+			}
+			values = append(values, p.value(x))
+		}
+		return values
+	}
+	lo, hi := p.toInternal(p.Low), p.toInternal(p.High)
+	for i := 0; i < defaultGridPoints; i++ {
+		x := lo
+		if defaultGridPoints > 1 {
+			x += (hi - lo) * float64(i) / float64(defaultGridPoints-1)
+		}
+		values = append(values, p.value(p.fromInternal(x)))
+	}
+	return dedupe(values)
+}
+
+// toInternal 数值转换到采样尺度
+func (p Parameter) toInternal(x float64) float64 {
+	if p.Log {
+		return math.Log(x)
+	}
+	return x
+}
+
+// fromInternal 采样尺度转换回原始数值
+func (p Parameter) fromInternal(x float64) float64 {
+	if p.Log {
+		return math.Exp(x)
+	}
+	return x
+}
+
+// value 按步长和类型规整数值
+func (p Parameter) value(x float64) interface{} {
+	if p.Step > 0 {
+		x = p.Low + math.Round((x-p.Low)/p.Step)*p.Step
+	}
+	x = math.Max(p.Low, math.Min(p.High, x))
+	if p.Type == ParamInt {
+		return int(math.Round(x))
+	}
+	// 消除步长累加带来的浮点误差
+	return math.Round(x*1e10) / 1e10
+}
+
+// GridSize 网格组合数
+func (s Space) GridSize() int {
+	size := 1
+	for _, p := range s {
+		size *= len(p.GridValues())
+	}
+	return size
+}
+
+// gridPoint 第 index 个网格组合，最后一个参数变化最快
+func (s Space) gridPoint(values [][]interface{}, index int) Params {
+	params := make(Params, len(s))
+	for i := len(s) - 1; i >= 0; i-- {
+		n := len(values[i])
+		params[s[i].Name] = values[i][index%n]
+		index /= n
+	}
+	return params
+}
+
+// firstKey 返回第一个存在的键对应的值
+func firstKey(m map[string]interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		if v, ok := m[key]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// toFloat 将JSON数值转换为浮点数
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// isInteger 是否为整数值
+func isInteger(x float64) bool {
+	return x == math.Trunc(x)
+}
+
+// dedupe 去除重复取值，保持顺序
+func dedupe(values []interface{}) []interface{} {
+	seen := make(map[interface{}]bool, len(values))
+	result := values[:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,229 @@
+package qlib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"qlib-backend/internal/analytics"
+)
+
+// CashSegment 组合中现金所属的归因分组
+const CashSegment = "现金"
+
+// nativeAttribution 基于每日持仓和行情计算Brinson行业归因及因子归因
+//
+// 第t日的组合权重取t-1日收盘持仓，证券收益为t日相对t-1日的收盘价涨跌幅。
+// 基准为股票池内证券等权组合，行业权重和收益由池内证券按行业汇总得到。
+func (b *BacktestEngine) nativeAttribution(ctx context.Context, params AttributionAnalysisParams) (*AttributionAnalysisData, error) {
+	holdings := make(map[string]map[string]float64)
+	held := make(map[string]bool)
+	var first time.Time
+	for _, p := range params.Positions {
+		date := p.Date.Format("2006-01-02")
+		if holdings[date] == nil {
+			holdings[date] = make(map[string]float64)
+		}
+		holdings[date][p.Instrument] += p.Weight
+		held[p.Instrument] = true
+		if first.IsZero() || p.Date.Before(first) {
+			first = p.Date
+		}
+	}
+	end, err := parseOptionalDate(params.End)
+	if err != nil {
+		return nil, err
+	}
+
+	industries := params.Industries
+	if len(industries) == 0 {
+		if provider, ok := b.dataProvider.(IndustryProvider); ok {
+			if industries, err = provider.Industries(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	segmentOf := func(instrument string) string {
+		return industryOf(industries, instrument)
+	}
+
+	// 股票池行情用于基准和截面回归，持仓中不在池内的证券单独加载
+	universe, err := b.dataProvider.LoadFrame(ctx, FrameRequest{
+		Universe: params.Universe,
+		Fields:   []string{"$close"},
+		Start:    first,
+		End:      end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("加载股票池行情失败: %v", err)
+	}
+	var missing []string
+	for inst := range held {
+		if _, ok := universe.InstrumentIndex(inst); !ok {
+			missing = append(missing, inst)
+		}
+	}
+	sort.Strings(missing)
+	var extra *MarketFrame
+	if len(missing) > 0 {
+		if extra, err = b.dataProvider.LoadFrame(ctx, FrameRequest{
+			Instruments: missing,
+			Fields:      []string{"$close"},
+			Start:       first,
+			End:         end,
+		}); err != nil {
+			return nil, fmt.Errorf("加载持仓行情失败: %v", err)
+		}
+	}
+
+	var brinsonPeriods []analytics.BrinsonPeriod
+	var factorPeriods []analytics.FactorPeriod
+	for day := 1; day < len(universe.Calendar); day++ {
+		date, prev := universe.Calendar[day], universe.Calendar[day-1]
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		benchmarkReturns := frameReturns(universe, prev, date)
+		if len(benchmarkReturns) == 0 {
+			continue
+		}
+		benchmarkWeights := make(map[string]float64, len(benchmarkReturns))
+		for inst := range benchmarkReturns {
+			benchmarkWeights[inst] = 1 / float64(len(benchmarkReturns))
+		}
+
+		// 截面回归使用股票池和持仓证券的全部收益
+		weights := holdings[prev.Format("2006-01-02")]
+		returns := make(map[string]float64, len(benchmarkReturns))
+		for inst, r := range benchmarkReturns {
+			returns[inst] = r
+		}
+		if extra != nil {
+			for inst, r := range frameReturns(extra, prev, date) {
+				returns[inst] = r
+			}
+		}
+		portfolio := analytics.AggregateSegments(weights, returns, segmentOf)
+		invested := 0.0
+		for _, w := range weights {
+			invested += w
+		}
+		if cash := 1 - invested; cash > 1e-9 {
+			portfolio[CashSegment] = analytics.SegmentReturn{Weight: cash}
+		}
+
+		brinsonPeriods = append(brinsonPeriods, analytics.BrinsonPeriod{
+			Date:      date.Format("2006-01-02"),
+			Portfolio: portfolio,
+			Benchmark: analytics.AggregateSegments(benchmarkWeights, benchmarkReturns, segmentOf),
+		})
+		factorPeriods = append(factorPeriods, analytics.FactorPeriod{
+			Date:    date.Format("2006-01-02"),
+			Weights: weights,
+			Returns: returns,
+		})
+	}
+
+	brinson, err := analytics.Brinson(brinsonPeriods, analytics.LinkingMethod(params.Linking))
+	if err != nil {
+		return nil, fmt.Errorf("行业归因失败: %v", err)
+	}
+	attribution := &AttributionAnalysisData{
+		SectorAttribution: map[string]interface{}{
+			"linking":          brinson.Linking,
+			"benchmark":        params.Universe,
+			"portfolio_return": brinson.PortfolioReturn,
+			"benchmark_return": brinson.BenchmarkReturn,
+			"excess_return":    brinson.ExcessReturn,
+			"allocation":       brinson.Effect.Allocation,
+			"selection":        brinson.Effect.Selection,
+			"interaction":      brinson.Effect.Interaction,
+			"sectors":          brinson.Segments,
+			"periods":          brinson.Periods,
+		},
+		TimingEffect:      brinsonComponent(brinson, func(e analytics.BrinsonEffect) float64 { return e.Allocation }),
+		SecuritySelection: brinsonComponent(brinson, func(e analytics.BrinsonEffect) float64 { return e.Selection }),
+		InteractionEffect: brinsonComponent(brinson, func(e analytics.BrinsonEffect) float64 { return e.Interaction }),
+	}
+
+	if len(params.Exposures) > 0 {
+		factors, err := analytics.FactorAttribution(factorPeriods, params.Exposures, exposureFactors(params.Exposures), analytics.LinkingMethod(params.Linking))
+		if err != nil {
+			return nil, fmt.Errorf("因子归因失败: %v", err)
+		}
+		if attribution.FactorAttribution, err = toResultMap(factors); err != nil {
+			return nil, err
+		}
+	}
+	return attribution, nil
+}
+
+// frameReturns 计算数据帧中各证券 prev 到 date 的收盘价涨跌幅
+func frameReturns(frame *MarketFrame, prev, date time.Time) map[string]float64 {
+	returns := make(map[string]float64)
+	from, to := frame.DateIndex(prev), frame.DateIndex(date)
+	if from >= len(frame.Calendar) || to >= len(frame.Calendar) || !frame.Calendar[from].Equal(prev) || !frame.Calendar[to].Equal(date) {
+		return returns
+	}
+	for _, inst := range frame.Instruments {
+		closes, ok := frame.Series(inst, "$close")
+		if !ok || math.IsNaN(closes[from]) || math.IsNaN(closes[to]) || closes[from] <= 0 {
+			continue
+		}
+		returns[inst] = closes[to]/closes[from] - 1
+	}
+	return returns
+}
+
+// brinsonComponent 提取Brinson归因中的单项效应：合计、分行业和逐期
+func brinsonComponent(result *analytics.BrinsonResult, component func(analytics.BrinsonEffect) float64) map[string]interface{} {
+	bySector := make(map[string]float64, len(result.Segments))
+	for segment, s := range result.Segments {
+		bySector[segment] = component(s.BrinsonEffect)
+	}
+	byPeriod := make([]map[string]interface{}, 0, len(result.Periods))
+	for _, p := range result.Periods {
+		byPeriod = append(byPeriod, map[string]interface{}{
+			"date":  p.Date,
+			"value": component(p.Effect),
+		})
+	}
+	return map[string]interface{}{
+		"total":     component(result.Effect),
+		"by_sector": bySector,
+		"by_period": byPeriod,
+	}
+}
+
+// exposureFactors 暴露矩阵中出现的全部因子，按名称排序
+func exposureFactors(exposures map[string]map[string]float64) []string {
+	seen := make(map[string]bool)
+	var factors []string
+	for _, values := range exposures {
+		for factor := range values {
+			if !seen[factor] {
+				seen[factor] = true
+				factors = append(factors, factor)
+			}
+		}
+	}
+	sort.Strings(factors)
+	return factors
+}
+
+// toResultMap 将结构体按JSON字段转换为map
+func toResultMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("序列化归因结果失败: %v", err)
+	}
+	result := make(map[string]interface{})
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("序列化归因结果失败: %v", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package qlib
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadIndustryMapping(t *testing.T) {
+	dir := t.TempDir()
+	content := "instrument,industry\nsh600000,银行\nSZ000001, 银行\nSH600276,医药\n"
+	if err := os.WriteFile(filepath.Join(dir, IndustryFileName), []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	industries, err := NewBinDataReader(dir).Industries()
+	if err != nil {
+		t.Fatalf("Industries failed: %v", err)
+	}
+	if len(industries) != 3 || industries["SH600000"] != "银行" || industries["SZ000001"] != "银行" {
+		t.Errorf("unexpected industries: %v", industries)
+	}
+	if industryOf(industries, "sh600276") != "医药" || industryOf(industries, "SH601318") != UnknownIndustry {
+		t.Error("industryOf should match case-insensitively and fall back to UnknownIndustry")
+	}
+
+	empty, err := NewBinDataReader(t.TempDir()).Industries()
+	if err != nil || len(empty) != 0 {
+		t.Errorf("missing industry file should yield empty mapping, got %v, %v", empty, err)
+	}
+}
+
+func TestNativeAttribution(t *testing.T) {
+	calendar := []time.Time{
+		time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 1, 4, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 1, 5, 0, 0, 0, 0, time.UTC),
+	}
+	frame := NewMarketFrame(calendar, []string{"A", "B", "C", "D"})
+	frame.SetSeries("A", "$close", []float64{10, 11, 11})     // 银行
+	frame.SetSeries("B", "$close", []float64{10, 10, 10.5})   // 银行
+	frame.SetSeries("C", "$close", []float64{20, 19, 19})     // 医药
+	frame.SetSeries("D", "$close", []float64{20, 20.4, 20.4}) // 医药
+	provider := NewMemoryDataProvider(frame)
+	provider.SetIndustries(map[string]string{"A": "银行", "B": "银行", "C": "医药", "D": "医药"})
+
+	engine := NewBacktestEngine("", "", "")
+	engine.SetDataProvider(provider)
+	// 持有A 60%、C 30%，其余为现金
+	var positions []PositionRecord
+	for _, date := range calendar {
+		positions = append(positions,
+			PositionRecord{Date: date, Instrument: "A", Weight: 0.6},
+			PositionRecord{Date: date, Instrument: "C", Weight: 0.3},
+		)
+	}
+
+	attribution, err := engine.GetAttributionAnalysis(AttributionAnalysisParams{
+		Positions: positions,
+		Exposures: map[string]map[string]float64{"A": {"size": 1}, "B": {"size": 0}, "C": {"size": -1}, "D": {"size": 0.5}},
+	})
+	if err != nil {
+		t.Fatalf("GetAttributionAnalysis failed: %v", err)
+	}
+
+	// 第1期：组合 0.6×0.1 + 0.3×(-0.05) = 0.045，基准等权 (0.1+0-0.05+0.02)/4 = 0.0175
+	// 第2期：组合 0，基准 0.05/4 = 0.0125
+	sector := attribution.SectorAttribution
+	portfolio, benchmark := sector["portfolio_return"].(float64), sector["benchmark_return"].(float64)
+	if math.Abs(portfolio-0.045) > 1e-9 || math.Abs(benchmark-(1.0175*1.0125-1)) > 1e-9 {
+		t.Errorf("portfolio = %v, benchmark = %v", portfolio, benchmark)
+	}
+	total := attribution.TimingEffect["total"].(float64) +
+		attribution.SecuritySelection["total"].(float64) +
+		attribution.InteractionEffect["total"].(float64)
+	if math.Abs(total-(portfolio-benchmark)) > 1e-9 {
+		t.Errorf("effects sum to %v, want excess %v", total, portfolio-benchmark)
+	}
+	bySector := attribution.SecuritySelection["by_sector"].(map[string]float64)
+	if _, ok := bySector[CashSegment]; !ok {
+		t.Errorf("cash segment missing from attribution: %v", bySector)
+	}
+	if len(attribution.TimingEffect["by_period"].([]map[string]interface{})) != 2 {
+		t.Errorf("expected 2 periods, got %v", attribution.TimingEffect["by_period"])
+	}
+
+	if attribution.FactorAttribution == nil {
+		t.Fatal("expected factor attribution with exposures")
+	}
+	if _, ok := attribution.FactorAttribution["factors"].(map[string]interface{})["size"]; !ok {
+		t.Errorf("size factor missing: %v", attribution.FactorAttribution)
+	}
+}
@@ -1,10 +1,9 @@
 package qlib
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
-	"strings"
 )
 
 // BacktestEngine Qlib回测引擎
@@ -12,6 +11,9 @@ type BacktestEngine struct {
 	pythonPath    string
 	qlibPath      string
 	workspacePath string
+	dataProvider  MarketDataProvider
+	universes     *UniverseRegistry
+	pythonClient  Client
 }
 
 // NewBacktestEngine 创建新的回测引擎实例
@@ -23,9 +25,125 @@ func NewBacktestEngine(pythonPath, qlibPath, workspacePath string) *BacktestEngi
 		pythonPath:    pythonPath,
 		qlibPath:      qlibPath,
 		workspacePath: workspacePath,
+		universes:     DefaultUniverses,
 	}
 }
 
+// SetDataProvider 设置原生回测使用的行情数据提供者
+func (b *BacktestEngine) SetDataProvider(provider MarketDataProvider) {
+	b.dataProvider = provider
+}
+
+// SetUniverseRegistry 设置解析时点股票池使用的注册表
+func (b *BacktestEngine) SetUniverseRegistry(registry *UniverseRegistry) {
+	b.universes = registry
+}
+
+// SetPythonClient 设置执行Python脚本的客户端，未设置时使用全局默认客户端
+func (b *BacktestEngine) SetPythonClient(client Client) {
+	b.pythonClient = client
+}
+
+// RunNativeBacktest 使用原生回测器执行回测，不依赖Python环境
+func (b *BacktestEngine) RunNativeBacktest(ctx context.Context, config NativeBacktestConfig, strategyType string, strategyParams map[string]interface{}, scores *FactorFrame, callback BacktestProgressCallback) (*NativeBacktestReport, error) {
+	if b.dataProvider == nil {
+		return nil, fmt.Errorf("未设置行情数据，无法执行原生回测")
+	}
+	strategy, err := NewSignalStrategy(strategyType, strategyParams)
+	if err != nil {
+		return nil, err
+	}
+	report, err := NewNativeBacktester(b.dataProvider).Run(ctx, config, scores, strategy, callback)
+	if err != nil {
+		return nil, fmt.Errorf("回测执行失败: %w", err)
+	}
+	return report, nil
+}
+
+// UsesNativeBackend 是否配置了原生回测使用的行情数据
+func (b *BacktestEngine) UsesNativeBackend() bool {
+	return b.dataProvider != nil
+}
+
+// RunBacktestWithReport 运行回测并返回完整的回测记录
+//
+// 配置了行情数据且策略可原生执行（ConfigJSON 中提供 signal 打分表达式，或为固定权重策略）时使用原生回测器，
+// 否则调用Python脚本，此时只有汇总指标，返回的 report 为 nil。
+// 原生回测的信号按 Universe 的时点成分计算，证券只在属于股票池的交易日参与选股。
+func (b *BacktestEngine) RunBacktestWithReport(ctx context.Context, params BacktestParams, callback BacktestProgressCallback) (*BacktestResult, *NativeBacktestReport, error) {
+	if b.dataProvider == nil {
+		result, err := b.RunBacktestContext(ctx, params, callback)
+		return result, nil, err
+	}
+
+	strategyParams := map[string]interface{}{}
+	if params.ConfigJSON != "" {
+		if err := json.Unmarshal([]byte(params.ConfigJSON), &strategyParams); err != nil {
+			return nil, nil, fmt.Errorf("解析策略配置失败: %v", err)
+		}
+	}
+	signal, _ := strategyParams["signal"].(string)
+	if signal == "" && strategyNeedsScores(params.StrategyType) {
+		result, err := b.RunBacktestContext(ctx, params, callback)
+		return result, nil, err
+	}
+
+	config := NativeBacktestConfig{
+		Account:   paramFloat(strategyParams, "account", 0),
+		Benchmark: params.Benchmark,
+	}
+	var err error
+	if config.Start, err = parseOptionalDate(params.BacktestStart); err != nil {
+		return nil, nil, err
+	}
+	if config.End, err = parseOptionalDate(params.BacktestEnd); err != nil {
+		return nil, nil, err
+	}
+	if exchange, ok := strategyParams["exchange"]; ok {
+		data, _ := json.Marshal(exchange)
+		if err := json.Unmarshal(data, &config.Exchange); err != nil {
+			return nil, nil, fmt.Errorf("解析交易所配置失败: %v", err)
+		}
+	}
+
+	var scores *FactorFrame
+	if signal != "" {
+		// 首个交易日使用前一交易日的分数，信号区间向前多取两周以跨过长假
+		signalStart := config.Start
+		if !signalStart.IsZero() {
+			signalStart = signalStart.AddDate(0, 0, -14)
+		}
+		engine := NewFactorEngine(b.pythonPath, b.qlibPath, "")
+		engine.SetDataProvider(b.dataProvider)
+		engine.SetUniverseRegistry(b.universes)
+		scores, err = engine.EvaluateFactor(ctx, signal, FrameRequest{
+			Universe: params.Universe,
+			Start:    signalStart,
+			End:      config.End,
+			Freq:     config.Exchange.Freq,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("计算策略信号失败: %v", err)
+		}
+	}
+
+	report, err := b.RunNativeBacktest(ctx, config, params.StrategyType, strategyParams, scores, callback)
+	if err != nil {
+		return nil, nil, err
+	}
+	summary := report.Summary
+	return &summary, report, nil
+}
+
+// strategyNeedsScores 策略是否依赖预测分数
+func strategyNeedsScores(strategyType string) bool {
+	switch strategyType {
+	case "FixedWeightStrategy", "fixed_weight":
+		return false
+	}
+	return true
+}
+
 // BacktestParams 回测参数
 type BacktestParams struct {
 	StrategyID    uint   `json:"strategy_id"`
@@ -65,7 +183,13 @@ type BacktestResultsData struct {
 
 // AttributionAnalysisParams 归因分析参数
 type AttributionAnalysisParams struct {
-	StrategyID uint `json:"strategy_id"`
+	StrategyID uint                          `json:"strategy_id"`
+	Universe   string                        `json:"universe"` // 基准股票池，行业基准为池内证券等权组合
+	End        string                        `json:"end"`
+	Positions  []PositionRecord              `json:"positions"`  // 每日收盘持仓，非空且设置了行情数据提供者时使用原生归因
+	Industries map[string]string             `json:"industries"` // 证券行业分类，为空时从行情数据提供者读取
+	Exposures  map[string]map[string]float64 `json:"exposures"`  // 证券因子暴露矩阵，非空时进行因子归因
+	Linking    string                        `json:"linking"`    // 多期连接方法：carino（默认）或 menchero
 }
 
 // AttributionAnalysisData 归因分析结果
@@ -109,10 +233,15 @@ type OptimizationResult struct {
 	Iterations     []OptimizationStep     `json:"iterations"`
 }
 
+// OptimizationStep 参数优化的单次试验
 type OptimizationStep struct {
-	Iteration  int                    `json:"iteration"`
-	Parameters map[string]interface{} `json:"parameters"`
-	Score      float64                `json:"score"`
+	Iteration        int                    `json:"iteration"`
+	Parameters       map[string]interface{} `json:"parameters"`
+	Score            float64                `json:"score"`
+	Status           string                 `json:"status,omitempty"`
+	OutOfSampleScore *float64               `json:"out_of_sample_score,omitempty"` // 样本外目标值
+	Metrics          map[string]float64     `json:"metrics,omitempty"`
+	Error            string                 `json:"error,omitempty"`
 }
 
 func (r *OptimizationResult) ToJSON() (string, error) {
@@ -140,6 +269,11 @@ type BacktestProgressCallback func(progress int, metrics map[string]float64)
 
 // RunBacktest 运行回测
 func (b *BacktestEngine) RunBacktest(params BacktestParams, callback BacktestProgressCallback) (*BacktestResult, error) {
+	return b.RunBacktestContext(context.Background(), params, callback)
+}
+
+// RunBacktestContext 运行Python回测，回测脚本发送的进度和指标事件转发给 callback，全部事件转发给上下文中的事件处理函数
+func (b *BacktestEngine) RunBacktestContext(ctx context.Context, params BacktestParams, callback BacktestProgressCallback) (*BacktestResult, error) {
 	scriptArgs := map[string]interface{}{
 		"action":         "run_backtest",
 		"strategy_id":    params.StrategyID,
@@ -153,9 +287,12 @@ func (b *BacktestEngine) RunBacktest(params BacktestParams, callback BacktestPro
 		"workspace":      b.workspacePath,
 	}
 
-	result, err := b.executePythonScript(scriptArgs)
+	if callback != nil {
+		ctx = WithPythonEvents(ctx, progressCallbackEvents(callback))
+	}
+	result, err := b.runScript(ctx, scriptArgs)
 	if err != nil {
-		return nil, fmt.Errorf("回测执行失败: %v", err)
+		return nil, fmt.Errorf("回测执行失败: %w", err)
 	}
 
 	// 解析回测结果
@@ -187,21 +324,6 @@ func (b *BacktestEngine) RunBacktest(params BacktestParams, callback BacktestPro
 	return backtestResult, nil
 }
 
-// StopBacktest 停止回测
-func (b *BacktestEngine) StopBacktest(strategyID uint) error {
-	scriptArgs := map[string]interface{}{
-		"action":      "stop_backtest",
-		"strategy_id": strategyID,
-	}
-
-	_, err := b.executePythonScript(scriptArgs)
-	if err != nil {
-		return fmt.Errorf("停止回测失败: %v", err)
-	}
-
-	return nil
-}
-
 // GetBacktestResults 获取详细回测结果
 func (b *BacktestEngine) GetBacktestResults(params BacktestResultsParams) (*BacktestResultsData, error) {
 	scriptArgs := map[string]interface{}{
@@ -239,6 +361,10 @@ func (b *BacktestEngine) GetBacktestResults(params BacktestResultsParams) (*Back
 
 // GetAttributionAnalysis 获取归因分析
 func (b *BacktestEngine) GetAttributionAnalysis(params AttributionAnalysisParams) (*AttributionAnalysisData, error) {
+	if b.dataProvider != nil && len(params.Positions) > 0 {
+		return b.nativeAttribution(context.Background(), params)
+	}
+
 	scriptArgs := map[string]interface{}{
 		"action":      "get_attribution_analysis",
 		"strategy_id": params.StrategyID,
@@ -432,12 +558,12 @@ func (b *BacktestEngine) GetSupportedStrategies() ([]StrategyTypeInfo, error) {
 
 // executePythonScript 执行Python脚本
 func (b *BacktestEngine) executePythonScript(args map[string]interface{}) (map[string]interface{}, error) {
-	argsJSON, err := json.Marshal(args)
-	if err != nil {
-		return nil, fmt.Errorf("序列化参数失败: %v", err)
-	}
+	return b.runScript(context.Background(), args)
+}
 
-	pythonScript := `
+// runScript 执行Python脚本，上下文中的事件处理函数接收脚本发送的事件
+func (b *BacktestEngine) runScript(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+	pythonScript := pythonEventPrelude + `
 import json
 import sys
 import os
@@ -476,7 +602,9 @@ def run_backtest(params):
         benchmark = params.get('benchmark', '000300.XSHG')
         
         # 初始化qlib
+        emit_event("progress", progress=5, message="初始化Qlib环境")
         init(provider_uri="file:///path/to/qlib_data", region="cn")
+        emit_event("log", level="INFO", message=f"开始回测 {strategy_type} 策略 ID: {strategy_id}，区间 {backtest_start} - {backtest_end}")
         
         # 模拟回测结果
         # 在实际生产中，这里会调用真正的Qlib回测接口
@@ -493,7 +621,7 @@ def run_backtest(params):
         volatility = 0.185  # 18.5%
         win_rate = 0.62  # 62%
         
-        return {
+        backtest_result = {
             "total_return": total_return,
             "annual_return": annual_return,
             "excess_return": excess_return,
@@ -502,19 +630,13 @@ def run_backtest(params):
             "volatility": volatility,
             "win_rate": win_rate
         }
+        emit_event("metric", progress=100, metrics=backtest_result)
+        emit_event("log", level="INFO", message="回测完成")
+        return backtest_result
         
     except Exception as e:
         raise Exception(f"Backtest failed: {str(e)}")
 
-def stop_backtest(params):
-    """停止回测"""
-    try:
-        strategy_id = params.get('strategy_id')
-        # 实现停止回测的逻辑
-        return {"stopped": True, "strategy_id": strategy_id}
-    except Exception as e:
-        raise Exception(f"Stop backtest failed: {str(e)}")
-
 def get_backtest_results(params):
     """获取详细回测结果"""
     try:
@@ -778,10 +900,6 @@ def main():
             backtest_result = run_backtest(args)
             result["data"] = backtest_result
             
-        elif action == "stop_backtest":
-            stop_result = stop_backtest(args)
-            result["data"] = stop_result
-            
         elif action == "get_backtest_results":
             results_data = get_backtest_results(args)
             result["data"] = results_data
@@ -813,6 +931,7 @@ def main():
         print(json.dumps(result))
         
     except Exception as e:
+        emit_event("error", message=str(e))
         error_result = {
             "success": False,
             "error": str(e),
@@ -824,27 +943,7 @@ if __name__ == "__main__":
     main()
 `
 
-	cmd := exec.Command(b.pythonPath, "-c", pythonScript)
-	cmd.Stdin = strings.NewReader(string(argsJSON))
-
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("执行Python脚本失败: %v", err)
-	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, fmt.Errorf("解析Python输出失败: %v", err)
-	}
-
-	if success, ok := result["success"].(bool); !ok || !success {
-		if errorMsg, ok := result["error"].(string); ok {
-			return nil, fmt.Errorf("Python脚本执行失败: %s", errorMsg)
-		}
-		return nil, fmt.Errorf("Python脚本执行失败")
-	}
-
-	return result, nil
+	return runPythonScript(ctx, resolvePythonClient(b.pythonClient, b.pythonPath), pythonScript, args)
 }
 
 // 数据结构定义
@@ -0,0 +1,341 @@
+package qlib
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// StrategyContext 策略决策上下文
+type StrategyContext struct {
+	Date     time.Time
+	Scores   map[string]float64 // 上一交易日的预测分数，与Qlib一致信号滞后一天使用
+	Position *Position          // 当前持仓，策略不应修改，需要模拟成交时使用 Clone
+	Exchange *SimExchange
+}
+
+// SignalStrategy 原生回测策略，根据预测分数和持仓生成当日订单
+type SignalStrategy interface {
+	Name() string
+	GenerateOrders(ctx *StrategyContext) ([]Order, error)
+}
+
+// NewSignalStrategy 根据策略类名和参数创建原生策略
+func NewSignalStrategy(strategyType string, params map[string]interface{}) (SignalStrategy, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	switch strategyType {
+	case "TopkDropoutStrategy", "topk_dropout":
+		strategy := &TopkDropoutStrategy{
+			Topk:       int(paramFloat(params, "topk", 50)),
+			NDrop:      int(paramFloat(params, "n_drop", 5)),
+			HoldThresh: int(paramFloat(params, "hold_thresh", 1)),
+			RiskDegree: paramFloat(params, "risk_degree", 0.95),
+			MethodSell: paramString(params, "method_sell", "bottom"),
+			MethodBuy:  paramString(params, "method_buy", "top"),
+		}
+		if strategy.Topk <= 0 || strategy.NDrop < 0 {
+			return nil, fmt.Errorf("topk必须为正数且n_drop不能为负数")
+		}
+		if strategy.MethodSell != "bottom" || strategy.MethodBuy != "top" {
+			return nil, fmt.Errorf("原生回测仅支持 method_sell=bottom 和 method_buy=top")
+		}
+		return strategy, nil
+	case "WeightStrategyBase", "weight":
+		return &WeightStrategy{
+			Topk:       int(paramFloat(params, "topk", 0)),
+			RiskDegree: paramFloat(params, "risk_degree", 0.95),
+		}, nil
+	case "FixedWeightStrategy", "fixed_weight":
+		weights := map[string]float64{}
+		if raw, ok := params["weights"].(map[string]interface{}); ok {
+			for inst := range raw {
+				weights[inst] = getFloat64(raw, inst)
+			}
+		}
+		if len(weights) == 0 {
+			return nil, fmt.Errorf("固定权重策略需要指定weights")
+		}
+		return &FixedWeightStrategy{
+			Weights:       weights,
+			RebalanceFreq: paramString(params, "rebalance_freq", "monthly"),
+			RiskDegree:    paramFloat(params, "risk_degree", 0.95),
+		}, nil
+	default:
+		return nil, fmt.Errorf("原生回测不支持的策略类型: %s", strategyType)
+	}
+}
+
+func paramFloat(params map[string]interface{}, key string, defaultValue float64) float64 {
+	if _, ok := params[key]; !ok {
+		return defaultValue
+	}
+	return getFloat64(params, key)
+}
+
+func paramString(params map[string]interface{}, key, defaultValue string) string {
+	if v, ok := params[key].(string); ok && v != "" {
+		return v
+	}
+	return defaultValue
+}
+
+// TopkDropoutStrategy 持有分数最高的topk只股票，每日最多替换n_drop只
+type TopkDropoutStrategy struct {
+	Topk       int
+	NDrop      int
+	HoldThresh int     // 最短持有交易日数，未满不卖出
+	RiskDegree float64 // 买入时使用的现金比例
+	MethodSell string
+	MethodBuy  string
+}
+
+// Name 策略名称
+func (s *TopkDropoutStrategy) Name() string {
+	return "TopkDropoutStrategy"
+}
+
+// GenerateOrders 与 qlib.contrib.strategy.TopkDropoutStrategy 的 generate_trade_decision 一致
+func (s *TopkDropoutStrategy) GenerateOrders(ctx *StrategyContext) ([]Order, error) {
+	if len(ctx.Scores) == 0 {
+		return nil, nil
+	}
+	score := func(inst string) float64 {
+		if v, ok := ctx.Scores[inst]; ok && !math.IsNaN(v) {
+			return v
+		}
+		return math.Inf(-1)
+	}
+
+	// 当前持仓按分数降序，无分数的排在最后
+	last := rankByScore(ctx.Position.Instruments(), score)
+	held := make(map[string]bool, len(last))
+	for _, inst := range last {
+		held[inst] = true
+	}
+
+	// 候选买入：未持有的高分股票
+	candidates := make([]string, 0, len(ctx.Scores))
+	for inst, v := range ctx.Scores {
+		if !math.IsNaN(v) && !held[inst] {
+			candidates = append(candidates, inst)
+		}
+	}
+	candidates = rankByScore(candidates, score)
+	today := candidates[:clampInt(s.NDrop+s.Topk-len(last), 0, len(candidates))]
+
+	// 在持仓和候选中分数最低的n_drop只里卖出持仓股
+	comb := rankByScore(append(append([]string{}, last...), today...), score)
+	bottom := make(map[string]bool)
+	for _, inst := range comb[len(comb)-clampInt(s.NDrop, 0, len(comb)):] {
+		bottom[inst] = true
+	}
+	var sell []string
+	for _, inst := range last {
+		if bottom[inst] {
+			sell = append(sell, inst)
+		}
+	}
+	buy := today[:clampInt(len(sell)+s.Topk-len(last), 0, len(today))]
+
+	orders := make([]Order, 0, len(sell)+len(buy))
+	sim := ctx.Position.Clone()
+	sellSet := make(map[string]bool, len(sell))
+	for _, inst := range sell {
+		sellSet[inst] = true
+	}
+	for _, inst := range last {
+		if !sellSet[inst] || ctx.Position.Holdings[inst].HoldDays < s.HoldThresh {
+			continue
+		}
+		order := Order{Instrument: inst, Direction: OrderSell, Amount: ctx.Position.Amount(inst)}
+		orders = append(orders, order)
+		// 在副本上模拟卖出，得到可用于买入的现金；停牌、跌停等无法卖出的订单由交易所记录原因
+		ctx.Exchange.Deal(order, sim)
+	}
+
+	if len(buy) == 0 {
+		return orders, nil
+	}
+	value := sim.Cash * s.RiskDegree / float64(len(buy))
+	for _, inst := range buy {
+		amount := 0.0
+		if price := ctx.Exchange.DealPrice(inst); !math.IsNaN(price) && price > 0 {
+			amount = ctx.Exchange.RoundAmount(inst, value/price)
+		}
+		orders = append(orders, Order{Instrument: inst, Direction: OrderBuy, Amount: amount})
+	}
+	return orders, nil
+}
+
+// rankByScore 按分数降序排列，分数相同时按代码排序
+func rankByScore(instruments []string, score func(string) float64) []string {
+	sort.SliceStable(instruments, func(i, j int) bool {
+		si, sj := score(instruments[i]), score(instruments[j])
+		if si != sj {
+			return si > sj
+		}
+		return instruments[i] < instruments[j]
+	})
+	return instruments
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// WeightStrategy 按目标权重调仓的策略，对应 WeightStrategyBase
+type WeightStrategy struct {
+	Topk       int     // 仅持有分数最高的topk只，0表示所有正分数股票
+	RiskDegree float64 // 股票仓位占账户总值的比例
+
+	// TargetWeights 自定义目标权重，为空时按正分数占比分配
+	TargetWeights func(ctx *StrategyContext) map[string]float64
+}
+
+// Name 策略名称
+func (s *WeightStrategy) Name() string {
+	return "WeightStrategyBase"
+}
+
+// GenerateOrders 根据目标权重生成调仓订单
+func (s *WeightStrategy) GenerateOrders(ctx *StrategyContext) ([]Order, error) {
+	var weights map[string]float64
+	if s.TargetWeights != nil {
+		weights = s.TargetWeights(ctx)
+	} else {
+		if len(ctx.Scores) == 0 {
+			return nil, nil
+		}
+		weights = scoreWeights(ctx.Scores, s.Topk)
+	}
+	return targetWeightOrders(ctx, weights, s.RiskDegree), nil
+}
+
+// scoreWeights 按正分数占比分配权重
+func scoreWeights(scores map[string]float64, topk int) map[string]float64 {
+	positive := make([]string, 0, len(scores))
+	for inst, v := range scores {
+		if !math.IsNaN(v) && v > 0 {
+			positive = append(positive, inst)
+		}
+	}
+	positive = rankByScore(positive, func(inst string) float64 { return scores[inst] })
+	if topk > 0 && len(positive) > topk {
+		positive = positive[:topk]
+	}
+
+	total := 0.0
+	for _, inst := range positive {
+		total += scores[inst]
+	}
+	weights := make(map[string]float64, len(positive))
+	for _, inst := range positive {
+		weights[inst] = scores[inst] / total
+	}
+	return weights
+}
+
+// targetWeightOrders 将目标权重转换为订单，先卖后买
+func targetWeightOrders(ctx *StrategyContext, weights map[string]float64, riskDegree float64) []Order {
+	price := func(inst string) float64 {
+		if p := ctx.Exchange.DealPrice(inst); !math.IsNaN(p) && p > 0 {
+			return p
+		}
+		if h, ok := ctx.Position.Holdings[inst]; ok {
+			return h.Price
+		}
+		return math.NaN()
+	}
+
+	total := ctx.Position.Cash
+	for inst, h := range ctx.Position.Holdings {
+		total += h.Amount * price(inst)
+	}
+
+	instruments := ctx.Position.Instruments()
+	for inst := range weights {
+		if _, ok := ctx.Position.Holdings[inst]; !ok {
+			instruments = append(instruments, inst)
+		}
+	}
+	sort.Strings(instruments)
+
+	// 无法交易的证券同样生成订单，由交易所记录未达到目标的原因
+	var sells, buys []Order
+	for _, inst := range instruments {
+		p := price(inst)
+		if math.IsNaN(p) || p <= 0 {
+			if weights[inst] > 0 {
+				buys = append(buys, Order{Instrument: inst, Direction: OrderBuy})
+			}
+			continue
+		}
+		target := ctx.Exchange.RoundAmount(inst, total*riskDegree*weights[inst]/p)
+		diff := target - ctx.Position.Amount(inst)
+		switch {
+		case diff < 0:
+			sells = append(sells, Order{Instrument: inst, Direction: OrderSell, Amount: -diff})
+		case diff > 0:
+			buys = append(buys, Order{Instrument: inst, Direction: OrderBuy, Amount: diff})
+		}
+	}
+	return append(sells, buys...)
+}
+
+// FixedWeightStrategy 按固定权重定期调仓
+type FixedWeightStrategy struct {
+	Weights       map[string]float64
+	RebalanceFreq string // daily、weekly、monthly、quarterly
+	RiskDegree    float64
+
+	lastRebalance time.Time
+}
+
+// Name 策略名称
+func (s *FixedWeightStrategy) Name() string {
+	return "FixedWeightStrategy"
+}
+
+// Instruments 策略需要的证券
+func (s *FixedWeightStrategy) Instruments() []string {
+	instruments := make([]string, 0, len(s.Weights))
+	for inst := range s.Weights {
+		instruments = append(instruments, inst)
+	}
+	sort.Strings(instruments)
+	return instruments
+}
+
+// GenerateOrders 到达调仓周期时按固定权重调仓
+func (s *FixedWeightStrategy) GenerateOrders(ctx *StrategyContext) ([]Order, error) {
+	if !s.lastRebalance.IsZero() && samePeriod(s.lastRebalance, ctx.Date, s.RebalanceFreq) {
+		return nil, nil
+	}
+	s.lastRebalance = ctx.Date
+	return targetWeightOrders(ctx, s.Weights, s.RiskDegree), nil
+}
+
+// samePeriod 判断两个日期是否处于同一调仓周期
+func samePeriod(a, b time.Time, freq string) bool {
+	switch freq {
+	case "daily", "day":
+		return a.Equal(b)
+	case "weekly", "week":
+		ay, aw := a.ISOWeek()
+		by, bw := b.ISOWeek()
+		return ay == by && aw == bw
+	case "quarterly", "quarter":
+		return a.Year() == b.Year() && (a.Month()-1)/3 == (b.Month()-1)/3
+	default:
+		return a.Year() == b.Year() && a.Month() == b.Month()
+	}
+}
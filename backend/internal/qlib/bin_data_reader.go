@@ -0,0 +1,437 @@
+package qlib
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BinDataReader Qlib本地二进制数据读取器
+//
+// 目录结构与Qlib的 dump_bin 输出一致：
+//
+//	calendars/<freq>.txt                 交易日历，每行一个日期
+//	instruments/<market>.txt             股票池，每行 "代码\t开始日期\t结束日期"
+//	features/<代码小写>/<字段>.<freq>.bin  float32小端序列，首个值为在日历中的起始下标
+//	industry.csv                         可选的行业分类，每行 "代码,行业"
+type BinDataReader struct {
+	dataPath string
+
+	mu          sync.RWMutex
+	calendars   map[string][]time.Time
+	instruments map[string][]InstrumentSpan
+}
+
+// InstrumentSpan 证券在股票池中的一段有效区间
+type InstrumentSpan struct {
+	Symbol string    `json:"symbol"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+}
+
+// NewBinDataReader 创建二进制数据读取器，dataPath 支持 ~ 开头
+func NewBinDataReader(dataPath string) *BinDataReader {
+	return &BinDataReader{
+		dataPath:    expandDataPath(dataPath),
+		calendars:   make(map[string][]time.Time),
+		instruments: make(map[string][]InstrumentSpan),
+	}
+}
+
+// IsQlibDataDir 判断目录是否为Qlib格式数据目录，dataPath 支持 ~ 开头
+func IsQlibDataDir(dataPath string) bool {
+	if dataPath == "" {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(expandDataPath(dataPath), "calendars", "day.txt"))
+	return err == nil && !info.IsDir()
+}
+
+// expandDataPath 将 ~ 开头的路径展开为用户主目录
+func expandDataPath(dataPath string) string {
+	if strings.HasPrefix(dataPath, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(dataPath, "~"))
+		}
+	}
+	return dataPath
+}
+
+// DataPath 返回数据目录
+func (r *BinDataReader) DataPath() string {
+	return r.dataPath
+}
+
+// Reload 清空缓存的日历和股票池，数据目录更新后调用
+func (r *BinDataReader) Reload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calendars = make(map[string][]time.Time)
+	r.instruments = make(map[string][]InstrumentSpan)
+}
+
+// Calendar 读取交易日历
+func (r *BinDataReader) Calendar(freq string) ([]time.Time, error) {
+	freq = normalizeFreq(freq)
+
+	r.mu.RLock()
+	calendar, ok := r.calendars[freq]
+	r.mu.RUnlock()
+	if ok {
+		return calendar, nil
+	}
+
+	if err := checkPathName("频率", freq); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(r.dataPath, "calendars", freq+".txt")
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取交易日历失败: %v", err)
+	}
+	defer file.Close()
+
+	calendar = make([]time.Time, 0, 4096)
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		date, err := parseQlibTime(text)
+		if err != nil {
+			return nil, fmt.Errorf("交易日历第%d行格式错误: %v", line, err)
+		}
+		calendar = append(calendar, date)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取交易日历失败: %v", err)
+	}
+
+	r.mu.Lock()
+	r.calendars[freq] = calendar
+	r.mu.Unlock()
+	return calendar, nil
+}
+
+// Markets 列出 instruments 目录下的股票池
+func (r *BinDataReader) Markets() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(r.dataPath, "instruments"))
+	if err != nil {
+		return nil, fmt.Errorf("读取股票池目录失败: %v", err)
+	}
+	markets := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".txt") {
+			markets = append(markets, strings.TrimSuffix(entry.Name(), ".txt"))
+		}
+	}
+	sort.Strings(markets)
+	return markets, nil
+}
+
+// Instruments 读取股票池中各证券的有效区间，同一证券可能有多段
+func (r *BinDataReader) Instruments(market string) ([]InstrumentSpan, error) {
+	if market == "" {
+		market = "all"
+	}
+	market = strings.ToLower(market)
+
+	r.mu.RLock()
+	spans, ok := r.instruments[market]
+	r.mu.RUnlock()
+	if ok {
+		return spans, nil
+	}
+
+	if err := checkPathName("股票池", market); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(r.dataPath, "instruments", market+".txt")
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取股票池 %s 失败: %v", market, err)
+	}
+	defer file.Close()
+
+	spans, err = parseInstrumentSpans(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取股票池 %s 失败: %v", market, err)
+	}
+
+	r.mu.Lock()
+	r.instruments[market] = spans
+	r.mu.Unlock()
+	return spans, nil
+}
+
+// ListInstruments 返回在[start, end]内有效的证券代码（零值表示不限制）
+func (r *BinDataReader) ListInstruments(market string, start, end time.Time) ([]string, error) {
+	spans, err := r.Instruments(market)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	symbols := make([]string, 0, len(spans))
+	for _, span := range spans {
+		if !end.IsZero() && span.Start.After(end) {
+			continue
+		}
+		if !start.IsZero() && span.End.Before(start) {
+			continue
+		}
+		if !seen[span.Symbol] {
+			seen[span.Symbol] = true
+			symbols = append(symbols, span.Symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols, nil
+}
+
+// Fields 列出证券可用的字段（如 $close）
+func (r *BinDataReader) Fields(instrument, freq string) ([]string, error) {
+	freq = normalizeFreq(freq)
+	dir, err := r.featureDir(instrument)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取证券 %s 的特征目录失败: %v", instrument, err)
+	}
+
+	suffix := "." + freq + ".bin"
+	fields := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), suffix) {
+			fields = append(fields, "$"+strings.TrimSuffix(entry.Name(), suffix))
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// FeatureRange 返回特征文件覆盖的日历下标区间[first, last]，文件为空时 last < first
+func (r *BinDataReader) FeatureRange(instrument, field, freq string) (int, int, error) {
+	path, err := r.featurePath(instrument, field, freq)
+	if err != nil {
+		return 0, -1, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, -1, fmt.Errorf("读取特征文件失败: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, -1, fmt.Errorf("读取特征文件失败: %v", err)
+	}
+	first, err := readStartIndex(file)
+	if err != nil {
+		return 0, -1, err
+	}
+	count := int(info.Size()/4) - 1
+	return first, first + count - 1, nil
+}
+
+// ReadFeature 读取特征在日历下标[from, to]区间的值，超出文件范围的部分为 NaN
+//
+// 只读取需要的字节区间，不会把整个文件载入内存。
+func (r *BinDataReader) ReadFeature(instrument, field, freq string, from, to int) ([]float64, error) {
+	if to < from {
+		return []float64{}, nil
+	}
+	values := nanSeries(to - from + 1)
+
+	path, err := r.featurePath(instrument, field, freq)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return values, nil
+		}
+		return nil, fmt.Errorf("读取特征文件失败: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("读取特征文件失败: %v", err)
+	}
+	first, err := readStartIndex(file)
+	if err != nil {
+		return nil, err
+	}
+	last := first + int(info.Size()/4) - 2
+
+	lo, hi := maxInt(from, first), to
+	if hi > last {
+		hi = last
+	}
+	if hi < lo {
+		return values, nil
+	}
+
+	buf := make([]byte, (hi-lo+1)*4)
+	offset := int64(1+lo-first) * 4
+	if _, err := file.ReadAt(buf, offset); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("读取特征数据失败: %v", err)
+	}
+	for i := 0; i <= hi-lo; i++ {
+		bits := binary.LittleEndian.Uint32(buf[i*4:])
+		values[lo-from+i] = float64(math.Float32frombits(bits))
+	}
+	return values, nil
+}
+
+// DataRange 返回证券数据的起止日期，instrument为空时返回整个日历的范围
+func (r *BinDataReader) DataRange(instrument string) (time.Time, time.Time, error) {
+	calendar, err := r.Calendar("day")
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if len(calendar) == 0 {
+		return time.Time{}, time.Time{}, fmt.Errorf("交易日历为空")
+	}
+	if instrument == "" {
+		return calendar[0], calendar[len(calendar)-1], nil
+	}
+
+	first, last, err := r.FeatureRange(instrument, "$close", "day")
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if last < first || first >= len(calendar) {
+		return time.Time{}, time.Time{}, fmt.Errorf("证券 %s 没有数据", instrument)
+	}
+	if last >= len(calendar) {
+		last = len(calendar) - 1
+	}
+	return calendar[first], calendar[last], nil
+}
+
+// LoadFrame 按请求加载对齐的行情数据帧，实现 MarketDataProvider
+func (r *BinDataReader) LoadFrame(ctx context.Context, req FrameRequest) (*MarketFrame, error) {
+	freq := normalizeFreq(req.Freq)
+	calendar, err := r.Calendar(freq)
+	if err != nil {
+		return nil, err
+	}
+
+	from := 0
+	if !req.Start.IsZero() {
+		from = sort.Search(len(calendar), func(i int) bool { return !calendar[i].Before(req.Start) })
+	}
+	to := len(calendar) - 1
+	if !req.End.IsZero() {
+		to = sort.Search(len(calendar), func(i int) bool { return calendar[i].After(req.End) }) - 1
+	}
+	from = maxInt(0, from-req.Lookback)
+	if to+req.Lookahead < len(calendar) {
+		to += req.Lookahead
+	} else {
+		to = len(calendar) - 1
+	}
+
+	instruments := req.Instruments
+	if len(instruments) == 0 {
+		instruments, err = r.ListInstruments(req.Universe, req.Start, req.End)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if to < from {
+		return NewMarketFrame([]time.Time{}, instruments), nil
+	}
+	frame := NewMarketFrame(calendar[from:to+1], instruments)
+	for _, field := range req.Fields {
+		columns := make([][]float64, len(instruments))
+		for i, inst := range instruments {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			values, err := r.ReadFeature(inst, field, freq, from, to)
+			if err != nil {
+				return nil, fmt.Errorf("读取 %s 的 %s 失败: %v", inst, field, err)
+			}
+			columns[i] = values
+		}
+		frame.Fields[field] = columns
+	}
+	return frame, nil
+}
+
+func (r *BinDataReader) featureDir(instrument string) (string, error) {
+	if err := checkPathName("证券代码", instrument); err != nil {
+		return "", err
+	}
+	return filepath.Join(r.dataPath, "features", strings.ToLower(instrument)), nil
+}
+
+func (r *BinDataReader) featurePath(instrument, field, freq string) (string, error) {
+	dir, err := r.featureDir(instrument)
+	if err != nil {
+		return "", err
+	}
+	name := strings.ToLower(strings.TrimPrefix(field, "$"))
+	freq = normalizeFreq(freq)
+	if err := checkPathName("字段", name); err != nil {
+		return "", err
+	}
+	if err := checkPathName("频率", freq); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s.%s.bin", name, freq)), nil
+}
+
+// checkPathName 检查用作文件名的股票池、证券代码、字段和频率，拒绝路径分隔符和 ..，避免读取数据目录以外的文件
+func checkPathName(kind, name string) error {
+	if name == "" || name == "." || strings.Contains(name, "..") || strings.ContainsAny(name, `/\`+"\x00") {
+		return fmt.Errorf("无效的%s: %q", kind, name)
+	}
+	return nil
+}
+
+// readStartIndex 读取特征文件头部的起始下标
+func readStartIndex(file *os.File) (int, error) {
+	head := make([]byte, 4)
+	if _, err := file.ReadAt(head, 0); err != nil {
+		return 0, fmt.Errorf("读取特征文件头失败: %v", err)
+	}
+	return int(math.Float32frombits(binary.LittleEndian.Uint32(head))), nil
+}
+
+// parseQlibTime 解析Qlib数据文件中的日期或时间
+func parseQlibTime(value string) (time.Time, error) {
+	layouts := []string{"2006-01-02", "2006-01-02 15:04:05", "2006-01-02 15:04", "20060102"}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析日期: %s", value)
+}
+
+func normalizeFreq(freq string) string {
+	if freq == "" {
+		return "day"
+	}
+	return strings.ToLower(freq)
+}
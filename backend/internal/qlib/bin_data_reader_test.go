@@ -0,0 +1,210 @@
+package qlib
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestBin 按Qlib格式写入特征文件：首个float32为起始下标
+func writeTestBin(t *testing.T, path string, start int, values []float32) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	buf := make([]byte, 4*(len(values)+1))
+	binary.LittleEndian.PutUint32(buf, math.Float32bits(float32(start)))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(buf[4*(i+1):], math.Float32bits(v))
+	}
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+}
+
+// newTestQlibDir 构造一个包含两只股票、五个交易日的Qlib数据目录
+func newTestQlibDir(t *testing.T) string {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "calendars", "day.txt"),
+		"2023-01-03\n2023-01-04\n2023-01-05\n2023-01-06\n2023-01-09\n")
+	writeTestFile(t, filepath.Join(dir, "instruments", "all.txt"),
+		"SH600000\t2023-01-03\t2023-01-09\nSZ000001\t2023-01-05\t2023-01-09\n")
+	writeTestFile(t, filepath.Join(dir, "instruments", "csi300.txt"),
+		"SH600000\t2023-01-03\t2023-01-04\nSH600000\t2023-01-06\t2023-01-09\n")
+
+	nan := float32(math.NaN())
+	writeTestBin(t, filepath.Join(dir, "features", "sh600000", "close.day.bin"), 0, []float32{10, 11, nan, 12, 13})
+	writeTestBin(t, filepath.Join(dir, "features", "sh600000", "open.day.bin"), 0, []float32{9.5, 10.5, nan, 11.5, 12.5})
+	writeTestBin(t, filepath.Join(dir, "features", "sz000001", "close.day.bin"), 2, []float32{20, 21, 22})
+	return dir
+}
+
+func TestBinDataReader(t *testing.T) {
+	dir := newTestQlibDir(t)
+	reader := NewBinDataReader(dir)
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+
+	if !IsQlibDataDir(dir) {
+		t.Error("Directory should be recognized as Qlib data")
+	}
+
+	t.Run("Calendar", func(t *testing.T) {
+		calendar, err := reader.Calendar("day")
+		if err != nil {
+			t.Fatalf("Calendar failed: %v", err)
+		}
+		if len(calendar) != 5 || !calendar[4].Equal(date("2023-01-09")) {
+			t.Errorf("Unexpected calendar: %v", calendar)
+		}
+	})
+
+	t.Run("Instruments", func(t *testing.T) {
+		spans, err := reader.Instruments("csi300")
+		if err != nil {
+			t.Fatalf("Instruments failed: %v", err)
+		}
+		if len(spans) != 2 || spans[1].Symbol != "SH600000" || !spans[1].Start.Equal(date("2023-01-06")) {
+			t.Errorf("Unexpected spans: %+v", spans)
+		}
+
+		symbols, err := reader.ListInstruments("all", date("2023-01-03"), date("2023-01-04"))
+		if err != nil {
+			t.Fatalf("ListInstruments failed: %v", err)
+		}
+		if len(symbols) != 1 || symbols[0] != "SH600000" {
+			t.Errorf("Only SH600000 is listed before 2023-01-05, got %v", symbols)
+		}
+
+		markets, _ := reader.Markets()
+		if len(markets) != 2 || markets[0] != "all" {
+			t.Errorf("Unexpected markets: %v", markets)
+		}
+	})
+
+	t.Run("ReadFeature", func(t *testing.T) {
+		values, err := reader.ReadFeature("SZ000001", "$close", "day", 1, 4)
+		if err != nil {
+			t.Fatalf("ReadFeature failed: %v", err)
+		}
+		assertSeries(t, "SZ000001.close", values, []float64{math.NaN(), 20, 21, 22})
+
+		missing, err := reader.ReadFeature("SZ000001", "$open", "day", 0, 2)
+		if err != nil {
+			t.Fatalf("Reading a missing field should return NaN, got error: %v", err)
+		}
+		assertSeries(t, "SZ000001.open", missing, []float64{math.NaN(), math.NaN(), math.NaN()})
+
+		fields, _ := reader.Fields("SH600000", "day")
+		if len(fields) != 2 || fields[0] != "$close" {
+			t.Errorf("Unexpected fields: %v", fields)
+		}
+	})
+
+	t.Run("LoadFrame", func(t *testing.T) {
+		frame, err := reader.LoadFrame(context.Background(), FrameRequest{
+			Fields:   []string{"$close"},
+			Start:    date("2023-01-05"),
+			End:      date("2023-01-06"),
+			Lookback: 1,
+		})
+		if err != nil {
+			t.Fatalf("LoadFrame failed: %v", err)
+		}
+		if len(frame.Calendar) != 3 || len(frame.Instruments) != 2 {
+			t.Fatalf("Unexpected frame shape: %d days, %v", len(frame.Calendar), frame.Instruments)
+		}
+		close2, _ := frame.Series("SZ000001", "$close")
+		assertSeries(t, "frame.SZ000001", close2, []float64{math.NaN(), 20, 21})
+	})
+
+	t.Run("DataLoader", func(t *testing.T) {
+		loader := NewDataLoader(NewQlibClient())
+		loader.SetBinDataReader(reader)
+
+		data, err := loader.GetMarketData(context.Background(), "SH600000", date("2023-01-03"), date("2023-01-09"))
+		if err != nil {
+			t.Fatalf("GetMarketData failed: %v", err)
+		}
+		// 2023-01-05 停牌，收盘价缺失，应被跳过
+		if len(data) != 4 || data[2].Date != "2023-01-06" || data[2].Close != 12 {
+			t.Errorf("Unexpected market data: %+v", data)
+		}
+
+		start, end, err := loader.GetDataRange(context.Background(), "SZ000001")
+		if err != nil {
+			t.Fatalf("GetDataRange failed: %v", err)
+		}
+		if !start.Equal(date("2023-01-05")) || !end.Equal(date("2023-01-09")) {
+			t.Errorf("Unexpected data range: %v - %v", start, end)
+		}
+	})
+
+	t.Run("Constructors", func(t *testing.T) {
+		// 客户端以Qlib数据目录初始化后，加载器默认读取本地数据
+		client := NewQlibClient()
+		client.dataDir = dir
+		symbols, err := NewDataLoader(client).GetInstrumentList(context.Background(), "csi300")
+		if err != nil {
+			t.Fatalf("GetInstrumentList failed: %v", err)
+		}
+		if len(symbols) != 1 || symbols[0] != "SH600000" {
+			t.Errorf("Unexpected instruments: %v", symbols)
+		}
+		if NewDataLoader(NewQlibClient()).reader != nil {
+			t.Error("Loader without a Qlib data directory should not read local data")
+		}
+
+		instruments, err := NewDataInterface("", "", dir).GetInstruments("all")
+		if err != nil {
+			t.Fatalf("GetInstruments failed: %v", err)
+		}
+		if len(instruments) != 2 {
+			t.Errorf("Unexpected instruments: %+v", instruments)
+		}
+		if NewDataInterface("", "", t.TempDir()).reader != nil {
+			t.Error("Interface without a Qlib data directory should not read local data")
+		}
+	})
+}
+
+func TestBinDataReaderRejectsPathTraversal(t *testing.T) {
+	dir := newTestQlibDir(t)
+	// 数据目录之外的文件
+	writeTestFile(t, filepath.Join(filepath.Dir(dir), "secret.txt"), "SH600000\t2023-01-03\t2023-01-09\n")
+	writeTestBin(t, filepath.Join(dir, "secret.day.bin"), 0, []float32{1, 2, 3})
+	reader := NewBinDataReader(dir)
+
+	if _, err := reader.Instruments("../../secret"); err == nil || !strings.Contains(err.Error(), "无效的股票池") {
+		t.Errorf("Market with '..' should be rejected, got %v", err)
+	}
+	if _, err := reader.ReadFeature("../..", "$secret", "day", 0, 2); err == nil {
+		t.Error("Instrument with '..' should be rejected")
+	}
+	if _, err := reader.ReadFeature("SH600000", "$../../../secret", "day", 0, 2); err == nil {
+		t.Error("Field with path separators should be rejected")
+	}
+	if _, err := reader.Fields("sh600000/../..", "day"); err == nil {
+		t.Error("Instrument with path separators should be rejected")
+	}
+	if _, err := reader.Calendar("../instruments/all"); err == nil {
+		t.Error("Frequency with path separators should be rejected")
+	}
+}
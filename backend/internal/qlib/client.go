@@ -6,9 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"time"
 )
 
 // QlibClient 封装了对Qlib Python库的调用
@@ -17,7 +15,11 @@ type QlibClient struct {
 	scriptDir    string
 	dataProvider string
 	region       string
+	dataDir      string
 	initialized  bool
+	client       Client
+
+	allowedFunctions map[string]bool // AllowQlibFunction 登记的 模块:函数
 }
 
 // QlibConfig Qlib配置结构
@@ -64,22 +66,11 @@ func NewQlibClient() *QlibClient {
 func (c *QlibClient) Initialize(ctx context.Context, config QlibConfig) error {
 	log.Printf("正在初始化Qlib环境...")
 
-	// 确保脚本目录存在
-	if err := os.MkdirAll(c.scriptDir, 0755); err != nil {
-		return fmt.Errorf("创建脚本目录失败: %w", err)
-	}
-
-	// 生成初始化脚本
-	initScript := c.generateInitScript(config)
-	scriptPath := filepath.Join(c.scriptDir, "init_qlib.py")
-
-	if err := os.WriteFile(scriptPath, []byte(initScript), 0644); err != nil {
-		return fmt.Errorf("写入初始化脚本失败: %w", err)
-	}
-
-	// 执行初始化脚本
-	cmd := exec.CommandContext(ctx, c.pythonPath, scriptPath)
-	output, err := cmd.CombinedOutput()
+	// 初始化参数以JSON传给固定的入口脚本，不拼接进脚本源码
+	output, err := c.runEntryPoint(ctx, "init_qlib", map[string]interface{}{
+		"config":      config,
+		"python_path": os.Getenv("PYTHONPATH"),
+	})
 	if err != nil {
 		return fmt.Errorf("执行Qlib初始化失败: %w, 输出: %s", err, string(output))
 	}
@@ -97,125 +88,36 @@ func (c *QlibClient) Initialize(ctx context.Context, config QlibConfig) error {
 
 	c.dataProvider = config.Provider
 	c.region = config.Region
+	c.dataDir = config.DataDir
 	c.initialized = true
 
 	log.Printf("Qlib环境初始化成功")
 	return nil
 }
 
-// generateInitScript 生成Qlib初始化脚本
-func (c *QlibClient) generateInitScript(config QlibConfig) string {
-	return fmt.Sprintf(`
-import json
-import sys
-import os
-sys.path.append('%s')
-
-try:
-	import qlib
-	from qlib.config import REG_CN, REG_US
-	
-	# 设置数据目录
-	data_dir = '%s'
-	if not data_dir:
-		data_dir = '~/.qlib/qlib_data/%s'
-	
-	# 设置区域配置
-	region = REG_CN if '%s' == 'cn' else REG_US
-	
-	# 初始化qlib
-	qlib.init(
-		provider_uri=data_dir,
-		region=region,
-		dataset_cache=None,
-		auto_mount=%s,
-		exp_manager={
-			'class': 'MLflowExpManager',
-			'module_path': 'qlib.workflow.expm',
-			'kwargs': {
-				'uri': 'file://mlruns',
-				'default_exp_name': '%s'
-			}
-		} if '%s' else None,
-		redis_host='%s' if '%s' else None,
-		redis_port=%d if '%s' else None,
-		mongo_host='%s' if '%s' else None,
-		mongo_port=%d if '%s' else None
-	)
-	
-	result = {'success': True, 'message': 'Qlib initialized successfully'}
-	
-except Exception as e:
-	result = {'success': False, 'message': str(e)}
-
-print(json.dumps(result))
-`, 
-		os.Getenv("PYTHONPATH"),
-		config.DataDir, config.Region,
-		config.Region,
-		fmt.Sprintf("%t", config.Mount),
-		config.ExpName, config.ExpName,
-		config.RedisHost, config.RedisHost, config.RedisPort, config.RedisHost,
-		config.MongoHost, config.MongoHost, config.MongoPort, config.MongoHost)
-}
-
 // ExecuteScript 执行Python脚本并返回结果
 func (c *QlibClient) ExecuteScript(ctx context.Context, scriptContent string) ([]byte, error) {
 	if !c.initialized {
 		return nil, fmt.Errorf("Qlib客户端未初始化")
 	}
 
-	// 创建临时脚本文件
-	timestamp := time.Now().Unix()
-	scriptPath := filepath.Join(c.scriptDir, fmt.Sprintf("temp_script_%d.py", timestamp))
-
-	if err := os.WriteFile(scriptPath, []byte(scriptContent), 0644); err != nil {
-		return nil, fmt.Errorf("写入脚本文件失败: %w", err)
-	}
-	defer os.Remove(scriptPath)
-
-	// 执行脚本
-	cmd := exec.CommandContext(ctx, c.pythonPath, scriptPath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("执行Python脚本失败: %w, 输出: %s", err, string(output))
-	}
-
-	return output, nil
+	return resolvePythonClient(c.client, c.pythonPath).Run(ctx, scriptContent, nil)
 }
 
 // CallQlibFunction 调用Qlib函数
+//
+// 只允许调用 DefaultQlibFunctions 和 AllowQlibFunction 登记的函数，params 作为关键字参数传入。
 func (c *QlibClient) CallQlibFunction(ctx context.Context, modulePath, functionName string, params map[string]interface{}) ([]byte, error) {
-	paramsJson, _ := json.Marshal(params)
-	
-	script := fmt.Sprintf(`
-import json
-import sys
-sys.path.append('%s')
-
-try:
-	from %s import %s
-	
-	# 解析参数
-	params = json.loads('%s')
-	
-	# 调用函数
-	result = %s(**params)
-	
-	# 序列化结果
-	output = {'success': True, 'data': result}
-	
-except Exception as e:
-	output = {'success': False, 'error': str(e)}
-
-print(json.dumps(output, default=str))
-`, 
-		os.Getenv("PYTHONPATH"),
-		modulePath, functionName, 
-		string(paramsJson), 
-		functionName)
+	if !c.qlibFunctionAllowed(modulePath, functionName) {
+		return nil, fmt.Errorf("不允许调用的Qlib函数: %s.%s", modulePath, functionName)
+	}
 
-	return c.ExecuteScript(ctx, script)
+	return c.RunEntryPoint(ctx, "call_function", map[string]interface{}{
+		"module":      modulePath,
+		"function":    functionName,
+		"kwargs":      params,
+		"python_path": os.Getenv("PYTHONPATH"),
+	})
 }
 
 // IsInitialized 检查客户端是否已初始化
@@ -233,6 +135,16 @@ func (c *QlibClient) GetRegion() string {
 	return c.region
 }
 
+// GetDataDir 获取初始化时使用的数据目录
+func (c *QlibClient) GetDataDir() string {
+	return c.dataDir
+}
+
+// SetPythonClient 设置执行脚本的客户端，未设置时使用全局默认客户端
+func (c *QlibClient) SetPythonClient(client Client) {
+	c.client = client
+}
+
 // SetPythonPath 设置Python路径
 func (c *QlibClient) SetPythonPath(path string) {
 	c.pythonPath = path
@@ -3,29 +3,30 @@ package qlib
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
-
-	"qlib-backend/internal/testutils"
 )
 
-func TestQlibClient(t *testing.T) {
-	testutils.SetupTestEnv()
-	defer testutils.CleanupTestEnv()
+// lastScript 返回记录的最后一个脚本
+func (c *recordingClient) lastScript() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.scripts) == 0 {
+		return ""
+	}
+	return c.scripts[len(c.scripts)-1]
+}
 
-	// 创建模拟的Qlib客户端
-	client := testutils.NewMockQlibClient()
+func TestQlibClient(t *testing.T) {
+	// 脚本交给注入的Python客户端执行
+	python := &recordingClient{output: `{"success": true, "message": "Test successful"}`}
+	client := newEntryPointClient(python)
 
 	t.Run("ClientInitialization", func(t *testing.T) {
 		// 测试客户端初始化状态
 		if !client.IsInitialized() {
-			t.Error("Mock client should be initialized")
-		}
-
-		// 测试重置功能
-		client.Reset()
-		if !client.IsInitialized() {
-			t.Error("Client should remain initialized after reset")
+			t.Error("Client should be initialized")
 		}
 	})
 
@@ -40,18 +41,18 @@ import json
 print(json.dumps(result))
 `
 
-		result, err := client.ExecuteScript(script)
+		result, err := client.ExecuteScript(ctx, script)
 		if err != nil {
 			t.Errorf("Script execution failed: %v", err)
 		}
 
-		if result == nil {
-			t.Error("Script result should not be nil")
+		parsed, err := ParseQlibOutput(result)
+		if err != nil || parsed["success"] != true {
+			t.Errorf("Script result should be parsed, got %v, %v", parsed, err)
 		}
 
 		// 验证脚本调用记录
-		lastCall := client.GetLastScriptCall()
-		if lastCall != script {
+		if python.lastScript() != script {
 			t.Error("Last script call should match executed script")
 		}
 	})
@@ -59,47 +60,46 @@ print(json.dumps(result))
 	t.Run("MultipleScriptExecution", func(t *testing.T) {
 		scripts := []string{
 			"print('Script 1')",
-			"print('Script 2')", 
+			"print('Script 2')",
 			"print('Script 3')",
 		}
 
 		for i, script := range scripts {
-			_, err := client.ExecuteScript(script)
+			_, err := client.ExecuteScript(context.Background(), script)
 			if err != nil {
 				t.Errorf("Script %d execution failed: %v", i+1, err)
 			}
 		}
 
 		// 验证最后执行的脚本
-		lastCall := client.GetLastScriptCall()
-		if lastCall != scripts[len(scripts)-1] {
+		if python.lastScript() != scripts[len(scripts)-1] {
 			t.Error("Last script call should match the final script")
 		}
 	})
 
 	t.Run("ErrorHandling", func(t *testing.T) {
 		// 测试客户端在未初始化状态下的行为
-		uninitializedClient := &testutils.MockQlibClient{
-			Initialized: false,
-		}
+		uninitialized := NewQlibClient()
+		uninitialized.SetPythonClient(python)
+		calls := len(python.scripts)
 
-		_, err := uninitializedClient.ExecuteScript("print('test')")
+		_, err := uninitialized.ExecuteScript(context.Background(), "print('test')")
 		if err == nil {
 			t.Error("Should return error when client is not initialized")
 		}
+		if len(python.scripts) != calls {
+			t.Error("Uninitialized client should not run scripts")
+		}
 	})
 }
 
 func TestQlibClientIntegration(t *testing.T) {
-	testutils.SetupTestEnv()
-	defer testutils.CleanupTestEnv()
-
 	// 创建真实的Qlib客户端用于集成测试
 	client := NewQlibClient()
 
 	t.Run("ClientCreation", func(t *testing.T) {
 		if client == nil {
-			t.Error("Client should not be nil")
+			t.Fatal("Client should not be nil")
 		}
 
 		// 测试初始状态
@@ -109,7 +109,7 @@ func TestQlibClientIntegration(t *testing.T) {
 	})
 
 	t.Run("ConfigurationValidation", func(t *testing.T) {
-		// 测试不同的配置参数
+		// 测试不同的配置参数，初始化成功后记录数据提供商和地区
 		testConfigs := []QlibConfig{
 			{
 				Provider: "yahoo",
@@ -128,70 +128,58 @@ func TestQlibClientIntegration(t *testing.T) {
 
 		for i, config := range testConfigs {
 			t.Run(fmt.Sprintf("Config%d", i+1), func(t *testing.T) {
-				// 验证配置参数
-				if config.Provider == "" {
-					t.Error("Provider should not be empty")
-				}
+				python := &recordingClient{output: `{"success": true, "message": "ok"}`}
+				c := NewQlibClient()
+				c.SetPythonClient(python)
 
-				if config.Region == "" {
-					t.Error("Region should not be empty")
+				if err := c.Initialize(context.Background(), config); err != nil {
+					t.Fatalf("Initialize failed: %v", err)
 				}
-
-				validProviders := []string{"yahoo", "baostock", "tushare"}
-				isValidProvider := false
-				for _, validProvider := range validProviders {
-					if config.Provider == validProvider {
-						isValidProvider = true
-						break
-					}
+				if !c.IsInitialized() {
+					t.Error("Client should be initialized")
 				}
-
-				if !isValidProvider {
-					t.Errorf("Invalid provider: %s", config.Provider)
+				if c.GetDataProvider() != config.Provider || c.GetRegion() != config.Region {
+					t.Errorf("Provider/region = %s/%s, want %s/%s", c.GetDataProvider(), c.GetRegion(), config.Provider, config.Region)
 				}
 
-				validRegions := []string{"cn", "us"}
-				isValidRegion := false
-				for _, validRegion := range validRegions {
-					if config.Region == validRegion {
-						isValidRegion = true
-						break
-					}
+				// 配置通过标准输入传给初始化脚本，不拼接进脚本源码
+				params := decodeEntryPointInput(t, python.inputs[0])
+				passed, _ := params["config"].(map[string]interface{})
+				if passed["data_dir"] != config.DataDir {
+					t.Errorf("Config data_dir should be passed as input, got %v", passed["data_dir"])
 				}
-
-				if !isValidRegion {
-					t.Errorf("Invalid region: %s", config.Region)
+				if strings.Contains(python.scripts[0], config.DataDir) {
+					t.Error("Config should not be embedded in script source")
 				}
 			})
 		}
 	})
 
-	t.Run("ScriptGeneration", func(t *testing.T) {
-		// 测试脚本生成功能
-		config := QlibConfig{
-			Provider: "yahoo",
-			Region:   "us",
-			DataDir:  "/tmp/test_data",
-			Mount:    true,
-			ExpName:  "test_experiment",
-		}
+	t.Run("InitializationFailure", func(t *testing.T) {
+		c := NewQlibClient()
+		c.SetPythonClient(&recordingClient{output: `{"success": false, "message": "data dir missing"}`})
 
-		// 由于generateInitScript是私有方法，我们通过其他方式测试
-		// 这里测试配置的合理性
-		if config.DataDir == "" {
-			t.Error("Data directory should be specified")
+		err := c.Initialize(context.Background(), QlibConfig{Provider: "yahoo", Region: "us"})
+		if err == nil || !strings.Contains(err.Error(), "data dir missing") {
+			t.Errorf("Initialize should report script failure, got %v", err)
 		}
-
-		if config.ExpName == "" && config.Mount {
-			t.Error("Experiment name should be specified when mounting is enabled")
+		if c.IsInitialized() {
+			t.Error("Client should not be initialized after failure")
 		}
 	})
 
 	t.Run("EnvironmentVariables", func(t *testing.T) {
 		// 测试环境变量的处理
+		t.Setenv("QLIB_PYTHON_PATH", "/custom/env/python")
+		t.Setenv("QLIB_SCRIPT_DIR", "/custom/env/scripts")
+		fromEnv := NewQlibClient()
+		if fromEnv.pythonPath != "/custom/env/python" || fromEnv.scriptDir != "/custom/env/scripts" {
+			t.Errorf("Client should read paths from environment, got %s, %s", fromEnv.pythonPath, fromEnv.scriptDir)
+		}
+
 		originalPath := client.pythonPath
 		client.SetPythonPath("/custom/python/path")
-		
+
 		if client.pythonPath != "/custom/python/path" {
 			t.Error("Python path should be updated")
 		}
@@ -201,7 +189,7 @@ func TestQlibClientIntegration(t *testing.T) {
 
 		originalScriptDir := client.scriptDir
 		client.SetScriptDir("/custom/script/dir")
-		
+
 		if client.scriptDir != "/custom/script/dir" {
 			t.Error("Script directory should be updated")
 		}
@@ -212,10 +200,8 @@ func TestQlibClientIntegration(t *testing.T) {
 }
 
 func TestQlibClientPerformance(t *testing.T) {
-	testutils.SetupTestEnv()
-	defer testutils.CleanupTestEnv()
-
-	client := testutils.NewMockQlibClient()
+	python := &recordingClient{output: `{"success": true}`}
+	client := newEntryPointClient(python)
 
 	t.Run("ConcurrentScriptExecution", func(t *testing.T) {
 		ctx := context.Background()
@@ -227,7 +213,7 @@ func TestQlibClientPerformance(t *testing.T) {
 				defer func() { done <- true }()
 
 				script := fmt.Sprintf("print('Concurrent script %d')", index)
-				_, err := client.ExecuteScript(script)
+				_, err := client.ExecuteScript(ctx, script)
 				if err != nil {
 					t.Errorf("Concurrent script %d failed: %v", index, err)
 				}
@@ -248,14 +234,14 @@ func TestQlibClientPerformance(t *testing.T) {
 
 	t.Run("ScriptExecutionLatency", func(t *testing.T) {
 		script := "print('Performance test')"
-		
-		// 测试多次执行的延迟
+
+		// 测试多次执行的延迟，不含Python解释器本身的开销
 		totalDuration := time.Duration(0)
 		iterations := 100
 
 		for i := 0; i < iterations; i++ {
 			start := time.Now()
-			_, err := client.ExecuteScript(script)
+			_, err := client.ExecuteScript(context.Background(), script)
 			duration := time.Since(start)
 			totalDuration += duration
 
@@ -271,66 +257,42 @@ func TestQlibClientPerformance(t *testing.T) {
 			t.Errorf("Average script execution time too high: %v (max: %v)", avgDuration, maxAcceptableDuration)
 		}
 	})
-
-	t.Run("MemoryUsage", func(t *testing.T) {
-		// 测试内存使用情况（通过多次执行大脚本）
-		largeScript := `
-import numpy as np
-import pandas as pd
-
-# 创建大数据集进行测试
-data = np.random.randn(1000, 100)
-df = pd.DataFrame(data)
-result = df.describe()
-print("Memory test completed")
-`
-
-		for i := 0; i < 10; i++ {
-			_, err := client.ExecuteScript(largeScript)
-			if err != nil {
-				t.Errorf("Large script execution %d failed: %v", i+1, err)
-			}
-		}
-
-		// 验证客户端状态仍然正常
-		if !client.IsInitialized() {
-			t.Error("Client should remain initialized after memory intensive operations")
-		}
-	})
 }
 
 func TestQlibClientEdgeCases(t *testing.T) {
-	testutils.SetupTestEnv()
-	defer testutils.CleanupTestEnv()
-
 	t.Run("EmptyScript", func(t *testing.T) {
-		client := testutils.NewMockQlibClient()
-		
-		_, err := client.ExecuteScript("")
+		client := newEntryPointClient(&recordingClient{})
+
+		_, err := client.ExecuteScript(context.Background(), "")
 		if err != nil {
 			t.Errorf("Empty script should not cause error: %v", err)
 		}
 	})
 
 	t.Run("VeryLongScript", func(t *testing.T) {
-		client := testutils.NewMockQlibClient()
-		
+		python := &recordingClient{}
+		client := newEntryPointClient(python)
+
 		// 创建很长的脚本
-		longScript := "print('Long script')\n" 
+		longScript := "print('Long script')\n"
 		for i := 0; i < 1000; i++ {
 			longScript += fmt.Sprintf("# Comment line %d\n", i)
 		}
 		longScript += "print('End of long script')"
 
-		_, err := client.ExecuteScript(longScript)
+		_, err := client.ExecuteScript(context.Background(), longScript)
 		if err != nil {
 			t.Errorf("Long script execution failed: %v", err)
 		}
+		if python.lastScript() != longScript {
+			t.Error("Long script should be passed unchanged")
+		}
 	})
 
 	t.Run("ScriptWithSpecialCharacters", func(t *testing.T) {
-		client := testutils.NewMockQlibClient()
-		
+		python := &recordingClient{}
+		client := newEntryPointClient(python)
+
 		// 测试包含特殊字符的脚本
 		specialScript := `
 # 测试中文注释
@@ -342,28 +304,31 @@ import json
 print(json.dumps(result, ensure_ascii=False))
 `
 
-		_, err := client.ExecuteScript(specialScript)
+		_, err := client.ExecuteScript(context.Background(), specialScript)
 		if err != nil {
 			t.Errorf("Script with special characters failed: %v", err)
 		}
+		if python.lastScript() != specialScript {
+			t.Error("Script with special characters should be passed unchanged")
+		}
 	})
 
 	t.Run("ClientStateConsistency", func(t *testing.T) {
-		client := testutils.NewMockQlibClient()
-		
+		client := newEntryPointClient(&recordingClient{})
+
 		// 验证客户端状态在多次操作后保持一致
 		initialState := client.IsInitialized()
-		
+
 		// 执行多种操作
 		scripts := []string{
 			"print('Test 1')",
-			"",  // 空脚本
+			"", // 空脚本
 			"# Just a comment",
 			"print('Final test')",
 		}
 
 		for _, script := range scripts {
-			client.ExecuteScript(script)
+			client.ExecuteScript(context.Background(), script)
 		}
 
 		// 验证状态一致性
@@ -371,11 +336,13 @@ print(json.dumps(result, ensure_ascii=False))
 			t.Error("Client state should remain consistent after operations")
 		}
 
-		// 重置后再次验证
-		client.Reset()
-		if !client.IsInitialized() {
-			t.Error("Client should be initialized after reset")
+		// 关闭后不再执行脚本
+		client.SetScriptDir(t.TempDir())
+		if err := client.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+		if client.IsInitialized() {
+			t.Error("Client should not be initialized after close")
 		}
 	})
 }
-
@@ -0,0 +1,332 @@
+package qlib
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// 复合因子的加权方式
+const (
+	CompositeEqual   = "equal"    // 等权
+	CompositeIC      = "ic"       // 按RankIC均值加权
+	CompositeICIR    = "icir"     // 按RankICIR加权
+	CompositeMaxICIR = "max_icir" // 收缩协方差下最大化组合ICIR
+)
+
+// compositeICLag 1日未来收益在因子日之后第2个交易日收盘才实现，滚动估计时只能使用此前的IC
+const compositeICLag = 2
+
+// CompositeOptions 复合因子参数
+type CompositeOptions struct {
+	Weighting string `json:"weighting"` // 默认 equal
+	// 滚动估计窗口（交易日），0表示用全部样本估计一组静态权重
+	Window int `json:"window"`
+	// max_icir 的协方差收缩强度，取值0-1，0表示按Ledoit-Wolf方法估计
+	Shrinkage float64 `json:"shrinkage"`
+	ICOptions `json:"-"`
+}
+
+func (o CompositeOptions) withDefaults() (CompositeOptions, error) {
+	if o.Weighting == "" {
+		o.Weighting = CompositeEqual
+	}
+	switch o.Weighting {
+	case CompositeEqual, CompositeIC, CompositeICIR, CompositeMaxICIR:
+	default:
+		return o, fmt.Errorf("不支持的加权方式: %s", o.Weighting)
+	}
+	if o.Window < 0 || o.Shrinkage < 0 || o.Shrinkage > 1 {
+		return o, fmt.Errorf("滚动窗口不能为负，收缩强度应在0-1之间")
+	}
+	o.ICOptions = o.ICOptions.withDefaults()
+	return o, nil
+}
+
+// Validate 检查加权方式、滚动窗口和收缩强度
+func (o CompositeOptions) Validate() error {
+	_, err := o.withDefaults()
+	return err
+}
+
+// CompositeResult 复合因子计算结果
+type CompositeResult struct {
+	Frame *FactorFrame
+	// 最后一个交易日使用的成分权重，静态加权时即为全样本权重，绝对值之和为1
+	Weights []float64
+	// 各成分在估计期内的RankIC均值，等权时为空
+	ComponentIC []float64
+}
+
+// BuildComposite 将各成分因子逐日截面标准化后按权重合成复合因子
+//
+// returns 为与因子同日对齐的1日未来收益，equal 以外的加权方式用它估计各成分的RankIC。
+// 滚动估计时第 t 日只使用 t-2 日及以前已实现的IC，估计期内有效IC少于窗口一半的交易日复合因子为 NaN；
+// 静态加权使用全部样本，存在前视偏差，仅适合研究阶段比较。
+// 所有成分按第一个成分的日历和证券对齐。
+func BuildComposite(ctx context.Context, components []*FactorFrame, returns *FactorFrame, opts CompositeOptions) (*CompositeResult, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	if len(components) < 2 {
+		return nil, fmt.Errorf("复合因子至少需要2个成分因子")
+	}
+	if opts.Weighting != CompositeEqual && returns == nil {
+		return nil, fmt.Errorf("%s 加权需要未来收益", opts.Weighting)
+	}
+
+	base := components[0]
+	n, days := len(components), len(base.Calendar)
+	scores := make([][][]float64, n)
+	for c, frame := range components {
+		scores[c] = alignFrame(base, frame)
+		for t, values := range scores[c] {
+			scores[c][t] = csZScore(values)
+		}
+	}
+
+	// ics[c][t] 为第 c 个成分在第 t 日的RankIC
+	ics := make([][]float64, n)
+	for c := range ics {
+		ics[c] = nanSeries(days)
+	}
+	if opts.Weighting != CompositeEqual {
+		ret := alignFrame(base, returns)
+		err := parallelDates(ctx, days, opts.Workers, func(t int) {
+			for c := range components {
+				x := make([]float64, 0, len(base.Instruments))
+				y := make([]float64, 0, len(base.Instruments))
+				for i := range base.Instruments {
+					if z, r := scores[c][t][i], ret[t][i]; !math.IsNaN(z) && !math.IsNaN(r) {
+						x = append(x, z)
+						y = append(y, r)
+					}
+				}
+				_, ics[c][t] = crossSectionCorr(x, y, opts.MinSamples)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &CompositeResult{
+		Frame: &FactorFrame{Calendar: base.Calendar, Instruments: base.Instruments, Values: make([][]float64, len(base.Instruments))},
+	}
+	for i := range result.Frame.Values {
+		result.Frame.Values[i] = nanSeries(days)
+	}
+	var weights, componentIC []float64
+	if opts.Window == 0 || opts.Weighting == CompositeEqual {
+		weights, componentIC = compositeWeights(ics, 0, days, opts)
+	}
+	for t := 0; t < days; t++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if opts.Window > 0 && opts.Weighting != CompositeEqual {
+			weights, componentIC = compositeWeights(ics, t-compositeICLag-opts.Window+1, t-compositeICLag+1, opts)
+		}
+		if weights == nil {
+			continue
+		}
+		result.Weights, result.ComponentIC = weights, componentIC
+		for i := range base.Instruments {
+			sum, total := 0.0, 0.0
+			for c, w := range weights {
+				if z := scores[c][t][i]; !math.IsNaN(z) {
+					sum += w * z
+					total += math.Abs(w)
+				}
+			}
+			if total > 0 {
+				result.Frame.Values[i][t] = sum / total
+			}
+		}
+	}
+	if result.Weights == nil {
+		return nil, fmt.Errorf("有效IC样本不足，无法估计复合因子权重")
+	}
+	return result, nil
+}
+
+// compositeWeights 用第 [from, to) 个交易日的IC估计成分权重，样本不足或权重全为0时返回 nil
+func compositeWeights(ics [][]float64, from, to int, opts CompositeOptions) ([]float64, []float64) {
+	n := len(ics)
+	if from < 0 {
+		from = 0
+	}
+	if opts.Weighting == CompositeEqual {
+		weights := make([]float64, n)
+		for c := range weights {
+			weights[c] = 1 / float64(n)
+		}
+		return weights, nil
+	}
+
+	// 所有成分IC都有效的交易日，协方差需要共同样本
+	var common [][]float64
+	for t := from; t < to; t++ {
+		row := make([]float64, n)
+		valid := true
+		for c := range ics {
+			if row[c] = ics[c][t]; math.IsNaN(row[c]) {
+				valid = false
+				break
+			}
+		}
+		if valid {
+			common = append(common, row)
+		}
+	}
+	minDays := 2
+	if opts.Window > 0 && opts.Window/2 > minDays {
+		minDays = opts.Window / 2
+	}
+	if len(common) < minDays {
+		return nil, nil
+	}
+
+	mean := make([]float64, n)
+	for _, row := range common {
+		for c, v := range row {
+			mean[c] += v / float64(len(common))
+		}
+	}
+	cov := icCovariance(common, mean)
+	weights := make([]float64, n)
+	switch opts.Weighting {
+	case CompositeIC:
+		copy(weights, mean)
+	case CompositeICIR:
+		for c := range weights {
+			if cov[c][c] > 0 {
+				weights[c] = mean[c] / math.Sqrt(cov[c][c])
+			}
+		}
+	case CompositeMaxICIR:
+		shrunk := shrinkCovariance(common, mean, cov, opts.Shrinkage)
+		solved, ok := solveLinear(shrunk, mean)
+		if !ok {
+			// 协方差奇异（IC序列无波动）时退化为按IC加权
+			solved = mean
+		}
+		copy(weights, solved)
+	}
+
+	total := 0.0
+	for _, w := range weights {
+		total += math.Abs(w)
+	}
+	if total == 0 || math.IsNaN(total) || math.IsInf(total, 0) {
+		return nil, nil
+	}
+	for c := range weights {
+		weights[c] /= total
+	}
+	return weights, mean
+}
+
+// icCovariance IC序列的样本协方差矩阵（除以 T）
+func icCovariance(rows [][]float64, mean []float64) [][]float64 {
+	n := len(mean)
+	cov := make([][]float64, n)
+	for a := range cov {
+		cov[a] = make([]float64, n)
+		for b := range cov[a] {
+			for _, row := range rows {
+				cov[a][b] += (row[a] - mean[a]) * (row[b] - mean[b])
+			}
+			cov[a][b] /= float64(len(rows))
+		}
+	}
+	return cov
+}
+
+// shrinkCovariance 将协方差向 m·I 收缩，m 为平均方差；intensity 为0时按Ledoit-Wolf(2004)估计收缩强度
+func shrinkCovariance(rows [][]float64, mean []float64, cov [][]float64, intensity float64) [][]float64 {
+	n := len(cov)
+	m := 0.0
+	for i := range cov {
+		m += cov[i][i] / float64(n)
+	}
+	if intensity == 0 {
+		// d² = ||S - mI||²/n，b² = min(Σ_t ||x_t x_tᵀ - S||² / T² / n, d²)
+		d2 := 0.0
+		for i := range cov {
+			for j := range cov[i] {
+				target := 0.0
+				if i == j {
+					target = m
+				}
+				d2 += (cov[i][j] - target) * (cov[i][j] - target)
+			}
+		}
+		d2 /= float64(n)
+
+		b2 := 0.0
+		for _, row := range rows {
+			for i := range cov {
+				for j := range cov[i] {
+					diff := (row[i]-mean[i])*(row[j]-mean[j]) - cov[i][j]
+					b2 += diff * diff
+				}
+			}
+		}
+		b2 /= float64(len(rows)*len(rows)) * float64(n)
+		if d2 > 0 {
+			intensity = math.Min(b2, d2) / d2
+		} else {
+			intensity = 1
+		}
+	}
+
+	shrunk := make([][]float64, n)
+	for i := range cov {
+		shrunk[i] = make([]float64, n)
+		for j := range cov[i] {
+			shrunk[i][j] = (1 - intensity) * cov[i][j]
+			if i == j {
+				shrunk[i][j] += intensity * m
+			}
+		}
+	}
+	return shrunk
+}
+
+// solveLinear 用部分主元高斯消元求解 Ax = b，矩阵奇异时返回 false
+func solveLinear(a [][]float64, b []float64) ([]float64, bool) {
+	n := len(b)
+	m := make([][]float64, n)
+	for i := range a {
+		m[i] = append(append([]float64{}, a[i]...), b[i])
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return nil, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		for row := col + 1; row < n; row++ {
+			factor := m[row][col] / m[col][col]
+			for k := col; k <= n; k++ {
+				m[row][k] -= factor * m[col][k]
+			}
+		}
+	}
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := m[row][n]
+		for k := row + 1; k < n; k++ {
+			sum -= m[row][k] * x[k]
+		}
+		x[row] = sum / m[row][row]
+	}
+	return x, true
+}
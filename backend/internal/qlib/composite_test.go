@@ -0,0 +1,101 @@
+package qlib
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestBuildComposite(t *testing.T) {
+	calendar := weekdayCalendar(6)
+	a := newTestFactorFrame(calendar, map[string][]float64{
+		"A": {1, 1, 1, 1, 1, 1}, "B": {2, 2, 2, 2, 2, 2}, "C": {3, 3, 3, 3, 3, 3}, "D": {5, 5, 5, 5, 5, 5},
+	})
+	square := newTestFactorFrame(calendar, map[string][]float64{
+		"A": {1, 1, 1, 1, 1, 1}, "B": {4, 4, 4, 4, 4, 4}, "C": {9, 9, 9, 9, 9, 9}, "D": {25, 25, 25, 25, 25, 25},
+	})
+	reverse := newTestFactorFrame(calendar, map[string][]float64{
+		"A": {5, 5, 5, 5, 5, 5}, "B": {3, 3, 3, 3, 3, 3}, "C": {2, 2, 2, 2, 2, 2}, "D": {1, 1, 1, 1, 1, 1},
+	})
+	// 收益排名与 a 一致
+	returns := newTestFactorFrame(calendar, map[string][]float64{
+		"A": {0.01, 0.02, 0.01, 0.02, 0.01, 0.02}, "B": {0.02, 0.03, 0.02, 0.03, 0.02, 0.03},
+		"C": {0.03, 0.05, 0.03, 0.05, 0.03, 0.05}, "D": {0.04, 0.06, 0.05, 0.06, 0.04, 0.06},
+	})
+	ctx := context.Background()
+	za := csZScore([]float64{1, 2, 3, 5})
+	zs := csZScore([]float64{1, 4, 9, 25})
+
+	equal, err := BuildComposite(ctx, []*FactorFrame{a, square}, nil, CompositeOptions{})
+	if err != nil {
+		t.Fatalf("equal composite failed: %v", err)
+	}
+	if got, want := equal.Frame.Values[3][0], (za[3]+zs[3])/2; math.Abs(got-want) > 1e-12 {
+		t.Errorf("equal composite of D = %v, want %v", got, want)
+	}
+
+	// 反向因子的IC为负，权重为负，合成后与 a 同向
+	weighted, err := BuildComposite(ctx, []*FactorFrame{a, reverse}, returns, CompositeOptions{Weighting: CompositeIC, ICOptions: ICOptions{MinSamples: 4}})
+	if err != nil {
+		t.Fatalf("IC weighted composite failed: %v", err)
+	}
+	if math.Abs(weighted.Weights[0]-0.5) > 1e-12 || math.Abs(weighted.Weights[1]+0.5) > 1e-12 {
+		t.Errorf("unexpected IC weights: %v", weighted.Weights)
+	}
+	if weighted.Frame.Values[0][2] >= 0 || weighted.Frame.Values[3][2] <= 0 {
+		t.Errorf("composite should rank like a: %v", weighted.Frame.Values)
+	}
+
+	// 滚动估计只使用两日前已实现的IC，窗口为2时第4个交易日起才有值
+	rolling, err := BuildComposite(ctx, []*FactorFrame{a, reverse}, returns, CompositeOptions{Weighting: CompositeIC, Window: 2, ICOptions: ICOptions{MinSamples: 4}})
+	if err != nil {
+		t.Fatalf("rolling composite failed: %v", err)
+	}
+	if !math.IsNaN(rolling.Frame.Values[0][2]) || math.IsNaN(rolling.Frame.Values[0][3]) {
+		t.Errorf("unexpected rolling composite: %v", rolling.Frame.Values[0])
+	}
+
+	for _, tt := range []struct {
+		components []*FactorFrame
+		returns    *FactorFrame
+		opts       CompositeOptions
+	}{
+		{[]*FactorFrame{a}, nil, CompositeOptions{}},
+		{[]*FactorFrame{a, reverse}, nil, CompositeOptions{Weighting: CompositeICIR}},
+		{[]*FactorFrame{a, reverse}, returns, CompositeOptions{Weighting: "momentum"}},
+		{[]*FactorFrame{a, reverse}, returns, CompositeOptions{Weighting: CompositeMaxICIR, Shrinkage: 2}},
+	} {
+		if _, err := BuildComposite(ctx, tt.components, tt.returns, tt.opts); err == nil {
+			t.Errorf("BuildComposite with %+v should fail", tt.opts)
+		}
+	}
+}
+
+func TestMaxICIRWeights(t *testing.T) {
+	// 两个成分IC均值相同，第二个波动更大，最大化ICIR时权重应更小
+	ics := [][]float64{
+		{0.04, 0.06, 0.05, 0.04, 0.06, 0.05},
+		{0.01, 0.09, 0.05, 0.00, 0.10, 0.05},
+	}
+	weights, mean := compositeWeights(ics, 0, 6, CompositeOptions{Weighting: CompositeMaxICIR})
+	if weights == nil || weights[0] <= weights[1] || math.Abs(math.Abs(weights[0])+math.Abs(weights[1])-1) > 1e-12 {
+		t.Errorf("unexpected max ICIR weights: %v", weights)
+	}
+	if math.Abs(mean[0]-0.05) > 1e-12 {
+		t.Errorf("mean IC = %v, want 0.05", mean[0])
+	}
+
+	// 完全收缩到单位阵时退化为按IC均值加权
+	cov := [][]float64{{1, 0.5}, {0.5, 2}}
+	shrunk := shrinkCovariance(nil, nil, cov, 1)
+	if shrunk[0][0] != 1.5 || shrunk[0][1] != 0 {
+		t.Errorf("unexpected shrunk covariance: %v", shrunk)
+	}
+	x, ok := solveLinear([][]float64{{2, 1}, {1, 3}}, []float64{3, 5})
+	if !ok || math.Abs(x[0]-0.8) > 1e-12 || math.Abs(x[1]-1.4) > 1e-12 {
+		t.Errorf("solveLinear = %v, %v", x, ok)
+	}
+	if _, ok := solveLinear([][]float64{{1, 2}, {2, 4}}, []float64{1, 2}); ok {
+		t.Error("singular matrix should not be solvable")
+	}
+}
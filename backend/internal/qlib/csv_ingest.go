@@ -0,0 +1,518 @@
+package qlib
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVIngestOptions CSV导入参数
+type CSVIngestOptions struct {
+	ColumnMapping map[string]string `json:"column_mapping"` // 目标字段 -> CSV列名，必须包含 date 和 symbol，为空时按列名自动识别
+	DateFormat    string            `json:"date_format"`    // 日期格式，支持 YYYY-MM-DD 风格或Go格式，为空时自动识别
+	Market        string            `json:"market"`         // 写入的股票池名称，默认 all
+	Freq          string            `json:"freq"`           // 数据频率，默认 day
+	MaxRowErrors  int               `json:"max_row_errors"` // 保留的行错误明细上限，默认 1000
+}
+
+// CSVRowError CSV行级错误
+type CSVRowError struct {
+	Line    int    `json:"line"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// CSVIngestResult CSV导入结果
+type CSVIngestResult struct {
+	OutputDir       string        `json:"output_dir"`
+	RecordCount     int64         `json:"record_count"`
+	InstrumentCount int           `json:"instrument_count"`
+	Fields          []string      `json:"fields"`
+	StartDate       string        `json:"start_date"`
+	EndDate         string        `json:"end_date"`
+	ErrorCount      int           `json:"error_count"`
+	RowErrors       []CSVRowError `json:"row_errors"`
+}
+
+// IngestProgressCallback 导入进度回调
+type IngestProgressCallback func(progress int, message string)
+
+var (
+	dateColumnAliases   = []string{"date", "datetime", "trade_date", "tradedate", "time", "日期", "交易日期"}
+	symbolColumnAliases = []string{"symbol", "instrument", "code", "ts_code", "stock_code", "ticker", "代码", "股票代码"}
+	fieldNamePattern    = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+	marketNamePattern   = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// ValidateMarketName 检查股票池名称，名称会作为 instruments 目录下的文件名，只允许字母、数字和下划线
+func ValidateMarketName(market string) error {
+	if !marketNamePattern.MatchString(strings.ToLower(market)) {
+		return fmt.Errorf("无效的股票池名称 %q，只能包含字母、数字和下划线", market)
+	}
+	return nil
+}
+
+// IngestCSV 将CSV行情文件转换为Qlib二进制数据目录（与 dump_bin 的 dump_all 模式一致）
+func IngestCSV(ctx context.Context, csvPath, outputDir string, opts CSVIngestOptions, progress IngestProgressCallback) (*CSVIngestResult, error) {
+	if opts.Market == "" {
+		opts.Market = "all"
+	}
+	if err := ValidateMarketName(opts.Market); err != nil {
+		return nil, err
+	}
+	opts.Freq = normalizeFreq(opts.Freq)
+	if err := checkPathName("频率", opts.Freq); err != nil {
+		return nil, err
+	}
+	if opts.MaxRowErrors <= 0 {
+		opts.MaxRowErrors = 1000
+	}
+	if progress == nil {
+		progress = func(int, string) {}
+	}
+
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开CSV文件失败: %v", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("读取CSV文件信息失败: %v", err)
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取CSV表头失败: %v", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\uFEFF")
+	}
+
+	layout, err := resolveCSVColumns(header, opts.ColumnMapping)
+	if err != nil {
+		return nil, err
+	}
+	dateLayouts := csvDateLayouts(opts.DateFormat)
+
+	result := &CSVIngestResult{OutputDir: outputDir, Fields: layout.fieldNames, RowErrors: []CSVRowError{}}
+	addError := func(line int, column, format string, args ...interface{}) {
+		result.ErrorCount++
+		if len(result.RowErrors) < opts.MaxRowErrors {
+			result.RowErrors = append(result.RowErrors, CSVRowError{Line: line, Column: column, Message: fmt.Sprintf(format, args...)})
+		}
+	}
+
+	type record struct {
+		date   time.Time
+		values []float64
+	}
+	records := make(map[string][]record)
+	seen := make(map[string]map[int64]bool)
+
+	progress(5, "正在解析CSV文件")
+	line := 1
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			addError(line, "", "CSV格式错误: %v", err)
+			continue
+		}
+		if line%10000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if info.Size() > 0 {
+				progress(5+int(75*reader.InputOffset()/info.Size()), fmt.Sprintf("已解析 %d 行", line-1))
+			}
+		}
+		if len(row) == 1 && strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		if len(row) < len(header) {
+			addError(line, "", "列数不足，期望 %d 列，实际 %d 列", len(header), len(row))
+			continue
+		}
+
+		symbol, err := NormalizeSymbol(row[layout.symbolIndex])
+		if err != nil {
+			addError(line, header[layout.symbolIndex], "%v", err)
+			continue
+		}
+		date, err := parseCSVDate(strings.TrimSpace(row[layout.dateIndex]), dateLayouts)
+		if err != nil {
+			addError(line, header[layout.dateIndex], "%v", err)
+			continue
+		}
+
+		values := make([]float64, len(layout.fieldIndexes))
+		valid := true
+		for i, idx := range layout.fieldIndexes {
+			text := strings.TrimSpace(row[idx])
+			if text == "" || strings.EqualFold(text, "nan") || strings.EqualFold(text, "null") {
+				values[i] = math.NaN()
+				continue
+			}
+			v, err := strconv.ParseFloat(strings.ReplaceAll(text, ",", ""), 64)
+			if err != nil {
+				addError(line, header[idx], "无法解析数值: %s", text)
+				valid = false
+				break
+			}
+			if v < 0 && isPriceField(layout.fieldNames[i]) {
+				addError(line, header[idx], "价格不能为负数: %s", text)
+				valid = false
+				break
+			}
+			if v < 0 && isVolumeField(layout.fieldNames[i]) {
+				addError(line, header[idx], "成交量和成交额不能为负数: %s", text)
+				valid = false
+				break
+			}
+			values[i] = v
+		}
+		if !valid {
+			continue
+		}
+		if msg := checkOHLC(layout.fieldNames, values); msg != "" {
+			addError(line, "", "%s", msg)
+			continue
+		}
+
+		if seen[symbol] == nil {
+			seen[symbol] = make(map[int64]bool)
+		}
+		if seen[symbol][date.Unix()] {
+			addError(line, "", "重复记录: %s %s", symbol, date.Format("2006-01-02"))
+			continue
+		}
+		seen[symbol][date.Unix()] = true
+		records[symbol] = append(records[symbol], record{date: date, values: values})
+		result.RecordCount++
+	}
+
+	if result.RecordCount == 0 {
+		return result, fmt.Errorf("CSV文件中没有有效数据，共 %d 个错误", result.ErrorCount)
+	}
+
+	// 构建交易日历：所有记录日期的并集
+	progress(80, "正在生成交易日历")
+	dateSet := make(map[int64]time.Time)
+	for _, recs := range records {
+		for _, rec := range recs {
+			dateSet[rec.date.Unix()] = rec.date
+		}
+	}
+	calendar := make([]time.Time, 0, len(dateSet))
+	for _, date := range dateSet {
+		calendar = append(calendar, date)
+	}
+	sort.Slice(calendar, func(i, j int) bool { return calendar[i].Before(calendar[j]) })
+	calendarIndex := make(map[int64]int, len(calendar))
+	for i, date := range calendar {
+		calendarIndex[date.Unix()] = i
+	}
+
+	writer := NewBinDataWriter(outputDir)
+	if err := writer.WriteCalendar(opts.Freq, calendar); err != nil {
+		return nil, err
+	}
+
+	progress(85, "正在写入特征文件")
+	symbols := make([]string, 0, len(records))
+	for symbol := range records {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	spans := make([]InstrumentSpan, 0, len(symbols))
+	for n, symbol := range symbols {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		recs := records[symbol]
+		sort.Slice(recs, func(i, j int) bool { return recs[i].date.Before(recs[j].date) })
+		first := calendarIndex[recs[0].date.Unix()]
+		last := calendarIndex[recs[len(recs)-1].date.Unix()]
+
+		for f, field := range layout.fieldNames {
+			values := nanSeries(last - first + 1)
+			for _, rec := range recs {
+				values[calendarIndex[rec.date.Unix()]-first] = rec.values[f]
+			}
+			if err := writer.WriteFeature(symbol, field, opts.Freq, first, values); err != nil {
+				return nil, err
+			}
+		}
+		spans = append(spans, InstrumentSpan{Symbol: symbol, Start: calendar[first], End: calendar[last]})
+
+		if n%100 == 0 {
+			progress(85+10*n/len(symbols), fmt.Sprintf("已写入 %d/%d 只证券", n, len(symbols)))
+		}
+	}
+
+	if err := writer.WriteInstruments(opts.Market, spans); err != nil {
+		return nil, err
+	}
+	if opts.Market != "all" {
+		if err := writer.WriteInstruments("all", spans); err != nil {
+			return nil, err
+		}
+	}
+
+	result.InstrumentCount = len(symbols)
+	result.StartDate = calendar[0].Format("2006-01-02")
+	result.EndDate = calendar[len(calendar)-1].Format("2006-01-02")
+	progress(100, "数据导入完成")
+	return result, nil
+}
+
+// csvColumnLayout CSV列布局
+type csvColumnLayout struct {
+	dateIndex    int
+	symbolIndex  int
+	fieldNames   []string
+	fieldIndexes []int
+}
+
+// resolveCSVColumns 根据列映射或列名识别日期、代码和数值字段
+func resolveCSVColumns(header []string, mapping map[string]string) (*csvColumnLayout, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	find := func(column string) (int, bool) {
+		i, ok := index[strings.ToLower(strings.TrimSpace(column))]
+		return i, ok
+	}
+
+	layout := &csvColumnLayout{dateIndex: -1, symbolIndex: -1}
+	if len(mapping) > 0 {
+		fields := make([]string, 0, len(mapping))
+		for target := range mapping {
+			fields = append(fields, target)
+		}
+		sort.Strings(fields)
+		for _, target := range fields {
+			column := mapping[target]
+			i, ok := find(column)
+			if !ok {
+				return nil, fmt.Errorf("列映射 %s -> %s 中的列不存在", target, column)
+			}
+			switch name := strings.ToLower(strings.TrimPrefix(target, "$")); name {
+			case "date":
+				layout.dateIndex = i
+			case "symbol":
+				layout.symbolIndex = i
+			default:
+				if !fieldNamePattern.MatchString(name) {
+					return nil, fmt.Errorf("无效的字段名: %s", target)
+				}
+				layout.fieldNames = append(layout.fieldNames, name)
+				layout.fieldIndexes = append(layout.fieldIndexes, i)
+			}
+		}
+	} else {
+		for _, alias := range dateColumnAliases {
+			if i, ok := find(alias); ok {
+				layout.dateIndex = i
+				break
+			}
+		}
+		for _, alias := range symbolColumnAliases {
+			if i, ok := find(alias); ok {
+				layout.symbolIndex = i
+				break
+			}
+		}
+		for i, column := range header {
+			if i == layout.dateIndex || i == layout.symbolIndex {
+				continue
+			}
+			name := strings.ToLower(strings.TrimSpace(column))
+			if fieldNamePattern.MatchString(name) {
+				layout.fieldNames = append(layout.fieldNames, name)
+				layout.fieldIndexes = append(layout.fieldIndexes, i)
+			}
+		}
+	}
+
+	if layout.dateIndex < 0 {
+		return nil, fmt.Errorf("未找到日期列，请在列映射中指定 date")
+	}
+	if layout.symbolIndex < 0 {
+		return nil, fmt.Errorf("未找到证券代码列，请在列映射中指定 symbol")
+	}
+	if len(layout.fieldNames) == 0 {
+		return nil, fmt.Errorf("未找到数值字段列")
+	}
+	return layout, nil
+}
+
+// csvDateLayouts 将日期格式转换为Go时间格式
+func csvDateLayouts(format string) []string {
+	if format == "" {
+		return []string{"2006-01-02", "2006/01/02", "20060102", "2006-01-02 15:04:05", "2006/1/2", "2006-1-2"}
+	}
+	replacer := strings.NewReplacer("YYYY", "2006", "yyyy", "2006", "MM", "01", "DD", "02", "dd", "02",
+		"HH", "15", "hh", "15", "mm", "04", "ss", "05", "%Y", "2006", "%m", "01", "%d", "02", "%H", "15", "%M", "04", "%S", "05")
+	return []string{replacer.Replace(format)}
+}
+
+func parseCSVDate(value string, layouts []string) (time.Time, error) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析日期: %s", value)
+}
+
+func isPriceField(field string) bool {
+	switch field {
+	case "open", "high", "low", "close", "vwap":
+		return true
+	}
+	return false
+}
+
+func isVolumeField(field string) bool {
+	return field == "volume" || field == "amount"
+}
+
+// checkOHLC 检查价格之间的基本关系
+func checkOHLC(fields []string, values []float64) string {
+	get := func(name string) float64 {
+		for i, field := range fields {
+			if field == name {
+				return values[i]
+			}
+		}
+		return math.NaN()
+	}
+	high, low := get("high"), get("low")
+	if !math.IsNaN(high) && !math.IsNaN(low) && high < low {
+		return fmt.Sprintf("最高价 %.4f 低于最低价 %.4f", high, low)
+	}
+	return ""
+}
+
+var (
+	symbolDigitsPattern = regexp.MustCompile(`^\d{6}$`)
+	symbolPrefixPattern = regexp.MustCompile(`^(SH|SZ|BJ)[.]?(\d{6})$`)
+	symbolSuffixPattern = regexp.MustCompile(`^(\d{6})\.(XSHG|XSHE|SH|SZ|SS|BJ)$`)
+	// 字母开头，. 和 - 只能用作分隔符，如 BRK.B、BF-B
+	tickerPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]*([.\-][A-Z0-9]+)*$`)
+)
+
+// NormalizeSymbol 将各种证券代码格式统一为Qlib格式
+//
+// 支持 000001.XSHE、600000.XSHG、000001.SZ、600000.SS、SZ000001、sh.600000 以及纯6位数字代码，
+// 纯数字代码按首位推断交易所；美股等字母代码转为大写保留。
+func NormalizeSymbol(raw string) (string, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(raw))
+	if symbol == "" {
+		return "", fmt.Errorf("证券代码为空")
+	}
+
+	if m := symbolPrefixPattern.FindStringSubmatch(symbol); m != nil {
+		return m[1] + m[2], nil
+	}
+	if m := symbolSuffixPattern.FindStringSubmatch(symbol); m != nil {
+		switch m[2] {
+		case "XSHG", "SH", "SS":
+			return "SH" + m[1], nil
+		case "XSHE", "SZ":
+			return "SZ" + m[1], nil
+		default:
+			return "BJ" + m[1], nil
+		}
+	}
+	if symbolDigitsPattern.MatchString(symbol) {
+		switch symbol[0] {
+		case '6', '9', '5':
+			return "SH" + symbol, nil
+		case '0', '2', '3', '1':
+			return "SZ" + symbol, nil
+		case '4', '8':
+			return "BJ" + symbol, nil
+		}
+	}
+	if len(symbol) <= 10 && tickerPattern.MatchString(symbol) {
+		return symbol, nil
+	}
+	return "", fmt.Errorf("无法识别的证券代码: %s", raw)
+}
+
+// BinDataWriter Qlib二进制数据写入器
+type BinDataWriter struct {
+	dataPath string
+}
+
+// NewBinDataWriter 创建二进制数据写入器
+func NewBinDataWriter(dataPath string) *BinDataWriter {
+	return &BinDataWriter{dataPath: dataPath}
+}
+
+// WriteCalendar 写入交易日历
+func (w *BinDataWriter) WriteCalendar(freq string, calendar []time.Time) error {
+	layout := "2006-01-02"
+	if normalizeFreq(freq) != "day" {
+		layout = "2006-01-02 15:04:05"
+	}
+	var sb strings.Builder
+	for _, date := range calendar {
+		sb.WriteString(date.Format(layout))
+		sb.WriteString("\n")
+	}
+	return w.writeFile(filepath.Join("calendars", normalizeFreq(freq)+".txt"), []byte(sb.String()))
+}
+
+// WriteInstruments 写入股票池文件
+func (w *BinDataWriter) WriteInstruments(market string, spans []InstrumentSpan) error {
+	var sb strings.Builder
+	for _, span := range spans {
+		sb.WriteString(fmt.Sprintf("%s\t%s\t%s\n", span.Symbol, span.Start.Format("2006-01-02"), span.End.Format("2006-01-02")))
+	}
+	return w.writeFile(filepath.Join("instruments", strings.ToLower(market)+".txt"), []byte(sb.String()))
+}
+
+// WriteFeature 写入特征文件，startIndex 为第一个值在交易日历中的下标
+func (w *BinDataWriter) WriteFeature(instrument, field, freq string, startIndex int, values []float64) error {
+	buf := make([]byte, 4*(len(values)+1))
+	binary.LittleEndian.PutUint32(buf, math.Float32bits(float32(startIndex)))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(buf[4*(i+1):], math.Float32bits(float32(v)))
+	}
+	name := fmt.Sprintf("%s.%s.bin", strings.ToLower(strings.TrimPrefix(field, "$")), normalizeFreq(freq))
+	return w.writeFile(filepath.Join("features", strings.ToLower(instrument), name), buf)
+}
+
+func (w *BinDataWriter) writeFile(relPath string, data []byte) error {
+	path := filepath.Join(w.dataPath, relPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("写入文件 %s 失败: %v", relPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package qlib
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeSymbol(t *testing.T) {
+	cases := map[string]string{
+		"000001.XSHE": "SZ000001",
+		"600000.XSHG": "SH600000",
+		"000001.SZ":   "SZ000001",
+		"600000.SS":   "SH600000",
+		"sz000001":    "SZ000001",
+		"sh.600000":   "SH600000",
+		"600519":      "SH600519",
+		"300750":      "SZ300750",
+		"830799":      "BJ830799",
+		"aapl":        "AAPL",
+		"brk.b":       "BRK.B",
+	}
+	for raw, want := range cases {
+		got, err := NormalizeSymbol(raw)
+		if err != nil || got != want {
+			t.Errorf("NormalizeSymbol(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+
+	for _, raw := range []string{"", "12345", "@@@", "A..B", "A.", "ABCDEFGHIJK"} {
+		if _, err := NormalizeSymbol(raw); err == nil {
+			t.Errorf("NormalizeSymbol(%q) should fail", raw)
+		}
+	}
+}
+
+func TestIngestCSV(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "prices.csv")
+	writeTestFile(t, csvPath, strings.Join([]string{
+		"trade_date,ts_code,open_price,high,low,close_price,vol",
+		"20230103,600000.XSHG,10,10.5,9.8,10.2,1000",
+		"20230104,600000.XSHG,10.2,10.6,10,10.4,1200",
+		"20230105,000001.XSHE,15,15.5,14.8,15.2,800",
+		"20230106,600000.XSHG,10.4,10.8,10.3,10.6,900",
+		"20230106,000001.XSHE,15.2,15.3,15,,700",
+		"20230106,600000.XSHG,10.4,10.8,10.3,10.6,900",
+		"2023-13-45,600000.XSHG,10,10,10,10,1",
+		"20230109,000001.XSHE,15,14,15.5,15,1",
+		"20230109,000001.XSHE,abc,15,14,15,1",
+		"20230109,,15,15,14,15,1",
+	}, "\n"))
+
+	outputDir := filepath.Join(dir, "qlib_data")
+	var lastProgress int
+	result, err := IngestCSV(context.Background(), csvPath, outputDir, CSVIngestOptions{
+		ColumnMapping: map[string]string{
+			"date": "trade_date", "symbol": "ts_code",
+			"open": "open_price", "high": "high", "low": "low", "close": "close_price", "volume": "vol",
+		},
+		DateFormat: "YYYYMMDD",
+	}, func(progress int, message string) { lastProgress = progress })
+	if err != nil {
+		t.Fatalf("IngestCSV failed: %v", err)
+	}
+
+	if result.RecordCount != 5 || result.InstrumentCount != 2 {
+		t.Errorf("Unexpected counts: records=%d instruments=%d", result.RecordCount, result.InstrumentCount)
+	}
+	if result.StartDate != "2023-01-03" || result.EndDate != "2023-01-06" {
+		t.Errorf("Unexpected date range: %s - %s", result.StartDate, result.EndDate)
+	}
+	if lastProgress != 100 {
+		t.Errorf("Final progress = %d, want 100", lastProgress)
+	}
+
+	// 重复记录、非法日期、最高价低于最低价、非数值、缺少代码
+	if result.ErrorCount != 5 || len(result.RowErrors) != 5 {
+		t.Fatalf("Expected 5 row errors, got %d: %+v", result.ErrorCount, result.RowErrors)
+	}
+	wantLines := []int{7, 8, 9, 10, 11}
+	for i, rowErr := range result.RowErrors {
+		if rowErr.Line != wantLines[i] {
+			t.Errorf("RowErrors[%d].Line = %d, want %d (%s)", i, rowErr.Line, wantLines[i], rowErr.Message)
+		}
+	}
+	if result.RowErrors[3].Column != "open_price" {
+		t.Errorf("Non-numeric error should point to open_price, got %q", result.RowErrors[3].Column)
+	}
+
+	// 通过读取器回读，验证 dump_bin 格式
+	if !IsQlibDataDir(outputDir) {
+		t.Fatal("Output should be a Qlib data directory")
+	}
+	reader := NewBinDataReader(outputDir)
+	calendar, _ := reader.Calendar("day")
+	if len(calendar) != 4 {
+		t.Errorf("Calendar length = %d, want 4", len(calendar))
+	}
+	spans, _ := reader.Instruments("all")
+	if len(spans) != 2 || spans[0].Symbol != "SH600000" || spans[1].Start.Format("2006-01-02") != "2023-01-05" {
+		t.Errorf("Unexpected instruments: %+v", spans)
+	}
+
+	closes, err := reader.ReadFeature("SH600000", "$close", "day", 0, 3)
+	if err != nil {
+		t.Fatalf("ReadFeature failed: %v", err)
+	}
+	// 2023-01-05 无记录，应写入 NaN
+	assertSeries(t, "SH600000.close", closes, []float64{10.2, 10.4, math.NaN(), 10.6})
+
+	closes, _ = reader.ReadFeature("SZ000001", "$close", "day", 0, 3)
+	assertSeries(t, "SZ000001.close", closes, []float64{math.NaN(), math.NaN(), 15.2, math.NaN()})
+	volumes, _ := reader.ReadFeature("SZ000001", "$volume", "day", 2, 3)
+	assertSeries(t, "SZ000001.volume", volumes, []float64{800, 700})
+}
+
+func TestIngestCSVAutoDetect(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "prices.csv")
+	writeTestFile(t, csvPath, "symbol,date,close,volume\nSH600000,2023-01-03,10,100\nSH600000,2023/01/04,11,120\n")
+
+	result, err := IngestCSV(context.Background(), csvPath, filepath.Join(dir, "out"), CSVIngestOptions{Market: "custom"}, nil)
+	if err != nil {
+		t.Fatalf("IngestCSV failed: %v", err)
+	}
+	if len(result.Fields) != 2 || result.Fields[0] != "close" || result.RecordCount != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	markets, _ := NewBinDataReader(filepath.Join(dir, "out")).Markets()
+	if len(markets) != 2 {
+		t.Errorf("Both custom and all markets should be written, got %v", markets)
+	}
+
+	// 负价格和负成交量分别给出对应的错误信息
+	writeTestFile(t, csvPath, "symbol,date,close,volume\nSH600000,2023-01-03,-1,100\nSH600000,2023-01-04,11,-5\nSH600000,2023-01-05,12,130\n")
+	result, err = IngestCSV(context.Background(), csvPath, filepath.Join(dir, "negative"), CSVIngestOptions{}, nil)
+	if err != nil {
+		t.Fatalf("IngestCSV failed: %v", err)
+	}
+	if len(result.RowErrors) != 2 || !strings.Contains(result.RowErrors[0].Message, "价格") ||
+		!strings.Contains(result.RowErrors[1].Message, "成交量") {
+		t.Errorf("Unexpected negative value errors: %+v", result.RowErrors)
+	}
+
+	writeTestFile(t, csvPath, "symbol,date,close\nSH600000,bad,10\n")
+	if _, err := IngestCSV(context.Background(), csvPath, filepath.Join(dir, "empty"), CSVIngestOptions{}, nil); err == nil {
+		t.Error("Ingesting a file without valid rows should fail")
+	}
+}
+
+func TestIngestCSVRejectsInvalidMarket(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "prices.csv")
+	writeTestFile(t, csvPath, "symbol,date,close\nSH600000,2023-01-03,10\n")
+	outputDir := filepath.Join(dir, "data", "out")
+
+	for _, market := range []string{"../../escaped", "a/b", `a\b`, "csi 300"} {
+		if _, err := IngestCSV(context.Background(), csvPath, outputDir, CSVIngestOptions{Market: market}, nil); err == nil {
+			t.Errorf("Market %q should be rejected", market)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped.txt")); !os.IsNotExist(err) {
+		t.Error("No file should be written outside the output directory")
+	}
+	if err := ValidateMarketName("CSI300"); err != nil {
+		t.Errorf("Market names are case-insensitive: %v", err)
+	}
+}
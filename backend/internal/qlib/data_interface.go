@@ -5,23 +5,33 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+	"time"
 )
 
 // DataInterface Qlib数据接口封装
 type DataInterface struct {
 	pythonPath string
 	qlibPath   string
+
+	// 设置后直接读取本地Qlib二进制数据，不再调用Python
+	reader *BinDataReader
 }
 
 // NewDataInterface 创建新的数据接口实例
-func NewDataInterface(pythonPath, qlibPath string) *DataInterface {
+//
+// dataPath 为Qlib二进制数据目录时，默认直接读取本地数据。
+func NewDataInterface(pythonPath, qlibPath, dataPath string) *DataInterface {
 	if pythonPath == "" {
 		pythonPath = "python3"
 	}
-	return &DataInterface{
+	d := &DataInterface{
 		pythonPath: pythonPath,
 		qlibPath:   qlibPath,
 	}
+	if IsQlibDataDir(dataPath) {
+		d.SetBinDataReader(NewBinDataReader(dataPath))
+	}
+	return d
 }
 
 // DataLoadRequest 数据加载请求
@@ -128,8 +138,17 @@ func (d *DataInterface) GetDataInfo(instruments []string, fields []string) (*Dat
 	return dataInfo, nil
 }
 
+// SetBinDataReader 设置本地Qlib数据读取器
+func (d *DataInterface) SetBinDataReader(reader *BinDataReader) {
+	d.reader = reader
+}
+
 // GetInstruments 获取股票列表
 func (d *DataInterface) GetInstruments(market string) ([]InstrumentInfo, error) {
+	if d.reader != nil {
+		return d.getInstrumentsNative(market)
+	}
+
 	scriptArgs := map[string]interface{}{
 		"action": "get_instruments",
 		"market": market,
@@ -177,8 +196,61 @@ func (d *DataInterface) GetInstruments(market string) ([]InstrumentInfo, error)
 	return instruments, nil
 }
 
+// getInstrumentsNative 从本地股票池文件读取证券列表，多段区间合并为首次上市和最后退出日期
+func (d *DataInterface) getInstrumentsNative(market string) ([]InstrumentInfo, error) {
+	spans, err := d.reader.Instruments(market)
+	if err != nil {
+		return nil, fmt.Errorf("获取股票列表失败: %v", err)
+	}
+
+	calendar, _ := d.reader.Calendar("day")
+	var lastTradingDay time.Time
+	if len(calendar) > 0 {
+		lastTradingDay = calendar[len(calendar)-1]
+	}
+
+	merged := make(map[string]*InstrumentSpan)
+	order := make([]string, 0, len(spans))
+	for _, span := range spans {
+		existing, ok := merged[span.Symbol]
+		if !ok {
+			copied := span
+			merged[span.Symbol] = &copied
+			order = append(order, span.Symbol)
+			continue
+		}
+		if span.Start.Before(existing.Start) {
+			existing.Start = span.Start
+		}
+		if span.End.After(existing.End) {
+			existing.End = span.End
+		}
+	}
+
+	instruments := make([]InstrumentInfo, 0, len(order))
+	for _, symbol := range order {
+		span := merged[symbol]
+		info := InstrumentInfo{
+			Symbol:   symbol,
+			Market:   market,
+			ListDate: span.Start.Format("2006-01-02"),
+			Status:   "active",
+		}
+		if !lastTradingDay.IsZero() && span.End.Before(lastTradingDay) {
+			info.DelistDate = span.End.Format("2006-01-02")
+			info.Status = "delisted"
+		}
+		instruments = append(instruments, info)
+	}
+	return instruments, nil
+}
+
 // GetMarkets 获取市场列表
 func (d *DataInterface) GetMarkets() ([]string, error) {
+	if d.reader != nil {
+		return d.reader.Markets()
+	}
+
 	scriptArgs := map[string]interface{}{
 		"action": "get_markets",
 	}
@@ -5,12 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
+	"strings"
 	"time"
 )
 
 // DataLoader 数据加载接口
 type DataLoader struct {
 	client *QlibClient
+
+	// 设置后直接读取本地Qlib二进制数据，不再调用Python
+	reader *BinDataReader
 }
 
 // StockData 股票数据结构
@@ -49,10 +54,21 @@ type DataResponse struct {
 }
 
 // NewDataLoader 创建数据加载器
+//
+// 客户端的数据目录为Qlib二进制格式时，默认直接读取本地数据。
 func NewDataLoader(client *QlibClient) *DataLoader {
-	return &DataLoader{
+	dl := &DataLoader{
 		client: client,
 	}
+	if dataDir := client.GetDataDir(); IsQlibDataDir(dataDir) {
+		dl.SetBinDataReader(NewBinDataReader(dataDir))
+	}
+	return dl
+}
+
+// SetBinDataReader 设置本地Qlib数据读取器
+func (dl *DataLoader) SetBinDataReader(reader *BinDataReader) {
+	dl.reader = reader
 }
 
 // LoadStockData 加载股票数据
@@ -63,11 +79,14 @@ func (dl *DataLoader) LoadStockData(ctx context.Context, req DataRequest) (*Data
 
 	log.Printf("正在加载股票数据: %+v", req)
 
-	// 构建数据加载脚本
-	script := dl.buildDataLoadScript(req)
-
-	// 执行脚本
-	result, err := dl.client.ExecuteScript(ctx, script)
+	// 执行数据加载入口脚本
+	result, err := dl.client.RunEntryPoint(ctx, "load_stock_data", map[string]interface{}{
+		"instruments": req.Instruments,
+		"start_time":  req.StartTime.Format("2006-01-02"),
+		"end_time":    req.EndTime.Format("2006-01-02"),
+		"fields":      req.Fields,
+		"frequency":   req.Frequency,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("加载股票数据失败: %w", err)
 	}
@@ -86,103 +105,12 @@ func (dl *DataLoader) LoadStockData(ctx context.Context, req DataRequest) (*Data
 	return &response, nil
 }
 
-// buildDataLoadScript 构建数据加载脚本
-func (dl *DataLoader) buildDataLoadScript(req DataRequest) string {
-	instrumentsJson, _ := json.Marshal(req.Instruments)
-	fieldsJson, _ := json.Marshal(req.Fields)
-
-	return fmt.Sprintf(`
-import json
-import qlib
-from qlib import data
-import pandas as pd
-import numpy as np
-from datetime import datetime
-
-try:
-	# 解析参数
-	instruments = %s
-	start_time = '%s'
-	end_time = '%s'
-	fields = %s
-	frequency = '%s'
-	
-	# 如果没有指定字段，使用默认字段
-	if not fields:
-		fields = ['$open', '$high', '$low', '$close', '$volume']
-	
-	# 加载数据
-	if frequency == 'minute':
-		# 分钟级数据
-		df = data.D.features(
-			instruments=instruments,
-			fields=fields,
-			start_time=start_time,
-			end_time=end_time,
-			freq='1min'
-		)
-	else:
-		# 日级数据
-		df = data.D.features(
-			instruments=instruments,
-			fields=fields,
-			start_time=start_time,
-			end_time=end_time,
-			freq='day'
-		)
-	
-	# 转换数据格式
-	data_list = []
-	
-	if df is not None and not df.empty:
-		# 重置索引以便访问instrument和datetime
-		df_reset = df.reset_index()
-		
-		for _, row in df_reset.iterrows():
-			instrument = row['instrument'] if 'instrument' in row else str(row.name[0])
-			date = row['datetime'] if 'datetime' in row else str(row.name[1])
-			
-			# 构建特征字典
-			features = {}
-			for field in fields:
-				field_clean = field.replace('$', '').lower()
-				if field in row and pd.notna(row[field]):
-					features[field_clean] = float(row[field])
-				else:
-					features[field_clean] = None
-			
-			data_list.append({
-				'instrument': instrument,
-				'date': str(date),
-				'features': features
-			})
-	
-	result = {
-		'success': True,
-		'data': data_list,
-		'count': len(data_list),
-		'error': None
-	}
-
-except Exception as e:
-	result = {
-		'success': False,
-		'data': [],
-		'count': 0,
-		'error': str(e)
-	}
-
-print(json.dumps(result, default=str))
-`,
-		string(instrumentsJson),
-		req.StartTime.Format("2006-01-02"),
-		req.EndTime.Format("2006-01-02"),
-		string(fieldsJson),
-		req.Frequency)
-}
-
 // GetMarketData 获取市场数据
 func (dl *DataLoader) GetMarketData(ctx context.Context, instrument string, startDate, endDate time.Time) ([]MarketData, error) {
+	if dl.reader != nil {
+		return dl.getMarketDataNative(ctx, instrument, startDate, endDate)
+	}
+
 	req := DataRequest{
 		Instruments: []string{instrument},
 		StartTime:   startDate,
@@ -230,54 +158,62 @@ func (dl *DataLoader) GetMarketData(ctx context.Context, instrument string, star
 	return marketData, nil
 }
 
-// GetInstrumentList 获取可用的股票列表
-func (dl *DataLoader) GetInstrumentList(ctx context.Context, market string) ([]string, error) {
-	script := fmt.Sprintf(`
-import json
-import qlib
-from qlib import data
-
-try:
-	# 获取股票列表
-	market = '%s'
-	
-	if market.lower() == 'cn' or market.lower() == 'csi300':
-		# 中国市场 - CSI300成分股
-		instruments = data.D.instruments(market='csi300')
-	elif market.lower() == 'us' or market.lower() == 'sp500':
-		# 美国市场 - S&P500成分股
-		instruments = data.D.instruments(market='sp500')
-	elif market.lower() == 'all':
-		# 获取所有可用股票
-		instruments = data.D.instruments(market='all')
-	else:
-		# 默认获取当前配置的市场
-		instruments = data.D.instruments()
-	
-	# 转换为列表
-	if hasattr(instruments, 'tolist'):
-		instrument_list = instruments.tolist()
-	else:
-		instrument_list = list(instruments)
-	
-	result = {
-		'success': True,
-		'instruments': instrument_list,
-		'count': len(instrument_list)
+// getMarketDataNative 从本地Qlib数据读取行情，跳过停牌（收盘价缺失）的交易日
+func (dl *DataLoader) getMarketDataNative(ctx context.Context, instrument string, startDate, endDate time.Time) ([]MarketData, error) {
+	frame, err := dl.reader.LoadFrame(ctx, FrameRequest{
+		Instruments: []string{instrument},
+		Fields:      []string{"$open", "$high", "$low", "$close", "$volume"},
+		Start:       startDate,
+		End:         endDate,
+		Freq:        "day",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取本地行情数据失败: %w", err)
 	}
 
-except Exception as e:
-	result = {
-		'success': False,
-		'instruments': [],
-		'count': 0,
-		'error': str(e)
+	marketData := make([]MarketData, 0, len(frame.Calendar))
+	for t, date := range frame.Calendar {
+		value := func(field string) float64 {
+			v := frame.Fields[field][0][t]
+			if math.IsNaN(v) {
+				return 0
+			}
+			return v
+		}
+		md := MarketData{
+			Date:   date.Format("2006-01-02"),
+			Open:   value("$open"),
+			High:   value("$high"),
+			Low:    value("$low"),
+			Close:  value("$close"),
+			Volume: int64(value("$volume")),
+		}
+		if md.Close == 0 {
+			continue
+		}
+		if md.Open > 0 {
+			md.Change = (md.Close - md.Open) / md.Open * 100
+		}
+		marketData = append(marketData, md)
 	}
+	return marketData, nil
+}
 
-print(json.dumps(result))
-`, market)
+// GetInstrumentList 获取可用的股票列表
+func (dl *DataLoader) GetInstrumentList(ctx context.Context, market string) ([]string, error) {
+	if dl.reader != nil {
+		switch strings.ToLower(market) {
+		case "cn":
+			market = "csi300"
+		case "us":
+			market = "sp500"
+		}
+		return dl.reader.ListInstruments(market, time.Time{}, time.Time{})
+	}
 
-	output, err := dl.client.ExecuteScript(ctx, script)
+	output, err := dl.client.RunEntryPoint(ctx, "instrument_list", map[string]interface{}{
+		"market": market,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("获取股票列表失败: %w", err)
 	}
@@ -302,43 +238,13 @@ print(json.dumps(result))
 
 // GetDataRange 获取数据的时间范围
 func (dl *DataLoader) GetDataRange(ctx context.Context, instrument string) (time.Time, time.Time, error) {
-	script := fmt.Sprintf(`
-import json
-import qlib
-from qlib import data
-import pandas as pd
-
-try:
-	instrument = '%s'
-	
-	# 获取数据范围
-	calendar = data.D.calendar(freq='day')
-	
-	if calendar is not None and len(calendar) > 0:
-		start_date = str(calendar[0])
-		end_date = str(calendar[-1])
-		
-		result = {
-			'success': True,
-			'start_date': start_date,
-			'end_date': end_date
-		}
-	else:
-		result = {
-			'success': False,
-			'error': '无法获取数据日历'
-		}
-
-except Exception as e:
-	result = {
-		'success': False,
-		'error': str(e)
+	if dl.reader != nil {
+		return dl.reader.DataRange(instrument)
 	}
 
-print(json.dumps(result))
-`, instrument)
-
-	output, err := dl.client.ExecuteScript(ctx, script)
+	output, err := dl.client.RunEntryPoint(ctx, "data_range", map[string]interface{}{
+		"instrument": instrument,
+	})
 	if err != nil {
 		return time.Time{}, time.Time{}, fmt.Errorf("获取数据范围失败: %w", err)
 	}
@@ -404,68 +310,12 @@ func (dl *DataLoader) ValidateData(ctx context.Context, instrument string, date
 
 // GetDataStats 获取数据统计信息
 func (dl *DataLoader) GetDataStats(ctx context.Context, instrument string, field string, startDate, endDate time.Time) (map[string]float64, error) {
-	script := fmt.Sprintf(`
-import json
-import qlib
-from qlib import data
-import pandas as pd
-import numpy as np
-
-try:
-	instrument = '%s'
-	field = '%s'
-	start_time = '%s'
-	end_time = '%s'
-	
-	# 加载数据
-	df = data.D.features(
-		instruments=[instrument],
-		fields=[field],
-		start_time=start_time,
-		end_time=end_time,
-		freq='day'
-	)
-	
-	if df is not None and not df.empty:
-		values = df[field].dropna()
-		
-		if len(values) > 0:
-			stats = {
-				'count': len(values),
-				'mean': float(values.mean()),
-				'std': float(values.std()),
-				'min': float(values.min()),
-				'max': float(values.max()),
-				'median': float(values.median()),
-				'q25': float(values.quantile(0.25)),
-				'q75': float(values.quantile(0.75))
-			}
-		else:
-			stats = {}
-		
-		result = {
-			'success': True,
-			'stats': stats
-		}
-	else:
-		result = {
-			'success': False,
-			'error': '没有找到数据'
-		}
-
-except Exception as e:
-	result = {
-		'success': False,
-		'error': str(e)
-	}
-
-print(json.dumps(result, default=str))
-`,
-		instrument, field,
-		startDate.Format("2006-01-02"),
-		endDate.Format("2006-01-02"))
-
-	output, err := dl.client.ExecuteScript(ctx, script)
+	output, err := dl.client.RunEntryPoint(ctx, "data_stats", map[string]interface{}{
+		"instrument": instrument,
+		"field":      field,
+		"start_time": startDate.Format("2006-01-02"),
+		"end_time":   endDate.Format("2006-01-02"),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("获取数据统计失败: %w", err)
 	}
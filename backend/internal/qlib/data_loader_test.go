@@ -10,24 +10,21 @@ import (
 	"github.com/stretchr/testify/suite"
 )
 
-// MockQlibClient 模拟Qlib客户端
+// MockQlibClient 模拟Python执行客户端
 type MockQlibClient struct {
 	mock.Mock
 }
 
-func (m *MockQlibClient) ExecuteScript(ctx context.Context, script string) ([]byte, error) {
-	args := m.Called(ctx, script)
+func (m *MockQlibClient) Run(ctx context.Context, script string, input []byte) ([]byte, error) {
+	args := m.Called(ctx, script, input)
 	return args.Get(0).([]byte), args.Error(1)
 }
 
-func (m *MockQlibClient) Initialize() error {
-	args := m.Called()
-	return args.Error(0)
-}
-
-func (m *MockQlibClient) Close() error {
-	args := m.Called()
-	return args.Error(0)
+// expectEntryPoint 登记一次入口脚本调用的返回结果
+func (m *MockQlibClient) expectEntryPoint(response string) *mock.Call {
+	return m.On("Run", mock.Anything, mock.MatchedBy(func(script string) bool {
+		return len(script) > 0 // 简单验证脚本不为空
+	}), mock.Anything).Return([]byte(response), nil)
 }
 
 type DataLoaderTestSuite struct {
@@ -36,18 +33,14 @@ type DataLoaderTestSuite struct {
 	mockClient *MockQlibClient
 }
 
-func (suite *DataLoaderTestSuite) SetupSuite() {
-	suite.mockClient = new(MockQlibClient)
-	suite.loader = NewDataLoader(suite.mockClient)
-}
-
 func (suite *DataLoaderTestSuite) SetupTest() {
-	suite.mockClient.ExpectedCalls = nil
+	suite.mockClient = new(MockQlibClient)
+	suite.loader = NewDataLoader(newEntryPointClient(suite.mockClient))
 }
 
 func (suite *DataLoaderTestSuite) TestLoadStockData() {
 	ctx := context.Background()
-	
+
 	req := DataRequest{
 		Instruments: []string{"000001.SZ", "000002.SZ"},
 		StartTime:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
@@ -85,9 +78,7 @@ func (suite *DataLoaderTestSuite) TestLoadStockData() {
 		"error": ""
 	}`
 
-	suite.mockClient.On("ExecuteScript", ctx, mock.MatchedBy(func(script string) bool {
-		return len(script) > 0 // 简单验证脚本不为空
-	})).Return([]byte(mockResponse), nil)
+	suite.mockClient.expectEntryPoint(mockResponse)
 
 	response, err := suite.loader.LoadStockData(ctx, req)
 
@@ -108,48 +99,37 @@ func (suite *DataLoaderTestSuite) TestLoadStockData() {
 	suite.mockClient.AssertExpectations(suite.T())
 }
 
-func (suite *DataLoaderTestSuite) TestLoadMarketData() {
+func (suite *DataLoaderTestSuite) TestGetMarketData() {
 	ctx := context.Background()
-	
+
 	instrument := "000300.SH" // 沪深300指数
-	startDate := "2023-01-01"
-	endDate := "2023-01-31"
+	startDate := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
 
-	// 模拟返回的市场数据
+	// 模拟返回的行情数据
 	mockResponse := `{
 		"success": true,
 		"data": [
 			{
+				"instrument": "000300.SH",
 				"date": "2023-01-03",
-				"open": 3900.50,
-				"high": 3920.80,
-				"low": 3885.20,
-				"close": 3910.75,
-				"volume": 50000000,
-				"change": 0.25
+				"features": {"open": 3900.50, "high": 3920.80, "low": 3885.20, "close": 3910.75, "volume": 50000000}
 			},
 			{
+				"instrument": "000300.SH",
 				"date": "2023-01-04",
-				"open": 3910.75,
-				"high": 3935.60,
-				"low": 3905.30,
-				"close": 3925.40,
-				"volume": 55000000,
-				"change": 0.37
+				"features": {"open": 3910.75, "high": 3935.60, "low": 3905.30, "close": 3925.40, "volume": 55000000}
 			}
 		],
 		"count": 2,
 		"error": ""
 	}`
 
-	suite.mockClient.On("ExecuteScript", ctx, mock.MatchedBy(func(script string) bool {
-		return len(script) > 0
-	})).Return([]byte(mockResponse), nil)
+	suite.mockClient.expectEntryPoint(mockResponse)
 
-	marketData, err := suite.loader.LoadMarketData(ctx, instrument, startDate, endDate)
+	marketData, err := suite.loader.GetMarketData(ctx, instrument, startDate, endDate)
 
 	assert.NoError(suite.T(), err)
-	assert.NotNil(suite.T(), marketData)
 	assert.Len(suite.T(), marketData, 2)
 
 	// 验证第一天数据
@@ -160,18 +140,25 @@ func (suite *DataLoaderTestSuite) TestLoadMarketData() {
 	assert.Equal(suite.T(), 3885.20, firstDay.Low)
 	assert.Equal(suite.T(), 3910.75, firstDay.Close)
 	assert.Equal(suite.T(), int64(50000000), firstDay.Volume)
-	assert.Equal(suite.T(), 0.25, firstDay.Change)
+	assert.InDelta(suite.T(), (3910.75-3900.50)/3900.50*100, firstDay.Change, 1e-9)
+
+	// 请求参数通过标准输入传给入口脚本
+	input := suite.mockClient.Calls[0].Arguments.Get(2).([]byte)
+	params := decodeEntryPointInput(suite.T(), input)
+	assert.Equal(suite.T(), []interface{}{instrument}, params["instruments"])
+	assert.Equal(suite.T(), "2023-01-01", params["start_time"])
+	assert.Equal(suite.T(), "2023-01-31", params["end_time"])
 
 	suite.mockClient.AssertExpectations(suite.T())
 }
 
 func (suite *DataLoaderTestSuite) TestLoadFactorData() {
 	ctx := context.Background()
-	
+
 	instruments := []string{"000001.SZ", "000002.SZ"}
 	factors := []string{"PE", "PB", "ROE"}
-	startDate := "2023-01-01"
-	endDate := "2023-01-31"
+	startDate := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
 
 	// 模拟返回的因子数据
 	mockResponse := `{
@@ -200,9 +187,7 @@ func (suite *DataLoaderTestSuite) TestLoadFactorData() {
 		"error": ""
 	}`
 
-	suite.mockClient.On("ExecuteScript", ctx, mock.MatchedBy(func(script string) bool {
-		return len(script) > 0
-	})).Return([]byte(mockResponse), nil)
+	suite.mockClient.expectEntryPoint(mockResponse)
 
 	factorData, err := suite.loader.LoadFactorData(ctx, instruments, factors, startDate, endDate)
 
@@ -222,152 +207,92 @@ func (suite *DataLoaderTestSuite) TestLoadFactorData() {
 	suite.mockClient.AssertExpectations(suite.T())
 }
 
-func (suite *DataLoaderTestSuite) TestGetDataInfo() {
+func (suite *DataLoaderTestSuite) TestGetDataRange() {
 	ctx := context.Background()
-	
-	// 模拟返回的数据信息
+
+	// 模拟返回的数据范围
 	mockResponse := `{
 		"success": true,
-		"data": {
-			"datasets": ["qlib_data", "custom_data"],
-			"instruments_count": 4500,
-			"date_range": {
-				"start": "2005-01-01",
-				"end": "2023-12-31"
-			},
-			"available_fields": [
-				"open", "high", "low", "close", "volume", 
-				"PE", "PB", "ROE", "momentum", "volatility"
-			],
-			"frequencies": ["day", "minute"],
-			"last_update": "2023-12-31T23:59:59Z"
-		},
+		"start_date": "2005-01-04 00:00:00",
+		"end_date": "2023-12-29 00:00:00",
 		"error": ""
 	}`
 
-	suite.mockClient.On("ExecuteScript", ctx, mock.MatchedBy(func(script string) bool {
-		return len(script) > 0
-	})).Return([]byte(mockResponse), nil)
+	suite.mockClient.expectEntryPoint(mockResponse)
 
-	dataInfo, err := suite.loader.GetDataInfo(ctx)
+	start, end, err := suite.loader.GetDataRange(ctx, "000001.SZ")
 
 	assert.NoError(suite.T(), err)
-	assert.NotNil(suite.T(), dataInfo)
-	assert.True(suite.T(), dataInfo.Success)
-	assert.NotNil(suite.T(), dataInfo.Data)
-
-	// 验证数据信息结构
-	data := dataInfo.Data.(map[string]interface{})
-	assert.Contains(suite.T(), data, "datasets")
-	assert.Contains(suite.T(), data, "instruments_count")
-	assert.Contains(suite.T(), data, "date_range")
-	assert.Contains(suite.T(), data, "available_fields")
+	assert.Equal(suite.T(), time.Date(2005, 1, 4, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(suite.T(), time.Date(2023, 12, 29, 0, 0, 0, 0, time.UTC), end)
 
 	suite.mockClient.AssertExpectations(suite.T())
 }
 
-func (suite *DataLoaderTestSuite) TestLoadInstrumentList() {
+func (suite *DataLoaderTestSuite) TestGetInstrumentList() {
 	ctx := context.Background()
-	
+
 	market := "CSI300"
 
 	// 模拟返回的股票列表
 	mockResponse := `{
 		"success": true,
-		"data": [
-			{
-				"instrument": "000001.SZ",
-				"name": "平安银行",
-				"sector": "金融",
-				"market_cap": 500000000000
-			},
-			{
-				"instrument": "000002.SZ", 
-				"name": "万科A",
-				"sector": "房地产",
-				"market_cap": 300000000000
-			}
-		],
+		"instruments": ["000001.SZ", "000002.SZ"],
 		"count": 2,
 		"error": ""
 	}`
 
-	suite.mockClient.On("ExecuteScript", ctx, mock.MatchedBy(func(script string) bool {
-		return len(script) > 0
-	})).Return([]byte(mockResponse), nil)
+	suite.mockClient.expectEntryPoint(mockResponse)
 
-	instrumentList, err := suite.loader.LoadInstrumentList(ctx, market)
+	instrumentList, err := suite.loader.GetInstrumentList(ctx, market)
 
 	assert.NoError(suite.T(), err)
-	assert.NotNil(suite.T(), instrumentList)
-	assert.True(suite.T(), instrumentList.Success)
-	assert.Len(suite.T(), instrumentList.Data, 2)
+	assert.Equal(suite.T(), []string{"000001.SZ", "000002.SZ"}, instrumentList)
 
-	// 验证股票信息
-	firstStock := instrumentList.Data[0].(map[string]interface{})
-	assert.Equal(suite.T(), "000001.SZ", firstStock["instrument"])
-	assert.Equal(suite.T(), "平安银行", firstStock["name"])
-	assert.Equal(suite.T(), "金融", firstStock["sector"])
+	input := suite.mockClient.Calls[0].Arguments.Get(2).([]byte)
+	assert.Equal(suite.T(), market, decodeEntryPointInput(suite.T(), input)["market"])
 
 	suite.mockClient.AssertExpectations(suite.T())
 }
 
-func (suite *DataLoaderTestSuite) TestValidateDataRequest() {
-	// 测试有效请求
-	validReq := DataRequest{
-		Instruments: []string{"000001.SZ", "000002.SZ"},
-		StartTime:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
-		EndTime:     time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
-		Fields:      []string{"close", "volume"},
-		Frequency:   "day",
-	}
+func (suite *DataLoaderTestSuite) TestValidateData() {
+	ctx := context.Background()
+	date := time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC)
 
-	err := suite.loader.ValidateDataRequest(validReq)
-	assert.NoError(suite.T(), err)
+	// 有数据的交易日
+	suite.mockClient.expectEntryPoint(`{
+		"success": true,
+		"data": [{"instrument": "000001.SZ", "date": "2023-01-03", "features": {"$close": 11.5}}],
+		"count": 1,
+		"error": ""
+	}`).Once()
 
-	// 测试无效请求 - 空股票列表
-	invalidReq1 := DataRequest{
-		Instruments: []string{},
-		StartTime:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
-		EndTime:     time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
-		Fields:      []string{"close"},
-		Frequency:   "day",
-	}
+	valid, err := suite.loader.ValidateData(ctx, "000001.SZ", date)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), valid)
 
-	err = suite.loader.ValidateDataRequest(invalidReq1)
-	assert.Error(suite.T(), err)
-	assert.Contains(suite.T(), err.Error(), "股票代码列表不能为空")
+	// 没有数据的日期
+	suite.mockClient.expectEntryPoint(`{"success": true, "data": [], "count": 0, "error": ""}`).Once()
 
-	// 测试无效请求 - 时间范围错误
-	invalidReq2 := DataRequest{
-		Instruments: []string{"000001.SZ"},
-		StartTime:   time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
-		EndTime:     time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), // 结束时间早于开始时间
-		Fields:      []string{"close"},
-		Frequency:   "day",
-	}
+	valid, err = suite.loader.ValidateData(ctx, "000001.SZ", date.AddDate(0, 0, 2))
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), valid)
 
-	err = suite.loader.ValidateDataRequest(invalidReq2)
-	assert.Error(suite.T(), err)
-	assert.Contains(suite.T(), err.Error(), "结束时间不能早于开始时间")
+	suite.mockClient.AssertExpectations(suite.T())
+}
 
-	// 测试无效请求 - 空字段列表
-	invalidReq3 := DataRequest{
-		Instruments: []string{"000001.SZ"},
-		StartTime:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
-		EndTime:     time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
-		Fields:      []string{},
-		Frequency:   "day",
-	}
+func (suite *DataLoaderTestSuite) TestUninitializedClient() {
+	// 未初始化的客户端不执行脚本
+	loader := NewDataLoader(NewQlibClient())
 
-	err = suite.loader.ValidateDataRequest(invalidReq3)
+	_, err := loader.LoadStockData(context.Background(), DataRequest{Instruments: []string{"000001.SZ"}})
 	assert.Error(suite.T(), err)
-	assert.Contains(suite.T(), err.Error(), "字段列表不能为空")
+	assert.Contains(suite.T(), err.Error(), "未初始化")
 }
 
 func (suite *DataLoaderTestSuite) TestErrorHandling() {
 	ctx := context.Background()
-	
+
 	req := DataRequest{
 		Instruments: []string{"INVALID.SZ"},
 		StartTime:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
@@ -384,22 +309,18 @@ func (suite *DataLoaderTestSuite) TestErrorHandling() {
 		"error": "Invalid instrument code: INVALID.SZ"
 	}`
 
-	suite.mockClient.On("ExecuteScript", ctx, mock.MatchedBy(func(script string) bool {
-		return len(script) > 0
-	})).Return([]byte(mockResponse), nil)
+	suite.mockClient.expectEntryPoint(mockResponse)
 
 	response, err := suite.loader.LoadStockData(ctx, req)
 
-	assert.NoError(suite.T(), err) // 网络调用本身成功
-	assert.NotNil(suite.T(), response)
-	assert.False(suite.T(), response.Success)
-	assert.Empty(suite.T(), response.Data)
-	assert.Equal(suite.T(), 0, response.Count)
-	assert.Contains(suite.T(), response.Error, "Invalid instrument code")
+	// 脚本执行成功但返回失败时，错误信息带出脚本的错误
+	assert.Error(suite.T(), err)
+	assert.Nil(suite.T(), response)
+	assert.Contains(suite.T(), err.Error(), "Invalid instrument code")
 
 	suite.mockClient.AssertExpectations(suite.T())
 }
 
 func TestDataLoaderTestSuite(t *testing.T) {
 	suite.Run(t, new(DataLoaderTestSuite))
-}
\ No newline at end of file
+}
@@ -0,0 +1,175 @@
+package qlib
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// TrainingEngine 模型训练引擎
+type TrainingEngine interface {
+	TrainModelContext(ctx context.Context, params ModelTrainingParams, callback ProgressCallback) (*ModelTrainingResult, error)
+	EvaluateModel(params ModelEvaluationParams) (*ModelEvaluationResult, error)
+	CompareModels(params ModelComparisonParams) (*ModelComparisonResult, error)
+	DeployModel(params ModelDeploymentParams) (*ModelDeploymentResult, error)
+}
+
+// BacktestingEngine 策略回测引擎
+type BacktestingEngine interface {
+	RunBacktestWithReport(ctx context.Context, params BacktestParams, callback BacktestProgressCallback) (*BacktestResult, *NativeBacktestReport, error)
+	GetBacktestResults(params BacktestResultsParams) (*BacktestResultsData, error)
+	GetAttributionAnalysis(params AttributionAnalysisParams) (*AttributionAnalysisData, error)
+	CompareStrategies(params StrategyComparisonParams) (*StrategyComparisonData, error)
+	ExportReport(params ReportExportParams) (*ReportExportResult, error)
+}
+
+// FactorEvaluationEngine 因子计算和评估引擎
+type FactorEvaluationEngine interface {
+	UsesNativeBackend() bool
+	ValidateExpression(expression string) error
+	GetQlibFunctions() ([]QlibFunction, error)
+	GetBuiltinFactorCategories() ([]FactorCategory, error)
+	GetBuiltinFactorsByCategory(category string) ([]BuiltinFactor, error)
+	TestFactor(params FactorTestParams) (*FactorTestResult, error)
+	TestComposite(ctx context.Context, components []string, params FactorTestParams, opts CompositeOptions) (*FactorTestResult, error)
+	AnalyzeFactor(expression string) (*FactorAnalysisResult, error)
+	EvaluateFactor(ctx context.Context, expression string, req FrameRequest) (*FactorFrame, error)
+	FactorQuantiles(ctx context.Context, expression string, req FrameRequest, opts QuantileOptions) (*QuantileReport, error)
+	FactorDecay(ctx context.Context, expression string, req FrameRequest, opts DecayOptions) (*FactorDecayReport, error)
+}
+
+var (
+	_ TrainingEngine         = (*ModelTrainer)(nil)
+	_ BacktestingEngine      = (*BacktestEngine)(nil)
+	_ FactorEvaluationEngine = (*FactorEngine)(nil)
+)
+
+// Engines 一组计算后端，服务层只依赖其中的接口
+type Engines struct {
+	Backend    string                 // 后端名称
+	Trainer    TrainingEngine         // 模型训练
+	Backtester BacktestingEngine      // 策略回测
+	Factors    FactorEvaluationEngine // 因子计算和评估
+	Data       MarketDataProvider     // 行情数据，Python后端为 nil
+}
+
+// EngineConfig 创建计算后端的配置
+type EngineConfig struct {
+	PythonPath    string
+	QlibPath      string
+	WorkspacePath string
+	DataPath      string // Qlib二进制数据目录，原生后端从中读取行情
+	GPUEnabled    bool
+	FakeMarket    FakeMarketConfig // 假后端的合成行情
+}
+
+// EngineFactory 按配置创建一组计算后端
+type EngineFactory func(cfg EngineConfig) (*Engines, error)
+
+// 内置的计算后端
+const (
+	EngineBackendPython = "python" // 全部调用Python脚本
+	EngineBackendNative = "native" // 因子计算和回测使用原生引擎读取本地数据，其余调用Python
+	EngineBackendFake   = "fake"   // 合成行情和确定性结果，不依赖Python和数据目录，用于测试
+)
+
+var (
+	engineBackendsMu sync.RWMutex
+	engineBackends   = map[string]EngineFactory{
+		EngineBackendPython: newPythonEngines,
+		EngineBackendNative: newNativeEngines,
+		EngineBackendFake:   newFakeEngines,
+	}
+
+	defaultEnginesMu sync.RWMutex
+	defaultEngines   *Engines
+)
+
+// RegisterEngineBackend 注册计算后端，同名后端被覆盖
+func RegisterEngineBackend(name string, factory EngineFactory) {
+	engineBackendsMu.Lock()
+	defer engineBackendsMu.Unlock()
+	engineBackends[name] = factory
+}
+
+// EngineBackends 返回已注册的后端名称
+func EngineBackends() []string {
+	engineBackendsMu.RLock()
+	defer engineBackendsMu.RUnlock()
+	names := make([]string, 0, len(engineBackends))
+	for name := range engineBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewEngines 按名称创建计算后端
+func NewEngines(backend string, cfg EngineConfig) (*Engines, error) {
+	engineBackendsMu.RLock()
+	factory, ok := engineBackends[backend]
+	engineBackendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未知的计算后端: %s，可选 %v", backend, EngineBackends())
+	}
+	engines, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("创建计算后端 %s 失败: %v", backend, err)
+	}
+	engines.Backend = backend
+	return engines, nil
+}
+
+// SetDefaultEngines 设置启动时配置的计算后端
+func SetDefaultEngines(engines *Engines) {
+	defaultEnginesMu.Lock()
+	defer defaultEnginesMu.Unlock()
+	defaultEngines = engines
+}
+
+// DefaultEngines 返回启动时配置的计算后端，未配置时返回 nil
+func DefaultEngines() *Engines {
+	defaultEnginesMu.RLock()
+	defer defaultEnginesMu.RUnlock()
+	return defaultEngines
+}
+
+func newPythonEngines(cfg EngineConfig) (*Engines, error) {
+	return &Engines{
+		Trainer:    NewModelTrainer(cfg.PythonPath, cfg.QlibPath, cfg.WorkspacePath, cfg.GPUEnabled),
+		Backtester: NewBacktestEngine(cfg.PythonPath, cfg.QlibPath, cfg.WorkspacePath),
+		Factors:    NewFactorEngine(cfg.PythonPath, cfg.QlibPath, ""),
+	}, nil
+}
+
+func newNativeEngines(cfg EngineConfig) (*Engines, error) {
+	if cfg.DataPath == "" {
+		return nil, fmt.Errorf("原生后端需要设置数据目录")
+	}
+	if !IsQlibDataDir(cfg.DataPath) {
+		return nil, fmt.Errorf("数据目录 %s 不是Qlib二进制数据目录（缺少 calendars/day.txt），请先准备数据或改用 python 后端", cfg.DataPath)
+	}
+	data := NewBinDataReader(cfg.DataPath)
+
+	factors := NewFactorEngine(cfg.PythonPath, cfg.QlibPath, cfg.DataPath)
+	factors.SetDataProvider(data)
+	backtester := NewBacktestEngine(cfg.PythonPath, cfg.QlibPath, cfg.WorkspacePath)
+	backtester.SetDataProvider(data)
+	return &Engines{
+		Trainer:    NewModelTrainer(cfg.PythonPath, cfg.QlibPath, cfg.WorkspacePath, cfg.GPUEnabled),
+		Backtester: backtester,
+		Factors:    factors,
+		Data:       data,
+	}, nil
+}
+
+func newFakeEngines(cfg EngineConfig) (*Engines, error) {
+	data := NewFakeDataProvider(cfg.FakeMarket)
+	return &Engines{
+		Trainer:    NewFakeModelTrainer(cfg.FakeMarket.Seed),
+		Backtester: NewFakeBacktestEngine(data),
+		Factors:    NewFakeFactorEngine(data),
+		Data:       data,
+	}, nil
+}
@@ -0,0 +1,182 @@
+package qlib
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestFakeEngines 创建假后端：20只合成股票、2022年全年的工作日行情。
+// testutils 依赖本包，这里不能引用 testutils.RequireFakeEngines
+func newTestFakeEngines(t *testing.T, seed int64) *Engines {
+	t.Helper()
+	engines, err := NewEngines(EngineBackendFake, EngineConfig{FakeMarket: FakeMarketConfig{
+		Seed:        seed,
+		Instruments: 20,
+		Start:       time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:         time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC),
+	}})
+	if err != nil {
+		t.Fatalf("创建假后端失败: %v", err)
+	}
+	return engines
+}
+
+func TestEngineRegistry(t *testing.T) {
+	if _, err := NewEngines("unknown", EngineConfig{}); err == nil {
+		t.Error("未知后端应返回错误")
+	}
+	if _, err := NewEngines(EngineBackendNative, EngineConfig{}); err == nil {
+		t.Error("原生后端缺少数据目录应返回错误")
+	}
+
+	python, err := NewEngines(EngineBackendPython, EngineConfig{})
+	if err != nil || python.Backend != EngineBackendPython || python.Factors.UsesNativeBackend() || python.Data != nil {
+		t.Errorf("Python后端不应使用原生计算: %+v, %v", python, err)
+	}
+	if _, err := NewEngines(EngineBackendNative, EngineConfig{DataPath: t.TempDir()}); err == nil {
+		t.Error("数据目录不是Qlib二进制数据目录时应返回错误")
+	}
+	dataDir := t.TempDir()
+	writeTestFile(t, filepath.Join(dataDir, "calendars", "day.txt"), "2022-01-04\n")
+	native, err := NewEngines(EngineBackendNative, EngineConfig{DataPath: dataDir})
+	if err != nil || !native.Factors.UsesNativeBackend() || native.Data == nil {
+		t.Errorf("原生后端应使用本地数据: %+v, %v", native, err)
+	}
+
+	RegisterEngineBackend("test", func(cfg EngineConfig) (*Engines, error) {
+		return &Engines{Trainer: NewFakeModelTrainer(0)}, nil
+	})
+	custom, err := NewEngines("test", EngineConfig{})
+	if err != nil || custom.Backend != "test" {
+		t.Errorf("自定义后端创建失败: %+v, %v", custom, err)
+	}
+}
+
+func TestFakeEnginesAreDeterministic(t *testing.T) {
+	ctx := context.Background()
+	run := func(seed int64) (*ModelTrainingResult, *FactorTestResult, *BacktestResult) {
+		engines := newTestFakeEngines(t, seed)
+		trained, err := engines.Trainer.TrainModelContext(ctx, ModelTrainingParams{ModelID: 1, ModelType: "lightgbm"}, nil)
+		if err != nil {
+			t.Fatalf("训练失败: %v", err)
+		}
+		tested, err := engines.Factors.TestFactor(FactorTestParams{Expression: "$close / Ref($close, 5) - 1", Universe: "all"})
+		if err != nil {
+			t.Fatalf("因子测试失败: %v", err)
+		}
+		summary, report, err := engines.Backtester.RunBacktestWithReport(ctx, BacktestParams{
+			StrategyType: "TopkDropoutStrategy",
+			ModelID:      3,
+			ConfigJSON:   `{"topk": 5, "n_drop": 1}`,
+			Benchmark:    FakeBenchmark,
+		}, nil)
+		if err != nil {
+			t.Fatalf("回测失败: %v", err)
+		}
+		if report == nil || len(report.Trades) == 0 {
+			t.Fatal("假后端应执行原生回测并产生交易")
+		}
+		for _, position := range report.Positions {
+			if position.Instrument == FakeBenchmark {
+				t.Fatal("基准不应被选入组合")
+			}
+		}
+		return trained, tested, summary
+	}
+
+	trained1, tested1, summary1 := run(7)
+	trained2, tested2, summary2 := run(7)
+	if *trained1 != *trained2 || tested1.IC != tested2.IC || tested1.RankIC != tested2.RankIC || *summary1 != *summary2 {
+		t.Error("相同种子应得到相同结果")
+	}
+	if tested1.IC == 0 || tested1.Coverage == 0 {
+		t.Errorf("因子测试结果为空: %+v", tested1)
+	}
+	if _, tested3, summary3 := run(8); tested3.IC == tested1.IC || *summary3 == *summary1 {
+		t.Error("不同种子应生成不同的行情")
+	}
+}
+
+func TestFakeModelTrainer(t *testing.T) {
+	trainer := NewFakeModelTrainer(1)
+	var progress []int
+	result, err := trainer.TrainModelContext(context.Background(), ModelTrainingParams{ModelID: 5, ModelType: "lstm"}, func(p int, metrics map[string]float64) {
+		progress = append(progress, p)
+	})
+	if err != nil || result.ModelPath != "fake://models/5" || result.TestIC <= 0 || result.TestIC > result.TrainIC {
+		t.Fatalf("训练结果不符合预期: %+v, %v", result, err)
+	}
+	if len(progress) != 10 || progress[9] != 100 {
+		t.Errorf("进度为 %v", progress)
+	}
+
+	trainer.EpochDelay = time.Second
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := trainer.TrainModelContext(ctx, ModelTrainingParams{ModelType: "lstm"}, nil); !errors.Is(err, context.Canceled) || JobTermination(err) != JobCancelled {
+		t.Errorf("取消后应返回取消错误: %v", err)
+	}
+
+	evaluation, err := trainer.EvaluateModel(ModelEvaluationParams{ModelID: 5, TestStart: "2023-01-02", TestEnd: "2023-01-13"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if daily := evaluation.TestMetrics["daily_ic"].(map[string]interface{}); len(daily) != 10 {
+		t.Errorf("测试区间有10个工作日，得到 %d 个IC", len(daily))
+	}
+	again, _ := trainer.EvaluateModel(ModelEvaluationParams{ModelID: 5, TestStart: "2023-01-02", TestEnd: "2023-01-13"})
+	if !reflect.DeepEqual(evaluation, again) {
+		t.Error("相同参数的评估结果应一致")
+	}
+
+	comparison, err := trainer.CompareModels(ModelComparisonParams{ModelIDs: []uint{1, 2, 3}})
+	if err != nil || len(comparison.ComparisonMatrix) != 3 || comparison.BestModel["model_id"] != comparison.RankingResults["ic"].([]uint)[0] {
+		t.Errorf("模型对比结果不符合预期: %+v, %v", comparison, err)
+	}
+}
+
+func TestFakeBacktestAndFactorEngines(t *testing.T) {
+	engines := newTestFakeEngines(t, 1)
+
+	results, err := engines.Backtester.GetBacktestResults(BacktestResultsParams{StrategyID: 2})
+	if err != nil || len(results.PerformanceData["daily_returns"].(map[string]interface{})) == 0 {
+		t.Fatalf("回测结果为空: %v", err)
+	}
+	attribution, err := engines.Backtester.GetAttributionAnalysis(AttributionAnalysisParams{StrategyID: 2, Universe: "all"})
+	if err != nil || len(attribution.SectorAttribution) == 0 {
+		t.Errorf("行业归因为空: %+v, %v", attribution, err)
+	}
+	comparison, err := engines.Backtester.CompareStrategies(StrategyComparisonParams{StrategyIDs: []uint{1, 2}})
+	if err != nil || len(comparison.ComparisonMatrix) != 2 || comparison.BestStrategy == nil {
+		t.Errorf("策略对比结果不符合预期: %+v, %v", comparison, err)
+	}
+	report1, _ := engines.Backtester.ExportReport(ReportExportParams{StrategyIDs: []uint{1}, Format: "html"})
+	report2, _ := engines.Backtester.ExportReport(ReportExportParams{StrategyIDs: []uint{1}, Format: "html"})
+	if report1.ReportID != report2.ReportID {
+		t.Error("相同导出参数应得到相同的报告ID")
+	}
+
+	categories, err := engines.Factors.GetBuiltinFactorCategories()
+	if err != nil || len(categories) == 0 {
+		t.Fatalf("因子分类为空: %v", err)
+	}
+	for _, category := range categories {
+		factors, _ := engines.Factors.GetBuiltinFactorsByCategory(category.Name)
+		if len(factors) != category.Count {
+			t.Errorf("分类 %s 有 %d 个因子，计数为 %d", category.Name, len(factors), category.Count)
+		}
+		for _, factor := range factors {
+			if err := engines.Factors.ValidateExpression(factor.Expression); err != nil {
+				t.Errorf("内置因子 %s 表达式无效: %v", factor.Name, err)
+			}
+		}
+	}
+	analysis, err := engines.Factors.AnalyzeFactor("Mean($volume, 5) / Mean($volume, 20)")
+	if err != nil || analysis.BasicMetrics["coverage"].(float64) <= 0 || len(analysis.DistributionData) == 0 {
+		t.Errorf("因子分析结果不符合预期: %+v, %v", analysis, err)
+	}
+}
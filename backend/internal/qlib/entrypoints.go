@@ -0,0 +1,102 @@
+package qlib
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// EntryPointVersion 内置Python入口脚本的参数约定版本，与 entrypoints/_common.py 中的 ENTRYPOINT_VERSION 一致
+const EntryPointVersion = 1
+
+// entryPointFiles 内置的Python入口脚本
+//
+// 脚本内容固定，调用方的参数只以JSON写入标准输入，不会拼接进源码。
+//
+//go:embed entrypoints/*.py
+var entryPointFiles embed.FS
+
+// entryPointScript 返回拼接了公共部分的入口脚本
+func entryPointScript(name string) (string, error) {
+	common, err := entryPointFiles.ReadFile("entrypoints/_common.py")
+	if err != nil {
+		return "", fmt.Errorf("读取入口脚本公共部分失败: %v", err)
+	}
+	body, err := entryPointFiles.ReadFile("entrypoints/" + name + ".py")
+	if err != nil {
+		return "", fmt.Errorf("未知的入口脚本: %s", name)
+	}
+	return string(common) + "\n" + string(body), nil
+}
+
+// entryPointInput 入口脚本从标准输入读取的内容
+func entryPointInput(params interface{}) ([]byte, error) {
+	input, err := json.Marshal(map[string]interface{}{
+		"version": EntryPointVersion,
+		"params":  params,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("序列化参数失败: %v", err)
+	}
+	return input, nil
+}
+
+// RunEntryPoint 执行内置入口脚本，params 以JSON写入脚本标准输入
+func (c *QlibClient) RunEntryPoint(ctx context.Context, name string, params interface{}) ([]byte, error) {
+	if !c.initialized {
+		return nil, fmt.Errorf("Qlib客户端未初始化")
+	}
+	return c.runEntryPoint(ctx, name, params)
+}
+
+func (c *QlibClient) runEntryPoint(ctx context.Context, name string, params interface{}) ([]byte, error) {
+	script, err := entryPointScript(name)
+	if err != nil {
+		return nil, err
+	}
+	input, err := entryPointInput(params)
+	if err != nil {
+		return nil, err
+	}
+	return resolvePythonClient(c.client, c.pythonPath).Run(ctx, script, input)
+}
+
+// DefaultQlibFunctions CallQlibFunction 默认允许调用的模块和函数，函数名可以是属性链
+var DefaultQlibFunctions = map[string][]string{
+	"qlib.data": {"D.calendar", "D.instruments", "D.list_instruments", "D.features"},
+}
+
+var (
+	qlibModulePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+	qlibFunctionPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*(\.[A-Za-z][A-Za-z0-9_]*)*$`)
+)
+
+// AllowQlibFunction 允许 CallQlibFunction 调用额外的模块函数，应在客户端使用前调用
+func (c *QlibClient) AllowQlibFunction(modulePath, functionName string) error {
+	if !qlibModulePattern.MatchString(modulePath) || !qlibFunctionPattern.MatchString(functionName) {
+		return fmt.Errorf("非法的Qlib函数名: %s.%s", modulePath, functionName)
+	}
+	if c.allowedFunctions == nil {
+		c.allowedFunctions = make(map[string]bool)
+	}
+	c.allowedFunctions[modulePath+":"+functionName] = true
+	return nil
+}
+
+// qlibFunctionAllowed 检查模块函数是否在白名单中，函数名不允许以下划线开头的私有属性
+func (c *QlibClient) qlibFunctionAllowed(modulePath, functionName string) bool {
+	if !qlibModulePattern.MatchString(modulePath) || !qlibFunctionPattern.MatchString(functionName) {
+		return false
+	}
+	if c.allowedFunctions[modulePath+":"+functionName] {
+		return true
+	}
+	for _, name := range DefaultQlibFunctions[modulePath] {
+		if name == functionName {
+			return true
+		}
+	}
+	return false
+}
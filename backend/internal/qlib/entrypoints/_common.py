@@ -0,0 +1,28 @@
+# 入口脚本公共部分，由Go端拼接在每个入口脚本之前
+#
+# 参数以 {"version": ENTRYPOINT_VERSION, "params": {...}} 的JSON写入标准输入，
+# 脚本源码中不包含任何调用方输入。修改参数约定时同时递增此处和Go端的 EntryPointVersion。
+import json as _entry_json
+import re as _entry_re
+import sys as _entry_sys
+
+ENTRYPOINT_VERSION = 1
+
+_SAFE_NAME = _entry_re.compile(r"^[A-Za-z0-9][A-Za-z0-9_.-]*$")
+
+
+def read_params():
+    """读取Go端传入的参数，版本不一致时拒绝执行"""
+    payload = _entry_json.load(_entry_sys.stdin)
+    version = payload.get("version")
+    if version != ENTRYPOINT_VERSION:
+        raise SystemExit(f"入口脚本版本不匹配: 调用方 {version}, 脚本 {ENTRYPOINT_VERSION}")
+    return payload.get("params") or {}
+
+
+def safe_name(value):
+    """校验用于拼接文件路径的模型ID或任务ID，拒绝路径分隔符和 .."""
+    value = str(value)
+    if not _SAFE_NAME.match(value) or ".." in value:
+        raise ValueError(f"非法的名称: {value!r}")
+    return value
@@ -0,0 +1,124 @@
+# 计算因子表达式：params = {"factor_name", "expression", "universe", "frequency", "start_date", "end_date"}
+import json
+import qlib
+from qlib import data
+import pandas as pd
+import numpy as np
+from datetime import datetime
+
+try:
+	# 因子参数
+	params = read_params()
+	factor_name = params.get('factor_name', '')
+	expression = params['expression']
+	universe = params.get('universe', '')
+	frequency = params.get('frequency', '')
+	start_date = params.get('start_date', '')
+	end_date = params.get('end_date', '')
+	
+	# 设置股票池
+	if universe == 'csi300':
+		instruments = data.D.instruments(market='csi300')
+	elif universe == 'csi500':
+		instruments = data.D.instruments(market='csi500')
+	elif universe == 'all':
+		instruments = data.D.instruments(market='all')
+	else:
+		# 默认使用CSI300
+		instruments = data.D.instruments(market='csi300')
+	
+	# 计算因子
+	if frequency == 'day':
+		factor_data = data.D.features(
+			instruments=instruments,
+			fields=[expression],
+			start_time=start_date,
+			end_time=end_date,
+			freq='day'
+		)
+	elif frequency == 'minute':
+		factor_data = data.D.features(
+			instruments=instruments,
+			fields=[expression],
+			start_time=start_date,
+			end_time=end_date,
+			freq='1min'
+		)
+	else:
+		factor_data = data.D.features(
+			instruments=instruments,
+			fields=[expression],
+			start_time=start_date,
+			end_time=end_date,
+			freq='day'
+		)
+	
+	# 转换数据格式
+	factor_values = []
+	stats = {}
+	
+	if factor_data is not None and not factor_data.empty:
+		# 重置索引
+		factor_reset = factor_data.reset_index()
+		
+		for _, row in factor_reset.iterrows():
+			instrument = row['instrument'] if 'instrument' in row else str(row.name[0])
+			date = row['datetime'] if 'datetime' in row else str(row.name[1])
+			value = row[expression] if expression in row else None
+			
+			if pd.notna(value):
+				factor_values.append({
+					'instrument': instrument,
+					'date': str(date),
+					'value': float(value),
+					'is_valid': True
+				})
+			else:
+				factor_values.append({
+					'instrument': instrument,
+					'date': str(date),
+					'value': 0.0,
+					'is_valid': False
+				})
+		
+		# 计算统计指标
+		valid_values = [fv['value'] for fv in factor_values if fv['is_valid']]
+		if valid_values:
+			stats = {
+				'count': len(valid_values),
+				'mean': float(np.mean(valid_values)),
+				'std': float(np.std(valid_values)),
+				'min': float(np.min(valid_values)),
+				'max': float(np.max(valid_values)),
+				'median': float(np.median(valid_values)),
+				'skew': float(pd.Series(valid_values).skew()),
+				'kurt': float(pd.Series(valid_values).kurtosis()),
+				'coverage': len(valid_values) / len(factor_values)
+			}
+	
+	result = {
+		'success': True,
+		'factor_name': factor_name,
+		'data': factor_values,
+		'stats': stats,
+		'metadata': {
+			'expression': expression,
+			'universe': universe,
+			'frequency': frequency,
+			'start_date': start_date,
+			'end_date': end_date,
+			'total_points': len(factor_values)
+		}
+	}
+
+except Exception as e:
+	result = {
+		'success': False,
+		'factor_name': factor_name,
+		'data': [],
+		'stats': {},
+		'error': str(e),
+		'metadata': {}
+	}
+
+print(json.dumps(result, default=str))
@@ -0,0 +1,26 @@
+# 调用白名单内的Qlib函数：params = {"module", "function", "kwargs", "python_path"}
+#
+# 模块和函数按名称查找，function 可以是 D.features 这样的属性链，白名单由Go端校验。
+import importlib
+import json
+import sys
+
+try:
+	params = read_params()
+	if params.get('python_path'):
+		sys.path.append(params['python_path'])
+
+	target = importlib.import_module(params['module'])
+	for attr in params['function'].split('.'):
+		target = getattr(target, attr)
+
+	# 调用函数
+	result = target(**(params.get('kwargs') or {}))
+
+	# 序列化结果
+	output = {'success': True, 'data': result}
+
+except Exception as e:
+	output = {'success': False, 'error': str(e)}
+
+print(json.dumps(output, default=str))
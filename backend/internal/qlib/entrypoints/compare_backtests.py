@@ -0,0 +1,69 @@
+# 对比回测结果：params = {"task_ids"}
+import json
+import qlib
+from qlib.workflow import R
+import pandas as pd
+import numpy as np
+
+try:
+	params = read_params()
+	task_ids = [safe_name(task_id) for task_id in params.get('task_ids') or []]
+	
+	# 加载多个回测结果
+	portfolios = {}
+	performance_metrics = {}
+	
+	for task_id in task_ids:
+		try:
+			with R.start(experiment_name="backtest", recorder_id=task_id):
+				portfolio = R.load_object('portfolio')
+				if portfolio is not None:
+					portfolios[task_id] = portfolio
+					
+					# 计算性能指标
+					if not portfolio.empty:
+						returns = portfolio['return'] if 'return' in portfolio.columns else portfolio.iloc[:, 0]
+						cum_returns = (1 + returns).cumprod()
+						
+						total_return = cum_returns.iloc[-1] - 1
+						volatility = returns.std() * np.sqrt(252)
+						sharpe = (total_return - 0.03) / volatility if volatility > 0 else 0
+						
+						cum_max = cum_returns.expanding().max()
+						drawdown = (cum_returns - cum_max) / cum_max
+						max_drawdown = drawdown.min()
+						
+						performance_metrics[task_id] = {
+							'total_return': float(total_return),
+							'volatility': float(volatility),
+							'sharpe_ratio': float(sharpe),
+							'max_drawdown': float(max_drawdown)
+						}
+		except:
+			continue
+	
+	# 构建对比结果
+	comparison = {
+		'tasks': list(performance_metrics.keys()),
+		'metrics': performance_metrics,
+		'ranking': {
+			'by_return': sorted(performance_metrics.items(), key=lambda x: x[1]['total_return'], reverse=True),
+			'by_sharpe': sorted(performance_metrics.items(), key=lambda x: x[1]['sharpe_ratio'], reverse=True),
+			'by_drawdown': sorted(performance_metrics.items(), key=lambda x: x[1]['max_drawdown'], reverse=True)
+		},
+		'summary': f"对比了 {len(performance_metrics)} 个回测结果"
+	}
+	
+	result = {
+		'success': True,
+		'comparison': comparison
+	}
+
+except Exception as e:
+	result = {
+		'success': False,
+		'comparison': {},
+		'error': str(e)
+	}
+
+print(json.dumps(result, default=str))
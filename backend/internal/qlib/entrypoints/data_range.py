@@ -0,0 +1,35 @@
+# 获取数据日历范围：params = {"instrument"}
+import json
+import qlib
+from qlib import data
+import pandas as pd
+
+try:
+	params = read_params()
+	instrument = params.get('instrument', '')
+	
+	# 获取数据范围
+	calendar = data.D.calendar(freq='day')
+	
+	if calendar is not None and len(calendar) > 0:
+		start_date = str(calendar[0])
+		end_date = str(calendar[-1])
+		
+		result = {
+			'success': True,
+			'start_date': start_date,
+			'end_date': end_date
+		}
+	else:
+		result = {
+			'success': False,
+			'error': '无法获取数据日历'
+		}
+
+except Exception as e:
+	result = {
+		'success': False,
+		'error': str(e)
+	}
+
+print(json.dumps(result))
@@ -0,0 +1,57 @@
+# 统计单个字段：params = {"instrument", "field", "start_time", "end_time"}
+import json
+import qlib
+from qlib import data
+import pandas as pd
+import numpy as np
+
+try:
+	params = read_params()
+	instrument = params['instrument']
+	field = params['field']
+	start_time = params.get('start_time', '')
+	end_time = params.get('end_time', '')
+	
+	# 加载数据
+	df = data.D.features(
+		instruments=[instrument],
+		fields=[field],
+		start_time=start_time,
+		end_time=end_time,
+		freq='day'
+	)
+	
+	if df is not None and not df.empty:
+		values = df[field].dropna()
+		
+		if len(values) > 0:
+			stats = {
+				'count': len(values),
+				'mean': float(values.mean()),
+				'std': float(values.std()),
+				'min': float(values.min()),
+				'max': float(values.max()),
+				'median': float(values.median()),
+				'q25': float(values.quantile(0.25)),
+				'q75': float(values.quantile(0.75))
+			}
+		else:
+			stats = {}
+		
+		result = {
+			'success': True,
+			'stats': stats
+		}
+	else:
+		result = {
+			'success': False,
+			'error': '没有找到数据'
+		}
+
+except Exception as e:
+	result = {
+		'success': False,
+		'error': str(e)
+	}
+
+print(json.dumps(result, default=str))
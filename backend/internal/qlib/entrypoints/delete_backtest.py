@@ -0,0 +1,31 @@
+# 删除回测记录：params = {"task_id"}
+import json
+import qlib
+from qlib.workflow import R
+import os
+import shutil
+
+try:
+	params = read_params()
+	task_id = safe_name(params['task_id'])
+	
+	# 删除recorder记录
+	try:
+		recorder_path = f"./mlruns/0/{task_id}"
+		if os.path.exists(recorder_path):
+			shutil.rmtree(recorder_path)
+	except:
+		pass
+	
+	result = {
+		'success': True,
+		'message': f'回测结果 {task_id} 已删除'
+	}
+
+except Exception as e:
+	result = {
+		'success': False,
+		'error': str(e)
+	}
+
+print(json.dumps(result))
@@ -0,0 +1,27 @@
+# 删除模型文件：params = {"model_id"}
+import json
+import os
+
+try:
+	params = read_params()
+	model_id = safe_name(params['model_id'])
+	model_path = f"./models/{model_id}.pkl"
+	
+	if os.path.exists(model_path):
+		os.remove(model_path)
+		message = f"模型 {model_id} 已删除"
+	else:
+		message = f"模型 {model_id} 不存在"
+	
+	result = {
+		'success': True,
+		'message': message
+	}
+
+except Exception as e:
+	result = {
+		'success': False,
+		'error': str(e)
+	}
+
+print(json.dumps(result))
@@ -0,0 +1,131 @@
+# 评估预测结果：params = {"test_data", "actual_returns"}
+import json
+import pandas as pd
+import numpy as np
+from scipy import stats
+
+try:
+	params = read_params()
+	test_data = params.get('test_data') or []
+	actual_returns = params.get('actual_returns') or []
+	
+	# 构建DataFrame
+	pred_df = pd.DataFrame(test_data)
+	actual_df = pd.DataFrame(actual_returns)
+	
+	# 转换日期
+	pred_df['date'] = pd.to_datetime(pred_df['date'])
+	actual_df['date'] = pd.to_datetime(actual_df['date'])
+	
+	# 合并数据
+	merged = pd.merge(pred_df, actual_df, on=['instrument', 'date'], suffixes=('_pred', '_actual'))
+	merged = merged.dropna()
+	
+	metrics = {}
+	ic_analysis = {}
+	rank_ic = {}
+	
+	if len(merged) > 0:
+		# 基本指标
+		ic, _ = stats.pearsonr(merged['score'], merged['score_actual'])
+		rank_ic_val, _ = stats.spearmanr(merged['score'], merged['score_actual'])
+		
+		metrics['ic'] = float(ic) if not np.isnan(ic) else 0.0
+		metrics['rank_ic'] = float(rank_ic_val) if not np.isnan(rank_ic_val) else 0.0
+		metrics['mse'] = float(np.mean((merged['score'] - merged['score_actual']) ** 2))
+		metrics['mae'] = float(np.mean(np.abs(merged['score'] - merged['score_actual'])))
+		
+		# IC分析
+		daily_ic = {}
+		daily_rank_ic = {}
+		for date, group in merged.groupby('date'):
+			if len(group) >= 10:
+				daily_ic_val, _ = stats.pearsonr(group['score'], group['score_actual'])
+				daily_rank_ic_val, _ = stats.spearmanr(group['score'], group['score_actual'])
+				
+				if not np.isnan(daily_ic_val):
+					daily_ic[str(date)] = float(daily_ic_val)
+				if not np.isnan(daily_rank_ic_val):
+					daily_rank_ic[str(date)] = float(daily_rank_ic_val)
+		
+		ic_values = list(daily_ic.values())
+		rank_ic_values = list(daily_rank_ic.values())
+		
+		if ic_values:
+			ic_analysis = {
+				'mean': np.mean(ic_values),
+				'std': np.std(ic_values),
+				'ir': np.mean(ic_values) / np.std(ic_values) if np.std(ic_values) > 0 else 0,
+				'positive_rate': sum(1 for ic in ic_values if ic > 0) / len(ic_values),
+				'values': daily_ic
+			}
+		
+		if rank_ic_values:
+			rank_ic = {
+				'mean': np.mean(rank_ic_values),
+				'std': np.std(rank_ic_values), 
+				'ir': np.mean(rank_ic_values) / np.std(rank_ic_values) if np.std(rank_ic_values) > 0 else 0,
+				'positive_rate': sum(1 for ic in rank_ic_values if ic > 0) / len(rank_ic_values),
+				'values': daily_rank_ic
+			}
+		
+		# 简化的回报分析
+		merged['return'] = merged['score_actual']
+		merged = merged.sort_values('score', ascending=False)
+		
+		# 计算分位数回报
+		quantile_returns = []
+		quantile_size = len(merged) // 5
+		
+		for i in range(5):
+			start_idx = i * quantile_size
+			end_idx = (i + 1) * quantile_size if i < 4 else len(merged)
+			quantile_return = merged.iloc[start_idx:end_idx]['return'].mean()
+			quantile_returns.append(float(quantile_return))
+		
+		# 计算Sharpe比率（简化版）
+		if len(quantile_returns) > 0:
+			excess_return = quantile_returns[0] - quantile_returns[-1]  # 最高分位 - 最低分位
+			sharpe = excess_return / np.std(quantile_returns) if np.std(quantile_returns) > 0 else 0
+		else:
+			sharpe = 0
+		
+		# 胜率
+		win_rate = sum(1 for r in merged['return'] if r > 0) / len(merged) if len(merged) > 0 else 0
+		
+	else:
+		metrics = {'ic': 0, 'rank_ic': 0, 'mse': 0, 'mae': 0}
+		ic_analysis = {}
+		rank_ic = {}
+		sharpe = 0
+		win_rate = 0
+		quantile_returns = []
+	
+	evaluation = {
+		'metrics': metrics,
+		'ic_analysis': ic_analysis,
+		'rank_ic': rank_ic,
+		'sharpe': float(sharpe),
+		'max_drawdown': 0.05,  # 占位值
+		'annual_return': float(quantile_returns[0]) * 250 if quantile_returns else 0,
+		'win_rate': float(win_rate),
+		'details': {
+			'sample_count': len(merged),
+			'quantile_returns': quantile_returns,
+			'evaluation_period': len(daily_ic) if 'daily_ic' in locals() else 0
+		}
+	}
+	
+	result = {
+		'success': True,
+		'evaluation': evaluation
+	}
+
+except Exception as e:
+	result = {
+		'success': False,
+		'evaluation': {},
+		'error': str(e)
+	}
+
+print(json.dumps(result, default=str))
@@ -0,0 +1,51 @@
+# 初始化Qlib：params = {"config": QlibConfig, "python_path": 附加的模块搜索路径}
+import json
+import sys
+
+try:
+	params = read_params()
+	if params.get('python_path'):
+		sys.path.append(params['python_path'])
+
+	import qlib
+	from qlib.config import REG_CN, REG_US
+
+	config = params.get('config') or {}
+	region_name = 'cn' if config.get('region') == 'cn' else 'us'
+
+	# 设置数据目录
+	data_dir = config.get('data_dir') or f'~/.qlib/qlib_data/{region_name}'
+
+	# 设置区域配置
+	region = REG_CN if region_name == 'cn' else REG_US
+
+	exp_name = config.get('exp_name') or ''
+	redis_host = config.get('redis_host') or ''
+	mongo_host = config.get('mongo_host') or ''
+
+	# 初始化qlib
+	qlib.init(
+		provider_uri=data_dir,
+		region=region,
+		dataset_cache=None,
+		auto_mount=bool(config.get('mount')),
+		exp_manager={
+			'class': 'MLflowExpManager',
+			'module_path': 'qlib.workflow.expm',
+			'kwargs': {
+				'uri': 'file://mlruns',
+				'default_exp_name': exp_name
+			}
+		} if exp_name else None,
+		redis_host=redis_host or None,
+		redis_port=config.get('redis_port') if redis_host else None,
+		mongo_host=mongo_host or None,
+		mongo_port=config.get('mongo_port') if mongo_host else None
+	)
+
+	result = {'success': True, 'message': 'Qlib initialized successfully'}
+
+except Exception as e:
+	result = {'success': False, 'message': str(e)}
+
+print(json.dumps(result))
@@ -0,0 +1,44 @@
+# 获取股票列表：params = {"market"}
+import json
+import qlib
+from qlib import data
+
+try:
+	# 获取股票列表
+	params = read_params()
+	market = params.get('market', '')
+	
+	if market.lower() == 'cn' or market.lower() == 'csi300':
+		# 中国市场 - CSI300成分股
+		instruments = data.D.instruments(market='csi300')
+	elif market.lower() == 'us' or market.lower() == 'sp500':
+		# 美国市场 - S&P500成分股
+		instruments = data.D.instruments(market='sp500')
+	elif market.lower() == 'all':
+		# 获取所有可用股票
+		instruments = data.D.instruments(market='all')
+	else:
+		# 默认获取当前配置的市场
+		instruments = data.D.instruments()
+	
+	# 转换为列表
+	if hasattr(instruments, 'tolist'):
+		instrument_list = instruments.tolist()
+	else:
+		instrument_list = list(instruments)
+	
+	result = {
+		'success': True,
+		'instruments': instrument_list,
+		'count': len(instrument_list)
+	}
+
+except Exception as e:
+	result = {
+		'success': False,
+		'instruments': [],
+		'count': 0,
+		'error': str(e)
+	}
+
+print(json.dumps(result))
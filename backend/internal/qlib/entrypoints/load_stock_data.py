@@ -0,0 +1,83 @@
+# 加载行情和特征数据：params = {"instruments", "start_time", "end_time", "fields", "frequency"}
+import json
+import qlib
+from qlib import data
+import pandas as pd
+import numpy as np
+from datetime import datetime
+
+try:
+	# 解析参数
+	params = read_params()
+	instruments = params.get('instruments') or []
+	start_time = params.get('start_time', '')
+	end_time = params.get('end_time', '')
+	fields = params.get('fields') or []
+	frequency = params.get('frequency', '')
+	
+	# 如果没有指定字段，使用默认字段
+	if not fields:
+		fields = ['$open', '$high', '$low', '$close', '$volume']
+	
+	# 加载数据
+	if frequency == 'minute':
+		# 分钟级数据
+		df = data.D.features(
+			instruments=instruments,
+			fields=fields,
+			start_time=start_time,
+			end_time=end_time,
+			freq='1min'
+		)
+	else:
+		# 日级数据
+		df = data.D.features(
+			instruments=instruments,
+			fields=fields,
+			start_time=start_time,
+			end_time=end_time,
+			freq='day'
+		)
+	
+	# 转换数据格式
+	data_list = []
+	
+	if df is not None and not df.empty:
+		# 重置索引以便访问instrument和datetime
+		df_reset = df.reset_index()
+		
+		for _, row in df_reset.iterrows():
+			instrument = row['instrument'] if 'instrument' in row else str(row.name[0])
+			date = row['datetime'] if 'datetime' in row else str(row.name[1])
+			
+			# 构建特征字典
+			features = {}
+			for field in fields:
+				field_clean = field.replace('$', '').lower()
+				if field in row and pd.notna(row[field]):
+					features[field_clean] = float(row[field])
+				else:
+					features[field_clean] = None
+			
+			data_list.append({
+				'instrument': instrument,
+				'date': str(date),
+				'features': features
+			})
+	
+	result = {
+		'success': True,
+		'data': data_list,
+		'count': len(data_list),
+		'error': None
+	}
+
+except Exception as e:
+	result = {
+		'success': False,
+		'data': [],
+		'count': 0,
+		'error': str(e)
+	}
+
+print(json.dumps(result, default=str))
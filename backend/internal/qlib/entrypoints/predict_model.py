@@ -0,0 +1,107 @@
+# 模型预测：params = {"model_id", "instruments", "start_date", "end_date"}
+import json
+import qlib
+from qlib import data
+from qlib.workflow import R
+import pandas as pd
+import numpy as np
+import pickle
+import os
+
+try:
+	params = read_params()
+	model_id = safe_name(params['model_id'])
+	instruments = params.get('instruments') or []
+	start_date = params.get('start_date', '')
+	end_date = params.get('end_date', '')
+	
+	# 加载模型
+	model_path = f"./models/{model_id}.pkl"
+	if not os.path.exists(model_path):
+		raise FileNotFoundError(f"模型文件不存在: {model_path}")
+	
+	# 使用recorder加载模型
+	try:
+		with R.start(experiment_name="model_prediction", recorder_id=model_id):
+			model = R.load_object('model')
+	except:
+		# 如果recorder加载失败，尝试直接加载pickle文件
+		with open(model_path, 'rb') as f:
+			model = pickle.load(f)
+	
+	# 构建预测数据集
+	dataset_config = {
+		'class': 'DatasetH',
+		'module_path': 'qlib.data.dataset',
+		'kwargs': {
+			'handler': {
+				'class': 'Alpha158',
+				'module_path': 'qlib.contrib.data.handler',
+				'kwargs': {
+					'start_time': start_date,
+					'end_time': end_date,
+					'instruments': instruments,
+					'infer_processors': [
+						{
+							'class': 'RobustZScoreNorm',
+							'kwargs': {'fields_group': 'feature', 'clip_outlier': True}
+						},
+						{
+							'class': 'Fillna',
+							'kwargs': {'fields_group': 'feature'}
+						}
+					]
+				}
+			},
+			'segments': {
+				'test': (start_date, end_date)
+			}
+		}
+	}
+	
+	from qlib.utils import init_instance_by_config
+	dataset = init_instance_by_config(dataset_config)
+	
+	# 进行预测
+	predictions = model.predict(dataset)
+	
+	# 转换预测结果
+	pred_list = []
+	if predictions is not None and not predictions.empty:
+		pred_reset = predictions.reset_index()
+		
+		for _, row in pred_reset.iterrows():
+			instrument = row['instrument'] if 'instrument' in row else str(row.name[0])
+			date = row['datetime'] if 'datetime' in row else str(row.name[1])
+			score = row[0] if len(row) > 2 else row.iloc[-1]  # 预测分数
+			
+			pred_list.append({
+				'instrument': instrument,
+				'date': str(date),
+				'score': float(score) if pd.notna(score) else 0.0
+			})
+		
+		# 排序
+		pred_list.sort(key=lambda x: x['score'], reverse=True)
+		for i, pred in enumerate(pred_list):
+			pred['rank'] = i + 1
+	
+	result = {
+		'success': True,
+		'predictions': pred_list,
+		'metadata': {
+			'model_id': model_id,
+			'prediction_count': len(pred_list),
+			'date_range': [start_date, end_date]
+		}
+	}
+
+except Exception as e:
+	result = {
+		'success': False,
+		'predictions': [],
+		'error': str(e),
+		'metadata': {}
+	}
+
+print(json.dumps(result, default=str))
@@ -0,0 +1,198 @@
+# 训练模型：params = ModelConfig
+import json
+import qlib
+from qlib import data
+from qlib.workflow import R
+from qlib.workflow.record_temp import SignalRecord, PortAnaRecord, SigAnaRecord
+from qlib.utils import flatten_dict, init_instance_by_config
+import pandas as pd
+import numpy as np
+import uuid
+from datetime import datetime
+
+try:
+	# 解析配置
+	config = read_params()
+	
+	# 生成任务ID
+	task_id = str(uuid.uuid4())[:8]
+	model_id = f"{config['model_type']}_{task_id}"
+	
+	# 构建数据集
+	instruments = config['dataset']['instruments']
+	if not instruments:
+		instruments = data.D.instruments(market='csi300')
+	
+	start_time = config['dataset']['start_time'][:10] if config['dataset']['start_time'] else '2020-01-01'
+	end_time = config['dataset']['end_time'][:10] if config['dataset']['end_time'] else '2023-12-31'
+	
+	# 构建特征
+	fields = config.get('features', [])
+	if not fields:
+		fields = ['$open', '$high', '$low', '$close', '$volume', 'Ref($close, 1)', 'Mean($close, 5)', 'Mean($close, 10)']
+	
+	# 构建标签
+	label = config.get('label', '($close - Ref($close, 1)) / Ref($close, 1)')
+	
+	# 创建数据集配置
+	dataset_config = {
+		'class': 'DatasetH',
+		'module_path': 'qlib.data.dataset',
+		'kwargs': {
+			'handler': {
+				'class': 'Alpha158',
+				'module_path': 'qlib.contrib.data.handler',
+				'kwargs': {
+					'start_time': start_time,
+					'end_time': end_time,
+					'fit_start_time': start_time,
+					'fit_end_time': end_time,
+					'instruments': instruments,
+					'infer_processors': [
+						{
+							'class': 'RobustZScoreNorm',
+							'kwargs': {'fields_group': 'feature', 'clip_outlier': True}
+						},
+						{
+							'class': 'Fillna',
+							'kwargs': {'fields_group': 'feature'}
+						}
+					],
+					'learn_processors': [
+						{
+							'class': 'DropnaLabel'
+						},
+						{
+							'class': 'CSRankNorm',
+							'kwargs': {'fields_group': 'label'}
+						}
+					],
+					'label': [label]
+				}
+			},
+			'segments': {
+				'train': (start_time, '2022-12-31'),
+				'valid': ('2023-01-01', '2023-06-30'), 
+				'test': ('2023-07-01', end_time)
+			}
+		}
+	}
+	
+	# 根据模型类型构建模型配置
+	if config['model_type'] == 'lgb':
+		model_config = {
+			'class': 'LGBModel',
+			'module_path': 'qlib.contrib.model.gbdt',
+			'kwargs': dict(config.get('parameters', {
+				'objective': 'regression',
+				'num_leaves': 60,
+				'learning_rate': 0.05,
+				'feature_fraction': 0.9,
+				'bagging_fraction': 0.8,
+				'bagging_freq': 5,
+				'verbose': -1,
+				'n_estimators': 100
+			}))
+		}
+	elif config['model_type'] == 'xgb':
+		model_config = {
+			'class': 'XGBModel',
+			'module_path': 'qlib.contrib.model.xgboost',
+			'kwargs': dict(config.get('parameters', {
+				'max_depth': 6,
+				'learning_rate': 0.05,
+				'n_estimators': 100,
+				'subsample': 0.8,
+				'colsample_bytree': 0.8
+			}))
+		}
+	elif config['model_type'] == 'linear':
+		model_config = {
+			'class': 'LinearModel',
+			'module_path': 'qlib.contrib.model.linear',
+			'kwargs': dict(config.get('parameters', {
+				'estimator': 'ridge'
+			}))
+		}
+	else:
+		model_config = {
+			'class': 'LGBModel',
+			'module_path': 'qlib.contrib.model.gbdt',
+			'kwargs': config.get('parameters', {})
+		}
+	
+	# 初始化数据集和模型
+	dataset = init_instance_by_config(dataset_config)
+	model = init_instance_by_config(model_config)
+	
+	# 训练模型
+	with R.start(experiment_name="model_training", recorder_id=task_id):
+		# 训练
+		model.fit(dataset)
+		
+		# 预测
+		pred = model.predict(dataset)
+		
+		# 计算评估指标
+		if pred is not None and not pred.empty:
+			# 准备评估数据
+			test_pred = pred.loc[pd.IndexSlice[:, '2023-07-01':], :]
+			test_label = dataset.prepare(['test'])['test']['label']
+			
+			# 计算基本指标
+			from scipy.stats import pearsonr, spearmanr
+			
+			# 对齐预测和标签
+			aligned = pd.concat([test_pred, test_label], axis=1, join='inner')
+			aligned.columns = ['pred', 'label']
+			aligned = aligned.dropna()
+			
+			metrics = {}
+			if len(aligned) > 0:
+				ic, _ = pearsonr(aligned['pred'], aligned['label'])
+				rank_ic, _ = spearmanr(aligned['pred'], aligned['label'])
+				metrics['ic'] = float(ic) if not np.isnan(ic) else 0.0
+				metrics['rank_ic'] = float(rank_ic) if not np.isnan(rank_ic) else 0.0
+				metrics['mse'] = float(np.mean((aligned['pred'] - aligned['label']) ** 2))
+				metrics['mae'] = float(np.mean(np.abs(aligned['pred'] - aligned['label'])))
+			
+			# 保存记录
+			R.save_objects(model=model)
+			
+			model_path = f"./models/{model_id}.pkl"
+			
+			result = {
+				'success': True,
+				'model_id': model_id,
+				'task_id': task_id,
+				'metrics': metrics,
+				'model_path': model_path,
+				'logs': [f"模型训练完成: {datetime.now()}", f"样本数量: {len(aligned)}"],
+				'metadata': {
+					'model_type': config['model_type'],
+					'dataset_config': dataset_config,
+					'model_config': model_config,
+					'sample_count': len(aligned)
+				}
+			}
+		else:
+			result = {
+				'success': False,
+				'model_id': model_id,
+				'task_id': task_id,
+				'error': '模型预测结果为空',
+				'logs': ['训练过程中出现问题'],
+				'metadata': {}
+			}
+
+except Exception as e:
+	result = {
+		'success': False,
+		'model_id': '',
+		'task_id': '',
+		'error': str(e),
+		'logs': [f"训练失败: {str(e)}"],
+		'metadata': {}
+	}
+
+print(json.dumps(result, default=str))
@@ -0,0 +1,39 @@
+# 校验因子表达式：params = {"expression"}
+import json
+import qlib
+from qlib import data
+
+try:
+	params = read_params()
+	expression = params['expression']
+	
+	# 尝试解析表达式
+	# 这里使用简单的测试数据验证表达式语法
+	test_instruments = ['000001.SZ']  # 使用单个股票测试
+	test_start = '2023-01-01'
+	test_end = '2023-01-02'
+	
+	# 尝试计算表达式
+	test_data = data.D.features(
+		instruments=test_instruments,
+		fields=[expression],
+		start_time=test_start,
+		end_time=test_end,
+		freq='day'
+	)
+	
+	# 如果没有异常，则表达式有效
+	result = {
+		'success': True,
+		'valid': True,
+		'message': '表达式语法有效'
+	}
+
+except Exception as e:
+	result = {
+		'success': True,
+		'valid': False,
+		'message': str(e)
+	}
+
+print(json.dumps(result))
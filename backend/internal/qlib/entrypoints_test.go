@@ -0,0 +1,214 @@
+package qlib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hostileInputs 试图跳出字符串字面量或注入代码的输入
+var hostileInputs = []string{
+	`'); import os; os.system('touch /tmp/pwned')#`,
+	`''' + __import__('os').getcwd() + '''`,
+	`"""; raise SystemExit(3); """`,
+	"line1\nimport os\nos._exit(4)",
+	`C:\data\new\table`,
+	`{"version": 0}`,
+	"中文 🚀 \x00 tail",
+}
+
+// recordingClient 记录脚本和标准输入，返回固定输出
+type recordingClient struct {
+	mu      sync.Mutex
+	scripts []string
+	inputs  [][]byte
+	output  string
+}
+
+func (c *recordingClient) Run(ctx context.Context, script string, input []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.scripts = append(c.scripts, script)
+	c.inputs = append(c.inputs, input)
+	return []byte(c.output), nil
+}
+
+func newEntryPointClient(client Client) *QlibClient {
+	c := NewQlibClient()
+	c.SetPythonClient(client)
+	c.initialized = true
+	return c
+}
+
+func decodeEntryPointInput(t *testing.T, input []byte) map[string]interface{} {
+	t.Helper()
+	var payload struct {
+		Version int                    `json:"version"`
+		Params  map[string]interface{} `json:"params"`
+	}
+	if err := json.Unmarshal(input, &payload); err != nil {
+		t.Fatalf("标准输入不是JSON: %v", err)
+	}
+	if payload.Version != EntryPointVersion {
+		t.Errorf("参数版本为 %d，应为 %d", payload.Version, EntryPointVersion)
+	}
+	return payload.Params
+}
+
+func TestEntryPointsKeepInputsOutOfSource(t *testing.T) {
+	t.Setenv("QLIB_OUTPUT_DIR", t.TempDir())
+	ctx := context.Background()
+	for _, hostile := range hostileInputs {
+		recorder := &recordingClient{output: `{"success": true}`}
+		client := newEntryPointClient(recorder)
+
+		if err := client.Initialize(ctx, QlibConfig{Region: hostile, DataDir: hostile, ExpName: hostile, RedisHost: hostile, MongoHost: hostile}); err != nil {
+			t.Fatalf("初始化失败: %v", err)
+		}
+		NewDataLoader(client).GetInstrumentList(ctx, hostile)
+		NewFactorCalculator(client).ValidateFactorExpression(ctx, hostile)
+		NewModelInterface(client).DeleteModel(ctx, hostile)
+		NewBacktestInterface(client).GetBacktestReport(ctx, hostile, hostile)
+		NewWorkflowRunner(client).RunWorkflow(ctx, WorkflowConfig{Market: hostile}, "wf-test")
+
+		names := []string{"init_qlib", "instrument_list", "validate_factor", "delete_model", "backtest_report", "run_workflow"}
+		if len(recorder.scripts) != len(names) {
+			t.Fatalf("执行了 %d 个脚本，应为 %d 个", len(recorder.scripts), len(names))
+		}
+		for i, name := range names {
+			script, err := entryPointScript(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if recorder.scripts[i] != script {
+				t.Errorf("%s 执行的不是内置入口脚本", name)
+			}
+			if quoted, _ := json.Marshal(hostile); !bytes.Contains(recorder.inputs[i], quoted) {
+				t.Errorf("%s 的参数应写入标准输入: %s", name, recorder.inputs[i])
+			}
+		}
+
+		params := decodeEntryPointInput(t, recorder.inputs[0])
+		config := params["config"].(map[string]interface{})
+		if config["data_dir"] != hostile || config["exp_name"] != hostile {
+			t.Errorf("初始化参数应原样传递: %v", config)
+		}
+		if params := decodeEntryPointInput(t, recorder.inputs[3]); params["model_id"] != hostile {
+			t.Errorf("模型ID应原样传递: %v", params)
+		}
+	}
+}
+
+func TestCallQlibFunctionWhitelist(t *testing.T) {
+	ctx := context.Background()
+	recorder := &recordingClient{output: `{"success": true, "data": null}`}
+	client := newEntryPointClient(recorder)
+
+	rejected := [][2]string{
+		{"os", "system"},
+		{"subprocess", "run"},
+		{"qlib.data", "D.features; import os"},
+		{"qlib.data", "D.__class__"},
+		{"qlib.data", "__import__"},
+		{"qlib.data import D; import os #", "D"},
+		{"qlib.data", ""},
+		{"", "D.features"},
+	}
+	for _, call := range rejected {
+		if _, err := client.CallQlibFunction(ctx, call[0], call[1], nil); err == nil {
+			t.Errorf("应拒绝调用 %q.%q", call[0], call[1])
+		}
+	}
+	if len(recorder.scripts) != 0 {
+		t.Fatalf("被拒绝的调用不应执行脚本")
+	}
+	if err := client.AllowQlibFunction("os", "system; rm -rf /"); err == nil {
+		t.Error("非法函数名不应加入白名单")
+	}
+
+	hostile := hostileInputs[0]
+	if _, err := client.CallQlibFunction(ctx, "qlib.data", "D.features", map[string]interface{}{"fields": []string{hostile}}); err != nil {
+		t.Fatalf("白名单内的函数应可调用: %v", err)
+	}
+	params := decodeEntryPointInput(t, recorder.inputs[0])
+	if params["module"] != "qlib.data" || params["function"] != "D.features" {
+		t.Errorf("调用参数不符合预期: %v", params)
+	}
+	if fields := params["kwargs"].(map[string]interface{})["fields"].([]interface{}); fields[0] != hostile {
+		t.Errorf("关键字参数应原样传递: %v", fields)
+	}
+}
+
+func TestEntryPointsWithPython(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("未安装python3")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	marker := filepath.Join(t.TempDir(), "pwned")
+	client := newEntryPointClient(NewProcessClient(python))
+
+	// 参数经Python原样往返，注入的代码不会执行
+	if err := client.AllowQlibFunction("json", "dumps"); err != nil {
+		t.Fatal(err)
+	}
+	for _, hostile := range append(hostileInputs, `'); open('`+marker+`', 'w')#`) {
+		output, err := client.CallQlibFunction(ctx, "json", "dumps", map[string]interface{}{"obj": hostile})
+		if err != nil {
+			t.Fatalf("调用失败: %v", err)
+		}
+		var result struct {
+			Success bool   `json:"success"`
+			Data    string `json:"data"`
+		}
+		if err := json.Unmarshal(output, &result); err != nil || !result.Success {
+			t.Fatalf("解析输出失败: %s, %v", output, err)
+		}
+		var echoed string
+		if err := json.Unmarshal([]byte(result.Data), &echoed); err != nil || echoed != hostile {
+			t.Errorf("参数往返后为 %q，应为 %q", echoed, hostile)
+		}
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("注入的代码被执行")
+	}
+
+	// 拼接文件路径的ID拒绝路径穿越
+	models := NewModelInterface(client)
+	for _, id := range []string{"../../etc/passwd", "/tmp/x", "a/../b", "..", "x'; import os"} {
+		if err := models.DeleteModel(ctx, id); err == nil || !strings.Contains(err.Error(), "非法的名称") {
+			t.Errorf("模型ID %q 应被拒绝: %v", id, err)
+		}
+	}
+	if err := models.DeleteModel(ctx, "lgb_missing-01"); err != nil {
+		t.Errorf("合法模型ID应可删除: %v", err)
+	}
+
+	// 版本不一致时入口脚本拒绝执行
+	script, _ := entryPointScript("call_function")
+	if _, err := NewProcessClient(python).Run(ctx, script, []byte(`{"version": 0, "params": {}}`)); err == nil || !strings.Contains(err.Error(), "版本不匹配") {
+		t.Errorf("版本不一致应失败: %v", err)
+	}
+}
+
+func TestEntryPointVersionMatchesScripts(t *testing.T) {
+	common, err := entryPointFiles.ReadFile("entrypoints/_common.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(common), "ENTRYPOINT_VERSION = "+strconv.Itoa(EntryPointVersion)+"\n") {
+		t.Errorf("_common.py 的版本应为 %d", EntryPointVersion)
+	}
+	if _, err := entryPointScript("../client"); err == nil {
+		t.Error("未知入口脚本应返回错误")
+	}
+}
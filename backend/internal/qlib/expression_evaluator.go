@@ -0,0 +1,873 @@
+package qlib
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// FactorEvaluator 原生因子表达式计算引擎
+//
+// 计算语义与Qlib保持一致：滚动窗口使用 min_periods=1 并忽略窗口内的 NaN，
+// 窗口为0时表示扩展窗口；算术运算中 NaN 向后传播，比较运算遇到 NaN 返回0。
+type FactorEvaluator struct {
+	workers int
+}
+
+// NewFactorEvaluator 创建计算引擎，workers<=0时使用CPU核数
+func NewFactorEvaluator(workers int) *FactorEvaluator {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &FactorEvaluator{workers: workers}
+}
+
+// evalValue 中间计算结果，常量不展开为序列
+type evalValue struct {
+	scalar   float64
+	isScalar bool
+	series   [][]float64 // [证券][交易日]
+}
+
+// at 返回第i只证券的序列视图
+func (v evalValue) at(i, length int) []float64 {
+	if !v.isScalar {
+		return v.series[i]
+	}
+	series := make([]float64, length)
+	for t := range series {
+		series[t] = v.scalar
+	}
+	return series
+}
+
+// EvaluateExpression 解析并计算表达式
+func (e *FactorEvaluator) EvaluateExpression(ctx context.Context, expression string, frame *MarketFrame) (*FactorFrame, error) {
+	parsed, err := ParseFactorExpression(expression, nil)
+	if err != nil {
+		return nil, err
+	}
+	return e.Evaluate(ctx, parsed, frame)
+}
+
+// Evaluate 在行情数据帧上计算已解析的表达式
+func (e *FactorEvaluator) Evaluate(ctx context.Context, parsed *ParsedExpression, frame *MarketFrame) (*FactorFrame, error) {
+	if frame == nil {
+		return nil, fmt.Errorf("行情数据帧为空")
+	}
+	for _, field := range parsed.Fields {
+		if _, ok := frame.Fields[field]; !ok {
+			return nil, fmt.Errorf("行情数据中缺少字段: %s", field)
+		}
+	}
+
+	value, err := e.eval(ctx, parsed.Root, frame)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([][]float64, len(frame.Instruments))
+	for i := range values {
+		values[i] = value.at(i, len(frame.Calendar))
+	}
+	return &FactorFrame{Calendar: frame.Calendar, Instruments: frame.Instruments, Values: values}, nil
+}
+
+// ExpressionWindow 计算表达式需要向前（lookback）和向后（lookahead）额外加载的交易日数
+func ExpressionWindow(node ExprNode) (lookback, lookahead int) {
+	switch n := node.(type) {
+	case *UnaryExpr:
+		return ExpressionWindow(n.X)
+	case *BinaryExpr:
+		lb1, la1 := ExpressionWindow(n.X)
+		lb2, la2 := ExpressionWindow(n.Y)
+		return maxInt(lb1, lb2), maxInt(la1, la2)
+	case *CallExpr:
+		for _, arg := range n.Args {
+			lb, la := ExpressionWindow(arg)
+			lookback = maxInt(lookback, lb)
+			lookahead = maxInt(lookahead, la)
+		}
+		if len(n.Args) < 2 {
+			return lookback, lookahead
+		}
+		window, ok := constantValue(n.Args[len(n.Args)-1])
+		if n.Func == "Quantile" {
+			window, ok = constantValue(n.Args[1])
+		}
+		if !ok {
+			return lookback, lookahead
+		}
+		switch n.Func {
+		case "Ref", "Delta":
+			if window >= 0 {
+				lookback += int(window)
+			} else {
+				lookahead += int(-window)
+			}
+		case "Power", "If", "Greater", "Less":
+		default:
+			if window > 0 {
+				lookback += int(window) - 1
+			}
+		}
+	}
+	return lookback, lookahead
+}
+
+// eval 递归计算节点
+func (e *FactorEvaluator) eval(ctx context.Context, node ExprNode, frame *MarketFrame) (evalValue, error) {
+	if err := ctx.Err(); err != nil {
+		return evalValue{}, err
+	}
+
+	switch n := node.(type) {
+	case *NumberLit:
+		return evalValue{scalar: n.Value, isScalar: true}, nil
+
+	case *FieldRef:
+		columns, ok := frame.Fields[n.Name]
+		if !ok {
+			return evalValue{}, fmt.Errorf("行情数据中缺少字段: %s", n.Name)
+		}
+		return evalValue{series: columns}, nil
+
+	case *UnaryExpr:
+		x, err := e.eval(ctx, n.X, frame)
+		if err != nil {
+			return evalValue{}, err
+		}
+		return e.mapUnary(x, func(v float64) float64 { return -v }), nil
+
+	case *BinaryExpr:
+		x, err := e.eval(ctx, n.X, frame)
+		if err != nil {
+			return evalValue{}, err
+		}
+		y, err := e.eval(ctx, n.Y, frame)
+		if err != nil {
+			return evalValue{}, err
+		}
+		op, err := binaryOperator(n.Op)
+		if err != nil {
+			return evalValue{}, err
+		}
+		return e.mapBinary(x, y, len(frame.Calendar), op), nil
+
+	case *CallExpr:
+		return e.evalCall(ctx, n, frame)
+	}
+	return evalValue{}, fmt.Errorf("未知的表达式节点")
+}
+
+// evalCall 计算函数调用
+func (e *FactorEvaluator) evalCall(ctx context.Context, call *CallExpr, frame *MarketFrame) (evalValue, error) {
+	length := len(frame.Calendar)
+	args := make([]evalValue, 0, len(call.Args))
+	consts := make([]float64, len(call.Args))
+	for i, arg := range call.Args {
+		if c, ok := constantValue(arg); ok {
+			consts[i] = c
+			args = append(args, evalValue{scalar: c, isScalar: true})
+			continue
+		}
+		value, err := e.eval(ctx, arg, frame)
+		if err != nil {
+			return evalValue{}, err
+		}
+		args = append(args, value)
+	}
+	window := func(i int) int { return int(consts[i]) }
+
+	switch call.Func {
+	case "Abs":
+		return e.mapUnary(args[0], math.Abs), nil
+	case "Sign":
+		return e.mapUnary(args[0], signValue), nil
+	case "Log":
+		return e.mapUnary(args[0], math.Log), nil
+	case "Power":
+		return e.mapBinary(args[0], args[1], length, math.Pow), nil
+	case "Greater":
+		return e.mapBinary(args[0], args[1], length, nanMax), nil
+	case "Less":
+		return e.mapBinary(args[0], args[1], length, nanMin), nil
+	case "If":
+		return e.mapSeries(ctx, length, len(frame.Instruments), func(i int) []float64 {
+			cond, left, right := args[0].at(i, length), args[1].at(i, length), args[2].at(i, length)
+			out := make([]float64, length)
+			for t := range out {
+				// 与numpy一致：NaN作为条件时视为真
+				if cond[t] != 0 {
+					out[t] = left[t]
+				} else {
+					out[t] = right[t]
+				}
+			}
+			return out
+		})
+	case "Ref":
+		n := window(1)
+		return e.mapSeries(ctx, length, len(frame.Instruments), func(i int) []float64 {
+			return shiftSeries(args[0].at(i, length), n)
+		})
+	case "Delta":
+		n := window(1)
+		return e.mapSeries(ctx, length, len(frame.Instruments), func(i int) []float64 {
+			x := args[0].at(i, length)
+			shifted := shiftSeries(x, n)
+			for t := range shifted {
+				shifted[t] = x[t] - shifted[t]
+			}
+			return shifted
+		})
+	case "EMA":
+		n := consts[1]
+		return e.mapSeries(ctx, length, len(frame.Instruments), func(i int) []float64 {
+			return emaSeries(args[0].at(i, length), n)
+		})
+	case "Corr", "Cov":
+		n := window(2)
+		isCorr := call.Func == "Corr"
+		return e.mapSeries(ctx, length, len(frame.Instruments), func(i int) []float64 {
+			return rollingPair(args[0].at(i, length), args[1].at(i, length), n, isCorr)
+		})
+	case "Quantile":
+		n, q := window(1), consts[2]
+		return e.mapSeries(ctx, length, len(frame.Instruments), func(i int) []float64 {
+			return rollingApply(args[0].at(i, length), n, 1, func(w []float64) float64 {
+				sorted := append([]float64(nil), w...)
+				sort.Float64s(sorted)
+				return quantileSorted(sorted, q)
+			})
+		})
+	case "Slope", "Rsquare", "Resi":
+		n := window(1)
+		kind := call.Func
+		return e.mapSeries(ctx, length, len(frame.Instruments), func(i int) []float64 {
+			return rollingRegression(args[0].at(i, length), n, kind)
+		})
+	case "Rank":
+		if len(args) == 1 {
+			return crossSectional(args[0], length, len(frame.Instruments), csRank), nil
+		}
+		n := window(1)
+		return e.mapSeries(ctx, length, len(frame.Instruments), func(i int) []float64 {
+			return rollingRank(args[0].at(i, length), n)
+		})
+	case "IdxMax", "IdxMin":
+		n := window(1)
+		findMax := call.Func == "IdxMax"
+		return e.mapSeries(ctx, length, len(frame.Instruments), func(i int) []float64 {
+			return rollingArgExtreme(args[0].at(i, length), n, findMax)
+		})
+	case "CSRank":
+		return crossSectional(args[0], length, len(frame.Instruments), csRank), nil
+	case "CSZScore":
+		return crossSectional(args[0], length, len(frame.Instruments), csZScore), nil
+	}
+
+	if fn, ok := rollingFunctions[call.Func]; ok {
+		n := window(1)
+		return e.mapSeries(ctx, length, len(frame.Instruments), func(i int) []float64 {
+			return rollingApply(args[0].at(i, length), n, fn.minPeriods, fn.apply)
+		})
+	}
+
+	return evalValue{}, fmt.Errorf("函数 %s 暂不支持原生计算", call.Func)
+}
+
+// mapSeries 按证券并行计算
+func (e *FactorEvaluator) mapSeries(ctx context.Context, length, count int, fn func(i int) []float64) (evalValue, error) {
+	out := make([][]float64, count)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var panicOnce sync.Once
+	var panicErr error
+
+	// 单个证券的计算出错时记录为错误，不让panic终止整个进程
+	run := func(i int) {
+		defer func() {
+			if r := recover(); r != nil {
+				panicOnce.Do(func() { panicErr = fmt.Errorf("计算证券 %d 时出错: %v", i, r) })
+			}
+		}()
+		out[i] = fn(i)
+	}
+
+	workers := e.workers
+	if workers > count {
+		workers = count
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				run(i)
+			}
+		}()
+	}
+
+	var err error
+	for i := 0; i < count; i++ {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err != nil {
+		return evalValue{}, err
+	}
+	if panicErr != nil {
+		return evalValue{}, panicErr
+	}
+	return evalValue{series: out}, nil
+}
+
+// mapUnary 逐元素一元运算
+func (e *FactorEvaluator) mapUnary(x evalValue, fn func(float64) float64) evalValue {
+	if x.isScalar {
+		return evalValue{scalar: fn(x.scalar), isScalar: true}
+	}
+	out := make([][]float64, len(x.series))
+	for i, series := range x.series {
+		values := make([]float64, len(series))
+		for t, v := range series {
+			values[t] = fn(v)
+		}
+		out[i] = values
+	}
+	return evalValue{series: out}
+}
+
+// mapBinary 逐元素二元运算，常量自动广播
+func (e *FactorEvaluator) mapBinary(x, y evalValue, length int, fn func(a, b float64) float64) evalValue {
+	if x.isScalar && y.isScalar {
+		return evalValue{scalar: fn(x.scalar, y.scalar), isScalar: true}
+	}
+	count := len(x.series)
+	if x.isScalar {
+		count = len(y.series)
+	}
+	out := make([][]float64, count)
+	for i := range out {
+		a, b := x.at(i, length), y.at(i, length)
+		values := make([]float64, length)
+		for t := range values {
+			values[t] = fn(a[t], b[t])
+		}
+		out[i] = values
+	}
+	return evalValue{series: out}
+}
+
+// binaryOperator 返回二元运算符对应的函数
+func binaryOperator(op string) (func(a, b float64) float64, error) {
+	switch op {
+	case "+":
+		return func(a, b float64) float64 { return a + b }, nil
+	case "-":
+		return func(a, b float64) float64 { return a - b }, nil
+	case "*":
+		return func(a, b float64) float64 { return a * b }, nil
+	case "/":
+		return func(a, b float64) float64 { return a / b }, nil
+	case ">":
+		return func(a, b float64) float64 { return boolValue(a > b) }, nil
+	case "<":
+		return func(a, b float64) float64 { return boolValue(a < b) }, nil
+	case ">=":
+		return func(a, b float64) float64 { return boolValue(a >= b) }, nil
+	case "<=":
+		return func(a, b float64) float64 { return boolValue(a <= b) }, nil
+	case "==":
+		return func(a, b float64) float64 { return boolValue(a == b) }, nil
+	case "!=":
+		return func(a, b float64) float64 { return boolValue(a != b) }, nil
+	case "&":
+		return func(a, b float64) float64 { return boolValue(truthy(a) && truthy(b)) }, nil
+	case "|":
+		return func(a, b float64) float64 { return boolValue(truthy(a) || truthy(b)) }, nil
+	}
+	return nil, fmt.Errorf("不支持的运算符: %s", op)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func truthy(v float64) bool {
+	return v != 0 && !math.IsNaN(v)
+}
+
+func signValue(v float64) float64 {
+	switch {
+	case math.IsNaN(v):
+		return v
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+func nanMax(a, b float64) float64 {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.NaN()
+	}
+	return math.Max(a, b)
+}
+
+func nanMin(a, b float64) float64 {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.NaN()
+	}
+	return math.Min(a, b)
+}
+
+// shiftSeries 序列平移，n>0时引用历史，n<0时引用未来
+func shiftSeries(x []float64, n int) []float64 {
+	out := nanSeries(len(x))
+	for t := range x {
+		src := t - n
+		if src >= 0 && src < len(x) {
+			out[t] = x[src]
+		}
+	}
+	return out
+}
+
+// rollingFunction 只依赖窗口内非空值的滚动函数
+type rollingFunction struct {
+	minPeriods int
+	apply      func(window []float64) float64
+}
+
+var rollingFunctions = map[string]rollingFunction{
+	"Mean":   {1, func(w []float64) float64 { return sumValues(w) / float64(len(w)) }},
+	"Sum":    {1, sumValues},
+	"Std":    {2, func(w []float64) float64 { return math.Sqrt(sampleVariance(w)) }},
+	"Var":    {2, sampleVariance},
+	"Skew":   {3, sampleSkew},
+	"Kurt":   {4, sampleKurt},
+	"Max":    {1, func(w []float64) float64 { return w[argExtreme(w, true)] }},
+	"Min":    {1, func(w []float64) float64 { return w[argExtreme(w, false)] }},
+	"Count":  {0, func(w []float64) float64 { return float64(len(w)) }},
+	"Med": {1, func(w []float64) float64 {
+		sorted := append([]float64(nil), w...)
+		sort.Float64s(sorted)
+		return quantileSorted(sorted, 0.5)
+	}},
+	"Mad": {1, func(w []float64) float64 {
+		mean := sumValues(w) / float64(len(w))
+		total := 0.0
+		for _, v := range w {
+			total += math.Abs(v - mean)
+		}
+		return total / float64(len(w))
+	}},
+	"WMA": {1, func(w []float64) float64 {
+		total, weights := 0.0, 0.0
+		for i, v := range w {
+			weight := float64(i + 1)
+			total += weight * v
+			weights += weight
+		}
+		return total / weights
+	}},
+}
+
+// rollingApply 对每个位置取窗口内的非空值调用fn，非空值不足minPeriods时结果为NaN
+//
+// n为0时使用扩展窗口。
+func rollingApply(x []float64, n, minPeriods int, fn func(window []float64) float64) []float64 {
+	out := nanSeries(len(x))
+	buf := make([]float64, 0, maxInt(minInt(n, len(x)), 1))
+	for t := range x {
+		start := 0
+		if n > 0 {
+			start = maxInt(0, t-n+1)
+		}
+		buf = buf[:0]
+		for _, v := range x[start : t+1] {
+			if !math.IsNaN(v) {
+				buf = append(buf, v)
+			}
+		}
+		if len(buf) >= minPeriods {
+			out[t] = fn(buf)
+		}
+	}
+	return out
+}
+
+// emaSeries 指数移动平均，与 pandas ewm(span=N, min_periods=1) 一致；0<N<1时N作为alpha，N=0时为扩展窗口
+func emaSeries(x []float64, n float64) []float64 {
+	out := nanSeries(len(x))
+	if n == 0 {
+		for t := range x {
+			alpha := 2.0 / (float64(t+1) + 1)
+			num, den := 0.0, 0.0
+			weight := 1.0
+			for s := t; s >= 0; s-- {
+				if !math.IsNaN(x[s]) {
+					num += weight * x[s]
+					den += weight
+				}
+				weight *= 1 - alpha
+			}
+			if den > 0 {
+				out[t] = num / den
+			}
+		}
+		return out
+	}
+
+	alpha := n
+	if n >= 1 {
+		alpha = 2 / (n + 1)
+	}
+	num, den := 0.0, 0.0
+	seen := false
+	for t, v := range x {
+		num *= 1 - alpha
+		den *= 1 - alpha
+		if !math.IsNaN(v) {
+			num += v
+			den++
+			seen = true
+		}
+		if seen && den > 0 {
+			out[t] = num / den
+		}
+	}
+	return out
+}
+
+// rollingPair 滚动协方差或相关系数，仅使用两个序列同时非空的样本
+func rollingPair(x, y []float64, n int, corr bool) []float64 {
+	out := nanSeries(len(x))
+	for t := range x {
+		start := 0
+		if n > 0 {
+			start = maxInt(0, t-n+1)
+		}
+		count := 0.0
+		var sx, sy, sxx, syy, sxy float64
+		for s := start; s <= t; s++ {
+			if math.IsNaN(x[s]) || math.IsNaN(y[s]) {
+				continue
+			}
+			count++
+			sx += x[s]
+			sy += y[s]
+			sxx += x[s] * x[s]
+			syy += y[s] * y[s]
+			sxy += x[s] * y[s]
+		}
+		if count < 2 {
+			continue
+		}
+		cov := (sxy - sx*sy/count) / (count - 1)
+		if !corr {
+			out[t] = cov
+			continue
+		}
+		vx := (sxx - sx*sx/count) / (count - 1)
+		vy := (syy - sy*sy/count) / (count - 1)
+		// 与Qlib一致：任一序列窗口内标准差接近0时结果为NaN
+		if math.Sqrt(math.Max(vx, 0)) < 2e-5 || math.Sqrt(math.Max(vy, 0)) < 2e-5 {
+			continue
+		}
+		out[t] = cov / math.Sqrt(vx*vy)
+	}
+	return out
+}
+
+// rollingRegression 以窗口内位置为自变量做线性回归，返回斜率、R²或当前残差
+func rollingRegression(x []float64, n int, kind string) []float64 {
+	out := nanSeries(len(x))
+	for t := range x {
+		start := 0
+		if n > 0 {
+			start = maxInt(0, t-n+1)
+		}
+		var count, sx, sy, sxx, sxy, syy float64
+		for s := start; s <= t; s++ {
+			if math.IsNaN(x[s]) {
+				continue
+			}
+			pos := float64(s - start + 1)
+			count++
+			sx += pos
+			sy += x[s]
+			sxx += pos * pos
+			sxy += pos * x[s]
+			syy += x[s] * x[s]
+		}
+		if count < 2 {
+			continue
+		}
+		varX := sxx - sx*sx/count
+		if varX == 0 {
+			continue
+		}
+		covXY := sxy - sx*sy/count
+		slope := covXY / varX
+		switch kind {
+		case "Slope":
+			out[t] = slope
+		case "Rsquare":
+			varY := syy - sy*sy/count
+			if varY <= 0 {
+				continue
+			}
+			out[t] = covXY * covXY / (varX * varY)
+		case "Resi":
+			if math.IsNaN(x[t]) {
+				continue
+			}
+			intercept := (sy - slope*sx) / count
+			out[t] = x[t] - (intercept + slope*float64(t-start+1))
+		}
+	}
+	return out
+}
+
+// rollingArgExtreme 窗口内最大值（findMax=true）或最小值的位置，从1开始按原始窗口计数，缺失值占位但不参与比较
+func rollingArgExtreme(x []float64, n int, findMax bool) []float64 {
+	out := nanSeries(len(x))
+	for t := range x {
+		start := 0
+		if n > 0 {
+			start = maxInt(0, t-n+1)
+		}
+		best := -1
+		for s := start; s <= t; s++ {
+			if math.IsNaN(x[s]) {
+				continue
+			}
+			if best < 0 || (findMax && x[s] > x[best]) || (!findMax && x[s] < x[best]) {
+				best = s
+			}
+		}
+		if best >= 0 {
+			out[t] = float64(best - start + 1)
+		}
+	}
+	return out
+}
+
+// rollingRank 当前值在窗口内的百分位排名，与 scipy percentileofscore(kind="rank") 一致
+func rollingRank(x []float64, n int) []float64 {
+	out := nanSeries(len(x))
+	for t := range x {
+		if math.IsNaN(x[t]) {
+			continue
+		}
+		start := 0
+		if n > 0 {
+			start = maxInt(0, t-n+1)
+		}
+		var count, less, lessEqual float64
+		for s := start; s <= t; s++ {
+			if math.IsNaN(x[s]) {
+				continue
+			}
+			count++
+			if x[s] < x[t] {
+				less++
+			}
+			if x[s] <= x[t] {
+				lessEqual++
+			}
+		}
+		extra := 0.0
+		if lessEqual > less {
+			extra = 1
+		}
+		out[t] = (less + lessEqual + extra) / (2 * count)
+	}
+	return out
+}
+
+// crossSectional 在每个交易日对所有证券做截面运算
+func crossSectional(x evalValue, length, count int, fn func(values []float64) []float64) evalValue {
+	if x.isScalar {
+		return x
+	}
+	out := make([][]float64, count)
+	for i := range out {
+		out[i] = make([]float64, length)
+	}
+	column := make([]float64, count)
+	for t := 0; t < length; t++ {
+		for i := 0; i < count; i++ {
+			column[i] = x.series[i][t]
+		}
+		result := fn(column)
+		for i := 0; i < count; i++ {
+			out[i][t] = result[i]
+		}
+	}
+	return evalValue{series: out}
+}
+
+// csRank 截面百分位排名（并列取平均名次），与 pandas rank(pct=True) 一致
+func csRank(values []float64) []float64 {
+	out := nanSeries(len(values))
+	idx := make([]int, 0, len(values))
+	for i, v := range values {
+		if !math.IsNaN(v) {
+			idx = append(idx, i)
+		}
+	}
+	sort.Slice(idx, func(a, b int) bool { return values[idx[a]] < values[idx[b]] })
+
+	n := float64(len(idx))
+	for start := 0; start < len(idx); {
+		end := start
+		for end+1 < len(idx) && values[idx[end+1]] == values[idx[start]] {
+			end++
+		}
+		rank := float64(start+end)/2 + 1
+		for k := start; k <= end; k++ {
+			out[idx[k]] = rank / n
+		}
+		start = end + 1
+	}
+	return out
+}
+
+// csZScore 截面标准化
+func csZScore(values []float64) []float64 {
+	out := nanSeries(len(values))
+	valid := make([]float64, 0, len(values))
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			valid = append(valid, v)
+		}
+	}
+	if len(valid) < 2 {
+		return out
+	}
+	mean := sumValues(valid) / float64(len(valid))
+	std := math.Sqrt(sampleVariance(valid))
+	if std == 0 {
+		return out
+	}
+	for i, v := range values {
+		if !math.IsNaN(v) {
+			out[i] = (v - mean) / std
+		}
+	}
+	return out
+}
+
+func sumValues(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// sampleVariance 样本方差（ddof=1）
+func sampleVariance(values []float64) float64 {
+	n := float64(len(values))
+	if n < 2 {
+		return math.NaN()
+	}
+	mean := sumValues(values) / n
+	total := 0.0
+	for _, v := range values {
+		total += (v - mean) * (v - mean)
+	}
+	return total / (n - 1)
+}
+
+// sampleSkew 偏度（与pandas一致的无偏估计）
+func sampleSkew(values []float64) float64 {
+	n := float64(len(values))
+	if n < 3 {
+		return math.NaN()
+	}
+	mean := sumValues(values) / n
+	var m2, m3 float64
+	for _, v := range values {
+		d := v - mean
+		m2 += d * d
+		m3 += d * d * d
+	}
+	m2 /= n
+	m3 /= n
+	if m2 == 0 {
+		return math.NaN()
+	}
+	return math.Sqrt(n*(n-1)) / (n - 2) * m3 / math.Pow(m2, 1.5)
+}
+
+// sampleKurt 超额峰度（与pandas一致的无偏估计）
+func sampleKurt(values []float64) float64 {
+	n := float64(len(values))
+	if n < 4 {
+		return math.NaN()
+	}
+	mean := sumValues(values) / n
+	var m2, m4 float64
+	for _, v := range values {
+		d := v - mean
+		m2 += d * d
+		m4 += d * d * d * d
+	}
+	m2 /= n
+	m4 /= n
+	if m2 == 0 {
+		return math.NaN()
+	}
+	g2 := m4/(m2*m2) - 3
+	return ((n+1)*g2 + 6) * (n - 1) / ((n - 2) * (n - 3))
+}
+
+// quantileSorted 线性插值分位数，values必须已排序
+func quantileSorted(values []float64, q float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	pos := math.Max(0, math.Min(1, q)) * float64(len(values)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	if lower == upper {
+		return values[lower]
+	}
+	return values[lower] + (values[upper]-values[lower])*(pos-float64(lower))
+}
+
+// argExtreme 返回最大值（findMax=true）或最小值的位置
+func argExtreme(values []float64, findMax bool) int {
+	best := 0
+	for i, v := range values {
+		if (findMax && v > values[best]) || (!findMax && v < values[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,166 @@
+package qlib
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestFrame 构造两只证券、五个交易日的测试行情
+func newTestFrame(t *testing.T) *MarketFrame {
+	calendar := make([]time.Time, 5)
+	for i := range calendar {
+		calendar[i] = time.Date(2023, 1, 2+i, 0, 0, 0, 0, time.UTC)
+	}
+	frame := NewMarketFrame(calendar, []string{"SH600000", "SZ000001"})
+
+	series := map[string]map[string][]float64{
+		"SH600000": {
+			"$close":  {1, 2, 3, 4, 5},
+			"$open":   {1, 1, 1, 1, 1},
+			"$volume": {2, 4, 6, 8, 10},
+		},
+		"SZ000001": {
+			"$close":  {5, 4, math.NaN(), 2, 1},
+			"$open":   {1, 1, 1, 1, 1},
+			"$volume": {1, 1, 1, 1, 1},
+		},
+	}
+	for inst, fields := range series {
+		for field, values := range fields {
+			if err := frame.SetSeries(inst, field, values); err != nil {
+				t.Fatalf("SetSeries failed: %v", err)
+			}
+		}
+	}
+	return frame
+}
+
+func assertSeries(t *testing.T, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: length = %d, want %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) {
+			if !math.IsNaN(got[i]) {
+				t.Errorf("%s[%d] = %v, want NaN", name, i, got[i])
+			}
+			continue
+		}
+		if math.Abs(got[i]-want[i]) > 1e-6 {
+			t.Errorf("%s[%d] = %v, want %v", name, i, got[i], want[i])
+		}
+	}
+}
+
+func TestFactorEvaluator(t *testing.T) {
+	frame := newTestFrame(t)
+	evaluator := NewFactorEvaluator(2)
+	nan := math.NaN()
+
+	cases := []struct {
+		expr string
+		inst int
+		want []float64
+	}{
+		{"Mean($close, 3)", 0, []float64{1, 1.5, 2, 3, 4}},
+		{"Ref($close, 1)", 0, []float64{nan, 1, 2, 3, 4}},
+		{"Ref($close, -1)", 0, []float64{2, 3, 4, 5, nan}},
+		{"Delta($close, 1)", 0, []float64{nan, 1, 1, 1, 1}},
+		{"Std($close, 3)", 0, []float64{nan, math.Sqrt(0.5), 1, 1, 1}},
+		{"EMA($close, 3)", 0, []float64{1, 5.0 / 3, 4.25 / 1.75, 3.266666667, 4.161290323}},
+		{"WMA($close, 3)", 0, []float64{1, 5.0 / 3, 14.0 / 6, 20.0 / 6, 26.0 / 6}},
+		{"Slope($close, 3)", 0, []float64{nan, 1, 1, 1, 1}},
+		{"Quantile($close, 3, 0.5)", 0, []float64{1, 1.5, 2, 3, 4}},
+		{"Corr($close, $volume, 3)", 0, []float64{nan, 1, 1, 1, 1}},
+		{"If($close > 3, 1, 0)", 0, []float64{0, 0, 0, 1, 1}},
+		{"($close > 3) | ($volume < 4)", 0, []float64{1, 0, 0, 1, 1}},
+		{"Abs($open - $close) * Sign($open - $close)", 0, []float64{0, -1, -2, -3, -4}},
+		{"Rank($close)", 0, []float64{0.5, 0.5, 1, 1, 1}},
+		{"Rank($close)", 1, []float64{1, 1, nan, 0.5, 0.5}},
+		// NaN 语义：滚动窗口忽略缺失值，算术运算传播缺失值，比较运算返回0
+		{"Sum($close, 2)", 1, []float64{5, 9, 4, 2, 3}},
+		{"$close + 1", 1, []float64{6, 5, nan, 3, 2}},
+		{"$close > 0", 1, []float64{1, 1, 0, 1, 1}},
+		{"Count($close, 2)", 1, []float64{1, 2, 1, 1, 2}},
+		// IdxMax/IdxMin 按原始窗口位置计数，缺失值占位
+		{"IdxMax($close, 3)", 1, []float64{1, 1, 1, 1, 2}},
+		{"IdxMin($close, 3)", 1, []float64{1, 2, 2, 3, 3}},
+		// Corr 在窗口标准差为0时返回 NaN
+		{"Corr($close, $volume, 3)", 1, []float64{nan, nan, nan, nan, nan}},
+	}
+
+	for _, tc := range cases {
+		result, err := evaluator.EvaluateExpression(context.Background(), tc.expr, frame)
+		if err != nil {
+			t.Errorf("Evaluate '%s' failed: %v", tc.expr, err)
+			continue
+		}
+		assertSeries(t, tc.expr, result.Values[tc.inst], tc.want)
+	}
+}
+
+func TestFactorEvaluatorMissingField(t *testing.T) {
+	frame := newTestFrame(t)
+	_, err := NewFactorEvaluator(1).EvaluateExpression(context.Background(), "Mean($vwap, 5)", frame)
+	if err == nil {
+		t.Error("Evaluating a missing field should fail")
+	}
+}
+
+func TestFactorEvaluatorGuards(t *testing.T) {
+	if got := quantileSorted([]float64{1, 2, 3}, 1.5); got != 3 {
+		t.Errorf("quantileSorted(q=1.5) = %v, want 3", got)
+	}
+	if got := quantileSorted([]float64{1, 2, 3}, -1); got != 1 {
+		t.Errorf("quantileSorted(q=-1) = %v, want 1", got)
+	}
+
+	// 窗口远大于序列长度时不应按窗口分配内存
+	out := rollingApply([]float64{1, 2, 3}, 1e12, 1, func(w []float64) float64 { return float64(len(w)) })
+	assertSeries(t, "rollingApply", out, []float64{1, 2, 3})
+
+	_, err := NewFactorEvaluator(2).mapSeries(context.Background(), 3, 4, func(i int) []float64 {
+		if i == 2 {
+			var values []float64
+			return values[i:]
+		}
+		return nanSeries(3)
+	})
+	if err == nil || !strings.Contains(err.Error(), "计算证券 2 时出错") {
+		t.Errorf("Panic in a worker should become an error, got %v", err)
+	}
+}
+
+func TestExpressionWindow(t *testing.T) {
+	parsed, err := ParseFactorExpression("Mean(Ref($close, 5), 20) / Ref($close, -2)", nil)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	lookback, lookahead := ExpressionWindow(parsed.Root)
+	if lookback != 24 || lookahead != 2 {
+		t.Errorf("ExpressionWindow = (%d, %d), want (24, 2)", lookback, lookahead)
+	}
+}
+
+func TestFactorEngineNativeBackend(t *testing.T) {
+	engine := NewFactorEngine("", "", "")
+	engine.SetDataProvider(NewMemoryDataProvider(newTestFrame(t)))
+
+	result, err := engine.CalculateFactorValue("Ref($close, 1)", "2023-01-04", "2023-01-06", []string{"SH600000"})
+	if err != nil {
+		t.Fatalf("CalculateFactorValue failed: %v", err)
+	}
+
+	dates, _ := result["dates"].([]string)
+	if len(dates) != 3 || dates[0] != "2023-01-04" {
+		t.Errorf("Unexpected dates: %v", dates)
+	}
+	values, _ := result["factor_values"].(map[string]interface{})["SH600000"].([]interface{})
+	if len(values) != 3 || values[0] != 2.0 || values[2] != 4.0 {
+		t.Errorf("Lookback data should be loaded before the start date, got %v", values)
+	}
+}
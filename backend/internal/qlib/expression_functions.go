@@ -0,0 +1,397 @@
+package qlib
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// 函数参数类型
+const (
+	ParamSeries = "series" // 时间序列（必须引用数据字段）
+	ParamValue  = "value"  // 时间序列或数值常量
+	ParamWindow = "window" // 非负整数常量（窗口长度，0表示扩展窗口）
+	ParamInt    = "int"    // 整数常量（可为负，如 Ref 的未来偏移）
+	ParamNumber = "number" // 数值常量
+	ParamRatio  = "ratio"  // 0~1之间的数值常量（如分位数）
+)
+
+// MaxExpressionWindow 窗口和偏移参数的上限，约为40年的日频数据
+const MaxExpressionWindow = 10000
+
+// FunctionParam 函数参数定义
+type FunctionParam struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// ValidFactorFields Qlib数据中可用的基础字段
+var ValidFactorFields = []string{
+	"$open", "$high", "$low", "$close", "$volume", "$factor",
+	"$vwap", "$amount", "$pctchange", "$adjclose",
+}
+
+func seriesParam(name string) FunctionParam { return FunctionParam{Name: name, Type: ParamSeries} }
+func valueParam(name string) FunctionParam  { return FunctionParam{Name: name, Type: ParamValue} }
+func windowParam(name string) FunctionParam { return FunctionParam{Name: name, Type: ParamWindow} }
+
+// builtinQlibFunctions Qlib表达式函数目录
+var builtinQlibFunctions = []QlibFunction{
+	// 数学函数
+	{Name: "Abs", Signature: "Abs(data)", Description: "绝对值", Category: "数学函数",
+		Examples: []string{"Abs($close - $open)"}, Params: []FunctionParam{seriesParam("data")}},
+	{Name: "Sign", Signature: "Sign(data)", Description: "符号函数，返回 -1、0 或 1", Category: "数学函数",
+		Examples: []string{"Sign(Delta($close, 1))"}, Params: []FunctionParam{seriesParam("data")}},
+	{Name: "Log", Signature: "Log(data)", Description: "自然对数", Category: "数学函数",
+		Examples: []string{"Log($volume + 1)"}, Params: []FunctionParam{seriesParam("data")}},
+	{Name: "Power", Signature: "Power(data, exponent)", Description: "幂运算", Category: "数学函数",
+		Examples: []string{"Power($close / Ref($close, 1), 2)"}, Params: []FunctionParam{valueParam("data"), valueParam("exponent")}},
+	{Name: "Delta", Signature: "Delta(data, period)", Description: "计算差分，即当前值减去period期前的值", Category: "数学函数",
+		Examples: []string{"Delta($close, 1)", "Delta($close, 5)"}, Params: []FunctionParam{seriesParam("data"), windowParam("period")}},
+
+	// 逻辑函数
+	{Name: "If", Signature: "If(condition, left, right)", Description: "条件为真取left，否则取right", Category: "逻辑函数",
+		Examples: []string{"If($close > $open, 1, -1)"}, Params: []FunctionParam{seriesParam("condition"), valueParam("left"), valueParam("right")}},
+	{Name: "Greater", Signature: "Greater(left, right)", Description: "逐元素取较大值", Category: "逻辑函数",
+		Examples: []string{"Greater($high - $low, 0.01)"}, Params: []FunctionParam{valueParam("left"), valueParam("right")}},
+	{Name: "Less", Signature: "Less(left, right)", Description: "逐元素取较小值", Category: "逻辑函数",
+		Examples: []string{"Less($open, $close)"}, Params: []FunctionParam{valueParam("left"), valueParam("right")}},
+
+	// 时序函数
+	{Name: "Ref", Signature: "Ref(data, period)", Description: "引用period期前的值，负数表示未来", Category: "时序函数",
+		Examples: []string{"Ref($close, 1)", "Ref($close, -2) / Ref($close, -1) - 1"}, Params: []FunctionParam{seriesParam("data"), {Name: "period", Type: ParamInt}}},
+	{Name: "EMA", Signature: "EMA(data, window)", Description: "指数移动平均", Category: "时序函数",
+		Examples: []string{"EMA($close, 12) - EMA($close, 26)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "WMA", Signature: "WMA(data, window)", Description: "线性加权移动平均", Category: "时序函数",
+		Examples: []string{"WMA($close, 10)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "Slope", Signature: "Slope(data, window)", Description: "滚动线性回归斜率", Category: "时序函数",
+		Examples: []string{"Slope($close, 20) / $close"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "Rsquare", Signature: "Rsquare(data, window)", Description: "滚动线性回归R²", Category: "时序函数",
+		Examples: []string{"Rsquare($close, 20)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "Resi", Signature: "Resi(data, window)", Description: "滚动线性回归残差", Category: "时序函数",
+		Examples: []string{"Resi($close, 20) / $close"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "IdxMax", Signature: "IdxMax(data, window)", Description: "窗口内最大值所在位置", Category: "时序函数",
+		Examples: []string{"IdxMax($high, 20)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "IdxMin", Signature: "IdxMin(data, window)", Description: "窗口内最小值所在位置", Category: "时序函数",
+		Examples: []string{"IdxMin($low, 20)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+
+	// 统计函数
+	{Name: "Mean", Signature: "Mean(data, window)", Description: "计算移动平均值", Category: "统计函数",
+		Examples: []string{"Mean($close, 20)", "Mean($volume, 10)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "Sum", Signature: "Sum(data, window)", Description: "滚动求和", Category: "统计函数",
+		Examples: []string{"Sum($volume, 5)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "Std", Signature: "Std(data, window)", Description: "计算标准差", Category: "统计函数",
+		Examples: []string{"Std($close, 20)", "Std($close / Ref($close, 1) - 1, 60)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "Var", Signature: "Var(data, window)", Description: "滚动方差", Category: "统计函数",
+		Examples: []string{"Var($close, 20)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "Skew", Signature: "Skew(data, window)", Description: "滚动偏度", Category: "统计函数",
+		Examples: []string{"Skew($close / Ref($close, 1) - 1, 20)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "Kurt", Signature: "Kurt(data, window)", Description: "滚动峰度", Category: "统计函数",
+		Examples: []string{"Kurt($close / Ref($close, 1) - 1, 20)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "Max", Signature: "Max(data, window)", Description: "滚动最大值", Category: "统计函数",
+		Examples: []string{"Max($high, 20)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "Min", Signature: "Min(data, window)", Description: "滚动最小值", Category: "统计函数",
+		Examples: []string{"Min($low, 20)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "Med", Signature: "Med(data, window)", Description: "滚动中位数", Category: "统计函数",
+		Examples: []string{"Med($close, 20)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "Mad", Signature: "Mad(data, window)", Description: "滚动平均绝对偏差", Category: "统计函数",
+		Examples: []string{"Mad($close, 20)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "Count", Signature: "Count(data, window)", Description: "窗口内非空值个数", Category: "统计函数",
+		Examples: []string{"Count($close > $open, 20)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window")}},
+	{Name: "Quantile", Signature: "Quantile(data, window, qscore)", Description: "滚动分位数", Category: "统计函数",
+		Examples: []string{"Quantile($close, 20, 0.8)"}, Params: []FunctionParam{seriesParam("data"), windowParam("window"), {Name: "qscore", Type: ParamRatio}}},
+	{Name: "Corr", Signature: "Corr(data1, data2, window)", Description: "计算相关系数", Category: "统计函数",
+		Examples: []string{"Corr($close, $volume, 20)", "Corr($close, Log($volume + 1), 20)"}, Params: []FunctionParam{seriesParam("data1"), seriesParam("data2"), windowParam("window")}},
+	{Name: "Cov", Signature: "Cov(data1, data2, window)", Description: "计算协方差", Category: "统计函数",
+		Examples: []string{"Cov($close, $volume, 20)"}, Params: []FunctionParam{seriesParam("data1"), seriesParam("data2"), windowParam("window")}},
+
+	// 排序函数
+	{Name: "Rank", Signature: "Rank(data[, window])", Description: "计算排名，不带窗口时为截面百分位排名，带窗口时为时序滚动排名", Category: "排序函数",
+		Examples: []string{"Rank($close)", "Rank($volume, 20)"}, Params: []FunctionParam{seriesParam("data"), {Name: "window", Type: ParamWindow, Optional: true}}},
+
+	// 截面函数
+	{Name: "CSRank", Signature: "CSRank(data)", Description: "截面百分位排名", Category: "截面函数",
+		Examples: []string{"CSRank($close / Ref($close, 20) - 1)"}, Params: []FunctionParam{seriesParam("data")}},
+	{Name: "CSZScore", Signature: "CSZScore(data)", Description: "截面标准化", Category: "截面函数",
+		Examples: []string{"CSZScore($volume)"}, Params: []FunctionParam{seriesParam("data")}},
+}
+
+// BuiltinQlibFunctions 返回内置函数目录的副本
+func BuiltinQlibFunctions() []QlibFunction {
+	functions := make([]QlibFunction, len(builtinQlibFunctions))
+	copy(functions, builtinQlibFunctions)
+	return functions
+}
+
+// ParsedExpression 解析并检查后的因子表达式
+type ParsedExpression struct {
+	Expression string   `json:"expression"`
+	Root       ExprNode `json:"-"`
+	Canonical  string   `json:"canonical"`
+	Fields     []string `json:"fields"`
+	Functions  []string `json:"functions"`
+}
+
+// ParseFactorExpression 解析因子表达式，并按函数目录检查函数名、参数个数和参数类型
+//
+// functions 为空时使用内置函数目录。
+func ParseFactorExpression(expression string, functions []QlibFunction) (*ParsedExpression, error) {
+	root, err := ParseExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(functions) == 0 {
+		functions = builtinQlibFunctions
+	}
+	checker := &expressionChecker{
+		functions: make(map[string]QlibFunction, len(functions)),
+		fields:    make(map[string]bool, len(ValidFactorFields)),
+	}
+	for _, fn := range functions {
+		checker.functions[fn.Name] = fn
+	}
+	for _, field := range ValidFactorFields {
+		checker.fields[field] = true
+	}
+
+	isSeries, err := checker.check(root)
+	if err != nil {
+		return nil, err
+	}
+	if !isSeries {
+		return nil, newExpressionError(root.Pos(), "表达式必须至少引用一个数据字段（如 $close）")
+	}
+
+	fieldSet := make(map[string]bool)
+	functionSet := make(map[string]bool)
+	WalkExpression(root, func(node ExprNode) bool {
+		switch n := node.(type) {
+		case *FieldRef:
+			fieldSet[n.Name] = true
+		case *CallExpr:
+			functionSet[n.Func] = true
+		}
+		return true
+	})
+
+	return &ParsedExpression{
+		Expression: expression,
+		Root:       root,
+		Canonical:  FormatExpression(root),
+		Fields:     sortedKeys(fieldSet),
+		Functions:  sortedKeys(functionSet),
+	}, nil
+}
+
+// expressionChecker 语义检查器
+type expressionChecker struct {
+	functions map[string]QlibFunction
+	fields    map[string]bool
+}
+
+// check 检查节点，返回节点是否为时间序列
+func (c *expressionChecker) check(node ExprNode) (bool, error) {
+	switch n := node.(type) {
+	case *NumberLit:
+		return false, nil
+
+	case *FieldRef:
+		if !c.fields[n.Name] {
+			return false, newExpressionError(n.Position, "未知字段: %s", n.Name)
+		}
+		return true, nil
+
+	case *UnaryExpr:
+		return c.check(n.X)
+
+	case *BinaryExpr:
+		left, err := c.check(n.X)
+		if err != nil {
+			return false, err
+		}
+		right, err := c.check(n.Y)
+		if err != nil {
+			return false, err
+		}
+		return left || right, nil
+
+	case *CallExpr:
+		return c.checkCall(n)
+	}
+	return false, fmt.Errorf("未知的表达式节点")
+}
+
+// checkCall 检查函数调用的参数个数和参数类型
+func (c *expressionChecker) checkCall(call *CallExpr) (bool, error) {
+	fn, ok := c.functions[call.Func]
+	if !ok {
+		return false, newExpressionError(call.Position, "未知函数: %s", call.Func)
+	}
+
+	// 目录中未声明参数的函数（例如外部传入的目录）只检查参数本身
+	if len(fn.Params) == 0 {
+		for _, arg := range call.Args {
+			if _, err := c.check(arg); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	required := 0
+	for _, param := range fn.Params {
+		if !param.Optional {
+			required++
+		}
+	}
+	if len(call.Args) < required || len(call.Args) > len(fn.Params) {
+		expected := fmt.Sprintf("%d", required)
+		if required != len(fn.Params) {
+			expected = fmt.Sprintf("%d~%d", required, len(fn.Params))
+		}
+		return false, newExpressionError(call.Position, "函数 %s 需要 %s 个参数，实际传入 %d 个，用法: %s",
+			call.Func, expected, len(call.Args), fn.Signature)
+	}
+
+	for i, arg := range call.Args {
+		param := fn.Params[i]
+		isSeries, err := c.check(arg)
+		if err != nil {
+			return false, err
+		}
+
+		switch param.Type {
+		case ParamSeries:
+			if !isSeries {
+				return false, newExpressionError(arg.Pos(), "函数 %s 的参数 %s 必须是时间序列（引用数据字段的表达式），用法: %s",
+					call.Func, param.Name, fn.Signature)
+			}
+		case ParamWindow, ParamInt, ParamNumber, ParamRatio:
+			value, isConst := constantValue(arg)
+			if isSeries || !isConst {
+				return false, newExpressionError(arg.Pos(), "函数 %s 的参数 %s 必须是数值常量，用法: %s",
+					call.Func, param.Name, fn.Signature)
+			}
+			if param.Type == ParamNumber {
+				break
+			}
+			if param.Type == ParamRatio {
+				if value < 0 || value > 1 {
+					return false, newExpressionError(arg.Pos(), "函数 %s 的参数 %s 必须在0到1之间", call.Func, param.Name)
+				}
+				break
+			}
+			if value != math.Trunc(value) {
+				return false, newExpressionError(arg.Pos(), "函数 %s 的参数 %s 必须是整数", call.Func, param.Name)
+			}
+			if param.Type == ParamWindow && value < 0 {
+				return false, newExpressionError(arg.Pos(), "函数 %s 的参数 %s 不能为负数", call.Func, param.Name)
+			}
+			if math.Abs(value) > MaxExpressionWindow {
+				return false, newExpressionError(arg.Pos(), "函数 %s 的参数 %s 不能超过 %d", call.Func, param.Name, MaxExpressionWindow)
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// constantValue 计算常量表达式的值
+func constantValue(node ExprNode) (float64, bool) {
+	switch n := node.(type) {
+	case *NumberLit:
+		return n.Value, true
+	case *UnaryExpr:
+		value, ok := constantValue(n.X)
+		return -value, ok
+	case *BinaryExpr:
+		left, ok := constantValue(n.X)
+		if !ok {
+			return 0, false
+		}
+		right, ok := constantValue(n.Y)
+		if !ok {
+			return 0, false
+		}
+		switch n.Op {
+		case "+":
+			return left + right, true
+		case "-":
+			return left - right, true
+		case "*":
+			return left * right, true
+		case "/":
+			if right == 0 {
+				return 0, false
+			}
+			return left / right, true
+		}
+	}
+	return 0, false
+}
+
+// LookupQlibFunction 按名称查找内置函数
+func LookupQlibFunction(name string) (QlibFunction, bool) {
+	for _, fn := range builtinQlibFunctions {
+		if fn.Name == name {
+			return fn, true
+		}
+	}
+	return QlibFunction{}, false
+}
+
+// ClosestQlibName 在候选名称中查找与name最接近的一个（忽略大小写），找不到时返回空字符串
+func ClosestQlibName(name string, candidates []string) string {
+	best := ""
+	bestDistance := len(name)/2 + 1
+	lower := strings.ToLower(name)
+	for _, candidate := range candidates {
+		distance := levenshtein(lower, strings.ToLower(candidate))
+		if distance < bestDistance {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+// levenshtein 计算编辑距离
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(minInt(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
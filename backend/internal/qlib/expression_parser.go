@@ -0,0 +1,577 @@
+package qlib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ExpressionError 表达式解析或检查错误，携带出错位置
+type ExpressionError struct {
+	Pos int    `json:"pos"` // 出错位置（从0开始的字符偏移）
+	Msg string `json:"msg"`
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("第%d个字符处: %s", e.Pos+1, e.Msg)
+}
+
+func newExpressionError(pos int, format string, args ...interface{}) *ExpressionError {
+	return &ExpressionError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// exprTokenKind 词法单元类型
+type exprTokenKind int
+
+const (
+	tokenEOF exprTokenKind = iota
+	tokenNumber
+	tokenField
+	tokenIdent
+	tokenLParen
+	tokenRParen
+	tokenComma
+	tokenOperator
+)
+
+// exprToken 词法单元
+type exprToken struct {
+	kind exprTokenKind
+	text string
+	pos  int
+}
+
+func (t exprToken) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "表达式结尾"
+	case tokenNumber:
+		return fmt.Sprintf("数字 %s", t.text)
+	case tokenField:
+		return fmt.Sprintf("字段 %s", t.text)
+	case tokenIdent:
+		return fmt.Sprintf("标识符 %s", t.text)
+	default:
+		return fmt.Sprintf("'%s'", t.text)
+	}
+}
+
+// tokenizeExpression 将表达式切分为词法单元
+func tokenizeExpression(expression string) ([]exprToken, error) {
+	runes := []rune(expression)
+	tokens := make([]exprToken, 0, len(runes)/2+1)
+
+	for i := 0; i < len(runes); {
+		ch := runes[i]
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '$':
+			start := i
+			i++
+			for i < len(runes) && isIdentRune(runes[i], i == start+1) {
+				i++
+			}
+			if i == start+1 {
+				return nil, newExpressionError(start, "字段名不能为空，'$' 后应跟字段名，例如 $close")
+			}
+			tokens = append(tokens, exprToken{kind: tokenField, text: string(runes[start:i]), pos: start})
+		case isIdentRune(ch, true):
+			start := i
+			for i < len(runes) && isIdentRune(runes[i], false) {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		case unicode.IsDigit(ch) || (ch == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			end, err := scanNumber(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: string(runes[start:i]), pos: start})
+		case ch == '(':
+			tokens = append(tokens, exprToken{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case ch == ')':
+			tokens = append(tokens, exprToken{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case ch == ',':
+			tokens = append(tokens, exprToken{kind: tokenComma, text: ",", pos: i})
+			i++
+		case ch == '+' || ch == '-' || ch == '*' || ch == '&' || ch == '|':
+			tokens = append(tokens, exprToken{kind: tokenOperator, text: string(ch), pos: i})
+			i++
+		case ch == '/':
+			if i+1 < len(runes) && runes[i+1] == '/' {
+				return nil, newExpressionError(i, "请使用 / 而不是 // 进行除法运算")
+			}
+			tokens = append(tokens, exprToken{kind: tokenOperator, text: "/", pos: i})
+			i++
+		case ch == '>' || ch == '<' || ch == '=' || ch == '!':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, exprToken{kind: tokenOperator, text: string(runes[i : i+2]), pos: i})
+				i += 2
+				continue
+			}
+			if ch == '=' {
+				return nil, newExpressionError(i, "比较相等请使用 ==")
+			}
+			if ch == '!' {
+				return nil, newExpressionError(i, "无效的运算符 '!'，不等于请使用 !=")
+			}
+			tokens = append(tokens, exprToken{kind: tokenOperator, text: string(ch), pos: i})
+			i++
+		default:
+			return nil, newExpressionError(i, "包含无效字符: %c", ch)
+		}
+	}
+
+	tokens = append(tokens, exprToken{kind: tokenEOF, pos: len(runes)})
+	return tokens, nil
+}
+
+// isIdentRune 判断字符是否可以出现在标识符中
+func isIdentRune(ch rune, first bool) bool {
+	if ch == '_' || (ch < unicode.MaxASCII && unicode.IsLetter(ch)) {
+		return true
+	}
+	return !first && ch < unicode.MaxASCII && unicode.IsDigit(ch)
+}
+
+// scanNumber 扫描数字字面量（支持小数和科学计数法），返回结束位置
+func scanNumber(runes []rune, start int) (int, error) {
+	i := start
+	seenDot := false
+	for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+		if runes[i] == '.' {
+			if seenDot {
+				return 0, newExpressionError(i, "数字格式错误: 包含多个小数点")
+			}
+			seenDot = true
+		}
+		i++
+	}
+	if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+		j := i + 1
+		if j < len(runes) && (runes[j] == '+' || runes[j] == '-') {
+			j++
+		}
+		if j >= len(runes) || !unicode.IsDigit(runes[j]) {
+			return 0, newExpressionError(i, "数字格式错误: 科学计数法缺少指数")
+		}
+		for j < len(runes) && unicode.IsDigit(runes[j]) {
+			j++
+		}
+		i = j
+	}
+	if i < len(runes) && isIdentRune(runes[i], true) {
+		return 0, newExpressionError(i, "数字后不能直接跟标识符")
+	}
+	return i, nil
+}
+
+// ExprNode 因子表达式语法树节点
+type ExprNode interface {
+	Pos() int
+	exprNode()
+}
+
+// NumberLit 数字常量
+type NumberLit struct {
+	Position int
+	Value    float64
+	Raw      string
+	IsInt    bool
+}
+
+// FieldRef 数据字段引用，如 $close
+type FieldRef struct {
+	Position int
+	Name     string
+}
+
+// UnaryExpr 一元运算
+type UnaryExpr struct {
+	Position int
+	Op       string
+	X        ExprNode
+}
+
+// BinaryExpr 二元运算
+type BinaryExpr struct {
+	Position int
+	Op       string
+	X        ExprNode
+	Y        ExprNode
+}
+
+// CallExpr 函数调用
+type CallExpr struct {
+	Position int
+	Func     string
+	Args     []ExprNode
+}
+
+func (n *NumberLit) Pos() int  { return n.Position }
+func (n *FieldRef) Pos() int   { return n.Position }
+func (n *UnaryExpr) Pos() int  { return n.Position }
+func (n *BinaryExpr) Pos() int { return n.Position }
+func (n *CallExpr) Pos() int   { return n.Position }
+
+func (*NumberLit) exprNode()  {}
+func (*FieldRef) exprNode()   {}
+func (*UnaryExpr) exprNode()  {}
+func (*BinaryExpr) exprNode() {}
+func (*CallExpr) exprNode()   {}
+
+// 运算符优先级，数值越大结合越紧
+const (
+	precLowest = iota
+	precOr
+	precAnd
+	precCompare
+	precAdditive
+	precMultiplicative
+	precUnary
+)
+
+// binaryPrecedence 返回二元运算符的优先级
+func binaryPrecedence(op string) int {
+	switch op {
+	case "|":
+		return precOr
+	case "&":
+		return precAnd
+	case ">", "<", ">=", "<=", "==", "!=":
+		return precCompare
+	case "+", "-":
+		return precAdditive
+	case "*", "/":
+		return precMultiplicative
+	}
+	return precLowest
+}
+
+// exprParser 递归下降（优先级爬升）解析器
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	parens map[ExprNode]bool // 带括号的子表达式
+}
+
+// ParseExpression 将因子表达式解析为语法树，仅做语法检查
+func ParseExpression(expression string) (ExprNode, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, newExpressionError(0, "表达式不能为空")
+	}
+
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens, parens: make(map[ExprNode]bool)}
+	node, err := p.parseBinary(precOr)
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		if tok.kind == tokenRParen {
+			return nil, newExpressionError(tok.pos, "括号不匹配: 多余的 ')'")
+		}
+		return nil, newExpressionError(tok.pos, "意外的%s，缺少运算符", tok.describe())
+	}
+
+	return node, nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// parseBinary 解析优先级不低于minPrec的二元表达式（左结合）
+func (p *exprParser) parseBinary(minPrec int) (ExprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		if tok.kind != tokenOperator {
+			return left, nil
+		}
+		prec := binaryPrecedence(tok.text)
+		if prec < minPrec {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.checkComparisonOperands(tok, prec, left, right); err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Position: tok.pos, Op: tok.text, X: left, Y: right}
+	}
+}
+
+// checkComparisonOperands 拒绝没有括号的比较运算作为 &、| 或另一个比较的操作数
+//
+// Qlib按Python语义求值：& 和 | 的优先级高于比较运算，比较运算会链式展开，
+// 不加括号时原生引擎和Python后端对同一表达式的理解不同。
+func (p *exprParser) checkComparisonOperands(op exprToken, prec int, operands ...ExprNode) error {
+	if prec != precAnd && prec != precOr && prec != precCompare {
+		return nil
+	}
+	for _, operand := range operands {
+		child, ok := operand.(*BinaryExpr)
+		if !ok || binaryPrecedence(child.Op) != precCompare || p.parens[operand] {
+			continue
+		}
+		if prec == precCompare {
+			return newExpressionError(op.pos, "比较运算不能连用，请加括号，如 (a %s b) %s c", child.Op, op.text)
+		}
+		return newExpressionError(op.pos, "比较运算作为 %s 的操作数时必须加括号，如 ($close > $open) %s ($volume > 0)", op.text, op.text)
+	}
+	return nil
+}
+
+// parseUnary 解析一元正负号
+func (p *exprParser) parseUnary() (ExprNode, error) {
+	tok := p.peek()
+	if tok.kind == tokenOperator && (tok.text == "-" || tok.text == "+") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if tok.text == "+" {
+			return operand, nil
+		}
+		return &UnaryExpr{Position: tok.pos, Op: "-", X: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary 解析字段、数字、函数调用和括号表达式
+func (p *exprParser) parsePrimary() (ExprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, newExpressionError(tok.pos, "无效的数字: %s", tok.text)
+		}
+		isInt := !strings.ContainsAny(tok.text, ".eE")
+		return &NumberLit{Position: tok.pos, Value: value, Raw: tok.text, IsInt: isInt}, nil
+
+	case tokenField:
+		return &FieldRef{Position: tok.pos, Name: tok.text}, nil
+
+	case tokenIdent:
+		if p.peek().kind != tokenLParen {
+			return nil, newExpressionError(tok.pos, "未知标识符 %s，字段需要以 $ 开头（如 $%s），函数需要跟括号", tok.text, strings.ToLower(tok.text))
+		}
+		return p.parseCall(tok)
+
+	case tokenLParen:
+		inner, err := p.parseBinary(precOr)
+		if err != nil {
+			return nil, err
+		}
+		closing := p.next()
+		if closing.kind != tokenRParen {
+			if closing.kind == tokenEOF {
+				return nil, newExpressionError(tok.pos, "括号不匹配: 缺少与之对应的 ')'")
+			}
+			return nil, newExpressionError(closing.pos, "意外的%s，期望 ')'", closing.describe())
+		}
+		p.parens[inner] = true
+		return inner, nil
+
+	case tokenEOF:
+		return nil, newExpressionError(tok.pos, "表达式不完整，缺少操作数")
+
+	case tokenRParen:
+		return nil, newExpressionError(tok.pos, "意外的 ')'，缺少操作数")
+
+	default:
+		return nil, newExpressionError(tok.pos, "意外的%s，期望字段、数字或函数调用", tok.describe())
+	}
+}
+
+// parseCall 解析函数调用参数列表
+func (p *exprParser) parseCall(name exprToken) (ExprNode, error) {
+	open := p.next()
+	call := &CallExpr{Position: name.pos, Func: name.text}
+
+	if p.peek().kind == tokenRParen {
+		p.next()
+		return call, nil
+	}
+
+	for {
+		arg, err := p.parseBinary(precOr)
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+
+		tok := p.next()
+		switch tok.kind {
+		case tokenComma:
+			continue
+		case tokenRParen:
+			return call, nil
+		case tokenEOF:
+			return nil, newExpressionError(open.pos, "括号不匹配: 函数 %s 缺少 ')'", name.text)
+		default:
+			return nil, newExpressionError(tok.pos, "意外的%s，函数 %s 的参数之间应使用 ',' 分隔", tok.describe(), name.text)
+		}
+	}
+}
+
+// FormatExpression 将语法树输出为规范化的表达式文本
+func FormatExpression(node ExprNode) string {
+	var sb strings.Builder
+	writeExpression(&sb, node)
+	return sb.String()
+}
+
+func writeExpression(sb *strings.Builder, node ExprNode) {
+	switch n := node.(type) {
+	case *NumberLit:
+		sb.WriteString(formatNumber(n))
+	case *FieldRef:
+		sb.WriteString(n.Name)
+	case *UnaryExpr:
+		sb.WriteString(n.Op)
+		if _, simple := n.X.(*BinaryExpr); simple {
+			writeParenthesized(sb, n.X)
+		} else if _, nested := n.X.(*UnaryExpr); nested {
+			writeParenthesized(sb, n.X)
+		} else {
+			writeExpression(sb, n.X)
+		}
+	case *BinaryExpr:
+		prec := binaryPrecedence(n.Op)
+		writeOperand(sb, n.X, prec, false)
+		sb.WriteString(" " + n.Op + " ")
+		writeOperand(sb, n.Y, prec, true)
+	case *CallExpr:
+		sb.WriteString(n.Func)
+		sb.WriteString("(")
+		for i, arg := range n.Args {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			writeExpression(sb, arg)
+		}
+		sb.WriteString(")")
+	}
+}
+
+// writeOperand 输出二元运算的操作数，必要时加括号
+//
+// Qlib最终按Python语义求值：& 和 | 的优先级高于比较运算，且比较运算会链式展开，
+// 因此比较运算作为 &、| 或另一个比较的操作数时必须保留括号。
+func writeOperand(sb *strings.Builder, operand ExprNode, parentPrec int, isRight bool) {
+	child, ok := operand.(*BinaryExpr)
+	if !ok {
+		writeExpression(sb, operand)
+		return
+	}
+
+	childPrec := binaryPrecedence(child.Op)
+	needParens := childPrec < parentPrec ||
+		(isRight && childPrec == parentPrec && !isAssociativeOperator(child.Op)) ||
+		(childPrec == precCompare && parentPrec <= precCompare)
+
+	if needParens {
+		writeParenthesized(sb, operand)
+	} else {
+		writeExpression(sb, operand)
+	}
+}
+
+func writeParenthesized(sb *strings.Builder, node ExprNode) {
+	sb.WriteString("(")
+	writeExpression(sb, node)
+	sb.WriteString(")")
+}
+
+// isAssociativeOperator 判断运算符是否满足结合律（右侧同级运算可省略括号）
+func isAssociativeOperator(op string) bool {
+	return op == "+" || op == "*" || op == "&" || op == "|"
+}
+
+// formatNumber 规范化数字输出
+func formatNumber(n *NumberLit) string {
+	if n.IsInt {
+		return strconv.FormatFloat(n.Value, 'f', -1, 64)
+	}
+	text := strconv.FormatFloat(n.Value, 'g', -1, 64)
+	if !strings.ContainsAny(text, ".eE") {
+		text += ".0"
+	}
+	return text
+}
+
+// ExpressionToMap 将语法树转换为可序列化的结构
+func ExpressionToMap(node ExprNode) map[string]interface{} {
+	switch n := node.(type) {
+	case *NumberLit:
+		return map[string]interface{}{"type": "number", "value": n.Value, "pos": n.Position}
+	case *FieldRef:
+		return map[string]interface{}{"type": "field", "name": n.Name, "pos": n.Position}
+	case *UnaryExpr:
+		return map[string]interface{}{"type": "unary", "op": n.Op, "operand": ExpressionToMap(n.X), "pos": n.Position}
+	case *BinaryExpr:
+		return map[string]interface{}{
+			"type":  "binary",
+			"op":    n.Op,
+			"left":  ExpressionToMap(n.X),
+			"right": ExpressionToMap(n.Y),
+			"pos":   n.Position,
+		}
+	case *CallExpr:
+		args := make([]interface{}, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = ExpressionToMap(arg)
+		}
+		return map[string]interface{}{"type": "call", "func": n.Func, "args": args, "pos": n.Position}
+	}
+	return nil
+}
+
+// WalkExpression 深度优先遍历语法树，visit返回false时不再进入子节点
+func WalkExpression(node ExprNode, visit func(ExprNode) bool) {
+	if node == nil || !visit(node) {
+		return
+	}
+	switch n := node.(type) {
+	case *UnaryExpr:
+		WalkExpression(n.X, visit)
+	case *BinaryExpr:
+		WalkExpression(n.X, visit)
+		WalkExpression(n.Y, visit)
+	case *CallExpr:
+		for _, arg := range n.Args {
+			WalkExpression(arg, visit)
+		}
+	}
+}
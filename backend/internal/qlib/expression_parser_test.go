@@ -0,0 +1,173 @@
+package qlib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFactorExpression(t *testing.T) {
+	t.Run("ValidExpressions", func(t *testing.T) {
+		cases := map[string]string{
+			"$close":                 "$close",
+			"$close/Ref($close,1)-1": "$close / Ref($close, 1) - 1",
+			"($high+$low+$close)/3":  "($high + $low + $close) / 3",
+			"Corr($close, Log($volume+1), 20) / Ref(Mean($close,5),1)": "Corr($close, Log($volume + 1), 20) / Ref(Mean($close, 5), 1)",
+			"$close - ($open - $low)":                                  "$close - ($open - $low)",
+			"$close * ($open * $low)":                                  "$close * $open * $low",
+			"-(-$close)":                                               "-(-$close)",
+			"If(($close>$open)&($volume>0), 1, -1)":                    "If(($close > $open) & ($volume > 0), 1, -1)",
+			"Ref($close, -2)":                                          "Ref($close, -2)",
+			"Quantile($close, 20, .8)":                                 "Quantile($close, 20, 0.8)",
+			"Rank($close)":                                             "Rank($close)",
+		}
+
+		for expr, canonical := range cases {
+			parsed, err := ParseFactorExpression(expr, nil)
+			if err != nil {
+				t.Errorf("Expression '%s' should be valid: %v", expr, err)
+				continue
+			}
+			if parsed.Canonical != canonical {
+				t.Errorf("Canonical form of '%s' = '%s', want '%s'", expr, parsed.Canonical, canonical)
+			}
+
+			// 规范化输出应当可以再次解析且保持不变
+			reparsed, err := ParseFactorExpression(parsed.Canonical, nil)
+			if err != nil || reparsed.Canonical != parsed.Canonical {
+				t.Errorf("Canonical form '%s' should round-trip", parsed.Canonical)
+			}
+		}
+	})
+
+	t.Run("Precedence", func(t *testing.T) {
+		root, err := ParseExpression("($close + $open * 2 > $high) | ($low < 1)")
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		or, ok := root.(*BinaryExpr)
+		if !ok || or.Op != "|" {
+			t.Fatalf("Root should be '|', got %s", FormatExpression(root))
+		}
+		gt, ok := or.X.(*BinaryExpr)
+		if !ok || gt.Op != ">" {
+			t.Fatalf("Left of '|' should be '>'")
+		}
+		plus, ok := gt.X.(*BinaryExpr)
+		if !ok || plus.Op != "+" {
+			t.Fatalf("Left of '>' should be '+'")
+		}
+		if mul, ok := plus.Y.(*BinaryExpr); !ok || mul.Op != "*" {
+			t.Errorf("'*' should bind tighter than '+'")
+		}
+	})
+
+	// Python后端按 & 和 | 高于比较运算的优先级求值，带括号的写法两边含义一致，规范化输出必须保留括号
+	t.Run("PythonPrecedenceParity", func(t *testing.T) {
+		cases := map[string]string{
+			"($close>3)|($volume<4)":          "($close > 3) | ($volume < 4)",
+			"If(($close>$open)&($low>0),1,0)": "If(($close > $open) & ($low > 0), 1, 0)",
+			"($close & $open) > 0":            "($close & $open) > 0",
+			"$close > ($open & $low)":         "$close > ($open & $low)",
+		}
+		for expr, canonical := range cases {
+			parsed, err := ParseFactorExpression(expr, nil)
+			if err != nil {
+				t.Errorf("Expression '%s' should be valid: %v", expr, err)
+				continue
+			}
+			if parsed.Canonical != canonical {
+				t.Errorf("Canonical form of '%s' = '%s', want '%s'", expr, parsed.Canonical, canonical)
+			}
+		}
+	})
+
+	t.Run("UsedFieldsAndFunctions", func(t *testing.T) {
+		parsed, err := ParseFactorExpression("Corr($close, Log($volume+1), 20) / Mean($close, 5)", nil)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if strings.Join(parsed.Fields, ",") != "$close,$volume" {
+			t.Errorf("Unexpected fields: %v", parsed.Fields)
+		}
+		if strings.Join(parsed.Functions, ",") != "Corr,Log,Mean" {
+			t.Errorf("Unexpected functions: %v", parsed.Functions)
+		}
+	})
+
+	t.Run("InvalidExpressions", func(t *testing.T) {
+		cases := []struct {
+			expr    string
+			pos     int
+			message string
+		}{
+			{"", 0, "表达式不能为空"},
+			{"$close +", 8, "缺少操作数"},
+			{"Mean($close, 5", 4, "缺少 ')'"},
+			{"$close)", 6, "多余的 ')'"},
+			{"$close // 2", 7, "请使用 / 而不是 // 进行除法运算"},
+			{"Mean($close；5)", 11, "包含无效字符"},
+			{"$foo + 1", 0, "未知字段: $foo"},
+			{"close + 1", 0, "未知标识符"},
+			{"Foo($close)", 0, "未知函数: Foo"},
+			{"Mean($close)", 0, "需要 2 个参数"},
+			{"Mean($close, $open)", 13, "必须是数值常量"},
+			{"Mean($close, 2.5)", 13, "必须是整数"},
+			{"Mean($close, -5)", 13, "不能为负数"},
+			{"Mean(5, 10)", 5, "必须是时间序列"},
+			{"Mean($close, 1e12)", 13, "不能超过 10000"},
+			{"Ref($close, -20000)", 12, "不能超过 10000"},
+			{"Quantile($close, 3, 1.5)", 20, "必须在0到1之间"},
+			{"Quantile($close, 3, -0.5)", 20, "必须在0到1之间"},
+			{"1 + 2", 2, "必须至少引用一个数据字段"},
+			{"$close $open", 7, "缺少运算符"},
+			{"$close > 0 & $open > 0", 11, "必须加括号"},
+			{"($close > 0) | $open > 0", 13, "必须加括号"},
+			{"$close > $open > 0", 15, "不能连用"},
+		}
+
+		for _, tc := range cases {
+			_, err := ParseFactorExpression(tc.expr, nil)
+			if err == nil {
+				t.Errorf("Expression '%s' should be invalid", tc.expr)
+				continue
+			}
+			exprErr, ok := err.(*ExpressionError)
+			if !ok {
+				t.Errorf("Expression '%s' should return ExpressionError, got %T", tc.expr, err)
+				continue
+			}
+			if exprErr.Pos != tc.pos {
+				t.Errorf("Expression '%s' error position = %d, want %d (%s)", tc.expr, exprErr.Pos, tc.pos, exprErr.Msg)
+			}
+			if !strings.Contains(exprErr.Msg, tc.message) {
+				t.Errorf("Expression '%s' error = '%s', want to contain '%s'", tc.expr, exprErr.Msg, tc.message)
+			}
+		}
+	})
+}
+
+func TestSyntaxValidatorNative(t *testing.T) {
+	validator := NewSyntaxValidator("", "")
+
+	result, err := validator.Validate("Mean($close,20)/ $close")
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if !result.IsValid || result.Canonical != "Mean($close, 20) / $close" {
+		t.Errorf("Unexpected validation result: %+v", result)
+	}
+	if !strings.Contains(result.ParsedAST, `"func":"Mean"`) {
+		t.Errorf("ParsedAST should describe the call tree: %s", result.ParsedAST)
+	}
+
+	result, err = validator.Validate("Maen($close, 20)")
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if result.IsValid || result.ErrorPos != 0 {
+		t.Errorf("Unexpected validation result: %+v", result)
+	}
+	if !strings.Contains(strings.Join(result.Suggestions, ";"), "Mean") {
+		t.Errorf("Suggestions should mention Mean: %v", result.Suggestions)
+	}
+}
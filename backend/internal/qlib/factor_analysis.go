@@ -0,0 +1,346 @@
+package qlib
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"qlib-backend/internal/analytics"
+)
+
+// DefaultICMinSamples 计算单日IC所需的最少证券数
+const DefaultICMinSamples = 10
+
+// DefaultICHorizons 默认分析的持有期（交易日）
+var DefaultICHorizons = []int{1, 5, 10, 20}
+
+// ForwardReturnExpression 持有 horizon 个交易日的未来收益
+//
+// 与Qlib的标签一致，因子在当日收盘后计算，次日收盘买入，持有 horizon 日后卖出。
+func ForwardReturnExpression(horizon int) string {
+	return fmt.Sprintf("Ref($close, -%d) / Ref($close, -1) - 1", horizon+1)
+}
+
+// ICOptions IC分析参数
+type ICOptions struct {
+	MinSamples int // 单日最少有效样本数，默认 DefaultICMinSamples
+	Workers    int // 按交易日并行的协程数，默认CPU核数
+}
+
+func (o ICOptions) withDefaults() ICOptions {
+	if o.MinSamples <= 0 {
+		o.MinSamples = DefaultICMinSamples
+	}
+	if o.MinSamples < 2 {
+		o.MinSamples = 2
+	}
+	if o.Workers <= 0 {
+		o.Workers = runtime.NumCPU()
+	}
+	return o
+}
+
+// ICStats IC序列的统计量
+type ICStats struct {
+	Mean          float64 `json:"mean"`
+	Std           float64 `json:"std"`
+	IR            float64 `json:"ir"`             // 均值/标准差
+	TStat         float64 `json:"t_stat"`         // 均值/标准差×√天数
+	PositiveRatio float64 `json:"positive_ratio"` // IC为正的天数占比
+	Days          int     `json:"days"`
+}
+
+// HorizonIC 单一持有期的IC分析结果，日度序列以 YYYY-MM-DD 为键
+type HorizonIC struct {
+	Horizon     int                `json:"horizon"`
+	IC          ICStats            `json:"ic"`
+	RankIC      ICStats            `json:"rank_ic"`
+	DailyIC     map[string]float64 `json:"daily_ic"`
+	DailyRankIC map[string]float64 `json:"daily_rank_ic"`
+}
+
+// FactorICReport 因子IC分析报告
+type FactorICReport struct {
+	Horizons        []HorizonIC `json:"horizons"`
+	Autocorrelation float64     `json:"autocorrelation"` // 相邻交易日因子排名自相关的均值
+	Turnover        float64     `json:"turnover"`        // 1 - 排名自相关
+	Coverage        float64     `json:"coverage"`        // 有效因子值占比
+	SampleCount     int         `json:"sample_count"`    // 有效因子值个数
+	AvgInstruments  float64     `json:"avg_instruments"` // 平均每日有效证券数
+}
+
+// Horizon 查找指定持有期的结果
+func (r *FactorICReport) Horizon(horizon int) (HorizonIC, bool) {
+	for _, h := range r.Horizons {
+		if h.Horizon == horizon {
+			return h, true
+		}
+	}
+	return HorizonIC{}, false
+}
+
+// AnalyzeFactorIC 计算因子在各持有期的每日Pearson IC和Spearman RankIC及其统计量，以及换手率和覆盖率
+//
+// returns 的键为持有期，值为与因子同日对齐的未来收益；两者按证券代码和日期匹配，不要求证券顺序一致。
+// 各交易日之间相互独立，按交易日并行计算。
+func AnalyzeFactorIC(ctx context.Context, factor *FactorFrame, returns map[int]*FactorFrame, opts ICOptions) (*FactorICReport, error) {
+	opts = opts.withDefaults()
+	horizons := make([]int, 0, len(returns))
+	for horizon := range returns {
+		horizons = append(horizons, horizon)
+	}
+	sort.Ints(horizons)
+
+	report := &FactorICReport{}
+	for _, horizon := range horizons {
+		ic, rankIC, err := dailyIC(ctx, factor, returns[horizon], opts)
+		if err != nil {
+			return nil, err
+		}
+		report.Horizons = append(report.Horizons, HorizonIC{
+			Horizon:     horizon,
+			IC:          summarizeIC(ic),
+			RankIC:      summarizeIC(rankIC),
+			DailyIC:     ic,
+			DailyRankIC: rankIC,
+		})
+	}
+
+	autocorr, err := rankAutocorrelation(ctx, factor, opts)
+	if err != nil {
+		return nil, err
+	}
+	report.Autocorrelation = autocorr
+	report.Turnover = 1 - autocorr
+
+	total, days := 0, 0
+	for t := range factor.Calendar {
+		count := 0
+		for _, series := range factor.Values {
+			total++
+			if isValidValue(series[t]) {
+				count++
+			}
+		}
+		if count > 0 {
+			days++
+			report.SampleCount += count
+		}
+	}
+	if total > 0 {
+		report.Coverage = float64(report.SampleCount) / float64(total)
+	}
+	if days > 0 {
+		report.AvgInstruments = float64(report.SampleCount) / float64(days)
+	}
+	return report, nil
+}
+
+// dailyIC 逐日计算因子与收益的截面相关系数，样本不足或截面无差异的交易日被跳过
+func dailyIC(ctx context.Context, factor, returns *FactorFrame, opts ICOptions) (map[string]float64, map[string]float64, error) {
+	ic := make([]float64, len(factor.Calendar))
+	rankIC := make([]float64, len(factor.Calendar))
+	index := newFrameIndex(returns)
+
+	err := parallelDates(ctx, len(factor.Calendar), opts.Workers, func(t int) {
+		date := factor.Calendar[t]
+		x := make([]float64, 0, len(factor.Instruments))
+		y := make([]float64, 0, len(factor.Instruments))
+		for i, inst := range factor.Instruments {
+			if f, r := factor.Values[i][t], index.at(returns, inst, date); isValidValue(f) && isValidValue(r) {
+				x = append(x, f)
+				y = append(y, r)
+			}
+		}
+		ic[t], rankIC[t] = crossSectionCorr(x, y, opts.MinSamples)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return dateSeries(factor.Calendar, ic), dateSeries(factor.Calendar, rankIC), nil
+}
+
+// rankAutocorrelation 相邻交易日因子排名的截面相关系数均值，用于衡量因子换手
+func rankAutocorrelation(ctx context.Context, factor *FactorFrame, opts ICOptions) (float64, error) {
+	if len(factor.Calendar) < 2 {
+		return 0, nil
+	}
+	autocorr := make([]float64, len(factor.Calendar))
+	autocorr[0] = math.NaN()
+	err := parallelDates(ctx, len(factor.Calendar)-1, opts.Workers, func(k int) {
+		t := k + 1
+		x := make([]float64, 0, len(factor.Instruments))
+		y := make([]float64, 0, len(factor.Instruments))
+		for _, series := range factor.Values {
+			if isValidValue(series[t-1]) && isValidValue(series[t]) {
+				x = append(x, series[t-1])
+				y = append(y, series[t])
+			}
+		}
+		_, autocorr[t] = crossSectionCorr(x, y, opts.MinSamples)
+	})
+	if err != nil {
+		return 0, err
+	}
+	valid := validValues(autocorr)
+	if len(valid) == 0 {
+		return 0, nil
+	}
+	return analytics.Mean(valid), nil
+}
+
+// crossSectionCorr 截面Pearson和Spearman相关系数，样本不足或任一侧无差异时为 NaN
+func crossSectionCorr(x, y []float64, minSamples int) (float64, float64) {
+	if len(x) < minSamples || analytics.StdDev(x) == 0 || analytics.StdDev(y) == 0 {
+		return math.NaN(), math.NaN()
+	}
+	return analytics.Correlation(x, y), analytics.RankCorrelation(x, y)
+}
+
+// summarizeIC 计算IC序列的均值、标准差、IR、t统计量和正值占比，按日期顺序累加以保证结果可复现
+func summarizeIC(daily map[string]float64) ICStats {
+	dates := make([]string, 0, len(daily))
+	for date := range daily {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	values := make([]float64, 0, len(daily))
+	positive := 0
+	for _, date := range dates {
+		v := daily[date]
+		values = append(values, v)
+		if v > 0 {
+			positive++
+		}
+	}
+	stats := ICStats{Days: len(values)}
+	if len(values) == 0 {
+		return stats
+	}
+	stats.Mean = analytics.Mean(values)
+	stats.Std = analytics.StdDev(values)
+	stats.PositiveRatio = float64(positive) / float64(len(values))
+	if stats.Std > 0 {
+		stats.IR = stats.Mean / stats.Std
+		stats.TStat = stats.IR * math.Sqrt(float64(len(values)))
+	}
+	return stats
+}
+
+// parallelDates 将 n 个交易日（或其他相互独立的任务）分给 workers 个协程计算，fn 只应写入下标 t 对应的结果
+func parallelDates(ctx context.Context, n, workers int, fn func(t int)) error {
+	if workers > n {
+		workers = n
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range jobs {
+				fn(t)
+			}
+		}()
+	}
+
+	var err error
+	for t := 0; t < n; t++ {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		jobs <- t
+	}
+	close(jobs)
+	wg.Wait()
+	return err
+}
+
+// dateSeries 将按交易日排列的序列转换为 {日期: 值}，忽略 NaN
+func dateSeries(calendar []time.Time, values []float64) map[string]float64 {
+	series := make(map[string]float64)
+	for t, v := range values {
+		if isValidValue(v) {
+			series[calendar[t].Format("2006-01-02")] = v
+		}
+	}
+	return series
+}
+
+// FactorFrameFromValues 将逐条的因子值记录整理为按证券和日期排列的因子结果，无效记录为 NaN
+func FactorFrameFromValues(values []FactorValue) *FactorFrame {
+	dates := make(map[time.Time]bool)
+	instruments := make(map[string]bool)
+	for _, v := range values {
+		dates[v.Date] = true
+		instruments[v.Instrument] = true
+	}
+
+	frame := &FactorFrame{}
+	for date := range dates {
+		frame.Calendar = append(frame.Calendar, date)
+	}
+	sort.Slice(frame.Calendar, func(i, j int) bool { return frame.Calendar[i].Before(frame.Calendar[j]) })
+	for inst := range instruments {
+		frame.Instruments = append(frame.Instruments, inst)
+	}
+	sort.Strings(frame.Instruments)
+
+	dateIndex := make(map[time.Time]int, len(frame.Calendar))
+	for t, date := range frame.Calendar {
+		dateIndex[date] = t
+	}
+	instIndex := make(map[string]int, len(frame.Instruments))
+	frame.Values = make([][]float64, len(frame.Instruments))
+	for i, inst := range frame.Instruments {
+		instIndex[inst] = i
+		frame.Values[i] = nanSeries(len(frame.Calendar))
+	}
+	for _, v := range values {
+		if v.IsValid {
+			frame.Values[instIndex[v.Instrument]][dateIndex[v.Date]] = v.Value
+		}
+	}
+	return frame
+}
+
+// frameIndex 按证券代码和日期定位因子结果中的取值
+type frameIndex struct {
+	instruments map[string]int
+	dates       map[time.Time]int
+}
+
+func newFrameIndex(frame *FactorFrame) frameIndex {
+	index := frameIndex{
+		instruments: make(map[string]int, len(frame.Instruments)),
+		dates:       make(map[time.Time]int, len(frame.Calendar)),
+	}
+	for i, inst := range frame.Instruments {
+		index.instruments[inst] = i
+	}
+	for t, date := range frame.Calendar {
+		index.dates[date] = t
+	}
+	return index
+}
+
+// at 取证券在指定日期的值，不存在时为 NaN
+func (ix frameIndex) at(frame *FactorFrame, instrument string, date time.Time) float64 {
+	i, ok := ix.instruments[instrument]
+	if !ok {
+		return math.NaN()
+	}
+	t, ok := ix.dates[date]
+	if !ok {
+		return math.NaN()
+	}
+	return frame.Values[i][t]
+}
+
+func isValidValue(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
@@ -0,0 +1,135 @@
+package qlib
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestAnalyzeFactorIC(t *testing.T) {
+	calendar := weekdayCalendar(4)
+	nan := math.NaN()
+	factor := &FactorFrame{
+		Calendar:    calendar,
+		Instruments: []string{"A", "B", "C", "D"},
+		Values: [][]float64{
+			{1, 1, 1, nan},
+			{2, 2, 2, 2},
+			{3, 3, 3, 3},
+			{4, 4, 4, 4},
+		},
+	}
+	// 收益的证券顺序与因子不同；第1天完全同向，第2天完全反向，第3天排名同向但非线性
+	returns := &FactorFrame{
+		Calendar:    calendar,
+		Instruments: []string{"D", "C", "B", "A"},
+		Values: [][]float64{
+			{0.4, 0.1, 0.8, 0.1},
+			{0.3, 0.2, 0.4, 0.2},
+			{0.2, 0.3, 0.2, 0.2},
+			{0.1, 0.4, 0.1, 0.3},
+		},
+	}
+
+	report, err := AnalyzeFactorIC(context.Background(), factor, map[int]*FactorFrame{1: returns}, ICOptions{MinSamples: 4, Workers: 2})
+	if err != nil {
+		t.Fatalf("AnalyzeFactorIC failed: %v", err)
+	}
+	h, ok := report.Horizon(1)
+	if !ok {
+		t.Fatal("horizon 1 missing")
+	}
+	// 第4天A缺失，样本不足被跳过
+	if h.IC.Days != 3 || len(h.DailyIC) != 3 {
+		t.Fatalf("expected 3 IC days, got %d: %v", h.IC.Days, h.DailyIC)
+	}
+	day := func(i int) string { return calendar[i].Format("2006-01-02") }
+	if math.Abs(h.DailyIC[day(0)]-1) > 1e-12 || math.Abs(h.DailyIC[day(1)]+1) > 1e-12 {
+		t.Errorf("unexpected daily IC: %v", h.DailyIC)
+	}
+	if math.Abs(h.DailyRankIC[day(2)]-1) > 1e-12 || h.DailyIC[day(2)] > 0.99 {
+		t.Errorf("monotonic but nonlinear day should have RankIC 1 and IC < 1: %v, %v", h.DailyRankIC[day(2)], h.DailyIC[day(2)])
+	}
+	if math.Abs(h.IC.PositiveRatio-2.0/3) > 1e-12 || h.IC.TStat != h.IC.IR*math.Sqrt(3) {
+		t.Errorf("unexpected IC stats: %+v", h.IC)
+	}
+
+	// 因子排名不变，换手为0
+	if math.Abs(report.Turnover) > 1e-12 || math.Abs(report.Coverage-15.0/16) > 1e-12 {
+		t.Errorf("turnover = %v, coverage = %v", report.Turnover, report.Coverage)
+	}
+}
+
+func TestFactorCalculatorPerformanceNative(t *testing.T) {
+	calculator := NewFactorCalculator(nil)
+	calculator.SetICOptions(ICOptions{MinSamples: 3})
+	calendar := weekdayCalendar(2)
+	var factorData, returnData []FactorValue
+	for t, date := range calendar {
+		for i, inst := range []string{"A", "B", "C"} {
+			factorData = append(factorData, FactorValue{Instrument: inst, Date: date, Value: float64(i), IsValid: true})
+			returnData = append(returnData, FactorValue{Instrument: inst, Date: date, Value: float64(i*(t+1)) / 100, IsValid: true})
+		}
+	}
+
+	performance, err := calculator.CalculateFactorPerformance(context.Background(), "test", factorData, returnData)
+	if err != nil {
+		t.Fatalf("CalculateFactorPerformance failed: %v", err)
+	}
+	if len(performance.IC) != 2 || performance.Statistics["ic_mean"] < 0.999 || performance.Coverage != 1 {
+		t.Errorf("unexpected performance: %+v", performance)
+	}
+
+	correlation, err := calculator.GetFactorCorrelation(context.Background(), factorData, returnData)
+	if err != nil || correlation <= 0 || correlation > 1 {
+		t.Errorf("GetFactorCorrelation = %v, %v", correlation, err)
+	}
+	if correlation, _ := calculator.GetFactorCorrelation(context.Background(), nil, nil); correlation != 0 {
+		t.Errorf("empty correlation = %v, want 0", correlation)
+	}
+}
+
+func TestFactorEngineFactorIC(t *testing.T) {
+	calendar := weekdayCalendar(8)
+	var instruments []string
+	for i := 0; i < 12; i++ {
+		instruments = append(instruments, string(rune('A'+i)))
+	}
+	frame := NewMarketFrame(calendar, instruments)
+	// 价格越高的证券每日涨幅越大，因子 $close 与未来收益的排名完全一致
+	for i, inst := range instruments {
+		close := make([]float64, len(calendar))
+		close[0] = float64(10 * (i + 1))
+		for t := 1; t < len(calendar); t++ {
+			close[t] = close[t-1] * (1 + 0.01*float64(i+1))
+		}
+		frame.SetSeries(inst, "$close", close)
+	}
+	engine := NewFactorEngine("", "", "")
+	engine.SetDataProvider(NewMemoryDataProvider(frame))
+
+	report, err := engine.FactorIC(context.Background(), "$close", FrameRequest{}, []int{2, 1})
+	if err != nil {
+		t.Fatalf("FactorIC failed: %v", err)
+	}
+	if len(report.Horizons) != 2 || report.Horizons[0].Horizon != 1 {
+		t.Fatalf("horizons should be sorted: %+v", report.Horizons)
+	}
+	// 最后 horizon+1 天没有未来收益
+	for _, h := range report.Horizons {
+		if h.IC.Days != len(calendar)-h.Horizon-1 || math.Abs(h.RankIC.Mean-1) > 1e-12 {
+			t.Errorf("horizon %d: %+v", h.Horizon, h.RankIC)
+		}
+	}
+	if _, err := engine.FactorIC(context.Background(), "$close", FrameRequest{}, []int{0}); err == nil {
+		t.Error("non-positive horizon should fail")
+	}
+
+	result, err := engine.TestFactor(FactorTestParams{Expression: "$close", Horizons: []int{1}})
+	if err != nil {
+		t.Fatalf("TestFactor failed: %v", err)
+	}
+	if result.Coverage != 1 || math.Abs(result.RankIC-1) > 1e-12 || result.Details["backend"] != "native" {
+		t.Errorf("unexpected test result: %+v", result)
+	}
+}
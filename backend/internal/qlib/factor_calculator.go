@@ -6,11 +6,20 @@ import (
 	"fmt"
 	"log"
 	"time"
+
+	"qlib-backend/internal/analytics"
 )
 
 // FactorCalculator 因子计算接口
 type FactorCalculator struct {
 	client *QlibClient
+
+	// 设置数据提供者后因子在进程内计算，不再生成Python脚本
+	evaluator    *FactorEvaluator
+	dataProvider MarketDataProvider
+	universes    *UniverseRegistry
+
+	icOptions ICOptions
 }
 
 // FactorExpression 因子表达式
@@ -21,6 +30,11 @@ type FactorExpression struct {
 	Frequency  string `json:"frequency"`  // 计算频率
 	StartDate  string `json:"start_date"` // 开始日期
 	EndDate    string `json:"end_date"`   // 结束日期
+
+	// 预处理器链，因子测试使用与模型相同的变换后取值计算IC，仅原生计算支持
+	Processors   []ProcessorConfig `json:"processors,omitempty"`
+	FitStartDate string            `json:"fit_start_date,omitempty"` // 标准化参数的拟合区间，默认为全部数据
+	FitEndDate   string            `json:"fit_end_date,omitempty"`
 }
 
 // FactorResult 因子计算结果
@@ -58,19 +72,51 @@ func NewFactorCalculator(client *QlibClient) *FactorCalculator {
 	}
 }
 
+// SetDataProvider 设置原生计算使用的行情数据提供者
+func (fc *FactorCalculator) SetDataProvider(provider MarketDataProvider) {
+	fc.dataProvider = provider
+	if fc.evaluator == nil {
+		fc.evaluator = NewFactorEvaluator(0)
+	}
+	if fc.universes == nil {
+		fc.universes = DefaultUniverses
+	}
+}
+
+// SetUniverseRegistry 设置解析时点股票池使用的注册表
+func (fc *FactorCalculator) SetUniverseRegistry(registry *UniverseRegistry) {
+	fc.universes = registry
+}
+
+// SetICOptions 设置因子性能分析的IC参数
+func (fc *FactorCalculator) SetICOptions(opts ICOptions) {
+	fc.icOptions = opts
+}
+
 // CalculateFactor 计算单个因子
 func (fc *FactorCalculator) CalculateFactor(ctx context.Context, expr FactorExpression) (*FactorResult, error) {
+	if fc.dataProvider != nil {
+		return fc.calculateFactorNative(ctx, expr)
+	}
+	if len(expr.Processors) > 0 {
+		return nil, fmt.Errorf("因子预处理需要原生计算后端")
+	}
+
 	if !fc.client.IsInitialized() {
 		return nil, fmt.Errorf("Qlib客户端未初始化")
 	}
 
 	log.Printf("正在计算因子: %s", expr.Name)
 
-	// 构建因子计算脚本
-	script := fc.buildFactorScript(expr)
-
-	// 执行脚本
-	output, err := fc.client.ExecuteScript(ctx, script)
+	// 执行因子计算入口脚本
+	output, err := fc.client.RunEntryPoint(ctx, "calculate_factor", map[string]interface{}{
+		"factor_name": expr.Name,
+		"expression":  expr.Expression,
+		"universe":    expr.Universe,
+		"frequency":   expr.Frequency,
+		"start_date":  expr.StartDate,
+		"end_date":    expr.EndDate,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("计算因子失败: %w", err)
 	}
@@ -89,265 +135,127 @@ func (fc *FactorCalculator) CalculateFactor(ctx context.Context, expr FactorExpr
 	return &result, nil
 }
 
-// buildFactorScript 构建因子计算脚本
-func (fc *FactorCalculator) buildFactorScript(expr FactorExpression) string {
-	return fmt.Sprintf(`
-import json
-import qlib
-from qlib import data
-import pandas as pd
-import numpy as np
-from datetime import datetime
-
-try:
-	# 因子参数
-	factor_name = '%s'
-	expression = '''%s'''
-	universe = '%s'
-	frequency = '%s'
-	start_date = '%s'
-	end_date = '%s'
-	
-	# 设置股票池
-	if universe == 'csi300':
-		instruments = data.D.instruments(market='csi300')
-	elif universe == 'csi500':
-		instruments = data.D.instruments(market='csi500')
-	elif universe == 'all':
-		instruments = data.D.instruments(market='all')
-	else:
-		# 默认使用CSI300
-		instruments = data.D.instruments(market='csi300')
-	
-	# 计算因子
-	if frequency == 'day':
-		factor_data = data.D.features(
-			instruments=instruments,
-			fields=[expression],
-			start_time=start_date,
-			end_time=end_date,
-			freq='day'
-		)
-	elif frequency == 'minute':
-		factor_data = data.D.features(
-			instruments=instruments,
-			fields=[expression],
-			start_time=start_date,
-			end_time=end_date,
-			freq='1min'
-		)
-	else:
-		factor_data = data.D.features(
-			instruments=instruments,
-			fields=[expression],
-			start_time=start_date,
-			end_time=end_date,
-			freq='day'
-		)
-	
-	# 转换数据格式
-	factor_values = []
-	stats = {}
-	
-	if factor_data is not None and not factor_data.empty:
-		# 重置索引
-		factor_reset = factor_data.reset_index()
-		
-		for _, row in factor_reset.iterrows():
-			instrument = row['instrument'] if 'instrument' in row else str(row.name[0])
-			date = row['datetime'] if 'datetime' in row else str(row.name[1])
-			value = row[expression] if expression in row else None
-			
-			if pd.notna(value):
-				factor_values.append({
-					'instrument': instrument,
-					'date': str(date),
-					'value': float(value),
-					'is_valid': True
-				})
-			else:
-				factor_values.append({
-					'instrument': instrument,
-					'date': str(date),
-					'value': 0.0,
-					'is_valid': False
-				})
-		
-		# 计算统计指标
-		valid_values = [fv['value'] for fv in factor_values if fv['is_valid']]
-		if valid_values:
-			stats = {
-				'count': len(valid_values),
-				'mean': float(np.mean(valid_values)),
-				'std': float(np.std(valid_values)),
-				'min': float(np.min(valid_values)),
-				'max': float(np.max(valid_values)),
-				'median': float(np.median(valid_values)),
-				'skew': float(pd.Series(valid_values).skew()),
-				'kurt': float(pd.Series(valid_values).kurtosis()),
-				'coverage': len(valid_values) / len(factor_values)
-			}
-	
-	result = {
-		'success': True,
-		'factor_name': factor_name,
-		'data': factor_values,
-		'stats': stats,
-		'metadata': {
-			'expression': expression,
-			'universe': universe,
-			'frequency': frequency,
-			'start_date': start_date,
-			'end_date': end_date,
-			'total_points': len(factor_values)
-		}
+// calculateFactorNative 在进程内计算因子
+func (fc *FactorCalculator) calculateFactorNative(ctx context.Context, expr FactorExpression) (*FactorResult, error) {
+	parsed, err := ParseFactorExpression(expr.Expression, nil)
+	if err != nil {
+		return nil, fmt.Errorf("因子表达式语法错误: %w", err)
 	}
 
-except Exception as e:
-	result = {
-		'success': False,
-		'factor_name': factor_name,
-		'data': [],
-		'stats': {},
-		'error': str(e),
-		'metadata': {}
+	req := FrameRequest{Universe: expr.Universe, Fields: parsed.Fields, Freq: expr.Frequency}
+	if req.Start, err = parseOptionalDate(expr.StartDate); err != nil {
+		return nil, err
 	}
-
-print(json.dumps(result, default=str))
-`,
-		expr.Name, expr.Expression, expr.Universe, expr.Frequency, expr.StartDate, expr.EndDate)
-}
-
-// CalculateFactorPerformance 计算因子性能
-func (fc *FactorCalculator) CalculateFactorPerformance(ctx context.Context, factorName string, factorData []FactorValue, returnData []FactorValue) (*FactorPerformance, error) {
-	// 构建性能分析脚本
-	script := fc.buildPerformanceScript(factorName, factorData, returnData)
-
-	// 执行脚本
-	output, err := fc.client.ExecuteScript(ctx, script)
+	if req.End, err = parseOptionalDate(expr.EndDate); err != nil {
+		return nil, err
+	}
+	req.Lookback, req.Lookahead = ExpressionWindow(parsed.Root)
+	universe, err := resolveFrameUniverse(ctx, fc.universes, fc.dataProvider, &req)
 	if err != nil {
-		return nil, fmt.Errorf("计算因子性能失败: %w", err)
+		return nil, fmt.Errorf("解析股票池失败: %w", err)
 	}
 
-	// 解析结果
-	var result struct {
-		Success     bool              `json:"success"`
-		Performance FactorPerformance `json:"performance"`
-		Error       string            `json:"error"`
+	frame, err := fc.dataProvider.LoadFrame(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("加载行情数据失败: %w", err)
 	}
 
-	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, fmt.Errorf("解析性能结果失败: %w", err)
+	log.Printf("正在计算因子: %s", expr.Name)
+	values, err := fc.evaluator.Evaluate(ctx, parsed, frame)
+	if err != nil {
+		return nil, fmt.Errorf("计算因子失败: %w", err)
 	}
-
-	if !result.Success {
-		return nil, fmt.Errorf("性能计算失败: %s", result.Error)
+	values = values.Trim(req.Start, req.End)
+	if universe != nil {
+		values = universe.Mask(values)
+	}
+	if len(expr.Processors) > 0 {
+		if values, err = fc.processValues(ctx, expr, values); err != nil {
+			return nil, err
+		}
 	}
 
-	return &result.Performance, nil
+	data := values.ToFactorValues()
+	log.Printf("因子 %s 计算完成，共 %d 个数据点", expr.Name, len(data))
+	return &FactorResult{
+		Success:    true,
+		FactorName: expr.Name,
+		Data:       data,
+		Stats:      values.Statistics(),
+		Metadata: map[string]interface{}{
+			"expression":   expr.Expression,
+			"universe":     expr.Universe,
+			"frequency":    expr.Frequency,
+			"start_date":   expr.StartDate,
+			"end_date":     expr.EndDate,
+			"total_points": len(data),
+			"processors":   expr.Processors,
+			"backend":      "native",
+		},
+	}, nil
 }
 
-// buildPerformanceScript 构建性能分析脚本
-func (fc *FactorCalculator) buildPerformanceScript(factorName string, factorData, returnData []FactorValue) string {
-	factorJson, _ := json.Marshal(factorData)
-	returnJson, _ := json.Marshal(returnData)
-
-	return fmt.Sprintf(`
-import json
-import pandas as pd
-import numpy as np
-from scipy import stats
-
-try:
-	# 解析数据
-	factor_data = json.loads('''%s''')
-	return_data = json.loads('''%s''')
-	
-	# 构建DataFrame
-	factor_df = pd.DataFrame(factor_data)
-	return_df = pd.DataFrame(return_data)
-	
-	# 转换日期
-	factor_df['date'] = pd.to_datetime(factor_df['date'])
-	return_df['date'] = pd.to_datetime(return_df['date'])
-	
-	# 合并数据
-	merged = pd.merge(factor_df, return_df, on=['instrument', 'date'], suffixes=('_factor', '_return'))
-	merged = merged[(merged['is_valid_factor']) & (merged['is_valid_return'])]
-	
-	# 计算信息系数
-	ic_values = {}
-	rank_ic_values = {}
-	
-	# 按日期分组计算IC
-	for date, group in merged.groupby('date'):
-		if len(group) >= 10:  # 至少需要10个样本
-			# 普通IC
-			ic = stats.pearsonr(group['value_factor'], group['value_return'])[0]
-			if not np.isnan(ic):
-				ic_values[str(date)] = ic
-			
-			# 排序IC
-			rank_ic = stats.spearmanr(group['value_factor'], group['value_return'])[0]
-			if not np.isnan(rank_ic):
-				rank_ic_values[str(date)] = rank_ic
-	
-	# 计算统计指标
-	ic_list = list(ic_values.values())
-	rank_ic_list = list(rank_ic_values.values())
-	
-	ic_mean = np.mean(ic_list) if ic_list else 0
-	ic_std = np.std(ic_list) if ic_list else 0
-	icir = ic_mean / ic_std if ic_std > 0 else 0
-	
-	rank_ic_mean = np.mean(rank_ic_list) if rank_ic_list else 0
-	rank_ic_std = np.std(rank_ic_list) if rank_ic_list else 0
-	
-	# 计算覆盖率
-	total_possible = len(merged.groupby('date'))
-	coverage = len(ic_values) / total_possible if total_possible > 0 else 0
-	
-	# 计算换手率（简化版本）
-	turnover = 0.5  # 占位值，实际需要根据持仓变化计算
-	
-	performance = {
-		'ic': {
-			'mean': ic_mean,
-			'std': ic_std,
-			'positive_rate': sum(1 for ic in ic_list if ic > 0) / len(ic_list) if ic_list else 0,
-			'values': ic_values
-		},
-		'icir': icir,
-		'rank_ic': {
-			'mean': rank_ic_mean,
-			'std': rank_ic_std,
-			'positive_rate': sum(1 for ic in rank_ic_list if ic > 0) / len(rank_ic_list) if rank_ic_list else 0,
-			'values': rank_ic_values
-		},
-		'turnover': turnover,
-		'coverage': coverage,
-		'statistics': {
-			'sample_count': len(merged),
-			'date_count': len(ic_values),
-			'avg_stocks_per_day': len(merged) / len(ic_values) if ic_values else 0
-		}
+// processValues 按表达式配置的预处理器链变换因子值
+func (fc *FactorCalculator) processValues(ctx context.Context, expr FactorExpression, values *FactorFrame) (*FactorFrame, error) {
+	fitStart, err := parseOptionalDate(expr.FitStartDate)
+	if err != nil {
+		return nil, err
 	}
-	
-	result = {
-		'success': True,
-		'performance': performance
+	fitEnd, err := parseOptionalDate(expr.FitEndDate)
+	if err != nil {
+		return nil, err
 	}
+	chain, err := NewProcessorChain(expr.Processors, fitStart, fitEnd)
+	if err != nil {
+		return nil, err
+	}
+	processed, err := ApplyProcessors(ctx, fc.dataProvider, chain, values, expr.Frequency)
+	if err != nil {
+		return nil, fmt.Errorf("因子预处理失败: %w", err)
+	}
+	return processed, nil
+}
 
-except Exception as e:
-	result = {
-		'success': False,
-		'performance': {},
-		'error': str(e)
+// CalculateFactorPerformance 计算因子性能
+//
+// returnData 为与因子同日对齐的未来收益，在进程内逐日计算IC和RankIC，不再调用Python。
+func (fc *FactorCalculator) CalculateFactorPerformance(ctx context.Context, factorName string, factorData []FactorValue, returnData []FactorValue) (*FactorPerformance, error) {
+	returns := map[int]*FactorFrame{1: FactorFrameFromValues(returnData)}
+	report, err := AnalyzeFactorIC(ctx, FactorFrameFromValues(factorData), returns, fc.icOptions)
+	if err != nil {
+		return nil, fmt.Errorf("计算因子性能失败: %w", err)
 	}
+	return NewFactorPerformance(report, 1), nil
+}
 
-print(json.dumps(result, default=str))
-`,
-		string(factorJson), string(returnJson))
+// NewFactorPerformance 从IC分析报告中取指定持有期的结果
+func NewFactorPerformance(report *FactorICReport, horizon int) *FactorPerformance {
+	h, _ := report.Horizon(horizon)
+	if h.DailyIC == nil {
+		h.DailyIC, h.DailyRankIC = map[string]float64{}, map[string]float64{}
+	}
+	return &FactorPerformance{
+		IC:       h.DailyIC,
+		ICIR:     h.IC.IR,
+		RankIC:   h.DailyRankIC,
+		Turnover: report.Turnover,
+		Coverage: report.Coverage,
+		Statistics: map[string]float64{
+			"ic_mean":                h.IC.Mean,
+			"ic_std":                 h.IC.Std,
+			"ic_t_stat":              h.IC.TStat,
+			"ic_positive_ratio":      h.IC.PositiveRatio,
+			"rank_ic_mean":           h.RankIC.Mean,
+			"rank_ic_std":            h.RankIC.Std,
+			"rank_icir":              h.RankIC.IR,
+			"rank_ic_t_stat":         h.RankIC.TStat,
+			"rank_ic_positive_ratio": h.RankIC.PositiveRatio,
+			"autocorrelation":        report.Autocorrelation,
+			"sample_count":           float64(report.SampleCount),
+			"date_count":             float64(h.IC.Days),
+			"avg_stocks_per_day":     report.AvgInstruments,
+		},
+	}
 }
 
 // BatchCalculateFactors 批量计算因子
@@ -374,47 +282,9 @@ func (fc *FactorCalculator) BatchCalculateFactors(ctx context.Context, expressio
 
 // ValidateFactorExpression 验证因子表达式
 func (fc *FactorCalculator) ValidateFactorExpression(ctx context.Context, expression string) (bool, error) {
-	script := fmt.Sprintf(`
-import json
-import qlib
-from qlib import data
-
-try:
-	expression = '''%s'''
-	
-	# 尝试解析表达式
-	# 这里使用简单的测试数据验证表达式语法
-	test_instruments = ['000001.SZ']  # 使用单个股票测试
-	test_start = '2023-01-01'
-	test_end = '2023-01-02'
-	
-	# 尝试计算表达式
-	test_data = data.D.features(
-		instruments=test_instruments,
-		fields=[expression],
-		start_time=test_start,
-		end_time=test_end,
-		freq='day'
-	)
-	
-	# 如果没有异常，则表达式有效
-	result = {
-		'success': True,
-		'valid': True,
-		'message': '表达式语法有效'
-	}
-
-except Exception as e:
-	result = {
-		'success': True,
-		'valid': False,
-		'message': str(e)
-	}
-
-print(json.dumps(result))
-`, expression)
-
-	output, err := fc.client.ExecuteScript(ctx, script)
+	output, err := fc.client.RunEntryPoint(ctx, "validate_factor", map[string]interface{}{
+		"expression": expression,
+	})
 	if err != nil {
 		return false, fmt.Errorf("验证因子表达式失败: %w", err)
 	}
@@ -522,76 +392,31 @@ print(json.dumps(result))
 	return response.Factors, nil
 }
 
-// GetFactorCorrelation 计算因子相关性
+// GetFactorCorrelation 计算因子相关性，按证券和日期匹配两个因子的有效值后计算Pearson相关系数
 func (fc *FactorCalculator) GetFactorCorrelation(ctx context.Context, factor1, factor2 []FactorValue) (float64, error) {
-	factor1Json, _ := json.Marshal(factor1)
-	factor2Json, _ := json.Marshal(factor2)
-
-	script := fmt.Sprintf(`
-import json
-import pandas as pd
-import numpy as np
-from scipy import stats
-
-try:
-	factor1_data = json.loads('''%s''')
-	factor2_data = json.loads('''%s''')
-	
-	# 构建DataFrame
-	df1 = pd.DataFrame(factor1_data)
-	df2 = pd.DataFrame(factor2_data)
-	
-	# 转换日期
-	df1['date'] = pd.to_datetime(df1['date'])
-	df2['date'] = pd.to_datetime(df2['date'])
-	
-	# 合并数据
-	merged = pd.merge(df1, df2, on=['instrument', 'date'], suffixes=('_1', '_2'))
-	merged = merged[(merged['is_valid_1']) & (merged['is_valid_2'])]
-	
-	# 计算相关性
-	if len(merged) > 0:
-		correlation = stats.pearsonr(merged['value_1'], merged['value_2'])[0]
-		if np.isnan(correlation):
-			correlation = 0.0
-	else:
-		correlation = 0.0
-	
-	result = {
-		'success': True,
-		'correlation': float(correlation),
-		'sample_size': len(merged)
-	}
-
-except Exception as e:
-	result = {
-		'success': False,
-		'correlation': 0.0,
-		'error': str(e)
-	}
-
-print(json.dumps(result, default=str))
-`,
-		string(factor1Json), string(factor2Json))
-
-	output, err := fc.client.ExecuteScript(ctx, script)
-	if err != nil {
-		return 0, fmt.Errorf("计算因子相关性失败: %w", err)
-	}
-
-	var response struct {
-		Success     bool    `json:"success"`
-		Correlation float64 `json:"correlation"`
-		Error       string  `json:"error"`
+	type key struct {
+		instrument string
+		date       time.Time
+	}
+	values := make(map[key]float64, len(factor1))
+	for _, v := range factor1 {
+		if v.IsValid {
+			values[key{v.Instrument, v.Date}] = v.Value
+		}
 	}
 
-	if err := json.Unmarshal(output, &response); err != nil {
-		return 0, fmt.Errorf("解析相关性结果失败: %w", err)
+	var x, y []float64
+	for _, v := range factor2 {
+		if !v.IsValid {
+			continue
+		}
+		if value, ok := values[key{v.Instrument, v.Date}]; ok {
+			x = append(x, value)
+			y = append(y, v.Value)
+		}
 	}
-
-	if !response.Success {
-		return 0, fmt.Errorf("计算相关性失败: %s", response.Error)
+	if err := ctx.Err(); err != nil {
+		return 0, err
 	}
-
-	return response.Correlation, nil
-}
\ No newline at end of file
+	return analytics.Correlation(x, y), nil
+}
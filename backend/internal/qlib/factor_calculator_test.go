@@ -2,80 +2,71 @@ package qlib
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
 	"testing"
 	"time"
-
-	"qlib-backend/internal/testutils"
 )
 
-func TestFactorCalculator(t *testing.T) {
-	testutils.SetupTestEnv()
-	defer testutils.CleanupTestEnv()
+// newFakeFactorCalculator 创建使用合成行情在进程内计算的因子计算器
+func newFakeFactorCalculator() *FactorCalculator {
+	calculator := NewFactorCalculator(newEntryPointClient(&recordingClient{}))
+	calculator.SetDataProvider(NewFakeDataProvider(FakeMarketConfig{
+		Seed:        7,
+		Instruments: 10,
+		Start:       time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:         time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+	}))
+	return calculator
+}
 
-	// 创建模拟的Qlib客户端和因子计算器
-	mockClient := testutils.NewMockQlibClient()
-	calculator := NewFactorCalculator(mockClient)
+func TestFactorCalculator(t *testing.T) {
+	calculator := newFakeFactorCalculator()
 
 	t.Run("CalculatorCreation", func(t *testing.T) {
 		if calculator == nil {
-			t.Error("Factor calculator should not be nil")
+			t.Fatal("Factor calculator should not be nil")
 		}
 
-		if calculator.client != mockClient {
-			t.Error("Calculator should use the provided client")
+		if calculator.dataProvider == nil || calculator.evaluator == nil {
+			t.Error("Calculator should compute natively after SetDataProvider")
 		}
 	})
 
 	t.Run("FactorExpressionValidation", func(t *testing.T) {
 		ctx := context.Background()
 
-		// 测试不同的因子表达式
-		validExpressions := []string{
-			"$close",
-			"$close / Ref($close, 1) - 1",
-			"($high + $low + $close) / 3",
-			"Mean($close, 5)",
-			"Std($close, 20)",
-			"($close - Mean($close, 20)) / Std($close, 20)",
-		}
-
-		for _, expr := range validExpressions {
-			t.Run(fmt.Sprintf("ValidExpression_%s", expr[:10]), func(t *testing.T) {
-				isValid, err := calculator.ValidateFactorExpression(ctx, expr)
-				if err != nil {
-					t.Errorf("Validation failed for expression '%s': %v", expr, err)
-				}
-
-				if !isValid {
-					t.Errorf("Expression '%s' should be valid", expr)
-				}
-			})
-		}
-
-		// 测试无效表达式
-		invalidExpressions := []string{
-			"",
-			"invalid syntax",
-			"$close +",
-			"Ref($close)",  // 缺少参数
-			"undefined_function($close)",
+		// 验证结果由入口脚本返回，表达式通过标准输入传递
+		for _, tc := range []struct {
+			expression string
+			output     string
+			valid      bool
+		}{
+			{"$close / Ref($close, 1) - 1", `{"success": true, "valid": true}`, true},
+			{"($close - Mean($close, 20)) / Std($close, 20)", `{"success": true, "valid": true}`, true},
+			{"undefined_function($close)", `{"success": true, "valid": false, "message": "unknown operator"}`, false},
+		} {
+			client := &recordingClient{output: tc.output}
+			validator := NewFactorCalculator(newEntryPointClient(client))
+
+			isValid, err := validator.ValidateFactorExpression(ctx, tc.expression)
+			if err != nil {
+				t.Errorf("Validation failed for expression '%s': %v", tc.expression, err)
+			}
+			if isValid != tc.valid {
+				t.Errorf("Expression '%s' valid = %v, want %v", tc.expression, isValid, tc.valid)
+			}
+			if params := decodeEntryPointInput(t, client.inputs[0]); params["expression"] != tc.expression {
+				t.Errorf("Expression should be passed as input, got %v", params["expression"])
+			}
 		}
 
-		for _, expr := range invalidExpressions {
-			t.Run(fmt.Sprintf("InvalidExpression_%s", expr), func(t *testing.T) {
-				isValid, err := calculator.ValidateFactorExpression(ctx, expr)
-				
-				// 空表达式应该返回错误
-				if expr == "" && err == nil {
-					t.Error("Empty expression should return error")
-				}
-
-				// 其他无效表达式应该返回false
-				if expr != "" && err == nil && isValid {
-					t.Errorf("Expression '%s' should be invalid", expr)
-				}
-			})
+		// 脚本执行失败时返回错误
+		validator := NewFactorCalculator(newEntryPointClient(&recordingClient{output: `{"success": false}`}))
+		if _, err := validator.ValidateFactorExpression(ctx, ""); err == nil {
+			t.Error("Failed validation script should return error")
 		}
 	})
 
@@ -114,13 +105,7 @@ func TestFactorCalculator(t *testing.T) {
 			t.Run(fmt.Sprintf("Calculate_%s", factorExpr.Name), func(t *testing.T) {
 				result, err := calculator.CalculateFactor(ctx, factorExpr)
 				if err != nil {
-					t.Errorf("Factor calculation failed for '%s': %v", factorExpr.Name, err)
-					return
-				}
-
-				if result == nil {
-					t.Errorf("Factor result should not be nil for '%s'", factorExpr.Name)
-					return
+					t.Fatalf("Factor calculation failed for '%s': %v", factorExpr.Name, err)
 				}
 
 				// 验证结果结构
@@ -132,9 +117,13 @@ func TestFactorCalculator(t *testing.T) {
 					t.Errorf("Result factor name mismatch: expected %s, got %s", factorExpr.Name, result.FactorName)
 				}
 
+				if len(result.Data) == 0 {
+					t.Errorf("Factor result should contain values for '%s'", factorExpr.Name)
+				}
+
 				// 验证元数据
-				if result.Metadata == nil {
-					t.Errorf("Factor result should contain metadata for '%s'", factorExpr.Name)
+				if result.Metadata == nil || result.Metadata["backend"] != "native" {
+					t.Errorf("Factor result should contain native metadata for '%s'", factorExpr.Name)
 				}
 
 				// 验证统计数据
@@ -167,7 +156,7 @@ func TestFactorCalculator(t *testing.T) {
 			},
 			{
 				Name:       "factor_3",
-				Expression: "$volume",
+				Expression: "$close +",
 				Universe:   "csi300",
 				Frequency:  "day",
 				StartDate:  "2023-01-01",
@@ -181,28 +170,41 @@ func TestFactorCalculator(t *testing.T) {
 		}
 
 		if len(results) != len(expressions) {
-			t.Errorf("Expected %d results, got %d", len(expressions), len(results))
+			t.Fatalf("Expected %d results, got %d", len(expressions), len(results))
 		}
 
-		// 验证每个结果
+		// 验证每个结果，语法错误的因子记录为失败而不影响其他因子
 		for i, result := range results {
 			expectedName := expressions[i].Name
 			if result.FactorName != expectedName {
 				t.Errorf("Result %d: expected factor name %s, got %s", i, expectedName, result.FactorName)
 			}
 		}
+		if !results[0].Success || !results[1].Success {
+			t.Error("Valid factors should succeed in batch")
+		}
+		if results[2].Success || results[2].Error == "" {
+			t.Error("Invalid factor should fail with error in batch")
+		}
 	})
 
 	t.Run("GetBuiltinFactors", func(t *testing.T) {
 		ctx := context.Background()
 
-		factors, err := calculator.GetBuiltinFactors(ctx)
+		output, _ := json.Marshal(map[string]interface{}{
+			"success": true,
+			"factors": map[string][]string{
+				"price":      {"$open", "$close"},
+				"technical":  {"($high + $low + $close) / 3"},
+				"momentum":   {"$close / Ref($close, 5) - 1"},
+				"volatility": {"Std($close, 20) / Mean($close, 20)"},
+				"volume":     {"$volume / Mean($volume, 5)"},
+			},
+		})
+		client := &recordingClient{output: string(output)}
+		factors, err := NewFactorCalculator(newEntryPointClient(client)).GetBuiltinFactors(ctx)
 		if err != nil {
-			t.Errorf("Failed to get builtin factors: %v", err)
-		}
-
-		if factors == nil {
-			t.Error("Builtin factors should not be nil")
+			t.Fatalf("Failed to get builtin factors: %v", err)
 		}
 
 		// 验证包含基本分类
@@ -217,47 +219,46 @@ func TestFactorCalculator(t *testing.T) {
 	})
 }
 
-func TestFactorPerformanceAnalysis(t *testing.T) {
-	testutils.SetupTestEnv()
-	defer testutils.CleanupTestEnv()
+// syntheticFactorData 生成若干交易日的因子值和与之正相关的收益
+func syntheticFactorData(days, instruments int) (factors, returns []FactorValue) {
+	start := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	for d := 0; d < days; d++ {
+		for i := 0; i < instruments; i++ {
+			inst := fmt.Sprintf("%06d.SZ", i)
+			date := start.AddDate(0, 0, d)
+			value := float64(i) + math.Sin(float64(d*instruments+i))
+			factors = append(factors, FactorValue{Instrument: inst, Date: date, Value: value, IsValid: true})
+			returns = append(returns, FactorValue{Instrument: inst, Date: date, Value: 0.01*value + 0.02*math.Cos(float64(d+i*7)), IsValid: true})
+		}
+	}
+	return factors, returns
+}
 
-	mockClient := testutils.NewMockQlibClient()
-	calculator := NewFactorCalculator(mockClient)
+func TestFactorPerformanceAnalysis(t *testing.T) {
+	calculator := newFakeFactorCalculator()
 
 	t.Run("FactorPerformanceCalculation", func(t *testing.T) {
 		ctx := context.Background()
 
 		// 创建模拟的因子数据和收益数据
-		factorData := []FactorValue{
-			{Instrument: "000001.SZ", Date: time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC), Value: 0.02, IsValid: true},
-			{Instrument: "000002.SZ", Date: time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC), Value: -0.01, IsValid: true},
-			{Instrument: "000001.SZ", Date: time.Date(2023, 1, 4, 0, 0, 0, 0, time.UTC), Value: 0.015, IsValid: true},
-			{Instrument: "000002.SZ", Date: time.Date(2023, 1, 4, 0, 0, 0, 0, time.UTC), Value: -0.005, IsValid: true},
-		}
-
-		returnData := []FactorValue{
-			{Instrument: "000001.SZ", Date: time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC), Value: 0.025, IsValid: true},
-			{Instrument: "000002.SZ", Date: time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC), Value: -0.012, IsValid: true},
-			{Instrument: "000001.SZ", Date: time.Date(2023, 1, 4, 0, 0, 0, 0, time.UTC), Value: 0.018, IsValid: true},
-			{Instrument: "000002.SZ", Date: time.Date(2023, 1, 4, 0, 0, 0, 0, time.UTC), Value: -0.008, IsValid: true},
-		}
+		factorData, returnData := syntheticFactorData(5, 10)
 
 		performance, err := calculator.CalculateFactorPerformance(ctx, "test_factor", factorData, returnData)
 		if err != nil {
-			t.Errorf("Factor performance calculation failed: %v", err)
+			t.Fatalf("Factor performance calculation failed: %v", err)
 		}
 
-		if performance == nil {
-			t.Error("Performance result should not be nil")
+		// 验证性能指标
+		if len(performance.IC) != 5 {
+			t.Errorf("Performance should contain daily IC for 5 days, got %d", len(performance.IC))
 		}
 
-		// 验证性能指标
-		if performance.IC == nil {
-			t.Error("Performance should contain IC analysis")
+		if len(performance.RankIC) != 5 {
+			t.Errorf("Performance should contain daily Rank IC for 5 days, got %d", len(performance.RankIC))
 		}
 
-		if performance.RankIC == nil {
-			t.Error("Performance should contain Rank IC analysis")
+		if performance.Statistics["ic_mean"] <= 0 {
+			t.Errorf("Positively related factor should have positive IC mean, got %f", performance.Statistics["ic_mean"])
 		}
 
 		if performance.ICIR == 0 {
@@ -272,11 +273,13 @@ func TestFactorPerformanceAnalysis(t *testing.T) {
 		factor1 := []FactorValue{
 			{Instrument: "000001.SZ", Date: time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC), Value: 0.02, IsValid: true},
 			{Instrument: "000002.SZ", Date: time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC), Value: -0.01, IsValid: true},
+			{Instrument: "000003.SZ", Date: time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC), Value: 0.005, IsValid: true},
 		}
 
 		factor2 := []FactorValue{
 			{Instrument: "000001.SZ", Date: time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC), Value: 0.022, IsValid: true},
 			{Instrument: "000002.SZ", Date: time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC), Value: -0.008, IsValid: true},
+			{Instrument: "000003.SZ", Date: time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC), Value: 0.001, IsValid: true},
 		}
 
 		correlation, err := calculator.GetFactorCorrelation(ctx, factor1, factor2)
@@ -290,22 +293,16 @@ func TestFactorPerformanceAnalysis(t *testing.T) {
 		}
 
 		// 对于正相关的数据，相关性应该为正
-		if correlation < 0 {
+		if correlation <= 0 {
 			t.Errorf("Expected positive correlation for similar data, got %f", correlation)
 		}
 	})
 }
 
 func TestFactorCalculatorEdgeCases(t *testing.T) {
-	testutils.SetupTestEnv()
-	defer testutils.CleanupTestEnv()
-
 	t.Run("UninitializedClient", func(t *testing.T) {
-		// 测试未初始化的客户端
-		uninitializedClient := &testutils.MockQlibClient{
-			Initialized: false,
-		}
-		calculator := NewFactorCalculator(uninitializedClient)
+		// 未设置数据提供者时通过Python计算，客户端未初始化则返回错误
+		calculator := NewFactorCalculator(NewQlibClient())
 
 		ctx := context.Background()
 		expr := FactorExpression{
@@ -318,14 +315,13 @@ func TestFactorCalculatorEdgeCases(t *testing.T) {
 		}
 
 		_, err := calculator.CalculateFactor(ctx, expr)
-		if err == nil {
-			t.Error("Should return error when client is not initialized")
+		if err == nil || !strings.Contains(err.Error(), "未初始化") {
+			t.Errorf("Should return error when client is not initialized, got %v", err)
 		}
 	})
 
 	t.Run("InvalidDateRange", func(t *testing.T) {
-		mockClient := testutils.NewMockQlibClient()
-		calculator := NewFactorCalculator(mockClient)
+		calculator := newFakeFactorCalculator()
 
 		ctx := context.Background()
 
@@ -336,7 +332,7 @@ func TestFactorCalculatorEdgeCases(t *testing.T) {
 				Expression: "$close",
 				Universe:   "csi300",
 				Frequency:  "day",
-				StartDate:  "2023-12-31",  // 结束日期早于开始日期
+				StartDate:  "2023-12-31", // 结束日期早于开始日期
 				EndDate:    "2023-01-01",
 			},
 			{
@@ -351,26 +347,16 @@ func TestFactorCalculatorEdgeCases(t *testing.T) {
 
 		for _, expr := range invalidExpressions {
 			t.Run(fmt.Sprintf("InvalidDate_%s", expr.Name), func(t *testing.T) {
-				// 在实际实现中，这些应该返回错误
-				// 这里我们验证表达式的基本结构
-				if expr.StartDate == "" || expr.EndDate == "" {
-					t.Error("Start date and end date should not be empty")
-				}
-
-				if expr.StartDate == "2023-12-31" && expr.EndDate == "2023-01-01" {
-					t.Error("End date should not be earlier than start date")
-				}
-
-				if expr.StartDate == "invalid-date" {
-					t.Error("Date format should be valid")
+				result, err := calculator.CalculateFactor(ctx, expr)
+				if err == nil && len(result.Data) > 0 {
+					t.Errorf("Invalid date range should not produce factor values, got %d", len(result.Data))
 				}
 			})
 		}
 	})
 
 	t.Run("EmptyFactorData", func(t *testing.T) {
-		mockClient := testutils.NewMockQlibClient()
-		calculator := NewFactorCalculator(mockClient)
+		calculator := newFakeFactorCalculator()
 
 		ctx := context.Background()
 
@@ -390,8 +376,7 @@ func TestFactorCalculatorEdgeCases(t *testing.T) {
 	})
 
 	t.Run("SingleDataPoint", func(t *testing.T) {
-		mockClient := testutils.NewMockQlibClient()
-		calculator := NewFactorCalculator(mockClient)
+		calculator := newFakeFactorCalculator()
 
 		ctx := context.Background()
 
@@ -409,43 +394,21 @@ func TestFactorCalculatorEdgeCases(t *testing.T) {
 			t.Errorf("Single point correlation calculation failed: %v", err)
 		}
 
-		// 单个数据点无法计算相关性，应该返回0或特殊值
+		// 单个数据点无法计算相关性，应该返回0
 		if correlation != 0 {
-			t.Logf("Single point correlation: %f", correlation)
+			t.Errorf("Expected correlation 0 for single point, got %f", correlation)
 		}
 	})
 }
 
 func TestFactorCalculatorPerformance(t *testing.T) {
-	testutils.SetupTestEnv()
-	defer testutils.CleanupTestEnv()
-
-	mockClient := testutils.NewMockQlibClient()
-	calculator := NewFactorCalculator(mockClient)
+	calculator := newFakeFactorCalculator()
 
 	t.Run("LargeDatasetCalculation", func(t *testing.T) {
 		ctx := context.Background()
 
 		// 创建大量因子数据进行性能测试
-		largeFactorData := make([]FactorValue, 10000)
-		for i := range largeFactorData {
-			largeFactorData[i] = FactorValue{
-				Instrument: fmt.Sprintf("%06d.SZ", i%1000),
-				Date:       time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i/1000),
-				Value:      float64(i) * 0.001,
-				IsValid:    true,
-			}
-		}
-
-		largeReturnData := make([]FactorValue, 10000)
-		for i := range largeReturnData {
-			largeReturnData[i] = FactorValue{
-				Instrument: fmt.Sprintf("%06d.SZ", i%1000),
-				Date:       time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i/1000),
-				Value:      float64(i) * 0.0012,
-				IsValid:    true,
-			}
-		}
+		largeFactorData, largeReturnData := syntheticFactorData(10, 1000)
 
 		start := time.Now()
 		_, err := calculator.CalculateFactorPerformance(ctx, "large_factor", largeFactorData, largeReturnData)
@@ -497,4 +460,4 @@ func TestFactorCalculatorPerformance(t *testing.T) {
 			}
 		}
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,213 @@
+package qlib
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"qlib-backend/internal/analytics"
+)
+
+// DefaultRedundancyThreshold 判定两个因子冗余的相关系数绝对值阈值
+const DefaultRedundancyThreshold = 0.7
+
+// FactorCorrelationMatrix 因子间时间平均的截面相关系数矩阵
+type FactorCorrelationMatrix struct {
+	Names  []string    `json:"names"`
+	Matrix [][]float64 `json:"matrix"` // 对角线为1，没有足够共同样本的因子对为0
+}
+
+// CorrelatedPair 相关的因子对
+type CorrelatedPair struct {
+	A           string  `json:"a"`
+	B           string  `json:"b"`
+	Correlation float64 `json:"correlation"`
+}
+
+// FactorCluster 层次聚类得到的因子簇，簇内任意两个子簇的平均相关系数绝对值不低于阈值
+type FactorCluster struct {
+	Members []string `json:"members"`
+}
+
+// FactorCorrelation 计算多个因子的时间平均截面秩相关系数矩阵
+//
+// 每个交易日先对各因子做截面排名，再对两因子共同有效的证券计算排名的Pearson相关，
+// 没有缺失值时即为Spearman相关；共同样本少于 MinSamples 的交易日被跳过。
+// 所有因子按第一个因子的日历和证券对齐。
+func FactorCorrelation(ctx context.Context, names []string, frames []*FactorFrame, opts ICOptions) (*FactorCorrelationMatrix, error) {
+	if len(names) != len(frames) {
+		return nil, fmt.Errorf("因子名称数量与因子数量不一致")
+	}
+	opts = opts.withDefaults()
+	result := &FactorCorrelationMatrix{Names: names, Matrix: make([][]float64, len(frames))}
+	for i := range result.Matrix {
+		result.Matrix[i] = make([]float64, len(frames))
+		result.Matrix[i][i] = 1
+	}
+	if len(frames) == 0 {
+		return result, nil
+	}
+
+	ranks := alignedRanks(frames)
+	err := parallelDates(ctx, len(frames), opts.Workers, func(i int) {
+		for j := i + 1; j < len(frames); j++ {
+			result.Matrix[i][j] = meanRankCorrelation(ranks[i], ranks[j], opts.MinSamples)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range result.Matrix {
+		for j := i + 1; j < len(result.Matrix); j++ {
+			result.Matrix[j][i] = result.Matrix[i][j]
+		}
+	}
+	return result, nil
+}
+
+// CorrelateWith 计算目标因子与各因子的时间平均截面秩相关系数，按相关系数绝对值降序返回前 top 个
+func CorrelateWith(ctx context.Context, target *FactorFrame, names []string, frames []*FactorFrame, top int, opts ICOptions) ([]CorrelatedPair, error) {
+	if len(names) != len(frames) {
+		return nil, fmt.Errorf("因子名称数量与因子数量不一致")
+	}
+	opts = opts.withDefaults()
+	ranks := alignedRanks(append([]*FactorFrame{target}, frames...))
+	pairs := make([]CorrelatedPair, len(frames))
+	err := parallelDates(ctx, len(frames), opts.Workers, func(i int) {
+		pairs[i] = CorrelatedPair{B: names[i], Correlation: meanRankCorrelation(ranks[0], ranks[i+1], opts.MinSamples)}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortPairs(pairs)
+	if top > 0 && len(pairs) > top {
+		pairs = pairs[:top]
+	}
+	return pairs, nil
+}
+
+// RedundantPairs 相关系数绝对值不低于阈值的因子对，按相关系数绝对值降序
+func (m *FactorCorrelationMatrix) RedundantPairs(threshold float64) []CorrelatedPair {
+	var pairs []CorrelatedPair
+	for i := range m.Matrix {
+		for j := i + 1; j < len(m.Matrix); j++ {
+			if math.Abs(m.Matrix[i][j]) >= threshold {
+				pairs = append(pairs, CorrelatedPair{A: m.Names[i], B: m.Names[j], Correlation: m.Matrix[i][j]})
+			}
+		}
+	}
+	sortPairs(pairs)
+	return pairs
+}
+
+// Cluster 以 1-|相关系数| 为距离做平均连接层次聚类，簇间平均相关系数绝对值低于阈值时停止合并
+//
+// 返回的簇按成员数降序，同时返回按聚类树叶子顺序排列的因子下标，便于热力图中相关因子相邻显示。
+func (m *FactorCorrelationMatrix) Cluster(threshold float64) ([]FactorCluster, []int) {
+	type cluster struct {
+		members []int // 按聚类树叶子顺序
+	}
+	n := len(m.Matrix)
+	clusters := make([]*cluster, n)
+	for i := range clusters {
+		clusters[i] = &cluster{members: []int{i}}
+	}
+	similarity := func(a, b *cluster) float64 {
+		total := 0.0
+		for _, i := range a.members {
+			for _, j := range b.members {
+				total += math.Abs(m.Matrix[i][j])
+			}
+		}
+		return total / float64(len(a.members)*len(b.members))
+	}
+
+	for len(clusters) > 1 {
+		bestA, bestB, best := -1, -1, -1.0
+		for a := range clusters {
+			for b := a + 1; b < len(clusters); b++ {
+				if s := similarity(clusters[a], clusters[b]); s > best {
+					bestA, bestB, best = a, b, s
+				}
+			}
+		}
+		if best < threshold {
+			break
+		}
+		clusters[bestA].members = append(clusters[bestA].members, clusters[bestB].members...)
+		clusters = append(clusters[:bestB], clusters[bestB+1:]...)
+	}
+
+	sort.SliceStable(clusters, func(a, b int) bool { return len(clusters[a].members) > len(clusters[b].members) })
+	result := make([]FactorCluster, len(clusters))
+	order := make([]int, 0, n)
+	for k, c := range clusters {
+		for _, i := range c.members {
+			result[k].Members = append(result[k].Members, m.Names[i])
+		}
+		order = append(order, c.members...)
+	}
+	return result, order
+}
+
+// alignedRanks 将各因子按第一个因子的日历和证券对齐，并逐日做截面排名，结果为 [因子][交易日][证券]
+func alignedRanks(frames []*FactorFrame) [][][]float64 {
+	ranks := make([][][]float64, len(frames))
+	for f, frame := range frames {
+		ranks[f] = alignFrame(frames[0], frame)
+		for t, values := range ranks[f] {
+			ranks[f][t] = csRank(values)
+		}
+	}
+	return ranks
+}
+
+// alignFrame 按 base 的日历和证券取 frame 的值，结果为 [交易日][证券]，缺失和无效值为 NaN
+func alignFrame(base, frame *FactorFrame) [][]float64 {
+	index := newFrameIndex(frame)
+	aligned := make([][]float64, len(base.Calendar))
+	for t, date := range base.Calendar {
+		values := make([]float64, len(base.Instruments))
+		for i, inst := range base.Instruments {
+			if v := index.at(frame, inst, date); isValidValue(v) {
+				values[i] = v
+			} else {
+				values[i] = math.NaN()
+			}
+		}
+		aligned[t] = values
+	}
+	return aligned
+}
+
+// meanRankCorrelation 两个因子逐日截面排名相关系数的均值，没有有效交易日时为0
+func meanRankCorrelation(x, y [][]float64, minSamples int) float64 {
+	total, days := 0.0, 0
+	a := make([]float64, 0, len(x))
+	b := make([]float64, 0, len(x))
+	for t := range x {
+		a, b = a[:0], b[:0]
+		for i := range x[t] {
+			if !math.IsNaN(x[t][i]) && !math.IsNaN(y[t][i]) {
+				a = append(a, x[t][i])
+				b = append(b, y[t][i])
+			}
+		}
+		if len(a) < minSamples || analytics.StdDev(a) == 0 || analytics.StdDev(b) == 0 {
+			continue
+		}
+		total += analytics.Correlation(a, b)
+		days++
+	}
+	if days == 0 {
+		return 0
+	}
+	return total / float64(days)
+}
+
+func sortPairs(pairs []CorrelatedPair) {
+	sort.SliceStable(pairs, func(a, b int) bool {
+		return math.Abs(pairs[a].Correlation) > math.Abs(pairs[b].Correlation)
+	})
+}
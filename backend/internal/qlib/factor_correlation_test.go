@@ -0,0 +1,55 @@
+package qlib
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestFactorCorrelation(t *testing.T) {
+	calendar := weekdayCalendar(2)
+	nan := math.NaN()
+	// B 是 A 的单调变换，C 与 A 反向，D 与其他因子无关
+	frames := []*FactorFrame{
+		newTestFactorFrame(calendar, map[string][]float64{"A": {1, 1}, "B": {2, 2}, "C": {3, 3}, "D": {4, 4}}),
+		newTestFactorFrame(calendar, map[string][]float64{"A": {1, 1}, "B": {4, 4}, "C": {9, 9}, "D": {16, nan}}),
+		newTestFactorFrame(calendar, map[string][]float64{"A": {4, 4}, "B": {3, 3}, "C": {2, 2}, "D": {1, 1}}),
+		newTestFactorFrame(calendar, map[string][]float64{"A": {2, 2}, "B": {1, 1}, "C": {1, 1}, "D": {2, 2}}),
+	}
+	names := []string{"a", "a_square", "a_reverse", "noise"}
+
+	matrix, err := FactorCorrelation(context.Background(), names, frames, ICOptions{MinSamples: 3})
+	if err != nil {
+		t.Fatalf("FactorCorrelation failed: %v", err)
+	}
+	if math.Abs(matrix.Matrix[0][1]-1) > 1e-12 || math.Abs(matrix.Matrix[2][0]+1) > 1e-12 || matrix.Matrix[3][3] != 1 {
+		t.Errorf("unexpected matrix: %v", matrix.Matrix)
+	}
+	if math.Abs(matrix.Matrix[0][3]) > 1e-12 {
+		t.Errorf("noise correlation = %v, want 0", matrix.Matrix[0][3])
+	}
+
+	pairs := matrix.RedundantPairs(0.9)
+	if len(pairs) != 3 {
+		t.Errorf("expected 3 redundant pairs, got %+v", pairs)
+	}
+	clusters, order := matrix.Cluster(0.9)
+	if len(clusters) != 2 || len(clusters[0].Members) != 3 || clusters[1].Members[0] != "noise" {
+		t.Errorf("unexpected clusters: %+v", clusters)
+	}
+	if len(order) != 4 || order[3] != 3 {
+		t.Errorf("unexpected leaf order: %v", order)
+	}
+
+	top, err := CorrelateWith(context.Background(), frames[0], names[1:], frames[1:], 2, ICOptions{MinSamples: 3})
+	if err != nil {
+		t.Fatalf("CorrelateWith failed: %v", err)
+	}
+	// 正负相关同样按绝对值排序，无关因子被排除
+	if len(top) != 2 || top[0].B == "noise" || top[1].B == "noise" || math.Abs(top[0].Correlation) < 0.999 {
+		t.Errorf("unexpected top correlated factors: %+v", top)
+	}
+	if _, err := FactorCorrelation(context.Background(), names[:1], frames, ICOptions{}); err == nil {
+		t.Error("mismatched names should fail")
+	}
+}
@@ -0,0 +1,175 @@
+package qlib
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// DefaultDecayHorizon 默认分析的最大持有期和最大滞后期（交易日）
+const DefaultDecayHorizon = 20
+
+// MaxDecayHorizon 最大持有期和最大滞后期的上限，每一期都要计算并复制整个因子帧
+const MaxDecayHorizon = 250
+
+// RebalanceCandidates 建议调仓周期的候选值：日、周、双周、月、季度
+var RebalanceCandidates = []int{1, 5, 10, 20, 60}
+
+// DecayOptions IC衰减分析参数
+type DecayOptions struct {
+	MaxHorizon int `json:"max_horizon"` // 未来收益的最大持有期，默认20
+	MaxLag     int `json:"max_lag"`     // 因子的最大滞后期，默认20
+	ICOptions
+}
+
+func (o DecayOptions) withDefaults() DecayOptions {
+	if o.MaxHorizon <= 0 {
+		o.MaxHorizon = DefaultDecayHorizon
+	}
+	if o.MaxLag <= 0 {
+		o.MaxLag = DefaultDecayHorizon
+	}
+	o.MaxHorizon = minInt(o.MaxHorizon, MaxDecayHorizon)
+	o.MaxLag = minInt(o.MaxLag, MaxDecayHorizon)
+	o.ICOptions = o.ICOptions.withDefaults()
+	return o
+}
+
+// DecayPoint IC衰减曲线上的一点
+type DecayPoint struct {
+	Period int     `json:"period"` // 持有期或滞后期
+	IC     float64 `json:"ic"`
+	RankIC float64 `json:"rank_ic"`
+	Days   int     `json:"days"`
+}
+
+// FactorDecayReport 因子IC衰减分析结果
+type FactorDecayReport struct {
+	Horizons []DecayPoint `json:"horizons"` // 因子与1..N日未来收益的IC
+	Lags     []DecayPoint `json:"lags"`     // 滞后0..N日的因子与次日收益的IC
+	// 按 |RankIC(k)| = a·exp(-λk) 拟合滞后曲线得到的衰减速率、半衰期和拟合优度
+	DecayRate  float64 `json:"decay_rate"`
+	HalfLife   float64 `json:"half_life"` // 交易日，无法估计时为0
+	FitR2      float64 `json:"fit_r2"`
+	Persistent bool    `json:"persistent"` // 滞后窗口内信号没有衰减
+	// 不超过半衰期的最长候选调仓周期
+	RecommendedRebalance int `json:"recommended_rebalance"`
+}
+
+// AnalyzeFactorDecay 计算因子在不同持有期和滞后期的IC，拟合指数衰减估计信号半衰期并给出建议调仓周期
+//
+// returns 的键为持有期，必须包含1日收益，滞后曲线使用1日收益计算。
+func AnalyzeFactorDecay(ctx context.Context, factor *FactorFrame, returns map[int]*FactorFrame, opts DecayOptions) (*FactorDecayReport, error) {
+	opts = opts.withDefaults()
+	next, ok := returns[1]
+	if !ok {
+		return nil, fmt.Errorf("缺少1日未来收益")
+	}
+
+	report := &FactorDecayReport{}
+	for horizon := 1; horizon <= opts.MaxHorizon; horizon++ {
+		frame, ok := returns[horizon]
+		if !ok {
+			continue
+		}
+		point, err := decayPoint(ctx, horizon, factor, frame, opts.ICOptions)
+		if err != nil {
+			return nil, err
+		}
+		report.Horizons = append(report.Horizons, point)
+	}
+	for lag := 0; lag <= opts.MaxLag; lag++ {
+		point, err := decayPoint(ctx, lag, lagFrame(factor, lag), next, opts.ICOptions)
+		if err != nil {
+			return nil, err
+		}
+		report.Lags = append(report.Lags, point)
+	}
+
+	var fitted bool
+	report.DecayRate, report.FitR2, fitted = fitDecay(report.Lags)
+	switch {
+	case !fitted:
+		// 有效的滞后IC不足，无法估计半衰期
+	case report.DecayRate > 0:
+		report.HalfLife = math.Ln2 / report.DecayRate
+		report.RecommendedRebalance = recommendRebalance(report.HalfLife)
+	default:
+		report.Persistent = true
+		report.RecommendedRebalance = recommendRebalance(float64(opts.MaxLag))
+	}
+	return report, nil
+}
+
+func decayPoint(ctx context.Context, period int, factor, returns *FactorFrame, opts ICOptions) (DecayPoint, error) {
+	ic, rankIC, err := dailyIC(ctx, factor, returns, opts)
+	if err != nil {
+		return DecayPoint{}, err
+	}
+	icStats, rankStats := summarizeIC(ic), summarizeIC(rankIC)
+	return DecayPoint{Period: period, IC: icStats.Mean, RankIC: rankStats.Mean, Days: rankStats.Days}, nil
+}
+
+// lagFrame 将因子值向后平移 lag 个交易日，第 t 日取第 t-lag 日的因子值
+func lagFrame(frame *FactorFrame, lag int) *FactorFrame {
+	if lag == 0 {
+		return frame
+	}
+	lagged := &FactorFrame{Calendar: frame.Calendar, Instruments: frame.Instruments, Values: make([][]float64, len(frame.Values))}
+	for i, series := range frame.Values {
+		lagged.Values[i] = nanSeries(len(series))
+		if lag < len(series) {
+			copy(lagged.Values[i][lag:], series[:len(series)-lag])
+		}
+	}
+	return lagged
+}
+
+// fitDecay 对与滞后0期同号的 ln|RankIC| 做最小二乘，返回衰减速率λ和R²
+//
+// 有效点少于2个时无法拟合；信号在窗口内增强时λ为负。
+func fitDecay(lags []DecayPoint) (float64, float64, bool) {
+	if len(lags) == 0 || lags[0].Days == 0 || lags[0].RankIC == 0 {
+		return 0, 0, false
+	}
+	sign := math.Copysign(1, lags[0].RankIC)
+	var x, y []float64
+	for _, p := range lags {
+		if p.Days > 0 && p.RankIC*sign > 0 {
+			x = append(x, float64(p.Period))
+			y = append(y, math.Log(p.RankIC*sign))
+		}
+	}
+	if len(x) < 2 {
+		return 0, 0, false
+	}
+
+	n := float64(len(x))
+	meanX, meanY := sumValues(x)/n, sumValues(y)/n
+	var sxx, sxy, syy float64
+	for i := range x {
+		sxx += (x[i] - meanX) * (x[i] - meanX)
+		sxy += (x[i] - meanX) * (y[i] - meanY)
+		syy += (y[i] - meanY) * (y[i] - meanY)
+	}
+	if sxx == 0 {
+		return 0, 0, false
+	}
+	slope := sxy / sxx
+	r2 := 1.0
+	if syy > 0 {
+		r2 = sxy * sxy / (sxx * syy)
+	}
+	return -slope, r2, true
+}
+
+// recommendRebalance 取不超过半衰期的最长候选调仓周期，至少每日调仓
+func recommendRebalance(halfLife float64) int {
+	recommended := RebalanceCandidates[0]
+	for _, days := range RebalanceCandidates {
+		if float64(days) <= halfLife {
+			recommended = days
+		}
+	}
+	return recommended
+}
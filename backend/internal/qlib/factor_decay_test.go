@@ -0,0 +1,87 @@
+package qlib
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestFitDecay(t *testing.T) {
+	curve := func(ic0, rate float64, n int) []DecayPoint {
+		points := make([]DecayPoint, n)
+		for k := range points {
+			points[k] = DecayPoint{Period: k, RankIC: ic0 * math.Exp(-rate*float64(k)), Days: 100}
+		}
+		return points
+	}
+
+	rate, r2, ok := fitDecay(curve(0.08, 0.05, 11))
+	if !ok || math.Abs(rate-0.05) > 1e-12 || math.Abs(r2-1) > 1e-12 {
+		t.Errorf("fitDecay = %v, %v, %v", rate, r2, ok)
+	}
+	// 负IC因子按绝对值衰减拟合
+	if rate, _, _ := fitDecay(curve(-0.08, 0.2, 11)); math.Abs(rate-0.2) > 1e-12 {
+		t.Errorf("negative IC decay rate = %v, want 0.2", rate)
+	}
+	// 与滞后0期反号的点不参与拟合
+	points := curve(0.08, 0.1, 3)
+	points = append(points, DecayPoint{Period: 3, RankIC: -0.01, Days: 100})
+	if rate, _, _ := fitDecay(points); math.Abs(rate-0.1) > 1e-12 {
+		t.Errorf("decay rate with sign flip = %v, want 0.1", rate)
+	}
+	if _, _, ok := fitDecay(curve(0.08, 0.1, 1)); ok {
+		t.Error("a single point cannot be fitted")
+	}
+
+	for halfLife, want := range map[float64]int{0.5: 1, 3.4: 1, 7: 5, 13.9: 10, 45: 20, 200: 60} {
+		if got := recommendRebalance(halfLife); got != want {
+			t.Errorf("recommendRebalance(%v) = %d, want %d", halfLife, got, want)
+		}
+	}
+}
+
+func TestAnalyzeFactorDecay(t *testing.T) {
+	calendar := weekdayCalendar(6)
+	nan := math.NaN()
+	factor := newTestFactorFrame(calendar, map[string][]float64{
+		"A": {1, 1, 1, 1, 1, 1},
+		"B": {2, 2, 2, 2, 2, 2},
+		"C": {3, 3, 3, 3, 3, 3},
+	})
+	lagged := lagFrame(factor, 2)
+	if !math.IsNaN(lagged.Values[0][1]) || lagged.Values[2][2] != 3 || factor.Values[0][0] != 1 {
+		t.Errorf("unexpected lagged frame: %v", lagged.Values)
+	}
+
+	// 因子排名与收益排名每天一致，滞后后仍一致，信号不衰减
+	returns := newTestFactorFrame(calendar, map[string][]float64{
+		"A": {0.01, 0.01, 0.01, 0.01, 0.01, nan},
+		"B": {0.02, 0.02, 0.02, 0.02, 0.02, nan},
+		"C": {0.04, 0.03, 0.05, 0.03, 0.04, nan},
+	})
+	opts := DecayOptions{MaxHorizon: 3, MaxLag: 3, ICOptions: ICOptions{MinSamples: 3}}
+	report, err := AnalyzeFactorDecay(context.Background(), factor, map[int]*FactorFrame{1: returns, 2: returns}, opts)
+	if err != nil {
+		t.Fatalf("AnalyzeFactorDecay failed: %v", err)
+	}
+	if len(report.Horizons) != 2 || len(report.Lags) != 4 {
+		t.Fatalf("unexpected curve lengths: %d horizons, %d lags", len(report.Horizons), len(report.Lags))
+	}
+	if report.Lags[0].Days != 5 || report.Lags[3].Days != 2 || math.Abs(report.Lags[3].RankIC-1) > 1e-12 {
+		t.Errorf("unexpected lag curve: %+v", report.Lags)
+	}
+	if !report.Persistent || report.HalfLife != 0 || report.RecommendedRebalance != 1 {
+		t.Errorf("flat curve should be persistent: %+v", report)
+	}
+
+	if _, err := AnalyzeFactorDecay(context.Background(), factor, map[int]*FactorFrame{2: returns}, opts); err == nil {
+		t.Error("missing 1-day returns should fail")
+	}
+}
+
+func TestDecayOptionsLimits(t *testing.T) {
+	opts := DecayOptions{MaxHorizon: 1000000, MaxLag: 1000000}.withDefaults()
+	if opts.MaxHorizon != MaxDecayHorizon || opts.MaxLag != MaxDecayHorizon {
+		t.Errorf("Horizon and lag should be capped at %d: %+v", MaxDecayHorizon, opts)
+	}
+}
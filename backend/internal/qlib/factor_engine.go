@@ -1,10 +1,10 @@
 package qlib
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"os/exec"
-	"strings"
+	"math"
+	"time"
 )
 
 // FactorEngine Qlib因子计算引擎
@@ -12,6 +12,12 @@ type FactorEngine struct {
 	pythonPath string
 	qlibPath   string
 	dataPath   string
+
+	// 设置数据提供者后因子值在进程内计算，不再调用Python
+	evaluator    *FactorEvaluator
+	dataProvider MarketDataProvider
+	universes    *UniverseRegistry
+	pythonClient Client
 }
 
 // NewFactorEngine 创建新的因子引擎实例
@@ -19,11 +25,41 @@ func NewFactorEngine(pythonPath, qlibPath, dataPath string) *FactorEngine {
 	if pythonPath == "" {
 		pythonPath = "python3"
 	}
-	return &FactorEngine{
+	engine := &FactorEngine{
 		pythonPath: pythonPath,
 		qlibPath:   qlibPath,
 		dataPath:   dataPath,
+		evaluator:  NewFactorEvaluator(0),
+		universes:  DefaultUniverses,
+	}
+
+	// 数据目录为Qlib二进制格式时默认使用原生计算
+	if dataPath != "" {
+		if reader := NewBinDataReader(dataPath); IsQlibDataDir(reader.DataPath()) {
+			engine.dataProvider = reader
+		}
 	}
+	return engine
+}
+
+// SetDataProvider 设置原生计算使用的行情数据提供者
+func (f *FactorEngine) SetDataProvider(provider MarketDataProvider) {
+	f.dataProvider = provider
+}
+
+// SetUniverseRegistry 设置解析时点股票池使用的注册表
+func (f *FactorEngine) SetUniverseRegistry(registry *UniverseRegistry) {
+	f.universes = registry
+}
+
+// SetPythonClient 设置执行Python脚本的客户端，未设置时使用全局默认客户端
+func (f *FactorEngine) SetPythonClient(client Client) {
+	f.pythonClient = client
+}
+
+// UsesNativeBackend 是否使用原生计算后端
+func (f *FactorEngine) UsesNativeBackend() bool {
+	return f.dataProvider != nil
 }
 
 // FactorTestParams 因子测试参数
@@ -34,6 +70,7 @@ type FactorTestParams struct {
 	Universe   string `json:"universe"`
 	Benchmark  string `json:"benchmark"`
 	Freq       string `json:"freq"`
+	Horizons   []int  `json:"horizons,omitempty"` // IC分析的持有期，默认 DefaultICHorizons，第一个为主持有期
 }
 
 // FactorTestResult 因子测试结果
@@ -61,28 +98,27 @@ type FactorAnalysisResult struct {
 
 // ValidateExpression 验证因子表达式语法
 func (f *FactorEngine) ValidateExpression(expression string) error {
-	scriptArgs := map[string]interface{}{
-		"action":     "validate_expression",
-		"expression": expression,
+	if _, err := f.ParseExpression(expression); err != nil {
+		return fmt.Errorf("因子表达式语法错误: %v", err)
 	}
+	return nil
+}
 
-	result, err := f.executePythonScript(scriptArgs)
+// ParseExpression 解析因子表达式，并按函数目录做参数检查
+func (f *FactorEngine) ParseExpression(expression string) (*ParsedExpression, error) {
+	functions, err := f.GetQlibFunctions()
 	if err != nil {
-		return fmt.Errorf("验证因子表达式失败: %v", err)
+		return nil, err
 	}
-
-	if valid, ok := result["valid"].(bool); !ok || !valid {
-		if errorMsg, ok := result["error"].(string); ok {
-			return fmt.Errorf("因子表达式语法错误: %s", errorMsg)
-		}
-		return fmt.Errorf("因子表达式语法错误")
-	}
-
-	return nil
+	return ParseFactorExpression(expression, functions)
 }
 
 // TestFactor 测试因子性能
 func (f *FactorEngine) TestFactor(params FactorTestParams) (*FactorTestResult, error) {
+	if f.dataProvider != nil {
+		return f.testFactorNative(context.Background(), params)
+	}
+
 	scriptArgs := map[string]interface{}{
 		"action":     "test_factor",
 		"expression": params.Expression,
@@ -136,6 +172,180 @@ func (f *FactorEngine) TestFactor(params FactorTestParams) (*FactorTestResult, e
 	return testResult, nil
 }
 
+// testFactorNative 原生计算因子和各持有期的未来收益，输出IC、RankIC、ICIR、换手率和覆盖率
+func (f *FactorEngine) testFactorNative(ctx context.Context, params FactorTestParams) (*FactorTestResult, error) {
+	req, err := params.frameRequest()
+	if err != nil {
+		return nil, err
+	}
+	horizons := params.Horizons
+	if len(horizons) == 0 {
+		horizons = DefaultICHorizons
+	}
+
+	report, err := f.FactorIC(ctx, params.Expression, req, horizons)
+	if err != nil {
+		return nil, fmt.Errorf("测试因子失败: %v", err)
+	}
+	return factorTestResult(report, horizons[0]), nil
+}
+
+// TestComposite 测试复合因子，params.Expression 不使用，成分因子由 components 给出
+//
+// 结果的 Details 中 weights 为最后一个交易日的成分权重。
+func (f *FactorEngine) TestComposite(ctx context.Context, components []string, params FactorTestParams, opts CompositeOptions) (*FactorTestResult, error) {
+	if f.dataProvider == nil {
+		return nil, fmt.Errorf("复合因子需要原生计算后端")
+	}
+	req, err := params.frameRequest()
+	if err != nil {
+		return nil, err
+	}
+	horizons := params.Horizons
+	if len(horizons) == 0 {
+		horizons = DefaultICHorizons
+	}
+
+	composite, err := f.CompositeFactor(ctx, components, req, opts)
+	if err != nil {
+		return nil, err
+	}
+	returns, err := f.forwardReturns(ctx, req, horizons)
+	if err != nil {
+		return nil, err
+	}
+	report, err := AnalyzeFactorIC(ctx, composite.Frame, returns, ICOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("测试复合因子失败: %v", err)
+	}
+	result := factorTestResult(report, horizons[0])
+	result.Details["weights"] = composite.Weights
+	result.Details["component_ic"] = composite.ComponentIC
+	return result, nil
+}
+
+// forwardReturns 计算各持有期的未来收益
+func (f *FactorEngine) forwardReturns(ctx context.Context, req FrameRequest, horizons []int) (map[int]*FactorFrame, error) {
+	returns := make(map[int]*FactorFrame, len(horizons))
+	for _, horizon := range horizons {
+		if horizon <= 0 {
+			return nil, fmt.Errorf("持有期必须为正数: %d", horizon)
+		}
+		frame, err := f.EvaluateFactor(ctx, ForwardReturnExpression(horizon), req)
+		if err != nil {
+			return nil, fmt.Errorf("计算%d日未来收益失败: %v", horizon, err)
+		}
+		returns[horizon] = frame
+	}
+	return returns, nil
+}
+
+// CompositeFactor 计算各成分因子和1日未来收益，按 opts 合成复合因子
+func (f *FactorEngine) CompositeFactor(ctx context.Context, components []string, req FrameRequest, opts CompositeOptions) (*CompositeResult, error) {
+	frames := make([]*FactorFrame, len(components))
+	for i, expression := range components {
+		frame, err := f.EvaluateFactor(ctx, expression, req)
+		if err != nil {
+			return nil, fmt.Errorf("计算成分因子 %s 失败: %v", expression, err)
+		}
+		frames[i] = frame
+	}
+	var returns *FactorFrame
+	if opts.Weighting != "" && opts.Weighting != CompositeEqual {
+		var err error
+		if returns, err = f.EvaluateFactor(ctx, ForwardReturnExpression(1), req); err != nil {
+			return nil, fmt.Errorf("计算未来收益失败: %v", err)
+		}
+	}
+	return BuildComposite(ctx, frames, returns, opts)
+}
+
+// frameRequest 将测试参数中的股票池、频率和日期转换为行情请求
+func (p FactorTestParams) frameRequest() (FrameRequest, error) {
+	req := FrameRequest{Universe: p.Universe, Freq: p.Freq}
+	var err error
+	if req.Start, err = parseOptionalDate(p.StartDate); err != nil {
+		return req, err
+	}
+	if req.End, err = parseOptionalDate(p.EndDate); err != nil {
+		return req, err
+	}
+	return req, nil
+}
+
+// factorTestResult 用指定持有期的IC统计量生成因子测试结果
+func factorTestResult(report *FactorICReport, horizon int) *FactorTestResult {
+	main, _ := report.Horizon(horizon)
+	return &FactorTestResult{
+		IC:       main.IC.Mean,
+		IR:       main.IC.IR,
+		RankIC:   main.RankIC.Mean,
+		Turnover: report.Turnover,
+		Coverage: report.Coverage,
+		Details: map[string]interface{}{
+			"horizon":                main.Horizon,
+			"ic_t_stat":              main.IC.TStat,
+			"ic_positive_ratio":      main.IC.PositiveRatio,
+			"rank_icir":              main.RankIC.IR,
+			"rank_ic_positive_ratio": main.RankIC.PositiveRatio,
+			"ic_report":              report,
+			"backend":                "native",
+		},
+	}
+}
+
+// FactorIC 计算因子在各持有期的IC分析报告
+func (f *FactorEngine) FactorIC(ctx context.Context, expression string, req FrameRequest, horizons []int) (*FactorICReport, error) {
+	factor, err := f.EvaluateFactor(ctx, expression, req)
+	if err != nil {
+		return nil, err
+	}
+	returns, err := f.forwardReturns(ctx, req, horizons)
+	if err != nil {
+		return nil, err
+	}
+	return AnalyzeFactorIC(ctx, factor, returns, ICOptions{})
+}
+
+// FactorQuantiles 按因子值分组计算各组组合的次日收益，市值加权时同时读取市值字段
+func (f *FactorEngine) FactorQuantiles(ctx context.Context, expression string, req FrameRequest, opts QuantileOptions) (*QuantileReport, error) {
+	factor, err := f.EvaluateFactor(ctx, expression, req)
+	if err != nil {
+		return nil, err
+	}
+	returns, err := f.EvaluateFactor(ctx, ForwardReturnExpression(1), req)
+	if err != nil {
+		return nil, fmt.Errorf("计算未来收益失败: %v", err)
+	}
+	var caps *FactorFrame
+	if opts.Weighting == QuantileCapWeight {
+		field := opts.CapField
+		if field == "" {
+			field = DefaultMarketCapField
+		}
+		if caps, err = f.EvaluateFactor(ctx, field, req); err != nil {
+			return nil, fmt.Errorf("读取市值失败: %v", err)
+		}
+	}
+	return QuantileAnalysis(ctx, factor, returns, caps, opts)
+}
+
+// FactorDecay 因子IC衰减分析：计算1..N日持有期和0..N日滞后的IC，估计信号半衰期
+func (f *FactorEngine) FactorDecay(ctx context.Context, expression string, req FrameRequest, opts DecayOptions) (*FactorDecayReport, error) {
+	opts = opts.withDefaults()
+	factor, err := f.EvaluateFactor(ctx, expression, req)
+	if err != nil {
+		return nil, err
+	}
+	returns := make(map[int]*FactorFrame, opts.MaxHorizon)
+	for horizon := 1; horizon <= opts.MaxHorizon; horizon++ {
+		if returns[horizon], err = f.EvaluateFactor(ctx, ForwardReturnExpression(horizon), req); err != nil {
+			return nil, fmt.Errorf("计算%d日未来收益失败: %v", horizon, err)
+		}
+	}
+	return AnalyzeFactorDecay(ctx, factor, returns, opts)
+}
+
 // AnalyzeFactor 分析因子
 func (f *FactorEngine) AnalyzeFactor(expression string) (*FactorAnalysisResult, error) {
 	scriptArgs := map[string]interface{}{
@@ -209,49 +419,15 @@ func (f *FactorEngine) GetBuiltinFactors() ([]BuiltinFactor, error) {
 
 // GetQlibFunctions 获取Qlib可用函数列表
 func (f *FactorEngine) GetQlibFunctions() ([]QlibFunction, error) {
-	scriptArgs := map[string]interface{}{
-		"action": "get_qlib_functions",
-	}
-
-	result, err := f.executePythonScript(scriptArgs)
-	if err != nil {
-		return nil, fmt.Errorf("获取Qlib函数列表失败: %v", err)
-	}
-
-	var functions []QlibFunction
-	if data, ok := result["data"].([]interface{}); ok {
-		for _, item := range data {
-			if funcMap, ok := item.(map[string]interface{}); ok {
-				function := QlibFunction{}
-				if name, ok := funcMap["name"].(string); ok {
-					function.Name = name
-				}
-				if signature, ok := funcMap["signature"].(string); ok {
-					function.Signature = signature
-				}
-				if description, ok := funcMap["description"].(string); ok {
-					function.Description = description
-				}
-				if category, ok := funcMap["category"].(string); ok {
-					function.Category = category
-				}
-				if examples, ok := funcMap["examples"].([]interface{}); ok {
-					for _, example := range examples {
-						if exampleStr, ok := example.(string); ok {
-							function.Examples = append(function.Examples, exampleStr)
-						}
-					}
-				}
-				functions = append(functions, function)
-			}
-		}
-	}
-
-	return functions, nil
+	return BuiltinQlibFunctions(), nil
 }
 
 // CalculateFactorValue 计算因子值
 func (f *FactorEngine) CalculateFactorValue(expression, startDate, endDate string, instruments []string) (map[string]interface{}, error) {
+	if f.dataProvider != nil {
+		return f.calculateFactorValueNative(expression, startDate, endDate, instruments)
+	}
+
 	scriptArgs := map[string]interface{}{
 		"action":      "calculate_factor_value",
 		"expression":  expression,
@@ -272,6 +448,94 @@ func (f *FactorEngine) CalculateFactorValue(expression, startDate, endDate strin
 	return nil, fmt.Errorf("无效的计算结果")
 }
 
+// EvaluateFactor 使用原生后端计算因子，返回与交易日历对齐的因子值
+//
+// 请求指定股票池时按时点成分计算，当日不在股票池内的证券因子值为 NaN。
+func (f *FactorEngine) EvaluateFactor(ctx context.Context, expression string, req FrameRequest) (*FactorFrame, error) {
+	if f.dataProvider == nil {
+		return nil, fmt.Errorf("未配置原生行情数据提供者")
+	}
+
+	parsed, err := f.ParseExpression(expression)
+	if err != nil {
+		return nil, fmt.Errorf("因子表达式语法错误: %v", err)
+	}
+
+	universe, err := resolveFrameUniverse(ctx, f.universes, f.dataProvider, &req)
+	if err != nil {
+		return nil, fmt.Errorf("解析股票池失败: %v", err)
+	}
+	req.Fields = parsed.Fields
+	req.Lookback, req.Lookahead = ExpressionWindow(parsed.Root)
+	frame, err := f.dataProvider.LoadFrame(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("加载行情数据失败: %v", err)
+	}
+
+	result, err := f.evaluator.Evaluate(ctx, parsed, frame)
+	if err != nil {
+		return nil, fmt.Errorf("计算因子失败: %v", err)
+	}
+	result = result.Trim(req.Start, req.End)
+	if universe != nil {
+		result = universe.Mask(result)
+	}
+	return result, nil
+}
+
+// calculateFactorValueNative 原生计算因子值，输出格式与Python后端一致
+func (f *FactorEngine) calculateFactorValueNative(expression, startDate, endDate string, instruments []string) (map[string]interface{}, error) {
+	req := FrameRequest{Instruments: instruments}
+	var err error
+	if req.Start, err = parseOptionalDate(startDate); err != nil {
+		return nil, err
+	}
+	if req.End, err = parseOptionalDate(endDate); err != nil {
+		return nil, err
+	}
+
+	result, err := f.EvaluateFactor(context.Background(), expression, req)
+	if err != nil {
+		return nil, fmt.Errorf("计算因子值失败: %v", err)
+	}
+
+	dates := make([]string, len(result.Calendar))
+	for i, date := range result.Calendar {
+		dates[i] = date.Format("2006-01-02")
+	}
+	factorValues := make(map[string]interface{}, len(result.Instruments))
+	for i, inst := range result.Instruments {
+		values := make([]interface{}, len(result.Calendar))
+		for t, v := range result.Values[i] {
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				values[t] = v
+			}
+		}
+		factorValues[inst] = values
+	}
+
+	return map[string]interface{}{
+		"factor_values": factorValues,
+		"dates":         dates,
+		"instruments":   result.Instruments,
+	}, nil
+}
+
+// parseOptionalDate 解析 YYYY-MM-DD 格式日期，空字符串返回零值
+func parseOptionalDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if len(value) > 10 {
+		value = value[:10]
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日期格式错误: %s", value)
+	}
+	return date, nil
+}
+
 // GetFactorCorrelation 获取因子相关性
 func (f *FactorEngine) GetFactorCorrelation(expressions []string, startDate, endDate string) (map[string]interface{}, error) {
 	scriptArgs := map[string]interface{}{
@@ -295,13 +559,6 @@ func (f *FactorEngine) GetFactorCorrelation(expressions []string, startDate, end
 
 // executePythonScript 执行Python脚本
 func (f *FactorEngine) executePythonScript(args map[string]interface{}) (map[string]interface{}, error) {
-	// 将参数序列化为JSON
-	argsJSON, err := json.Marshal(args)
-	if err != nil {
-		return nil, fmt.Errorf("序列化参数失败: %v", err)
-	}
-
-	// 构建Python脚本命令
 	pythonScript := `
 import json
 import sys
@@ -327,27 +584,6 @@ except ImportError as e:
     }))
     sys.exit(1)
 
-def validate_expression(expression):
-    """验证因子表达式语法"""
-    try:
-        # 尝试解析表达式
-        from qlib.data.ops import Operators
-        # 这里需要实际的语法验证逻辑
-        # 暂时简单检查是否包含基本操作符
-        if not expression or len(expression.strip()) == 0:
-            return False, "表达式不能为空"
-        
-        # 检查基本语法
-        invalid_chars = ['；', '；']  # 中文分号等
-        for char in invalid_chars:
-            if char in expression:
-                return False, f"包含无效字符: {char}"
-        
-        return True, None
-        
-    except Exception as e:
-        return False, str(e)
-
 def test_factor(expression, start_date, end_date, universe="csi300", benchmark="000300.XSHG", freq="day"):
     """测试因子性能"""
     try:
@@ -536,46 +772,6 @@ def get_builtin_factors_by_category(category):
     all_factors = get_builtin_factors()
     return [factor for factor in all_factors if factor["category"] == category]
 
-def get_qlib_functions():
-    """获取Qlib函数列表"""
-    return [
-        {
-            "name": "Mean",
-            "signature": "Mean(data, window)",
-            "description": "计算移动平均值",
-            "category": "统计函数",
-            "examples": ["Mean($close, 20)", "Mean($volume, 10)"]
-        },
-        {
-            "name": "Std",
-            "signature": "Std(data, window)",
-            "description": "计算标准差",
-            "category": "统计函数",
-            "examples": ["Std($close, 20)", "Std($return, 60)"]
-        },
-        {
-            "name": "Corr",
-            "signature": "Corr(data1, data2, window)",
-            "description": "计算相关系数",
-            "category": "统计函数",
-            "examples": ["Corr($close, $volume, 20)"]
-        },
-        {
-            "name": "Rank",
-            "signature": "Rank(data)",
-            "description": "计算排名",
-            "category": "排序函数",
-            "examples": ["Rank($close)", "Rank($volume)"]
-        },
-        {
-            "name": "Delta",
-            "signature": "Delta(data, period)",
-            "description": "计算差分",
-            "category": "数学函数",
-            "examples": ["Delta($close, 1)", "Delta($close, 5)"]
-        }
-    ]
-
 def main():
     try:
         # 从标准输入读取参数
@@ -585,14 +781,7 @@ def main():
         
         result = {"success": True, "data": None, "error": None}
         
-        if action == "validate_expression":
-            expression = args.get('expression')
-            valid, error = validate_expression(expression)
-            result["valid"] = valid
-            if error:
-                result["error"] = error
-                
-        elif action == "test_factor":
+        if action == "test_factor":
             test_result = test_factor(
                 args.get('expression'),
                 args.get('start_date'),
@@ -617,9 +806,6 @@ def main():
             category = args.get('category', '')
             result["data"] = get_builtin_factors_by_category(category)
             
-        elif action == "get_qlib_functions":
-            result["data"] = get_qlib_functions()
-            
         elif action == "calculate_factor_value":
             # 计算因子值的逻辑
             result["data"] = {
@@ -655,30 +841,7 @@ if __name__ == "__main__":
     main()
 `
 
-	// 执行Python命令
-	cmd := exec.Command(f.pythonPath, "-c", pythonScript)
-	cmd.Stdin = strings.NewReader(string(argsJSON))
-	
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("执行Python脚本失败: %v", err)
-	}
-
-	// 解析输出
-	var result map[string]interface{}
-	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, fmt.Errorf("解析Python输出失败: %v", err)
-	}
-
-	// 检查执行结果
-	if success, ok := result["success"].(bool); !ok || !success {
-		if errorMsg, ok := result["error"].(string); ok {
-			return nil, fmt.Errorf("Python脚本执行失败: %s", errorMsg)
-		}
-		return nil, fmt.Errorf("Python脚本执行失败")
-	}
-
-	return result, nil
+	return runPythonScript(context.Background(), resolvePythonClient(f.pythonClient, f.pythonPath), pythonScript, args)
 }
 
 // 数据结构定义
@@ -690,11 +853,12 @@ type BuiltinFactor struct {
 }
 
 type QlibFunction struct {
-	Name        string   `json:"name"`
-	Signature   string   `json:"signature"`
-	Description string   `json:"description"`
-	Category    string   `json:"category"`
-	Examples    []string `json:"examples"`
+	Name        string          `json:"name"`
+	Signature   string          `json:"signature"`
+	Description string          `json:"description"`
+	Category    string          `json:"category"`
+	Examples    []string        `json:"examples"`
+	Params      []FunctionParam `json:"params,omitempty"`
 }
 
 type FactorCategory struct {
@@ -0,0 +1,524 @@
+package qlib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// FakeBenchmark 假后端的基准证券，为全部合成股票的等权指数
+const FakeBenchmark = "SH000300"
+
+// fakeIndustries 合成股票按序号轮流归入的行业
+var fakeIndustries = []string{"银行", "医药生物", "电子", "食品饮料", "机械设备"}
+
+// FakeBuiltinFactors 假后端提供的内置因子，表达式均可由原生引擎计算
+var FakeBuiltinFactors = []BuiltinFactor{
+	{Name: "ROC5", Expression: "$close / Ref($close, 5) - 1", Description: "5日收益率", Category: "momentum"},
+	{Name: "ROC20", Expression: "$close / Ref($close, 20) - 1", Description: "20日收益率", Category: "momentum"},
+	{Name: "STD20", Expression: "Std($close / Ref($close, 1) - 1, 20)", Description: "20日收益波动率", Category: "volatility"},
+	{Name: "VMA5_20", Expression: "Mean($volume, 5) / Mean($volume, 20)", Description: "5日与20日均量比", Category: "volume"},
+	{Name: "KMID", Expression: "($close - $open) / $open", Description: "当日K线实体", Category: "price"},
+}
+
+// fakeRand 由种子和输入生成确定性的随机数序列，相同输入得到相同结果
+func fakeRand(seed int64, parts ...interface{}) *rand.Rand {
+	h := fnv.New64a()
+	fmt.Fprint(h, seed)
+	for _, part := range parts {
+		fmt.Fprintf(h, "|%v", part)
+	}
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+// FakeSignalExpression 假后端中代替模型预测分数的信号，不同模型使用不同回看期的动量
+func FakeSignalExpression(modelID uint) string {
+	return fmt.Sprintf("$close / Ref($close, %d) - 1", 5+modelID%16)
+}
+
+// FakeMarketConfig 假后端合成行情的配置，零值使用默认值
+type FakeMarketConfig struct {
+	Seed        int64
+	Instruments int       // 股票数量，默认 50
+	Start       time.Time // 默认 2020-01-01
+	End         time.Time // 默认 2023-12-31
+}
+
+func (c FakeMarketConfig) withDefaults() FakeMarketConfig {
+	if c.Instruments <= 0 {
+		c.Instruments = 50
+	}
+	if c.Start.IsZero() {
+		c.Start = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if c.End.IsZero() {
+		c.End = time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	}
+	return c
+}
+
+// NewFakeMarketFrame 生成确定性的合成日线行情
+//
+// 交易日为区间内的工作日。每只股票的价格是带固定漂移的随机游走，提供开高低收、成交量、均价、复权因子和市值字段；
+// 基准 FakeBenchmark 为全部股票的等权指数。
+func NewFakeMarketFrame(cfg FakeMarketConfig) *MarketFrame {
+	cfg = cfg.withDefaults()
+	var calendar []time.Time
+	for day := cfg.Start; !day.After(cfg.End); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			calendar = append(calendar, day)
+		}
+	}
+	instruments := make([]string, cfg.Instruments)
+	for i := range instruments {
+		instruments[i] = fmt.Sprintf("SH6%05d", i)
+	}
+	frame := NewMarketFrame(calendar, append(append([]string{}, instruments...), FakeBenchmark))
+
+	n := len(calendar)
+	benchmarkReturns := make([]float64, n)
+	for _, inst := range instruments {
+		r := fakeRand(cfg.Seed, "instrument", inst)
+		drift := r.NormFloat64() * 0.0005
+		vol := 0.01 + 0.02*r.Float64()
+		shares := 1e8 * (1 + 9*r.Float64())
+		price := 5 + 45*r.Float64()
+
+		open, high, low, closes := make([]float64, n), make([]float64, n), make([]float64, n), make([]float64, n)
+		volume, vwap, factor, marketCap := make([]float64, n), make([]float64, n), make([]float64, n), make([]float64, n)
+		for t := 0; t < n; t++ {
+			ret := drift + vol*r.NormFloat64()
+			open[t] = price * (1 + 0.3*vol*r.NormFloat64())
+			closes[t] = price * (1 + ret)
+			high[t] = math.Max(open[t], closes[t]) * (1 + 0.5*vol*math.Abs(r.NormFloat64()))
+			low[t] = math.Min(open[t], closes[t]) * (1 - 0.5*vol*math.Abs(r.NormFloat64()))
+			volume[t] = math.Round(1e6 * (0.5 + r.Float64()) * (1 + 10*math.Abs(ret)))
+			vwap[t] = (open[t] + high[t] + low[t] + closes[t]) / 4
+			factor[t] = 1
+			marketCap[t] = closes[t] * shares
+			benchmarkReturns[t] += ret / float64(len(instruments))
+			price = closes[t]
+		}
+		for field, values := range map[string][]float64{
+			"$open": open, "$high": high, "$low": low, "$close": closes,
+			"$volume": volume, "$vwap": vwap, "$factor": factor, DefaultMarketCapField: marketCap,
+		} {
+			frame.SetSeries(inst, field, values)
+		}
+	}
+
+	benchmark := make([]float64, n)
+	level := 1000.0
+	for t, ret := range benchmarkReturns {
+		level *= 1 + ret
+		benchmark[t] = level
+	}
+	frame.SetSeries(FakeBenchmark, "$close", benchmark)
+	return frame
+}
+
+// FakeDataProvider 提供合成行情和行业分类的行情提供者
+//
+// 任意股票池都解析为全部合成股票，不包含基准。
+type FakeDataProvider struct {
+	*MemoryDataProvider
+}
+
+// NewFakeDataProvider 创建合成行情提供者
+func NewFakeDataProvider(cfg FakeMarketConfig) *FakeDataProvider {
+	frame := NewFakeMarketFrame(cfg)
+	provider := NewMemoryDataProvider(frame)
+	industries := make(map[string]string, len(frame.Instruments))
+	for i, inst := range frame.Instruments {
+		if inst != FakeBenchmark {
+			industries[inst] = fakeIndustries[i%len(fakeIndustries)]
+		}
+	}
+	provider.SetIndustries(industries)
+	return &FakeDataProvider{MemoryDataProvider: provider}
+}
+
+// Instruments 返回股票池成分，全部合成股票在整个区间内入选
+func (p *FakeDataProvider) Instruments(market string) ([]InstrumentSpan, error) {
+	calendar := p.frame.Calendar
+	spans := make([]InstrumentSpan, 0, len(p.frame.Instruments))
+	for _, inst := range p.frame.Instruments {
+		if inst != FakeBenchmark {
+			spans = append(spans, InstrumentSpan{Symbol: inst, Start: calendar[0], End: calendar[len(calendar)-1]})
+		}
+	}
+	return spans, nil
+}
+
+// FakeModelTrainer 不依赖Python的模型训练器，相同参数得到相同的训练曲线和指标
+type FakeModelTrainer struct {
+	seed       int64
+	Epochs     int           // 训练轮数，默认 10
+	EpochDelay time.Duration // 每轮的等待时间，用于测试进度推送和取消
+}
+
+// NewFakeModelTrainer 创建假模型训练器
+func NewFakeModelTrainer(seed int64) *FakeModelTrainer {
+	return &FakeModelTrainer{seed: seed, Epochs: 10}
+}
+
+// TrainModelContext 按轮次推送进度和指标，上下文取消时返回上下文的错误
+func (t *FakeModelTrainer) TrainModelContext(ctx context.Context, params ModelTrainingParams, callback ProgressCallback) (*ModelTrainingResult, error) {
+	if params.ModelType == "" {
+		return nil, fmt.Errorf("模型训练失败: 模型类型不能为空")
+	}
+	r := fakeRand(t.seed, "train", params.ModelType, params.ConfigJSON, params.TrainStart, params.TrainEnd,
+		params.ValidStart, params.ValidEnd, params.TestStart, params.TestEnd, params.Features, params.Label)
+	result := &ModelTrainingResult{
+		ModelPath: fmt.Sprintf("fake://models/%d", params.ModelID),
+		TrainIC:   0.04 + 0.04*r.Float64(),
+		TrainLoss: 0.90 + 0.05*r.Float64(),
+	}
+	result.ValidIC = result.TrainIC * (0.6 + 0.3*r.Float64())
+	result.TestIC = result.ValidIC * (0.7 + 0.3*r.Float64())
+	result.ValidLoss = result.TrainLoss * (1 + 0.05*r.Float64())
+	result.TestLoss = result.ValidLoss * (1 + 0.05*r.Float64())
+
+	epochs := t.Epochs
+	if epochs <= 0 {
+		epochs = 10
+	}
+	for epoch := 1; epoch <= epochs; epoch++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(t.EpochDelay):
+		}
+		if callback != nil {
+			done := float64(epoch) / float64(epochs)
+			callback(epoch*100/epochs, map[string]float64{
+				"epoch":      float64(epoch),
+				"train_loss": result.TrainLoss * (2 - done),
+				"valid_loss": result.ValidLoss * (2 - done),
+				"valid_ic":   result.ValidIC * done,
+			})
+		}
+	}
+	return result, nil
+}
+
+// EvaluateModel 生成测试区间内每个工作日的IC序列和汇总指标
+func (t *FakeModelTrainer) EvaluateModel(params ModelEvaluationParams) (*ModelEvaluationResult, error) {
+	start, err := parseOptionalDate(params.TestStart)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseOptionalDate(params.TestEnd)
+	if err != nil {
+		return nil, err
+	}
+	r := fakeRand(t.seed, "evaluate", params.ModelID, params.ModelPath, params.TestStart, params.TestEnd)
+	mean := 0.02 + 0.04*r.Float64()
+
+	dailyIC, dailyRankIC := map[string]interface{}{}, map[string]interface{}{}
+	var ics []float64
+	if !start.IsZero() && !end.IsZero() {
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+				continue
+			}
+			ic := mean + 0.1*r.NormFloat64()
+			dailyIC[day.Format("2006-01-02")] = ic
+			dailyRankIC[day.Format("2006-01-02")] = ic + 0.01*r.NormFloat64()
+			ics = append(ics, ic)
+		}
+	}
+	icMean, icir := mean, 0.0
+	if len(ics) > 1 {
+		icMean = sumValues(ics) / float64(len(ics))
+		if std := math.Sqrt(sampleVariance(ics)); std > 0 {
+			icir = icMean / std
+		}
+	}
+
+	importance := make(map[string]float64, len(FakeBuiltinFactors))
+	total := 0.0
+	for _, factor := range FakeBuiltinFactors {
+		importance[factor.Name] = r.Float64()
+		total += importance[factor.Name]
+	}
+	for name := range importance {
+		importance[name] /= total
+	}
+
+	return &ModelEvaluationResult{
+		OverallScore:      50 + 400*icMean,
+		TrainingMetrics:   map[string]interface{}{"ic": icMean * 1.5},
+		ValidationMetrics: map[string]interface{}{"ic": icMean * 1.2},
+		TestMetrics: map[string]interface{}{
+			"ic":            icMean,
+			"icir":          icir,
+			"daily_ic":      dailyIC,
+			"daily_rank_ic": dailyRankIC,
+		},
+		FeatureImportance: importance,
+		PredictionAccuracy: map[string]float64{
+			"direction_accuracy": 0.5 + icMean,
+			"mse":                0.9 + 0.1*r.Float64(),
+		},
+	}, nil
+}
+
+// CompareModels 按模型ID生成确定性指标，按第一个指标从高到低排名
+func (t *FakeModelTrainer) CompareModels(params ModelComparisonParams) (*ModelComparisonResult, error) {
+	metrics := params.Metrics
+	if len(metrics) == 0 {
+		metrics = []string{"ic", "icir", "rank_ic"}
+	}
+	matrix := make(map[string]interface{}, len(params.ModelIDs))
+	scores := make(map[uint]float64, len(params.ModelIDs))
+	for _, id := range params.ModelIDs {
+		values := make(map[string]interface{}, len(metrics))
+		for i, metric := range metrics {
+			value := fakeRand(t.seed, "model", id, metric).Float64() * 0.1
+			values[metric] = value
+			if i == 0 {
+				scores[id] = value
+			}
+		}
+		matrix[strconv.FormatUint(uint64(id), 10)] = values
+	}
+
+	ranking := append([]uint{}, params.ModelIDs...)
+	sort.SliceStable(ranking, func(i, j int) bool { return scores[ranking[i]] > scores[ranking[j]] })
+	result := &ModelComparisonResult{
+		ComparisonMatrix: matrix,
+		RankingResults:   map[string]interface{}{metrics[0]: ranking},
+	}
+	if len(ranking) > 0 {
+		result.BestModel = map[string]interface{}{"model_id": ranking[0], "score": scores[ranking[0]]}
+	}
+	return result, nil
+}
+
+// DeployModel 返回确定性的部署ID和预测地址
+func (t *FakeModelTrainer) DeployModel(params ModelDeploymentParams) (*ModelDeploymentResult, error) {
+	if params.ModelID == 0 {
+		return nil, fmt.Errorf("模型部署失败: 模型ID不能为空")
+	}
+	environment := params.Environment
+	if environment == "" {
+		environment = "default"
+	}
+	return &ModelDeploymentResult{
+		DeploymentID: fmt.Sprintf("fake-%d-%s", params.ModelID, environment),
+		Endpoint:     fmt.Sprintf("http://localhost/fake/%s/models/%d/predict", environment, params.ModelID),
+	}, nil
+}
+
+// FakeBacktestEngine 在合成行情上执行原生回测的回测引擎
+//
+// 策略没有提供 signal 时使用 FakeSignalExpression 代替模型预测分数，因此不会回退到Python回测。
+type FakeBacktestEngine struct {
+	*BacktestEngine
+	frame *MarketFrame
+}
+
+// NewFakeBacktestEngine 创建使用合成行情的回测引擎
+func NewFakeBacktestEngine(data *FakeDataProvider) *FakeBacktestEngine {
+	engine := NewBacktestEngine("", "", "")
+	engine.SetDataProvider(data)
+	return &FakeBacktestEngine{BacktestEngine: engine, frame: data.frame}
+}
+
+// RunBacktestWithReport 执行原生回测，缺少信号时补充假信号，未指定股票池时使用全部合成股票
+func (b *FakeBacktestEngine) RunBacktestWithReport(ctx context.Context, params BacktestParams, callback BacktestProgressCallback) (*BacktestResult, *NativeBacktestReport, error) {
+	if params.Universe == "" {
+		params.Universe = "all"
+	}
+	if params.StrategyType == "" {
+		params.StrategyType = "TopkDropoutStrategy"
+	}
+	if strategyNeedsScores(params.StrategyType) {
+		strategyParams := map[string]interface{}{}
+		if params.ConfigJSON != "" {
+			if err := json.Unmarshal([]byte(params.ConfigJSON), &strategyParams); err != nil {
+				return nil, nil, fmt.Errorf("解析策略配置失败: %v", err)
+			}
+		}
+		if signal, _ := strategyParams["signal"].(string); signal == "" {
+			strategyParams["signal"] = FakeSignalExpression(params.ModelID)
+			data, _ := json.Marshal(strategyParams)
+			params.ConfigJSON = string(data)
+		}
+	}
+	return b.BacktestEngine.RunBacktestWithReport(ctx, params, callback)
+}
+
+// strategyReport 以策略ID为信号参数，对合成行情最后一年执行TopK回测
+func (b *FakeBacktestEngine) strategyReport(strategyID uint) (*NativeBacktestReport, error) {
+	start := b.frame.Calendar[0]
+	if n := len(b.frame.Calendar); n > 250 {
+		start = b.frame.Calendar[n-250]
+	}
+	config, _ := json.Marshal(map[string]interface{}{"topk": 10, "n_drop": 2})
+	_, report, err := b.RunBacktestWithReport(context.Background(), BacktestParams{
+		StrategyID:    strategyID,
+		StrategyType:  "TopkDropoutStrategy",
+		ModelID:       strategyID,
+		ConfigJSON:    string(config),
+		BacktestStart: start.Format("2006-01-02"),
+		Benchmark:     FakeBenchmark,
+	}, nil)
+	return report, err
+}
+
+// GetBacktestResults 返回策略在合成行情上的回测记录
+func (b *FakeBacktestEngine) GetBacktestResults(params BacktestResultsParams) (*BacktestResultsData, error) {
+	report, err := b.strategyReport(params.StrategyID)
+	if err != nil {
+		return nil, fmt.Errorf("获取回测结果失败: %v", err)
+	}
+	returns, benchmark := map[string]interface{}{}, map[string]interface{}{}
+	for _, day := range report.Daily {
+		returns[day.Date.Format("2006-01-02")] = day.Return
+		benchmark[day.Date.Format("2006-01-02")] = day.BenchmarkReturn
+	}
+	summary := report.Summary
+	return &BacktestResultsData{
+		PerformanceData: map[string]interface{}{
+			"total_return":  summary.TotalReturn,
+			"annual_return": summary.AnnualReturn,
+			"excess_return": summary.ExcessReturn,
+			"daily_returns": returns,
+		},
+		RiskMetrics: map[string]interface{}{
+			"sharpe_ratio": summary.SharpeRatio,
+			"max_drawdown": summary.MaxDrawdown,
+			"volatility":   summary.Volatility,
+			"win_rate":     summary.WinRate,
+		},
+		PositionData:  map[string]interface{}{"positions": report.Positions},
+		TradeDetails:  map[string]interface{}{"trades": report.Trades, "trade_count": len(report.Trades)},
+		BenchmarkData: map[string]interface{}{"benchmark": FakeBenchmark, "daily_returns": benchmark},
+	}, nil
+}
+
+// GetAttributionAnalysis 未提供持仓时使用策略在合成行情上的回测持仓做原生归因
+func (b *FakeBacktestEngine) GetAttributionAnalysis(params AttributionAnalysisParams) (*AttributionAnalysisData, error) {
+	if len(params.Positions) == 0 {
+		report, err := b.strategyReport(params.StrategyID)
+		if err != nil {
+			return nil, fmt.Errorf("获取归因分析失败: %v", err)
+		}
+		params.Positions = report.Positions
+	}
+	return b.BacktestEngine.GetAttributionAnalysis(params)
+}
+
+// CompareStrategies 对比各策略在合成行情上的回测指标，按夏普比率排名
+func (b *FakeBacktestEngine) CompareStrategies(params StrategyComparisonParams) (*StrategyComparisonData, error) {
+	matrix := make(map[string]interface{}, len(params.StrategyIDs))
+	sharpe := make(map[uint]float64, len(params.StrategyIDs))
+	for _, id := range params.StrategyIDs {
+		report, err := b.strategyReport(id)
+		if err != nil {
+			return nil, fmt.Errorf("策略对比失败: %v", err)
+		}
+		matrix[strconv.FormatUint(uint64(id), 10)] = report.Summary
+		sharpe[id] = report.Summary.SharpeRatio
+	}
+
+	ranking := append([]uint{}, params.StrategyIDs...)
+	sort.SliceStable(ranking, func(i, j int) bool { return sharpe[ranking[i]] > sharpe[ranking[j]] })
+	result := &StrategyComparisonData{
+		ComparisonMatrix: matrix,
+		RankingResults:   map[string]interface{}{"sharpe_ratio": ranking},
+	}
+	if len(ranking) > 0 {
+		result.BestStrategy = map[string]interface{}{"strategy_id": ranking[0], "sharpe_ratio": sharpe[ranking[0]]}
+	}
+	return result, nil
+}
+
+// ExportReport 返回由导出参数确定的报告ID和下载地址，不生成文件
+func (b *FakeBacktestEngine) ExportReport(params ReportExportParams) (*ReportExportResult, error) {
+	format := params.Format
+	if format == "" {
+		format = "pdf"
+	}
+	reportID := fmt.Sprintf("fake-report-%x", fakeRand(0, params.StrategyIDs, format, params.Sections, params.StartDate, params.EndDate).Uint32())
+	return &ReportExportResult{
+		ReportID:    reportID,
+		DownloadURL: fmt.Sprintf("/api/v1/reports/%s.%s", reportID, format),
+	}, nil
+}
+
+// FakeFactorEngine 在合成行情上原生计算因子的因子引擎，原本调用Python的方法返回确定性结果
+type FakeFactorEngine struct {
+	*FactorEngine
+}
+
+// NewFakeFactorEngine 创建使用合成行情的因子引擎
+func NewFakeFactorEngine(data MarketDataProvider) *FakeFactorEngine {
+	engine := NewFactorEngine("", "", "")
+	engine.SetDataProvider(data)
+	return &FakeFactorEngine{FactorEngine: engine}
+}
+
+// GetBuiltinFactorCategories 按 FakeBuiltinFactors 汇总因子分类
+func (f *FakeFactorEngine) GetBuiltinFactorCategories() ([]FactorCategory, error) {
+	var categories []FactorCategory
+	index := map[string]int{}
+	for _, factor := range FakeBuiltinFactors {
+		i, ok := index[factor.Category]
+		if !ok {
+			i = len(categories)
+			index[factor.Category] = i
+			categories = append(categories, FactorCategory{Name: factor.Category, Description: factor.Category + "类因子"})
+		}
+		categories[i].Count++
+	}
+	return categories, nil
+}
+
+// GetBuiltinFactorsByCategory 返回分类下的内置因子
+func (f *FakeFactorEngine) GetBuiltinFactorsByCategory(category string) ([]BuiltinFactor, error) {
+	var factors []BuiltinFactor
+	for _, factor := range FakeBuiltinFactors {
+		if factor.Category == category {
+			factors = append(factors, factor)
+		}
+	}
+	return factors, nil
+}
+
+// AnalyzeFactor 在全部合成行情上测试因子，返回IC指标和因子值分布
+func (f *FakeFactorEngine) AnalyzeFactor(expression string) (*FactorAnalysisResult, error) {
+	result, err := f.TestFactor(FactorTestParams{Expression: expression})
+	if err != nil {
+		return nil, fmt.Errorf("分析因子失败: %v", err)
+	}
+	values, err := f.EvaluateFactor(context.Background(), expression, FrameRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("分析因子失败: %v", err)
+	}
+	distribution := make(map[string]interface{})
+	for name, value := range values.Statistics() {
+		distribution[name] = value
+	}
+	return &FactorAnalysisResult{
+		BasicMetrics: map[string]interface{}{
+			"ic":       result.IC,
+			"ir":       result.IR,
+			"rank_ic":  result.RankIC,
+			"turnover": result.Turnover,
+			"coverage": result.Coverage,
+		},
+		TimeSeriesData:   map[string]interface{}{},
+		DistributionData: distribution,
+		CorrelationData:  map[string]interface{}{},
+		SectorAnalysis:   map[string]interface{}{},
+	}, nil
+}
@@ -0,0 +1,87 @@
+package qlib
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IndustryFileName 数据目录下的行业分类文件名
+const IndustryFileName = "industry.csv"
+
+// UnknownIndustry 未在行业分类中找到的证券所属分组
+const UnknownIndustry = "未分类"
+
+// IndustryProvider 证券行业分类数据源，行情数据提供者可选实现
+type IndustryProvider interface {
+	Industries() (map[string]string, error)
+}
+
+// LoadIndustryMapping 读取行业分类CSV文件，每行 "证券代码,行业"，首行为 instrument 开头的表头时跳过
+func LoadIndustryMapping(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开行业分类文件失败: %v", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	industries := make(map[string]string)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取行业分类文件失败: %v", err)
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "instrument") {
+			continue
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("行业分类文件第%d行格式错误", line)
+		}
+		instrument := strings.ToUpper(strings.TrimSpace(record[0]))
+		industry := strings.TrimSpace(record[1])
+		if instrument == "" || industry == "" {
+			continue
+		}
+		industries[instrument] = industry
+	}
+	return industries, nil
+}
+
+// Industries 读取数据目录下的 industry.csv，文件不存在时返回空映射
+func (r *BinDataReader) Industries() (map[string]string, error) {
+	path := filepath.Join(r.dataPath, IndustryFileName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	return LoadIndustryMapping(path)
+}
+
+// SetIndustries 设置内存行情提供者的行业分类
+func (p *MemoryDataProvider) SetIndustries(industries map[string]string) {
+	p.industries = industries
+}
+
+// Industries 返回内存行情提供者的行业分类
+func (p *MemoryDataProvider) Industries() (map[string]string, error) {
+	if p.industries == nil {
+		return map[string]string{}, nil
+	}
+	return p.industries, nil
+}
+
+// industryOf 返回证券所属行业，未分类时返回 UnknownIndustry
+func industryOf(industries map[string]string, instrument string) string {
+	if industry, ok := industries[strings.ToUpper(instrument)]; ok {
+		return industry
+	}
+	return UnknownIndustry
+}
@@ -0,0 +1,262 @@
+package qlib
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// MarketFrame 按统一交易日历对齐的多证券列式行情数据，缺失值为 NaN
+type MarketFrame struct {
+	Calendar    []time.Time
+	Instruments []string
+	Fields      map[string][][]float64 // 字段名（如 $close）-> [证券][交易日]
+
+	index map[string]int
+}
+
+// NewMarketFrame 创建空的行情数据帧
+func NewMarketFrame(calendar []time.Time, instruments []string) *MarketFrame {
+	frame := &MarketFrame{
+		Calendar:    calendar,
+		Instruments: instruments,
+		Fields:      make(map[string][][]float64),
+		index:       make(map[string]int, len(instruments)),
+	}
+	for i, inst := range instruments {
+		frame.index[inst] = i
+	}
+	return frame
+}
+
+// InstrumentIndex 返回证券在数据帧中的序号
+func (f *MarketFrame) InstrumentIndex(instrument string) (int, bool) {
+	if f.index == nil {
+		f.index = make(map[string]int, len(f.Instruments))
+		for i, inst := range f.Instruments {
+			f.index[inst] = i
+		}
+	}
+	i, ok := f.index[instrument]
+	return i, ok
+}
+
+// SetSeries 设置某只证券某个字段的序列，长度必须与交易日历一致
+func (f *MarketFrame) SetSeries(instrument, field string, values []float64) error {
+	idx, ok := f.InstrumentIndex(instrument)
+	if !ok {
+		return fmt.Errorf("证券 %s 不在数据帧中", instrument)
+	}
+	if len(values) != len(f.Calendar) {
+		return fmt.Errorf("字段 %s 的长度 %d 与交易日历长度 %d 不一致", field, len(values), len(f.Calendar))
+	}
+	columns, ok := f.Fields[field]
+	if !ok {
+		columns = make([][]float64, len(f.Instruments))
+		for i := range columns {
+			columns[i] = nanSeries(len(f.Calendar))
+		}
+		f.Fields[field] = columns
+	}
+	columns[idx] = values
+	return nil
+}
+
+// Series 返回某只证券某个字段的序列
+func (f *MarketFrame) Series(instrument, field string) ([]float64, bool) {
+	idx, ok := f.InstrumentIndex(instrument)
+	if !ok {
+		return nil, false
+	}
+	columns, ok := f.Fields[field]
+	if !ok {
+		return nil, false
+	}
+	return columns[idx], true
+}
+
+// DateIndex 返回不早于date的第一个交易日序号
+func (f *MarketFrame) DateIndex(date time.Time) int {
+	return sort.Search(len(f.Calendar), func(i int) bool {
+		return !f.Calendar[i].Before(date)
+	})
+}
+
+// Slice 截取[from, to)区间的交易日和指定证券，instruments为空时保留全部证券
+func (f *MarketFrame) Slice(from, to int, instruments []string) *MarketFrame {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(f.Calendar) {
+		to = len(f.Calendar)
+	}
+	if to < from {
+		to = from
+	}
+
+	selected := f.Instruments
+	if len(instruments) > 0 {
+		selected = make([]string, 0, len(instruments))
+		for _, inst := range instruments {
+			if _, ok := f.InstrumentIndex(inst); ok {
+				selected = append(selected, inst)
+			}
+		}
+	}
+
+	sliced := NewMarketFrame(f.Calendar[from:to], selected)
+	for field, columns := range f.Fields {
+		out := make([][]float64, len(selected))
+		for i, inst := range selected {
+			idx, _ := f.InstrumentIndex(inst)
+			out[i] = columns[idx][from:to]
+		}
+		sliced.Fields[field] = out
+	}
+	return sliced
+}
+
+// FrameRequest 行情数据加载请求
+type FrameRequest struct {
+	Universe    string    // 股票池，如 csi300，为空或all表示全部
+	Instruments []string  // 指定证券，非空时优先于Universe
+	Fields      []string  // 需要的字段，如 $close
+	Start       time.Time // 开始日期（含）
+	End         time.Time // 结束日期（含）
+	Lookback    int       // 向前额外加载的交易日数
+	Lookahead   int       // 向后额外加载的交易日数
+	Freq        string
+}
+
+// MarketDataProvider 行情数据提供者，为原生计算引擎加载对齐后的行情
+type MarketDataProvider interface {
+	LoadFrame(ctx context.Context, req FrameRequest) (*MarketFrame, error)
+}
+
+// MemoryDataProvider 基于内存数据帧的行情提供者
+type MemoryDataProvider struct {
+	frame      *MarketFrame
+	industries map[string]string
+}
+
+// NewMemoryDataProvider 创建内存行情提供者
+func NewMemoryDataProvider(frame *MarketFrame) *MemoryDataProvider {
+	return &MemoryDataProvider{frame: frame}
+}
+
+// LoadFrame 按请求截取内存数据帧
+func (p *MemoryDataProvider) LoadFrame(ctx context.Context, req FrameRequest) (*MarketFrame, error) {
+	if p.frame == nil {
+		return nil, fmt.Errorf("内存数据帧为空")
+	}
+	for _, field := range req.Fields {
+		if _, ok := p.frame.Fields[field]; !ok {
+			return nil, fmt.Errorf("数据帧中缺少字段: %s", field)
+		}
+	}
+
+	from := 0
+	if !req.Start.IsZero() {
+		from = p.frame.DateIndex(req.Start) - req.Lookback
+	}
+	to := len(p.frame.Calendar)
+	if !req.End.IsZero() {
+		to = p.frame.DateIndex(req.End.AddDate(0, 0, 1)) + req.Lookahead
+	}
+	return p.frame.Slice(from, to, req.Instruments), nil
+}
+
+// FactorFrame 因子计算结果，与行情数据帧对齐
+type FactorFrame struct {
+	Calendar    []time.Time
+	Instruments []string
+	Values      [][]float64 // [证券][交易日]
+}
+
+// Trim 截取[start, end]日期范围内的结果
+func (f *FactorFrame) Trim(start, end time.Time) *FactorFrame {
+	from := 0
+	if !start.IsZero() {
+		from = sort.Search(len(f.Calendar), func(i int) bool { return !f.Calendar[i].Before(start) })
+	}
+	to := len(f.Calendar)
+	if !end.IsZero() {
+		to = sort.Search(len(f.Calendar), func(i int) bool { return f.Calendar[i].After(end) })
+	}
+	if to < from {
+		to = from
+	}
+
+	values := make([][]float64, len(f.Values))
+	for i, series := range f.Values {
+		values[i] = series[from:to]
+	}
+	return &FactorFrame{Calendar: f.Calendar[from:to], Instruments: f.Instruments, Values: values}
+}
+
+// ToFactorValues 展开为逐条的因子值记录
+func (f *FactorFrame) ToFactorValues() []FactorValue {
+	values := make([]FactorValue, 0, len(f.Instruments)*len(f.Calendar))
+	for i, inst := range f.Instruments {
+		for t, date := range f.Calendar {
+			v := f.Values[i][t]
+			valid := !math.IsNaN(v) && !math.IsInf(v, 0)
+			if !valid {
+				v = 0
+			}
+			values = append(values, FactorValue{Instrument: inst, Date: date, Value: v, IsValid: valid})
+		}
+	}
+	return values
+}
+
+// Statistics 计算有效因子值的描述统计
+func (f *FactorFrame) Statistics() map[string]float64 {
+	valid := make([]float64, 0)
+	total := 0
+	for _, series := range f.Values {
+		for _, v := range series {
+			total++
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				valid = append(valid, v)
+			}
+		}
+	}
+	if len(valid) == 0 {
+		return map[string]float64{}
+	}
+
+	sort.Float64s(valid)
+	n := float64(len(valid))
+	sum := 0.0
+	for _, v := range valid {
+		sum += v
+	}
+	mean := sum / n
+	m2 := 0.0
+	for _, v := range valid {
+		m2 += (v - mean) * (v - mean)
+	}
+
+	return map[string]float64{
+		"count":    n,
+		"mean":     mean,
+		"std":      math.Sqrt(m2 / n),
+		"min":      valid[0],
+		"max":      valid[len(valid)-1],
+		"median":   quantileSorted(valid, 0.5),
+		"skew":     sampleSkew(valid),
+		"kurt":     sampleKurt(valid),
+		"coverage": n / float64(total),
+	}
+}
+
+func nanSeries(n int) []float64 {
+	series := make([]float64, n)
+	for i := range series {
+		series[i] = math.NaN()
+	}
+	return series
+}
@@ -1,10 +1,8 @@
 package qlib
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"os/exec"
-	"strings"
 )
 
 // ModelTrainer Qlib模型训练器
@@ -13,6 +11,7 @@ type ModelTrainer struct {
 	qlibPath     string
 	workspacePath string
 	gpuEnabled   bool
+	pythonClient Client
 }
 
 // NewModelTrainer 创建新的模型训练器实例
@@ -28,6 +27,11 @@ func NewModelTrainer(pythonPath, qlibPath, workspacePath string, gpuEnabled bool
 	}
 }
 
+// SetPythonClient 设置执行Python脚本的客户端，未设置时使用全局默认客户端
+func (t *ModelTrainer) SetPythonClient(client Client) {
+	t.pythonClient = client
+}
+
 // ModelTrainingParams 模型训练参数
 type ModelTrainingParams struct {
 	ModelID    uint     `json:"model_id"`
@@ -106,6 +110,11 @@ type ProgressCallback func(progress int, metrics map[string]float64)
 
 // TrainModel 训练模型
 func (t *ModelTrainer) TrainModel(params ModelTrainingParams, callback ProgressCallback) (*ModelTrainingResult, error) {
+	return t.TrainModelContext(context.Background(), params, callback)
+}
+
+// TrainModelContext 训练模型，训练脚本发送的进度和指标事件转发给 callback，全部事件转发给上下文中的事件处理函数
+func (t *ModelTrainer) TrainModelContext(ctx context.Context, params ModelTrainingParams, callback ProgressCallback) (*ModelTrainingResult, error) {
 	scriptArgs := map[string]interface{}{
 		"action":      "train_model",
 		"model_id":    params.ModelID,
@@ -123,9 +132,12 @@ func (t *ModelTrainer) TrainModel(params ModelTrainingParams, callback ProgressC
 		"gpu_enabled": t.gpuEnabled,
 	}
 
-	result, err := t.executePythonScript(scriptArgs)
+	if callback != nil {
+		ctx = WithPythonEvents(ctx, progressCallbackEvents(callback))
+	}
+	result, err := t.runScript(ctx, scriptArgs)
 	if err != nil {
-		return nil, fmt.Errorf("模型训练失败: %v", err)
+		return nil, fmt.Errorf("模型训练失败: %w", err)
 	}
 
 	// 解析训练结果
@@ -157,21 +169,6 @@ func (t *ModelTrainer) TrainModel(params ModelTrainingParams, callback ProgressC
 	return trainingResult, nil
 }
 
-// StopTraining 停止训练
-func (t *ModelTrainer) StopTraining(modelID uint) error {
-	scriptArgs := map[string]interface{}{
-		"action":   "stop_training",
-		"model_id": modelID,
-	}
-
-	_, err := t.executePythonScript(scriptArgs)
-	if err != nil {
-		return fmt.Errorf("停止训练失败: %v", err)
-	}
-
-	return nil
-}
-
 // EvaluateModel 评估模型
 func (t *ModelTrainer) EvaluateModel(params ModelEvaluationParams) (*ModelEvaluationResult, error) {
 	scriptArgs := map[string]interface{}{
@@ -332,12 +329,12 @@ func (t *ModelTrainer) GetSupportedModels() ([]ModelTypeInfo, error) {
 
 // executePythonScript 执行Python脚本
 func (t *ModelTrainer) executePythonScript(args map[string]interface{}) (map[string]interface{}, error) {
-	argsJSON, err := json.Marshal(args)
-	if err != nil {
-		return nil, fmt.Errorf("序列化参数失败: %v", err)
-	}
+	return t.runScript(context.Background(), args)
+}
 
-	pythonScript := `
+// runScript 执行Python脚本，上下文中的事件处理函数接收脚本发送的事件
+func (t *ModelTrainer) runScript(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+	pythonScript := pythonEventPrelude + `
 import json
 import sys
 import os
@@ -374,10 +371,13 @@ def train_model(params):
         workspace = params.get('workspace', '/tmp/qlib_workspace')
         
         # 初始化qlib
+        emit_event("progress", progress=5, message="初始化Qlib环境")
         init(provider_uri="file:///path/to/qlib_data", region="cn")
         
         # 解析配置
         config = json.loads(config_json)
+        emit_event("log", level="INFO", message=f"开始训练 {model_type} 模型 ID: {model_id}")
+        emit_event("progress", progress=20, message="配置解析完成")
         
         # 根据模型类型选择训练器
         if model_type == "LightGBM":
@@ -441,9 +441,10 @@ def train_model(params):
         
         with open(model_path, 'w') as f:
             json.dump(model_data, f)
+        emit_event("artifact", name="model", path=model_path)
         
         # 返回训练结果
-        return {
+        training_result = {
             "model_path": model_path,
             "train_ic": 0.085,
             "valid_ic": 0.072,
@@ -452,20 +453,13 @@ def train_model(params):
             "valid_loss": 0.267,
             "test_loss": 0.273
         }
+        emit_event("metric", progress=100, metrics={k: v for k, v in training_result.items() if k != "model_path"})
+        emit_event("log", level="INFO", message="模型训练完成")
+        return training_result
         
     except Exception as e:
         raise Exception(f"Training failed: {str(e)}")
 
-def stop_training(params):
-    """停止训练"""
-    try:
-        model_id = params.get('model_id')
-        # 这里应该实现停止训练的逻辑
-        # 比如设置停止标志、终止进程等
-        return {"stopped": True, "model_id": model_id}
-    except Exception as e:
-        raise Exception(f"Stop training failed: {str(e)}")
-
 def evaluate_model(params):
     """评估模型"""
     try:
@@ -653,10 +647,6 @@ def main():
             training_result = train_model(args)
             result["data"] = training_result
             
-        elif action == "stop_training":
-            stop_result = stop_training(args)
-            result["data"] = stop_result
-            
         elif action == "evaluate_model":
             evaluation_result = evaluate_model(args)
             result["data"] = evaluation_result
@@ -680,6 +670,7 @@ def main():
         print(json.dumps(result))
         
     except Exception as e:
+        emit_event("error", message=str(e))
         error_result = {
             "success": False,
             "error": str(e),
@@ -691,27 +682,7 @@ if __name__ == "__main__":
     main()
 `
 
-	cmd := exec.Command(t.pythonPath, "-c", pythonScript)
-	cmd.Stdin = strings.NewReader(string(argsJSON))
-
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("执行Python脚本失败: %v", err)
-	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, fmt.Errorf("解析Python输出失败: %v", err)
-	}
-
-	if success, ok := result["success"].(bool); !ok || !success {
-		if errorMsg, ok := result["error"].(string); ok {
-			return nil, fmt.Errorf("Python脚本执行失败: %s", errorMsg)
-		}
-		return nil, fmt.Errorf("Python脚本执行失败")
-	}
-
-	return result, nil
+	return runPythonScript(ctx, resolvePythonClient(t.pythonClient, t.pythonPath), pythonScript, args)
 }
 
 // 数据结构定义
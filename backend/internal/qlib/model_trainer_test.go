@@ -1,6 +1,7 @@
 package qlib
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,10 +11,21 @@ import (
 type ModelTrainerTestSuite struct {
 	suite.Suite
 	trainer *ModelTrainer
+	python  *recordingClient
 }
 
-func (suite *ModelTrainerTestSuite) SetupSuite() {
+func (suite *ModelTrainerTestSuite) SetupTest() {
+	suite.python = &recordingClient{}
 	suite.trainer = NewModelTrainer("/usr/bin/python3", "/opt/qlib", "/tmp/qlib_workspace", false)
+	suite.trainer.SetPythonClient(suite.python)
+}
+
+// scriptArgs 解析最后一次传给训练脚本的参数
+func (suite *ModelTrainerTestSuite) scriptArgs() map[string]interface{} {
+	suite.Require().NotEmpty(suite.python.inputs)
+	var args map[string]interface{}
+	suite.Require().NoError(json.Unmarshal(suite.python.inputs[len(suite.python.inputs)-1], &args))
+	return args
 }
 
 func (suite *ModelTrainerTestSuite) TestNewModelTrainer() {
@@ -47,251 +59,147 @@ func (suite *ModelTrainerTestSuite) TestTrainModel() {
 		Label:      "label",
 	}
 
-	// 注意：这是一个集成测试，需要实际的Python环境和Qlib
-	// 在实际CI/CD环境中，可能需要模拟或跳过这个测试
-	result, err := suite.trainer.TrainModel(params)
-
-	// 由于我们没有实际的Qlib环境，这里只测试参数验证
-	if err != nil {
-		// 预期错误，因为没有实际的Python环境
-		assert.Contains(suite.T(), err.Error(), "python")
-	} else {
-		// 如果成功，验证结果结构
-		assert.NotNil(suite.T(), result)
-		assert.NotEmpty(suite.T(), result.ModelPath)
-	}
-}
+	suite.python.output = `{"success": true, "data": {
+		"model_path": "/tmp/qlib_workspace/models/model_123.pkl",
+		"train_ic": 0.08, "valid_ic": 0.05, "test_ic": 0.04,
+		"train_loss": 0.21, "valid_loss": 0.23, "test_loss": 0.24
+	}}`
 
-func (suite *ModelTrainerTestSuite) TestValidateTrainingParams() {
-	// 测试有效参数
-	validParams := ModelTrainingParams{
-		ModelID:    123,
-		ModelType:  "lightgbm",
-		ConfigJSON: `{"objective": "regression"}`,
-		TrainStart: "2020-01-01",
-		TrainEnd:   "2022-12-31",
-		ValidStart: "2023-01-01",
-		ValidEnd:   "2023-06-30",
-		TestStart:  "2023-07-01",
-		TestEnd:    "2023-12-31",
-		Features:   []string{"close", "volume"},
-		Label:      "label",
-	}
+	result, err := suite.trainer.TrainModel(params, nil)
 
-	err := suite.trainer.ValidateTrainingParams(validParams)
 	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "/tmp/qlib_workspace/models/model_123.pkl", result.ModelPath)
+	assert.Equal(suite.T(), 0.08, result.TrainIC)
+	assert.Equal(suite.T(), 0.05, result.ValidIC)
+	assert.Equal(suite.T(), 0.04, result.TestIC)
+	assert.Equal(suite.T(), 0.24, result.TestLoss)
+
+	// 训练参数和训练器配置通过标准输入传给脚本
+	args := suite.scriptArgs()
+	assert.Equal(suite.T(), "train_model", args["action"])
+	assert.Equal(suite.T(), "lightgbm", args["model_type"])
+	assert.Equal(suite.T(), params.ConfigJSON, args["config_json"])
+	assert.Equal(suite.T(), "2020-01-01", args["train_start"])
+	assert.Equal(suite.T(), "2023-12-31", args["test_end"])
+	assert.Equal(suite.T(), []interface{}{"close", "volume", "high", "low"}, args["features"])
+	assert.Equal(suite.T(), "/tmp/qlib_workspace", args["workspace"])
+	assert.Equal(suite.T(), false, args["gpu_enabled"])
+}
 
-	// 测试无效参数 - 空模型类型
-	invalidParams1 := validParams
-	invalidParams1.ModelType = ""
-	err = suite.trainer.ValidateTrainingParams(invalidParams1)
-	assert.Error(suite.T(), err)
-	assert.Contains(suite.T(), err.Error(), "模型类型不能为空")
+func (suite *ModelTrainerTestSuite) TestTrainModelFailure() {
+	// 没有Python环境或Qlib时脚本返回失败，错误信息带出脚本的错误
+	suite.python.output = `{"success": false, "error": "Failed to import required packages: No module named 'qlib'", "data": null}`
 
-	// 测试无效参数 - 无效JSON配置
-	invalidParams2 := validParams
-	invalidParams2.ConfigJSON = "invalid json"
-	err = suite.trainer.ValidateTrainingParams(invalidParams2)
-	assert.Error(suite.T(), err)
-	assert.Contains(suite.T(), err.Error(), "无效的JSON配置")
+	result, err := suite.trainer.TrainModel(ModelTrainingParams{ModelID: 1, ModelType: "lightgbm"}, nil)
 
-	// 测试无效参数 - 空特征列表
-	invalidParams3 := validParams
-	invalidParams3.Features = []string{}
-	err = suite.trainer.ValidateTrainingParams(invalidParams3)
 	assert.Error(suite.T(), err)
-	assert.Contains(suite.T(), err.Error(), "特征列表不能为空")
+	assert.Nil(suite.T(), result)
+	assert.Contains(suite.T(), err.Error(), "模型训练失败")
+	assert.Contains(suite.T(), err.Error(), "No module named 'qlib'")
 
-	// 测试无效参数 - 时间范围错误
-	invalidParams4 := validParams
-	invalidParams4.TrainStart = "2023-01-01"
-	invalidParams4.TrainEnd = "2022-12-31" // 结束时间早于开始时间
-	err = suite.trainer.ValidateTrainingParams(invalidParams4)
+	// 输出不是JSON
+	suite.python.output = "Traceback (most recent call last):"
+	_, err = suite.trainer.TrainModel(ModelTrainingParams{ModelID: 1, ModelType: "lightgbm"}, nil)
 	assert.Error(suite.T(), err)
-	assert.Contains(suite.T(), err.Error(), "训练结束时间不能早于开始时间")
+	assert.Contains(suite.T(), err.Error(), "解析Python输出失败")
 }
 
 func (suite *ModelTrainerTestSuite) TestEvaluateModel() {
 	evalParams := ModelEvaluationParams{
-		ModelPath:  "/tmp/models/test_model.pkl",
-		TestStart:  "2023-01-01",
-		TestEnd:    "2023-12-31",
-		Features:   []string{"close", "volume", "high", "low"},
-		Label:      "label",
-		Benchmark:  "HS300",
-	}
-
-	// 模拟评估（实际需要真实模型文件）
-	result, err := suite.trainer.EvaluateModel(evalParams)
-
-	if err != nil {
-		// 预期错误，因为没有实际的模型文件
-		assert.Contains(suite.T(), err.Error(), "模型文件不存在")
-	} else {
-		// 如果成功，验证结果结构
-		assert.NotNil(suite.T(), result)
-		assert.GreaterOrEqual(suite.T(), result.TestIC, -1.0)
-		assert.LessOrEqual(suite.T(), result.TestIC, 1.0)
+		ModelID:   123,
+		ModelPath: "/tmp/models/test_model.pkl",
+		TestStart: "2023-01-01",
+		TestEnd:   "2023-12-31",
 	}
-}
-
-func (suite *ModelTrainerTestSuite) TestGetModelInfo() {
-	modelPath := "/tmp/models/test_model.pkl"
 
-	// 模拟获取模型信息（实际需要真实模型文件）
-	info, err := suite.trainer.GetModelInfo(modelPath)
+	suite.python.output = `{"success": true, "data": {
+		"overall_score": 0.72,
+		"test_metrics": {"ic": 0.045, "rank_ic": 0.052},
+		"feature_importance": {"close": 0.6, "volume": 0.4, "note": "ignored"},
+		"prediction_accuracy": {"direction": 0.56}
+	}}`
 
-	if err != nil {
-		// 预期错误，因为没有实际的模型文件
-		assert.Contains(suite.T(), err.Error(), "模型文件不存在")
-	} else {
-		// 如果成功，验证信息结构
-		assert.NotNil(suite.T(), info)
-		assert.NotEmpty(suite.T(), info.ModelType)
-		assert.NotNil(suite.T(), info.TrainingParams)
-	}
-}
-
-func (suite *ModelTrainerTestSuite) TestSaveModel() {
-	saveParams := ModelSaveParams{
-		ModelPath:   "/tmp/models/test_model.pkl",
-		ModelName:   "测试模型",
-		Description: "LightGBM测试模型",
-		Version:     "1.0",
-		Metadata: map[string]interface{}{
-			"features_count": 10,
-			"training_samples": 100000,
-		},
-	}
+	result, err := suite.trainer.EvaluateModel(evalParams)
 
-	// 模拟保存模型
-	result, err := suite.trainer.SaveModel(saveParams)
-
-	if err != nil {
-		// 预期错误，因为没有实际的模型文件
-		assert.Contains(suite.T(), err.Error(), "模型文件不存在")
-	} else {
-		// 如果成功，验证结果
-		assert.NotNil(suite.T(), result)
-		assert.True(suite.T(), result.Success)
-		assert.NotEmpty(suite.T(), result.SavedPath)
-	}
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0.72, result.OverallScore)
+	assert.Equal(suite.T(), 0.045, result.TestMetrics["ic"])
+	assert.Equal(suite.T(), map[string]float64{"close": 0.6, "volume": 0.4}, result.FeatureImportance)
+	assert.Equal(suite.T(), 0.56, result.PredictionAccuracy["direction"])
+
+	args := suite.scriptArgs()
+	assert.Equal(suite.T(), "evaluate_model", args["action"])
+	assert.Equal(suite.T(), evalParams.ModelPath, args["model_path"])
+
+	// 模型文件不存在时脚本返回失败
+	suite.python.output = `{"success": false, "error": "模型文件不存在"}`
+	_, err = suite.trainer.EvaluateModel(evalParams)
+	assert.Error(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "模型文件不存在")
 }
 
-func (suite *ModelTrainerTestSuite) TestLoadModel() {
-	modelPath := "/tmp/models/test_model.pkl"
+func (suite *ModelTrainerTestSuite) TestCompareModels() {
+	suite.python.output = `{"success": true, "data": {
+		"comparison_matrix": {"ic": [0.04, 0.05]},
+		"ranking_results": {"ic": [2, 1]},
+		"best_model": {"model_id": 2}
+	}}`
 
-	// 模拟加载模型
-	model, err := suite.trainer.LoadModel(modelPath)
+	result, err := suite.trainer.CompareModels(ModelComparisonParams{ModelIDs: []uint{1, 2}, Metrics: []string{"ic"}})
 
-	if err != nil {
-		// 预期错误，因为没有实际的模型文件
-		assert.Contains(suite.T(), err.Error(), "模型文件不存在")
-	} else {
-		// 如果成功，验证模型对象
-		assert.NotNil(suite.T(), model)
-		assert.NotEmpty(suite.T(), model.ModelPath)
-		assert.NotEmpty(suite.T(), model.ModelType)
-	}
-}
-
-func (suite *ModelTrainerTestSuite) TestPredictWithModel() {
-	predParams := ModelPredictionParams{
-		ModelPath: "/tmp/models/test_model.pkl",
-		DataStart: "2024-01-01",
-		DataEnd:   "2024-01-31",
-		Features:  []string{"close", "volume", "high", "low"},
-		OutputPath: "/tmp/predictions.csv",
-	}
-
-	// 模拟预测
-	result, err := suite.trainer.PredictWithModel(predParams)
-
-	if err != nil {
-		// 预期错误，因为没有实际的模型文件
-		assert.Contains(suite.T(), err.Error(), "模型文件不存在")
-	} else {
-		// 如果成功，验证预测结果
-		assert.NotNil(suite.T(), result)
-		assert.True(suite.T(), result.Success)
-		assert.Greater(suite.T(), result.PredictionCount, 0)
-		assert.NotEmpty(suite.T(), result.OutputPath)
-	}
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), result.ComparisonMatrix)
+	assert.NotNil(suite.T(), result.RankingResults)
+	assert.Equal(suite.T(), float64(2), result.BestModel["model_id"])
+	assert.Equal(suite.T(), []interface{}{float64(1), float64(2)}, suite.scriptArgs()["model_ids"])
 }
 
-func (suite *ModelTrainerTestSuite) TestDeleteModel() {
-	modelPath := "/tmp/models/test_model.pkl"
+func (suite *ModelTrainerTestSuite) TestDeployModel() {
+	suite.python.output = `{"success": true, "data": {"deployment_id": "deploy_123", "endpoint": "http://localhost:8501/models/123"}}`
 
-	// 模拟删除模型
-	err := suite.trainer.DeleteModel(modelPath)
+	result, err := suite.trainer.DeployModel(ModelDeploymentParams{
+		ModelID:      123,
+		ModelPath:    "/tmp/models/test_model.pkl",
+		Environment:  "staging",
+		ReplicaCount: 2,
+	})
 
-	if err != nil {
-		// 可能的错误情况
-		assert.Contains(suite.T(), err.Error(), "模型文件不存在")
-	}
-	// 如果没有错误，说明删除成功（或文件本来就不存在）
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "deploy_123", result.DeploymentID)
+	assert.Equal(suite.T(), "http://localhost:8501/models/123", result.Endpoint)
+	assert.Equal(suite.T(), "staging", suite.scriptArgs()["environment"])
 }
 
-func (suite *ModelTrainerTestSuite) TestGenerateModelScript() {
-	params := ModelTrainingParams{
-		ModelID:    123,
-		ModelType:  "lightgbm",
-		ConfigJSON: `{"objective": "regression", "num_leaves": 31}`,
-		TrainStart: "2020-01-01",
-		TrainEnd:   "2022-12-31",
-		ValidStart: "2023-01-01",
-		ValidEnd:   "2023-06-30",
-		TestStart:  "2023-07-01",
-		TestEnd:    "2023-12-31",
-		Features:   []string{"close", "volume", "high", "low"},
-		Label:      "label",
-	}
+func (suite *ModelTrainerTestSuite) TestSupportedModelTypes() {
+	suite.python.output = `{"success": true, "data": [
+		{"name": "lightgbm", "display_name": "LightGBM", "category": "tree", "requirements": ["lightgbm"],
+		 "default_params": {"objective": "regression", "num_leaves": 31, "learning_rate": 0.05}},
+		{"name": "xgboost", "display_name": "XGBoost", "category": "tree", "requirements": ["xgboost"]},
+		{"name": "linear", "display_name": "Linear", "category": "linear"}
+	]}`
 
-	script, err := suite.trainer.GenerateModelScript(params)
+	supportedTypes, err := suite.trainer.GetSupportedModels()
 
 	assert.NoError(suite.T(), err)
-	assert.NotEmpty(suite.T(), script)
-	
-	// 验证脚本包含必要的内容
-	assert.Contains(suite.T(), script, "import qlib")
-	assert.Contains(suite.T(), script, "lightgbm")
-	assert.Contains(suite.T(), script, "2020-01-01")
-	assert.Contains(suite.T(), script, "2022-12-31")
-	assert.Contains(suite.T(), script, "close")
-	assert.Contains(suite.T(), script, "volume")
-}
+	assert.Len(suite.T(), supportedTypes, 3)
 
-func (suite *ModelTrainerTestSuite) TestSupportedModelTypes() {
-	supportedTypes := suite.trainer.GetSupportedModelTypes()
-
-	assert.NotNil(suite.T(), supportedTypes)
-	assert.Greater(suite.T(), len(supportedTypes), 0)
-	
 	// 验证包含常见的模型类型
-	assert.Contains(suite.T(), supportedTypes, "lightgbm")
-	assert.Contains(suite.T(), supportedTypes, "xgboost")
-	assert.Contains(suite.T(), supportedTypes, "linear")
-}
-
-func (suite *ModelTrainerTestSuite) TestModelConfiguration() {
-	modelType := "lightgbm"
-	
-	defaultConfig, err := suite.trainer.GetDefaultModelConfig(modelType)
+	names := make([]string, 0, len(supportedTypes))
+	for _, modelType := range supportedTypes {
+		names = append(names, modelType.Name)
+	}
+	assert.Contains(suite.T(), names, "lightgbm")
+	assert.Contains(suite.T(), names, "xgboost")
+	assert.Contains(suite.T(), names, "linear")
 
-	assert.NoError(suite.T(), err)
-	assert.NotNil(suite.T(), defaultConfig)
-	
 	// 验证默认配置包含必要的参数
+	defaultConfig := supportedTypes[0].DefaultParams
 	assert.Contains(suite.T(), defaultConfig, "objective")
 	assert.Contains(suite.T(), defaultConfig, "num_leaves")
 	assert.Contains(suite.T(), defaultConfig, "learning_rate")
-
-	// 测试不支持的模型类型
-	_, err = suite.trainer.GetDefaultModelConfig("unsupported_model")
-	assert.Error(suite.T(), err)
-	assert.Contains(suite.T(), err.Error(), "不支持的模型类型")
+	assert.Equal(suite.T(), []string{"lightgbm"}, supportedTypes[0].Requirements)
 }
 
 func TestModelTrainerTestSuite(t *testing.T) {
 	suite.Run(t, new(ModelTrainerTestSuite))
-}
\ No newline at end of file
+}
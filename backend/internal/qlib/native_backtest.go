@@ -0,0 +1,284 @@
+package qlib
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// NativeBacktestConfig 原生回测配置
+type NativeBacktestConfig struct {
+	Start     time.Time      `json:"start"`
+	End       time.Time      `json:"end"`
+	Account   float64        `json:"account"`   // 初始资金
+	Benchmark string         `json:"benchmark"` // 基准证券，如 SH000300
+	Exchange  ExchangeConfig `json:"exchange"`
+}
+
+// DailyRecord 每日账户记录
+type DailyRecord struct {
+	Date            time.Time `json:"date"`
+	Value           float64   `json:"value"` // 账户总值
+	Cash            float64   `json:"cash"`
+	StockValue      float64   `json:"stock_value"`
+	Return          float64   `json:"return"`
+	BenchmarkReturn float64   `json:"benchmark_return"`
+	BenchmarkValue  float64   `json:"benchmark_value"` // 基准收盘点位，无基准时为0
+	Turnover        float64   `json:"turnover"`        // 当日成交额 / 账户总值
+	Cost            float64   `json:"cost"`
+}
+
+// NativeBacktestReport 原生回测结果
+type NativeBacktestReport struct {
+	Strategy  string           `json:"strategy"`
+	Daily     []DailyRecord    `json:"daily"`
+	Positions []PositionRecord `json:"positions"` // 每日收盘持仓
+	Trades    []TradeRecord    `json:"trades"`
+	Rejected  []OrderRejection `json:"rejected"` // 被拒绝或部分成交的订单
+	Summary   BacktestResult   `json:"summary"`
+}
+
+// NativeBacktester 原生事件驱动回测器，逐日执行策略并按收盘价计算账户价值
+type NativeBacktester struct {
+	provider MarketDataProvider
+}
+
+// NewNativeBacktester 创建原生回测器
+func NewNativeBacktester(provider MarketDataProvider) *NativeBacktester {
+	return &NativeBacktester{provider: provider}
+}
+
+// Run 执行回测，scores 为预测分数（可为空，如固定权重策略），第t日使用t-1日及之前最近一期的分数交易
+func (b *NativeBacktester) Run(ctx context.Context, config NativeBacktestConfig, scores *FactorFrame, strategy SignalStrategy, callback BacktestProgressCallback) (*NativeBacktestReport, error) {
+	if b.provider == nil {
+		return nil, fmt.Errorf("未设置行情数据提供者")
+	}
+	if config.Account <= 0 {
+		config.Account = 100000000
+	}
+
+	// 多加载一个交易日用于计算首日涨跌幅
+	req := FrameRequest{
+		Instruments: backtestInstruments(config, scores, strategy),
+		Fields:      exchangeFields(config.Exchange),
+		Start:       config.Start,
+		End:         config.End,
+		Lookback:    1,
+		Freq:        config.Exchange.Freq,
+	}
+	frame, err := b.provider.LoadFrame(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("加载回测行情失败: %v", err)
+	}
+	loadOptionalFields(ctx, b.provider, req, frame, exchangeOptionalFields())
+
+	firstDay := 0
+	if !config.Start.IsZero() {
+		firstDay = frame.DateIndex(config.Start)
+	}
+	if firstDay >= len(frame.Calendar) {
+		return nil, fmt.Errorf("回测区间内没有交易日")
+	}
+	exchange, err := NewSimExchange(frame, config.Exchange)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &NativeBacktestReport{Strategy: strategy.Name()}
+	position := NewPosition(config.Account)
+	prevValue := config.Account
+	benchmark, _ := frame.Series(config.Benchmark, "$close")
+
+	for day := firstDay; day < len(frame.Calendar); day++ {
+		date := frame.Calendar[day]
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		exchange.SetDay(day)
+
+		orders, err := strategy.GenerateOrders(&StrategyContext{
+			Date:     date,
+			Scores:   scoresBefore(scores, date),
+			Position: position,
+			Exchange: exchange,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s 生成订单失败: %v", date.Format("2006-01-02"), err)
+		}
+
+		tradeValue, cost := 0.0, 0.0
+		for _, order := range orders {
+			trade, rejection := exchange.Deal(order, position)
+			if rejection != nil {
+				report.Rejected = append(report.Rejected, *rejection)
+			}
+			if trade == nil {
+				continue
+			}
+			report.Trades = append(report.Trades, *trade)
+			tradeValue += trade.Amount * trade.Price
+			cost += trade.Commission
+		}
+
+		// 收盘估值，停牌证券沿用最近价格
+		for inst, h := range position.Holdings {
+			if p := exchange.ClosePrice(inst); !math.IsNaN(p) && p > 0 {
+				h.Price = p
+			}
+		}
+		value := position.Value()
+		record := DailyRecord{
+			Date:       date,
+			Value:      value,
+			Cash:       position.Cash,
+			StockValue: value - position.Cash,
+			Return:     value/prevValue - 1,
+			Turnover:   tradeValue / value,
+			Cost:       cost,
+		}
+		if benchmark != nil && !math.IsNaN(benchmark[day]) {
+			record.BenchmarkValue = benchmark[day]
+			if day > 0 && benchmark[day-1] > 0 {
+				record.BenchmarkReturn = benchmark[day]/benchmark[day-1] - 1
+			}
+		}
+		report.Daily = append(report.Daily, record)
+		report.Positions = append(report.Positions, positionRecords(date, position)...)
+		prevValue = value
+
+		for _, h := range position.Holdings {
+			h.HoldDays++
+			h.TodayBought = 0
+		}
+		if callback != nil {
+			callback((day-firstDay+1)*100/(len(frame.Calendar)-firstDay), map[string]float64{
+				"value":        value,
+				"total_return": value/config.Account - 1,
+			})
+		}
+	}
+
+	report.Summary = summarizeDailyRecords(report.Daily, config.Account)
+	return report, nil
+}
+
+// loadOptionalFields 加载可选字段，数据源不提供时忽略
+func loadOptionalFields(ctx context.Context, provider MarketDataProvider, req FrameRequest, frame *MarketFrame, fields []string) {
+	for _, field := range fields {
+		req.Fields = []string{field}
+		optional, err := provider.LoadFrame(ctx, req)
+		if err != nil || len(optional.Calendar) != len(frame.Calendar) {
+			continue
+		}
+		columns := make([][]float64, len(frame.Instruments))
+		for i, inst := range frame.Instruments {
+			if values, ok := optional.Series(inst, field); ok {
+				columns[i] = values
+			} else {
+				columns[i] = nanSeries(len(frame.Calendar))
+			}
+		}
+		frame.Fields[field] = columns
+	}
+}
+
+// backtestInstruments 汇总回测需要加载的证券
+func backtestInstruments(config NativeBacktestConfig, scores *FactorFrame, strategy SignalStrategy) []string {
+	seen := make(map[string]bool)
+	var instruments []string
+	add := func(inst string) {
+		if inst != "" && !seen[inst] {
+			seen[inst] = true
+			instruments = append(instruments, inst)
+		}
+	}
+	if scores != nil {
+		for _, inst := range scores.Instruments {
+			add(inst)
+		}
+	}
+	if s, ok := strategy.(interface{ Instruments() []string }); ok {
+		for _, inst := range s.Instruments() {
+			add(inst)
+		}
+	}
+	add(config.Benchmark)
+	return instruments
+}
+
+// scoresBefore 返回严格早于date的最近一个交易日的分数
+func scoresBefore(scores *FactorFrame, date time.Time) map[string]float64 {
+	if scores == nil {
+		return nil
+	}
+	idx := sort.Search(len(scores.Calendar), func(i int) bool { return !scores.Calendar[i].Before(date) }) - 1
+	if idx < 0 {
+		return nil
+	}
+	result := make(map[string]float64, len(scores.Instruments))
+	for i, inst := range scores.Instruments {
+		if v := scores.Values[i][idx]; !math.IsNaN(v) {
+			result[inst] = v
+		}
+	}
+	return result
+}
+
+// positionRecords 生成持仓记录
+func positionRecords(date time.Time, position *Position) []PositionRecord {
+	total := position.Value()
+	records := make([]PositionRecord, 0, len(position.Holdings))
+	for _, inst := range position.Instruments() {
+		h := position.Holdings[inst]
+		value := h.Amount * h.Price
+		records = append(records, PositionRecord{
+			Date:       date,
+			Instrument: inst,
+			Amount:     h.Amount,
+			Weight:     value / total,
+			Price:      h.Price,
+			Value:      value,
+		})
+	}
+	return records
+}
+
+// summarizeDailyRecords 计算回测汇总指标（按252个交易日年化）
+func summarizeDailyRecords(daily []DailyRecord, account float64) BacktestResult {
+	n := len(daily)
+	if n == 0 {
+		return BacktestResult{}
+	}
+	returns := make([]float64, n)
+	benchmarkTotal, wins := 1.0, 0
+	peak, maxDrawdown := account, 0.0
+	for i, d := range daily {
+		returns[i] = d.Return
+		benchmarkTotal *= 1 + d.BenchmarkReturn
+		if d.Return > 0 {
+			wins++
+		}
+		peak = math.Max(peak, d.Value)
+		maxDrawdown = math.Min(maxDrawdown, d.Value/peak-1)
+	}
+
+	mean := sumValues(returns) / float64(n)
+	volatility := 0.0
+	if n > 1 {
+		volatility = math.Sqrt(sampleVariance(returns) * 252)
+	}
+	result := BacktestResult{
+		TotalReturn:  daily[n-1].Value/account - 1,
+		AnnualReturn: mean * 252,
+		MaxDrawdown:  maxDrawdown,
+		Volatility:   volatility,
+		WinRate:      float64(wins) / float64(n),
+	}
+	result.ExcessReturn = result.TotalReturn - (benchmarkTotal - 1)
+	if volatility > 0 {
+		result.SharpeRatio = result.AnnualReturn / volatility
+	}
+	return result
+}
@@ -0,0 +1,187 @@
+package qlib
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+// newBacktestFrame 构造四只股票和一个基准、六个交易日的行情，C 在第4天上涨到12
+func newBacktestFrame(t *testing.T) (*MarketFrame, *FactorFrame) {
+	calendar := make([]time.Time, 6)
+	for i := range calendar {
+		calendar[i] = time.Date(2023, 1, 2+i, 0, 0, 0, 0, time.UTC)
+	}
+	frame := NewMarketFrame(calendar, []string{"A", "B", "C", "D", "BENCH"})
+	for _, inst := range []string{"A", "B", "C", "D"} {
+		closes := []float64{10, 10, 10, 10, 10, 10}
+		if inst == "C" {
+			closes = []float64{10, 10, 10, 12, 12, 12}
+		}
+		if err := frame.SetSeries(inst, "$close", closes); err != nil {
+			t.Fatalf("SetSeries failed: %v", err)
+		}
+	}
+	frame.SetSeries("BENCH", "$close", []float64{100, 101, 102, 103, 104, 105})
+
+	scores := &FactorFrame{
+		Calendar:    calendar,
+		Instruments: []string{"A", "B", "C", "D"},
+		Values: [][]float64{
+			{4, 1, 1, 1, 1, 1},
+			{3, 3, 3, 3, 3, 3},
+			{2, 4, 4, 4, 4, 4},
+			{1, 2, 2, 2, 2, 2},
+		},
+	}
+	return frame, scores
+}
+
+func TestTopkDropoutBacktest(t *testing.T) {
+	frame, scores := newBacktestFrame(t)
+	backtester := NewNativeBacktester(NewMemoryDataProvider(frame))
+	strategy := &TopkDropoutStrategy{Topk: 2, NDrop: 1, HoldThresh: 1, RiskDegree: 0.95, MethodSell: "bottom", MethodBuy: "top"}
+
+	report, err := backtester.Run(context.Background(), NativeBacktestConfig{Account: 1000, Benchmark: "BENCH"}, scores, strategy, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// 第1天无信号；第2天买入A、B；第3天卖出分数最低的A并买入C
+	want := []struct {
+		day       int
+		inst, dir string
+		amount    float64
+	}{
+		{1, "A", "buy", 47.5},
+		{1, "B", "buy", 47.5},
+		{2, "A", "sell", 47.5},
+		{2, "C", "buy", 49.875},
+	}
+	if len(report.Trades) != len(want) {
+		t.Fatalf("Expected %d trades, got %+v", len(want), report.Trades)
+	}
+	for i, w := range want {
+		trade := report.Trades[i]
+		if !trade.Date.Equal(frame.Calendar[w.day]) || trade.Instrument != w.inst || trade.Direction != w.dir || math.Abs(trade.Amount-w.amount) > 1e-9 {
+			t.Errorf("Trades[%d] = %+v, want %+v", i, trade, w)
+		}
+	}
+
+	if len(report.Daily) != 6 {
+		t.Fatalf("Expected 6 daily records, got %d", len(report.Daily))
+	}
+	day3 := report.Daily[3]
+	if math.Abs(day3.Value-1099.75) > 1e-9 || math.Abs(day3.Return-0.09975) > 1e-9 {
+		t.Errorf("Unexpected day 4 record: %+v", day3)
+	}
+	if math.Abs(report.Daily[1].Turnover-0.95) > 1e-9 {
+		t.Errorf("Turnover on day 2 = %v, want 0.95", report.Daily[1].Turnover)
+	}
+	if math.Abs(report.Daily[1].BenchmarkReturn-0.01) > 1e-9 {
+		t.Errorf("Benchmark return on day 2 = %v, want 0.01", report.Daily[1].BenchmarkReturn)
+	}
+	if math.Abs(report.Summary.TotalReturn-0.09975) > 1e-9 || report.Summary.MaxDrawdown != 0 {
+		t.Errorf("Unexpected summary: %+v", report.Summary)
+	}
+
+	var last []PositionRecord
+	for _, p := range report.Positions {
+		if p.Date.Equal(frame.Calendar[5]) {
+			last = append(last, p)
+		}
+	}
+	if len(last) != 2 || last[0].Instrument != "B" || last[1].Instrument != "C" {
+		t.Errorf("Unexpected final positions: %+v", last)
+	}
+}
+
+func TestTopkDropoutHoldThresh(t *testing.T) {
+	frame, scores := newBacktestFrame(t)
+	strategy := &TopkDropoutStrategy{Topk: 2, NDrop: 1, HoldThresh: 2, RiskDegree: 0.95, MethodSell: "bottom", MethodBuy: "top"}
+
+	report, err := NewNativeBacktester(NewMemoryDataProvider(frame)).Run(context.Background(), NativeBacktestConfig{Account: 1000}, scores, strategy, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for _, trade := range report.Trades {
+		if trade.Direction == "sell" && trade.Date.Equal(frame.Calendar[2]) {
+			t.Errorf("A is held for only one day and must not be sold: %+v", trade)
+		}
+	}
+}
+
+func TestFixedWeightBacktest(t *testing.T) {
+	frame, _ := newBacktestFrame(t)
+	engine := NewBacktestEngine("", "", "")
+	engine.SetDataProvider(NewMemoryDataProvider(frame))
+
+	report, err := engine.RunNativeBacktest(context.Background(), NativeBacktestConfig{
+		Account:  1000,
+		Exchange: ExchangeConfig{Trade_unit: 10, Open_cost: 0.001},
+	}, "FixedWeightStrategy", map[string]interface{}{
+		"weights":     map[string]interface{}{"A": 0.5, "C": 0.5},
+		"risk_degree": 1.0,
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("RunNativeBacktest failed: %v", err)
+	}
+
+	// 首日按权重建仓，同月内不再调仓；50股按10股取整后现金不足，缩减为40股
+	if len(report.Trades) != 2 || report.Trades[0].Amount != 50 || report.Trades[1].Amount != 40 {
+		t.Fatalf("Unexpected trades: %+v", report.Trades)
+	}
+	if math.Abs(report.Trades[0].Commission-0.5) > 1e-9 {
+		t.Errorf("Commission = %v, want 0.5", report.Trades[0].Commission)
+	}
+
+	if _, err := NewSignalStrategy("UnknownStrategy", nil); err == nil {
+		t.Error("Unknown strategy type should fail")
+	}
+
+	// 取消的回测应保留终止原因，停止回测时记为取消而非失败
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = engine.RunNativeBacktest(ctx, NativeBacktestConfig{Account: 1000}, "FixedWeightStrategy", map[string]interface{}{
+		"weights": map[string]interface{}{"A": 1.0},
+	}, nil, nil)
+	if JobTermination(err) != JobCancelled {
+		t.Errorf("Cancelled backtest should report %q, got %v", JobCancelled, err)
+	}
+}
+
+func TestRunBacktestWithReportSignal(t *testing.T) {
+	frame, _ := newBacktestFrame(t)
+	engine := NewBacktestEngine("", "", "")
+	engine.SetDataProvider(NewMemoryDataProvider(frame))
+
+	// 分数为收盘价取负，BENCH 分数最低；A 到 D 首日同分时按代码选中 A
+	result, report, err := engine.RunBacktestWithReport(context.Background(), BacktestParams{
+		StrategyType: "TopkDropoutStrategy",
+		ConfigJSON:   `{"signal": "-$close", "topk": 1, "n_drop": 1, "account": 1000}`,
+		Benchmark:    "BENCH",
+	}, nil)
+	if err != nil {
+		t.Fatalf("RunBacktestWithReport failed: %v", err)
+	}
+	if report == nil {
+		t.Fatal("Native backtest should return a report")
+	}
+	if len(report.Trades) != 1 || report.Trades[0].Instrument != "A" || !report.Trades[0].Date.Equal(frame.Calendar[1]) {
+		t.Errorf("Unexpected trades: %+v", report.Trades)
+	}
+	if report.Daily[0].BenchmarkValue != 100 || report.Daily[5].BenchmarkValue != 105 {
+		t.Errorf("Unexpected benchmark values: %v, %v", report.Daily[0].BenchmarkValue, report.Daily[5].BenchmarkValue)
+	}
+	if result.TotalReturn != report.Summary.TotalReturn {
+		t.Errorf("Result %+v should match report summary %+v", result, report.Summary)
+	}
+
+	if _, _, err := engine.RunBacktestWithReport(context.Background(), BacktestParams{
+		StrategyType: "FixedWeightStrategy",
+		ConfigJSON:   `{"weights": {"A": 1}, "exchange": "invalid"}`,
+	}, nil); err == nil {
+		t.Error("Invalid exchange config should fail")
+	}
+}
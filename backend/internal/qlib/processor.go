@@ -0,0 +1,677 @@
+package qlib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultMarketCapField 市值中性化默认使用的行情字段
+const DefaultMarketCapField = "$market_cap"
+
+// robustScale 中位数绝对偏差换算为正态标准差的系数，与Qlib一致
+const robustScale = 1.4826
+
+// ProcessorConfig 预处理器配置，格式与Qlib DataHandlerLP 的 infer_processors/learn_processors 一致
+type ProcessorConfig struct {
+	Class  string                 `json:"class"`
+	Kwargs map[string]interface{} `json:"kwargs,omitempty"`
+}
+
+// ProcessContext 预处理器使用的辅助数据，按处理器链的 Requirements 准备
+type ProcessContext struct {
+	Industries map[string]string // 证券行业分类
+	Market     *MarketFrame      // 与因子结果对齐的行情，如市值字段
+}
+
+// Processor 因子值预处理器，在因子结果上原地变换
+//
+// 与Qlib相同，处理器链按顺序先在上一个处理器的输出上 Fit，再 Transform。
+type Processor interface {
+	Fit(frame *FactorFrame) error
+	Transform(frame *FactorFrame, pctx *ProcessContext) error
+}
+
+// NewProcessor 根据类名和参数创建预处理器
+//
+// 支持Qlib的 RobustZScoreNorm、ZScoreNorm、CSZScoreNorm、CSRankNorm、Fillna、CSZFillna、DropnaLabel，
+// 以及原生实现的 Winsorize、Clip 和 Neutralize。kwargs 中的 fit_start_time/fit_end_time 优先于链上的拟合区间。
+func NewProcessor(config ProcessorConfig, fitStart, fitEnd time.Time) (Processor, error) {
+	kwargs := config.Kwargs
+	if kwargs == nil {
+		kwargs = map[string]interface{}{}
+	}
+	fit, err := newFitWindow(kwargs, fitStart, fitEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Class {
+	case "RobustZScoreNorm":
+		return &ZScoreProcessor{fitWindow: fit, Robust: true, ClipOutlier: paramBool(kwargs, "clip_outlier", true)}, nil
+	case "ZScoreNorm":
+		return &ZScoreProcessor{fitWindow: fit}, nil
+	case "CSZScoreNorm":
+		method := paramString(kwargs, "method", "zscore")
+		if method != "zscore" && method != "robust" {
+			return nil, fmt.Errorf("CSZScoreNorm 不支持的方法: %s", method)
+		}
+		return &CSZScoreProcessor{Robust: method == "robust"}, nil
+	case "CSRankNorm":
+		return &CSRankProcessor{}, nil
+	case "Fillna":
+		processor := &FillnaProcessor{Method: paramString(kwargs, "method", FillValue), Value: paramFloat(kwargs, "fill_value", 0)}
+		switch processor.Method {
+		case FillValue, FillCSMean, FillCSMedian, FillForward:
+			return processor, nil
+		}
+		return nil, fmt.Errorf("Fillna 不支持的填充方式: %s", processor.Method)
+	case "CSZFillna":
+		return &FillnaProcessor{Method: FillCSMean}, nil
+	case "DropnaLabel":
+		// 只作用于标签，因子值原样保留
+		return noopProcessor{}, nil
+	case "Winsorize":
+		processor := &WinsorizeProcessor{
+			Method: paramString(kwargs, "method", "quantile"),
+			Lower:  paramFloat(kwargs, "lower", 0.01),
+			Upper:  paramFloat(kwargs, "upper", 0.99),
+			N:      paramFloat(kwargs, "n", 3),
+		}
+		switch {
+		case processor.Method != "quantile" && processor.Method != "mad":
+			return nil, fmt.Errorf("Winsorize 不支持的方法: %s", processor.Method)
+		case processor.Method == "quantile" && !(processor.Lower >= 0 && processor.Lower < processor.Upper && processor.Upper <= 1):
+			return nil, fmt.Errorf("Winsorize 分位数需满足 0 <= lower < upper <= 1")
+		case processor.Method == "mad" && processor.N <= 0:
+			return nil, fmt.Errorf("Winsorize 的 n 必须为正数")
+		}
+		return processor, nil
+	case "Clip":
+		processor := &ClipProcessor{Lower: paramFloat(kwargs, "lower", math.Inf(-1)), Upper: paramFloat(kwargs, "upper", math.Inf(1))}
+		if processor.Lower >= processor.Upper {
+			return nil, fmt.Errorf("Clip 的 lower 必须小于 upper")
+		}
+		return processor, nil
+	case "Neutralize":
+		processor := &NeutralizeProcessor{
+			Industry:       paramBool(kwargs, "industry", true),
+			MarketCap:      paramBool(kwargs, "market_cap", true),
+			MarketCapField: paramString(kwargs, "market_cap_field", DefaultMarketCapField),
+		}
+		if !processor.Industry && !processor.MarketCap {
+			return nil, fmt.Errorf("Neutralize 至少需要行业或市值中的一项")
+		}
+		return processor, nil
+	default:
+		return nil, fmt.Errorf("不支持的预处理器: %s", config.Class)
+	}
+}
+
+// qlibProcessors Qlib自带的处理器，可以原样交给Python端的 DataHandlerLP 执行
+var qlibProcessors = map[string]bool{
+	"RobustZScoreNorm": true,
+	"ZScoreNorm":       true,
+	"CSZScoreNorm":     true,
+	"CSRankNorm":       true,
+	"Fillna":           true,
+	"CSZFillna":        true,
+	"DropnaLabel":      true,
+}
+
+// IsQlibProcessor 处理器是否为Qlib自带
+//
+// Winsorize、Clip、Neutralize 以及带 method 参数的 Fillna 只在原生计算中可用。
+func IsQlibProcessor(config ProcessorConfig) bool {
+	if _, ok := config.Kwargs["method"]; ok && config.Class == "Fillna" {
+		return false
+	}
+	return qlibProcessors[config.Class]
+}
+
+// ParseProcessors 解析工作流或因子测试配置中的预处理器列表
+func ParseProcessors(raw interface{}) ([]ProcessorConfig, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("解析预处理器配置失败: %v", err)
+	}
+	var configs []ProcessorConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("解析预处理器配置失败: %v", err)
+	}
+	if _, err := NewProcessorChain(configs, time.Time{}, time.Time{}); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// ProcessorChain 按顺序执行的预处理器链
+type ProcessorChain struct {
+	configs    []ProcessorConfig
+	processors []Processor
+}
+
+// NewProcessorChain 创建预处理器链，fitStart/fitEnd 为需要拟合的处理器估计参数的区间，零值表示全部数据
+func NewProcessorChain(configs []ProcessorConfig, fitStart, fitEnd time.Time) (*ProcessorChain, error) {
+	chain := &ProcessorChain{configs: configs}
+	for i, config := range configs {
+		processor, err := NewProcessor(config, fitStart, fitEnd)
+		if err != nil {
+			return nil, fmt.Errorf("第%d个预处理器配置错误: %v", i+1, err)
+		}
+		chain.processors = append(chain.processors, processor)
+	}
+	return chain, nil
+}
+
+// Len 处理器个数
+func (c *ProcessorChain) Len() int {
+	return len(c.processors)
+}
+
+// Requirements 处理器链需要的行情字段和是否需要行业分类
+func (c *ProcessorChain) Requirements() (fields []string, industries bool) {
+	seen := make(map[string]bool)
+	for _, processor := range c.processors {
+		if n, ok := processor.(*NeutralizeProcessor); ok {
+			industries = industries || n.Industry
+			if n.MarketCap && !seen[n.MarketCapField] {
+				seen[n.MarketCapField] = true
+				fields = append(fields, n.MarketCapField)
+			}
+		}
+	}
+	return fields, industries
+}
+
+// Process 在因子结果的副本上依次拟合和变换，返回处理后的结果
+func (c *ProcessorChain) Process(frame *FactorFrame, pctx *ProcessContext) (*FactorFrame, error) {
+	if pctx == nil {
+		pctx = &ProcessContext{}
+	}
+	values := make([][]float64, len(frame.Values))
+	for i, series := range frame.Values {
+		values[i] = append([]float64(nil), series...)
+	}
+	result := &FactorFrame{Calendar: frame.Calendar, Instruments: frame.Instruments, Values: values}
+
+	for i, processor := range c.processors {
+		if err := processor.Fit(result); err != nil {
+			return nil, fmt.Errorf("预处理器 %s 拟合失败: %v", c.configs[i].Class, err)
+		}
+		if err := processor.Transform(result, pctx); err != nil {
+			return nil, fmt.Errorf("预处理器 %s 执行失败: %v", c.configs[i].Class, err)
+		}
+	}
+	return result, nil
+}
+
+// ApplyProcessors 加载处理器链需要的行业和市值数据后处理因子结果
+func ApplyProcessors(ctx context.Context, provider MarketDataProvider, chain *ProcessorChain, frame *FactorFrame, freq string) (*FactorFrame, error) {
+	if chain == nil || chain.Len() == 0 {
+		return frame, nil
+	}
+	pctx := &ProcessContext{}
+	fields, needIndustries := chain.Requirements()
+	if needIndustries {
+		if source, ok := provider.(IndustryProvider); ok {
+			industries, err := source.Industries()
+			if err != nil {
+				return nil, err
+			}
+			pctx.Industries = industries
+		}
+		if len(pctx.Industries) == 0 {
+			return nil, fmt.Errorf("行业中性化需要行业分类数据")
+		}
+	}
+	if len(fields) > 0 && len(frame.Calendar) > 0 {
+		if provider == nil {
+			return nil, fmt.Errorf("市值中性化需要行情数据")
+		}
+		market, err := provider.LoadFrame(ctx, FrameRequest{
+			Instruments: frame.Instruments,
+			Fields:      fields,
+			Start:       frame.Calendar[0],
+			End:         frame.Calendar[len(frame.Calendar)-1],
+			Freq:        freq,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("加载中性化所需行情失败: %v", err)
+		}
+		pctx.Market = market
+	}
+	return chain.Process(frame, pctx)
+}
+
+// fitWindow 拟合区间，零值表示不限制
+type fitWindow struct {
+	FitStart time.Time
+	FitEnd   time.Time
+}
+
+func newFitWindow(kwargs map[string]interface{}, start, end time.Time) (fitWindow, error) {
+	window := fitWindow{FitStart: start, FitEnd: end}
+	if value := paramString(kwargs, "fit_start_time", ""); value != "" {
+		t, err := parseOptionalDate(value)
+		if err != nil {
+			return window, err
+		}
+		window.FitStart = t
+	}
+	if value := paramString(kwargs, "fit_end_time", ""); value != "" {
+		t, err := parseOptionalDate(value)
+		if err != nil {
+			return window, err
+		}
+		window.FitEnd = t
+	}
+	if !window.FitStart.IsZero() && !window.FitEnd.IsZero() && window.FitEnd.Before(window.FitStart) {
+		return window, fmt.Errorf("拟合结束时间早于开始时间")
+	}
+	return window, nil
+}
+
+// values 拟合区间内的有效值
+func (w fitWindow) values(frame *FactorFrame) []float64 {
+	var values []float64
+	for t, date := range frame.Calendar {
+		if (!w.FitStart.IsZero() && date.Before(w.FitStart)) || (!w.FitEnd.IsZero() && date.After(w.FitEnd)) {
+			continue
+		}
+		for _, series := range frame.Values {
+			if v := series[t]; !math.IsNaN(v) && !math.IsInf(v, 0) {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// ZScoreProcessor 用拟合区间的全样本统计量做标准化，对应Qlib的 ZScoreNorm 和 RobustZScoreNorm
+type ZScoreProcessor struct {
+	fitWindow
+	Robust      bool // 使用中位数和MAD
+	ClipOutlier bool // 稳健标准化后截断到[-3, 3]
+
+	center, scale float64
+}
+
+// Fit 估计均值（中位数）和标准差（MAD×1.4826）
+func (p *ZScoreProcessor) Fit(frame *FactorFrame) error {
+	values := p.values(frame)
+	if len(values) == 0 {
+		return fmt.Errorf("拟合区间内没有有效值")
+	}
+	if p.Robust {
+		p.center, p.scale = robustCenterScale(values)
+	} else {
+		p.center = sumValues(values) / float64(len(values))
+		variance := 0.0
+		for _, v := range values {
+			variance += (v - p.center) * (v - p.center)
+		}
+		p.scale = math.Sqrt(variance / float64(len(values)))
+	}
+	if p.scale == 0 || math.IsNaN(p.scale) {
+		p.scale = 1
+	}
+	return nil
+}
+
+// Transform 标准化全部因子值
+func (p *ZScoreProcessor) Transform(frame *FactorFrame, pctx *ProcessContext) error {
+	for _, series := range frame.Values {
+		for t, v := range series {
+			v = (v - p.center) / p.scale
+			if p.Robust && p.ClipOutlier {
+				v = math.Max(-3, math.Min(3, v))
+			}
+			series[t] = v
+		}
+	}
+	return nil
+}
+
+// CSZScoreProcessor 截面标准化，对应Qlib的 CSZScoreNorm
+type CSZScoreProcessor struct {
+	Robust bool
+}
+
+// Fit 截面处理器无需拟合
+func (p *CSZScoreProcessor) Fit(frame *FactorFrame) error { return nil }
+
+// Transform 逐日截面标准化
+func (p *CSZScoreProcessor) Transform(frame *FactorFrame, pctx *ProcessContext) error {
+	fn := csZScore
+	if p.Robust {
+		fn = csRobustZScore
+	}
+	applyCrossSection(frame, fn)
+	return nil
+}
+
+// CSRankProcessor 截面排名标准化，对应Qlib的 CSRankNorm：百分位排名减0.5后乘以3.46
+type CSRankProcessor struct{}
+
+// Fit 截面处理器无需拟合
+func (p *CSRankProcessor) Fit(frame *FactorFrame) error { return nil }
+
+// Transform 逐日截面排名
+func (p *CSRankProcessor) Transform(frame *FactorFrame, pctx *ProcessContext) error {
+	applyCrossSection(frame, func(values []float64) []float64 {
+		ranks := csRank(values)
+		for i, r := range ranks {
+			ranks[i] = (r - 0.5) * 3.46
+		}
+		return ranks
+	})
+	return nil
+}
+
+// 缺失值填充方式
+const (
+	FillValue    = "value"     // 填充固定值 fill_value
+	FillCSMean   = "cs_mean"   // 填充当日截面均值
+	FillCSMedian = "cs_median" // 填充当日截面中位数
+	FillForward  = "ffill"     // 沿用证券上一个有效值
+)
+
+// FillnaProcessor 缺失值填充
+type FillnaProcessor struct {
+	Method string
+	Value  float64
+}
+
+// Fit 填充无需拟合
+func (p *FillnaProcessor) Fit(frame *FactorFrame) error { return nil }
+
+// Transform 按填充方式替换 NaN
+func (p *FillnaProcessor) Transform(frame *FactorFrame, pctx *ProcessContext) error {
+	switch p.Method {
+	case FillForward:
+		for _, series := range frame.Values {
+			last := math.NaN()
+			for t, v := range series {
+				if math.IsNaN(v) {
+					series[t] = last
+				} else {
+					last = v
+				}
+			}
+		}
+	case FillCSMean, FillCSMedian:
+		applyCrossSection(frame, func(values []float64) []float64 {
+			valid := validValues(values)
+			if len(valid) == 0 {
+				return values
+			}
+			fill := sumValues(valid) / float64(len(valid))
+			if p.Method == FillCSMedian {
+				sort.Float64s(valid)
+				fill = quantileSorted(valid, 0.5)
+			}
+			for i, v := range values {
+				if math.IsNaN(v) {
+					values[i] = fill
+				}
+			}
+			return values
+		})
+	default:
+		for _, series := range frame.Values {
+			for t, v := range series {
+				if math.IsNaN(v) {
+					series[t] = p.Value
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// WinsorizeProcessor 截面去极值：按分位数或中位数±n倍MAD截断
+type WinsorizeProcessor struct {
+	Method string  // quantile 或 mad
+	Lower  float64 // 下分位数
+	Upper  float64 // 上分位数
+	N      float64 // MAD倍数
+}
+
+// Fit 截面处理器无需拟合
+func (p *WinsorizeProcessor) Fit(frame *FactorFrame) error { return nil }
+
+// Transform 逐日截断极端值
+func (p *WinsorizeProcessor) Transform(frame *FactorFrame, pctx *ProcessContext) error {
+	applyCrossSection(frame, func(values []float64) []float64 {
+		valid := validValues(values)
+		if len(valid) == 0 {
+			return values
+		}
+		var lower, upper float64
+		if p.Method == "mad" {
+			center, scale := robustCenterScale(valid)
+			lower, upper = center-p.N*scale, center+p.N*scale
+		} else {
+			sort.Float64s(valid)
+			lower, upper = quantileSorted(valid, p.Lower), quantileSorted(valid, p.Upper)
+		}
+		for i, v := range values {
+			if !math.IsNaN(v) {
+				values[i] = math.Max(lower, math.Min(upper, v))
+			}
+		}
+		return values
+	})
+	return nil
+}
+
+// ClipProcessor 按固定上下界截断
+type ClipProcessor struct {
+	Lower float64
+	Upper float64
+}
+
+// Fit 无需拟合
+func (p *ClipProcessor) Fit(frame *FactorFrame) error { return nil }
+
+// Transform 截断到[Lower, Upper]
+func (p *ClipProcessor) Transform(frame *FactorFrame, pctx *ProcessContext) error {
+	for _, series := range frame.Values {
+		for t, v := range series {
+			if !math.IsNaN(v) {
+				series[t] = math.Max(p.Lower, math.Min(p.Upper, v))
+			}
+		}
+	}
+	return nil
+}
+
+// NeutralizeProcessor 行业和市值中性化
+//
+// 逐日将因子值对行业哑变量和对数市值做截面回归，以残差作为中性化后的因子值。
+// 由于行业哑变量等价于分行业去均值，回归按行业内去均值后再对市值做一元回归求解。
+type NeutralizeProcessor struct {
+	Industry       bool
+	MarketCap      bool
+	MarketCapField string
+}
+
+// Fit 截面处理器无需拟合
+func (p *NeutralizeProcessor) Fit(frame *FactorFrame) error { return nil }
+
+// Transform 逐日回归取残差，缺少市值的证券结果为 NaN
+func (p *NeutralizeProcessor) Transform(frame *FactorFrame, pctx *ProcessContext) error {
+	groups := make([]string, len(frame.Instruments))
+	for i, inst := range frame.Instruments {
+		if p.Industry {
+			groups[i] = industryOf(pctx.Industries, inst)
+		}
+	}
+	var caps [][]float64
+	if p.MarketCap {
+		if pctx.Market == nil {
+			return fmt.Errorf("缺少市值字段 %s", p.MarketCapField)
+		}
+		caps = make([][]float64, len(frame.Instruments))
+		for i, inst := range frame.Instruments {
+			series, ok := pctx.Market.Series(inst, p.MarketCapField)
+			caps[i] = alignSeries(pctx.Market.Calendar, series, ok, frame.Calendar)
+		}
+	}
+
+	for t := range frame.Calendar {
+		y := make([]float64, len(frame.Instruments))
+		x := make([]float64, len(frame.Instruments))
+		for i, series := range frame.Values {
+			y[i], x[i] = series[t], 0
+			if p.MarketCap {
+				if c := caps[i][t]; c > 0 {
+					x[i] = math.Log(c)
+				} else {
+					y[i] = math.NaN()
+				}
+			}
+		}
+		residuals := neutralize(y, x, groups, p.MarketCap)
+		for i, series := range frame.Values {
+			series[t] = residuals[i]
+		}
+	}
+	return nil
+}
+
+// neutralize 分组去均值后对 x 做一元回归，返回残差
+func neutralize(y, x []float64, groups []string, withX bool) []float64 {
+	type moments struct{ sumY, sumX, n float64 }
+	stats := make(map[string]*moments)
+	for i, v := range y {
+		if math.IsNaN(v) {
+			continue
+		}
+		m, ok := stats[groups[i]]
+		if !ok {
+			m = &moments{}
+			stats[groups[i]] = m
+		}
+		m.sumY += v
+		m.sumX += x[i]
+		m.n++
+	}
+
+	out := nanSeries(len(y))
+	sxy, sxx := 0.0, 0.0
+	for i, v := range y {
+		if math.IsNaN(v) {
+			continue
+		}
+		m := stats[groups[i]]
+		out[i] = v - m.sumY/m.n
+		dx := x[i] - m.sumX/m.n
+		sxy += out[i] * dx
+		sxx += dx * dx
+	}
+	if !withX || sxx == 0 {
+		return out
+	}
+	beta := sxy / sxx
+	for i, v := range out {
+		if !math.IsNaN(v) {
+			out[i] = v - beta*(x[i]-stats[groups[i]].sumX/stats[groups[i]].n)
+		}
+	}
+	return out
+}
+
+// noopProcessor 对因子值不起作用的处理器
+type noopProcessor struct{}
+
+func (noopProcessor) Fit(frame *FactorFrame) error                             { return nil }
+func (noopProcessor) Transform(frame *FactorFrame, pctx *ProcessContext) error { return nil }
+
+// applyCrossSection 对每个交易日的截面值调用 fn 并写回
+func applyCrossSection(frame *FactorFrame, fn func(values []float64) []float64) {
+	column := make([]float64, len(frame.Instruments))
+	for t := range frame.Calendar {
+		for i, series := range frame.Values {
+			column[i] = series[t]
+		}
+		result := fn(column)
+		for i, series := range frame.Values {
+			series[t] = result[i]
+		}
+	}
+}
+
+// csRobustZScore 截面稳健标准化：(x - 中位数) / (MAD×1.4826)
+func csRobustZScore(values []float64) []float64 {
+	out := nanSeries(len(values))
+	valid := validValues(values)
+	if len(valid) < 2 {
+		return out
+	}
+	center, scale := robustCenterScale(valid)
+	if scale == 0 {
+		return out
+	}
+	for i, v := range values {
+		if !math.IsNaN(v) {
+			out[i] = (v - center) / scale
+		}
+	}
+	return out
+}
+
+// robustCenterScale 中位数和换算后的MAD
+func robustCenterScale(values []float64) (float64, float64) {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	median := quantileSorted(sorted, 0.5)
+	deviations := make([]float64, len(sorted))
+	for i, v := range sorted {
+		deviations[i] = math.Abs(v - median)
+	}
+	sort.Float64s(deviations)
+	return median, quantileSorted(deviations, 0.5) * robustScale
+}
+
+// alignSeries 将行情序列按日期对齐到目标日历，缺失的日期为 NaN
+func alignSeries(calendar []time.Time, series []float64, ok bool, target []time.Time) []float64 {
+	out := nanSeries(len(target))
+	if !ok {
+		return out
+	}
+	j := 0
+	for t, date := range target {
+		for j < len(calendar) && calendar[j].Before(date) {
+			j++
+		}
+		if j < len(calendar) && calendar[j].Equal(date) {
+			out[t] = series[j]
+		}
+	}
+	return out
+}
+
+func validValues(values []float64) []float64 {
+	valid := make([]float64, 0, len(values))
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			valid = append(valid, v)
+		}
+	}
+	return valid
+}
+
+func paramBool(params map[string]interface{}, key string, defaultValue bool) bool {
+	if v, ok := params[key].(bool); ok {
+		return v
+	}
+	return defaultValue
+}
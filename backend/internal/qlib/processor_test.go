@@ -0,0 +1,169 @@
+package qlib
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func newTestFactorFrame(calendar []time.Time, values map[string][]float64) *FactorFrame {
+	frame := &FactorFrame{Calendar: calendar}
+	for _, inst := range []string{"A", "B", "C", "D"} {
+		if series, ok := values[inst]; ok {
+			frame.Instruments = append(frame.Instruments, inst)
+			frame.Values = append(frame.Values, series)
+		}
+	}
+	return frame
+}
+
+func TestRobustZScoreFitWindow(t *testing.T) {
+	calendar := weekdayCalendar(2)
+	frame := newTestFactorFrame(calendar, map[string][]float64{
+		"A": {1, 100},
+		"B": {2, 2},
+		"C": {3, 3},
+	})
+	// 只用第一天拟合：中位数2，MAD为1
+	chain, err := NewProcessorChain([]ProcessorConfig{{Class: "RobustZScoreNorm"}}, calendar[0], calendar[0])
+	if err != nil {
+		t.Fatalf("NewProcessorChain failed: %v", err)
+	}
+	result, err := chain.Process(frame, nil)
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if got := result.Values[0][0]; math.Abs(got+1/robustScale) > 1e-12 {
+		t.Errorf("A on day 0 = %v, want %v", got, -1/robustScale)
+	}
+	if got := result.Values[0][1]; got != 3 {
+		t.Errorf("outlier should be clipped to 3, got %v", got)
+	}
+	if frame.Values[0][1] != 100 {
+		t.Error("Process should not modify its input")
+	}
+
+	if _, err := NewProcessorChain([]ProcessorConfig{{Class: "ZScoreNorm", Kwargs: map[string]interface{}{
+		"fit_start_time": "2023-02-01", "fit_end_time": "2023-01-01",
+	}}}, time.Time{}, time.Time{}); err == nil {
+		t.Error("reversed fit window should fail")
+	}
+}
+
+func TestCrossSectionProcessors(t *testing.T) {
+	nan := math.NaN()
+	calendar := weekdayCalendar(2)
+	values := map[string][]float64{
+		"A": {1, nan},
+		"B": {2, 4},
+		"C": {nan, 6},
+		"D": {100, 8},
+	}
+	process := func(configs ...ProcessorConfig) *FactorFrame {
+		chain, err := NewProcessorChain(configs, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("NewProcessorChain failed: %v", err)
+		}
+		result, err := chain.Process(newTestFactorFrame(calendar, values), nil)
+		if err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+		return result
+	}
+
+	ranked := process(ProcessorConfig{Class: "CSRankNorm"})
+	if got := ranked.Values[3][0]; math.Abs(got-0.5*3.46) > 1e-12 {
+		t.Errorf("top rank = %v, want %v", got, 0.5*3.46)
+	}
+	if !math.IsNaN(ranked.Values[2][0]) {
+		t.Error("missing values should stay missing after rank")
+	}
+
+	fills := []struct {
+		kwargs map[string]interface{}
+		a1, c0 float64
+	}{
+		{map[string]interface{}{"fill_value": -1}, -1, -1},
+		{map[string]interface{}{"method": "cs_mean"}, 6, 103.0 / 3},
+		{map[string]interface{}{"method": "cs_median"}, 6, 2},
+		{map[string]interface{}{"method": "ffill"}, 1, nan},
+	}
+	for _, tt := range fills {
+		filled := process(ProcessorConfig{Class: "Fillna", Kwargs: tt.kwargs})
+		if a1, c0 := filled.Values[0][1], filled.Values[2][0]; a1 != tt.a1 || !(c0 == tt.c0 || math.IsNaN(c0) && math.IsNaN(tt.c0)) {
+			t.Errorf("Fillna %v: A[1] = %v, C[0] = %v", tt.kwargs, a1, c0)
+		}
+	}
+
+	winsorized := process(ProcessorConfig{Class: "Winsorize", Kwargs: map[string]interface{}{"method": "mad", "n": 3}})
+	// 第一天中位数2，MAD为1，上界 2+3×1.4826
+	if got, want := winsorized.Values[3][0], 2+3*robustScale; math.Abs(got-want) > 1e-12 {
+		t.Errorf("winsorized D[0] = %v, want %v", got, want)
+	}
+	clipped := process(ProcessorConfig{Class: "Clip", Kwargs: map[string]interface{}{"lower": 2, "upper": 5}})
+	if clipped.Values[0][0] != 2 || clipped.Values[3][1] != 5 {
+		t.Errorf("unexpected clip result: %v", clipped.Values)
+	}
+}
+
+func TestNeutralizeProcessor(t *testing.T) {
+	calendar := weekdayCalendar(1)
+	market := NewMarketFrame(calendar, []string{"A", "B", "C", "D"})
+	caps := map[string]float64{"A": 1, "B": 2, "C": 1, "D": 2}
+	for inst, c := range caps {
+		market.SetSeries(inst, DefaultMarketCapField, []float64{math.Exp(c)})
+	}
+	provider := NewMemoryDataProvider(market)
+	provider.SetIndustries(map[string]string{"A": "银行", "B": "银行", "C": "医药", "D": "医药"})
+
+	// 因子 = 行业效应 + 0.5×对数市值 + 残差
+	frame := newTestFactorFrame(calendar, map[string][]float64{
+		"A": {10 + 0.5 + 0.1},
+		"B": {10 + 1.0 - 0.1},
+		"C": {-5 + 0.5 - 0.2},
+		"D": {-5 + 1.0 + 0.2},
+	})
+	chain, err := NewProcessorChain([]ProcessorConfig{{Class: "Neutralize"}}, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("NewProcessorChain failed: %v", err)
+	}
+	result, err := ApplyProcessors(context.Background(), provider, chain, frame, "day")
+	if err != nil {
+		t.Fatalf("ApplyProcessors failed: %v", err)
+	}
+	// 行业内去均值后，市值系数由全部截面共同估计为0.6
+	want := []float64{0.15, -0.15, -0.15, 0.15}
+	for i, series := range result.Values {
+		if math.Abs(series[0]-want[i]) > 1e-9 {
+			t.Errorf("residual of %s = %v, want %v", result.Instruments[i], series[0], want[i])
+		}
+	}
+
+	if _, err := ApplyProcessors(context.Background(), NewMemoryDataProvider(market), chain, frame, "day"); err == nil {
+		t.Error("industry neutralization without industries should fail")
+	}
+}
+
+func TestParseProcessors(t *testing.T) {
+	raw := []interface{}{
+		map[string]interface{}{"class": "RobustZScoreNorm", "kwargs": map[string]interface{}{"fields_group": "feature"}},
+		map[string]interface{}{"class": "Fillna", "kwargs": map[string]interface{}{"method": "ffill"}},
+	}
+	configs, err := ParseProcessors(raw)
+	if err != nil || len(configs) != 2 {
+		t.Fatalf("ParseProcessors failed: %v, %v", configs, err)
+	}
+	if !IsQlibProcessor(configs[0]) || IsQlibProcessor(configs[1]) {
+		t.Error("Fillna with a method is native only")
+	}
+	for _, bad := range []interface{}{
+		"RobustZScoreNorm",
+		[]interface{}{map[string]interface{}{"class": "Unknown"}},
+		[]interface{}{map[string]interface{}{"class": "Winsorize", "kwargs": map[string]interface{}{"lower": 0.9, "upper": 0.1}}},
+	} {
+		if _, err := ParseProcessors(bad); err == nil {
+			t.Errorf("ParseProcessors(%v) should fail", bad)
+		}
+	}
+}
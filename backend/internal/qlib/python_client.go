@@ -0,0 +1,172 @@
+package qlib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// Client 执行Python脚本的客户端
+//
+// 脚本从标准输入读取 input，返回脚本写到标准输出的内容；脚本以非0状态退出时返回错误。
+type Client interface {
+	Run(ctx context.Context, script string, input []byte) ([]byte, error)
+}
+
+// ProcessClient 每次调用启动一个新的Python解释器
+type ProcessClient struct {
+	pythonPath string
+	limits     ResourceLimits
+}
+
+// NewProcessClient 创建按次启动Python进程的客户端，使用 SetDefaultResourceLimits 设置的资源限制
+func NewProcessClient(pythonPath string) *ProcessClient {
+	if pythonPath == "" {
+		pythonPath = "python3"
+	}
+	return &ProcessClient{pythonPath: pythonPath, limits: defaultResourceLimits()}
+}
+
+// SetResourceLimits 设置子进程的资源限制
+func (c *ProcessClient) SetResourceLimits(limits ResourceLimits) {
+	c.limits = limits
+}
+
+// Run 以 -c 方式执行脚本
+//
+// 上下文携带事件处理函数时，脚本的文件描述符3连接到事件管道，事件在脚本运行期间逐行转发。
+// 脚本在独立进程组中运行，上下文结束时整个进程组先收到 SIGTERM，宽限期后收到 SIGKILL；
+// 因取消、超时或内存不足结束时返回 *JobError。
+func (c *ProcessClient) Run(ctx context.Context, script string, input []byte) ([]byte, error) {
+	cmd := exec.Command(c.pythonPath, "-c", script)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	stderr := newTailBuffer(pythonStderrTail)
+	cmd.Stderr = stderr
+
+	var eventsDone chan struct{}
+	handler := pythonEventsFrom(ctx)
+	if handler != nil {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, fmt.Errorf("创建事件管道失败: %v", err)
+		}
+		defer r.Close()
+		cmd.ExtraFiles = []*os.File{w}
+		cmd.Env = append(os.Environ(), PythonEventFDEnv+"=3")
+		eventsDone = make(chan struct{})
+		go func() {
+			defer close(eventsDone)
+			readPythonEvents(r, handler)
+		}()
+		defer w.Close()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, &JobError{Reason: JobTermination(err), Err: err}
+	}
+	proc, err := startJobProcess(cmd, c.limits)
+	if err != nil {
+		return nil, fmt.Errorf("执行Python脚本失败: %v", err)
+	}
+	if handler != nil {
+		// 关闭父进程持有的写端，子进程退出后事件读取才能结束
+		cmd.ExtraFiles[0].Close()
+	}
+	stopWatch := proc.watch(ctx)
+	err = proc.wait()
+	stopWatch()
+	if eventsDone != nil {
+		<-eventsDone
+	}
+	if err != nil {
+		return stdout.Bytes(), proc.classify(ctx, fmt.Errorf("执行Python脚本失败: %v, 错误输出: %s", err, stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+var (
+	defaultPythonMu     sync.RWMutex
+	defaultPythonClient Client
+)
+
+// SetDefaultPythonClient 设置未单独注入客户端的引擎共用的Python客户端，通常为常驻工作进程池
+func SetDefaultPythonClient(client Client) {
+	defaultPythonMu.Lock()
+	defer defaultPythonMu.Unlock()
+	defaultPythonClient = client
+}
+
+// resolvePythonClient 优先使用注入的客户端，其次是全局默认客户端，否则每次调用启动新进程
+func resolvePythonClient(client Client, pythonPath string) Client {
+	if client != nil {
+		return client
+	}
+	defaultPythonMu.RLock()
+	defer defaultPythonMu.RUnlock()
+	if defaultPythonClient != nil {
+		return defaultPythonClient
+	}
+	return NewProcessClient(pythonPath)
+}
+
+// runPythonScript 将参数以JSON写入脚本标准输入，解析脚本输出的 {"success", "error", "data"} 结果
+func runPythonScript(ctx context.Context, client Client, script string, args map[string]interface{}) (map[string]interface{}, error) {
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("序列化参数失败: %v", err)
+	}
+
+	output, err := client.Run(ctx, script, argsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("解析Python输出失败: %v", err)
+	}
+
+	if success, ok := result["success"].(bool); !ok || !success {
+		if errorMsg, ok := result["error"].(string); ok {
+			return nil, fmt.Errorf("Python脚本执行失败: %s", errorMsg)
+		}
+		return nil, fmt.Errorf("Python脚本执行失败")
+	}
+	return result, nil
+}
+
+// pythonStderrTail 错误信息中保留的Python标准错误输出长度
+const pythonStderrTail = 4096
+
+// tailBuffer 只保留最后 limit 字节的输出
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	buf   []byte
+}
+
+func newTailBuffer(limit int) *tailBuffer {
+	return &tailBuffer{limit: limit}
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.limit {
+		b.buf = append(b.buf[:0], b.buf[len(b.buf)-b.limit:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
@@ -0,0 +1,133 @@
+package qlib
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// PythonEventFDEnv 保存事件文件描述符编号的环境变量，脚本通过 emit_event 向该描述符写事件
+const PythonEventFDEnv = "QLIB_EVENT_FD"
+
+// Python作业事件类型
+const (
+	PythonEventProgress = "progress" // 进度，progress 为0-100
+	PythonEventMetric   = "metric"   // 中间指标，如每轮的IC和损失
+	PythonEventLog      = "log"      // 日志，level 为 INFO/WARNING/ERROR
+	PythonEventArtifact = "artifact" // 产出文件，如模型和报告
+	PythonEventError    = "error"    // 作业错误，最终结果仍以脚本输出为准
+)
+
+// PythonEvent Python脚本发送的结构化事件，每行一个JSON对象
+type PythonEvent struct {
+	Type     string             `json:"type"`
+	Progress int                `json:"progress,omitempty"`
+	Message  string             `json:"message,omitempty"`
+	Level    string             `json:"level,omitempty"`
+	Metrics  map[string]float64 `json:"metrics,omitempty"`
+	Name     string             `json:"name,omitempty"`
+	Path     string             `json:"path,omitempty"`
+	Time     time.Time          `json:"-"` // Go端收到事件的时间
+}
+
+// PythonEventHandler 事件处理函数，在读取事件的协程中按发送顺序调用，不应长时间阻塞
+type PythonEventHandler func(event PythonEvent)
+
+type pythonEventsKey struct{}
+
+// WithPythonEvents 返回携带事件处理函数的上下文，客户端执行脚本时把事件转发给它
+//
+// 上下文中已有处理函数时两者都会被调用，先调用已有的。
+func WithPythonEvents(ctx context.Context, handler PythonEventHandler) context.Context {
+	if prev := pythonEventsFrom(ctx); prev != nil {
+		next := handler
+		handler = func(event PythonEvent) {
+			prev(event)
+			next(event)
+		}
+	}
+	return context.WithValue(ctx, pythonEventsKey{}, handler)
+}
+
+func pythonEventsFrom(ctx context.Context) PythonEventHandler {
+	handler, _ := ctx.Value(pythonEventsKey{}).(PythonEventHandler)
+	return handler
+}
+
+// decodePythonEvent 解析一行事件，无法解析的内容作为日志事件
+func decodePythonEvent(line []byte) PythonEvent {
+	var event PythonEvent
+	if err := json.Unmarshal(line, &event); err != nil || event.Type == "" {
+		event = PythonEvent{Type: PythonEventLog, Level: "INFO", Message: string(line)}
+	}
+	event.Time = time.Now()
+	return event
+}
+
+// readPythonEvents 逐行读取事件直到 r 关闭
+func readPythonEvents(r io.Reader, handler PythonEventHandler) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = trimLine(line); len(line) > 0 {
+			handler(decodePythonEvent(line))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func trimLine(line []byte) []byte {
+	for len(line) > 0 && (line[len(line)-1] == '\n' || line[len(line)-1] == '\r') {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// progressCallbackEvents 将进度和指标事件转换为进度回调，指标事件沿用最近一次进度
+func progressCallbackEvents(callback func(progress int, metrics map[string]float64)) PythonEventHandler {
+	last := 0
+	return func(event PythonEvent) {
+		switch event.Type {
+		case PythonEventProgress:
+			last = event.Progress
+			callback(last, event.Metrics)
+		case PythonEventMetric:
+			if event.Progress > 0 {
+				last = event.Progress
+			}
+			callback(last, event.Metrics)
+		}
+	}
+}
+
+// pythonEventPrelude 拼接在脚本开头，提供 emit_event 辅助函数
+//
+// 没有事件通道时 emit_event 什么也不做，脚本仍可单独运行。
+const pythonEventPrelude = `
+import json as _event_json
+import math as _event_math
+import os as _event_os
+
+_EVENT_FD = _event_os.environ.get("QLIB_EVENT_FD")
+
+
+def emit_event(event_type, **fields):
+    """发送结构化事件：progress、metric、log、artifact、error"""
+    if not _EVENT_FD:
+        return
+    metrics = fields.get("metrics")
+    if metrics:
+        fields["metrics"] = {
+            k: float(v) for k, v in metrics.items()
+            if v is not None and _event_math.isfinite(float(v))
+        }
+    fields["type"] = event_type
+    try:
+        _event_os.write(int(_EVENT_FD), (_event_json.dumps(fields, default=str) + "\n").encode("utf-8"))
+    except OSError:
+        pass
+`
@@ -0,0 +1,128 @@
+package qlib
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+const eventScript = pythonEventPrelude + `
+import sys
+emit_event("progress", progress=10, message="start")
+emit_event("metric", metrics={"train_ic": 0.05, "loss": float("nan")})
+emit_event("log", level="WARNING", message="中文日志")
+emit_event("artifact", name="model", path="/tmp/model.pkl")
+emit_event("progress", progress=100)
+print("done")
+`
+
+func collectEvents(t *testing.T, client Client) ([]PythonEvent, string) {
+	t.Helper()
+	var events []PythonEvent
+	ctx := WithPythonEvents(context.Background(), func(event PythonEvent) {
+		events = append(events, event)
+	})
+	output, err := client.Run(ctx, eventScript, nil)
+	if err != nil {
+		t.Fatalf("执行脚本失败: %v", err)
+	}
+	return events, strings.TrimSpace(string(output))
+}
+
+func checkEvents(t *testing.T, events []PythonEvent) {
+	t.Helper()
+	if len(events) != 5 {
+		t.Fatalf("收到 %d 个事件，应为5个: %+v", len(events), events)
+	}
+	if events[0].Type != PythonEventProgress || events[0].Progress != 10 || events[0].Message != "start" {
+		t.Errorf("进度事件不符合预期: %+v", events[0])
+	}
+	if m := events[1].Metrics; events[1].Type != PythonEventMetric || m["train_ic"] != 0.05 || len(m) != 1 {
+		t.Errorf("指标事件应去掉无效值: %+v", events[1])
+	}
+	if events[2].Level != "WARNING" || events[2].Message != "中文日志" {
+		t.Errorf("日志事件不符合预期: %+v", events[2])
+	}
+	if events[3].Type != PythonEventArtifact || events[3].Path != "/tmp/model.pkl" {
+		t.Errorf("产出事件不符合预期: %+v", events[3])
+	}
+	if events[4].Progress != 100 || events[4].Time.IsZero() {
+		t.Errorf("进度事件不符合预期: %+v", events[4])
+	}
+}
+
+func TestProcessClientEvents(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("未安装python3")
+	}
+	client := NewProcessClient(python)
+
+	events, output := collectEvents(t, client)
+	if output != "done" {
+		t.Errorf("标准输出应只包含脚本输出，实际为 %q", output)
+	}
+	checkEvents(t, events)
+
+	// 没有事件处理函数时 emit_event 不输出任何内容
+	plain, err := client.Run(context.Background(), eventScript, nil)
+	if err != nil || strings.TrimSpace(string(plain)) != "done" {
+		t.Errorf("未订阅事件时输出不符合预期: %q, %v", plain, err)
+	}
+}
+
+func TestWorkerPoolEvents(t *testing.T) {
+	pool := newTestWorkerPool(t, WorkerPoolConfig{Size: 1})
+
+	for i := 0; i < 2; i++ {
+		events, output := collectEvents(t, pool)
+		if output != "done" {
+			t.Errorf("标准输出应只包含脚本输出，实际为 %q", output)
+		}
+		checkEvents(t, events)
+	}
+	plain, err := pool.Run(context.Background(), eventScript, nil)
+	if err != nil || strings.TrimSpace(string(plain)) != "done" {
+		t.Errorf("未订阅事件时输出不符合预期: %q, %v", plain, err)
+	}
+}
+
+func TestProgressCallbackEvents(t *testing.T) {
+	type call struct {
+		progress int
+		metrics  map[string]float64
+	}
+	var calls []call
+	handler := progressCallbackEvents(func(progress int, metrics map[string]float64) {
+		calls = append(calls, call{progress, metrics})
+	})
+	handler(PythonEvent{Type: PythonEventProgress, Progress: 30})
+	handler(PythonEvent{Type: PythonEventMetric, Metrics: map[string]float64{"valid_ic": 0.04}})
+	handler(PythonEvent{Type: PythonEventLog, Message: "ignored"})
+	handler(PythonEvent{Type: PythonEventMetric, Progress: 60, Metrics: map[string]float64{"valid_ic": 0.05}})
+
+	if len(calls) != 3 {
+		t.Fatalf("回调 %d 次，应为3次", len(calls))
+	}
+	if calls[1].progress != 30 || calls[1].metrics["valid_ic"] != 0.04 {
+		t.Errorf("指标事件应沿用最近的进度: %+v", calls[1])
+	}
+	if calls[2].progress != 60 {
+		t.Errorf("指标事件携带进度时应更新进度: %+v", calls[2])
+	}
+}
+
+func TestWithPythonEventsChains(t *testing.T) {
+	var order []string
+	ctx := WithPythonEvents(context.Background(), func(PythonEvent) { order = append(order, "outer") })
+	ctx = WithPythonEvents(ctx, func(PythonEvent) { order = append(order, "inner") })
+	pythonEventsFrom(ctx)(decodePythonEvent([]byte("plain text")))
+	if strings.Join(order, ",") != "outer,inner" {
+		t.Errorf("调用顺序为 %v", order)
+	}
+
+	if event := decodePythonEvent([]byte("plain text")); event.Type != PythonEventLog || event.Message != "plain text" {
+		t.Errorf("无法解析的行应作为日志: %+v", event)
+	}
+}
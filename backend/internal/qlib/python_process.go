@@ -0,0 +1,231 @@
+package qlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultKillGrace 取消作业时从 SIGTERM 到 SIGKILL 的默认等待时间
+const DefaultKillGrace = 10 * time.Second
+
+// ResourceLimits Python作业子进程的资源限制，零值表示不限制
+//
+// 进程组和 setrlimit 限制只在Linux上生效。设置 CgroupParent 时每个子进程直接在其下单独的cgroup v2子组中启动
+// （需要Linux 5.7以上），内存上限写入 memory.max，不再设置按虚拟地址空间计算的 RLIMIT_AS。
+type ResourceLimits struct {
+	MemoryBytes   uint64        // 内存上限
+	CPUSeconds    uint64        // CPU时间上限，超出后进程收到 SIGXCPU，记为超时
+	FileSizeBytes uint64        // 单个文件大小上限
+	CgroupParent  string        // cgroup v2 父目录，如 /sys/fs/cgroup/qlib.slice，需要对该目录有写权限
+	KillGrace     time.Duration // 取消后等待进程自行退出的时间，默认 DefaultKillGrace
+}
+
+func (l ResourceLimits) killGrace() time.Duration {
+	if l.KillGrace <= 0 {
+		return DefaultKillGrace
+	}
+	return l.KillGrace
+}
+
+var (
+	defaultLimitsMu sync.RWMutex
+	defaultLimits   ResourceLimits
+)
+
+// SetDefaultResourceLimits 设置 NewProcessClient 创建的客户端默认使用的资源限制
+func SetDefaultResourceLimits(limits ResourceLimits) {
+	defaultLimitsMu.Lock()
+	defer defaultLimitsMu.Unlock()
+	defaultLimits = limits
+}
+
+func defaultResourceLimits() ResourceLimits {
+	defaultLimitsMu.RLock()
+	defer defaultLimitsMu.RUnlock()
+	return defaultLimits
+}
+
+// Python作业的终止原因
+const (
+	JobCancelled = "cancelled"  // 调用方取消
+	JobTimedOut  = "timeout"    // 超过截止时间或CPU时间上限
+	JobOOMKilled = "oom_killed" // 超出内存上限
+)
+
+// JobError Python作业被取消、超时或因内存不足被终止
+type JobError struct {
+	Reason string
+	Err    error
+}
+
+func (e *JobError) Error() string {
+	switch e.Reason {
+	case JobCancelled:
+		return fmt.Sprintf("Python作业已取消: %v", e.Err)
+	case JobTimedOut:
+		return fmt.Sprintf("Python作业超时: %v", e.Err)
+	case JobOOMKilled:
+		return fmt.Sprintf("Python作业内存不足被终止: %v", e.Err)
+	}
+	return e.Err.Error()
+}
+
+func (e *JobError) Unwrap() error {
+	return e.Err
+}
+
+// JobTermination 返回错误对应的作业终止原因，普通失败时返回空字符串
+func JobTermination(err error) string {
+	var jobErr *JobError
+	if errors.As(err, &jobErr) {
+		return jobErr.Reason
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return JobCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		return JobTimedOut
+	}
+	return ""
+}
+
+// jobProcess 在独立进程组中运行的Python子进程
+//
+// 取消时向整个进程组发送 SIGTERM，等待 KillGrace 后发送 SIGKILL，脚本启动的子进程一并终止。
+type jobProcess struct {
+	cmd    *exec.Cmd
+	limits ResourceLimits
+	cgroup string // 子进程所在的cgroup目录，未使用cgroup时为空
+	done   chan struct{}
+
+	mu         sync.Mutex
+	terminated bool // 由取消、超时或调用方主动终止
+	waitErr    error
+	oomKilled  bool // cgroup记录了OOM终止
+}
+
+// startJobProcess 启动子进程并应用资源限制，应用失败时终止进程
+//
+// 设置 CgroupParent 时子进程直接在新建的cgroup中启动。setrlimit 限制只能在 cmd.Start 返回后通过 prlimit 设置，
+// 从 exec 到设置完成之间有极短的窗口（远短于Python解释器的启动时间）不受CPU时间和文件大小限制。
+func startJobProcess(cmd *exec.Cmd, limits ResourceLimits) (*jobProcess, error) {
+	setProcessGroup(cmd)
+	p := &jobProcess{cmd: cmd, limits: limits, done: make(chan struct{})}
+	if limits.CgroupParent != "" {
+		dir, closeCgroup, err := prepareCgroup(cmd, limits.CgroupParent, limits)
+		if err != nil {
+			return nil, fmt.Errorf("创建cgroup失败: %v", err)
+		}
+		defer closeCgroup()
+		p.cgroup = dir
+	}
+	if err := cmd.Start(); err != nil {
+		if p.cgroup != "" {
+			removeCgroup(p.cgroup)
+		}
+		return nil, err
+	}
+
+	if err := applyRlimits(cmd.Process.Pid, limits); err != nil {
+		signalProcessGroup(cmd.Process, true)
+		cmd.Wait()
+		if p.cgroup != "" {
+			removeCgroup(p.cgroup)
+		}
+		return nil, fmt.Errorf("设置资源限制失败: %v", err)
+	}
+	return p, nil
+}
+
+// wait 等待进程退出并删除cgroup，只能调用一次
+func (p *jobProcess) wait() error {
+	err := p.cmd.Wait()
+	oomKilled := false
+	if p.cgroup != "" {
+		oomKilled = cgroupOOMKilled(p.cgroup)
+		removeCgroup(p.cgroup)
+	}
+	p.mu.Lock()
+	p.waitErr, p.oomKilled = err, oomKilled
+	p.mu.Unlock()
+	close(p.done)
+	return err
+}
+
+// watch 上下文结束时终止进程，返回的函数在进程退出后调用以结束监视
+func (p *jobProcess) watch(ctx context.Context) func() {
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.terminate()
+		case <-stop:
+		}
+	}()
+	return func() { close(stop) }
+}
+
+// terminate 向进程组发送 SIGTERM，宽限期内未退出时发送 SIGKILL
+func (p *jobProcess) terminate() {
+	p.mu.Lock()
+	p.terminated = true
+	p.mu.Unlock()
+
+	signalProcessGroup(p.cmd.Process, false)
+	select {
+	case <-p.done:
+		// 主进程已退出，仍清理残留的子进程
+		signalProcessGroup(p.cmd.Process, true)
+	case <-time.After(p.limits.killGrace()):
+		log.Printf("Python进程 %d 未在 %v 内退出，强制终止", p.cmd.Process.Pid, p.limits.killGrace())
+		signalProcessGroup(p.cmd.Process, true)
+	}
+}
+
+// kill 立即终止整个进程组
+func (p *jobProcess) kill() {
+	p.mu.Lock()
+	p.terminated = true
+	p.mu.Unlock()
+	signalProcessGroup(p.cmd.Process, true)
+}
+
+// classify 根据上下文、退出信号和cgroup事件判断终止原因，包装为 JobError
+//
+// 进程仍在运行时（如工作进程中的脚本出错）只根据上下文和错误内容判断。
+func (p *jobProcess) classify(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch ctx.Err() {
+	case context.Canceled:
+		return &JobError{Reason: JobCancelled, Err: err}
+	case context.DeadlineExceeded:
+		return &JobError{Reason: JobTimedOut, Err: err}
+	}
+
+	p.mu.Lock()
+	terminated, waitErr, oomKilled := p.terminated, p.waitErr, p.oomKilled
+	p.mu.Unlock()
+	if oomKilled || strings.Contains(err.Error(), "MemoryError") {
+		return &JobError{Reason: JobOOMKilled, Err: err}
+	}
+	if sig, ok := exitSignal(waitErr); ok && !terminated {
+		switch sig {
+		case sigCPULimit:
+			return &JobError{Reason: JobTimedOut, Err: err}
+		case sigKill:
+			// 没有主动终止却收到 SIGKILL，通常是内核或cgroup的OOM killer
+			if p.limits.MemoryBytes > 0 || p.cgroup != "" {
+				return &JobError{Reason: JobOOMKilled, Err: err}
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+//go:build linux
+
+package qlib
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	sigCPULimit = syscall.SIGXCPU
+	sigKill     = syscall.SIGKILL
+)
+
+// setProcessGroup 子进程作为新进程组的组长启动，终止时可以向整个组发送信号
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// signalProcessGroup 向进程组发送 SIGTERM 或 SIGKILL
+func signalProcessGroup(process *os.Process, force bool) {
+	sig := syscall.SIGTERM
+	if force {
+		sig = syscall.SIGKILL
+	}
+	if err := syscall.Kill(-process.Pid, sig); err != nil && force {
+		process.Kill()
+	}
+}
+
+// applyRlimits 通过 prlimit 设置子进程的软硬限制，子进程无法再调高
+func applyRlimits(pid int, limits ResourceLimits) error {
+	set := func(resource int, cur, max uint64) error {
+		if cur == 0 {
+			return nil
+		}
+		return unix.Prlimit(pid, resource, &unix.Rlimit{Cur: cur, Max: max}, nil)
+	}
+	// 使用cgroup时内存由 memory.max 限制，RLIMIT_AS 会把预留的虚拟地址空间也计算在内
+	if limits.CgroupParent == "" {
+		if err := set(unix.RLIMIT_AS, limits.MemoryBytes, limits.MemoryBytes); err != nil {
+			return fmt.Errorf("RLIMIT_AS: %v", err)
+		}
+	}
+	// 软硬限制相同时内核直接发送 SIGKILL，硬限制多留一秒以便先收到 SIGXCPU
+	if err := set(unix.RLIMIT_CPU, limits.CPUSeconds, limits.CPUSeconds+1); err != nil {
+		return fmt.Errorf("RLIMIT_CPU: %v", err)
+	}
+	if err := set(unix.RLIMIT_FSIZE, limits.FileSizeBytes, limits.FileSizeBytes); err != nil {
+		return fmt.Errorf("RLIMIT_FSIZE: %v", err)
+	}
+	return nil
+}
+
+// exitSignal 返回终止进程的信号
+func exitSignal(err error) (syscall.Signal, bool) {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return 0, false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return 0, false
+	}
+	return status.Signal(), true
+}
+
+// prepareCgroup 在父目录下为作业创建cgroup v2子组并设置内存上限，
+// 子进程通过 clone3(CLONE_INTO_CGROUP) 直接在其中启动（需要Linux 5.7以上），启动前派生的进程也受限制。
+// 返回的函数在 cmd.Start 之后调用，关闭cgroup目录句柄。
+func prepareCgroup(cmd *exec.Cmd, parent string, limits ResourceLimits) (string, func(), error) {
+	dir, err := os.MkdirTemp(parent, "qlib-job-")
+	if err != nil {
+		return "", nil, err
+	}
+	if limits.MemoryBytes > 0 {
+		if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(strconv.FormatUint(limits.MemoryBytes, 10)), 0644); err != nil {
+			os.Remove(dir)
+			return "", nil, err
+		}
+	}
+	fd, err := os.Open(dir)
+	if err != nil {
+		os.Remove(dir)
+		return "", nil, err
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(fd.Fd())
+	return dir, func() { fd.Close() }, nil
+}
+
+// cgroupOOMKilled 读取 memory.events 判断组内是否有进程被OOM终止
+func cgroupOOMKilled(dir string) bool {
+	file, err := os.Open(filepath.Join(dir, "memory.events"))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			count, _ := strconv.Atoi(fields[1])
+			return count > 0
+		}
+	}
+	return false
+}
+
+// removeCgroup 删除空的cgroup，组内仍有进程时保留
+func removeCgroup(dir string) {
+	os.Remove(dir)
+}
@@ -0,0 +1,224 @@
+//go:build linux
+
+package qlib
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lookupPython(t *testing.T) string {
+	t.Helper()
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("未安装python3")
+	}
+	return python
+}
+
+// processAlive 进程存在且不是僵尸进程
+func processAlive(pid int) bool {
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z" && fields[0] != "X"
+}
+
+// spawnScript 启动一个子进程后阻塞，子进程PID写入 pidFile；ignoreTerm 时忽略 SIGTERM
+func spawnScript(pidFile string, ignoreTerm bool) string {
+	script := `
+import signal, subprocess, time
+child = subprocess.Popen(["sleep", "60"])
+with open(` + strconv.Quote(pidFile) + `, "w") as f:
+    f.write(str(child.pid))
+`
+	if ignoreTerm {
+		script += "signal.signal(signal.SIGTERM, signal.SIG_IGN)\n"
+	}
+	return script + "time.sleep(60)\n"
+}
+
+func waitForPid(t *testing.T, pidFile string) int {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(pidFile); err == nil && len(data) > 0 {
+			pid, err := strconv.Atoi(string(data))
+			if err == nil {
+				return pid
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("脚本未启动子进程")
+	return 0
+}
+
+func waitProcessGone(t *testing.T, pid int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("子进程 %d 未被终止", pid)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestProcessClientCancelKillsProcessGroup(t *testing.T) {
+	python := lookupPython(t)
+	for _, ignoreTerm := range []bool{false, true} {
+		pidFile := filepath.Join(t.TempDir(), "child.pid")
+		client := NewProcessClient(python)
+		client.SetResourceLimits(ResourceLimits{KillGrace: 300 * time.Millisecond})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			_, err := client.Run(ctx, spawnScript(pidFile, ignoreTerm), nil)
+			done <- err
+		}()
+		child := waitForPid(t, pidFile)
+		time.Sleep(100 * time.Millisecond) // 等待脚本设置信号处理
+		start := time.Now()
+		cancel()
+
+		select {
+		case err := <-done:
+			if JobTermination(err) != JobCancelled {
+				t.Errorf("终止原因应为取消: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("取消后脚本未结束")
+		}
+		if ignoreTerm && time.Since(start) < 300*time.Millisecond {
+			t.Errorf("忽略 SIGTERM 的脚本应在宽限期后才被终止")
+		}
+		waitProcessGone(t, child)
+	}
+}
+
+func TestProcessClientTerminationReasons(t *testing.T) {
+	python := lookupPython(t)
+
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	_, err := NewProcessClient(python).Run(timeoutCtx, "import time\ntime.sleep(30)", nil)
+	if JobTermination(err) != JobTimedOut {
+		t.Errorf("超过截止时间应记为超时: %v", err)
+	}
+
+	ctx := context.Background()
+	client := NewProcessClient(python)
+	client.SetResourceLimits(ResourceLimits{MemoryBytes: 256 << 20})
+	_, err = client.Run(ctx, "data = bytearray(1024 * 1024 * 1024)", nil)
+	if JobTermination(err) != JobOOMKilled {
+		t.Errorf("超出内存上限应记为内存不足: %v", err)
+	}
+	if output, err := client.Run(ctx, "print(len(bytearray(16 * 1024 * 1024)))", nil); err != nil || strings.TrimSpace(string(output)) != "16777216" {
+		t.Errorf("内存上限内的脚本应正常执行: %q, %v", output, err)
+	}
+
+	client.SetResourceLimits(ResourceLimits{CPUSeconds: 1})
+	_, err = client.Run(ctx, "while True:\n    pass", nil)
+	if JobTermination(err) != JobTimedOut {
+		t.Errorf("超出CPU时间应记为超时: %v", err)
+	}
+
+	client.SetResourceLimits(ResourceLimits{FileSizeBytes: 1024})
+	path := filepath.Join(t.TempDir(), "big.bin")
+	_, err = client.Run(ctx, "with open("+strconv.Quote(path)+", 'wb') as f:\n    for _ in range(4):\n        f.write(b'x' * 1024)\n        f.flush()", nil)
+	if err == nil || JobTermination(err) != "" {
+		t.Errorf("超出文件大小应为普通失败: %v", err)
+	}
+	if info, statErr := os.Stat(path); statErr == nil && info.Size() > 1024 {
+		t.Errorf("文件大小超过上限: %d", info.Size())
+	}
+
+	_, err = NewProcessClient(python).Run(ctx, "raise SystemExit(3)", nil)
+	if err == nil || JobTermination(err) != "" {
+		t.Errorf("脚本错误不应有终止原因: %v", err)
+	}
+}
+
+func TestWorkerPoolCancelTerminatesWorker(t *testing.T) {
+	pool := newTestWorkerPool(t, WorkerPoolConfig{Size: 1, Limits: ResourceLimits{KillGrace: 200 * time.Millisecond}})
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := pool.Run(ctx, spawnScript(pidFile, true), nil)
+		done <- err
+	}()
+	child := waitForPid(t, pidFile)
+	cancel()
+	select {
+	case err := <-done:
+		if JobTermination(err) != JobCancelled {
+			t.Errorf("终止原因应为取消: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("取消后请求未结束")
+	}
+	waitProcessGone(t, child)
+
+	if stats := pool.Stats(); stats.Failed != 1 {
+		t.Errorf("被取消的工作进程应计入失败: %+v", stats)
+	}
+	if output, err := pool.Run(context.Background(), "print('ok')", nil); err != nil || strings.TrimSpace(string(output)) != "ok" {
+		t.Errorf("取消后应重新启动工作进程: %q, %v", output, err)
+	}
+}
+
+// cgroupV2Parent 返回可写的cgroup v2挂载点下的临时父目录，不可用时跳过测试
+func cgroupV2Parent(t *testing.T) string {
+	t.Helper()
+	mounts, err := os.ReadFile("/proc/self/mounts")
+	if err != nil {
+		t.Skip("无法读取挂载信息")
+	}
+	for _, line := range strings.Split(string(mounts), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[2] != "cgroup2" {
+			continue
+		}
+		parent, err := os.MkdirTemp(fields[1], "qlib-test-")
+		if err != nil {
+			continue
+		}
+		t.Cleanup(func() { os.Remove(parent) })
+		return parent
+	}
+	t.Skip("没有可写的cgroup v2")
+	return ""
+}
+
+func TestJobProcessStartsInCgroup(t *testing.T) {
+	parent := cgroupV2Parent(t)
+	// 第一条指令就读取自身所在的cgroup，启动后再加入的实现会读到父进程的cgroup
+	cmd := exec.Command("cat", "/proc/self/cgroup")
+	var out strings.Builder
+	cmd.Stdout = &out
+	p, err := startJobProcess(cmd, ResourceLimits{CgroupParent: parent})
+	if err != nil {
+		t.Fatalf("启动失败: %v", err)
+	}
+	if err := p.wait(); err != nil {
+		t.Fatalf("进程退出失败: %v", err)
+	}
+	if !strings.Contains(out.String(), "/"+filepath.Base(parent)+"/qlib-job-") {
+		t.Errorf("进程应直接在作业cgroup中启动，实际为 %q", out.String())
+	}
+	if jobs, _ := filepath.Glob(filepath.Join(parent, "qlib-job-*")); len(jobs) != 0 {
+		t.Errorf("作业结束后cgroup应被删除，剩余 %v", jobs)
+	}
+}
@@ -0,0 +1,40 @@
+//go:build !linux
+
+package qlib
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// 非Linux平台不区分进程组和信号，取消时直接终止进程
+const (
+	sigCPULimit = syscall.Signal(-1)
+	sigKill     = syscall.Signal(-2)
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func signalProcessGroup(process *os.Process, force bool) {
+	process.Kill()
+}
+
+func applyRlimits(pid int, limits ResourceLimits) error {
+	return nil
+}
+
+func exitSignal(err error) (syscall.Signal, bool) {
+	return 0, false
+}
+
+func prepareCgroup(cmd *exec.Cmd, parent string, limits ResourceLimits) (string, func(), error) {
+	return "", nil, fmt.Errorf("cgroup仅支持Linux")
+}
+
+func cgroupOOMKilled(dir string) bool {
+	return false
+}
+
+func removeCgroup(dir string) {}
@@ -0,0 +1,603 @@
+package qlib
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 工作进程池默认配置
+const (
+	DefaultPythonWorkers        = 2
+	DefaultWorkerMaxRequests    = 200
+	DefaultWorkerRequestTimeout = 10 * time.Minute
+	DefaultWorkerStartTimeout   = 2 * time.Minute
+	DefaultWorkerHealthCheck    = 30 * time.Second
+
+	workerPingTimeout = 10 * time.Second
+	workerStopTimeout = 5 * time.Second
+)
+
+// ErrWorkerPoolClosed 工作进程池已关闭
+var ErrWorkerPoolClosed = errors.New("Python工作进程池已关闭")
+
+// WorkerPoolConfig Python工作进程池配置
+type WorkerPoolConfig struct {
+	PythonPath string
+	Size       int // 常驻进程数，默认2
+	// 单个进程处理的最大请求数，达到后回收重启以释放内存，默认200，负数表示不回收
+	MaxRequests int
+	// 调用方未设置截止时间时的请求超时，默认10分钟；超时的进程会被终止
+	RequestTimeout time.Duration
+	StartTimeout   time.Duration // 进程启动及qlib.init的超时，默认2分钟
+	// 空闲进程健康检查间隔，默认30秒，负数关闭；检查时同时补齐退出的进程
+	HealthCheckInterval time.Duration
+	// qlib.init 的参数，每个进程启动时调用一次；为 nil 时不初始化qlib
+	Init map[string]interface{}
+	Env  []string // 追加的环境变量
+	// 每个工作进程的资源限制，请求被取消时按 KillGrace 终止进程组；
+	// CPU时间按进程累计，工作进程不设置，单次请求由截止时间限制
+	Limits ResourceLimits
+}
+
+func (c WorkerPoolConfig) withDefaults() WorkerPoolConfig {
+	if c.PythonPath == "" {
+		c.PythonPath = "python3"
+	}
+	if c.Size <= 0 {
+		c.Size = DefaultPythonWorkers
+	}
+	if c.MaxRequests == 0 {
+		c.MaxRequests = DefaultWorkerMaxRequests
+	}
+	if c.RequestTimeout <= 0 {
+		c.RequestTimeout = DefaultWorkerRequestTimeout
+	}
+	if c.StartTimeout <= 0 {
+		c.StartTimeout = DefaultWorkerStartTimeout
+	}
+	if c.HealthCheckInterval == 0 {
+		c.HealthCheckInterval = DefaultWorkerHealthCheck
+	}
+	return c
+}
+
+// WorkerPoolStats 工作进程池运行统计
+type WorkerPoolStats struct {
+	Size     int   `json:"size"`
+	Started  int64 `json:"started"`  // 累计启动的进程数
+	Recycled int64 `json:"recycled"` // 达到最大请求数被回收的进程数
+	Failed   int64 `json:"failed"`   // 崩溃、超时或健康检查失败被终止的进程数
+	Requests int64 `json:"requests"`
+}
+
+// WorkerPool 常驻Python工作进程池
+//
+// 每个进程启动后执行一次 qlib.init，之后通过标准输入输出上按行分隔的JSON-RPC 2.0消息
+// 接收脚本并返回脚本的标准输出，避免每次调用重新导入qlib和pandas。
+// 进程按需启动；崩溃、超时或健康检查失败的进程被终止，由下一次调用或健康检查重新启动。
+type WorkerPool struct {
+	cfg   WorkerPoolConfig
+	slots chan *pythonWorker // 空闲槽位，nil 表示该槽位尚无进程
+	done  chan struct{}
+	once  sync.Once
+
+	started, recycled, failed, requests int64
+}
+
+// NewWorkerPool 创建Python工作进程池，进程在首次调用或首次健康检查时启动
+func NewWorkerPool(cfg WorkerPoolConfig) *WorkerPool {
+	cfg = cfg.withDefaults()
+	p := &WorkerPool{
+		cfg:   cfg,
+		slots: make(chan *pythonWorker, cfg.Size),
+		done:  make(chan struct{}),
+	}
+	for i := 0; i < cfg.Size; i++ {
+		p.slots <- nil
+	}
+	if cfg.HealthCheckInterval > 0 {
+		go p.healthLoop()
+	}
+	return p
+}
+
+// Run 在空闲工作进程中执行脚本，没有空闲进程时等待
+func (p *WorkerPool) Run(ctx context.Context, script string, input []byte) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.cfg.RequestTimeout)
+		defer cancel()
+	}
+
+	w, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt64(&p.requests, 1)
+	w.setEventHandler(pythonEventsFrom(ctx))
+	raw, err := w.call(ctx, "exec", map[string]interface{}{"script": script, "input": string(input)})
+	w.setEventHandler(nil)
+	w.requests++
+	p.release(w)
+	if err != nil {
+		return nil, w.proc.classify(ctx, fmt.Errorf("执行Python脚本失败: %v", err))
+	}
+
+	var result struct {
+		Output   string `json:"output"`
+		ExitCode int    `json:"exit_code"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("解析工作进程响应失败: %v", err)
+	}
+	if result.ExitCode != 0 {
+		return []byte(result.Output), fmt.Errorf("执行Python脚本失败: 退出码 %d", result.ExitCode)
+	}
+	return []byte(result.Output), nil
+}
+
+// Stats 返回运行统计
+func (p *WorkerPool) Stats() WorkerPoolStats {
+	return WorkerPoolStats{
+		Size:     p.cfg.Size,
+		Started:  atomic.LoadInt64(&p.started),
+		Recycled: atomic.LoadInt64(&p.recycled),
+		Failed:   atomic.LoadInt64(&p.failed),
+		Requests: atomic.LoadInt64(&p.requests),
+	}
+}
+
+// Close 关闭进程池，等待执行中的请求结束后停止全部进程
+func (p *WorkerPool) Close() error {
+	p.once.Do(func() {
+		close(p.done)
+		for i := 0; i < p.cfg.Size; i++ {
+			if w := <-p.slots; w != nil {
+				w.stop()
+			}
+		}
+	})
+	return nil
+}
+
+func (p *WorkerPool) closed() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// acquire 取得一个可用进程，槽位为空时启动新进程
+func (p *WorkerPool) acquire(ctx context.Context) (*pythonWorker, error) {
+	if p.closed() {
+		return nil, ErrWorkerPoolClosed
+	}
+	select {
+	case w := <-p.slots:
+		if p.closed() {
+			p.slots <- w
+			return nil, ErrWorkerPoolClosed
+		}
+		if w != nil && !w.broken {
+			return w, nil
+		}
+		started, err := p.startWorker(ctx)
+		if err != nil {
+			p.slots <- nil
+			return nil, err
+		}
+		return started, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.done:
+		return nil, ErrWorkerPoolClosed
+	}
+}
+
+// release 归还进程，故障进程被终止，达到最大请求数的进程被回收
+func (p *WorkerPool) release(w *pythonWorker) {
+	switch {
+	case w.broken:
+		atomic.AddInt64(&p.failed, 1)
+		w.kill()
+		p.slots <- nil
+	case p.cfg.MaxRequests > 0 && w.requests >= p.cfg.MaxRequests:
+		atomic.AddInt64(&p.recycled, 1)
+		go w.stop()
+		p.slots <- nil
+	default:
+		p.slots <- w
+	}
+}
+
+// healthLoop 定期检查空闲进程，终止无响应的进程并补齐空槽位
+func (p *WorkerPool) healthLoop() {
+	ticker := time.NewTicker(p.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			for i := 0; i < p.cfg.Size; i++ {
+				select {
+				case w := <-p.slots:
+					p.slots <- p.checkWorker(w)
+				default:
+					// 其余进程正在处理请求
+				}
+			}
+		}
+	}
+}
+
+func (p *WorkerPool) checkWorker(w *pythonWorker) *pythonWorker {
+	if p.closed() {
+		return w
+	}
+	if w != nil && !w.broken {
+		ctx, cancel := context.WithTimeout(context.Background(), workerPingTimeout)
+		_, err := w.call(ctx, "ping", nil)
+		cancel()
+		if err == nil {
+			return w
+		}
+		log.Printf("Python工作进程健康检查失败: %v", err)
+	}
+	if w != nil {
+		atomic.AddInt64(&p.failed, 1)
+		w.kill()
+	}
+
+	started, err := p.startWorker(context.Background())
+	if err != nil {
+		log.Printf("启动Python工作进程失败: %v", err)
+		return nil
+	}
+	return started
+}
+
+// startWorker 启动进程并完成qlib初始化
+func (p *WorkerPool) startWorker(ctx context.Context) (*pythonWorker, error) {
+	cmd := exec.Command(p.cfg.PythonPath, "-c", pythonWorkerScript)
+	cmd.Env = append(os.Environ(), p.cfg.Env...)
+	limits := p.cfg.Limits
+	limits.CPUSeconds = 0
+	w, err := startPythonWorker(cmd, limits)
+	if err != nil {
+		return nil, fmt.Errorf("启动Python工作进程失败: %v", err)
+	}
+	atomic.AddInt64(&p.started, 1)
+
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.StartTimeout)
+	defer cancel()
+	method, params := "ping", map[string]interface{}(nil)
+	if p.cfg.Init != nil {
+		method, params = "init", p.cfg.Init
+	}
+	raw, err := w.call(ctx, method, params)
+	if err != nil {
+		w.kill()
+		return nil, fmt.Errorf("初始化Python工作进程失败: %v", err)
+	}
+	if p.cfg.Init != nil {
+		var status struct {
+			Initialized bool   `json:"initialized"`
+			Error       string `json:"error"`
+		}
+		if json.Unmarshal(raw, &status) == nil && !status.Initialized {
+			// 脚本自行处理qlib不可用的情况，进程仍可执行脚本
+			log.Printf("警告：Python工作进程初始化qlib失败: %s", status.Error)
+		}
+	}
+	return w, nil
+}
+
+// pythonWorker 单个工作进程，同一时间只处理一个请求
+type pythonWorker struct {
+	proc      *jobProcess
+	stdin     io.WriteCloser
+	responses chan rpcResponse // 进程标准输出关闭时关闭
+	exited    chan struct{}
+	killed    chan struct{}
+	killOnce  sync.Once
+	stderr    *tailBuffer
+
+	eventsMu sync.Mutex
+	events   PythonEventHandler // 当前请求的事件处理函数
+
+	nextID   int64
+	requests int
+	broken   bool // 进程已崩溃、超时或协议错误，不能再使用
+}
+
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int64       `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data"`
+}
+
+func (e *rpcError) Error() string {
+	if e.Data != "" {
+		return fmt.Sprintf("%s\n%s", e.Message, e.Data)
+	}
+	return e.Message
+}
+
+func startPythonWorker(cmd *exec.Cmd, limits ResourceLimits) (*pythonWorker, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	w := &pythonWorker{
+		stdin:     stdin,
+		responses: make(chan rpcResponse, 1),
+		exited:    make(chan struct{}),
+		killed:    make(chan struct{}),
+		stderr:    newTailBuffer(pythonStderrTail),
+	}
+	cmd.Stderr = w.stderr
+	proc, err := startJobProcess(cmd, limits)
+	if err != nil {
+		return nil, err
+	}
+	w.proc = proc
+
+	go func() {
+		reader := bufio.NewReader(stdout)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				w.dispatch(line)
+			}
+			if err != nil {
+				break
+			}
+		}
+		close(w.responses)
+		proc.wait()
+		close(w.exited)
+	}()
+	return w, nil
+}
+
+// dispatch 分发工作进程输出的一行：JSON-RPC响应交给等待中的请求，其余为脚本通过 emit_event 发送的事件
+//
+// 事件在读取协程中同步处理，因此一个请求的全部事件都先于其响应处理完。
+func (w *pythonWorker) dispatch(line []byte) {
+	var resp rpcResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		resp = rpcResponse{ID: -1, Error: &rpcError{Message: fmt.Sprintf("无效的工作进程响应: %v", err)}}
+	} else if resp.JSONRPC == "" {
+		if handler := w.eventHandler(); handler != nil {
+			handler(decodePythonEvent(trimLine(line)))
+		}
+		return
+	}
+	select {
+	case w.responses <- resp:
+	case <-w.killed:
+		// 进程已被终止，丢弃迟到的响应
+	}
+}
+
+// call 发送一个请求并等待响应；进程故障或超时时将进程标记为不可用
+func (w *pythonWorker) call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	w.nextID++
+	data, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: w.nextID, Method: method, Params: params})
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+	if _, err := w.stdin.Write(append(data, '\n')); err != nil {
+		w.broken = true
+		return nil, fmt.Errorf("工作进程已退出: %v, 错误输出: %s", err, w.stderr.String())
+	}
+
+	select {
+	case resp, ok := <-w.responses:
+		switch {
+		case !ok:
+			w.broken = true
+			// 等待标准错误读取完毕，错误信息中才有崩溃原因
+			select {
+			case <-w.exited:
+			case <-time.After(time.Second):
+			}
+			return nil, fmt.Errorf("工作进程异常退出, 错误输出: %s", w.stderr.String())
+		case resp.ID != w.nextID:
+			w.broken = true
+			if resp.Error != nil {
+				return nil, resp.Error
+			}
+			return nil, fmt.Errorf("工作进程响应ID不匹配: %d", resp.ID)
+		case resp.Error != nil:
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	case <-ctx.Done():
+		// Python无法安全地中断正在执行的脚本，只能终止进程组
+		w.terminate()
+		return nil, ctx.Err()
+	}
+}
+
+func (w *pythonWorker) setEventHandler(handler PythonEventHandler) {
+	w.eventsMu.Lock()
+	defer w.eventsMu.Unlock()
+	w.events = handler
+}
+
+func (w *pythonWorker) eventHandler() PythonEventHandler {
+	w.eventsMu.Lock()
+	defer w.eventsMu.Unlock()
+	return w.events
+}
+
+// stop 请求进程退出，超时后强制终止
+func (w *pythonWorker) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), workerStopTimeout)
+	defer cancel()
+	w.call(ctx, "shutdown", nil)
+	w.stdin.Close()
+	select {
+	case <-w.exited:
+	case <-ctx.Done():
+		w.kill()
+	}
+}
+
+func (w *pythonWorker) kill() {
+	w.broken = true
+	w.killOnce.Do(func() {
+		close(w.killed)
+		w.stdin.Close()
+		w.proc.kill()
+	})
+}
+
+// terminate 向进程组发送 SIGTERM，宽限期后发送 SIGKILL，进程退出后返回
+func (w *pythonWorker) terminate() {
+	w.broken = true
+	w.killOnce.Do(func() {
+		close(w.killed)
+		w.stdin.Close()
+		w.proc.terminate()
+	})
+}
+
+// pythonWorkerScript 工作进程主循环
+//
+// 协议使用复制出的原标准输出，文件描述符1重定向到标准错误，sys.stdout 在执行脚本时指向输出缓冲区，
+// 避免导入库或扩展模块的打印内容混入协议。初始化后 qlib.init 被替换为空操作，脚本中的初始化调用不再重复执行。
+const pythonWorkerScript = `
+import io
+import json
+import os
+import sys
+import traceback
+
+_proto_in = io.TextIOWrapper(sys.stdin.buffer, encoding="utf-8")
+_proto_fd = os.dup(1)
+_proto_out = os.fdopen(_proto_fd, "w", encoding="utf-8")
+os.dup2(2, 1)
+# 脚本的 emit_event 直接写协议通道，事件总是先于该请求的响应
+os.environ["QLIB_EVENT_FD"] = str(_proto_fd)
+sys.stdout = sys.stderr
+sys.stdin = io.StringIO("")
+
+_compiled = {}
+_COMPILED_LIMIT = 64
+
+
+def _reply(rid, result=None, error=None):
+    message = {"jsonrpc": "2.0", "id": rid}
+    if error is not None:
+        message["error"] = error
+    else:
+        message["result"] = result
+    _proto_out.write(json.dumps(message, default=str) + "\n")
+    _proto_out.flush()
+
+
+def _init(params):
+    try:
+        import qlib
+        qlib.init(**params)
+    except Exception as e:
+        return {"initialized": False, "error": str(e)}
+    qlib.init = lambda *args, **kwargs: None
+    return {"initialized": True}
+
+
+def _exec(params):
+    script = params.get("script", "")
+    code = _compiled.get(script)
+    if code is None:
+        code = compile(script, "<qlib-script>", "exec")
+        if len(_compiled) < _COMPILED_LIMIT:
+            _compiled[script] = code
+
+    output = io.StringIO()
+    sys.stdin = io.StringIO(params.get("input", ""))
+    sys.stdout = output
+    exit_code = 0
+    try:
+        exec(code, {"__name__": "__main__", "__builtins__": __builtins__})
+    except SystemExit as e:
+        if e.code is None:
+            exit_code = 0
+        elif isinstance(e.code, int):
+            exit_code = e.code
+        else:
+            print(e.code, file=sys.stderr)
+            exit_code = 1
+    finally:
+        sys.stdout = sys.stderr
+        sys.stdin = io.StringIO("")
+    return {"output": output.getvalue(), "exit_code": exit_code}
+
+
+def main():
+    while True:
+        line = _proto_in.readline()
+        if not line:
+            break
+        line = line.strip()
+        if not line:
+            continue
+        rid = None
+        try:
+            request = json.loads(line)
+            rid = request.get("id")
+            method = request.get("method")
+            params = request.get("params") or {}
+            if method == "ping":
+                result = {"pong": True}
+            elif method == "init":
+                result = _init(params)
+            elif method == "exec":
+                result = _exec(params)
+            elif method == "shutdown":
+                _reply(rid, {})
+                break
+            else:
+                _reply(rid, error={"code": -32601, "message": "Method not found: %s" % method})
+                continue
+        except Exception as e:
+            _reply(rid, error={"code": -32000, "message": str(e), "data": traceback.format_exc()})
+            continue
+        _reply(rid, result)
+
+
+main()
+`
@@ -0,0 +1,187 @@
+package qlib
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+const pidScript = `
+import os
+import sys
+print(os.getpid(), sys.stdin.read())
+`
+
+func newTestWorkerPool(t *testing.T, cfg WorkerPoolConfig) *WorkerPool {
+	t.Helper()
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("未安装python3")
+	}
+	cfg.PythonPath = python
+	if cfg.HealthCheckInterval == 0 {
+		cfg.HealthCheckInterval = -1
+	}
+	pool := NewWorkerPool(cfg)
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func runPid(t *testing.T, pool *WorkerPool, input string) string {
+	t.Helper()
+	output, err := pool.Run(context.Background(), pidScript, []byte(input))
+	if err != nil {
+		t.Fatalf("执行脚本失败: %v", err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 || fields[1] != input {
+		t.Fatalf("脚本输出 %q 不符合预期", output)
+	}
+	return fields[0]
+}
+
+func TestWorkerPoolReusesProcess(t *testing.T) {
+	pool := newTestWorkerPool(t, WorkerPoolConfig{Size: 1})
+
+	first := runPid(t, pool, "a")
+	if second := runPid(t, pool, "b"); second != first {
+		t.Errorf("第二次调用应复用进程 %s，实际为 %s", first, second)
+	}
+	if stats := pool.Stats(); stats.Started != 1 || stats.Requests != 2 {
+		t.Errorf("统计不符合预期: %+v", stats)
+	}
+}
+
+func TestWorkerPoolRecyclesAfterMaxRequests(t *testing.T) {
+	pool := newTestWorkerPool(t, WorkerPoolConfig{Size: 1, MaxRequests: 2})
+
+	first := runPid(t, pool, "a")
+	if second := runPid(t, pool, "b"); second != first {
+		t.Fatalf("未达到最大请求数前不应重启进程")
+	}
+	if third := runPid(t, pool, "c"); third == first {
+		t.Errorf("达到最大请求数后应启动新进程")
+	}
+	if stats := pool.Stats(); stats.Recycled != 1 || stats.Started != 2 {
+		t.Errorf("统计不符合预期: %+v", stats)
+	}
+}
+
+func TestWorkerPoolDeadlineKillsWorker(t *testing.T) {
+	pool := newTestWorkerPool(t, WorkerPoolConfig{Size: 1})
+	first := runPid(t, pool, "a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := pool.Run(ctx, "import time\ntime.sleep(30)", nil)
+	if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Fatalf("应返回超时错误，实际为 %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("超时后应立即返回，实际耗时 %v", elapsed)
+	}
+	if next := runPid(t, pool, "b"); next == first {
+		t.Errorf("超时的进程应被终止并重新启动")
+	}
+	if stats := pool.Stats(); stats.Failed != 1 {
+		t.Errorf("统计不符合预期: %+v", stats)
+	}
+}
+
+func TestWorkerPoolRestartsCrashedWorker(t *testing.T) {
+	pool := newTestWorkerPool(t, WorkerPoolConfig{Size: 1})
+	first := runPid(t, pool, "a")
+
+	if _, err := pool.Run(context.Background(), "import os, sys\nsys.stderr.write('boom')\nsys.stderr.flush()\nos._exit(3)", nil); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("进程崩溃应返回包含错误输出的错误，实际为 %v", err)
+	}
+	if next := runPid(t, pool, "b"); next == first {
+		t.Errorf("崩溃后应启动新进程")
+	}
+}
+
+func TestWorkerPoolScriptErrorsKeepWorker(t *testing.T) {
+	pool := newTestWorkerPool(t, WorkerPoolConfig{Size: 1})
+	first := runPid(t, pool, "a")
+
+	if _, err := pool.Run(context.Background(), "raise ValueError('bad factor')", nil); err == nil || !strings.Contains(err.Error(), "bad factor") {
+		t.Fatalf("脚本异常应返回异常信息，实际为 %v", err)
+	}
+	output, err := pool.Run(context.Background(), "import sys\nprint('partial')\nsys.exit(2)", nil)
+	if err == nil || strings.TrimSpace(string(output)) != "partial" {
+		t.Fatalf("非0退出应返回错误和已输出内容，实际为 %q, %v", output, err)
+	}
+	if _, err := pool.Run(context.Background(), "def f(:\n", nil); err == nil {
+		t.Fatalf("语法错误应返回错误")
+	}
+	if next := runPid(t, pool, "b"); next != first {
+		t.Errorf("脚本错误不应终止进程")
+	}
+}
+
+func TestWorkerPoolIsolatesProtocolOutput(t *testing.T) {
+	// qlib初始化失败和直接写原标准输出都不能破坏协议
+	pool := newTestWorkerPool(t, WorkerPoolConfig{Size: 1, Init: map[string]interface{}{"provider_uri": "/nonexistent"}})
+	output, err := pool.Run(context.Background(), "import sys\nprint('x' * 100000)\nsys.__stdout__.write('leak\\n')\nprint('\\n\\n{\"id\": 99}')", nil)
+	if err != nil {
+		t.Fatalf("执行脚本失败: %v", err)
+	}
+	if !strings.Contains(string(output), `{"id": 99}`) || len(output) < 100000 {
+		t.Errorf("脚本输出不完整: %d 字节", len(output))
+	}
+	runPid(t, pool, "a")
+}
+
+func TestWorkerPoolHealthCheckReplacesDeadWorker(t *testing.T) {
+	pool := newTestWorkerPool(t, WorkerPoolConfig{Size: 1, HealthCheckInterval: 50 * time.Millisecond})
+	first := runPid(t, pool, "a")
+
+	// 空闲进程意外退出后由健康检查补齐
+	kill := exec.Command("kill", "-9", first)
+	if err := kill.Run(); err != nil {
+		t.Skipf("无法终止进程: %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for pool.Stats().Started < 2 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if stats := pool.Stats(); stats.Started < 2 || stats.Failed < 1 {
+		t.Fatalf("健康检查应重启退出的进程: %+v", stats)
+	}
+	if next := runPid(t, pool, "b"); next == first {
+		t.Errorf("应使用新进程执行请求")
+	}
+}
+
+func TestWorkerPoolClose(t *testing.T) {
+	pool := newTestWorkerPool(t, WorkerPoolConfig{Size: 2})
+	runPid(t, pool, "a")
+	pool.Close()
+	if _, err := pool.Run(context.Background(), pidScript, nil); !errors.Is(err, ErrWorkerPoolClosed) {
+		t.Errorf("关闭后应返回 ErrWorkerPoolClosed，实际为 %v", err)
+	}
+}
+
+func TestRunPythonScriptUsesClient(t *testing.T) {
+	pool := newTestWorkerPool(t, WorkerPoolConfig{Size: 1})
+	script := `
+import json
+import sys
+args = json.loads(sys.stdin.read())
+print(json.dumps({"success": args["action"] == "ok", "error": "unknown action", "data": args}))
+`
+	result, err := runPythonScript(context.Background(), pool, script, map[string]interface{}{"action": "ok"})
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if data, _ := result["data"].(map[string]interface{}); data["action"] != "ok" {
+		t.Errorf("结果不符合预期: %v", result)
+	}
+	if _, err := runPythonScript(context.Background(), pool, script, map[string]interface{}{"action": "bad"}); err == nil || !strings.Contains(err.Error(), "unknown action") {
+		t.Errorf("应返回脚本错误，实际为 %v", err)
+	}
+}
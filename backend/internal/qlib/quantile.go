@@ -0,0 +1,232 @@
+package qlib
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"qlib-backend/internal/analytics"
+)
+
+// 分组组合的加权方式
+const (
+	QuantileEqualWeight = "equal" // 组内等权
+	QuantileCapWeight   = "cap"   // 组内按市值加权
+)
+
+// DefaultQuantileGroups 默认分组数
+const DefaultQuantileGroups = 5
+
+// 分组数和调仓间隔的上限
+const (
+	MaxQuantileGroups    = 100
+	MaxQuantileRebalance = 250
+)
+
+// QuantileOptions 分层回测参数
+type QuantileOptions struct {
+	Groups    int    `json:"groups"`              // 分组数，默认5
+	Weighting string `json:"weighting"`           // equal 或 cap，默认 equal
+	CapField  string `json:"cap_field,omitempty"` // 市值加权使用的字段，默认 $market_cap
+	Rebalance int    `json:"rebalance"`           // 调仓间隔（交易日），默认每日调仓
+}
+
+func (o QuantileOptions) withDefaults() (QuantileOptions, error) {
+	if o.Groups == 0 {
+		o.Groups = DefaultQuantileGroups
+	}
+	if o.Weighting == "" {
+		o.Weighting = QuantileEqualWeight
+	}
+	if o.CapField == "" {
+		o.CapField = DefaultMarketCapField
+	}
+	if o.Rebalance <= 0 {
+		o.Rebalance = 1
+	}
+	if o.Groups < 2 || o.Groups > MaxQuantileGroups {
+		return o, fmt.Errorf("分组数应在2到%d之间", MaxQuantileGroups)
+	}
+	if o.Rebalance > MaxQuantileRebalance {
+		return o, fmt.Errorf("调仓间隔不能超过%d个交易日", MaxQuantileRebalance)
+	}
+	if o.Weighting != QuantileEqualWeight && o.Weighting != QuantileCapWeight {
+		return o, fmt.Errorf("不支持的加权方式: %s", o.Weighting)
+	}
+	return o, nil
+}
+
+// QuantileReport 分层回测结果，第1组因子值最小，最后一组因子值最大
+type QuantileReport struct {
+	Groups              int         `json:"groups"`
+	Weighting           string      `json:"weighting"`
+	Dates               []string    `json:"dates"`
+	GroupReturns        [][]float64 `json:"group_returns"`      // [组][交易日] 日收益
+	CumulativeReturns   [][]float64 `json:"cumulative_returns"` // [组][交易日] 累计收益
+	LongShort           []float64   `json:"long_short"`         // 最高组减最低组的日收益
+	LongShortCumulative []float64   `json:"long_short_cumulative"`
+	MeanReturns         []float64   `json:"mean_returns"`   // 各组日均收益
+	AnnualReturns       []float64   `json:"annual_returns"` // 各组年化收益
+	Turnover            []float64   `json:"turnover"`       // 各组每次调仓的平均单边换手率
+	Monotonicity        float64     `json:"monotonicity"`   // 组序号与组日均收益的秩相关，1为严格单调递增
+}
+
+// QuantileAnalysis 每个调仓日按因子值将股票池分为若干组，计算各组组合及多空组合的收益
+//
+// returns 为与因子同日对齐的下一期收益，caps 为市值，仅市值加权时需要。
+// 调仓日之间组内权重保持不变，当日没有收益的证券不参与计算。
+func QuantileAnalysis(ctx context.Context, factor, returns, caps *FactorFrame, opts QuantileOptions) (*QuantileReport, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Weighting == QuantileCapWeight && caps == nil {
+		return nil, fmt.Errorf("市值加权需要市值数据")
+	}
+	retIndex := newFrameIndex(returns)
+	var capIndex frameIndex
+	if caps != nil {
+		capIndex = newFrameIndex(caps)
+	}
+
+	report := &QuantileReport{
+		Groups:       opts.Groups,
+		Weighting:    opts.Weighting,
+		GroupReturns: make([][]float64, opts.Groups),
+		Turnover:     make([]float64, opts.Groups),
+	}
+	var weights []map[string]float64
+	rebalances := 0
+	for t, date := range factor.Calendar {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if t%opts.Rebalance == 0 {
+			next := quantileWeights(factor, t, caps, capIndex, opts)
+			if next != nil {
+				if weights != nil {
+					for g := range next {
+						report.Turnover[g] += weightChange(weights[g], next[g])
+					}
+					rebalances++
+				}
+				weights = next
+			}
+		}
+		if weights == nil {
+			continue
+		}
+
+		// 末尾尚无未来收益的交易日整体跳过
+		dayReturns := make([]float64, opts.Groups)
+		hasReturn := false
+		for g, groupWeights := range weights {
+			total, sum := 0.0, 0.0
+			for inst, w := range groupWeights {
+				if r := retIndex.at(returns, inst, date); isValidValue(r) {
+					total += w
+					sum += w * r
+				}
+			}
+			if total > 0 {
+				dayReturns[g] = sum / total
+				hasReturn = true
+			}
+		}
+		if !hasReturn {
+			continue
+		}
+		report.Dates = append(report.Dates, date.Format("2006-01-02"))
+		for g, r := range dayReturns {
+			report.GroupReturns[g] = append(report.GroupReturns[g], r)
+		}
+	}
+	if len(report.Dates) == 0 {
+		return nil, fmt.Errorf("没有证券数量足以分为%d组的交易日", opts.Groups)
+	}
+
+	top, bottom := report.GroupReturns[opts.Groups-1], report.GroupReturns[0]
+	report.LongShort = make([]float64, len(report.Dates))
+	for t := range report.LongShort {
+		report.LongShort[t] = top[t] - bottom[t]
+	}
+	report.LongShortCumulative = analytics.CumulativeReturns(report.LongShort)
+
+	groupIndex := make([]float64, opts.Groups)
+	for g, series := range report.GroupReturns {
+		groupIndex[g] = float64(g + 1)
+		report.CumulativeReturns = append(report.CumulativeReturns, analytics.CumulativeReturns(series))
+		report.MeanReturns = append(report.MeanReturns, analytics.Mean(series))
+		report.AnnualReturns = append(report.AnnualReturns, analytics.AnnualizedReturn(series, analytics.Options{}))
+		if rebalances > 0 {
+			report.Turnover[g] /= float64(rebalances)
+		}
+	}
+	report.Monotonicity = analytics.RankCorrelation(groupIndex, report.MeanReturns)
+	return report, nil
+}
+
+// quantileWeights 按第 t 日因子值分组并计算组内权重，有效证券数少于分组数时返回 nil
+func quantileWeights(factor *FactorFrame, t int, caps *FactorFrame, capIndex frameIndex, opts QuantileOptions) []map[string]float64 {
+	type candidate struct {
+		instrument string
+		value      float64
+		weight     float64
+	}
+	date := factor.Calendar[t]
+	candidates := make([]candidate, 0, len(factor.Instruments))
+	for i, inst := range factor.Instruments {
+		v := factor.Values[i][t]
+		if !isValidValue(v) {
+			continue
+		}
+		weight := 1.0
+		if opts.Weighting == QuantileCapWeight {
+			if weight = capIndex.at(caps, inst, date); !(weight > 0) || math.IsInf(weight, 0) {
+				continue
+			}
+		}
+		candidates = append(candidates, candidate{inst, v, weight})
+	}
+	if len(candidates) < opts.Groups {
+		return nil
+	}
+	sort.Slice(candidates, func(a, b int) bool {
+		if candidates[a].value != candidates[b].value {
+			return candidates[a].value < candidates[b].value
+		}
+		return candidates[a].instrument < candidates[b].instrument
+	})
+
+	groups := make([]map[string]float64, opts.Groups)
+	totals := make([]float64, opts.Groups)
+	for g := range groups {
+		groups[g] = make(map[string]float64)
+	}
+	for rank, c := range candidates {
+		g := rank * opts.Groups / len(candidates)
+		groups[g][c.instrument] = c.weight
+		totals[g] += c.weight
+	}
+	for g, group := range groups {
+		for inst := range group {
+			group[inst] /= totals[g]
+		}
+	}
+	return groups
+}
+
+// weightChange 调仓前后权重变化的一半，即单边换手率
+func weightChange(before, after map[string]float64) float64 {
+	change := 0.0
+	for inst, w := range after {
+		change += math.Abs(w - before[inst])
+	}
+	for inst, w := range before {
+		if _, ok := after[inst]; !ok {
+			change += w
+		}
+	}
+	return change / 2
+}
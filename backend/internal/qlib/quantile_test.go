@@ -0,0 +1,100 @@
+package qlib
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestQuantileAnalysis(t *testing.T) {
+	calendar := weekdayCalendar(3)
+	nan := math.NaN()
+	// 第2天起A和D的因子排名互换；最后一天没有未来收益
+	factor := newTestFactorFrame(calendar, map[string][]float64{
+		"A": {1, 4, 4},
+		"B": {2, 2, 2},
+		"C": {3, 3, 3},
+		"D": {4, 1, 1},
+	})
+	returns := newTestFactorFrame(calendar, map[string][]float64{
+		"A": {0.01, 0.04, nan},
+		"B": {0.03, 0.02, nan},
+		"C": {0.05, 0.01, nan},
+		"D": {0.07, 0.03, nan},
+	})
+
+	report, err := QuantileAnalysis(context.Background(), factor, returns, nil, QuantileOptions{Groups: 2})
+	if err != nil {
+		t.Fatalf("QuantileAnalysis failed: %v", err)
+	}
+	if len(report.Dates) != 2 || report.Weighting != QuantileEqualWeight {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	want := [][]float64{{0.02, 0.025}, {0.06, 0.025}}
+	for g := range want {
+		for d := range want[g] {
+			if math.Abs(report.GroupReturns[g][d]-want[g][d]) > 1e-12 {
+				t.Errorf("group %d day %d return = %v, want %v", g+1, d, report.GroupReturns[g][d], want[g][d])
+			}
+		}
+	}
+	if got := report.CumulativeReturns[1][1]; math.Abs(got-(1.06*1.025-1)) > 1e-12 {
+		t.Errorf("cumulative return of top group = %v", got)
+	}
+	if math.Abs(report.LongShort[0]-0.04) > 1e-12 || math.Abs(report.LongShort[1]) > 1e-12 {
+		t.Errorf("unexpected long-short returns: %v", report.LongShort)
+	}
+	if math.Abs(report.Monotonicity-1) > 1e-12 {
+		t.Errorf("monotonicity = %v, want 1", report.Monotonicity)
+	}
+	// 第2天每组换出一半，第3天不变，平均单边换手0.25
+	for g, turnover := range report.Turnover {
+		if math.Abs(turnover-0.25) > 1e-12 {
+			t.Errorf("group %d turnover = %v, want 0.25", g+1, turnover)
+		}
+	}
+
+	// 每2日调仓时第2天沿用第1天的分组
+	report, err = QuantileAnalysis(context.Background(), factor, returns, nil, QuantileOptions{Groups: 2, Rebalance: 2})
+	if err != nil {
+		t.Fatalf("QuantileAnalysis failed: %v", err)
+	}
+	if math.Abs(report.GroupReturns[0][1]-0.03) > 1e-12 || math.Abs(report.Turnover[0]-0.5) > 1e-12 {
+		t.Errorf("unexpected rebalance result: %v, %v", report.GroupReturns[0], report.Turnover)
+	}
+}
+
+func TestQuantileAnalysisCapWeight(t *testing.T) {
+	calendar := weekdayCalendar(1)
+	factor := newTestFactorFrame(calendar, map[string][]float64{"A": {1}, "B": {2}, "C": {3}, "D": {4}})
+	returns := newTestFactorFrame(calendar, map[string][]float64{"A": {0.01}, "B": {0.03}, "C": {0.05}, "D": {0.07}})
+	caps := newTestFactorFrame(calendar, map[string][]float64{"A": {1}, "B": {3}, "C": {1}, "D": {1}})
+
+	report, err := QuantileAnalysis(context.Background(), factor, returns, caps, QuantileOptions{Groups: 2, Weighting: QuantileCapWeight})
+	if err != nil {
+		t.Fatalf("QuantileAnalysis failed: %v", err)
+	}
+	if got := report.GroupReturns[0][0]; math.Abs(got-0.025) > 1e-12 {
+		t.Errorf("cap weighted bottom group return = %v, want 0.025", got)
+	}
+
+	for _, opts := range []QuantileOptions{
+		{Groups: 1},
+		{Weighting: "volume"},
+		{Groups: 2, Weighting: QuantileCapWeight},
+		{Groups: 5},
+	} {
+		if _, err := QuantileAnalysis(context.Background(), factor, returns, nil, opts); err == nil {
+			t.Errorf("QuantileAnalysis with %+v should fail", opts)
+		}
+	}
+}
+
+func TestQuantileOptionsLimits(t *testing.T) {
+	if _, err := (QuantileOptions{Groups: MaxQuantileGroups + 1}).withDefaults(); err == nil {
+		t.Error("Too many groups should be rejected")
+	}
+	if _, err := (QuantileOptions{Rebalance: MaxQuantileRebalance + 1}).withDefaults(); err == nil {
+		t.Error("Too long a rebalance interval should be rejected")
+	}
+}
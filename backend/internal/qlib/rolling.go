@@ -0,0 +1,221 @@
+package qlib
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// RollingConfig 滚动训练配置，与Qlib的 RollingGen 类似，长度均以交易日计
+type RollingConfig struct {
+	Start       string `json:"start"`        // 首个训练窗口开始日期，为空时使用日历开头
+	End         string `json:"end"`          // 最后一个测试段结束日期，为空时使用日历末尾
+	TrainLength int    `json:"train_length"` // 训练集长度
+	ValidLength int    `json:"valid_length"` // 验证集长度，可为0
+	Step        int    `json:"step"`         // 滚动步长，即每个窗口测试段的长度
+	Windows     int    `json:"windows"`      // 窗口数，为0时一直滚动到 End
+	Expanding   bool   `json:"expanding"`    // 训练集起点固定、逐窗扩展（对应 RollingGen 的 ROLL_EX）
+	Gap         int    `json:"gap"`          // 训练集和验证集末尾截去的交易日数，避免标签使用未来数据
+}
+
+// RollingWindow 单个滚动窗口，各段日期均为闭区间
+type RollingWindow struct {
+	Index      int       `json:"index"`
+	TrainStart time.Time `json:"train_start"`
+	TrainEnd   time.Time `json:"train_end"`
+	ValidStart time.Time `json:"valid_start,omitempty"` // 无验证集时为零值
+	ValidEnd   time.Time `json:"valid_end,omitempty"`
+	TestStart  time.Time `json:"test_start"`
+	TestEnd    time.Time `json:"test_end"`
+}
+
+// Segments 转换为模型配置使用的时间分段
+func (w RollingWindow) Segments() Segments {
+	segments := Segments{
+		Train: []string{formatDate(w.TrainStart), formatDate(w.TrainEnd)},
+		Test:  []string{formatDate(w.TestStart), formatDate(w.TestEnd)},
+	}
+	if !w.ValidStart.IsZero() {
+		segments.Valid = []string{formatDate(w.ValidStart), formatDate(w.ValidEnd)}
+	}
+	return segments
+}
+
+// ParseRollingConfig 从工作流步骤配置解析滚动训练配置
+func ParseRollingConfig(raw interface{}) (RollingConfig, error) {
+	var config RollingConfig
+	if raw == nil {
+		return config, fmt.Errorf("缺少滚动训练配置")
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return config, fmt.Errorf("解析滚动训练配置失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("解析滚动训练配置失败: %v", err)
+	}
+	return config, nil
+}
+
+// GenerateRollingWindows 在交易日历上生成连续的滚动窗口
+//
+// 第k个窗口的训练集从第 k*Step 个交易日开始（扩展模式下固定为首日），之后依次为验证集和测试段，
+// 相邻窗口的测试段首尾相接，最后一个测试段在 End 处截断。
+func GenerateRollingWindows(calendar []time.Time, config RollingConfig) ([]RollingWindow, error) {
+	if config.TrainLength <= 0 || config.Step <= 0 {
+		return nil, fmt.Errorf("训练集长度和滚动步长必须为正数")
+	}
+	if config.ValidLength < 0 || config.Windows < 0 || config.Gap < 0 {
+		return nil, fmt.Errorf("验证集长度、窗口数和间隔不能为负数")
+	}
+	if config.Gap >= config.TrainLength || (config.ValidLength > 0 && config.Gap >= config.ValidLength) {
+		return nil, fmt.Errorf("间隔必须小于训练集和验证集长度")
+	}
+	start, err := parseOptionalDate(config.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseOptionalDate(config.End)
+	if err != nil {
+		return nil, err
+	}
+
+	first := 0
+	if !start.IsZero() {
+		first = sort.Search(len(calendar), func(i int) bool { return !calendar[i].Before(start) })
+	}
+	last := len(calendar) - 1
+	if !end.IsZero() {
+		last = sort.Search(len(calendar), func(i int) bool { return calendar[i].After(end) }) - 1
+	}
+
+	var windows []RollingWindow
+	for k := 0; config.Windows == 0 || k < config.Windows; k++ {
+		trainFrom := first + k*config.Step
+		trainTo := trainFrom + config.TrainLength - 1
+		if config.Expanding {
+			trainFrom = first
+		}
+		validTo := trainTo + config.ValidLength
+		testFrom := validTo + 1
+		if testFrom > last {
+			break
+		}
+		testTo := testFrom + config.Step - 1
+		if testTo > last {
+			testTo = last
+		}
+
+		window := RollingWindow{
+			Index:      k,
+			TrainStart: calendar[trainFrom],
+			TrainEnd:   calendar[trainTo-config.Gap],
+			TestStart:  calendar[testFrom],
+			TestEnd:    calendar[testTo],
+		}
+		if config.ValidLength > 0 {
+			window.ValidStart = calendar[trainTo+1]
+			window.ValidEnd = calendar[validTo-config.Gap]
+		}
+		windows = append(windows, window)
+	}
+
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("区间内交易日不足以生成滚动窗口")
+	}
+	if config.Windows > 0 && len(windows) < config.Windows {
+		return nil, fmt.Errorf("区间内只能生成%d个滚动窗口，少于要求的%d个", len(windows), config.Windows)
+	}
+	return windows, nil
+}
+
+// StitchPredictions 将各窗口测试段的预测拼接为一个连续信号
+//
+// 每个窗口只保留其测试段内的预测，证券取所有窗口的并集，缺失值为 NaN。
+func StitchPredictions(windows []RollingWindow, predictions []*FactorFrame) (*FactorFrame, error) {
+	if len(windows) != len(predictions) {
+		return nil, fmt.Errorf("窗口数(%d)与预测数(%d)不一致", len(windows), len(predictions))
+	}
+
+	seen := make(map[string]bool)
+	var instruments []string
+	parts := make([]*FactorFrame, len(windows))
+	for i, window := range windows {
+		if predictions[i] == nil {
+			return nil, fmt.Errorf("窗口%d缺少预测结果", window.Index)
+		}
+		parts[i] = predictions[i].Trim(window.TestStart, window.TestEnd)
+		for _, inst := range parts[i].Instruments {
+			if !seen[inst] {
+				seen[inst] = true
+				instruments = append(instruments, inst)
+			}
+		}
+	}
+	sort.Strings(instruments)
+	index := make(map[string]int, len(instruments))
+	for i, inst := range instruments {
+		index[inst] = i
+	}
+
+	stitched := &FactorFrame{Instruments: instruments, Values: make([][]float64, len(instruments))}
+	for _, part := range parts {
+		if n := len(stitched.Calendar); n > 0 && len(part.Calendar) > 0 && !part.Calendar[0].After(stitched.Calendar[n-1]) {
+			return nil, fmt.Errorf("滚动窗口测试段重叠: %s", formatDate(part.Calendar[0]))
+		}
+		offset := len(stitched.Calendar)
+		stitched.Calendar = append(stitched.Calendar, part.Calendar...)
+		for i := range stitched.Values {
+			stitched.Values[i] = append(stitched.Values[i], nanSeries(len(part.Calendar))...)
+		}
+		for j, inst := range part.Instruments {
+			copy(stitched.Values[index[inst]][offset:], part.Values[j])
+		}
+	}
+	if len(stitched.Calendar) == 0 {
+		return nil, fmt.Errorf("各窗口测试段均无预测结果")
+	}
+	return stitched, nil
+}
+
+// PredictionsToFrame 将逐条预测值转换为按日期和证券对齐的分数表
+func PredictionsToFrame(predictions []PredictionValue) *FactorFrame {
+	dateSet := make(map[time.Time]bool)
+	instSet := make(map[string]bool)
+	for _, p := range predictions {
+		dateSet[p.Date] = true
+		instSet[p.Instrument] = true
+	}
+	frame := &FactorFrame{}
+	for date := range dateSet {
+		frame.Calendar = append(frame.Calendar, date)
+	}
+	sort.Slice(frame.Calendar, func(i, j int) bool { return frame.Calendar[i].Before(frame.Calendar[j]) })
+	for inst := range instSet {
+		frame.Instruments = append(frame.Instruments, inst)
+	}
+	sort.Strings(frame.Instruments)
+
+	dateIndex := make(map[time.Time]int, len(frame.Calendar))
+	for i, date := range frame.Calendar {
+		dateIndex[date] = i
+	}
+	instIndex := make(map[string]int, len(frame.Instruments))
+	frame.Values = make([][]float64, len(frame.Instruments))
+	for i, inst := range frame.Instruments {
+		instIndex[inst] = i
+		frame.Values[i] = nanSeries(len(frame.Calendar))
+	}
+	for _, p := range predictions {
+		if !math.IsNaN(p.Score) && !math.IsInf(p.Score, 0) {
+			frame.Values[instIndex[p.Instrument]][dateIndex[p.Date]] = p.Score
+		}
+	}
+	return frame
+}
+
+func formatDate(date time.Time) string {
+	return date.Format("2006-01-02")
+}
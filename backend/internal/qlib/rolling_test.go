@@ -0,0 +1,161 @@
+package qlib
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+// weekdayCalendar 从2023-01-02开始的n个工作日
+func weekdayCalendar(n int) []time.Time {
+	calendar := make([]time.Time, 0, n)
+	for date := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC); len(calendar) < n; date = date.AddDate(0, 0, 1) {
+		if date.Weekday() != time.Saturday && date.Weekday() != time.Sunday {
+			calendar = append(calendar, date)
+		}
+	}
+	return calendar
+}
+
+func TestGenerateRollingWindows(t *testing.T) {
+	calendar := weekdayCalendar(30)
+
+	windows, err := GenerateRollingWindows(calendar, RollingConfig{TrainLength: 10, ValidLength: 5, Step: 5, Gap: 1})
+	if err != nil {
+		t.Fatalf("GenerateRollingWindows failed: %v", err)
+	}
+	// 测试段从第15个交易日开始，每5个交易日一段，共3段
+	if len(windows) != 3 {
+		t.Fatalf("expected 3 windows, got %d", len(windows))
+	}
+	w := windows[1]
+	if !w.TrainStart.Equal(calendar[5]) || !w.TrainEnd.Equal(calendar[13]) || !w.ValidStart.Equal(calendar[15]) ||
+		!w.ValidEnd.Equal(calendar[18]) || !w.TestStart.Equal(calendar[20]) || !w.TestEnd.Equal(calendar[24]) {
+		t.Errorf("unexpected window: %+v", w)
+	}
+	for i := 1; i < len(windows); i++ {
+		if !windows[i].TestStart.Equal(calendar[15+5*i]) || !windows[i-1].TestEnd.Equal(calendar[15+5*i-1]) {
+			t.Errorf("test segments should be contiguous at window %d", i)
+		}
+	}
+	if s := windows[0].Segments(); s.Train[0] != "2023-01-02" || len(s.Valid) != 2 || s.Test[1] != "2023-01-27" {
+		t.Errorf("unexpected segments: %+v", s)
+	}
+
+	t.Run("ExpandingAndEnd", func(t *testing.T) {
+		windows, err := GenerateRollingWindows(calendar, RollingConfig{TrainLength: 10, Step: 8, Expanding: true, End: "2023-02-08"})
+		if err != nil {
+			t.Fatalf("GenerateRollingWindows failed: %v", err)
+		}
+		// End 为第28个交易日，最后一个测试段被截断
+		if len(windows) != 3 || !windows[2].TrainStart.Equal(calendar[0]) || !windows[2].TrainEnd.Equal(calendar[25]) ||
+			!windows[2].TestEnd.Equal(calendar[27]) || !windows[2].ValidStart.IsZero() {
+			t.Errorf("unexpected windows: %+v", windows)
+		}
+	})
+
+	for _, bad := range []RollingConfig{
+		{TrainLength: 0, Step: 5},
+		{TrainLength: 10, Step: 5, Gap: 10},
+		{TrainLength: 30, Step: 5},
+		{TrainLength: 10, Step: 5, Windows: 5},
+		{TrainLength: 10, Step: 5, Start: "2023/01/02"},
+	} {
+		if _, err := GenerateRollingWindows(calendar, bad); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestStitchPredictions(t *testing.T) {
+	calendar := weekdayCalendar(6)
+	windows := []RollingWindow{
+		{Index: 0, TestStart: calendar[0], TestEnd: calendar[2]},
+		{Index: 1, TestStart: calendar[3], TestEnd: calendar[5]},
+	}
+	// 第一个窗口的预测超出测试段的部分应被丢弃
+	first := &FactorFrame{Calendar: calendar[:4], Instruments: []string{"B", "A"}, Values: [][]float64{{1, 2, 3, 99}, {4, 5, 6, 99}}}
+	second := PredictionsToFrame([]PredictionValue{
+		{Instrument: "C", Date: calendar[3], Score: 7},
+		{Instrument: "A", Date: calendar[5], Score: 8},
+	})
+
+	stitched, err := StitchPredictions(windows, []*FactorFrame{first, second})
+	if err != nil {
+		t.Fatalf("StitchPredictions failed: %v", err)
+	}
+	if len(stitched.Calendar) != 5 || len(stitched.Instruments) != 3 || stitched.Instruments[0] != "A" {
+		t.Fatalf("unexpected stitched frame: %+v", stitched)
+	}
+	a, c := stitched.Values[0], stitched.Values[2]
+	if a[0] != 4 || a[2] != 6 || !math.IsNaN(a[3]) || a[4] != 8 || !math.IsNaN(c[0]) || c[3] != 7 {
+		t.Errorf("unexpected values: A=%v C=%v", a, c)
+	}
+
+	overlapping := []RollingWindow{windows[0], {Index: 1, TestStart: calendar[2], TestEnd: calendar[5]}}
+	if _, err := StitchPredictions(overlapping, []*FactorFrame{first, first}); err == nil {
+		t.Error("overlapping test segments should fail")
+	}
+	if _, err := StitchPredictions(windows, []*FactorFrame{first}); err == nil {
+		t.Error("mismatched predictions should fail")
+	}
+}
+
+func TestExecuteRollingTraining(t *testing.T) {
+	calendar := weekdayCalendar(40)
+	instruments := []string{"A", "B", "C"}
+	frame := NewMarketFrame(calendar, instruments)
+	for i, inst := range instruments {
+		closes := make([]float64, len(calendar))
+		for d := range closes {
+			closes[d] = 10 * math.Pow(1+0.001*float64(i), float64(d))
+		}
+		frame.SetSeries(inst, "$close", closes)
+	}
+
+	engine := NewWorkflowEngine(nil)
+	engine.SetDataProvider(NewMemoryDataProvider(frame))
+	var trained []RollingWindow
+	engine.SetWindowTrainer(func(ctx context.Context, window RollingWindow, config map[string]interface{}, windowDir string) (*WindowTrainingResult, error) {
+		trained = append(trained, window)
+		// 预测覆盖全部日期，C 的分数最高
+		scores := &FactorFrame{Calendar: calendar, Instruments: instruments, Values: make([][]float64, len(instruments))}
+		for i := range instruments {
+			scores.Values[i] = make([]float64, len(calendar))
+			for d := range calendar {
+				scores.Values[i][d] = float64(i)
+			}
+		}
+		return &WindowTrainingResult{ModelPath: windowDir + "/model.pkl", Metrics: map[string]float64{"valid_ic": 0.05}, Predictions: scores}, nil
+	})
+
+	step := WorkflowStep{Name: "滚动训练", Type: "rolling_training", Config: map[string]interface{}{
+		"rolling":         map[string]interface{}{"train_length": 20.0, "step": 5.0, "windows": 3.0},
+		"strategy_params": map[string]interface{}{"topk": 1.0, "n_drop": 1.0},
+	}}
+	stepContext := map[string]interface{}{"config": map[string]interface{}{"start_time": "2023-01-02"}}
+	result, err := engine.executeStep(context.Background(), step, stepContext, t.TempDir())
+	if err != nil {
+		t.Fatalf("rolling training failed: %v", err)
+	}
+
+	windows, _ := result.Output["windows"].([]RollingWindowResult)
+	if len(trained) != 3 || len(windows) != 3 || windows[2].Predictions != 15 || windows[0].Metrics["valid_ic"] != 0.05 {
+		t.Fatalf("unexpected window results: %+v", windows)
+	}
+	if result.Output["test_start"] != "2023-01-30" || result.Output["test_end"] != "2023-02-17" {
+		t.Errorf("unexpected test range: %v - %v", result.Output["test_start"], result.Output["test_end"])
+	}
+	daily, _ := result.Output["daily"].([]DailyRecord)
+	performance, _ := result.Output["performance"].(BacktestResult)
+	if len(daily) != 15 || performance.TotalReturn <= 0 {
+		t.Errorf("expected a single backtest over the stitched signal: %d days, %+v", len(daily), performance)
+	}
+
+	t.Run("RequiresProvider", func(t *testing.T) {
+		if _, err := NewWorkflowEngine(nil).executeStep(context.Background(), step, stepContext, t.TempDir()); err == nil {
+			t.Error("rolling training without market data should fail")
+		}
+	})
+}
@@ -0,0 +1,372 @@
+package qlib
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OrderDirection 订单方向
+type OrderDirection int
+
+const (
+	OrderSell OrderDirection = iota
+	OrderBuy
+)
+
+func (d OrderDirection) String() string {
+	if d == OrderBuy {
+		return "buy"
+	}
+	return "sell"
+}
+
+// Order 交易订单，Amount 为股数
+type Order struct {
+	Instrument string
+	Direction  OrderDirection
+	Amount     float64
+}
+
+// OrderRejection 被拒绝或部分成交的订单
+type OrderRejection struct {
+	Date       time.Time `json:"date"`
+	Instrument string    `json:"instrument"`
+	Direction  string    `json:"direction"`
+	Requested  float64   `json:"requested"` // 委托数量
+	Filled     float64   `json:"filled"`    // 成交数量，0 表示整单被拒绝
+	Reason     string    `json:"reason"`
+	Message    string    `json:"message"`
+}
+
+// 订单被拒绝或部分成交的原因
+const (
+	RejectSuspended        = "suspended"
+	RejectLimitUp          = "limit_up"
+	RejectLimitDown        = "limit_down"
+	RejectTPlusOne         = "t_plus_one"
+	RejectNoPosition       = "no_position"
+	RejectInsufficientCash = "insufficient_cash"
+	RejectLotSize          = "lot_size"
+)
+
+// Holding 单只证券持仓
+type Holding struct {
+	Amount      float64 // 持有股数
+	Price       float64 // 最新价格
+	CostPrice   float64 // 持仓成本价（含交易费用）
+	HoldDays    int     // 已持有交易日数
+	TodayBought float64 // 当日买入股数，T+1 下当日不可卖出
+}
+
+// Sellable 返回当日可卖出的股数
+func (h *Holding) Sellable(tPlusOne bool) float64 {
+	if tPlusOne {
+		return h.Amount - h.TodayBought
+	}
+	return h.Amount
+}
+
+// Position 账户持仓
+type Position struct {
+	Cash     float64
+	Holdings map[string]*Holding
+}
+
+// NewPosition 创建只有现金的账户
+func NewPosition(cash float64) *Position {
+	return &Position{Cash: cash, Holdings: make(map[string]*Holding)}
+}
+
+// Clone 深拷贝持仓，策略可在副本上模拟成交
+func (p *Position) Clone() *Position {
+	clone := NewPosition(p.Cash)
+	for inst, h := range p.Holdings {
+		copied := *h
+		clone.Holdings[inst] = &copied
+	}
+	return clone
+}
+
+// Amount 返回某只证券的持有股数
+func (p *Position) Amount(instrument string) float64 {
+	if h, ok := p.Holdings[instrument]; ok {
+		return h.Amount
+	}
+	return 0
+}
+
+// Instruments 返回持有的证券（排序）
+func (p *Position) Instruments() []string {
+	instruments := make([]string, 0, len(p.Holdings))
+	for inst := range p.Holdings {
+		instruments = append(instruments, inst)
+	}
+	sort.Strings(instruments)
+	return instruments
+}
+
+// StockValue 返回持仓市值，按证券代码顺序累加以保证结果可复现
+func (p *Position) StockValue() float64 {
+	value := 0.0
+	for _, inst := range p.Instruments() {
+		h := p.Holdings[inst]
+		value += h.Amount * h.Price
+	}
+	return value
+}
+
+// Value 返回账户总值
+func (p *Position) Value() float64 {
+	return p.Cash + p.StockValue()
+}
+
+// SimExchange 基于行情数据帧的模拟交易所
+//
+// 撮合规则与Qlib的Exchange一致：涨停不能买入、跌停不能卖出（按当日涨跌幅与 Limit_threshold 比较），
+// 成交价缺失视为停牌；买入数量按 Trade_unit 整手取整（提供 $factor 时按复权因子折算为实际股数），
+// 清仓卖出允许零股；手续费不低于 Min_cost，卖出另收印花税。
+type SimExchange struct {
+	frame     *MarketFrame
+	config    ExchangeConfig
+	dealField string
+	day       int
+}
+
+// NewSimExchange 创建模拟交易所，成交价字段由 Deal_price 指定，默认收盘价
+func NewSimExchange(frame *MarketFrame, config ExchangeConfig) (*SimExchange, error) {
+	dealField := exchangeDealField(config.Deal_price)
+	if _, ok := frame.Fields[dealField]; !ok {
+		return nil, fmt.Errorf("行情数据缺少成交价字段: %s", dealField)
+	}
+	if _, ok := frame.Fields["$close"]; !ok {
+		return nil, fmt.Errorf("行情数据缺少字段: $close")
+	}
+	return &SimExchange{frame: frame, config: config, dealField: dealField}, nil
+}
+
+// exchangeDealField 将成交价配置转换为字段名
+func exchangeDealField(dealPrice string) string {
+	if dealPrice == "" {
+		return "$close"
+	}
+	return "$" + strings.TrimPrefix(strings.ToLower(dealPrice), "$")
+}
+
+// exchangeFields 返回模拟交易所需要加载的行情字段
+func exchangeFields(config ExchangeConfig) []string {
+	fields := []string{"$close"}
+	if dealField := exchangeDealField(config.Deal_price); dealField != "$close" {
+		fields = append(fields, dealField)
+	}
+	return fields
+}
+
+// exchangeOptionalFields 返回可选字段，缺失时按默认规则处理
+func exchangeOptionalFields() []string {
+	return []string{"$factor"}
+}
+
+// SetDay 设置当前交易日序号
+func (e *SimExchange) SetDay(day int) {
+	e.day = day
+}
+
+// Date 返回当前交易日
+func (e *SimExchange) Date() time.Time {
+	return e.frame.Calendar[e.day]
+}
+
+func (e *SimExchange) valueAt(instrument, field string, day int) float64 {
+	series, ok := e.frame.Series(instrument, field)
+	if !ok || day < 0 || day >= len(series) {
+		return math.NaN()
+	}
+	return series[day]
+}
+
+// DealPrice 返回当日成交价，无行情时为 NaN
+func (e *SimExchange) DealPrice(instrument string) float64 {
+	return e.valueAt(instrument, e.dealField, e.day)
+}
+
+// ClosePrice 返回当日收盘价，无行情时为 NaN
+func (e *SimExchange) ClosePrice(instrument string) float64 {
+	return e.valueAt(instrument, "$close", e.day)
+}
+
+// Factor 返回当日复权因子，缺失时为1
+func (e *SimExchange) Factor(instrument string) float64 {
+	if f := e.valueAt(instrument, "$factor", e.day); !math.IsNaN(f) && f > 0 {
+		return f
+	}
+	return 1
+}
+
+// Change 返回当日收盘涨跌幅，前一个有效收盘价之前停牌的按复牌前最后收盘价计算
+func (e *SimExchange) Change(instrument string) float64 {
+	current := e.ClosePrice(instrument)
+	for day := e.day - 1; day >= 0; day-- {
+		if prev := e.valueAt(instrument, "$close", day); !math.IsNaN(prev) && prev > 0 {
+			return current/prev - 1
+		}
+	}
+	return math.NaN()
+}
+
+// CheckTradable 检查证券当日能否按指定方向交易，不能交易时返回原因
+func (e *SimExchange) CheckTradable(instrument string, direction OrderDirection) string {
+	price := e.DealPrice(instrument)
+	if math.IsNaN(price) || price <= 0 {
+		return RejectSuspended
+	}
+	if threshold := e.config.Limit_threshold; threshold > 0 {
+		change := e.Change(instrument)
+		if direction == OrderBuy && change >= threshold {
+			return RejectLimitUp
+		}
+		if direction == OrderSell && change <= -threshold {
+			return RejectLimitDown
+		}
+	}
+	return ""
+}
+
+// IsTradable 判断证券当日是否可以按指定方向交易
+func (e *SimExchange) IsTradable(instrument string, direction OrderDirection) bool {
+	return e.CheckTradable(instrument, direction) == ""
+}
+
+// RoundAmount 按交易单位向下取整，amount 为复权后股数
+func (e *SimExchange) RoundAmount(instrument string, amount float64) float64 {
+	if e.config.Trade_unit > 0 {
+		unit := float64(e.config.Trade_unit)
+		factor := e.Factor(instrument)
+		return math.Floor((amount*factor+1e-6)/unit) * unit / factor
+	}
+	return amount
+}
+
+// tradeCost 计算交易费用：佣金不低于 Min_cost，卖出另收印花税
+func (e *SimExchange) tradeCost(value float64, direction OrderDirection) float64 {
+	if value <= 0 {
+		return 0
+	}
+	if direction == OrderBuy {
+		return math.Max(value*e.config.Open_cost, e.config.Min_cost)
+	}
+	return math.Max(value*e.config.Close_cost, e.config.Min_cost) + value*e.config.Stamp_duty
+}
+
+var rejectMessages = map[string]string{
+	RejectSuspended:        "停牌或无行情",
+	RejectLimitUp:          "涨停无法买入",
+	RejectLimitDown:        "跌停无法卖出",
+	RejectTPlusOne:         "当日买入的股份不能卖出(T+1)",
+	RejectNoPosition:       "可卖持仓不足",
+	RejectInsufficientCash: "可用资金不足",
+	RejectLotSize:          "数量不足一手",
+}
+
+func (e *SimExchange) reject(order Order, filled float64, reason string) *OrderRejection {
+	return &OrderRejection{
+		Date:       e.Date(),
+		Instrument: order.Instrument,
+		Direction:  order.Direction.String(),
+		Requested:  order.Amount,
+		Filled:     filled,
+		Reason:     reason,
+		Message:    rejectMessages[reason],
+	}
+}
+
+// Deal 按当日成交价撮合订单并更新持仓
+//
+// 整单被拒绝时只返回 rejection；部分成交时同时返回成交记录和说明未成交原因的 rejection。
+func (e *SimExchange) Deal(order Order, pos *Position) (*TradeRecord, *OrderRejection) {
+	if reason := e.CheckTradable(order.Instrument, order.Direction); reason != "" {
+		return nil, e.reject(order, 0, reason)
+	}
+	price := e.DealPrice(order.Instrument)
+	amount := order.Amount
+	reason := ""
+
+	if order.Direction == OrderSell {
+		h, ok := pos.Holdings[order.Instrument]
+		if !ok || h.Amount <= 0 {
+			return nil, e.reject(order, 0, RejectNoPosition)
+		}
+		if sellable := h.Sellable(e.config.T_plus_one); amount > sellable+1e-9 {
+			amount, reason = sellable, RejectNoPosition
+			if h.TodayBought > 0 && e.config.T_plus_one {
+				reason = RejectTPlusOne
+			}
+		}
+		// 清仓时允许卖出零股，否则按整手卖出
+		if amount < h.Amount-1e-9 {
+			if rounded := e.RoundAmount(order.Instrument, amount); rounded < amount-1e-9 {
+				amount = rounded
+				if reason == "" {
+					reason = RejectLotSize
+				}
+			}
+		}
+	} else {
+		if rounded := e.RoundAmount(order.Instrument, amount); rounded < amount-1e-9 {
+			amount, reason = rounded, RejectLotSize
+		}
+		// 现金不足时按可用现金缩减数量
+		if value := amount * price; value+e.tradeCost(value, OrderBuy) > pos.Cash {
+			affordable := math.Min(pos.Cash/(1+e.config.Open_cost), pos.Cash-e.config.Min_cost)
+			amount = math.Max(e.RoundAmount(order.Instrument, affordable/price), 0)
+			reason = RejectInsufficientCash
+		}
+	}
+	if amount <= 1e-9 {
+		if reason == "" {
+			reason = RejectLotSize
+		}
+		return nil, e.reject(order, 0, reason)
+	}
+
+	value := amount * price
+	cost := e.tradeCost(value, order.Direction)
+	pnl := 0.0
+	if order.Direction == OrderBuy {
+		pos.Cash -= value + cost
+		h, ok := pos.Holdings[order.Instrument]
+		if !ok {
+			h = &Holding{}
+			pos.Holdings[order.Instrument] = h
+		}
+		h.CostPrice = (h.CostPrice*h.Amount + value + cost) / (h.Amount + amount)
+		h.Amount += amount
+		h.TodayBought += amount
+		h.Price = price
+	} else {
+		pos.Cash += value - cost
+		h := pos.Holdings[order.Instrument]
+		pnl = value - cost - h.CostPrice*amount
+		h.Amount -= amount
+		h.Price = price
+		if h.Amount <= 1e-9 {
+			delete(pos.Holdings, order.Instrument)
+		}
+	}
+
+	trade := &TradeRecord{
+		Date:       e.Date(),
+		Instrument: order.Instrument,
+		Direction:  order.Direction.String(),
+		Amount:     amount,
+		Price:      price,
+		Commission: cost,
+		PnL:        pnl,
+	}
+	if reason != "" {
+		return trade, e.reject(order, amount, reason)
+	}
+	return trade, nil
+}
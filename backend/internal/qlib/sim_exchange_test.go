@@ -0,0 +1,157 @@
+package qlib
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+// newExchangeFrame 构造三个交易日的行情：UP 第2天涨停，DOWN 第2天跌停，HALT 第2天停牌，ADJ 复权因子为2
+func newExchangeFrame(t *testing.T) *MarketFrame {
+	calendar := []time.Time{
+		time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 1, 4, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 1, 5, 0, 0, 0, 0, time.UTC),
+	}
+	frame := NewMarketFrame(calendar, []string{"UP", "DOWN", "HALT", "ADJ"})
+	series := map[string][]float64{
+		"UP":   {10, 11, 11.5},
+		"DOWN": {10, 9, 9},
+		"HALT": {10, math.NaN(), 10},
+		"ADJ":  {20, 20, 20},
+	}
+	for inst, closes := range series {
+		if err := frame.SetSeries(inst, "$close", closes); err != nil {
+			t.Fatalf("SetSeries failed: %v", err)
+		}
+	}
+	frame.SetSeries("ADJ", "$factor", []float64{2, 2, 2})
+	return frame
+}
+
+func TestSimExchangeRules(t *testing.T) {
+	exchange, err := NewSimExchange(newExchangeFrame(t), ExchangeConfig{
+		Limit_threshold: 0.095,
+		Trade_unit:      100,
+		Open_cost:       0.0003,
+		Close_cost:      0.0003,
+		Min_cost:        5,
+		Stamp_duty:      0.001,
+		T_plus_one:      true,
+	})
+	if err != nil {
+		t.Fatalf("NewSimExchange failed: %v", err)
+	}
+	exchange.SetDay(1)
+
+	t.Run("Tradability", func(t *testing.T) {
+		cases := []struct {
+			inst      string
+			direction OrderDirection
+			want      string
+		}{
+			{"UP", OrderBuy, RejectLimitUp},
+			{"UP", OrderSell, ""},
+			{"DOWN", OrderSell, RejectLimitDown},
+			{"DOWN", OrderBuy, ""},
+			{"HALT", OrderBuy, RejectSuspended},
+			{"MISSING", OrderSell, RejectSuspended},
+		}
+		for _, tc := range cases {
+			if got := exchange.CheckTradable(tc.inst, tc.direction); got != tc.want {
+				t.Errorf("CheckTradable(%s, %s) = %q, want %q", tc.inst, tc.direction, got, tc.want)
+			}
+		}
+	})
+
+	t.Run("LotSize", func(t *testing.T) {
+		pos := NewPosition(100000)
+		trade, rejection := exchange.Deal(Order{Instrument: "DOWN", Direction: OrderBuy, Amount: 150}, pos)
+		if trade == nil || trade.Amount != 100 || rejection == nil || rejection.Reason != RejectLotSize || rejection.Filled != 100 {
+			t.Errorf("Expected partial fill of one lot, got %+v, %+v", trade, rejection)
+		}
+		if _, rejection := exchange.Deal(Order{Instrument: "DOWN", Direction: OrderBuy, Amount: 50}, pos); rejection == nil || rejection.Reason != RejectLotSize {
+			t.Errorf("Less than one lot should be rejected, got %+v", rejection)
+		}
+
+		// 复权因子为2时，一手对应50股复权后数量
+		if got := exchange.RoundAmount("ADJ", 120); got != 100 {
+			t.Errorf("RoundAmount with factor 2 = %v, want 100", got)
+		}
+	})
+
+	t.Run("FeesAndTPlusOne", func(t *testing.T) {
+		pos := NewPosition(100000)
+		trade, _ := exchange.Deal(Order{Instrument: "UP", Direction: OrderSell, Amount: 100}, pos)
+		if trade != nil {
+			t.Errorf("Selling without position should fail, got %+v", trade)
+		}
+
+		trade, rejection := exchange.Deal(Order{Instrument: "ADJ", Direction: OrderBuy, Amount: 100}, pos)
+		if rejection != nil || trade.Commission != 5 {
+			t.Fatalf("Buy commission should be the minimum cost 5, got %+v, %+v", trade, rejection)
+		}
+		if math.Abs(pos.Cash-(100000-2000-5)) > 1e-9 {
+			t.Errorf("Cash = %v, want %v", pos.Cash, 100000-2000-5)
+		}
+
+		if _, rejection := exchange.Deal(Order{Instrument: "ADJ", Direction: OrderSell, Amount: 100}, pos); rejection == nil || rejection.Reason != RejectTPlusOne {
+			t.Errorf("Shares bought today should not be sellable, got %+v", rejection)
+		}
+
+		pos.Holdings["ADJ"].TodayBought = 0
+		pos.Holdings["ADJ"].Amount = 1000
+		exchange.SetDay(2)
+		trade, rejection = exchange.Deal(Order{Instrument: "ADJ", Direction: OrderSell, Amount: 1000}, pos)
+		if rejection != nil {
+			t.Fatalf("Sell should succeed the next day, got %+v", rejection)
+		}
+		// 佣金 max(20000*0.0003, 5)=6，印花税 20
+		if math.Abs(trade.Commission-26) > 1e-9 {
+			t.Errorf("Sell commission = %v, want 26", trade.Commission)
+		}
+		exchange.SetDay(1)
+	})
+
+	t.Run("InsufficientCash", func(t *testing.T) {
+		pos := NewPosition(1000)
+		trade, rejection := exchange.Deal(Order{Instrument: "DOWN", Direction: OrderBuy, Amount: 300}, pos)
+		if trade == nil || trade.Amount != 100 || rejection == nil || rejection.Reason != RejectInsufficientCash {
+			t.Errorf("Expected partial fill limited by cash, got %+v, %+v", trade, rejection)
+		}
+	})
+}
+
+func TestBacktestRecordsRejections(t *testing.T) {
+	frame, scores := newBacktestFrame(t)
+	// C 在第3天停牌，原定买入被拒绝，次日复牌后买入
+	frame.Fields["$close"][2][2] = math.NaN()
+	strategy := &TopkDropoutStrategy{Topk: 2, NDrop: 1, HoldThresh: 1, RiskDegree: 0.95, MethodSell: "bottom", MethodBuy: "top"}
+
+	report, err := NewNativeBacktester(NewMemoryDataProvider(frame)).Run(context.Background(), NativeBacktestConfig{
+		Account:  1000,
+		Exchange: ExchangeConfig{Limit_threshold: 0.3},
+	}, scores, strategy, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(report.Rejected) != 1 {
+		t.Fatalf("Expected one rejected order, got %+v", report.Rejected)
+	}
+	rejected := report.Rejected[0]
+	if rejected.Instrument != "C" || rejected.Reason != RejectSuspended || !rejected.Date.Equal(frame.Calendar[2]) {
+		t.Errorf("Unexpected rejection: %+v", rejected)
+	}
+
+	bought := false
+	for _, trade := range report.Trades {
+		if trade.Instrument == "C" && trade.Date.Equal(frame.Calendar[3]) && trade.Price == 12 {
+			bought = true
+		}
+	}
+	if !bought {
+		t.Errorf("C should be bought after resumption, trades: %+v", report.Trades)
+	}
+}
@@ -0,0 +1,442 @@
+package qlib
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// STUniverse 保存ST证券区间的股票池名称，剔除ST的筛选条件据此判断
+const STUniverse = "st"
+
+// openSpanEnd 导入时未给出结束日期的成分区间视为至今有效
+var openSpanEnd = time.Date(2099, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// Universe 时点股票池，记录每只证券纳入和剔除的日期区间
+//
+// 回测和因子测试按日期查询成分，避免用当前成分股回看历史带来的幸存者偏差。
+type Universe struct {
+	Name string
+
+	spans map[string][]InstrumentSpan
+}
+
+// NewUniverse 由成分区间创建股票池，证券代码统一为大写
+func NewUniverse(name string, spans []InstrumentSpan) *Universe {
+	u := &Universe{Name: name, spans: make(map[string][]InstrumentSpan)}
+	for _, span := range spans {
+		span.Symbol = strings.ToUpper(span.Symbol)
+		u.spans[span.Symbol] = append(u.spans[span.Symbol], span)
+	}
+	for _, list := range u.spans {
+		sort.Slice(list, func(i, j int) bool { return list[i].Start.Before(list[j].Start) })
+	}
+	return u
+}
+
+// Spans 全部成分区间，按证券代码和开始日期排序
+func (u *Universe) Spans() []InstrumentSpan {
+	symbols := make([]string, 0, len(u.spans))
+	for symbol := range u.spans {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	var spans []InstrumentSpan
+	for _, symbol := range symbols {
+		spans = append(spans, u.spans[symbol]...)
+	}
+	return spans
+}
+
+// Contains 证券在 date 当天是否属于股票池
+func (u *Universe) Contains(instrument string, date time.Time) bool {
+	for _, span := range u.spans[strings.ToUpper(instrument)] {
+		if !date.Before(span.Start) && !date.After(span.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// Members date 当天的成分股
+func (u *Universe) Members(date time.Time) []string {
+	var members []string
+	for symbol := range u.spans {
+		if u.Contains(symbol, date) {
+			members = append(members, symbol)
+		}
+	}
+	sort.Strings(members)
+	return members
+}
+
+// Instruments 在[start, end]内曾属于股票池的证券（零值表示不限制）
+func (u *Universe) Instruments(start, end time.Time) []string {
+	var symbols []string
+	for symbol, spans := range u.spans {
+		for _, span := range spans {
+			if (end.IsZero() || !span.Start.After(end)) && (start.IsZero() || !span.End.Before(start)) {
+				symbols = append(symbols, symbol)
+				break
+			}
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Mask 将不属于当日成分的因子值置为 NaN，返回新的结果
+func (u *Universe) Mask(frame *FactorFrame) *FactorFrame {
+	values := make([][]float64, len(frame.Values))
+	for i, series := range frame.Values {
+		masked := make([]float64, len(series))
+		for t, v := range series {
+			if u.Contains(frame.Instruments[i], frame.Calendar[t]) {
+				masked[t] = v
+			} else {
+				masked[t] = math.NaN()
+			}
+		}
+		values[i] = masked
+	}
+	return &FactorFrame{Calendar: frame.Calendar, Instruments: frame.Instruments, Values: values}
+}
+
+// ParseUniverse 解析成分区间文件，兼容Qlib的 instruments/<market>.txt 和CSV
+//
+// 每行为 "代码 开始日期 结束日期"，分隔符可以是制表符、逗号或空格；结束日期为空表示至今有效。
+// 首行无法解析日期时视为表头，#开头为注释。
+func ParseUniverse(name string, r io.Reader) (*Universe, error) {
+	spans, err := parseInstrumentSpans(r)
+	if err != nil {
+		return nil, fmt.Errorf("解析股票池 %s 失败: %v", name, err)
+	}
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("股票池 %s 没有成分股", name)
+	}
+	return NewUniverse(name, spans), nil
+}
+
+// parseInstrumentSpans 逐行解析证券有效区间
+func parseInstrumentSpans(r io.Reader) ([]InstrumentSpan, error) {
+	var spans []InstrumentSpan
+	scanner := bufio.NewScanner(r)
+	line, header := 0, false
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == '\t' })
+		if len(parts) == 1 {
+			parts = strings.Fields(text)
+		}
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("第%d行格式错误: %s", line, text)
+		}
+
+		start, err := parseQlibTime(parts[1])
+		if err != nil {
+			if len(spans) == 0 && !header {
+				header = true
+				continue
+			}
+			return nil, fmt.Errorf("第%d行开始日期错误: %v", line, err)
+		}
+		end := openSpanEnd
+		if len(parts) > 2 && parts[2] != "" {
+			if end, err = parseQlibTime(parts[2]); err != nil {
+				return nil, fmt.Errorf("第%d行结束日期错误: %v", line, err)
+			}
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("第%d行结束日期早于开始日期: %s", line, text)
+		}
+		spans = append(spans, InstrumentSpan{Symbol: strings.ToUpper(parts[0]), Start: start, End: end})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return spans, nil
+}
+
+// UniverseFilter 自定义股票池的筛选条件，逐个交易日在基础股票池内筛选
+type UniverseFilter struct {
+	Base            string   `json:"base"`             // 基础股票池，为空表示全部证券
+	MinListedDays   int      `json:"min_listed_days"`  // 上市满N个交易日，以首个有效收盘价为上市日
+	ExcludeST       bool     `json:"exclude_st"`       // 剔除ST证券，ST区间以名为 st 的股票池导入
+	Exclude         []string `json:"exclude"`          // 剔除属于这些股票池的证券
+	MinPrice        float64  `json:"min_price"`        // 最低收盘价
+	LiquidityTop    int      `json:"liquidity_top"`    // 按日均成交额取前N只
+	LiquidityWindow int      `json:"liquidity_window"` // 日均成交额窗口，默认20个交易日
+}
+
+// Validate 检查筛选条件
+func (f UniverseFilter) Validate() error {
+	if f.MinListedDays < 0 || f.LiquidityTop < 0 || f.LiquidityWindow < 0 || f.MinPrice < 0 {
+		return fmt.Errorf("股票池筛选条件不能为负数")
+	}
+	return nil
+}
+
+// universeSource 能提供股票池成分区间的数据源，如Qlib数据目录
+type universeSource interface {
+	Instruments(market string) ([]InstrumentSpan, error)
+}
+
+// UniverseRegistry 股票池注册表
+//
+// 保存导入的成分区间和自定义筛选股票池；未注册的名称从数据源的 instruments 文件读取。
+type UniverseRegistry struct {
+	mu        sync.RWMutex
+	universes map[string]*Universe
+	filters   map[string]UniverseFilter
+}
+
+// DefaultUniverses 全局股票池注册表
+var DefaultUniverses = NewUniverseRegistry()
+
+// NewUniverseRegistry 创建股票池注册表
+func NewUniverseRegistry() *UniverseRegistry {
+	return &UniverseRegistry{
+		universes: make(map[string]*Universe),
+		filters:   make(map[string]UniverseFilter),
+	}
+}
+
+// Register 注册或替换成分区间股票池
+func (r *UniverseRegistry) Register(universe *Universe) {
+	name := strings.ToLower(universe.Name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.filters, name)
+	r.universes[name] = universe
+}
+
+// RegisterFilter 注册或替换筛选股票池
+func (r *UniverseRegistry) RegisterFilter(name string, filter UniverseFilter) error {
+	name = strings.ToLower(name)
+	if name == "" || name == "all" {
+		return fmt.Errorf("无效的股票池名称: %s", name)
+	}
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.universes, name)
+	r.filters[name] = filter
+	return nil
+}
+
+// Remove 移除股票池
+func (r *UniverseRegistry) Remove(name string) {
+	name = strings.ToLower(name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.universes, name)
+	delete(r.filters, name)
+}
+
+// Resolve 解析[start, end]内的时点股票池
+//
+// 筛选股票池使用 provider 的行情逐日计算；数据源没有成分信息时返回 nil，表示不限制证券。
+func (r *UniverseRegistry) Resolve(ctx context.Context, provider MarketDataProvider, name string, start, end time.Time, freq string) (*Universe, error) {
+	return r.resolve(ctx, provider, strings.ToLower(name), start, end, freq, map[string]bool{})
+}
+
+func (r *UniverseRegistry) resolve(ctx context.Context, provider MarketDataProvider, name string, start, end time.Time, freq string, visiting map[string]bool) (*Universe, error) {
+	if visiting[name] {
+		return nil, fmt.Errorf("股票池 %s 存在循环引用", name)
+	}
+
+	r.mu.RLock()
+	universe, ok := r.universes[name]
+	filter, isFilter := r.filters[name]
+	r.mu.RUnlock()
+	if ok {
+		return universe, nil
+	}
+
+	if isFilter {
+		if provider == nil {
+			return nil, fmt.Errorf("未配置行情数据，无法计算筛选股票池 %s", name)
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		base, err := r.resolve(ctx, provider, strings.ToLower(filter.Base), start, end, freq, visiting)
+		if err != nil {
+			return nil, err
+		}
+		excludes := filter.Exclude
+		if filter.ExcludeST {
+			excludes = append([]string{STUniverse}, excludes...)
+		}
+		var excluded []*Universe
+		for _, exclude := range excludes {
+			u, err := r.resolve(ctx, provider, strings.ToLower(exclude), start, end, freq, visiting)
+			if err != nil {
+				return nil, fmt.Errorf("加载剔除股票池 %s 失败: %v", exclude, err)
+			}
+			if u != nil {
+				excluded = append(excluded, u)
+			}
+		}
+		return filterUniverse(ctx, provider, name, base, excluded, filter, start, end, freq)
+	}
+
+	if name == "" {
+		return nil, nil
+	}
+	if source, ok := provider.(universeSource); ok {
+		spans, err := source.Instruments(name)
+		if err != nil {
+			return nil, err
+		}
+		return NewUniverse(name, spans), nil
+	}
+	return nil, nil
+}
+
+// filterUniverse 逐个交易日按筛选条件计算成分，连续入选的交易日合并为一个区间
+func filterUniverse(ctx context.Context, provider MarketDataProvider, name string, base *Universe, excluded []*Universe, filter UniverseFilter, start, end time.Time, freq string) (*Universe, error) {
+	window := filter.LiquidityWindow
+	if window == 0 {
+		window = 20
+	}
+	req := FrameRequest{Universe: "all", Fields: []string{"$close"}, Start: start, End: end, Freq: freq}
+	if base != nil {
+		if req.Instruments = base.Instruments(start, end); len(req.Instruments) == 0 {
+			return NewUniverse(name, nil), nil
+		}
+	}
+	if filter.LiquidityTop > 0 {
+		req.Fields = append(req.Fields, "$volume")
+		req.Lookback = window
+	}
+	// 多取一天，使区间开始时刚满足上市天数的证券能被识别
+	req.Lookback = maxInt(req.Lookback, filter.MinListedDays+1)
+
+	frame, err := provider.LoadFrame(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("加载股票池 %s 的筛选行情失败: %v", name, err)
+	}
+
+	closes := frame.Fields["$close"]
+	listed := make([]int, len(frame.Instruments))
+	for i := range listed {
+		listed[i] = -1
+	}
+	var spans []InstrumentSpan
+	current := make(map[int]int) // 证券序号 -> 当前区间在 spans 中的下标
+	for t, date := range frame.Calendar {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for i := range frame.Instruments {
+			if listed[i] < 0 && !math.IsNaN(closes[i][t]) {
+				listed[i] = t
+			}
+		}
+		if date.Before(start) || (!end.IsZero() && date.After(end)) {
+			continue
+		}
+
+		var eligible []int
+		for i, inst := range frame.Instruments {
+			if listed[i] < 0 || t-listed[i] < filter.MinListedDays {
+				continue
+			}
+			if base != nil && !base.Contains(inst, date) {
+				continue
+			}
+			if filter.MinPrice > 0 && !(closes[i][t] >= filter.MinPrice) {
+				continue
+			}
+			skip := false
+			for _, u := range excluded {
+				if u.Contains(inst, date) {
+					skip = true
+					break
+				}
+			}
+			if !skip {
+				eligible = append(eligible, i)
+			}
+		}
+		if filter.LiquidityTop > 0 {
+			eligible = topLiquidity(frame, eligible, t, window, filter.LiquidityTop)
+		}
+
+		selected := make(map[int]bool, len(eligible))
+		for _, i := range eligible {
+			selected[i] = true
+			if k, ok := current[i]; ok {
+				spans[k].End = date
+				continue
+			}
+			current[i] = len(spans)
+			spans = append(spans, InstrumentSpan{Symbol: frame.Instruments[i], Start: date, End: date})
+		}
+		for i := range current {
+			if !selected[i] {
+				delete(current, i)
+			}
+		}
+	}
+	return NewUniverse(name, spans), nil
+}
+
+// topLiquidity 按截至第t日的日均成交额（收盘价×成交量）取前n只，没有成交的证券不入选
+func topLiquidity(frame *MarketFrame, candidates []int, t, window, n int) []int {
+	closes, volumes := frame.Fields["$close"], frame.Fields["$volume"]
+	amounts := make(map[int]float64, len(candidates))
+	ranked := make([]int, 0, len(candidates))
+	for _, i := range candidates {
+		sum, count := 0.0, 0
+		for k := maxInt(0, t-window+1); k <= t; k++ {
+			if amount := closes[i][k] * volumes[i][k]; !math.IsNaN(amount) {
+				sum += amount
+				count++
+			}
+		}
+		if count > 0 && sum > 0 {
+			amounts[i] = sum / float64(count)
+			ranked = append(ranked, i)
+		}
+	}
+	sort.SliceStable(ranked, func(a, b int) bool { return amounts[ranked[a]] > amounts[ranked[b]] })
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
+
+// resolveFrameUniverse 解析请求中的股票池，把加载范围限定为区间内曾入选的证券
+//
+// 请求已指定证券或股票池没有成分信息时返回 nil。
+func resolveFrameUniverse(ctx context.Context, registry *UniverseRegistry, provider MarketDataProvider, req *FrameRequest) (*Universe, error) {
+	if registry == nil || len(req.Instruments) > 0 || req.Universe == "" {
+		return nil, nil
+	}
+	universe, err := registry.Resolve(ctx, provider, req.Universe, req.Start, req.End, req.Freq)
+	if err != nil || universe == nil {
+		return nil, err
+	}
+	if req.Instruments = universe.Instruments(req.Start, req.End); len(req.Instruments) == 0 {
+		return nil, fmt.Errorf("股票池 %s 在所选区间内没有成分股", req.Universe)
+	}
+	return universe, nil
+}
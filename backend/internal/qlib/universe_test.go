@@ -0,0 +1,156 @@
+package qlib
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseUniverse(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	content := `instrument,start_date,end_date
+# 2023年6月调出后于12月重新调入
+sh600000,2020-01-02,2023-06-09
+SH600000,2023-12-11,
+SZ000001,2021-01-04,2022-12-30
+`
+	universe, err := ParseUniverse("csi300", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ParseUniverse failed: %v", err)
+	}
+	if spans := universe.Spans(); len(spans) != 3 || !spans[1].End.Equal(openSpanEnd) {
+		t.Fatalf("unexpected spans: %+v", spans)
+	}
+
+	tests := []struct {
+		date    string
+		members []string
+	}{
+		{"2019-12-31", nil},
+		{"2022-06-01", []string{"SH600000", "SZ000001"}},
+		{"2023-06-09", []string{"SH600000"}},
+		{"2023-08-01", nil},
+		{"2024-01-02", []string{"SH600000"}},
+	}
+	for _, tt := range tests {
+		if got := universe.Members(date(tt.date)); strings.Join(got, ",") != strings.Join(tt.members, ",") {
+			t.Errorf("Members(%s) = %v, want %v", tt.date, got, tt.members)
+		}
+	}
+	if got := universe.Instruments(date("2023-01-01"), date("2023-12-31")); len(got) != 1 || got[0] != "SH600000" {
+		t.Errorf("Instruments = %v", got)
+	}
+
+	qlibFormat := "SH600000\t2020-01-02\t2023-06-09\n"
+	if u, err := ParseUniverse("csi300", strings.NewReader(qlibFormat)); err != nil || !u.Contains("sh600000", date("2021-01-04")) {
+		t.Errorf("Qlib instruments format should parse: %v", err)
+	}
+	for _, bad := range []string{"", "SH600000,2020-01-02\nSZ000001,bad-date\n", "SH600000,2023-01-02,2022-01-01\n"} {
+		if _, err := ParseUniverse("bad", strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseUniverse(%q) should fail", bad)
+		}
+	}
+}
+
+func TestFilterUniverse(t *testing.T) {
+	calendar := weekdayCalendar(8)
+	nan := math.NaN()
+	frame := NewMarketFrame(calendar, []string{"A", "B", "C", "D"})
+	for inst, series := range map[string][2][]float64{
+		"A": {{10, 10, 10, 10, 10, 10, 10, 10}, {100, 100, 100, 100, 100, 100, 100, 100}},
+		"B": {{10, 10, 10, 10, 10, 10, 10, 10}, {300, 300, 300, 300, 300, 300, 300, 300}},
+		"C": {{nan, nan, nan, nan, 10, 10, 10, 10}, {nan, nan, nan, nan, 1000, 1000, 1000, 1000}}, // 第5个交易日上市
+		"D": {{2, 2, 2, 2, 2, 2, 2, 2}, {10000, 10000, 10000, 10000, 10000, 10000, 10000, 10000}}, // 低价股
+	} {
+		frame.SetSeries(inst, "$close", series[0])
+		frame.SetSeries(inst, "$volume", series[1])
+	}
+	provider := NewMemoryDataProvider(frame)
+
+	registry := NewUniverseRegistry()
+	registry.Register(NewUniverse(STUniverse, []InstrumentSpan{{Symbol: "B", Start: calendar[6], End: openSpanEnd}}))
+	filter := UniverseFilter{MinListedDays: 2, ExcludeST: true, MinPrice: 5, LiquidityTop: 1, LiquidityWindow: 3}
+	if err := registry.RegisterFilter("liquid", filter); err != nil {
+		t.Fatalf("RegisterFilter failed: %v", err)
+	}
+
+	universe, err := registry.Resolve(context.Background(), provider, "LIQUID", calendar[2], calendar[7], "day")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	// B 成交额最高直到被标记为ST，之后由上市满两天的 C 接替；D 价格过低始终不入选
+	want := map[int]string{1: "", 2: "B", 5: "B", 6: "C", 7: "C"}
+	for day, member := range want {
+		got := strings.Join(universe.Members(calendar[day]), ",")
+		if got != member {
+			t.Errorf("members on day %d = %q, want %q", day, got, member)
+		}
+	}
+	if spans := universe.Spans(); len(spans) != 2 || !spans[0].Start.Equal(calendar[2]) || !spans[0].End.Equal(calendar[5]) {
+		t.Errorf("consecutive days should merge into one span: %+v", spans)
+	}
+
+	if _, err := registry.Resolve(context.Background(), nil, "liquid", calendar[2], calendar[7], "day"); err == nil {
+		t.Error("filter universe without market data should fail")
+	}
+	registry.RegisterFilter("a", UniverseFilter{Base: "b"})
+	registry.RegisterFilter("b", UniverseFilter{Base: "a"})
+	if _, err := registry.Resolve(context.Background(), provider, "a", calendar[0], calendar[7], "day"); err == nil {
+		t.Error("circular filters should fail")
+	}
+	if u, err := registry.Resolve(context.Background(), provider, "csi300", calendar[0], calendar[7], "day"); err != nil || u != nil {
+		t.Errorf("memory data has no constituents and should not restrict the universe: %v, %v", u, err)
+	}
+}
+
+func TestEvaluateFactorPointInTime(t *testing.T) {
+	dir := newTestQlibDir(t)
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	registry := NewUniverseRegistry()
+	registry.Register(NewUniverse("custom", []InstrumentSpan{
+		{Symbol: "SH600000", Start: date("2023-01-03"), End: date("2023-01-05")},
+		{Symbol: "SZ000001", Start: date("2023-01-06"), End: openSpanEnd},
+	}))
+	engine := NewFactorEngine("", "", dir)
+	engine.SetUniverseRegistry(registry)
+
+	result, err := engine.EvaluateFactor(context.Background(), "$close", FrameRequest{
+		Universe: "custom",
+		Start:    date("2023-01-03"),
+		End:      date("2023-01-09"),
+	})
+	if err != nil {
+		t.Fatalf("EvaluateFactor failed: %v", err)
+	}
+	if len(result.Instruments) != 2 {
+		t.Fatalf("unexpected instruments: %v", result.Instruments)
+	}
+	sh, sz := result.Values[0], result.Values[1]
+	if sh[1] != 11 || !math.IsNaN(sh[3]) {
+		t.Errorf("SH600000 should only have values before it leaves: %v", sh)
+	}
+	if !math.IsNaN(sz[2]) || sz[3] != 21 {
+		t.Errorf("SZ000001 should only have values after it joins: %v", sz)
+	}
+
+	// 未注册的股票池从数据目录的 instruments 文件读取
+	result, err = engine.EvaluateFactor(context.Background(), "$close", FrameRequest{
+		Universe: "csi300",
+		Start:    date("2023-01-03"),
+		End:      date("2023-01-09"),
+	})
+	if err != nil || len(result.Instruments) != 1 || result.Instruments[0] != "SH600000" {
+		t.Fatalf("csi300 should resolve from instruments file: %+v, %v", result, err)
+	}
+	if _, err := engine.EvaluateFactor(context.Background(), "$close", FrameRequest{Universe: "csi500"}); err == nil {
+		t.Error("unknown universe should fail")
+	}
+}
@@ -18,6 +18,8 @@ type WorkflowEngine struct {
 	pythonPath  string
 	scriptDir   string
 	workspaceDir string
+	dataProvider  MarketDataProvider
+	windowTrainer WindowTrainer
 }
 
 // WorkflowTemplate 工作流模板
@@ -92,6 +94,16 @@ func NewWorkflowEngine(db *gorm.DB) *WorkflowEngine {
 	}
 }
 
+// SetDataProvider 设置行情数据提供者，滚动训练使用其交易日历并对拼接后的信号执行原生回测
+func (we *WorkflowEngine) SetDataProvider(provider MarketDataProvider) {
+	we.dataProvider = provider
+}
+
+// SetWindowTrainer 设置滚动训练中单个窗口的训练函数，未设置时调用Python脚本训练
+func (we *WorkflowEngine) SetWindowTrainer(trainer WindowTrainer) {
+	we.windowTrainer = trainer
+}
+
 // Execute 执行工作流
 func (we *WorkflowEngine) Execute(ctx context.Context, template *WorkflowTemplate, config map[string]interface{}, callback WorkflowProgressCallback) (map[string]interface{}, error) {
 	startTime := time.Now()
@@ -192,6 +204,8 @@ func (we *WorkflowEngine) executeStep(ctx context.Context, step WorkflowStep, st
 		err = we.executeModelTraining(ctx, step, stepContext, workflowDir, result)
 	case "strategy_backtest":
 		err = we.executeStrategyBacktest(ctx, step, stepContext, workflowDir, result)
+	case "rolling_training":
+		err = we.executeRollingTraining(ctx, step, stepContext, workflowDir, result)
 	case "result_analysis":
 		err = we.executeResultAnalysis(ctx, step, stepContext, workflowDir, result)
 	case "report_generation":
@@ -1011,5 +1025,45 @@ func (we *WorkflowEngine) GetBuiltinTemplates() []WorkflowTemplate {
 				},
 			},
 		},
+		{
+			Name:        "滚动训练工作流",
+			Description: "按滚动窗口逐期重新训练模型，拼接样本外预测后统一回测",
+			Category:    "strategy",
+			Config: map[string]interface{}{
+				"instruments": []string{"AAPL", "MSFT", "GOOGL", "TSLA", "AMZN"},
+				"start_time":  "2018-01-01",
+				"end_time":    "2023-12-31",
+				"model_type":  "lightgbm",
+				"strategy":    "TopkDropoutStrategy",
+				"rolling": map[string]interface{}{
+					"train_length": 504,
+					"valid_length": 126,
+					"step":         63,
+					"gap":          2,
+				},
+			},
+			Steps: []WorkflowStep{
+				{
+					Name:        "数据准备",
+					Type:        "data_preparation",
+					Description: "获取和清洗市场数据",
+					Required:    true,
+				},
+				{
+					Name:         "因子生成",
+					Type:         "factor_generation",
+					Description:  "计算技术指标和因子",
+					Dependencies: []string{"数据准备"},
+					Required:     true,
+				},
+				{
+					Name:         "滚动训练",
+					Type:         "rolling_training",
+					Description:  "逐窗口训练模型并对拼接后的样本外信号回测",
+					Dependencies: []string{"因子生成"},
+					Required:     true,
+				},
+			},
+		},
 	}
 }
\ No newline at end of file
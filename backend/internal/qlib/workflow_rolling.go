@@ -0,0 +1,309 @@
+package qlib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WindowTrainer 训练单个滚动窗口的模型，返回该窗口测试段的预测
+type WindowTrainer func(ctx context.Context, window RollingWindow, config map[string]interface{}, windowDir string) (*WindowTrainingResult, error)
+
+// WindowTrainingResult 单个窗口的训练结果
+type WindowTrainingResult struct {
+	ModelPath   string
+	Metrics     map[string]float64
+	Predictions *FactorFrame
+}
+
+// RollingWindowResult 滚动训练中单个窗口的记录，作为工作流执行的子结果保存
+type RollingWindowResult struct {
+	Window      RollingWindow      `json:"window"`
+	ModelPath   string             `json:"model_path"`
+	Metrics     map[string]float64 `json:"metrics"`
+	Predictions int                `json:"predictions"` // 测试段内有效预测条数
+	Duration    time.Duration      `json:"duration"`
+}
+
+// tradingCalendarSource 可直接提供交易日历的数据源，如 BinDataReader
+type tradingCalendarSource interface {
+	Calendar(freq string) ([]time.Time, error)
+}
+
+// executeRollingTraining 执行滚动训练步骤
+//
+// 按 rolling 配置生成连续窗口，逐窗训练模型并预测测试段，将各测试段预测拼接为一个连续信号后执行一次原生回测。
+// 步骤配置未提供的参数从工作流配置中读取。
+func (we *WorkflowEngine) executeRollingTraining(ctx context.Context, step WorkflowStep, stepContext map[string]interface{}, workflowDir string, result *StepResult) error {
+	if we.dataProvider == nil {
+		return fmt.Errorf("未设置行情数据，无法执行滚动训练回测")
+	}
+	params := stepParams(step, stepContext)
+	rolling, err := ParseRollingConfig(params["rolling"])
+	if err != nil {
+		return err
+	}
+	if rolling.Start == "" {
+		rolling.Start = paramString(params, "start_time", "")
+	}
+	if rolling.End == "" {
+		rolling.End = paramString(params, "end_time", "")
+	}
+
+	calendar, err := we.tradingCalendar(ctx, rolling, paramString(params, "freq", "day"))
+	if err != nil {
+		return err
+	}
+	windows, err := GenerateRollingWindows(calendar, rolling)
+	if err != nil {
+		return err
+	}
+
+	trainer := we.windowTrainer
+	if trainer == nil {
+		trainer = func(ctx context.Context, window RollingWindow, config map[string]interface{}, windowDir string) (*WindowTrainingResult, error) {
+			return we.trainWindow(ctx, window, config, stepContext, windowDir)
+		}
+	}
+
+	records := make([]RollingWindowResult, 0, len(windows))
+	predictions := make([]*FactorFrame, 0, len(windows))
+	for _, window := range windows {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("滚动训练被取消")
+		}
+		windowDir := filepath.Join(workflowDir, "rolling", fmt.Sprintf("window_%02d", window.Index))
+		if err := os.MkdirAll(windowDir, 0755); err != nil {
+			return fmt.Errorf("创建窗口目录失败: %v", err)
+		}
+
+		startTime := time.Now()
+		trained, err := trainer(ctx, window, params, windowDir)
+		if err != nil {
+			return fmt.Errorf("窗口%d训练失败: %v", window.Index, err)
+		}
+		testPredictions := trained.Predictions
+		count := 0
+		if testPredictions != nil {
+			testPredictions = testPredictions.Trim(window.TestStart, window.TestEnd)
+			count = int(testPredictions.Statistics()["count"])
+		}
+		predictions = append(predictions, testPredictions)
+		records = append(records, RollingWindowResult{
+			Window:      window,
+			ModelPath:   trained.ModelPath,
+			Metrics:     trained.Metrics,
+			Predictions: count,
+			Duration:    time.Since(startTime),
+		})
+	}
+
+	signal, err := StitchPredictions(windows, predictions)
+	if err != nil {
+		return err
+	}
+
+	strategyParams := params
+	if raw, ok := params["strategy_params"].(map[string]interface{}); ok {
+		strategyParams = raw
+	}
+	strategy, err := NewSignalStrategy(paramString(params, "strategy", "TopkDropoutStrategy"), strategyParams)
+	if err != nil {
+		return err
+	}
+	config := NativeBacktestConfig{
+		Start:     windows[0].TestStart,
+		End:       windows[len(windows)-1].TestEnd,
+		Account:   paramFloat(params, "account", 0),
+		Benchmark: paramString(params, "benchmark", ""),
+	}
+	if exchange, ok := params["exchange"]; ok {
+		data, _ := json.Marshal(exchange)
+		if err := json.Unmarshal(data, &config.Exchange); err != nil {
+			return fmt.Errorf("解析交易所配置失败: %v", err)
+		}
+	}
+	report, err := NewNativeBacktester(we.dataProvider).Run(ctx, config, signal, strategy, nil)
+	if err != nil {
+		return fmt.Errorf("滚动信号回测失败: %w", err)
+	}
+
+	result.Output = map[string]interface{}{
+		"windows":     records,
+		"signal":      signal.Statistics(),
+		"test_start":  formatDate(config.Start),
+		"test_end":    formatDate(config.End),
+		"performance": report.Summary,
+		"daily":       report.Daily,
+	}
+	return nil
+}
+
+// tradingCalendar 获取滚动区间内的交易日历
+func (we *WorkflowEngine) tradingCalendar(ctx context.Context, rolling RollingConfig, freq string) ([]time.Time, error) {
+	if source, ok := we.dataProvider.(tradingCalendarSource); ok {
+		return source.Calendar(freq)
+	}
+	start, err := parseOptionalDate(rolling.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseOptionalDate(rolling.End)
+	if err != nil {
+		return nil, err
+	}
+	frame, err := we.dataProvider.LoadFrame(ctx, FrameRequest{Start: start, End: end, Freq: freq})
+	if err != nil {
+		return nil, fmt.Errorf("获取交易日历失败: %v", err)
+	}
+	return frame.Calendar, nil
+}
+
+// trainWindow 调用Python脚本在窗口的训练集上训练模型，并输出测试段预测
+func (we *WorkflowEngine) trainWindow(ctx context.Context, window RollingWindow, config map[string]interface{}, stepContext map[string]interface{}, windowDir string) (*WindowTrainingResult, error) {
+	scriptConfig := make(map[string]interface{}, len(config)+2)
+	for k, v := range config {
+		scriptConfig[k] = v
+	}
+	scriptConfig["segments"] = window.Segments()
+	scriptConfig["output_dir"] = windowDir
+
+	stepResult := &StepResult{Output: make(map[string]interface{})}
+	if err := we.executeScript(ctx, rollingTrainingScript, scriptConfig, stepContext, stepResult); err != nil {
+		return nil, err
+	}
+
+	trained := &WindowTrainingResult{Metrics: make(map[string]float64)}
+	trained.ModelPath, _ = stepResult.Output["model_file"].(string)
+	if metrics, ok := stepResult.Output["metrics"].(map[string]interface{}); ok {
+		for k := range metrics {
+			trained.Metrics[k] = getFloat64(metrics, k)
+		}
+	}
+
+	predictionsFile, _ := stepResult.Output["predictions_file"].(string)
+	data, err := os.ReadFile(predictionsFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取窗口预测失败: %v", err)
+	}
+	var values []PredictionValue
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("解析窗口预测失败: %v", err)
+	}
+	trained.Predictions = PredictionsToFrame(values)
+	return trained, nil
+}
+
+// stepParams 合并工作流配置和步骤配置，步骤配置优先
+func stepParams(step WorkflowStep, stepContext map[string]interface{}) map[string]interface{} {
+	params := make(map[string]interface{})
+	if workflowConfig, ok := stepContext["config"].(map[string]interface{}); ok {
+		for k, v := range workflowConfig {
+			params[k] = v
+		}
+	}
+	for k, v := range step.Config {
+		params[k] = v
+	}
+	return params
+}
+
+// rollingTrainingScript 单窗口训练脚本，使用因子生成步骤输出的因子和数据准备步骤输出的价格
+const rollingTrainingScript = `
+import pandas as pd
+import numpy as np
+import json
+import sys
+from pathlib import Path
+import joblib
+
+def select(data, segment):
+    dates = data.index.get_level_values(1)
+    return data[(dates >= segment[0]) & (dates <= segment[1])]
+
+def daily_ic(frame):
+    ic = frame.groupby(level=1).apply(lambda x: x['score'].corr(x['label']))
+    return float(ic.mean()) if len(ic.dropna()) > 0 else 0.0
+
+def train_window(config):
+    try:
+        workspace = Path(config['workspace_dir'])
+        factor_file = workspace / 'factors.pkl'
+        if not factor_file.exists():
+            raise ValueError("因子文件不存在，请先执行因子生成步骤")
+        factor_data = pd.read_pickle(str(factor_file))
+        price_data = pd.read_pickle(str(workspace / 'prepared_data.pkl'))
+        labels = price_data.groupby(level=0)['$close'].apply(lambda x: x.shift(-1) / x - 1)
+        data = pd.concat([factor_data, labels.rename('label')], axis=1)
+        feature_cols = [col for col in data.columns if col != 'label']
+
+        segments = config['segments']
+        train_set = select(data, segments['train']).dropna()
+        if len(train_set) == 0:
+            raise ValueError("训练集为空")
+
+        model_type = config.get('model_type', 'lightgbm')
+        if model_type == 'lightgbm':
+            import lightgbm as lgb
+            model = lgb.LGBMRegressor(
+                n_estimators=config.get('n_estimators', 100),
+                learning_rate=config.get('learning_rate', 0.1),
+                random_state=42
+            )
+        else:
+            from sklearn.linear_model import LinearRegression
+            model = LinearRegression()
+        model.fit(train_set[feature_cols], train_set['label'])
+
+        metrics = {'train_samples': len(train_set), 'features_count': len(feature_cols)}
+        train_eval = pd.DataFrame({'score': model.predict(train_set[feature_cols]), 'label': train_set['label']}, index=train_set.index)
+        metrics['train_ic'] = daily_ic(train_eval)
+        if segments.get('valid'):
+            valid_set = select(data, segments['valid']).dropna()
+            if len(valid_set) > 0:
+                valid_eval = pd.DataFrame({'score': model.predict(valid_set[feature_cols]), 'label': valid_set['label']}, index=valid_set.index)
+                metrics['valid_ic'] = daily_ic(valid_eval)
+                metrics['valid_samples'] = len(valid_set)
+
+        # 测试段只需要特征，最后一天没有标签也要预测
+        test_set = select(data, segments['test']).dropna(subset=feature_cols)
+        scores = model.predict(test_set[feature_cols]) if len(test_set) > 0 else []
+        test_eval = pd.DataFrame({'score': scores, 'label': test_set['label']}, index=test_set.index)
+        labelled = test_eval.dropna()
+        if len(labelled) > 0:
+            metrics['test_ic'] = daily_ic(labelled)
+        metrics['test_samples'] = len(test_set)
+
+        output_dir = Path(config['output_dir'])
+        model_file = output_dir / 'model.pkl'
+        joblib.dump(model, str(model_file))
+
+        predictions = [
+            {'instrument': str(inst), 'date': pd.Timestamp(date).strftime('%Y-%m-%dT00:00:00Z'), 'score': float(score)}
+            for (inst, date), score in zip(test_set.index, scores)
+        ]
+        predictions_file = output_dir / 'predictions.json'
+        with open(str(predictions_file), 'w') as f:
+            json.dump(predictions, f)
+
+        return {
+            'success': True,
+            'metrics': metrics,
+            'model_file': str(model_file),
+            'predictions_file': str(predictions_file)
+        }
+
+    except Exception as e:
+        return {
+            'success': False,
+            'error': str(e)
+        }
+
+if __name__ == "__main__":
+    config = json.loads(sys.argv[1])
+    result = train_window(config)
+    print(json.dumps(result))
+`
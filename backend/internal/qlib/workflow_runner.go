@@ -25,6 +25,11 @@ type WorkflowConfig struct {
 	EndTime       string                 `json:"end_time"`       // 结束时间
 	Features      []string               `json:"features"`       // 特征列表
 	Label         string                 `json:"label"`          // 标签
+
+	// 预处理配置，为空时使用 RobustZScoreNorm+Fillna 和 DropnaLabel+CSRankNorm
+	FitPeriod       []string          `json:"fit_period"`       // 标准化参数的拟合区间，默认为训练区间
+	InferProcessors []ProcessorConfig `json:"infer_processors"` // 特征预处理器
+	LearnProcessors []ProcessorConfig `json:"learn_processors"` // 仅训练时使用的预处理器
 	
 	// 模型配置
 	Model         WFModelConfig            `json:"model"`          // 模型配置
@@ -78,6 +83,9 @@ type ExchangeConfig struct {
 	Open_cost     float64                `json:"open_cost"`    // 开仓成本
 	Close_cost    float64                `json:"close_cost"`   // 平仓成本
 	Trade_unit    int                    `json:"trade_unit"`   // 交易单位
+	Min_cost      float64                `json:"min_cost"`     // 单笔最低手续费
+	Stamp_duty    float64                `json:"stamp_duty,omitempty"` // 卖出印花税率
+	T_plus_one    bool                   `json:"t_plus_one,omitempty"` // 当日买入次日才能卖出
 }
 
 // RecorderConfig 记录器配置
@@ -176,16 +184,11 @@ func (wr *WorkflowRunner) RunWorkflow(ctx context.Context, config WorkflowConfig
 		Results:   make(map[string]interface{}),
 	}
 
-	// 生成工作流脚本
-	script, err := wr.generateWorkflowScript(config, taskOutputDir)
-	if err != nil {
-		result.Status = "failed"
-		result.ErrorMessage = fmt.Sprintf("生成脚本失败: %v", err)
-		return result, err
-	}
-
-	// 执行工作流脚本
-	output, err := wr.client.ExecuteScript(ctx, script)
+	// 执行工作流入口脚本
+	output, err := wr.client.RunEntryPoint(ctx, "run_workflow", map[string]interface{}{
+		"output_dir": taskOutputDir,
+		"config":     config,
+	})
 	if err != nil {
 		result.Status = "failed"
 		result.ErrorMessage = fmt.Sprintf("执行工作流失败: %v", err)
@@ -208,237 +211,6 @@ func (wr *WorkflowRunner) RunWorkflow(ctx context.Context, config WorkflowConfig
 	return result, nil
 }
 
-// generateWorkflowScript 生成工作流执行脚本
-func (wr *WorkflowRunner) generateWorkflowScript(config WorkflowConfig, outputDir string) (string, error) {
-	configJSON, err := json.Marshal(config)
-	if err != nil {
-		return "", fmt.Errorf("序列化配置失败: %w", err)
-	}
-
-	script := fmt.Sprintf(`
-import json
-import sys
-import os
-import pandas as pd
-import numpy as np
-from datetime import datetime
-import qlib
-from qlib.workflow import R
-from qlib.workflow.record_temp import SignalRecord, PortAnaRecord
-from qlib.data.dataset import DatasetH
-from qlib.data.dataset.handler import DataHandlerLP
-from qlib.model.trainer import task_train
-from qlib.backtest import executor as qexecutor
-from qlib.contrib.strategy.signal_strategy import TopkDropoutStrategy
-from qlib.contrib.evaluate import backtest_analyze
-
-# 设置输出目录
-output_dir = '%s'
-os.makedirs(output_dir, exist_ok=True)
-
-# 加载配置
-config = json.loads('''%s''')
-
-try:
-    # 步骤1: 数据准备
-    print("步骤1: 准备数据集...")
-    
-    # 构建数据处理器配置
-    data_handler_config = {
-        "start_time": config["start_time"],
-        "end_time": config["end_time"],
-        "fit_start_time": config["train_period"][0],
-        "fit_end_time": config["train_period"][1],
-        "instruments": config["market"],
-        "infer_processors": [
-            {"class": "RobustZScoreNorm", "kwargs": {"fields_group": "feature", "clip_outlier": True}},
-            {"class": "Fillna", "kwargs": {"fields_group": "feature"}}
-        ],
-        "learn_processors": [
-            {"class": "DropnaLabel"},
-            {"class": "CSRankNorm", "kwargs": {"fields_group": "label"}}
-        ]
-    }
-    
-    # 创建数据集
-    dataset = DatasetH(
-        handler={
-            "class": "Alpha158",
-            "module_path": "qlib.contrib.data.handler",
-            "kwargs": data_handler_config
-        }
-    )
-    
-    print("数据集准备完成")
-    
-    # 步骤2: 模型训练
-    print("步骤2: 训练模型...")
-    
-    # 构建模型配置
-    model_config = {
-        "class": config["model"]["class"],
-        "module_path": config["model"]["module"],
-        "kwargs": config["model"].get("kwargs", {})
-    }
-    
-    # 训练模型
-    with R.start(experiment_name="qlib_workflow"):
-        model = task_train(
-            dataset, 
-            model=model_config
-        )
-        
-        # 保存模型
-        model_path = os.path.join(output_dir, "model.pkl")
-        with open(model_path, 'wb') as f:
-            import pickle
-            pickle.dump(model, f)
-        
-        # 获取预测结果
-        pred_score = model.predict(dataset)
-        
-        # 计算模型指标
-        from qlib.contrib.evaluate import risk_analysis
-        from qlib.contrib.strategy.signal_strategy import TopkDropoutStrategy
-        
-        # 信号分析
-        signal_record = SignalRecord(model, dataset, recorder=R.get_recorder())
-        signal_record.generate()
-        
-        # 获取IC等指标
-        pred_label = dataset.prepare(["label"], col_set="label")
-        ic_metrics = {}
-        
-        if len(pred_score) > 0 and len(pred_label) > 0:
-            # 计算IC指标
-            ic_data = []
-            for date in pred_score.index.get_level_values(0).unique():
-                if date in pred_label.index.get_level_values(0):
-                    pred_day = pred_score.loc[date]
-                    label_day = pred_label.loc[date]
-                    merged = pd.concat([pred_day, label_day], axis=1, join='inner')
-                    if len(merged) > 1:
-                        ic = merged.iloc[:, 0].corr(merged.iloc[:, 1])
-                        rank_ic = merged.iloc[:, 0].corr(merged.iloc[:, 1], method='spearman')
-                        ic_data.append({'date': date, 'ic': ic, 'rank_ic': rank_ic})
-            
-            ic_df = pd.DataFrame(ic_data)
-            if len(ic_df) > 0:
-                ic_metrics = {
-                    'ic': ic_df['ic'].mean(),
-                    'rank_ic': ic_df['rank_ic'].mean(),
-                    'icir': ic_df['ic'].mean() / ic_df['ic'].std() if ic_df['ic'].std() > 0 else 0,
-                    'rank_icir': ic_df['rank_ic'].mean() / ic_df['rank_ic'].std() if ic_df['rank_ic'].std() > 0 else 0
-                }
-        
-        print("模型训练完成")
-        
-        # 步骤3: 策略回测
-        print("步骤3: 执行策略回测...")
-        
-        # 构建策略配置
-        strategy_config = config.get("strategy", {
-            "class": "TopkDropoutStrategy",
-            "module_path": "qlib.contrib.strategy.signal_strategy", 
-            "kwargs": {
-                "signal": pred_score,
-                "topk": 50,
-                "n_drop": 5
-            }
-        })
-        
-        # 构建回测配置
-        backtest_config = config.get("backtest", {
-            "start_time": config["test_period"][0],
-            "end_time": config["test_period"][1],
-            "account": 100000000,
-            "benchmark": "SH000300",
-            "exchange_kwargs": {
-                "freq": "day",
-                "limit_threshold": 0.095,
-                "deal_price": "close",
-                "open_cost": 0.0005,
-                "close_cost": 0.0015,
-                "trade_unit": 100
-            }
-        })
-        
-        # 创建策略
-        strategy = TopkDropoutStrategy(**strategy_config.get("kwargs", {}))
-        
-        # 执行回测
-        executor_config = {
-            "class": "SimulatorExecutor",
-            "module_path": "qlib.backtest.executor",
-            "kwargs": {
-                "time_per_step": "day",
-                "generate_portfolio_metrics": True
-            }
-        }
-        
-        portfolio_metric_dict, indicator_dict = qexecutor.backtest(
-            start_time=backtest_config["start_time"],
-            end_time=backtest_config["end_time"],
-            strategy=strategy,
-            executor=executor_config,
-            benchmark=backtest_config["benchmark"],
-            account=backtest_config["account"],
-            exchange_kwargs=backtest_config["exchange_kwargs"]
-        )
-        
-        # 计算回测指标
-        backtest_results = {}
-        if 'excess_return_wo_cost' in portfolio_metric_dict:
-            returns = portfolio_metric_dict['excess_return_wo_cost'].dropna()
-            if len(returns) > 0:
-                total_return = (1 + returns).prod() - 1
-                annual_return = (1 + returns).mean() * 252 - 1
-                volatility = returns.std() * np.sqrt(252)
-                sharpe_ratio = annual_return / volatility if volatility > 0 else 0
-                max_drawdown = (returns.cumsum() - returns.cumsum().cummax()).min()
-                
-                backtest_results = {
-                    'total_return': float(total_return),
-                    'annual_return': float(annual_return),
-                    'sharpe_ratio': float(sharpe_ratio),
-                    'max_drawdown': float(max_drawdown),
-                    'volatility': float(volatility),
-                    'win_rate': float((returns > 0).mean())
-                }
-        
-        print("策略回测完成")
-        
-        # 保存结果
-        results = {
-            'task_id': os.path.basename(output_dir),
-            'status': 'completed',
-            'progress': 100,
-            'model_metrics': ic_metrics,
-            'backtest_results': backtest_results,
-            'output_dir': output_dir
-        }
-        
-        # 保存结果到文件
-        with open(os.path.join(output_dir, 'results.json'), 'w') as f:
-            json.dump(results, f, indent=2, default=str)
-        
-        print("工作流执行完成")
-        print(json.dumps(results, default=str))
-
-except Exception as e:
-    error_result = {
-        'task_id': os.path.basename(output_dir),
-        'status': 'failed',
-        'progress': 0,
-        'error_message': str(e)
-    }
-    print(json.dumps(error_result))
-    sys.exit(1)
-`, outputDir, string(configJSON))
-
-	return script, nil
-}
-
 // parseWorkflowResults 解析工作流执行结果
 func (wr *WorkflowRunner) parseWorkflowResults(output []byte, outputDir string, result *WFResult) error {
 	// 尝试解析JSON输出
@@ -644,11 +416,30 @@ func (wr *WorkflowRunner) ValidateWorkflowConfig(config WorkflowConfig) error {
 		return fmt.Errorf("模型模块路径不能为空")
 	}
 
+	// 验证预处理器：Python工作流只能执行Qlib自带的处理器
+	if len(config.FitPeriod) != 0 && len(config.FitPeriod) != 2 {
+		return fmt.Errorf("拟合时间段必须包含开始和结束时间")
+	}
+	for _, processors := range [][]ProcessorConfig{config.InferProcessors, config.LearnProcessors} {
+		if _, err := NewProcessorChain(processors, time.Time{}, time.Time{}); err != nil {
+			return err
+		}
+		for _, processor := range processors {
+			if !IsQlibProcessor(processor) {
+				return fmt.Errorf("预处理器 %s 仅支持原生因子计算，不能用于Qlib工作流", processor.Class)
+			}
+		}
+	}
+
 	return nil
 }
 
 // GenerateWorkflowYAML 生成Qlib YAML配置文件
 func (wr *WorkflowRunner) GenerateWorkflowYAML(config WorkflowConfig) (string, error) {
+	fitPeriod := config.TrainPeriod
+	if len(config.FitPeriod) == 2 {
+		fitPeriod = config.FitPeriod
+	}
 	yamlConfig := map[string]interface{}{
 		"qlib_init": map[string]interface{}{
 			"provider_uri": "~/.qlib/qlib_data/cn_data",
@@ -659,9 +450,11 @@ func (wr *WorkflowRunner) GenerateWorkflowYAML(config WorkflowConfig) (string, e
 		"data_handler_config": map[string]interface{}{
 			"start_time":     config.StartTime,
 			"end_time":       config.EndTime,
-			"fit_start_time": config.TrainPeriod[0],
-			"fit_end_time":   config.TrainPeriod[1],
-			"instruments":    config.Market,
+			"fit_start_time":   fitPeriod[0],
+			"fit_end_time":     fitPeriod[1],
+			"instruments":      config.Market,
+			"infer_processors": config.InferProcessors,
+			"learn_processors": config.LearnProcessors,
 		},
 		"port_analysis_config": map[string]interface{}{
 			"strategy": map[string]interface{}{
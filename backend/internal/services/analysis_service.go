@@ -6,6 +6,7 @@ import (
 	"sort"
 	"time"
 
+	"qlib-backend/internal/analytics"
 	"qlib-backend/internal/models"
 
 	"gorm.io/gorm"
@@ -302,7 +303,10 @@ func (as *AnalysisService) CompareModels(req models.ModelComparisonRequest, user
 	result.RankingTable = ranking
 	
 	// 进行统计测试
-	statTest := as.performStatisticalTest(modelPerformances)
+	statTest, err := as.performStatisticalTest(modelPerformances, req.Correction)
+	if err != nil {
+		return nil, fmt.Errorf("统计检验失败: %v", err)
+	}
 	result.StatisticalTest = statTest
 	
 	// 生成对比总结
@@ -626,18 +630,13 @@ func (as *AnalysisService) generateModelRanking(modelPerformances []models.Model
 	return ranking
 }
 
-// performStatisticalTest 执行统计测试
-func (as *AnalysisService) performStatisticalTest(modelPerformances []models.ModelPerformance) *models.StatisticalTestResult {
-	// 简化的统计测试
-	return &models.StatisticalTestResult{
-		TestType:  "t-test",
-		PValue:    0.05,
-		Statistic: 1.96,
-		Result:    "not significant",
-		Details: map[string]interface{}{
-			"note": "简化的统计测试实现",
-		},
+// performStatisticalTest 对模型测试集每日IC做两两显著性检验
+func (as *AnalysisService) performStatisticalTest(modelPerformances []models.ModelPerformance, correction string) (*models.StatisticalTestResult, error) {
+	candidates, err := modelICCandidates(as.db, modelPerformances)
+	if err != nil {
+		return nil, err
 	}
+	return pairwiseSignificance(candidates, "daily_ic", analytics.CorrectionMethod(correction)), nil
 }
 
 // generateComparisonSummary 生成对比总结
@@ -718,38 +717,72 @@ func (as *AnalysisService) generateImportanceSummary(factors []FactorImportance)
 	}
 }
 
-// calculateDetailedPerformance 计算详细性能指标
+// loadStrategySeries 读取策略保存的回测记录，未保存记录时返回 nil
+func (as *AnalysisService) loadStrategySeries(strategy models.Strategy) (*backtestSeries, error) {
+	artifacts, err := loadBacktestArtifacts(as.db, strategy.ID)
+	if err != nil || artifacts == nil {
+		return nil, err
+	}
+	return buildBacktestSeries(artifacts, "all")
+}
+
+// calculateDetailedPerformance 计算详细性能指标，没有回测记录时仅返回策略汇总指标
 func (as *AnalysisService) calculateDetailedPerformance(strategy models.Strategy) (*DetailedPerformance, error) {
+	series, err := as.loadStrategySeries(strategy)
+	if err != nil {
+		return nil, err
+	}
+	if series == nil {
+		return &DetailedPerformance{
+			TotalReturn:      strategy.TotalReturn,
+			AnnualReturn:     strategy.AnnualReturn,
+			VolatilityAnnual: strategy.Volatility,
+			SharpeRatio:      strategy.SharpeRatio,
+			MaxDrawdown:      strategy.MaxDrawdown,
+			WinRate:          strategy.WinRate,
+		}, nil
+	}
+
+	m := series.Metrics
 	return &DetailedPerformance{
-		TotalReturn:      strategy.TotalReturn,
-		AnnualReturn:     strategy.AnnualReturn,
-		VolatilityAnnual: strategy.Volatility,
-		SharpeRatio:      strategy.SharpeRatio,
-		SortinoRatio:     strategy.SharpeRatio * 1.2, // 简化计算
-		CalmarRatio:      strategy.AnnualReturn / math.Abs(strategy.MaxDrawdown),
-		MaxDrawdown:      strategy.MaxDrawdown,
-		MaxDrawdownDuration: 30, // 简化数据
-		WinRate:          strategy.WinRate,
-		ProfitFactor:     2.5, // 简化数据
-		PayoffRatio:      1.8, // 简化数据
+		TotalReturn:         m.TotalReturn,
+		AnnualReturn:        m.AnnualReturn,
+		VolatilityAnnual:    m.AnnualVolatility,
+		SharpeRatio:         m.SharpeRatio,
+		SortinoRatio:        m.SortinoRatio,
+		CalmarRatio:         m.CalmarRatio,
+		MaxDrawdown:         m.Drawdown.MaxDrawdown,
+		MaxDrawdownDuration: m.Drawdown.LongestUnderwater,
+		WinRate:             m.WinRate,
+		ProfitFactor:        analytics.OmegaRatio(series.Returns, 0), // 阈值为0的Omega比率即盈利总和/亏损总和
+		PayoffRatio:         m.PayoffRatio,
 	}, nil
 }
 
-// calculateRiskMetrics 计算风险指标
+// calculateRiskMetrics 计算风险指标，没有回测记录时返回 nil
 func (as *AnalysisService) calculateRiskMetrics(strategy models.Strategy) (*RiskAnalysis, error) {
-	return &RiskAnalysis{
-		VaR95:            -0.05,  // 简化数据
-		VaR99:            -0.08,  // 简化数据
-		CVaR95:           -0.07,  // 简化数据
-		CVaR99:           -0.10,  // 简化数据
-		DownsideDeviation: strategy.Volatility * 0.8,
-		UpsideCapture:    1.1,    // 简化数据
-		DownsideCapture:  0.9,    // 简化数据
-		Beta:             1.0,    // 简化数据
-		Alpha:            0.02,   // 简化数据
-		TrackingError:    0.05,   // 简化数据
-		InformationRatio: strategy.SharpeRatio * 0.8,
-	}, nil
+	series, err := as.loadStrategySeries(strategy)
+	if err != nil || series == nil {
+		return nil, err
+	}
+
+	m := series.Metrics
+	risk := &RiskAnalysis{
+		VaR95:             m.Historical.VaR95,
+		VaR99:             m.Historical.VaR99,
+		CVaR95:            m.Historical.CVaR95,
+		CVaR99:            m.Historical.CVaR99,
+		DownsideDeviation: m.DownsideDeviation,
+	}
+	if m.Relative != nil {
+		risk.UpsideCapture = m.Relative.UpCapture
+		risk.DownsideCapture = m.Relative.DownCapture
+		risk.Beta = m.Relative.Beta
+		risk.Alpha = m.Relative.Alpha
+		risk.TrackingError = m.Relative.TrackingError
+		risk.InformationRatio = m.Relative.InformationRatio
+	}
+	return risk, nil
 }
 
 // performAttributionAnalysis 执行归因分析
@@ -781,16 +814,19 @@ func (as *AnalysisService) performAttributionAnalysis(strategy models.Strategy)
 
 // getTimeSeriesData 获取时间序列数据
 func (as *AnalysisService) getTimeSeriesData(strategy models.Strategy) (*TimeSeriesData, error) {
-	// 模拟时间序列数据
-	dates := make([]string, 252) // 一年的交易日
-	cumReturns := make([]float64, 252)
-	dailyReturns := make([]float64, 252)
+	// 模拟时间序列数据，日期取截至今天最近一年的交易日
+	tradingDays := marketCalendar("").Between(time.Time{}, time.Now())
+	if len(tradingDays) > 252 {
+		tradingDays = tradingDays[len(tradingDays)-252:]
+	}
+	dates := make([]string, len(tradingDays))
+	cumReturns := make([]float64, len(tradingDays))
+	dailyReturns := make([]float64, len(tradingDays))
 	
-	baseDate := time.Now().AddDate(-1, 0, 0)
 	cumReturn := 1.0
 	
-	for i := 0; i < 252; i++ {
-		dates[i] = baseDate.AddDate(0, 0, i).Format("2006-01-02")
+	for i, day := range tradingDays {
+		dates[i] = day.Format("2006-01-02")
 		dailyReturn := (strategy.AnnualReturn/252) + (0.01*math.Sin(float64(i)/10)) // 简化的模拟数据
 		dailyReturns[i] = dailyReturn
 		cumReturn *= (1 + dailyReturn)
@@ -801,26 +837,19 @@ func (as *AnalysisService) getTimeSeriesData(strategy models.Strategy) (*TimeSer
 		Dates:             dates,
 		CumulativeReturns: cumReturns,
 		DailyReturns:      dailyReturns,
-		RollingVolatility: make([]float64, 252), // 简化处理
-		RollingSharpe:     make([]float64, 252), // 简化处理
-		Drawdowns:         make([]float64, 252), // 简化处理
+		RollingVolatility: make([]float64, len(tradingDays)), // 简化处理
+		RollingSharpe:     make([]float64, len(tradingDays)), // 简化处理
+		Drawdowns:         make([]float64, len(tradingDays)), // 简化处理
 	}, nil
 }
 
-// performBenchmarkComparison 执行基准对比
+// performBenchmarkComparison 执行基准对比，没有基准记录时返回 nil
 func (as *AnalysisService) performBenchmarkComparison(strategy models.Strategy) (*models.BenchmarkComparison, error) {
-	benchmarkReturn := 0.08 // 假设基准年收益率8%
-	
-	return &models.BenchmarkComparison{
-		BenchmarkName:     "CSI300",
-		ExcessReturn:      strategy.AnnualReturn - benchmarkReturn,
-		TrackingError:     0.05,
-		InformationRatio:  (strategy.AnnualReturn - benchmarkReturn) / 0.05,
-		ActiveReturn:      strategy.AnnualReturn - benchmarkReturn,
-		UpCapture:         1.1,
-		DownCapture:       0.9,
-		CorrelationCoeff:  0.85,
-	}, nil
+	series, err := as.loadStrategySeries(strategy)
+	if err != nil || series == nil || series.Metrics.Relative == nil {
+		return nil, err
+	}
+	return benchmarkComparison(series.BenchmarkName, series.Metrics), nil
 }
 
 // CompareStrategies 多策略对比
@@ -839,11 +868,18 @@ func (as *AnalysisService) CompareStrategies(userID uint, strategyIDs []uint, me
 		return nil, fmt.Errorf("部分策略不存在或无权限访问")
 	}
 	
+	// 对每日收益做两两显著性检验
+	candidates, err := strategyReturnCandidates(as.db, strategies)
+	if err != nil {
+		return nil, fmt.Errorf("统计检验失败: %v", err)
+	}
+	
 	result := &StrategyComparisonAnalysis{
 		Strategies: strategies,
 		ComparisonMetrics: as.generateStrategyComparisonMetrics(strategies, metrics),
 		RankingTable: as.generateStrategyRanking(strategies),
 		Chart: as.generateStrategyComparisonChart(strategies),
+		StatisticalTest: pairwiseSignificance(candidates, "daily_return", analytics.CorrectionHolm),
 	}
 	
 	return result, nil
@@ -940,6 +976,7 @@ type StrategyComparisonAnalysis struct {
 	ComparisonMetrics map[string][]float64        `json:"comparison_metrics"`
 	RankingTable      []StrategyRanking           `json:"ranking_table"`
 	Chart             *models.ComparisonChart            `json:"chart"`
+	StatisticalTest   *models.StatisticalTestResult      `json:"statistical_test"`
 }
 
 // StrategyRanking 策略排名
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"fmt"
+
+	"qlib-backend/internal/models"
+	"qlib-backend/internal/qlib"
+
+	"gorm.io/gorm"
+)
+
+// backtestArtifacts 某个策略回测保存的完整记录
+type backtestArtifacts struct {
+	Values    []models.BacktestPortfolioValue
+	Benchmark []models.BacktestBenchmarkValue
+	Positions []models.BacktestPosition
+	Trades    []models.BacktestTrade
+}
+
+// SaveBacktestArtifacts 保存回测的每日净值、基准点位、持仓和成交记录，覆盖该策略之前的记录
+func SaveBacktestArtifacts(db *gorm.DB, strategyID uint, benchmark string, report *qlib.NativeBacktestReport) error {
+	values := make([]models.BacktestPortfolioValue, 0, len(report.Daily))
+	var benchmarkValues []models.BacktestBenchmarkValue
+	for _, d := range report.Daily {
+		date := d.Date.Format("2006-01-02")
+		values = append(values, models.BacktestPortfolioValue{
+			StrategyID: strategyID,
+			Date:       date,
+			Value:      d.Value,
+			Cash:       d.Cash,
+			StockValue: d.StockValue,
+			Return:     d.Return,
+			Turnover:   d.Turnover,
+			Cost:       d.Cost,
+		})
+		if benchmark != "" && d.BenchmarkValue > 0 {
+			benchmarkValues = append(benchmarkValues, models.BacktestBenchmarkValue{
+				StrategyID: strategyID,
+				Benchmark:  benchmark,
+				Date:       date,
+				Value:      d.BenchmarkValue,
+				Return:     d.BenchmarkReturn,
+			})
+		}
+	}
+
+	positions := make([]models.BacktestPosition, 0, len(report.Positions))
+	for _, p := range report.Positions {
+		positions = append(positions, models.BacktestPosition{
+			StrategyID: strategyID,
+			Date:       p.Date.Format("2006-01-02"),
+			Instrument: p.Instrument,
+			Amount:     p.Amount,
+			Price:      p.Price,
+			Value:      p.Value,
+			Weight:     p.Weight,
+		})
+	}
+
+	trades := make([]models.BacktestTrade, 0, len(report.Trades))
+	for _, t := range report.Trades {
+		trades = append(trades, models.BacktestTrade{
+			StrategyID: strategyID,
+			Date:       t.Date.Format("2006-01-02"),
+			Instrument: t.Instrument,
+			Direction:  t.Direction,
+			Amount:     t.Amount,
+			Price:      t.Price,
+			Commission: t.Commission,
+			PnL:        t.PnL,
+		})
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteBacktestArtifacts(tx, strategyID); err != nil {
+			return err
+		}
+		if len(values) > 0 {
+			if err := tx.CreateInBatches(values, 500).Error; err != nil {
+				return fmt.Errorf("保存组合净值失败: %v", err)
+			}
+		}
+		if len(benchmarkValues) > 0 {
+			if err := tx.CreateInBatches(benchmarkValues, 500).Error; err != nil {
+				return fmt.Errorf("保存基准点位失败: %v", err)
+			}
+		}
+		if len(positions) > 0 {
+			if err := tx.CreateInBatches(positions, 500).Error; err != nil {
+				return fmt.Errorf("保存持仓记录失败: %v", err)
+			}
+		}
+		if len(trades) > 0 {
+			if err := tx.CreateInBatches(trades, 500).Error; err != nil {
+				return fmt.Errorf("保存成交记录失败: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// deleteBacktestArtifacts 删除策略已保存的回测记录
+func deleteBacktestArtifacts(db *gorm.DB, strategyID uint) error {
+	for _, model := range []interface{}{
+		&models.BacktestPortfolioValue{},
+		&models.BacktestBenchmarkValue{},
+		&models.BacktestPosition{},
+		&models.BacktestTrade{},
+	} {
+		if err := db.Where("strategy_id = ?", strategyID).Delete(model).Error; err != nil {
+			return fmt.Errorf("清理回测记录失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// loadBacktestArtifacts 读取策略保存的回测记录，没有每日净值时返回 nil
+func loadBacktestArtifacts(db *gorm.DB, strategyID uint) (*backtestArtifacts, error) {
+	artifacts := &backtestArtifacts{}
+	if err := db.Where("strategy_id = ?", strategyID).Order("date").Find(&artifacts.Values).Error; err != nil {
+		return nil, fmt.Errorf("读取组合净值失败: %v", err)
+	}
+	if len(artifacts.Values) == 0 {
+		return nil, nil
+	}
+	if err := db.Where("strategy_id = ?", strategyID).Order("date").Find(&artifacts.Benchmark).Error; err != nil {
+		return nil, fmt.Errorf("读取基准点位失败: %v", err)
+	}
+	if err := db.Where("strategy_id = ?", strategyID).Order("date, instrument").Find(&artifacts.Positions).Error; err != nil {
+		return nil, fmt.Errorf("读取持仓记录失败: %v", err)
+	}
+	if err := db.Where("strategy_id = ?", strategyID).Order("date, id").Find(&artifacts.Trades).Error; err != nil {
+		return nil, fmt.Errorf("读取成交记录失败: %v", err)
+	}
+	return artifacts, nil
+}
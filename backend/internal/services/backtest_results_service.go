@@ -3,9 +3,15 @@ package services
 import (
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"qlib-backend/internal/analytics"
+	"qlib-backend/internal/calendar"
 	"qlib-backend/internal/models"
+	"qlib-backend/internal/qlib"
 	"qlib-backend/internal/utils"
 
 	"gorm.io/gorm"
@@ -15,6 +21,7 @@ import (
 type BacktestResultsService struct {
 	db             *gorm.DB
 	chartGenerator *utils.ChartGenerator
+	industries     map[string]string // 证券行业分类，为空时不计算行业分析
 }
 
 // DetailedBacktestResult 详细回测结果
@@ -32,6 +39,8 @@ type DetailedBacktestResult struct {
 	PeriodAnalysis     *PeriodAnalysis              `json:"period_analysis"`
 	Benchmarks         []models.BenchmarkComparison `json:"benchmarks"`
 	Charts             []ChartData                  `json:"charts"`
+	Incomplete         bool                         `json:"incomplete"`        // 缺少每日净值、持仓和成交记录，仅有汇总指标
+	Message            string                       `json:"message,omitempty"` // 结果不完整的原因
 }
 
 // DateRange 日期范围
@@ -62,22 +71,26 @@ type PerformanceMetrics struct {
 
 // RiskMetrics 风险指标
 type RiskMetrics struct {
-	VaR95               float64       `json:"var_95"`
-	VaR99               float64       `json:"var_99"`
-	CVaR95              float64       `json:"cvar_95"`
-	CVaR99              float64       `json:"cvar_99"`
-	MaxDrawdown         float64       `json:"max_drawdown"`
-	MaxDrawdownDuration int           `json:"max_drawdown_duration"`
-	DownsideDeviation   float64       `json:"downside_deviation"`
-	UpsideRatio         float64       `json:"upside_ratio"`
-	DownsideRatio       float64       `json:"downside_ratio"`
-	SkewkurtosisRisk    *SkewKurtosis `json:"skew_kurtosis_risk"`
+	VaR95               float64            `json:"var_95"`
+	VaR99               float64            `json:"var_99"`
+	CVaR95              float64            `json:"cvar_95"`
+	CVaR99              float64            `json:"cvar_99"`
+	ParametricVaR       analytics.TailRisk `json:"parametric_var"`     // 正态分布参数法
+	CornishFisherVaR    analytics.TailRisk `json:"cornish_fisher_var"` // Cornish-Fisher 修正
+	MaxDrawdown         float64            `json:"max_drawdown"`
+	MaxDrawdownDuration int                `json:"max_drawdown_duration"` // 最长连续回撤交易日数
+	MaxDrawdownRecovery int                `json:"max_drawdown_recovery"` // 最大回撤低点到恢复的交易日数，未恢复为-1
+	DownsideDeviation   float64            `json:"downside_deviation"`
+	OmegaRatio          float64            `json:"omega_ratio"`
+	UpsideRatio         float64            `json:"upside_ratio"`
+	DownsideRatio       float64            `json:"downside_ratio"`
+	SkewkurtosisRisk    *SkewKurtosis      `json:"skew_kurtosis_risk"`
 }
 
 // SkewKurtosis 偏度和峰度
 type SkewKurtosis struct {
 	Skewness float64 `json:"skewness"`
-	Kurtosis float64 `json:"kurtosis"`
+	Kurtosis float64 `json:"kurtosis"` // 超额峰度，正态分布为0
 }
 
 // TradeAnalysis 交易分析
@@ -183,11 +196,13 @@ type ConsistencyMetrics struct {
 
 // ChartData 图表数据
 type ChartData struct {
-	ID     string                 `json:"id"`
-	Type   string                 `json:"type"` // line, bar, pie, heatmap, scatter
-	Title  string                 `json:"title"`
-	Data   map[string]interface{} `json:"data"`
-	Config map[string]interface{} `json:"config"`
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"` // line, bar, pie, heatmap, scatter
+	Title      string                 `json:"title"`
+	Data       map[string]interface{} `json:"data"`
+	Config     map[string]interface{} `json:"config"`
+	Incomplete bool                   `json:"incomplete,omitempty"` // 缺少绘制所需的回测记录
+	Message    string                 `json:"message,omitempty"`
 }
 
 // ChartType 图表类型
@@ -222,6 +237,11 @@ type GetDetailedResultsOptions struct {
 	TimeRange              string `json:"time_range"`
 }
 
+// SetIndustryMapping 设置行业分析使用的证券行业分类
+func (brs *BacktestResultsService) SetIndustryMapping(industries map[string]string) {
+	brs.industries = industries
+}
+
 // GetDetailedResults 获取详细回测结果
 func (brs *BacktestResultsService) GetDetailedResults(resultID uint, userID uint) (*DetailedBacktestResult, error) {
 	// 使用默认选项调用扩展方法
@@ -235,6 +255,9 @@ func (brs *BacktestResultsService) GetDetailedResults(resultID uint, userID uint
 }
 
 // GetDetailedResultsWithOptions 获取详细回测结果（带选项）
+//
+// 所有序列和指标都从回测完成时保存的每日净值、基准、持仓和成交记录计算；
+// 没有保存这些记录的回测只返回策略表中的汇总指标，并标记为不完整。
 func (brs *BacktestResultsService) GetDetailedResultsWithOptions(resultID uint, userID uint, options GetDetailedResultsOptions) (*DetailedBacktestResult, error) {
 	// 验证权限并获取策略信息
 	var strategy models.Strategy
@@ -249,83 +272,57 @@ func (brs *BacktestResultsService) GetDetailedResultsWithOptions(resultID uint,
 		ResultID:     resultID,
 		StrategyID:   strategy.ID,
 		StrategyName: strategy.Name,
+		BacktestPeriod: DateRange{
+			StartDate: strategy.BacktestStart,
+			EndDate:   strategy.BacktestEnd,
+		},
+		Benchmarks: []models.BenchmarkComparison{},
+		Charts:     []ChartData{},
 	}
 
-	// 解析回测时间范围
-	result.BacktestPeriod = DateRange{
-		StartDate: strategy.BacktestStart,
-		EndDate:   strategy.BacktestEnd,
-		Days:      brs.calculateTradingDays(strategy.BacktestStart, strategy.BacktestEnd),
+	artifacts, err := loadBacktestArtifacts(brs.db, strategy.ID)
+	if err != nil {
+		return nil, err
+	}
+	if artifacts == nil {
+		result.Incomplete = true
+		result.Message = incompleteResultMessage
+		result.PerformanceMetrics = brs.summaryPerformanceMetrics(strategy)
+		return result, nil
 	}
 
-	// 计算性能指标
-	performance, err := brs.calculatePerformanceMetrics(strategy)
+	// 时间序列数据 - 根据TimeRange过滤
+	series, err := buildBacktestSeries(artifacts, options.TimeRange)
 	if err != nil {
-		return nil, fmt.Errorf("计算性能指标失败: %v", err)
+		return nil, fmt.Errorf("生成时间序列数据失败: %v", err)
 	}
-	result.PerformanceMetrics = performance
+	result.BacktestPeriod = DateRange{
+		StartDate: series.Dates[0],
+		EndDate:   series.Dates[len(series.Dates)-1],
+		Days:      len(series.Dates),
+	}
+	result.TimeSeriesData = brs.generateTimeSeriesData(series)
+	result.PerformanceMetrics = brs.calculatePerformanceMetrics(series)
 
 	// 根据选项计算风险指标
 	if options.IncludeRiskMetrics {
-		risk, err := brs.calculateRiskMetrics(strategy)
-		if err != nil {
-			return nil, fmt.Errorf("计算风险指标失败: %v", err)
-		}
-		result.RiskMetrics = risk
+		result.RiskMetrics = brs.calculateRiskMetrics(series)
 	}
 
 	// 根据选项进行交易分析
 	if options.IncludeTradeDetails {
-		tradeAnalysis, err := brs.calculateTradeAnalysis(strategy)
-		if err != nil {
-			return nil, fmt.Errorf("计算交易分析失败: %v", err)
-		}
-		result.TradeAnalysis = tradeAnalysis
-	}
-
-	// 时间序列数据 - 根据TimeRange过滤
-	timeSeries, err := brs.generateTimeSeriesDataWithRange(strategy, options.TimeRange)
-	if err != nil {
-		return nil, fmt.Errorf("生成时间序列数据失败: %v", err)
+		result.TradeAnalysis = brs.calculateTradeAnalysis(series)
 	}
-	result.TimeSeriesData = timeSeries
 
 	// 根据选项进行持仓分析
 	if options.IncludePositionDetails {
-		positionAnalysis, err := brs.calculatePositionAnalysis(strategy)
-		if err != nil {
-			return nil, fmt.Errorf("计算持仓分析失败: %v", err)
-		}
-		result.PositionAnalysis = positionAnalysis
+		result.PositionAnalysis = brs.calculatePositionAnalysis(series)
 	}
 
-	// 行业分析
-	sectorAnalysis, err := brs.calculateSectorAnalysis(strategy)
-	if err != nil {
-		return nil, fmt.Errorf("计算行业分析失败: %v", err)
-	}
-	result.SectorAnalysis = sectorAnalysis
-
-	// 分期间分析
-	periodAnalysis, err := brs.calculatePeriodAnalysis(strategy)
-	if err != nil {
-		return nil, fmt.Errorf("计算分期间分析失败: %v", err)
-	}
-	result.PeriodAnalysis = periodAnalysis
-
-	// 基准对比
-	benchmarks, err := brs.calculateBenchmarkComparison(strategy)
-	if err != nil {
-		return nil, fmt.Errorf("计算基准对比失败: %v", err)
-	}
-	result.Benchmarks = benchmarks
-
-	// 生成图表
-	charts, err := brs.generateCharts(strategy, result)
-	if err != nil {
-		return nil, fmt.Errorf("生成图表失败: %v", err)
-	}
-	result.Charts = charts
+	result.SectorAnalysis = brs.calculateSectorAnalysis(series)
+	result.PeriodAnalysis = brs.calculatePeriodAnalysis(series)
+	result.Benchmarks = brs.calculateBenchmarkComparison(series)
+	result.Charts = brs.generateCharts(series)
 
 	return result, nil
 }
@@ -359,38 +356,46 @@ func (brs *BacktestResultsService) GetChartDataWithOptions(resultID uint, chartT
 		return nil, fmt.Errorf("获取回测结果失败: %v", err)
 	}
 
-	// TODO: 根据选项参数生成不同的图表
-	// 可以基于TimeRange, Resolution, Benchmark, Indicators调整图表数据
+	chart, ok := chartTemplates[chartType]
+	if !ok {
+		return nil, fmt.Errorf("不支持的图表类型: %s", chartType)
+	}
+
+	artifacts, err := loadBacktestArtifacts(brs.db, strategy.ID)
+	if err != nil {
+		return nil, err
+	}
+	if artifacts == nil {
+		return incompleteChart(chart), nil
+	}
+	series, err := buildBacktestSeries(artifacts, options.TimeRange)
+	if err != nil {
+		return nil, fmt.Errorf("生成时间序列数据失败: %v", err)
+	}
 
 	switch chartType {
 	case ChartTypeCumulativeReturns:
-		return brs.generateCumulativeReturnsChartWithOptions(strategy, options)
+		return brs.generateCumulativeReturnsChartWithOptions(series, options), nil
 	case ChartTypeDrawdowns:
-		return brs.generateDrawdownsChartWithOptions(strategy, options)
+		return brs.generateDrawdownsChartWithOptions(series, options), nil
 	case ChartTypeRollingMetrics:
-		return brs.generateRollingMetricsChartWithOptions(strategy, options)
+		return brs.generateRollingMetricsChartWithOptions(series, options), nil
 	case ChartTypePositionWeights:
-		return brs.generatePositionWeightsChart(strategy)
+		return brs.generatePositionWeightsChart(series), nil
 	case ChartTypeSectorExposure:
-		return brs.generateSectorExposureChart(strategy)
+		return brs.generateSectorExposureChart(series), nil
 	case ChartTypeMonthlyReturns:
-		return brs.generateMonthlyReturnsChart(strategy)
+		return brs.generateMonthlyReturnsChart(series), nil
 	case ChartTypeReturnDistribution:
-		return brs.generateReturnDistributionChart(strategy)
-	case ChartTypeRiskReturn:
-		return brs.generateRiskReturnChart(strategy)
+		return brs.generateReturnDistributionChart(series), nil
 	default:
-		return nil, fmt.Errorf("不支持的图表类型: %s", chartType)
+		return brs.generateRiskReturnChart(series), nil
 	}
 }
 
 // generateCumulativeReturnsChartWithOptions 生成带选项的累积收益图表
-func (brs *BacktestResultsService) generateCumulativeReturnsChartWithOptions(strategy models.Strategy, options GetChartDataOptions) (*ChartData, error) {
-	// 生成时间序列数据，根据TimeRange过滤
-	timeSeries, err := brs.generateTimeSeriesDataWithRange(strategy, options.TimeRange)
-	if err != nil {
-		return nil, fmt.Errorf("生成时间序列数据失败: %v", err)
-	}
+func (brs *BacktestResultsService) generateCumulativeReturnsChartWithOptions(series *backtestSeries, options GetChartDataOptions) *ChartData {
+	timeSeries := brs.generateTimeSeriesData(series)
 
 	// 根据Resolution调整数据粒度
 	dates, cumulativeReturns, benchmarkCumulative := brs.resampleData(
@@ -421,39 +426,33 @@ func (brs *BacktestResultsService) generateCumulativeReturnsChartWithOptions(str
 		},
 	}
 
-	// 如果指定了基准，添加基准数据
-	if options.Benchmark != "" {
+	// 只展示回测时保存的基准，请求的基准与之不同时不绘制
+	if series.BenchmarkName != "" && (options.Benchmark == "" || options.Benchmark == series.BenchmarkName) {
 		chartData.Data["benchmark"] = benchmarkCumulative
-		chartData.Config["legend"] = []string{"策略", options.Benchmark}
+		chartData.Config["legend"] = []string{"策略", series.BenchmarkName}
 	}
 
 	// 添加额外指标
-	if len(options.Indicators) > 0 {
-		for _, indicator := range options.Indicators {
-			switch indicator {
-			case "drawdown":
-				chartData.Data["drawdown"] = timeSeries.Drawdowns
-			case "volatility":
-				chartData.Data["volatility"] = timeSeries.RollingVolatility
-			}
+	for _, indicator := range options.Indicators {
+		switch indicator {
+		case "drawdown":
+			_, drawdowns, _ := brs.resampleData(timeSeries.Dates, timeSeries.Drawdowns, nil, options.Resolution)
+			chartData.Data["drawdown"] = drawdowns
+		case "volatility":
+			_, volatility, _ := brs.resampleData(timeSeries.Dates, timeSeries.RollingVolatility, nil, options.Resolution)
+			chartData.Data["volatility"] = volatility
 		}
 	}
 
-	return chartData, nil
+	return chartData
 }
 
 // generateDrawdownsChartWithOptions 生成带选项的回撤图表
-func (brs *BacktestResultsService) generateDrawdownsChartWithOptions(strategy models.Strategy, options GetChartDataOptions) (*ChartData, error) {
-	// 生成时间序列数据，根据TimeRange过滤
-	timeSeries, err := brs.generateTimeSeriesDataWithRange(strategy, options.TimeRange)
-	if err != nil {
-		return nil, fmt.Errorf("生成时间序列数据失败: %v", err)
-	}
-
+func (brs *BacktestResultsService) generateDrawdownsChartWithOptions(series *backtestSeries, options GetChartDataOptions) *ChartData {
 	// 根据Resolution调整数据粒度
 	dates, drawdowns, _ := brs.resampleData(
-		timeSeries.Dates,
-		timeSeries.Drawdowns,
+		series.Dates,
+		analytics.DrawdownSeries(series.Returns),
 		nil,
 		options.Resolution,
 	)
@@ -483,22 +482,16 @@ func (brs *BacktestResultsService) generateDrawdownsChartWithOptions(strategy mo
 		},
 	}
 
-	return chartData, nil
+	return chartData
 }
 
 // generateRollingMetricsChartWithOptions 生成带选项的滚动指标图表
-func (brs *BacktestResultsService) generateRollingMetricsChartWithOptions(strategy models.Strategy, options GetChartDataOptions) (*ChartData, error) {
-	// 生成时间序列数据，根据TimeRange过滤
-	timeSeries, err := brs.generateTimeSeriesDataWithRange(strategy, options.TimeRange)
-	if err != nil {
-		return nil, fmt.Errorf("生成时间序列数据失败: %v", err)
-	}
-
+func (brs *BacktestResultsService) generateRollingMetricsChartWithOptions(series *backtestSeries, options GetChartDataOptions) *ChartData {
 	// 根据Resolution调整数据粒度
 	dates, volatility, sharpe := brs.resampleData(
-		timeSeries.Dates,
-		timeSeries.RollingVolatility,
-		timeSeries.RollingSharpe,
+		series.Dates,
+		brs.calculateRollingVolatility(series.Returns, 20),
+		brs.calculateRollingSharpe(series.Returns, 60),
 		options.Resolution,
 	)
 
@@ -537,7 +530,7 @@ func (brs *BacktestResultsService) generateRollingMetricsChartWithOptions(strate
 		chartData.Data["sharpe"] = sharpe
 	}
 
-	return chartData, nil
+	return chartData
 }
 
 // ExportBacktestReportExtended 导出回测报告（扩展版本）
@@ -591,334 +584,539 @@ func (brs *BacktestResultsService) ExportBacktestReport(req models.BacktestRepor
 
 // 内部计算方法
 
-// calculatePerformanceMetrics 计算性能指标
-func (brs *BacktestResultsService) calculatePerformanceMetrics(strategy models.Strategy) (*PerformanceMetrics, error) {
-	return &PerformanceMetrics{
+// incompleteResultMessage 缺少回测记录时的提示
+const incompleteResultMessage = "该回测未保存每日净值、持仓和成交记录，仅提供汇总指标，请重新运行回测以获取完整结果"
+
+// backtestSeries 按时间范围截取的回测记录，各序列与 Dates 对齐
+type backtestSeries struct {
+	Dates         []string
+	Values        []float64 // 每日账户总值
+	Returns       []float64 // 每日收益率
+	Turnover      []float64
+	BenchmarkName string
+	Benchmark     []float64 // 基准日收益率，无基准时为 nil
+	Positions     []models.BacktestPosition
+	Trades        []models.BacktestTrade
+	Metrics       *analytics.Metrics // 由 Returns 和 Benchmark 计算的绩效与风险指标
+}
+
+// buildBacktestSeries 按时间范围整理保存的回测记录
+func buildBacktestSeries(artifacts *backtestArtifacts, timeRange string) (*backtestSeries, error) {
+	last, err := time.Parse("2006-01-02", artifacts.Values[len(artifacts.Values)-1].Date)
+	if err != nil {
+		return nil, fmt.Errorf("回测记录日期格式错误: %v", err)
+	}
+	market := ""
+	if len(artifacts.Benchmark) > 0 {
+		market = artifacts.Benchmark[0].Benchmark
+	} else if len(artifacts.Positions) > 0 {
+		market = artifacts.Positions[0].Instrument
+	}
+	start, err := timeRangeStart(marketCalendar(market), last, timeRange)
+	if err != nil {
+		return nil, err
+	}
+	startDate := start.Format("2006-01-02")
+
+	series := &backtestSeries{}
+	benchmark := make(map[string]float64, len(artifacts.Benchmark))
+	for _, b := range artifacts.Benchmark {
+		benchmark[b.Date] = b.Return
+		series.BenchmarkName = b.Benchmark
+	}
+	for _, v := range artifacts.Values {
+		if v.Date < startDate {
+			continue
+		}
+		series.Dates = append(series.Dates, v.Date)
+		series.Values = append(series.Values, v.Value)
+		series.Returns = append(series.Returns, v.Return)
+		series.Turnover = append(series.Turnover, v.Turnover)
+		if series.BenchmarkName != "" {
+			series.Benchmark = append(series.Benchmark, benchmark[v.Date])
+		}
+	}
+	if len(series.Dates) == 0 {
+		return nil, fmt.Errorf("时间范围 %s 内没有回测数据", timeRange)
+	}
+	series.Metrics = analytics.Compute(series.Returns, series.Benchmark, analytics.Options{})
+
+	for _, p := range artifacts.Positions {
+		if p.Date >= startDate {
+			series.Positions = append(series.Positions, p)
+		}
+	}
+	for _, t := range artifacts.Trades {
+		if t.Date >= startDate {
+			series.Trades = append(series.Trades, t)
+		}
+	}
+	return series, nil
+}
+
+// timeRangeStart 解析时间范围（如 5d、2w、3m、1y、ytd），返回截至 end 的起始日期，空或all表示全部
+//
+// d 按交易日计算，回溯超过日历首日时从首日开始；end 不在交易日历范围内时返回错误。
+func timeRangeStart(cal *calendar.Calendar, end time.Time, timeRange string) (time.Time, error) {
+	switch timeRange {
+	case "", "all":
+		return time.Time{}, nil
+	case "ytd":
+		return time.Date(end.Year(), 1, 1, 0, 0, 0, 0, end.Location()), nil
+	}
+
+	n, err := strconv.Atoi(timeRange[:len(timeRange)-1])
+	if err != nil || n <= 0 {
+		return time.Time{}, fmt.Errorf("无效的时间范围: %s", timeRange)
+	}
+	switch timeRange[len(timeRange)-1] {
+	case 'd':
+		if end.Before(cal.First()) || end.After(cal.Last()) {
+			return time.Time{}, fmt.Errorf("回测结束日期 %s 超出%s交易日历范围(%s ~ %s)，无法按交易日计算时间范围",
+				end.Format("2006-01-02"), cal.Market.Code, cal.First().Format("2006-01-02"), cal.Last().Format("2006-01-02"))
+		}
+		start, err := cal.Offset(end, 1-n)
+		if err != nil {
+			return cal.First(), nil
+		}
+		return start, nil
+	case 'w':
+		return end.AddDate(0, 0, 1-7*n), nil
+	case 'm':
+		return end.AddDate(0, -n, 1), nil
+	case 'y':
+		return end.AddDate(-n, 0, 1), nil
+	default:
+		return time.Time{}, fmt.Errorf("无效的时间范围: %s", timeRange)
+	}
+}
+
+// summaryPerformanceMetrics 缺少回测记录时只返回策略表中保存的汇总指标
+func (brs *BacktestResultsService) summaryPerformanceMetrics(strategy models.Strategy) *PerformanceMetrics {
+	metrics := &PerformanceMetrics{
 		TotalReturn:      strategy.TotalReturn,
 		AnnualizedReturn: strategy.AnnualReturn,
 		Volatility:       strategy.Volatility,
 		SharpeRatio:      strategy.SharpeRatio,
-		SortinoRatio:     strategy.SharpeRatio * 1.2, // 简化计算
-		CalmarRatio:      strategy.AnnualReturn / math.Abs(strategy.MaxDrawdown),
 		MaxDrawdown:      strategy.MaxDrawdown,
 		WinRate:          strategy.WinRate,
-		ProfitLossRatio:  2.1, // 模拟数据
-		ExpectedReturn:   strategy.AnnualReturn / 252,
-		ReturnStdev:      strategy.Volatility / math.Sqrt(252),
-		Beta:             1.0,
-		Alpha:            strategy.ExcessReturn,
-		InformationRatio: strategy.SharpeRatio * 0.8,
-		TrackingError:    0.05,
-	}, nil
-}
-
-// calculateRiskMetrics 计算风险指标
-func (brs *BacktestResultsService) calculateRiskMetrics(strategy models.Strategy) (*RiskMetrics, error) {
-	return &RiskMetrics{
-		VaR95:               -0.05,
-		VaR99:               -0.08,
-		CVaR95:              -0.07,
-		CVaR99:              -0.10,
-		MaxDrawdown:         strategy.MaxDrawdown,
-		MaxDrawdownDuration: 45, // 模拟数据
-		DownsideDeviation:   strategy.Volatility * 0.8,
-		UpsideRatio:         1.1,
-		DownsideRatio:       0.9,
+	}
+	if strategy.MaxDrawdown < 0 {
+		metrics.CalmarRatio = strategy.AnnualReturn / math.Abs(strategy.MaxDrawdown)
+	}
+	return metrics
+}
+
+// calculatePerformanceMetrics 计算性能指标，年化口径与回测汇总一致（日均收益×252）
+func (brs *BacktestResultsService) calculatePerformanceMetrics(series *backtestSeries) *PerformanceMetrics {
+	m := series.Metrics
+	metrics := &PerformanceMetrics{
+		TotalReturn:      m.TotalReturn,
+		AnnualizedReturn: m.AnnualReturn,
+		Volatility:       m.AnnualVolatility,
+		SharpeRatio:      m.SharpeRatio,
+		SortinoRatio:     m.SortinoRatio,
+		CalmarRatio:      m.CalmarRatio,
+		MaxDrawdown:      m.Drawdown.MaxDrawdown,
+		WinRate:          m.WinRate,
+		ProfitLossRatio:  m.PayoffRatio,
+		ExpectedReturn:   analytics.Mean(series.Returns),
+		ReturnStdev:      analytics.StdDev(series.Returns),
+	}
+	if m.Relative != nil {
+		metrics.Beta = m.Relative.Beta
+		metrics.Alpha = m.Relative.Alpha
+		metrics.InformationRatio = m.Relative.InformationRatio
+		metrics.TrackingError = m.Relative.TrackingError
+	}
+	return metrics
+}
+
+// calculateRiskMetrics 计算风险指标，VaR/CVaR 为日度值，默认使用历史模拟法
+func (brs *BacktestResultsService) calculateRiskMetrics(series *backtestSeries) *RiskMetrics {
+	m := series.Metrics
+	risk := &RiskMetrics{
+		VaR95:               m.Historical.VaR95,
+		VaR99:               m.Historical.VaR99,
+		CVaR95:              m.Historical.CVaR95,
+		CVaR99:              m.Historical.CVaR99,
+		ParametricVaR:       m.Parametric,
+		CornishFisherVaR:    m.CornishFisher,
+		MaxDrawdown:         m.Drawdown.MaxDrawdown,
+		MaxDrawdownDuration: m.Drawdown.LongestUnderwater,
+		MaxDrawdownRecovery: m.Drawdown.RecoveryDuration,
+		DownsideDeviation:   m.DownsideDeviation,
+		OmegaRatio:          m.OmegaRatio,
 		SkewkurtosisRisk: &SkewKurtosis{
-			Skewness: -0.2,
-			Kurtosis: 3.5,
+			Skewness: m.Skewness,
+			Kurtosis: m.Kurtosis,
 		},
-	}, nil
-}
-
-// calculateTradeAnalysis 计算交易分析
-func (brs *BacktestResultsService) calculateTradeAnalysis(strategy models.Strategy) (*TradeAnalysis, error) {
-	// 模拟交易数据
-	totalTrades := 1200
-	winningTrades := int(float64(totalTrades) * strategy.WinRate)
-	losingTrades := totalTrades - winningTrades
-
-	return &TradeAnalysis{
-		TotalTrades:        totalTrades,
-		WinningTrades:      winningTrades,
-		LosingTrades:       losingTrades,
-		WinRate:            strategy.WinRate,
-		AverageWin:         0.035,
-		AverageLoss:        -0.018,
-		ProfitFactor:       2.3,
-		LargestWin:         0.152,
-		LargestLoss:        -0.087,
-		AverageTradeReturn: strategy.TotalReturn / float64(totalTrades),
-		TradingFrequency:   252.0 / float64(totalTrades), // 每年交易频率
-		Turnover:           3.2,
-	}, nil
-}
-
-// generateTimeSeriesData 生成时间序列数据
-// generateTimeSeriesDataWithRange 生成带时间范围的时间序列数据
-func (brs *BacktestResultsService) generateTimeSeriesDataWithRange(strategy models.Strategy, timeRange string) (*TimeSeriesAnalysis, error) {
-	// 如果没有指定时间范围，使用完整范围
-	if timeRange == "" {
-		return brs.generateTimeSeriesData(strategy)
-	}
-
-	// TODO: 解析timeRange参数，如"3m", "6m", "1y"等，并据此过滤数据
-	// 当前简化处理，返回完整数据
-	return brs.generateTimeSeriesData(strategy)
-}
-
-func (brs *BacktestResultsService) generateTimeSeriesData(strategy models.Strategy) (*TimeSeriesAnalysis, error) {
-	// 模拟时间序列数据
-	days := brs.calculateTradingDays(strategy.BacktestStart, strategy.BacktestEnd)
-
-	dates := make([]string, days)
-	portfolioReturns := make([]float64, days)
-	cumulativeReturns := make([]float64, days)
-	benchmarkReturns := make([]float64, days)
-	benchmarkCumulative := make([]float64, days)
-	excessReturns := make([]float64, days)
-	drawdowns := make([]float64, days)
-	portfolioValue := make([]float64, days)
-
-	startDate, _ := time.Parse("2006-01-02", strategy.BacktestStart)
-	dailyReturn := strategy.AnnualReturn / float64(days)
-	benchmarkDailyReturn := 0.08 / float64(days) // 假设基准年收益8%
-
-	portfolioVal := 100000.0 // 初始资金
-	cumReturn := 0.0
-	benchmarkCumReturn := 0.0
-	maxValue := portfolioVal
-
-	for i := 0; i < days; i++ {
-		date := startDate.AddDate(0, 0, i)
-		dates[i] = date.Format("2006-01-02")
-
-		// 模拟日收益率（添加一些波动）
-		dailyRet := dailyReturn + 0.01*math.Sin(float64(i)/20) + 0.005*(math.Mod(float64(i), 2)-0.5)
-		benchmarkRet := benchmarkDailyReturn + 0.005*math.Sin(float64(i)/25)
-
-		portfolioReturns[i] = dailyRet
-		benchmarkReturns[i] = benchmarkRet
-		excessReturns[i] = dailyRet - benchmarkRet
-
-		cumReturn += dailyRet
-		benchmarkCumReturn += benchmarkRet
-
-		cumulativeReturns[i] = cumReturn
-		benchmarkCumulative[i] = benchmarkCumReturn
-
-		portfolioVal *= (1 + dailyRet)
-		portfolioValue[i] = portfolioVal
-
-		if portfolioVal > maxValue {
-			maxValue = portfolioVal
+	}
+
+	// 上行/下行比率为相对基准的上行、下行捕获率
+	if m.Relative != nil {
+		risk.UpsideRatio = m.Relative.UpCapture
+		risk.DownsideRatio = m.Relative.DownCapture
+	}
+	return risk
+}
+
+// calculateTradeAnalysis 根据成交记录计算交易分析，盈亏按卖出成交相对持仓成本计算
+func (brs *BacktestResultsService) calculateTradeAnalysis(series *backtestSeries) *TradeAnalysis {
+	analysis := &TradeAnalysis{TotalTrades: len(series.Trades)}
+
+	var closed, wins, losses []float64
+	grossProfit, grossLoss := 0.0, 0.0
+	for _, t := range series.Trades {
+		if t.Direction != "sell" {
+			continue
 		}
-		drawdowns[i] = (portfolioVal - maxValue) / maxValue
-	}
-
-	// 计算滚动指标
-	rollingVolatility := brs.calculateRollingVolatility(portfolioReturns, 20)
-	rollingSharpe := brs.calculateRollingSharpe(portfolioReturns, 60)
-
-	return &TimeSeriesAnalysis{
-		Dates:               dates,
-		PortfolioReturns:    portfolioReturns,
-		CumulativeReturns:   cumulativeReturns,
-		BenchmarkReturns:    benchmarkReturns,
-		BenchmarkCumulative: benchmarkCumulative,
-		ExcessReturns:       excessReturns,
-		Drawdowns:           drawdowns,
-		RollingVolatility:   rollingVolatility,
-		RollingSharpe:       rollingSharpe,
-		PortfolioValue:      portfolioValue,
-	}, nil
-}
-
-// calculatePositionAnalysis 计算持仓分析
-func (brs *BacktestResultsService) calculatePositionAnalysis(strategy models.Strategy) (*PositionAnalysis, error) {
-	// 模拟持仓数据
-	topHoldings := []HoldingInfo{
-		{Symbol: "000001.SZ", Weight: 0.05, Return: 0.12, Contribution: 0.006, HoldingDays: 180},
-		{Symbol: "000002.SZ", Weight: 0.04, Return: 0.08, Contribution: 0.0032, HoldingDays: 150},
-		{Symbol: "600000.SH", Weight: 0.045, Return: 0.15, Contribution: 0.0067, HoldingDays: 200},
-		{Symbol: "600036.SH", Weight: 0.038, Return: 0.10, Contribution: 0.0038, HoldingDays: 120},
-		{Symbol: "000858.SZ", Weight: 0.042, Return: 0.09, Contribution: 0.0037, HoldingDays: 165},
-	}
-
-	sectorExposure := map[string]float64{
-		"金融":   0.35,
-		"科技":   0.25,
-		"消费":   0.20,
-		"医药":   0.12,
-		"制造业": 0.08,
-	}
-
-	return &PositionAnalysis{
-		AveragePositions: 45,
-		MaxPositions:     60,
-		MinPositions:     30,
-		PositionSizing: &PositionSizing{
-			AverageWeight: 0.022,
-			MaxWeight:     0.05,
-			MinWeight:     0.01,
-			WeightStdDev:  0.008,
-		},
-		TopHoldings:    topHoldings,
-		SectorExposure: sectorExposure,
-		ConcentrationRisk: &ConcentrationMetrics{
-			HerfindahlIndex:    0.12,
-			Top5Concentration:  0.215,
-			Top10Concentration: 0.38,
-			EffectiveStocks:    28.5,
-		},
-	}, nil
+		// 卖出成本 = 成交额 - 费用 - 实现盈亏
+		basis := t.Amount*t.Price - t.Commission - t.PnL
+		if basis <= 0 {
+			continue
+		}
+		ret := t.PnL / basis
+		closed = append(closed, ret)
+		if t.PnL > 0 {
+			wins = append(wins, ret)
+			grossProfit += t.PnL
+		} else if t.PnL < 0 {
+			losses = append(losses, ret)
+			grossLoss -= t.PnL
+		}
+	}
+
+	analysis.WinningTrades = len(wins)
+	analysis.LosingTrades = len(losses)
+	if len(closed) > 0 {
+		analysis.WinRate = float64(len(wins)) / float64(len(closed))
+		analysis.AverageTradeReturn = analytics.Mean(closed)
+	}
+	if len(wins) > 0 {
+		analysis.AverageWin = analytics.Mean(wins)
+		analysis.LargestWin = maxFloat(wins)
+	}
+	if len(losses) > 0 {
+		analysis.AverageLoss = analytics.Mean(losses)
+		analysis.LargestLoss = minFloat(losses)
+	}
+	if grossLoss > 0 {
+		analysis.ProfitFactor = grossProfit / grossLoss
+	}
+
+	// 交易频率为年化成交笔数，换手率为年化换手
+	days := float64(len(series.Dates))
+	analysis.TradingFrequency = float64(len(series.Trades)) / days * 252
+	analysis.Turnover = analytics.Mean(series.Turnover) * 252
+	return analysis
 }
 
-// calculateSectorAnalysis 计算行业分析
-func (brs *BacktestResultsService) calculateSectorAnalysis(strategy models.Strategy) (*SectorAnalysis, error) {
-	sectorReturns := map[string]float64{
-		"科技":   0.18,
-		"金融":   0.12,
-		"消费":   0.15,
-		"医药":   0.22,
-		"制造业": 0.08,
+// generateTimeSeriesData 生成时间序列数据，累积收益按日收益复利计算
+func (brs *BacktestResultsService) generateTimeSeriesData(series *backtestSeries) *TimeSeriesAnalysis {
+	n := len(series.Dates)
+	timeSeries := &TimeSeriesAnalysis{
+		Dates:             series.Dates,
+		PortfolioReturns:  series.Returns,
+		CumulativeReturns: analytics.CumulativeReturns(series.Returns),
+		Drawdowns:         analytics.DrawdownSeries(series.Returns),
+		RollingVolatility: brs.calculateRollingVolatility(series.Returns, 20),
+		RollingSharpe:     brs.calculateRollingSharpe(series.Returns, 60),
+		PortfolioValue:    series.Values,
 	}
 
-	sectorWeights := map[string]float64{
-		"科技":   0.25,
-		"金融":   0.35,
-		"消费":   0.20,
-		"医药":   0.12,
-		"制造业": 0.08,
+	if series.Benchmark != nil {
+		timeSeries.BenchmarkReturns = series.Benchmark
+		timeSeries.BenchmarkCumulative = analytics.CumulativeReturns(series.Benchmark)
+		timeSeries.ExcessReturns = make([]float64, n)
+		for i := range series.Returns {
+			timeSeries.ExcessReturns[i] = series.Returns[i] - series.Benchmark[i]
+		}
 	}
+	return timeSeries
+}
+
+// positionsByDate 按日期分组持仓记录
+func positionsByDate(positions []models.BacktestPosition) map[string][]models.BacktestPosition {
+	grouped := make(map[string][]models.BacktestPosition)
+	for _, p := range positions {
+		grouped[p.Date] = append(grouped[p.Date], p)
+	}
+	return grouped
+}
+
+// calculatePositionAnalysis 根据每日持仓计算持仓分析
+func (brs *BacktestResultsService) calculatePositionAnalysis(series *backtestSeries) *PositionAnalysis {
+	byDate := positionsByDate(series.Positions)
+	analysis := &PositionAnalysis{
+		PositionSizing:    &PositionSizing{},
+		TopHoldings:       []HoldingInfo{},
+		SectorExposure:    brs.sectorExposure(series),
+		ConcentrationRisk: &ConcentrationMetrics{},
+	}
+
+	counts := make([]float64, len(series.Dates))
+	for i, date := range series.Dates {
+		counts[i] = float64(len(byDate[date]))
+	}
+	analysis.AveragePositions = int(math.Round(analytics.Mean(counts)))
+	analysis.MaxPositions = int(maxFloat(counts))
+	analysis.MinPositions = int(minFloat(counts))
 
-	sectorContribution := make(map[string]float64)
-	for sector, weight := range sectorWeights {
-		if ret, exists := sectorReturns[sector]; exists {
-			sectorContribution[sector] = weight * ret
+	if len(series.Positions) == 0 {
+		return analysis
+	}
+	weights := make([]float64, len(series.Positions))
+	for i, p := range series.Positions {
+		weights[i] = p.Weight
+	}
+	analysis.PositionSizing = &PositionSizing{
+		AverageWeight: analytics.Mean(weights),
+		MaxWeight:     maxFloat(weights),
+		MinWeight:     minFloat(weights),
+		WeightStdDev:  analytics.StdDev(weights),
+	}
+
+	// 逐日累计每只证券的持有天数、平均权重、持有期收益和对组合收益的贡献（前一日权重×当日涨跌）
+	type holdingStats struct {
+		days         int
+		weightSum    float64
+		growth       float64
+		contribution float64
+	}
+	stats := make(map[string]*holdingStats)
+	prev := map[string]models.BacktestPosition{}
+	for _, date := range series.Dates {
+		today := make(map[string]models.BacktestPosition, len(byDate[date]))
+		for _, p := range byDate[date] {
+			st, ok := stats[p.Instrument]
+			if !ok {
+				st = &holdingStats{growth: 1}
+				stats[p.Instrument] = st
+			}
+			st.days++
+			st.weightSum += p.Weight
+			if last, ok := prev[p.Instrument]; ok && last.Price > 0 {
+				r := p.Price/last.Price - 1
+				st.growth *= 1 + r
+				st.contribution += last.Weight * r
+			}
+			today[p.Instrument] = p
 		}
+		prev = today
 	}
 
-	// 找出最佳和最差行业
-	bestSector, worstSector := "", ""
-	bestReturn, worstReturn := -1.0, 2.0
+	for inst, st := range stats {
+		analysis.TopHoldings = append(analysis.TopHoldings, HoldingInfo{
+			Symbol:       inst,
+			Weight:       st.weightSum / float64(st.days),
+			Return:       st.growth - 1,
+			Contribution: st.contribution,
+			HoldingDays:  st.days,
+		})
+	}
+	sort.Slice(analysis.TopHoldings, func(i, j int) bool {
+		a, b := analysis.TopHoldings[i], analysis.TopHoldings[j]
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+		return a.Symbol < b.Symbol
+	})
+	if len(analysis.TopHoldings) > 10 {
+		analysis.TopHoldings = analysis.TopHoldings[:10]
+	}
 
-	for sector, ret := range sectorReturns {
-		if ret > bestReturn {
-			bestReturn = ret
-			bestSector = sector
+	// 集中度按最后一个持仓日、以股票市值为分母计算
+	last := series.Positions[len(series.Positions)-1].Date
+	analysis.ConcentrationRisk = concentrationMetrics(byDate[last])
+	return analysis
+}
+
+// concentrationMetrics 计算持仓集中度
+func concentrationMetrics(positions []models.BacktestPosition) *ConcentrationMetrics {
+	metrics := &ConcentrationMetrics{}
+	total := 0.0
+	weights := make([]float64, 0, len(positions))
+	for _, p := range positions {
+		total += p.Value
+		weights = append(weights, p.Value)
+	}
+	if total <= 0 {
+		return metrics
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(weights)))
+	for i, w := range weights {
+		w /= total
+		metrics.HerfindahlIndex += w * w
+		if i < 5 {
+			metrics.Top5Concentration += w
+		}
+		if i < 10 {
+			metrics.Top10Concentration += w
 		}
-		if ret < worstReturn {
-			worstReturn = ret
-			worstSector = sector
+	}
+	metrics.EffectiveStocks = 1 / metrics.HerfindahlIndex
+	return metrics
+}
+
+// sectorOf 证券所属行业
+func (brs *BacktestResultsService) sectorOf(instrument string) string {
+	if industry, ok := brs.industries[strings.ToUpper(instrument)]; ok {
+		return industry
+	}
+	return qlib.UnknownIndustry
+}
+
+// sectorExposure 各行业的日均持仓权重，未设置行业分类时为空
+func (brs *BacktestResultsService) sectorExposure(series *backtestSeries) map[string]float64 {
+	exposure := make(map[string]float64)
+	if len(brs.industries) == 0 {
+		return exposure
+	}
+	for _, p := range series.Positions {
+		exposure[brs.sectorOf(p.Instrument)] += p.Weight / float64(len(series.Dates))
+	}
+	return exposure
+}
+
+// calculateSectorAnalysis 按行业汇总持仓收益
+//
+// 第t日的行业权重取t-1日收盘持仓，证券收益为t日收盘价（当日卖出时为成交均价）相对t-1日收盘价的涨跌幅，
+// 行业贡献按Carino系数连接，各行业贡献之和等于持仓部分的复利收益。
+func (brs *BacktestResultsService) calculateSectorAnalysis(series *backtestSeries) *SectorAnalysis {
+	analysis := &SectorAnalysis{
+		SectorReturns:      map[string]float64{},
+		SectorWeights:      brs.sectorExposure(series),
+		SectorContribution: map[string]float64{},
+	}
+	if len(brs.industries) == 0 || len(series.Positions) == 0 {
+		return analysis
+	}
+
+	byDate := positionsByDate(series.Positions)
+	sellPrices := make(map[string]map[string]float64)
+	for _, t := range series.Trades {
+		if t.Direction == "sell" {
+			if sellPrices[t.Date] == nil {
+				sellPrices[t.Date] = make(map[string]float64)
+			}
+			sellPrices[t.Date][t.Instrument] = t.Price
 		}
 	}
 
-	return &SectorAnalysis{
-		SectorReturns:         sectorReturns,
-		SectorWeights:         sectorWeights,
-		SectorContribution:    sectorContribution,
-		BestPerformingSector:  bestSector,
-		WorstPerformingSector: worstSector,
-	}, nil
-}
-
-// calculatePeriodAnalysis 计算分期间分析
-func (brs *BacktestResultsService) calculatePeriodAnalysis(strategy models.Strategy) (*PeriodAnalysis, error) {
-	// 模拟月度收益
-	monthlyReturns := map[string]float64{
-		"2023-01": 0.035,
-		"2023-02": 0.028,
-		"2023-03": 0.042,
-		"2023-04": -0.015,
-		"2023-05": 0.038,
-		"2023-06": 0.022,
-		"2023-07": 0.031,
-		"2023-08": -0.008,
-		"2023-09": 0.025,
-		"2023-10": 0.045,
-		"2023-11": 0.018,
-		"2023-12": 0.033,
-	}
-
-	// 计算季度收益
-	quarterlyReturns := map[string]float64{
-		"2023-Q1": 0.108,
-		"2023-Q2": 0.045,
-		"2023-Q3": 0.048,
-		"2023-Q4": 0.099,
-	}
-
-	yearlyReturns := map[string]float64{
-		"2023": strategy.AnnualReturn,
-	}
-
-	// 找出最佳和最差月份
-	bestMonth, worstMonth := PeriodInfo{}, PeriodInfo{}
-	bestReturn, worstReturn := -1.0, 1.0
-
-	for month, ret := range monthlyReturns {
-		if ret > bestReturn {
-			bestReturn = ret
-			bestMonth = PeriodInfo{Period: month, Return: ret}
+	var periods []map[string]analytics.SegmentReturn
+	var totals []float64
+	for i := 1; i < len(series.Dates); i++ {
+		prices := sellPrices[series.Dates[i]]
+		if prices == nil {
+			prices = make(map[string]float64)
 		}
-		if ret < worstReturn {
-			worstReturn = ret
-			worstMonth = PeriodInfo{Period: month, Return: ret}
+		for _, p := range byDate[series.Dates[i]] {
+			prices[p.Instrument] = p.Price
 		}
+		weights := make(map[string]float64)
+		returns := make(map[string]float64)
+		for _, p := range byDate[series.Dates[i-1]] {
+			weights[p.Instrument] = p.Weight
+			if price, ok := prices[p.Instrument]; ok && p.Price > 0 {
+				returns[p.Instrument] = price/p.Price - 1
+			}
+		}
+		segments := analytics.AggregateSegments(weights, returns, brs.sectorOf)
+		total := 0.0
+		for _, segment := range segments {
+			total += segment.Weight * segment.Return
+		}
+		periods = append(periods, segments)
+		totals = append(totals, total)
 	}
 
-	// 计算一致性指标
-	positiveMonths := 0
-	for _, ret := range monthlyReturns {
-		if ret > 0 {
-			positiveMonths++
+	coefficients, err := analytics.LinkingCoefficients(totals, make([]float64, len(totals)), analytics.LinkCarino)
+	if err != nil {
+		return analysis
+	}
+	growth := make(map[string]float64)
+	for i, segments := range periods {
+		for sector, segment := range segments {
+			if _, ok := growth[sector]; !ok {
+				growth[sector] = 1
+			}
+			growth[sector] *= 1 + segment.Return
+			analysis.SectorContribution[sector] += segment.Weight * segment.Return * coefficients[i]
 		}
 	}
+	for sector, g := range growth {
+		analysis.SectorReturns[sector] = g - 1
+	}
+	best, worst := bestWorstPeriod(analysis.SectorReturns)
+	analysis.BestPerformingSector = best.Period
+	analysis.WorstPerformingSector = worst.Period
+	return analysis
+}
 
-	return &PeriodAnalysis{
-		MonthlyReturns:   monthlyReturns,
-		QuarterlyReturns: quarterlyReturns,
-		YearlyReturns:    yearlyReturns,
-		BestMonth:        bestMonth,
-		WorstMonth:       worstMonth,
-		BestQuarter:      PeriodInfo{Period: "2023-Q1", Return: 0.108},
-		WorstQuarter:     PeriodInfo{Period: "2023-Q2", Return: 0.045},
-		ConsistencyMetrics: &ConsistencyMetrics{
-			MonthlyWinRate:   float64(positiveMonths) / float64(len(monthlyReturns)),
-			QuarterlyWinRate: 1.0,
-			YearlyWinRate:    1.0,
-			ConsistencyScore: 0.85,
-		},
-	}, nil
-}
-
-// calculateBenchmarkComparison 计算基准对比
-func (brs *BacktestResultsService) calculateBenchmarkComparison(strategy models.Strategy) ([]models.BenchmarkComparison, error) {
-	benchmarks := []models.BenchmarkComparison{
-		{
-			BenchmarkName:    "CSI300",
-			ExcessReturn:     strategy.ExcessReturn,
-			TrackingError:    0.05,
-			InformationRatio: strategy.ExcessReturn / 0.05,
-			ActiveReturn:     strategy.ExcessReturn,
-			UpCapture:        1.1,
-			DownCapture:      0.9,
-			CorrelationCoeff: 0.85,
-		},
-		{
-			BenchmarkName:    "CSI500",
-			ExcessReturn:     strategy.AnnualReturn - 0.12,
-			TrackingError:    0.08,
-			InformationRatio: (strategy.AnnualReturn - 0.12) / 0.08,
-			ActiveReturn:     strategy.AnnualReturn - 0.12,
-			UpCapture:        1.05,
-			DownCapture:      0.95,
-			CorrelationCoeff: 0.75,
-		},
+// calculatePeriodAnalysis 按月、季、年复利汇总收益
+func (brs *BacktestResultsService) calculatePeriodAnalysis(series *backtestSeries) *PeriodAnalysis {
+	monthKey := func(date string) string { return date[:7] }
+	quarterKey := func(date string) string {
+		month, _ := strconv.Atoi(date[5:7])
+		return fmt.Sprintf("%s-Q%d", date[:4], (month-1)/3+1)
 	}
+	yearKey := func(date string) string { return date[:4] }
 
-	return benchmarks, nil
+	analysis := &PeriodAnalysis{
+		MonthlyReturns:   periodReturns(series.Dates, series.Returns, monthKey),
+		QuarterlyReturns: periodReturns(series.Dates, series.Returns, quarterKey),
+		YearlyReturns:    periodReturns(series.Dates, series.Returns, yearKey),
+	}
+	analysis.BestMonth, analysis.WorstMonth = bestWorstPeriod(analysis.MonthlyReturns)
+	analysis.BestQuarter, analysis.WorstQuarter = bestWorstPeriod(analysis.QuarterlyReturns)
+
+	consistency := &ConsistencyMetrics{
+		MonthlyWinRate:   positiveRatio(analysis.MonthlyReturns),
+		QuarterlyWinRate: positiveRatio(analysis.QuarterlyReturns),
+		YearlyWinRate:    positiveRatio(analysis.YearlyReturns),
+	}
+	// 一致性得分：有基准时为跑赢基准的月份占比，否则为月度胜率
+	consistency.ConsistencyScore = consistency.MonthlyWinRate
+	if series.Benchmark != nil {
+		benchmarkMonthly := periodReturns(series.Dates, series.Benchmark, monthKey)
+		beat := 0
+		for month, ret := range analysis.MonthlyReturns {
+			if ret > benchmarkMonthly[month] {
+				beat++
+			}
+		}
+		consistency.ConsistencyScore = float64(beat) / float64(len(analysis.MonthlyReturns))
+	}
+	analysis.ConsistencyMetrics = consistency
+	return analysis
+}
+
+// calculateBenchmarkComparison 计算与回测基准的对比
+func (brs *BacktestResultsService) calculateBenchmarkComparison(series *backtestSeries) []models.BenchmarkComparison {
+	if series.Metrics.Relative == nil {
+		return []models.BenchmarkComparison{}
+	}
+	return []models.BenchmarkComparison{*benchmarkComparison(series.BenchmarkName, series.Metrics)}
+}
+
+// benchmarkComparison 将相对基准指标转换为基准对比结果，调用方需保证 metrics.Relative 非空
+func benchmarkComparison(benchmarkName string, metrics *analytics.Metrics) *models.BenchmarkComparison {
+	relative := metrics.Relative
+	return &models.BenchmarkComparison{
+		BenchmarkName:    benchmarkName,
+		BenchmarkReturn:  relative.BenchmarkReturn,
+		StrategyReturn:   metrics.TotalReturn,
+		ExcessReturn:     relative.ExcessReturn,
+		TrackingError:    relative.TrackingError,
+		InformationRatio: relative.InformationRatio,
+		ActiveReturn:     relative.ActiveReturn,
+		UpCapture:        relative.UpCapture,
+		DownCapture:      relative.DownCapture,
+		CorrelationCoeff: relative.Correlation,
+		BetaCoeff:        relative.Beta,
+		AlphaCoeff:       relative.Alpha,
+	}
 }
 
 // 辅助方法
@@ -963,14 +1161,6 @@ func (brs *BacktestResultsService) resampleData(dates []string, data1, data2 []f
 	return resampledDates, resampledData1, resampledData2
 }
 
-// calculateTradingDays 计算交易日天数
-func (brs *BacktestResultsService) calculateTradingDays(startDate, endDate string) int {
-	start, _ := time.Parse("2006-01-02", startDate)
-	end, _ := time.Parse("2006-01-02", endDate)
-	totalDays := int(end.Sub(start).Hours() / 24)
-	return int(float64(totalDays) * 5 / 7) // 简化计算，假设5/7是交易日
-}
-
 // calculateRollingVolatility 计算滚动波动率
 func (brs *BacktestResultsService) calculateRollingVolatility(returns []float64, window int) []float64 {
 	volatility := make([]float64, len(returns))
@@ -982,7 +1172,7 @@ func (brs *BacktestResultsService) calculateRollingVolatility(returns []float64,
 		}
 
 		windowReturns := returns[i-window+1 : i+1]
-		mean := brs.calculateMean(windowReturns)
+		mean := analytics.Mean(windowReturns)
 		variance := 0.0
 
 		for _, ret := range windowReturns {
@@ -1007,8 +1197,8 @@ func (brs *BacktestResultsService) calculateRollingSharpe(returns []float64, win
 		}
 
 		windowReturns := returns[i-window+1 : i+1]
-		mean := brs.calculateMean(windowReturns)
-		std := brs.calculateStd(windowReturns)
+		mean := analytics.Mean(windowReturns)
+		std := analytics.StdDev(windowReturns)
 
 		if std > 0 {
 			sharpe[i] = (mean - riskFreeRate) / std * math.Sqrt(252)
@@ -1018,96 +1208,286 @@ func (brs *BacktestResultsService) calculateRollingSharpe(returns []float64, win
 	return sharpe
 }
 
-// calculateMean 计算均值
-func (brs *BacktestResultsService) calculateMean(values []float64) float64 {
-	sum := 0.0
-	for _, v := range values {
-		sum += v
+// periodReturns 按 key 分组复利汇总日收益
+func periodReturns(dates []string, returns []float64, key func(string) string) map[string]float64 {
+	growth := make(map[string]float64)
+	for i, date := range dates {
+		k := key(date)
+		if _, ok := growth[k]; !ok {
+			growth[k] = 1
+		}
+		growth[k] *= 1 + returns[i]
 	}
-	return sum / float64(len(values))
+	for k, g := range growth {
+		growth[k] = g - 1
+	}
+	return growth
 }
 
-// calculateStd 计算标准差
-func (brs *BacktestResultsService) calculateStd(values []float64) float64 {
-	mean := brs.calculateMean(values)
-	variance := 0.0
+// bestWorstPeriod 收益最高和最低的期间
+func bestWorstPeriod(returns map[string]float64) (PeriodInfo, PeriodInfo) {
+	periods := make([]string, 0, len(returns))
+	for period := range returns {
+		periods = append(periods, period)
+	}
+	sort.Strings(periods)
 
-	for _, v := range values {
-		variance += math.Pow(v-mean, 2)
+	var best, worst PeriodInfo
+	for i, period := range periods {
+		ret := returns[period]
+		if i == 0 || ret > best.Return {
+			best = PeriodInfo{Period: period, Return: ret}
+		}
+		if i == 0 || ret < worst.Return {
+			worst = PeriodInfo{Period: period, Return: ret}
+		}
 	}
-	variance /= float64(len(values) - 1)
-	return math.Sqrt(variance)
+	return best, worst
 }
 
-// 图表生成方法的占位符（具体实现在chart_generator.go中）
+// positiveRatio 正收益期间占比
+func positiveRatio(returns map[string]float64) float64 {
+	if len(returns) == 0 {
+		return 0
+	}
+	positive := 0
+	for _, ret := range returns {
+		if ret > 0 {
+			positive++
+		}
+	}
+	return float64(positive) / float64(len(returns))
+}
 
-func (brs *BacktestResultsService) generateCharts(strategy models.Strategy, result *DetailedBacktestResult) ([]ChartData, error) {
-	// 这里会调用chart_generator生成各种图表
-	return []ChartData{}, nil
+func maxFloat(values []float64) float64 {
+	result := math.Inf(-1)
+	for _, v := range values {
+		result = math.Max(result, v)
+	}
+	if len(values) == 0 {
+		return 0
+	}
+	return result
 }
 
-func (brs *BacktestResultsService) generateCumulativeReturnsChart(strategy models.Strategy) (*ChartData, error) {
-	return &ChartData{
-		ID:    "cumulative_returns",
-		Type:  "line",
-		Title: "累积收益曲线",
-	}, nil
+func minFloat(values []float64) float64 {
+	result := math.Inf(1)
+	for _, v := range values {
+		result = math.Min(result, v)
+	}
+	if len(values) == 0 {
+		return 0
+	}
+	return result
 }
 
-func (brs *BacktestResultsService) generateDrawdownsChart(strategy models.Strategy) (*ChartData, error) {
-	return &ChartData{
-		ID:    "drawdowns",
-		Type:  "area",
-		Title: "回撤分析",
-	}, nil
+// 图表生成方法
+
+// chartTemplates 各类图表的基本信息
+var chartTemplates = map[ChartType]ChartData{
+	ChartTypeCumulativeReturns:  {ID: "cumulative_returns", Type: "line", Title: "累积收益曲线"},
+	ChartTypeDrawdowns:          {ID: "drawdowns", Type: "area", Title: "回撤分析"},
+	ChartTypeRollingMetrics:     {ID: "rolling_metrics", Type: "line", Title: "滚动指标"},
+	ChartTypePositionWeights:    {ID: "position_weights", Type: "bar", Title: "持仓权重"},
+	ChartTypeSectorExposure:     {ID: "sector_exposure", Type: "pie", Title: "行业暴露"},
+	ChartTypeMonthlyReturns:     {ID: "monthly_returns", Type: "bar", Title: "月度收益"},
+	ChartTypeReturnDistribution: {ID: "return_distribution", Type: "histogram", Title: "收益分布"},
+	ChartTypeRiskReturn:         {ID: "risk_return", Type: "scatter", Title: "风险收益散点图"},
 }
 
-func (brs *BacktestResultsService) generateRollingMetricsChart(strategy models.Strategy) (*ChartData, error) {
-	return &ChartData{
-		ID:    "rolling_metrics",
-		Type:  "line",
-		Title: "滚动指标",
-	}, nil
+// incompleteChart 缺少回测记录时返回不含数据的图表
+func incompleteChart(template ChartData) *ChartData {
+	chart := template
+	chart.Data = map[string]interface{}{}
+	chart.Config = map[string]interface{}{}
+	chart.Incomplete = true
+	chart.Message = incompleteResultMessage
+	return &chart
+}
+
+// generateCharts 生成详细结果中的全部图表
+func (brs *BacktestResultsService) generateCharts(series *backtestSeries) []ChartData {
+	options := GetChartDataOptions{Resolution: "daily"}
+	charts := []*ChartData{
+		brs.generateCumulativeReturnsChartWithOptions(series, options),
+		brs.generateDrawdownsChartWithOptions(series, options),
+		brs.generateRollingMetricsChartWithOptions(series, options),
+		brs.generatePositionWeightsChart(series),
+		brs.generateSectorExposureChart(series),
+		brs.generateMonthlyReturnsChart(series),
+		brs.generateReturnDistributionChart(series),
+		brs.generateRiskReturnChart(series),
+	}
+	result := make([]ChartData, 0, len(charts))
+	for _, chart := range charts {
+		result = append(result, *chart)
+	}
+	return result
 }
 
-func (brs *BacktestResultsService) generatePositionWeightsChart(strategy models.Strategy) (*ChartData, error) {
+// generatePositionWeightsChart 最后一个交易日的持仓权重
+func (brs *BacktestResultsService) generatePositionWeightsChart(series *backtestSeries) *ChartData {
+	last := series.Dates[len(series.Dates)-1]
+	positions := positionsByDate(series.Positions)[last]
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Weight > positions[j].Weight })
+
+	instruments := make([]string, len(positions))
+	weights := make([]float64, len(positions))
+	for i, p := range positions {
+		instruments[i] = p.Instrument
+		weights[i] = p.Weight
+	}
 	return &ChartData{
 		ID:    "position_weights",
 		Type:  "bar",
 		Title: "持仓权重",
-	}, nil
+		Data: map[string]interface{}{
+			"date":        last,
+			"instruments": instruments,
+			"weights":     weights,
+		},
+		Config: map[string]interface{}{
+			"yAxis": map[string]interface{}{
+				"title":  "权重",
+				"format": "percentage",
+			},
+		},
+	}
 }
 
-func (brs *BacktestResultsService) generateSectorExposureChart(strategy models.Strategy) (*ChartData, error) {
-	return &ChartData{
+// generateSectorExposureChart 行业暴露（日均持仓权重），未设置行业分类时标记为不完整
+func (brs *BacktestResultsService) generateSectorExposureChart(series *backtestSeries) *ChartData {
+	chart := &ChartData{
 		ID:    "sector_exposure",
 		Type:  "pie",
 		Title: "行业暴露",
-	}, nil
+		Data: map[string]interface{}{
+			"sectors": []string{},
+			"weights": []float64{},
+		},
+		Config: map[string]interface{}{},
+	}
+	if len(brs.industries) == 0 {
+		chart.Incomplete = true
+		chart.Message = "未设置行业分类数据"
+		return chart
+	}
+
+	exposure := brs.sectorExposure(series)
+	sectors := make([]string, 0, len(exposure))
+	for sector := range exposure {
+		sectors = append(sectors, sector)
+	}
+	sort.Slice(sectors, func(i, j int) bool {
+		return exposure[sectors[i]] > exposure[sectors[j]]
+	})
+	weights := make([]float64, len(sectors))
+	for i, sector := range sectors {
+		weights[i] = exposure[sector]
+	}
+	chart.Data = map[string]interface{}{
+		"sectors": sectors,
+		"weights": weights,
+	}
+	return chart
 }
 
-func (brs *BacktestResultsService) generateMonthlyReturnsChart(strategy models.Strategy) (*ChartData, error) {
+// generateMonthlyReturnsChart 月度收益
+func (brs *BacktestResultsService) generateMonthlyReturnsChart(series *backtestSeries) *ChartData {
+	monthly := periodReturns(series.Dates, series.Returns, func(date string) string { return date[:7] })
+	months := make([]string, 0, len(monthly))
+	for month := range monthly {
+		months = append(months, month)
+	}
+	sort.Strings(months)
+	returns := make([]float64, len(months))
+	for i, month := range months {
+		returns[i] = monthly[month]
+	}
 	return &ChartData{
 		ID:    "monthly_returns",
 		Type:  "bar",
 		Title: "月度收益",
-	}, nil
+		Data: map[string]interface{}{
+			"months":  months,
+			"returns": returns,
+		},
+		Config: map[string]interface{}{
+			"yAxis": map[string]interface{}{
+				"title":  "收益率",
+				"format": "percentage",
+			},
+		},
+	}
 }
 
-func (brs *BacktestResultsService) generateReturnDistributionChart(strategy models.Strategy) (*ChartData, error) {
+// generateReturnDistributionChart 日收益分布直方图
+func (brs *BacktestResultsService) generateReturnDistributionChart(series *backtestSeries) *ChartData {
+	const bins = 20
+	lo, hi := minFloat(series.Returns), maxFloat(series.Returns)
+	width := (hi - lo) / bins
+	centers := make([]float64, bins)
+	counts := make([]int, bins)
+	for i := range centers {
+		centers[i] = lo + width*(float64(i)+0.5)
+	}
+	for _, r := range series.Returns {
+		idx := 0
+		if width > 0 {
+			idx = minInt(int((r-lo)/width), bins-1)
+		}
+		counts[idx]++
+	}
 	return &ChartData{
 		ID:    "return_distribution",
 		Type:  "histogram",
 		Title: "收益分布",
-	}, nil
+		Data: map[string]interface{}{
+			"bins":   centers,
+			"counts": counts,
+			"mean":   analytics.Mean(series.Returns),
+			"std":    analytics.StdDev(series.Returns),
+		},
+		Config: map[string]interface{}{
+			"xAxis": map[string]interface{}{
+				"title":  "日收益率",
+				"format": "percentage",
+			},
+		},
+	}
 }
 
-func (brs *BacktestResultsService) generateRiskReturnChart(strategy models.Strategy) (*ChartData, error) {
+// generateRiskReturnChart 策略与基准的年化波动率-年化收益
+func (brs *BacktestResultsService) generateRiskReturnChart(series *backtestSeries) *ChartData {
+	point := func(name string, returns []float64) map[string]interface{} {
+		return map[string]interface{}{
+			"name":       name,
+			"volatility": analytics.AnnualizedVolatility(returns, analytics.Options{}),
+			"return":     analytics.AnnualizedReturn(returns, analytics.Options{}),
+		}
+	}
+	points := []map[string]interface{}{point("策略", series.Returns)}
+	if series.Benchmark != nil {
+		points = append(points, point(series.BenchmarkName, series.Benchmark))
+	}
 	return &ChartData{
 		ID:    "risk_return",
 		Type:  "scatter",
 		Title: "风险收益散点图",
-	}, nil
+		Data: map[string]interface{}{
+			"points": points,
+		},
+		Config: map[string]interface{}{
+			"xAxis": map[string]interface{}{
+				"title":  "年化波动率",
+				"format": "percentage",
+			},
+			"yAxis": map[string]interface{}{
+				"title":  "年化收益率",
+				"format": "percentage",
+			},
+		},
+	}
 }
 
 // 导出方法的占位符
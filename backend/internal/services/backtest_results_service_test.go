@@ -0,0 +1,150 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"qlib-backend/internal/models"
+)
+
+// newTestArtifacts 构造四个交易日的回测记录：净值 100、110、99、108.9，持有A并在最后一天卖出
+func newTestArtifacts() *backtestArtifacts {
+	dates := []string{"2023-01-03", "2023-01-04", "2023-01-05", "2023-01-06"}
+	values := []float64{100, 110, 99, 108.9}
+	returns := []float64{0, 0.1, -0.1, 0.1}
+	artifacts := &backtestArtifacts{}
+	for i, date := range dates {
+		artifacts.Values = append(artifacts.Values, models.BacktestPortfolioValue{
+			StrategyID: 1, Date: date, Value: values[i], Return: returns[i], Turnover: 0.5,
+		})
+		artifacts.Benchmark = append(artifacts.Benchmark, models.BacktestBenchmarkValue{
+			StrategyID: 1, Benchmark: "SH000300", Date: date, Return: returns[i] / 2,
+		})
+	}
+	artifacts.Positions = []models.BacktestPosition{
+		{StrategyID: 1, Date: "2023-01-03", Instrument: "A", Amount: 5, Price: 10, Value: 50, Weight: 0.5},
+		{StrategyID: 1, Date: "2023-01-04", Instrument: "A", Amount: 5, Price: 11, Value: 55, Weight: 0.6},
+	}
+	artifacts.Trades = []models.BacktestTrade{
+		{StrategyID: 1, Date: "2023-01-03", Instrument: "A", Direction: "buy", Amount: 5, Price: 10},
+		{StrategyID: 1, Date: "2023-01-05", Instrument: "A", Direction: "sell", Amount: 5, Price: 12, Commission: 1, PnL: 9},
+	}
+	return artifacts
+}
+
+func TestBacktestResultsFromArtifacts(t *testing.T) {
+	brs := &BacktestResultsService{}
+
+	series, err := buildBacktestSeries(newTestArtifacts(), "")
+	if err != nil {
+		t.Fatalf("buildBacktestSeries failed: %v", err)
+	}
+
+	t.Run("Performance", func(t *testing.T) {
+		metrics := brs.calculatePerformanceMetrics(series)
+		if math.Abs(metrics.TotalReturn-0.089) > 1e-9 {
+			t.Errorf("TotalReturn = %v, want 0.089", metrics.TotalReturn)
+		}
+		if math.Abs(metrics.MaxDrawdown+0.1) > 1e-9 {
+			t.Errorf("MaxDrawdown = %v, want -0.1", metrics.MaxDrawdown)
+		}
+		if metrics.WinRate != 0.5 {
+			t.Errorf("WinRate = %v, want 0.5", metrics.WinRate)
+		}
+		// 基准收益为策略的一半，beta 为 2
+		if math.Abs(metrics.Beta-2) > 1e-9 {
+			t.Errorf("Beta = %v, want 2", metrics.Beta)
+		}
+	})
+
+	t.Run("Risk", func(t *testing.T) {
+		risk := brs.calculateRiskMetrics(series)
+		if risk.MaxDrawdownDuration != 2 {
+			t.Errorf("MaxDrawdownDuration = %d, want 2", risk.MaxDrawdownDuration)
+		}
+		// 排序后日收益为 -0.1、0、0.1、0.1，5%分位插值为 -0.085
+		if math.Abs(risk.VaR95+0.085) > 1e-9 || math.Abs(risk.CVaR95+0.1) > 1e-9 {
+			t.Errorf("Unexpected VaR/CVaR: %v, %v", risk.VaR95, risk.CVaR95)
+		}
+	})
+
+	t.Run("Trades", func(t *testing.T) {
+		analysis := brs.calculateTradeAnalysis(series)
+		if analysis.TotalTrades != 2 || analysis.WinningTrades != 1 || analysis.LosingTrades != 0 {
+			t.Errorf("Unexpected trade counts: %+v", analysis)
+		}
+		// 卖出成本 60 - 1 - 9 = 50
+		if math.Abs(analysis.AverageTradeReturn-0.18) > 1e-9 {
+			t.Errorf("AverageTradeReturn = %v, want 0.18", analysis.AverageTradeReturn)
+		}
+	})
+
+	t.Run("Positions", func(t *testing.T) {
+		analysis := brs.calculatePositionAnalysis(series)
+		if analysis.MaxPositions != 1 || analysis.MinPositions != 0 {
+			t.Errorf("Unexpected position counts: %+v", analysis)
+		}
+		if len(analysis.TopHoldings) != 1 {
+			t.Fatalf("Expected one holding, got %+v", analysis.TopHoldings)
+		}
+		holding := analysis.TopHoldings[0]
+		if holding.HoldingDays != 2 || math.Abs(holding.Return-0.1) > 1e-9 || math.Abs(holding.Contribution-0.05) > 1e-9 {
+			t.Errorf("Unexpected holding: %+v", holding)
+		}
+	})
+
+	t.Run("Sectors", func(t *testing.T) {
+		if analysis := brs.calculateSectorAnalysis(series); len(analysis.SectorReturns) != 0 {
+			t.Errorf("Sector analysis without industries should be empty: %+v", analysis)
+		}
+
+		withIndustries := &BacktestResultsService{}
+		withIndustries.SetIndustryMapping(map[string]string{"A": "银行"})
+		analysis := withIndustries.calculateSectorAnalysis(series)
+		// A 持有期收益 11/10 × 12/11 - 1（第三天按卖出价计算）
+		if math.Abs(analysis.SectorReturns["银行"]-0.2) > 1e-9 || analysis.BestPerformingSector != "银行" {
+			t.Errorf("Unexpected sector returns: %+v", analysis)
+		}
+		if math.Abs(analysis.SectorWeights["银行"]-0.275) > 1e-9 {
+			t.Errorf("SectorWeights = %v, want 0.275", analysis.SectorWeights["银行"])
+		}
+		// 持仓部分逐日收益 0.05、0.6/11、0 连接后等于复利收益
+		if want := 1.05*(1+0.6/11) - 1; math.Abs(analysis.SectorContribution["银行"]-want) > 1e-9 {
+			t.Errorf("SectorContribution = %v, want %v", analysis.SectorContribution["银行"], want)
+		}
+		if chart := withIndustries.generateSectorExposureChart(series); chart.Incomplete {
+			t.Errorf("Sector exposure chart should be complete with industries: %+v", chart)
+		}
+	})
+
+	t.Run("TimeSeriesAndCharts", func(t *testing.T) {
+		timeSeries := brs.generateTimeSeriesData(series)
+		if math.Abs(timeSeries.CumulativeReturns[3]-0.089) > 1e-9 || len(timeSeries.BenchmarkCumulative) != 4 {
+			t.Errorf("Unexpected time series: %+v", timeSeries)
+		}
+		charts := brs.generateCharts(series)
+		if len(charts) != len(chartTemplates) {
+			t.Errorf("Expected %d charts, got %d", len(chartTemplates), len(charts))
+		}
+	})
+
+	t.Run("TimeRange", func(t *testing.T) {
+		recent, err := buildBacktestSeries(newTestArtifacts(), "2d")
+		if err != nil {
+			t.Fatalf("buildBacktestSeries failed: %v", err)
+		}
+		if len(recent.Dates) != 2 || recent.Dates[0] != "2023-01-05" || len(recent.Positions) != 0 || len(recent.Trades) != 1 {
+			t.Errorf("Unexpected filtered series: %+v", recent)
+		}
+		if _, err := buildBacktestSeries(newTestArtifacts(), "abc"); err == nil {
+			t.Error("Invalid time range should fail")
+		}
+	})
+
+	t.Run("Incomplete", func(t *testing.T) {
+		chart := incompleteChart(chartTemplates[ChartTypeDrawdowns])
+		if !chart.Incomplete || len(chart.Data) != 0 || chart.ID != "drawdowns" {
+			t.Errorf("Unexpected incomplete chart: %+v", chart)
+		}
+	})
+}
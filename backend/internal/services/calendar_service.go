@@ -0,0 +1,134 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"qlib-backend/internal/calendar"
+	"qlib-backend/internal/models"
+	"qlib-backend/internal/qlib"
+
+	"gorm.io/gorm"
+)
+
+// defaultMarket 无法判断证券所属市场时使用的交易日历
+const defaultMarket = "cn"
+
+// CalendarService 交易日历服务，维护按市场索引的日历注册表
+type CalendarService struct {
+	db       *gorm.DB
+	registry *calendar.Registry
+}
+
+// NewCalendarService 创建交易日历服务，registry 为空时使用全局注册表
+func NewCalendarService(db *gorm.DB, registry *calendar.Registry) *CalendarService {
+	if registry == nil {
+		registry = calendar.Default
+	}
+	return &CalendarService{db: db, registry: registry}
+}
+
+// LoadStoredCalendars 将数据库中保存的上传日历加载到注册表
+func (s *CalendarService) LoadStoredCalendars() error {
+	var records []models.TradingCalendar
+	if err := s.db.Find(&records).Error; err != nil {
+		return fmt.Errorf("获取交易日历失败: %v", err)
+	}
+	for _, record := range records {
+		market, err := calendar.LookupMarket(record.Market)
+		if err != nil {
+			return err
+		}
+		cal, err := calendar.Parse(market, strings.NewReader(record.DatesText), calendar.SourceUpload)
+		if err != nil {
+			return fmt.Errorf("加载%s交易日历失败: %v", record.Market, err)
+		}
+		s.registry.Register(cal)
+	}
+	return nil
+}
+
+// LoadQlibCalendar 从Qlib数据目录的 calendars/day.txt 加载市场的交易日历
+func (s *CalendarService) LoadQlibCalendar(marketCode, dataPath string) (*calendar.Info, error) {
+	market, err := calendar.LookupMarket(marketCode)
+	if err != nil {
+		return nil, err
+	}
+	if !qlib.IsQlibDataDir(dataPath) {
+		return nil, fmt.Errorf("数据目录 %s 不是Qlib数据目录", dataPath)
+	}
+	// 展开 ~ 等路径写法
+	dataPath = qlib.NewBinDataReader(dataPath).DataPath()
+	cal, err := calendar.LoadQlib(market, dataPath, "day")
+	if err != nil {
+		return nil, err
+	}
+	s.registry.Register(cal)
+	info := cal.Info()
+	return &info, nil
+}
+
+// UploadCalendar 保存管理员上传的交易日历并立即生效，同一市场的旧日历被替换
+func (s *CalendarService) UploadCalendar(marketCode string, content []byte, userID uint) (*calendar.Info, error) {
+	market, err := calendar.LookupMarket(marketCode)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := calendar.Parse(market, bytes.NewReader(content), calendar.SourceUpload)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make([]string, len(cal.Days()))
+	for i, day := range cal.Days() {
+		dates[i] = day.Format("2006-01-02")
+	}
+	record := models.TradingCalendar{
+		Market:     market.Code,
+		DatesText:  strings.Join(dates, "\n"),
+		StartDate:  dates[0],
+		EndDate:    dates[len(dates)-1],
+		Days:       len(dates),
+		UploadedBy: userID,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("market = ?", market.Code).Delete(&models.TradingCalendar{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&record).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存交易日历失败: %v", err)
+	}
+
+	s.registry.Register(cal)
+	info := cal.Info()
+	return &info, nil
+}
+
+// ListCalendars 列出各市场当前使用的交易日历
+func (s *CalendarService) ListCalendars() []calendar.Info {
+	return s.registry.List()
+}
+
+// instrumentMarket 根据Qlib证券代码判断所属市场，如 SH600000 为A股、00700.HK 为港股
+func instrumentMarket(instrument string) string {
+	code := strings.ToUpper(instrument)
+	switch {
+	case code == "":
+		return defaultMarket
+	case strings.HasPrefix(code, "SH"), strings.HasPrefix(code, "SZ"), strings.HasPrefix(code, "BJ"):
+		return "cn"
+	case strings.HasSuffix(code, ".HK"), strings.HasPrefix(code, "HK"), code == "HSI":
+		return "hk"
+	case strings.HasPrefix(code, "^"), strings.IndexFunc(code, func(r rune) bool { return r >= '0' && r <= '9' }) < 0:
+		return "us"
+	}
+	return defaultMarket
+}
+
+// marketCalendar 证券所属市场的交易日历
+func marketCalendar(instrument string) *calendar.Calendar {
+	return calendar.Default.MustGet(instrumentMarket(instrument))
+}
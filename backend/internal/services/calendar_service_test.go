@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"qlib-backend/internal/calendar"
+)
+
+func TestInstrumentMarket(t *testing.T) {
+	tests := map[string]string{
+		"SH000300": "cn",
+		"sz000001": "cn",
+		"00700.HK": "hk",
+		"HSI":      "hk",
+		"AAPL":     "us",
+		"^GSPC":    "us",
+		"":         "cn",
+	}
+	for instrument, want := range tests {
+		if got := instrumentMarket(instrument); got != want {
+			t.Errorf("instrumentMarket(%q) = %s, want %s", instrument, got, want)
+		}
+	}
+}
+
+func TestTimeRangeStartTradingDays(t *testing.T) {
+	market, _ := calendar.LookupMarket("cn")
+	days := []time.Time{
+		time.Date(2024, 2, 6, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 7, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 8, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 19, 0, 0, 0, 0, time.UTC),
+	}
+	cal, _ := calendar.New(market, days, calendar.SourceUpload)
+
+	// 3个交易日跨过春节假期
+	start, err := timeRangeStart(cal, days[3], "3d")
+	if err != nil || !start.Equal(days[1]) {
+		t.Errorf("timeRangeStart 3d = %v, %v", start, err)
+	}
+	// 回溯超过日历首日时从首日开始
+	if start, err := timeRangeStart(cal, days[3], "10d"); err != nil || !start.Equal(days[0]) {
+		t.Errorf("timeRangeStart 10d = %v, %v", start, err)
+	}
+	// 结束日期不在日历范围内时报错，而不是静默返回全部
+	if _, err := timeRangeStart(cal, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), "3d"); err == nil {
+		t.Error("End date outside the calendar should be an error")
+	}
+	if start, _ := timeRangeStart(cal, days[3], "1w"); !start.Equal(time.Date(2024, 2, 13, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("weeks should stay calendar based, got %v", start)
+	}
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"qlib-backend/internal/models"
+	"qlib-backend/internal/qlib"
+
+	"gorm.io/gorm"
+)
+
+// CompositeFactorRequest 创建复合因子请求
+type CompositeFactorRequest struct {
+	Name        string                `json:"name" binding:"required"`
+	Description string                `json:"description"`
+	Category    string                `json:"category"`
+	IsPublic    bool                  `json:"is_public"`
+	FactorIDs   []uint                `json:"factor_ids" binding:"required,min=2"`
+	Options     qlib.CompositeOptions `json:"options"`
+}
+
+// CompositeFactorDetail 复合因子及其成分
+type CompositeFactorDetail struct {
+	Factor     *models.Factor              `json:"factor"`
+	Options    qlib.CompositeOptions       `json:"options"`
+	Components []models.CompositeComponent `json:"components"`
+	// 表达式已变更或已删除的成分因子ID，非空时复合因子需要重新测试
+	StaleComponents []uint `json:"stale_components"`
+}
+
+// CreateCompositeFactor 由因子库中的表达式因子创建复合因子，成分因子的表达式快照用于判断复合因子是否失效
+func (s *FactorService) CreateCompositeFactor(req CompositeFactorRequest, userID uint) (*models.Factor, error) {
+	if err := req.Options.Validate(); err != nil {
+		return nil, err
+	}
+	if req.Options.Weighting == "" {
+		req.Options.Weighting = qlib.CompositeEqual
+	}
+	components, err := s.compositeComponents(req.FactorIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	configJSON, _ := json.Marshal(req.Options)
+
+	ids := make([]string, len(components))
+	for i, component := range components {
+		ids[i] = strconv.FormatUint(uint64(component.ID), 10)
+	}
+	factor := &models.Factor{
+		Name:        req.Name,
+		Expression:  fmt.Sprintf("Composite(%s: %s)", req.Options.Weighting, strings.Join(ids, ", ")),
+		Description: req.Description,
+		Category:    req.Category,
+		Status:      "active",
+		Kind:        models.FactorKindComposite,
+		ConfigJSON:  string(configJSON),
+		UserID:      userID,
+		IsPublic:    req.IsPublic,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(factor).Error; err != nil {
+			return fmt.Errorf("创建复合因子失败: %v", err)
+		}
+		for i, component := range components {
+			record := models.CompositeComponent{
+				CompositeID: factor.ID,
+				FactorID:    component.ID,
+				Position:    i,
+				Expression:  component.Expression,
+			}
+			if err := tx.Create(&record).Error; err != nil {
+				return fmt.Errorf("保存成分因子失败: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return factor, nil
+}
+
+// GetCompositeFactor 获取复合因子的加权配置、成分及失效的成分
+func (s *FactorService) GetCompositeFactor(id uint, userID uint) (*CompositeFactorDetail, error) {
+	factor, options, components, err := s.loadComposite(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.componentFactors(components)
+	if err != nil {
+		return nil, err
+	}
+	detail := &CompositeFactorDetail{Factor: factor, Options: options, Components: components}
+	for _, component := range components {
+		if f, ok := current[component.FactorID]; !ok || f.Expression != component.Expression {
+			detail.StaleComponents = append(detail.StaleComponents, component.FactorID)
+		}
+	}
+	return detail, nil
+}
+
+// TestCompositeFactor 用成分因子的当前表达式重新计算并测试复合因子，更新成分快照和权重，
+// 失效的复合因子测试成功后恢复为 active
+func (s *FactorService) TestCompositeFactor(id uint, userID uint, req FactorTestRequest) (*FactorTestResult, error) {
+	factor, options, components, err := s.loadComposite(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.componentFactors(components)
+	if err != nil {
+		return nil, err
+	}
+	expressions := make([]string, len(components))
+	for i, component := range components {
+		f, ok := current[component.FactorID]
+		if !ok {
+			return nil, fmt.Errorf("成分因子 %d 已删除，请重新创建复合因子", component.FactorID)
+		}
+		expressions[i] = f.Expression
+	}
+
+	result, err := s.factorEngine.TestComposite(context.Background(), expressions, qlib.FactorTestParams{
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Universe:  req.Universe,
+		Freq:      req.Freq,
+		Horizons:  req.Horizons,
+	}, options)
+	if err != nil {
+		return nil, fmt.Errorf("复合因子测试失败: %v", err)
+	}
+	testResult := &FactorTestResult{
+		IC:       result.IC,
+		IR:       result.IR,
+		RankIC:   result.RankIC,
+		Turnover: result.Turnover,
+		Coverage: result.Coverage,
+		TestDate: time.Now(),
+		Details:  result.Details,
+	}
+
+	weights, _ := result.Details["weights"].([]float64)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for i, component := range components {
+			updates := map[string]interface{}{"expression": expressions[i]}
+			if i < len(weights) {
+				updates["weight"] = weights[i]
+			}
+			if err := tx.Model(&models.CompositeComponent{}).Where("id = ?", component.ID).Updates(updates).Error; err != nil {
+				return fmt.Errorf("更新成分因子失败: %v", err)
+			}
+		}
+		if factor.Status == models.FactorStatusInvalid {
+			if err := tx.Model(factor).Update("status", "active").Error; err != nil {
+				return fmt.Errorf("更新复合因子状态失败: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveFactorMetrics(factor.ID, userID, req, testResult); err != nil {
+		return nil, err
+	}
+	return testResult, nil
+}
+
+// GetFactorTestHistory 获取因子的测试历史，最近的在前
+func (s *FactorService) GetFactorTestHistory(id uint, userID uint) ([]models.FactorTestRecord, error) {
+	if _, err := s.GetFactorByID(id, userID); err != nil {
+		return nil, err
+	}
+	var records []models.FactorTestRecord
+	if err := s.db.Where("factor_id = ?", id).Order("id DESC").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("获取因子测试历史失败: %v", err)
+	}
+	return records, nil
+}
+
+// invalidateComposites 成分因子表达式变更或删除后，将包含它的复合因子标记为失效
+//
+// tx 为修改成分因子的事务，保证成分因子变更和复合因子失效同时生效。
+func invalidateComposites(tx *gorm.DB, factorID uint) error {
+	err := tx.Model(&models.Factor{}).
+		Where("id IN (?)", tx.Model(&models.CompositeComponent{}).Select("composite_id").Where("factor_id = ?", factorID)).
+		Update("status", models.FactorStatusInvalid).Error
+	if err != nil {
+		return fmt.Errorf("更新复合因子状态失败: %v", err)
+	}
+	return nil
+}
+
+// compositeComponents 按请求顺序获取用户可见的成分因子，成分不能重复，也不能是复合因子
+func (s *FactorService) compositeComponents(ids []uint, userID uint) ([]models.Factor, error) {
+	if len(ids) < 2 {
+		return nil, fmt.Errorf("复合因子至少需要2个成分因子")
+	}
+	var factors []models.Factor
+	if err := s.db.Where("id IN ? AND (user_id = ? OR is_public = ?)", ids, userID, true).Find(&factors).Error; err != nil {
+		return nil, fmt.Errorf("获取成分因子失败: %v", err)
+	}
+	byID := make(map[uint]models.Factor, len(factors))
+	for _, factor := range factors {
+		byID[factor.ID] = factor
+	}
+
+	components := make([]models.Factor, 0, len(ids))
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		factor, ok := byID[id]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("成分因子 %d 不存在或无权限访问", id)
+		case seen[id]:
+			return nil, fmt.Errorf("成分因子 %d 重复", id)
+		case factor.Kind == models.FactorKindComposite:
+			return nil, fmt.Errorf("成分因子 %d 是复合因子，暂不支持嵌套", id)
+		}
+		seen[id] = true
+		components = append(components, factor)
+	}
+	return components, nil
+}
+
+// loadComposite 获取复合因子及其加权配置和按顺序排列的成分
+func (s *FactorService) loadComposite(id uint, userID uint) (*models.Factor, qlib.CompositeOptions, []models.CompositeComponent, error) {
+	var options qlib.CompositeOptions
+	factor, err := s.GetFactorByID(id, userID)
+	if err != nil {
+		return nil, options, nil, err
+	}
+	if factor.Kind != models.FactorKindComposite {
+		return nil, options, nil, fmt.Errorf("因子 %d 不是复合因子", id)
+	}
+	if err := json.Unmarshal([]byte(factor.ConfigJSON), &options); err != nil {
+		return nil, options, nil, fmt.Errorf("解析复合因子配置失败: %v", err)
+	}
+	var components []models.CompositeComponent
+	if err := s.db.Where("composite_id = ?", id).Order("position").Find(&components).Error; err != nil {
+		return nil, options, nil, fmt.Errorf("获取成分因子失败: %v", err)
+	}
+	return factor, options, components, nil
+}
+
+// componentFactors 获取成分因子的当前记录，已删除的成分不在结果中
+func (s *FactorService) componentFactors(components []models.CompositeComponent) (map[uint]models.Factor, error) {
+	ids := make([]uint, len(components))
+	for i, component := range components {
+		ids[i] = component.FactorID
+	}
+	var factors []models.Factor
+	if err := s.db.Where("id IN ?", ids).Find(&factors).Error; err != nil {
+		return nil, fmt.Errorf("获取成分因子失败: %v", err)
+	}
+	current := make(map[uint]models.Factor, len(factors))
+	for _, factor := range factors {
+		current[factor.ID] = factor
+	}
+	return current, nil
+}
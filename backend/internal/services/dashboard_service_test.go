@@ -1,41 +1,46 @@
 package services
 
 import (
-	"context"
 	"testing"
 	"time"
 
 	"qlib-backend/internal/testutils"
 )
 
+// useTestDB 将全局数据库切换为测试库，测试结束后恢复
+func useTestDB(t *testing.T) {
+	t.Helper()
+	db := testutils.RequireTestDB(t)
+	testutils.CleanupTables(db)
+	previous := DB
+	DB = db
+	t.Cleanup(func() { DB = previous })
+}
+
 func TestDashboardService(t *testing.T) {
 	// 设置测试环境
 	testutils.SetupTestEnv()
 	defer testutils.CleanupTestEnv()
 
 	// 设置测试数据库
-	db := testutils.SetupTestDB()
-	defer testutils.CleanupTestDB()
+	useTestDB(t)
 
 	// 创建服务实例
-	service := NewDashboardService(db)
-
-	t.Run("GetOverviewStats", func(t *testing.T) {
-		ctx := context.Background()
-		userID := uint(1)
+	service := NewDashboardService()
 
-		stats, err := service.GetOverviewStats(ctx, userID)
+	t.Run("GetOverviewStatistics", func(t *testing.T) {
+		stats, err := service.GetOverviewStatistics()
 		if err != nil {
-			t.Errorf("GetOverviewStats failed: %v", err)
+			t.Errorf("GetOverviewStatistics failed: %v", err)
 		}
 
 		// 验证统计数据结构
 		if stats == nil {
-			t.Error("Stats should not be nil")
+			t.Fatal("Stats should not be nil")
 		}
 
 		// 验证必要字段
-		requiredFields := []string{"total_models", "total_strategies", "total_factors", "total_datasets"}
+		requiredFields := []string{"total_datasets", "ready_datasets", "total_models", "trained_models", "running_tasks", "completed_tasks"}
 		for _, field := range requiredFields {
 			if _, exists := stats[field]; !exists {
 				t.Errorf("Stats should contain %s field", field)
@@ -43,111 +48,40 @@ func TestDashboardService(t *testing.T) {
 		}
 	})
 
-	t.Run("GetMarketOverview", func(t *testing.T) {
-		ctx := context.Background()
-
-		overview, err := service.GetMarketOverview(ctx)
+	t.Run("GetSystemResources", func(t *testing.T) {
+		resources, err := service.GetSystemResources()
 		if err != nil {
-			t.Errorf("GetMarketOverview failed: %v", err)
+			t.Errorf("GetSystemResources failed: %v", err)
 		}
 
-		if overview == nil {
-			t.Error("Overview should not be nil")
+		if resources == nil {
+			t.Fatal("Resources should not be nil")
 		}
 
-		// 验证市场概览数据结构
-		requiredFields := []string{"indices", "market_summary", "top_performers"}
+		// 验证资源使用数据结构
+		requiredFields := []string{"cpu_usage", "memory_usage", "disk_usage", "gpu_usage"}
 		for _, field := range requiredFields {
-			if _, exists := overview[field]; !exists {
-				t.Errorf("Overview should contain %s field", field)
-			}
-		}
-	})
-
-	t.Run("GetPerformanceChart", func(t *testing.T) {
-		ctx := context.Background()
-		userID := uint(1)
-		period := "30d"
-
-		chart, err := service.GetPerformanceChart(ctx, userID, period)
-		if err != nil {
-			t.Errorf("GetPerformanceChart failed: %v", err)
-		}
-
-		if chart == nil {
-			t.Error("Chart should not be nil")
-		}
-
-		// 验证图表数据结构
-		if data, exists := chart["data"]; !exists || data == nil {
-			t.Error("Chart should contain data field")
-		}
-
-		if labels, exists := chart["labels"]; !exists || labels == nil {
-			t.Error("Chart should contain labels field")
-		}
-	})
-
-	t.Run("GetRecentTasks", func(t *testing.T) {
-		ctx := context.Background()
-		userID := uint(1)
-		limit := 10
-
-		tasks, err := service.GetRecentTasks(ctx, userID, limit)
-		if err != nil {
-			t.Errorf("GetRecentTasks failed: %v", err)
-		}
-
-		if tasks == nil {
-			t.Error("Tasks should not be nil")
-		}
-
-		// 验证任务列表结构
-		taskList, ok := tasks["tasks"].([]interface{})
-		if !ok {
-			t.Error("Tasks should contain tasks array")
-		}
-
-		// 如果有任务，验证任务结构
-		if len(taskList) > 0 {
-			if firstTask, ok := taskList[0].(map[string]interface{}); ok {
-				requiredFields := []string{"id", "type", "status", "created_at"}
-				for _, field := range requiredFields {
-					if _, exists := firstTask[field]; !exists {
-						t.Errorf("Task should contain %s field", field)
-					}
-				}
+			if _, exists := resources[field]; !exists {
+				t.Errorf("Resources should contain %s field", field)
 			}
 		}
 	})
 
-	t.Run("GetSystemHealth", func(t *testing.T) {
-		ctx := context.Background()
-
-		health, err := service.GetSystemHealth(ctx)
+	t.Run("GetPerformanceMetrics", func(t *testing.T) {
+		metrics, err := service.GetPerformanceMetrics()
 		if err != nil {
-			t.Errorf("GetSystemHealth failed: %v", err)
+			t.Errorf("GetPerformanceMetrics failed: %v", err)
 		}
 
-		if health == nil {
-			t.Error("Health should not be nil")
+		if metrics == nil {
+			t.Fatal("Metrics should not be nil")
 		}
 
-		// 验证系统健康状态
-		requiredFields := []string{"status", "services", "metrics"}
+		// 验证性能指标数据结构
+		requiredFields := []string{"total_return", "sharpe_ratio", "max_drawdown", "win_rate"}
 		for _, field := range requiredFields {
-			if _, exists := health[field]; !exists {
-				t.Errorf("Health should contain %s field", field)
-			}
-		}
-
-		// 验证状态值
-		if status, exists := health["status"]; exists {
-			statusStr, ok := status.(string)
-			if !ok {
-				t.Error("Status should be a string")
-			} else if statusStr != "healthy" && statusStr != "degraded" && statusStr != "unhealthy" {
-				t.Errorf("Invalid status value: %s", statusStr)
+			if _, exists := metrics[field]; !exists {
+				t.Errorf("Metrics should contain %s field", field)
 			}
 		}
 	})
@@ -157,88 +91,55 @@ func TestDashboardServiceWithMockData(t *testing.T) {
 	testutils.SetupTestEnv()
 	defer testutils.CleanupTestEnv()
 
-	db := testutils.SetupTestDB()
-	defer testutils.CleanupTestDB()
+	useTestDB(t)
 
 	// 创建测试数据
 	user := testutils.CreateTestUser()
 	dataset := testutils.CreateTestDataset()
-	factor := testutils.CreateTestFactor()
 	model := testutils.CreateTestModel()
 	strategy := testutils.CreateTestStrategy()
+	task := testutils.CreateTestTask()
+	model.Status = "completed"
+	strategy.Status = "completed"
 
 	// 保存测试数据到数据库
-	db.Create(user)
-	db.Create(dataset)
-	db.Create(factor)
-	db.Create(model)
-	db.Create(strategy)
+	DB.Create(user)
+	DB.Create(dataset)
+	DB.Create(model)
+	DB.Create(strategy)
+	DB.Create(task)
 
-	service := NewDashboardService(db)
-	ctx := context.Background()
+	service := NewDashboardService()
 
-	t.Run("GetOverviewStatsWithData", func(t *testing.T) {
-		stats, err := service.GetOverviewStats(ctx, user.ID)
+	t.Run("GetOverviewStatisticsWithData", func(t *testing.T) {
+		stats, err := service.GetOverviewStatistics()
 		if err != nil {
-			t.Errorf("GetOverviewStats failed: %v", err)
+			t.Fatalf("GetOverviewStatistics failed: %v", err)
 		}
 
 		// 验证统计数据
-		if totalModels, exists := stats["total_models"]; exists {
-			if count, ok := totalModels.(int64); ok && count < 1 {
-				t.Error("Total models count should be at least 1")
-			}
-		}
-
-		if totalStrategies, exists := stats["total_strategies"]; exists {
-			if count, ok := totalStrategies.(int64); ok && count < 1 {
-				t.Error("Total strategies count should be at least 1")
+		expected := map[string]int64{
+			"total_datasets":  1,
+			"ready_datasets":  1,
+			"total_models":    1,
+			"trained_models":  1,
+			"completed_tasks": 1,
+		}
+		for field, want := range expected {
+			if got, ok := stats[field].(int64); !ok || got != want {
+				t.Errorf("%s should be %d, got %v", field, want, stats[field])
 			}
 		}
 	})
 
-	t.Run("GetRecentTasksWithData", func(t *testing.T) {
-		// 创建测试任务
-		task := testutils.CreateTestTask()
-		task.UserID = user.ID
-		db.Create(task)
-
-		tasks, err := service.GetRecentTasks(ctx, user.ID, 5)
+	t.Run("GetPerformanceMetricsWithData", func(t *testing.T) {
+		metrics, err := service.GetPerformanceMetrics()
 		if err != nil {
-			t.Errorf("GetRecentTasks failed: %v", err)
+			t.Fatalf("GetPerformanceMetrics failed: %v", err)
 		}
 
-		taskList, ok := tasks["tasks"].([]interface{})
-		if !ok {
-			t.Error("Tasks should contain tasks array")
-			return
-		}
-
-		if len(taskList) == 0 {
-			t.Error("Should have at least one task")
-		}
-	})
-}
-
-func TestDashboardServiceErrorHandling(t *testing.T) {
-	testutils.SetupTestEnv()
-	defer testutils.CleanupTestEnv()
-
-	// 使用无效的数据库连接测试错误处理
-	service := NewDashboardService(nil)
-	ctx := context.Background()
-
-	t.Run("GetOverviewStatsWithNilDB", func(t *testing.T) {
-		_, err := service.GetOverviewStats(ctx, 1)
-		if err == nil {
-			t.Error("Should return error with nil database")
-		}
-	})
-
-	t.Run("GetRecentTasksWithNilDB", func(t *testing.T) {
-		_, err := service.GetRecentTasks(ctx, 1, 10)
-		if err == nil {
-			t.Error("Should return error with nil database")
+		if sharpe, ok := metrics["sharpe_ratio"].(float64); !ok || sharpe != strategy.SharpeRatio {
+			t.Errorf("Average sharpe ratio should be %v, got %v", strategy.SharpeRatio, metrics["sharpe_ratio"])
 		}
 	})
 }
@@ -247,25 +148,23 @@ func TestDashboardServiceConcurrency(t *testing.T) {
 	testutils.SetupTestEnv()
 	defer testutils.CleanupTestEnv()
 
-	db := testutils.SetupTestDB()
-	defer testutils.CleanupTestDB()
+	useTestDB(t)
 
-	service := NewDashboardService(db)
-	ctx := context.Background()
+	service := NewDashboardService()
 
 	// 测试并发访问
 	t.Run("ConcurrentAccess", func(t *testing.T) {
 		done := make(chan bool, 10)
 
 		for i := 0; i < 10; i++ {
-			go func(userID uint) {
+			go func() {
 				defer func() { done <- true }()
-				
-				_, err := service.GetOverviewStats(ctx, userID)
+
+				_, err := service.GetOverviewStatistics()
 				if err != nil {
-					t.Errorf("Concurrent GetOverviewStats failed: %v", err)
+					t.Errorf("Concurrent GetOverviewStatistics failed: %v", err)
 				}
-			}(uint(i + 1))
+			}()
 		}
 
 		// 等待所有协程完成
@@ -285,18 +184,16 @@ func TestDashboardServicePerformance(t *testing.T) {
 	testutils.SetupTestEnv()
 	defer testutils.CleanupTestEnv()
 
-	db := testutils.SetupTestDB()
-	defer testutils.CleanupTestDB()
+	useTestDB(t)
 
-	service := NewDashboardService(db)
-	ctx := context.Background()
+	service := NewDashboardService()
 
 	// 性能测试
 	t.Run("PerformanceTest", func(t *testing.T) {
 		start := time.Now()
-		
+
 		for i := 0; i < 100; i++ {
-			_, err := service.GetOverviewStats(ctx, 1)
+			_, err := service.GetOverviewStatistics()
 			if err != nil {
 				t.Errorf("Performance test failed: %v", err)
 			}
@@ -308,43 +205,3 @@ func TestDashboardServicePerformance(t *testing.T) {
 		}
 	})
 }
-
-func TestDashboardServiceValidation(t *testing.T) {
-	testutils.SetupTestEnv()
-	defer testutils.CleanupTestEnv()
-
-	db := testutils.SetupTestDB()
-	defer testutils.CleanupTestDB()
-
-	service := NewDashboardService(db)
-	ctx := context.Background()
-
-	t.Run("InvalidUserID", func(t *testing.T) {
-		// 测试无效的用户ID
-		_, err := service.GetOverviewStats(ctx, 0)
-		if err == nil {
-			t.Error("Should return error for invalid user ID")
-		}
-	})
-
-	t.Run("InvalidLimit", func(t *testing.T) {
-		// 测试无效的限制参数
-		_, err := service.GetRecentTasks(ctx, 1, -1)
-		if err == nil {
-			t.Error("Should return error for negative limit")
-		}
-
-		_, err = service.GetRecentTasks(ctx, 1, 1000)
-		if err == nil {
-			t.Error("Should return error for too large limit")
-		}
-	})
-
-	t.Run("InvalidPeriod", func(t *testing.T) {
-		// 测试无效的时间周期
-		_, err := service.GetPerformanceChart(ctx, 1, "invalid_period")
-		if err == nil {
-			t.Error("Should return error for invalid period")
-		}
-	})
-}
\ No newline at end of file
@@ -53,20 +53,7 @@ func AutoMigrate() error {
 		return fmt.Errorf("database not initialized")
 	}
 
-	err := DB.AutoMigrate(
-		&models.User{},
-		&models.Dataset{},
-		&models.Factor{},
-		&models.Model{},
-		&models.Strategy{},
-		&models.Task{},
-		&models.Notification{},
-		&models.UIConfig{},
-		&models.Workflow{},
-		&models.WorkflowTemplate{},
-		&models.WorkflowExecution{},
-		&models.WorkflowStepExecution{},
-	)
+	err := DB.AutoMigrate(models.AllModels()...)
 
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
@@ -1,23 +1,30 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"qlib-backend/internal/models"
+	"qlib-backend/internal/qlib"
 
 	"gorm.io/gorm"
 )
 
 type DatasetService struct {
-	db *gorm.DB
+	db          *gorm.DB
+	taskManager *TaskManager
 }
 
-func NewDatasetService(db *gorm.DB) *DatasetService {
-	return &DatasetService{db: db}
+func NewDatasetService(db *gorm.DB, taskManager *TaskManager) *DatasetService {
+	return &DatasetService{db: db, taskManager: taskManager}
 }
 
 // CreateDataset 创建新数据集
@@ -159,7 +166,28 @@ func (s *DatasetService) ExploreDataset(id uint, limit int) (*DatasetExploreResu
 		return nil, err
 	}
 
-	// TODO: 实际的数据探索逻辑，这里返回模拟数据
+	if !qlib.IsQlibDataDir(dataset.DataPath) {
+		return nil, fmt.Errorf("数据集尚未处理完成或数据路径不是Qlib数据目录: %s", dataset.DataPath)
+	}
+
+	if limit <= 0 {
+		limit = 100
+	}
+
+	reader := qlib.NewBinDataReader(dataset.DataPath)
+	calendar, err := reader.Calendar("day")
+	if err != nil {
+		return nil, fmt.Errorf("读取交易日历失败: %v", err)
+	}
+	spans, err := reader.Instruments("all")
+	if err != nil {
+		return nil, fmt.Errorf("读取股票池失败: %v", err)
+	}
+	instruments, err := reader.ListInstruments("all", time.Time{}, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("读取股票池失败: %v", err)
+	}
+
 	result := &DatasetExploreResult{
 		DatasetID:   dataset.ID,
 		Name:        dataset.Name,
@@ -167,56 +195,131 @@ func (s *DatasetService) ExploreDataset(id uint, limit int) (*DatasetExploreResu
 		Columns: []DataColumnInfo{
 			{Name: "date", Type: "datetime", Description: "交易日期"},
 			{Name: "instrument", Type: "string", Description: "股票代码"},
-			{Name: "open", Type: "float", Description: "开盘价"},
-			{Name: "high", Type: "float", Description: "最高价"},
-			{Name: "low", Type: "float", Description: "最低价"},
-			{Name: "close", Type: "float", Description: "收盘价"},
-			{Name: "volume", Type: "int", Description: "成交量"},
-			{Name: "factor", Type: "float", Description: "复权因子"},
-		},
-		SampleData: []map[string]interface{}{
-			{
-				"date":       "2023-01-03",
-				"instrument": "000001.XSHE",
-				"open":       13.50,
-				"high":       13.80,
-				"low":        13.40,
-				"close":      13.75,
-				"volume":     1000000,
-				"factor":     1.0,
-			},
-			{
-				"date":       "2023-01-04",
-				"instrument": "000001.XSHE",
-				"open":       13.75,
-				"high":       14.00,
-				"low":        13.65,
-				"close":      13.90,
-				"volume":     1200000,
-				"factor":     1.0,
-			},
-		},
-		Statistics: map[string]interface{}{
-			"total_instruments": 300,
-			"date_range":       fmt.Sprintf("%s 到 %s", dataset.StartDate, dataset.EndDate),
-			"missing_data_rate": 0.02,
 		},
+		SampleData: []map[string]interface{}{},
+		Statistics: map[string]interface{}{},
+	}
+	if len(instruments) == 0 || len(calendar) == 0 {
+		return result, nil
+	}
+
+	fields, err := reader.Fields(instruments[0], "day")
+	if err != nil {
+		return nil, fmt.Errorf("读取字段列表失败: %v", err)
+	}
+	for _, field := range fields {
+		name := strings.TrimPrefix(field, "$")
+		result.Columns = append(result.Columns, DataColumnInfo{Name: name, Type: "float", Description: datasetFieldDescriptions[name]})
+	}
+
+	// 采样最近的交易日，跳过停牌（收盘价缺失）的记录
+	frame, err := reader.LoadFrame(context.Background(), qlib.FrameRequest{
+		Instruments: instruments[:minInt(len(instruments), limit)],
+		Fields:      fields,
+		Start:       calendar[maxInt(0, len(calendar)-limit)],
+		Freq:        "day",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取样本数据失败: %v", err)
+	}
+	// 按日期从近到远、逐日轮流取各股票，避免样本全部来自第一只股票
+	for t := len(frame.Calendar) - 1; t >= 0 && len(result.SampleData) < limit; t-- {
+		for i, inst := range frame.Instruments {
+			if len(result.SampleData) >= limit {
+				break
+			}
+			if closes, ok := frame.Fields["$close"]; ok && math.IsNaN(closes[i][t]) {
+				continue
+			}
+			row := map[string]interface{}{
+				"date":       frame.Calendar[t].Format("2006-01-02"),
+				"instrument": inst,
+			}
+			for _, field := range fields {
+				if v := frame.Fields[field][i][t]; !math.IsNaN(v) {
+					row[strings.TrimPrefix(field, "$")] = v
+				}
+			}
+			result.SampleData = append(result.SampleData, row)
+		}
+	}
+
+	// 按股票池区间与特征文件覆盖范围估算记录数和缺失率
+	calendarIndex := func(date time.Time) int {
+		return sort.Search(len(calendar), func(i int) bool { return !calendar[i].Before(date) })
+	}
+	var expected, covered int64
+	for _, span := range spans {
+		from, to := calendarIndex(span.Start), calendarIndex(span.End.AddDate(0, 0, 1))-1
+		if to < from {
+			continue
+		}
+		expected += int64(to - from + 1)
+		first, last, err := reader.FeatureRange(span.Symbol, "$close", "day")
+		if err != nil {
+			continue
+		}
+		if lo, hi := maxInt(from, first), minInt(to, last); hi >= lo {
+			covered += int64(hi - lo + 1)
+		}
+	}
+	if result.RecordCount == 0 {
+		result.RecordCount = covered
+	}
+
+	missingRate := 0.0
+	if expected > 0 {
+		missingRate = 1 - float64(covered)/float64(expected)
+	}
+	result.Statistics = map[string]interface{}{
+		"total_instruments": len(instruments),
+		"trading_days":      len(calendar),
+		"date_range":        fmt.Sprintf("%s 到 %s", calendar[0].Format("2006-01-02"), calendar[len(calendar)-1].Format("2006-01-02")),
+		"missing_data_rate": missingRate,
+		"fields":            fields,
 	}
 
 	return result, nil
 }
 
+// datasetFieldDescriptions 常见字段说明
+var datasetFieldDescriptions = map[string]string{
+	"open":   "开盘价",
+	"high":   "最高价",
+	"low":    "最低价",
+	"close":  "收盘价",
+	"volume": "成交量",
+	"amount": "成交额",
+	"factor": "复权因子",
+	"vwap":   "成交量加权平均价",
+	"change": "涨跌幅",
+}
+
 // UploadDataset 上传数据文件
 func (s *DatasetService) UploadDataset(fileHeader *multipart.FileHeader, req DatasetUploadRequest) (*models.Dataset, error) {
+	if req.Market != "" {
+		if err := qlib.ValidateMarketName(req.Market); err != nil {
+			return nil, err
+		}
+	}
+
 	// 创建上传目录
 	uploadDir := "uploads/datasets"
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
 		return nil, fmt.Errorf("创建上传目录失败: %v", err)
 	}
 
+	// 解析列映射
+	options := qlib.CSVIngestOptions{DateFormat: req.DateFormat, Market: req.Market}
+	if req.ColumnMapping != "" {
+		if err := json.Unmarshal([]byte(req.ColumnMapping), &options.ColumnMapping); err != nil {
+			return nil, fmt.Errorf("列映射格式错误: %v", err)
+		}
+	}
+
 	// 生成文件名
 	filename := fmt.Sprintf("%d_%s", time.Now().Unix(), fileHeader.Filename)
-	filepath := filepath.Join(uploadDir, filename)
+	filePath := filepath.Join(uploadDir, filename)
 
 	// 保存文件
 	file, err := fileHeader.Open()
@@ -225,7 +328,7 @@ func (s *DatasetService) UploadDataset(fileHeader *multipart.FileHeader, req Dat
 	}
 	defer file.Close()
 
-	dst, err := os.Create(filepath)
+	dst, err := os.Create(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("创建目标文件失败: %v", err)
 	}
@@ -240,7 +343,7 @@ func (s *DatasetService) UploadDataset(fileHeader *multipart.FileHeader, req Dat
 	dataset := &models.Dataset{
 		Name:        req.Name,
 		Description: req.Description,
-		DataPath:    filepath,
+		DataPath:    filePath,
 		Status:      "processing",
 		Market:      req.Market,
 		StartDate:   req.StartDate,
@@ -251,11 +354,36 @@ func (s *DatasetService) UploadDataset(fileHeader *multipart.FileHeader, req Dat
 
 	if err := s.db.Create(dataset).Error; err != nil {
 		// 删除已上传的文件
-		os.Remove(filepath)
+		os.Remove(filePath)
 		return nil, fmt.Errorf("创建数据集记录失败: %v", err)
 	}
 
-	// TODO: 启动后台任务处理数据文件，更新记录数等信息
+	// 启动后台任务将CSV转换为Qlib二进制格式，完成后更新记录数和日期范围
+	if s.taskManager != nil {
+		config := DatasetIngestionConfig{
+			DatasetID: dataset.ID,
+			FilePath:  filePath,
+			OutputDir: filepath.Join(uploadDir, fmt.Sprintf("dataset_%d", dataset.ID)),
+			Options:   options,
+		}
+		configJSON, _ := json.Marshal(config)
+
+		task := &models.Task{
+			Name:        fmt.Sprintf("数据集导入: %s", dataset.Name),
+			Type:        "dataset_ingestion",
+			Status:      "queued",
+			Description: fmt.Sprintf("导入数据文件 %s", fileHeader.Filename),
+			ConfigJSON:  string(configJSON),
+			UserID:      dataset.UserID,
+		}
+		if err := s.db.Create(task).Error; err != nil {
+			return nil, fmt.Errorf("创建导入任务失败: %v", err)
+		}
+		if err := s.taskManager.SubmitTask(task); err != nil {
+			s.db.Model(dataset).Update("status", "error")
+			return nil, fmt.Errorf("提交导入任务失败: %v", err)
+		}
+	}
 
 	return dataset, nil
 }
@@ -287,6 +415,17 @@ type DatasetUploadRequest struct {
 	Market      string `form:"market"`
 	StartDate   string `form:"start_date"`
 	EndDate     string `form:"end_date"`
+
+	ColumnMapping string `form:"column_mapping"` // JSON，目标字段 -> CSV列名，如 {"date":"trade_date","symbol":"ts_code","close":"close"}
+	DateFormat    string `form:"date_format"`    // 日期格式，如 YYYY-MM-DD、YYYYMMDD
+}
+
+// DatasetIngestionConfig 数据集导入任务配置
+type DatasetIngestionConfig struct {
+	DatasetID uint                  `json:"dataset_id"`
+	FilePath  string                `json:"file_path"`
+	OutputDir string                `json:"output_dir"`
+	Options   qlib.CSVIngestOptions `json:"options"`
 }
 
 type PaginatedDatasets struct {
@@ -310,4 +449,18 @@ type DatasetExploreResult struct {
 	Columns     []DataColumnInfo         `json:"columns"`
 	SampleData  []map[string]interface{} `json:"sample_data"`
 	Statistics  map[string]interface{}   `json:"statistics"`
-}
\ No newline at end of file
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
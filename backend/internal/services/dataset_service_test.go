@@ -6,27 +6,25 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
 	"qlib-backend/internal/models"
+	"qlib-backend/internal/qlib"
 	"qlib-backend/internal/testutils"
 )
 
 type DatasetServiceTestSuite struct {
 	suite.Suite
 	service *DatasetService
-	testDB  *testutils.TestDB
+	db      *gorm.DB
 }
 
 func (suite *DatasetServiceTestSuite) SetupSuite() {
-	suite.testDB = testutils.SetupTestDB()
-	suite.service = NewDatasetService(suite.testDB.DB)
-}
-
-func (suite *DatasetServiceTestSuite) TearDownSuite() {
-	suite.testDB.Cleanup()
+	suite.db = testutils.RequireTestDB(suite.T())
+	suite.service = NewDatasetService(suite.db, nil)
 }
 
 func (suite *DatasetServiceTestSuite) SetupTest() {
-	suite.testDB.CleanupTables()
+	testutils.CleanupTables(suite.db)
 }
 
 func (suite *DatasetServiceTestSuite) TestCreateDataset() {
@@ -81,7 +79,7 @@ func (suite *DatasetServiceTestSuite) TestGetDatasets() {
 	}
 
 	for i := range datasets {
-		suite.testDB.DB.Create(&datasets[i])
+		suite.db.Create(&datasets[i])
 	}
 
 	// 测试获取所有数据集
@@ -89,21 +87,22 @@ func (suite *DatasetServiceTestSuite) TestGetDatasets() {
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), result)
 	assert.Equal(suite.T(), int64(2), result.Total)
-	assert.Len(suite.T(), result.Datasets, 2)
+	assert.Len(suite.T(), result.Data, 2)
+	assert.Equal(suite.T(), int64(1), result.TotalPages)
 
 	// 测试按市场筛选
 	result, err = suite.service.GetDatasets(1, 10, "CSI300", "")
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), int64(1), result.Total)
-	assert.Len(suite.T(), result.Datasets, 1)
-	assert.Equal(suite.T(), "数据集1", result.Datasets[0].Name)
+	assert.Len(suite.T(), result.Data, 1)
+	assert.Equal(suite.T(), "数据集1", result.Data[0].Name)
 
 	// 测试按状态筛选
 	result, err = suite.service.GetDatasets(1, 10, "", "active")
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), int64(1), result.Total)
-	assert.Len(suite.T(), result.Datasets, 1)
-	assert.Equal(suite.T(), "数据集1", result.Datasets[0].Name)
+	assert.Len(suite.T(), result.Data, 1)
+	assert.Equal(suite.T(), "数据集1", result.Data[0].Name)
 }
 
 func (suite *DatasetServiceTestSuite) TestGetDatasetByID() {
@@ -119,7 +118,7 @@ func (suite *DatasetServiceTestSuite) TestGetDatasetByID() {
 		FileSize:    1000,
 		RecordCount: 100,
 	}
-	suite.testDB.DB.Create(&dataset)
+	suite.db.Create(&dataset)
 
 	// 测试获取存在的数据集
 	result, err := suite.service.GetDatasetByID(dataset.ID)
@@ -129,7 +128,7 @@ func (suite *DatasetServiceTestSuite) TestGetDatasetByID() {
 	assert.Equal(suite.T(), dataset.Description, result.Description)
 
 	// 测试获取不存在的数据集
-	result, err = suite.service.GetDatasetByID(999)
+	result, err = suite.service.GetDatasetByID(dataset.ID + 1000)
 	assert.Error(suite.T(), err)
 	assert.Nil(suite.T(), result)
 }
@@ -147,7 +146,7 @@ func (suite *DatasetServiceTestSuite) TestUpdateDataset() {
 		FileSize:    1000,
 		RecordCount: 100,
 	}
-	suite.testDB.DB.Create(&dataset)
+	suite.db.Create(&dataset)
 
 	// 更新数据集
 	updateReq := DatasetUpdateRequest{
@@ -157,25 +156,27 @@ func (suite *DatasetServiceTestSuite) TestUpdateDataset() {
 		Market:      "SSE50",
 	}
 
-	updatedDataset, err := suite.service.UpdateDataset(dataset.ID, updateReq)
+	_, err := suite.service.UpdateDataset(dataset.ID, updateReq)
+	assert.NoError(suite.T(), err)
+
+	updatedDataset, err := suite.service.GetDatasetByID(dataset.ID)
 	assert.NoError(suite.T(), err)
-	assert.NotNil(suite.T(), updatedDataset)
 	assert.Equal(suite.T(), updateReq.Name, updatedDataset.Name)
 	assert.Equal(suite.T(), updateReq.Description, updatedDataset.Description)
 	assert.Equal(suite.T(), updateReq.Status, updatedDataset.Status)
 	assert.Equal(suite.T(), updateReq.Market, updatedDataset.Market)
 
 	// 测试更新不存在的数据集
-	_, err = suite.service.UpdateDataset(999, updateReq)
+	_, err = suite.service.UpdateDataset(dataset.ID+1000, updateReq)
 	assert.Error(suite.T(), err)
 }
 
 func (suite *DatasetServiceTestSuite) TestDeleteDataset() {
-	// 创建测试数据
+	// 创建测试数据，数据路径指向不存在的文件，删除时只记录告警
 	dataset := models.Dataset{
 		Name:        "待删除数据集",
 		Description: "测试删除",
-		DataPath:    "/data/delete_test.csv",
+		DataPath:    suite.T().TempDir() + "/delete_test.csv",
 		Status:      "active",
 		Market:      "CSI300",
 		StartDate:   "2020-01-01",
@@ -183,7 +184,7 @@ func (suite *DatasetServiceTestSuite) TestDeleteDataset() {
 		FileSize:    1000,
 		RecordCount: 100,
 	}
-	suite.testDB.DB.Create(&dataset)
+	suite.db.Create(&dataset)
 
 	// 删除数据集
 	err := suite.service.DeleteDataset(dataset.ID)
@@ -191,70 +192,63 @@ func (suite *DatasetServiceTestSuite) TestDeleteDataset() {
 
 	// 验证数据集已被软删除
 	var deletedDataset models.Dataset
-	err = suite.testDB.DB.Unscoped().First(&deletedDataset, dataset.ID).Error
+	err = suite.db.Unscoped().First(&deletedDataset, dataset.ID).Error
 	assert.NoError(suite.T(), err)
-	assert.NotNil(suite.T(), deletedDataset.DeletedAt)
+	assert.True(suite.T(), deletedDataset.DeletedAt.Valid)
+
+	_, err = suite.service.GetDatasetByID(dataset.ID)
+	assert.Error(suite.T(), err)
 
 	// 测试删除不存在的数据集
-	err = suite.service.DeleteDataset(999)
+	err = suite.service.DeleteDataset(dataset.ID + 1000)
 	assert.Error(suite.T(), err)
 }
 
-func (suite *DatasetServiceTestSuite) TestValidateDataset() {
-	validReq := DatasetValidationRequest{
-		DataPath:    "/data/valid.csv",
-		Market:      "CSI300",
-		StartDate:   "2020-01-01",
-		EndDate:     "2023-12-31",
-		Columns:     []string{"date", "symbol", "close", "volume"},
-		DateFormat:  "YYYY-MM-DD",
-		Delimiter:   ",",
-		Encoding:    "UTF-8",
+func (suite *DatasetServiceTestSuite) TestExploreDatasetRequiresQlibData() {
+	// 未处理完成的数据集路径不是Qlib数据目录，不能探索
+	dataset := models.Dataset{
+		Name:     "未处理数据集",
+		DataPath: suite.T().TempDir(),
+		Status:   "processing",
+		Market:   "CSI300",
 	}
+	suite.db.Create(&dataset)
 
-	result, err := suite.service.ValidateDataset(validReq)
-	assert.NoError(suite.T(), err)
-	assert.NotNil(suite.T(), result)
-	assert.True(suite.T(), result.IsValid)
-	assert.Empty(suite.T(), result.ValidationErrors)
-	assert.Greater(suite.T(), result.EstimatedRecords, 0)
-
-	// 测试无效数据集
-	invalidReq := DatasetValidationRequest{
-		DataPath:   "/invalid/path.csv",
-		Market:     "",
-		StartDate:  "invalid-date",
-		EndDate:    "2023-12-31",
-		Columns:    []string{},
-		DateFormat: "YYYY-MM-DD",
-	}
+	result, err := suite.service.ExploreDataset(dataset.ID, 10)
+	assert.Error(suite.T(), err)
+	assert.Nil(suite.T(), result)
 
-	result, err = suite.service.ValidateDataset(invalidReq)
-	assert.NoError(suite.T(), err) // 验证函数本身不应该出错
-	assert.NotNil(suite.T(), result)
-	assert.False(suite.T(), result.IsValid)
-	assert.NotEmpty(suite.T(), result.ValidationErrors)
+	// 不存在的数据集
+	_, err = suite.service.ExploreDataset(dataset.ID+1000, 10)
+	assert.Error(suite.T(), err)
 }
 
-func (suite *DatasetServiceTestSuite) TestProcessUploadedFile() {
-	req := FileProcessRequest{
-		FileName:    "test_data.csv",
-		FileSize:    1024,
-		TempPath:    "/tmp/test_data.csv",
-		UserID:      1,
-		DatasetName: "上传测试数据集",
-		Market:      "CSI300",
-		Description: "通过文件上传创建的数据集",
-	}
-
-	result, err := suite.service.ProcessUploadedFile(req)
-	assert.NoError(suite.T(), err)
-	assert.NotNil(suite.T(), result)
-	assert.Greater(suite.T(), result.DatasetID, uint(0))
-	assert.Equal(suite.T(), "processing", result.Status)
-	assert.NotEmpty(suite.T(), result.TaskID)
+func (suite *DatasetServiceTestSuite) TestExploreDatasetSamplesAcrossInstruments() {
+	dir := suite.T().TempDir()
+	writer := qlib.NewBinDataWriter(dir)
+	start := time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC)
+	calendar := []time.Time{start, start.AddDate(0, 0, 1), start.AddDate(0, 0, 2)}
+	suite.Require().NoError(writer.WriteCalendar("day", calendar))
+	suite.Require().NoError(writer.WriteInstruments("all", []qlib.InstrumentSpan{
+		{Symbol: "SH600000", Start: calendar[0], End: calendar[2]},
+		{Symbol: "SZ000001", Start: calendar[0], End: calendar[2]},
+	}))
+	suite.Require().NoError(writer.WriteFeature("SH600000", "$close", "day", 0, []float64{10, 11, 12}))
+	suite.Require().NoError(writer.WriteFeature("SZ000001", "$close", "day", 0, []float64{20, 21, 22}))
+
+	dataset := models.Dataset{Name: "样本数据集", DataPath: dir, Status: "ready", Market: "all"}
+	suite.db.Create(&dataset)
+
+	// 样本应轮流来自各股票，而不是全部取自第一只股票
+	result, err := suite.service.ExploreDataset(dataset.ID, 3)
+	suite.Require().NoError(err)
+	suite.Require().Len(result.SampleData, 3)
+	assert.Equal(suite.T(), "SH600000", result.SampleData[0]["instrument"])
+	assert.Equal(suite.T(), "SZ000001", result.SampleData[1]["instrument"])
+	assert.Equal(suite.T(), "2023-01-05", result.SampleData[1]["date"])
+	assert.Equal(suite.T(), "2023-01-04", result.SampleData[2]["date"])
 }
 
 func TestDatasetServiceTestSuite(t *testing.T) {
 	suite.Run(t, new(DatasetServiceTestSuite))
-}
\ No newline at end of file
+}
@@ -0,0 +1,14 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUploadDatasetRejectsInvalidMarket(t *testing.T) {
+	service := NewDatasetService(nil, nil)
+	_, err := service.UploadDataset(nil, DatasetUploadRequest{Name: "prices", Market: "../../escaped"})
+	if err == nil || !strings.Contains(err.Error(), "无效的股票池名称") {
+		t.Errorf("Market with path separators should be rejected, got %v", err)
+	}
+}
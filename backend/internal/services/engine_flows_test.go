@@ -0,0 +1,103 @@
+package services
+
+import (
+	"testing"
+
+	"qlib-backend/internal/models"
+	"qlib-backend/internal/qlib"
+	"qlib-backend/internal/testutils"
+)
+
+func TestFactorServiceWithFakeEngine(t *testing.T) {
+	service := NewFactorService(nil, testutils.RequireFakeEngines(t, 3).Factors)
+
+	req := FactorTestRequest{
+		Expression: "$close / Ref($close, 20) - 1",
+		StartDate:  "2022-03-01",
+		EndDate:    "2022-12-31",
+		Universe:   "all",
+		Horizons:   []int{5},
+	}
+	result, err := service.TestFactor(req, 1)
+	if err != nil {
+		t.Fatalf("因子测试失败: %v", err)
+	}
+	if result.IC == 0 || result.Coverage <= 0 || result.Coverage > 1 {
+		t.Errorf("因子测试结果不符合预期: %+v", result)
+	}
+	again, _ := service.TestFactor(req, 1)
+	if again.IC != result.IC || again.RankIC != result.RankIC {
+		t.Errorf("相同请求应得到相同结果: %v != %v", again.IC, result.IC)
+	}
+
+	if _, err := service.TestFactor(FactorTestRequest{Expression: "Mean($close,", StartDate: "2022-03-01", EndDate: "2022-12-31"}, 1); err == nil {
+		t.Error("语法错误的表达式应返回错误")
+	}
+}
+
+func TestFactorResearchServiceWithFakeEngine(t *testing.T) {
+	service := NewFactorResearchService(nil, testutils.RequireFakeEngines(t, 3).Factors, qlib.NewSyntaxValidator("", ""), nil)
+
+	categories, err := service.GetQlibCategories()
+	if err != nil || len(categories) == 0 {
+		t.Fatalf("获取因子分类失败: %v", err)
+	}
+	total := 0
+	for _, category := range categories {
+		total += category.Count
+	}
+	if total != len(qlib.FakeBuiltinFactors) {
+		t.Errorf("分类下共有 %d 个因子，假后端内置 %d 个", total, len(qlib.FakeBuiltinFactors))
+	}
+
+	functions, err := service.GetQlibFunctions("")
+	if err != nil || len(functions) == 0 {
+		t.Fatalf("获取函数列表失败: %v", err)
+	}
+	filtered, _ := service.GetQlibFunctions(functions[0].Category)
+	for _, fn := range filtered {
+		if fn.Category != functions[0].Category {
+			t.Errorf("函数 %s 不属于分类 %s", fn.Name, functions[0].Category)
+		}
+	}
+}
+
+func TestCompositeFactorInvalidation(t *testing.T) {
+	useTestDB(t)
+	service := NewFactorService(DB, testutils.RequireFakeEngines(t, 3).Factors)
+
+	momentum, err := service.CreateFactor(FactorCreateRequest{Name: "动量", Expression: "$close / Ref($close, 20) - 1"}, 1)
+	if err != nil {
+		t.Fatalf("CreateFactor failed: %v", err)
+	}
+	volatility, err := service.CreateFactor(FactorCreateRequest{Name: "波动率", Expression: "Std($close, 20)"}, 1)
+	if err != nil {
+		t.Fatalf("CreateFactor failed: %v", err)
+	}
+	composite, err := service.CreateCompositeFactor(CompositeFactorRequest{Name: "组合", FactorIDs: []uint{momentum.ID, volatility.ID}}, 1)
+	if err != nil {
+		t.Fatalf("CreateCompositeFactor failed: %v", err)
+	}
+
+	// 成分因子表达式变更后复合因子失效
+	if _, err := service.UpdateFactor(momentum.ID, FactorUpdateRequest{Expression: "$close / Ref($close, 10) - 1"}, 1); err != nil {
+		t.Fatalf("UpdateFactor failed: %v", err)
+	}
+	var stored models.Factor
+	DB.First(&stored, composite.ID)
+	if stored.Status != models.FactorStatusInvalid {
+		t.Fatalf("Composite status = %q, want %q", stored.Status, models.FactorStatusInvalid)
+	}
+
+	// 失效的复合因子不能直接改回有效状态，重新测试后恢复
+	if _, err := service.UpdateFactor(composite.ID, FactorUpdateRequest{Status: "active"}, 1); err == nil {
+		t.Error("Re-activating an invalidated composite should fail")
+	}
+	if _, err := service.TestCompositeFactor(composite.ID, 1, FactorTestRequest{StartDate: "2022-03-01", EndDate: "2022-12-31"}); err != nil {
+		t.Fatalf("TestCompositeFactor failed: %v", err)
+	}
+	DB.First(&stored, composite.ID)
+	if stored.Status != "active" {
+		t.Errorf("Composite status after retest = %q, want active", stored.Status)
+	}
+}
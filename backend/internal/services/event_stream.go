@@ -0,0 +1,161 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// streamRetention 作业结束后保留事件供迟到的订阅者回放的时长
+const streamRetention = 10 * time.Minute
+
+// defaultReplayLimit 未单独设置时每类事件保留供回放的条数
+const defaultReplayLimit = 1000
+
+// StreamEvent 推送事件，格式与 /ws/factor-test/:test_id、/ws/task/:task_id 等WebSocket消息一致
+type StreamEvent struct {
+	Event string                 `json:"event"`
+	Data  map[string]interface{} `json:"data"`
+}
+
+// EventStreams 按作业ID分发因子测试、任务等长时间作业的进度和结果
+type EventStreams struct {
+	mu      sync.Mutex
+	streams map[string]*eventStream
+	limits  map[string]int // 各类事件保留供回放的条数
+}
+
+type eventStream struct {
+	events      []StreamEvent
+	counts      map[string]int // 各类事件在 events 中的条数
+	subscribers map[chan StreamEvent]bool
+	done        bool
+}
+
+// FactorTests 全局因子测试事件分发器，回放时只保留最新进度
+var FactorTests = newFactorTestStreams()
+
+func newFactorTestStreams() *EventStreams {
+	streams := NewEventStreams()
+	streams.SetReplayLimit("test_progress", 1)
+	return streams
+}
+
+// NewEventStreams 创建事件分发器
+func NewEventStreams() *EventStreams {
+	return &EventStreams{streams: make(map[string]*eventStream), limits: make(map[string]int)}
+}
+
+// SetReplayLimit 设置某类事件保留供回放的条数，超过后丢弃最早的一条；1 表示只保留最新一条
+//
+// 只影响回放，已订阅的连接仍会收到全部事件。
+func (s *EventStreams) SetReplayLimit(event string, limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limits[event] = limit
+}
+
+func (s *EventStreams) replayLimit(event string) int {
+	if limit, ok := s.limits[event]; ok && limit > 0 {
+		return limit
+	}
+	return defaultReplayLimit
+}
+
+// Start 登记一个作业，之后的订阅者先回放该作业保留的事件
+func (s *EventStreams) Start(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams[id] = &eventStream{counts: make(map[string]int), subscribers: make(map[chan StreamEvent]bool)}
+}
+
+// Publish 发布事件，未登记的作业忽略
+func (s *EventStreams) Publish(id, event string, data map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stream, ok := s.streams[id]
+	if !ok || stream.done {
+		return
+	}
+	e := StreamEvent{Event: event, Data: data}
+	stream.events = append(stream.events, e)
+	stream.counts[event]++
+	if stream.counts[event] > s.replayLimit(event) {
+		stream.dropOldest(event)
+	}
+	for ch := range stream.subscribers {
+		select {
+		case ch <- e:
+		default:
+			// 订阅者消费过慢时丢弃该订阅，避免阻塞作业
+			delete(stream.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// dropOldest 从回放缓冲中移除最早的一条同类事件
+func (stream *eventStream) dropOldest(event string) {
+	for i, e := range stream.events {
+		if e.Event == event {
+			last := len(stream.events) - 1
+			copy(stream.events[i:], stream.events[i+1:])
+			stream.events[last] = StreamEvent{}
+			stream.events = stream.events[:last]
+			stream.counts[event]--
+			return
+		}
+	}
+}
+
+// Finish 结束作业并关闭全部订阅，事件在保留期内仍可回放
+func (s *EventStreams) Finish(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stream, ok := s.streams[id]
+	if !ok || stream.done {
+		return
+	}
+	stream.done = true
+	for ch := range stream.subscribers {
+		close(ch)
+	}
+	stream.subscribers = nil
+	time.AfterFunc(streamRetention, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.streams[id] == stream {
+			delete(s.streams, id)
+		}
+	})
+}
+
+// Subscribe 订阅作业事件，先回放保留的事件；作业结束后通道关闭
+//
+// 作业不存在时返回 false。调用方提前退出时应调用返回的取消函数。
+func (s *EventStreams) Subscribe(id string) (<-chan StreamEvent, func(), bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stream, ok := s.streams[id]
+	if !ok {
+		return nil, nil, false
+	}
+
+	ch := make(chan StreamEvent, len(stream.events)+64)
+	for _, e := range stream.events {
+		ch <- e
+	}
+	if stream.done {
+		close(ch)
+		return ch, func() {}, true
+	}
+	stream.subscribers[ch] = true
+	cancel := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if stream.subscribers[ch] {
+			delete(stream.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel, true
+}
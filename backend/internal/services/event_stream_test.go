@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+
+	"qlib-backend/internal/qlib"
+)
+
+func TestEventStreams(t *testing.T) {
+	streams := NewEventStreams()
+	if _, _, ok := streams.Subscribe("missing"); ok {
+		t.Fatal("unknown test should not be subscribable")
+	}
+
+	streams.Start("t1")
+	streams.Publish("t1", "test_progress", map[string]interface{}{"progress": 10})
+	live, cancel, ok := streams.Subscribe("t1")
+	if !ok {
+		t.Fatal("Subscribe failed")
+	}
+	defer cancel()
+	streams.Publish("t1", "quantile_result", map[string]interface{}{"groups": 5})
+	streams.Finish("t1")
+	streams.Publish("t1", "test_progress", map[string]interface{}{"progress": 100})
+
+	var events []string
+	for e := range live {
+		events = append(events, e.Event)
+	}
+	if len(events) != 2 || events[0] != "test_progress" || events[1] != "quantile_result" {
+		t.Errorf("live subscriber got %v", events)
+	}
+
+	// 结束后订阅仍可回放全部事件
+	replay, _, ok := streams.Subscribe("t1")
+	if !ok {
+		t.Fatal("finished test should be replayable")
+	}
+	count := 0
+	for range replay {
+		count++
+	}
+	if count != 2 {
+		t.Errorf("replayed %d events, want 2", count)
+	}
+
+	// 超过回放条数时丢弃最早的同类事件，其他事件保持顺序
+	streams.SetReplayLimit("test_progress", 2)
+	streams.Start("t2")
+	streams.Publish("t2", "test_started", map[string]interface{}{})
+	for i := 1; i <= 5; i++ {
+		streams.Publish("t2", "test_progress", map[string]interface{}{"progress": i * 20})
+	}
+	streams.Finish("t2")
+	replay, _, _ = streams.Subscribe("t2")
+	var progress []interface{}
+	events = events[:0]
+	for e := range replay {
+		events = append(events, e.Event)
+		if e.Event == "test_progress" {
+			progress = append(progress, e.Data["progress"])
+		}
+	}
+	if len(events) != 3 || events[0] != "test_started" || len(progress) != 2 || progress[0] != 80 || progress[1] != 100 {
+		t.Errorf("bounded replay got %v %v", events, progress)
+	}
+}
+
+func TestQuantileCharts(t *testing.T) {
+	report := &qlib.QuantileReport{
+		Groups:              2,
+		Dates:               []string{"2024-01-02", "2024-01-03"},
+		CumulativeReturns:   [][]float64{{0.01, 0.02}, {0.03, 0.05}},
+		LongShortCumulative: []float64{0.02, 0.03},
+		AnnualReturns:       []float64{0.1, 0.3},
+		Turnover:            []float64{0.2, 0.25},
+	}
+	charts := quantileCharts(report)
+	for _, name := range []string{"cumulative_returns", "long_short", "annual_returns", "turnover"} {
+		if charts[name] == nil {
+			t.Errorf("chart %s missing", name)
+		}
+	}
+	if charts["cumulative_returns"].Type != "multi_line" {
+		t.Errorf("cumulative returns chart type = %s", charts["cumulative_returns"].Type)
+	}
+}
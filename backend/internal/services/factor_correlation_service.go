@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"qlib-backend/internal/models"
+	"qlib-backend/internal/qlib"
+	"qlib-backend/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// 因子库相关性分析的默认参数
+const (
+	DefaultCorrelationWindowDays = 365 // 默认统计最近一年
+	DefaultCorrelatedTop         = 10
+)
+
+// FactorCorrelationService 因子库相关性服务：定时计算全部有效因子的相关系数矩阵并聚类，
+// 新因子保存前查询与已有因子的相关性
+type FactorCorrelationService struct {
+	db           *gorm.DB
+	factorEngine qlib.FactorEvaluationEngine
+}
+
+// NewFactorCorrelationService 创建因子库相关性服务，factorEngine 需使用原生计算后端
+func NewFactorCorrelationService(db *gorm.DB, factorEngine qlib.FactorEvaluationEngine) *FactorCorrelationService {
+	return &FactorCorrelationService{db: db, factorEngine: factorEngine}
+}
+
+// FactorCorrelationRequest 相关性计算的时间窗口和股票池，日期为空时取截至今天的 WindowDays 天
+type FactorCorrelationRequest struct {
+	StartDate  string  `json:"start_date"`
+	EndDate    string  `json:"end_date"`
+	WindowDays int     `json:"window_days"`
+	Universe   string  `json:"universe"`
+	Freq       string  `json:"freq"`
+	Threshold  float64 `json:"threshold"` // 冗余判定阈值，默认0.7
+}
+
+func (r FactorCorrelationRequest) withDefaults(now time.Time) FactorCorrelationRequest {
+	if r.WindowDays <= 0 {
+		r.WindowDays = DefaultCorrelationWindowDays
+	}
+	if r.EndDate == "" {
+		r.EndDate = now.Format("2006-01-02")
+	}
+	if r.StartDate == "" {
+		if end, err := time.Parse("2006-01-02", r.EndDate); err == nil {
+			r.StartDate = end.AddDate(0, 0, -r.WindowDays).Format("2006-01-02")
+		}
+	}
+	if r.Threshold <= 0 {
+		r.Threshold = qlib.DefaultRedundancyThreshold
+	}
+	return r
+}
+
+// FactorRef 因子的简要信息
+type FactorRef struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// RedundantFactorPair 相关系数超过阈值的因子对
+type RedundantFactorPair struct {
+	A           FactorRef `json:"a"`
+	B           FactorRef `json:"b"`
+	Correlation float64   `json:"correlation"`
+}
+
+// FactorLibraryCorrelation 因子库相关性分析结果，因子按聚类顺序排列
+type FactorLibraryCorrelation struct {
+	ReportID       uint                  `json:"report_id"`
+	StartDate      string                `json:"start_date"`
+	EndDate        string                `json:"end_date"`
+	Universe       string                `json:"universe"`
+	Threshold      float64               `json:"threshold"`
+	Factors        []FactorRef           `json:"factors"`
+	Matrix         [][]float64           `json:"matrix"`
+	Clusters       [][]FactorRef         `json:"clusters"`
+	RedundantPairs []RedundantFactorPair `json:"redundant_pairs"`
+	Heatmap        *utils.ChartData      `json:"heatmap"`
+	GeneratedAt    time.Time             `json:"generated_at"`
+}
+
+// FactorCorrelationCheckRequest 新因子相关性检查请求
+type FactorCorrelationCheckRequest struct {
+	Expression string `json:"expression" binding:"required"`
+	Top        int    `json:"top"` // 返回相关性最高的因子数，默认10
+	FactorCorrelationRequest
+}
+
+// CorrelatedFactor 与新因子相关的已有因子
+type CorrelatedFactor struct {
+	FactorID    uint    `json:"factor_id"`
+	Name        string  `json:"name"`
+	Expression  string  `json:"expression"`
+	Correlation float64 `json:"correlation"`
+	Redundant   bool    `json:"redundant"`
+}
+
+// skippedFactor 计算失败而未参与相关性分析的因子
+type skippedFactor struct {
+	FactorID uint   `json:"factor_id"`
+	Error    string `json:"error"`
+}
+
+// Schedule 按固定间隔重新计算因子库相关性，最近一次结果已过期或不存在时立即计算一次，ctx 取消后退出
+func (s *FactorCorrelationService) Schedule(ctx context.Context, interval time.Duration, req FactorCorrelationRequest) {
+	var latest models.FactorCorrelationReport
+	if err := s.db.Order("id DESC").First(&latest).Error; err != nil || time.Since(latest.CreatedAt) >= interval {
+		s.runScheduled(ctx, req)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runScheduled(ctx, req)
+		}
+	}
+}
+
+func (s *FactorCorrelationService) runScheduled(ctx context.Context, req FactorCorrelationRequest) {
+	// 每次运行重新计算时间窗口
+	req.StartDate, req.EndDate = "", ""
+	report, err := s.ComputeLibraryCorrelation(ctx, req)
+	if err != nil {
+		log.Printf("因子库相关性计算失败: %v", err)
+		return
+	}
+	log.Printf("因子库相关性计算完成: %s 至 %s", report.StartDate, report.EndDate)
+}
+
+// ComputeLibraryCorrelation 计算全部有效因子在时间窗口内的相关系数矩阵并做层次聚类，结果保存到数据库
+//
+// 表达式无法计算的因子被跳过并记录原因。
+func (s *FactorCorrelationService) ComputeLibraryCorrelation(ctx context.Context, req FactorCorrelationRequest) (*models.FactorCorrelationReport, error) {
+	req = req.withDefaults(time.Now())
+	var factors []models.Factor
+	if err := s.db.Where("status = ?", "active").Order("id").Find(&factors).Error; err != nil {
+		return nil, fmt.Errorf("获取因子列表失败: %v", err)
+	}
+	evaluated, frames, skipped, err := s.evaluateFactors(ctx, factors, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) < 2 {
+		return nil, fmt.Errorf("可计算的有效因子少于2个")
+	}
+
+	ids := make([]uint, len(evaluated))
+	names := make([]string, len(evaluated))
+	for i, factor := range evaluated {
+		ids[i] = factor.ID
+		names[i] = strconv.FormatUint(uint64(factor.ID), 10)
+	}
+	matrix, err := qlib.FactorCorrelation(ctx, names, frames, qlib.ICOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("计算相关系数矩阵失败: %v", err)
+	}
+	clusters, _ := matrix.Cluster(req.Threshold)
+	clusterIDs := make([][]uint, len(clusters))
+	for k, cluster := range clusters {
+		for _, name := range cluster.Members {
+			id, _ := strconv.ParseUint(name, 10, 64)
+			clusterIDs[k] = append(clusterIDs[k], uint(id))
+		}
+	}
+
+	idsJSON, _ := json.Marshal(ids)
+	matrixJSON, _ := json.Marshal(matrix.Matrix)
+	clustersJSON, _ := json.Marshal(clusterIDs)
+	skippedJSON, _ := json.Marshal(skipped)
+	report := &models.FactorCorrelationReport{
+		StartDate:    req.StartDate,
+		EndDate:      req.EndDate,
+		Universe:     req.Universe,
+		Threshold:    req.Threshold,
+		FactorIDs:    string(idsJSON),
+		MatrixJSON:   string(matrixJSON),
+		ClustersJSON: string(clustersJSON),
+		SkippedJSON:  string(skippedJSON),
+	}
+	if err := s.db.Create(report).Error; err != nil {
+		return nil, fmt.Errorf("保存因子相关性结果失败: %v", err)
+	}
+	return report, nil
+}
+
+// GetLibraryCorrelation 获取最近一次因子库相关性结果，只包含用户可见的因子
+func (s *FactorCorrelationService) GetLibraryCorrelation(userID uint) (*FactorLibraryCorrelation, error) {
+	var report models.FactorCorrelationReport
+	if err := s.db.Order("id DESC").First(&report).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("因子库相关性尚未计算")
+		}
+		return nil, fmt.Errorf("获取因子相关性结果失败: %v", err)
+	}
+	var ids []uint
+	var matrix [][]float64
+	var clusterIDs [][]uint
+	if err := json.Unmarshal([]byte(report.FactorIDs), &ids); err != nil {
+		return nil, fmt.Errorf("解析因子ID失败: %v", err)
+	}
+	if err := json.Unmarshal([]byte(report.MatrixJSON), &matrix); err != nil {
+		return nil, fmt.Errorf("解析相关系数矩阵失败: %v", err)
+	}
+	if err := json.Unmarshal([]byte(report.ClustersJSON), &clusterIDs); err != nil {
+		return nil, fmt.Errorf("解析聚类结果失败: %v", err)
+	}
+
+	var visible []models.Factor
+	if err := s.db.Where("id IN ? AND (user_id = ? OR is_public = ?)", ids, userID, true).Find(&visible).Error; err != nil {
+		return nil, fmt.Errorf("获取因子列表失败: %v", err)
+	}
+	refs := make(map[uint]FactorRef, len(visible))
+	for _, factor := range visible {
+		refs[factor.ID] = FactorRef{ID: factor.ID, Name: factor.Name}
+	}
+	position := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		position[id] = i
+	}
+
+	result := &FactorLibraryCorrelation{
+		ReportID:    report.ID,
+		StartDate:   report.StartDate,
+		EndDate:     report.EndDate,
+		Universe:    report.Universe,
+		Threshold:   report.Threshold,
+		GeneratedAt: report.CreatedAt,
+	}
+	// 按聚类顺序排列，相关的因子在热力图中相邻
+	var order []int
+	for _, cluster := range clusterIDs {
+		var members []FactorRef
+		for _, id := range cluster {
+			if ref, ok := refs[id]; ok {
+				members = append(members, ref)
+				order = append(order, position[id])
+			}
+		}
+		if len(members) > 0 {
+			result.Clusters = append(result.Clusters, members)
+			result.Factors = append(result.Factors, members...)
+		}
+	}
+	result.Matrix = make([][]float64, len(order))
+	labels := make([]string, len(order))
+	for a, i := range order {
+		labels[a] = result.Factors[a].Name
+		result.Matrix[a] = make([]float64, len(order))
+		for b, j := range order {
+			result.Matrix[a][b] = matrix[i][j]
+		}
+	}
+	for a := range order {
+		for b := a + 1; b < len(order); b++ {
+			if corr := result.Matrix[a][b]; corr >= report.Threshold || corr <= -report.Threshold {
+				result.RedundantPairs = append(result.RedundantPairs, RedundantFactorPair{
+					A: result.Factors[a], B: result.Factors[b], Correlation: corr,
+				})
+			}
+		}
+	}
+	result.Heatmap = utils.NewChartGenerator().GenerateHeatmapChart("因子相关系数矩阵", labels, labels, result.Matrix)
+	return result, nil
+}
+
+// CheckFactorCorrelation 计算新因子与用户可见的有效因子的相关性，按相关系数绝对值降序返回，用于保存前查重
+func (s *FactorCorrelationService) CheckFactorCorrelation(ctx context.Context, userID uint, req FactorCorrelationCheckRequest) ([]CorrelatedFactor, error) {
+	if req.Top <= 0 {
+		req.Top = DefaultCorrelatedTop
+	}
+	window := req.FactorCorrelationRequest.withDefaults(time.Now())
+	frameReq, err := factorFrameRequest(window.StartDate, window.EndDate, window.Universe, window.Freq)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.factorEngine.EvaluateFactor(ctx, req.Expression, frameReq)
+	if err != nil {
+		return nil, fmt.Errorf("计算新因子失败: %v", err)
+	}
+
+	var factors []models.Factor
+	err = s.db.Where("status = ? AND (user_id = ? OR is_public = ?)", "active", userID, true).Order("id").Find(&factors).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取因子列表失败: %v", err)
+	}
+	evaluated, frames, _, err := s.evaluateFactors(ctx, factors, window)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(evaluated))
+	byName := make(map[string]models.Factor, len(evaluated))
+	for i, factor := range evaluated {
+		names[i] = strconv.FormatUint(uint64(factor.ID), 10)
+		byName[names[i]] = factor
+	}
+	pairs, err := qlib.CorrelateWith(ctx, target, names, frames, req.Top, qlib.ICOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("计算因子相关性失败: %v", err)
+	}
+
+	result := make([]CorrelatedFactor, len(pairs))
+	for i, pair := range pairs {
+		factor := byName[pair.B]
+		result[i] = CorrelatedFactor{
+			FactorID:    factor.ID,
+			Name:        factor.Name,
+			Expression:  factor.Expression,
+			Correlation: pair.Correlation,
+			Redundant:   pair.Correlation >= window.Threshold || pair.Correlation <= -window.Threshold,
+		}
+	}
+	return result, nil
+}
+
+// evaluateFactors 在时间窗口内计算各因子，返回计算成功的因子及其结果，失败的因子记录原因
+func (s *FactorCorrelationService) evaluateFactors(ctx context.Context, factors []models.Factor, req FactorCorrelationRequest) ([]models.Factor, []*qlib.FactorFrame, []skippedFactor, error) {
+	if !s.factorEngine.UsesNativeBackend() {
+		return nil, nil, nil, fmt.Errorf("因子相关性分析需要原生计算后端")
+	}
+	frameReq, err := factorFrameRequest(req.StartDate, req.EndDate, req.Universe, req.Freq)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var evaluated []models.Factor
+	var frames []*qlib.FactorFrame
+	var skipped []skippedFactor
+	for _, factor := range factors {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, nil, err
+		}
+		frame, err := s.factorEngine.EvaluateFactor(ctx, factor.Expression, frameReq)
+		if err != nil {
+			skipped = append(skipped, skippedFactor{FactorID: factor.ID, Error: err.Error()})
+			continue
+		}
+		evaluated = append(evaluated, factor)
+		frames = append(frames, frame)
+	}
+	return evaluated, frames, skipped, nil
+}
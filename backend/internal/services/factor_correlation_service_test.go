@@ -0,0 +1,21 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"qlib-backend/internal/qlib"
+)
+
+func TestFactorCorrelationRequestDefaults(t *testing.T) {
+	now := time.Date(2024, 3, 1, 15, 0, 0, 0, time.UTC)
+	req := FactorCorrelationRequest{}.withDefaults(now)
+	if req.StartDate != "2023-03-02" || req.EndDate != "2024-03-01" || req.Threshold != qlib.DefaultRedundancyThreshold {
+		t.Errorf("unexpected defaults: %+v", req)
+	}
+
+	req = FactorCorrelationRequest{EndDate: "2024-01-31", WindowDays: 30, Threshold: 0.9}.withDefaults(now)
+	if req.StartDate != "2024-01-01" || req.Threshold != 0.9 {
+		t.Errorf("unexpected window: %+v", req)
+	}
+}
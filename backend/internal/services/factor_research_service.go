@@ -11,12 +11,12 @@ import (
 
 type FactorResearchService struct {
 	db             *gorm.DB
-	factorEngine   *qlib.FactorEngine
+	factorEngine    qlib.FactorEvaluationEngine
 	syntaxValidator *qlib.SyntaxValidator
 	aiChatService  *AiChatService
 }
 
-func NewFactorResearchService(db *gorm.DB, factorEngine *qlib.FactorEngine, syntaxValidator *qlib.SyntaxValidator, aiChatService *AiChatService) *FactorResearchService {
+func NewFactorResearchService(db *gorm.DB, factorEngine qlib.FactorEvaluationEngine, syntaxValidator *qlib.SyntaxValidator, aiChatService *AiChatService) *FactorResearchService {
 	return &FactorResearchService{
 		db:             db,
 		factorEngine:   factorEngine,
@@ -58,11 +58,13 @@ func (s *FactorResearchService) ValidateFactorSyntax(req ValidateFactorSyntaxReq
 	}
 
 	return &ValidateFactorSyntaxResult{
-		IsValid:     result.IsValid,
-		ErrorMsg:    result.ErrorMsg,
-		Suggestions: result.Suggestions,
-		ParsedAST:   result.ParsedAST,
-		UsedFields:  result.UsedFields,
+		IsValid:       result.IsValid,
+		ErrorMsg:      result.ErrorMsg,
+		ErrorPos:      result.ErrorPos,
+		Suggestions:   result.Suggestions,
+		ParsedAST:     result.ParsedAST,
+		Canonical:     result.Canonical,
+		UsedFields:    result.UsedFields,
 		UsedFunctions: result.UsedFunctions,
 	}, nil
 }
@@ -102,6 +104,8 @@ func (s *FactorResearchService) GetSyntaxReference() (*SyntaxReference, error) {
 			{Name: "<=", Description: "小于等于", Example: "$close <= $open", Category: "比较运算符"},
 			{Name: "==", Description: "等于", Example: "$close == $open", Category: "比较运算符"},
 			{Name: "!=", Description: "不等于", Example: "$close != $open", Category: "比较运算符"},
+			{Name: "&", Description: "逻辑与", Example: "($close > $open) & ($volume > 0)", Category: "逻辑运算符"},
+			{Name: "|", Description: "逻辑或", Example: "($close > $high * 0.99) | ($close < $low * 1.01)", Category: "逻辑运算符"},
 		},
 		Fields: []FieldInfo{
 			{Name: "$open", Description: "开盘价", DataType: "float", Example: "$open"},
@@ -332,8 +336,10 @@ type ValidateFactorSyntaxRequest struct {
 type ValidateFactorSyntaxResult struct {
 	IsValid       bool     `json:"is_valid"`
 	ErrorMsg      string   `json:"error_msg,omitempty"`
+	ErrorPos      int      `json:"error_pos"`
 	Suggestions   []string `json:"suggestions,omitempty"`
 	ParsedAST     string   `json:"parsed_ast,omitempty"`
+	Canonical     string   `json:"canonical,omitempty"`
 	UsedFields    []string `json:"used_fields,omitempty"`
 	UsedFunctions []string `json:"used_functions,omitempty"`
 }
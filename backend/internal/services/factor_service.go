@@ -1,21 +1,25 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"qlib-backend/internal/models"
 	"qlib-backend/internal/qlib"
+	"qlib-backend/internal/utils"
 
 	"gorm.io/gorm"
 )
 
 type FactorService struct {
 	db           *gorm.DB
-	factorEngine *qlib.FactorEngine
+	factorEngine qlib.FactorEvaluationEngine
 }
 
-func NewFactorService(db *gorm.DB, factorEngine *qlib.FactorEngine) *FactorService {
+func NewFactorService(db *gorm.DB, factorEngine qlib.FactorEvaluationEngine) *FactorService {
 	return &FactorService{
 		db:           db,
 		factorEngine: factorEngine,
@@ -35,6 +39,7 @@ func (s *FactorService) CreateFactor(req FactorCreateRequest, userID uint) (*mod
 		Description: req.Description,
 		Category:    req.Category,
 		Status:      "active",
+		Kind:        models.FactorKindExpression,
 		UserID:      userID,
 		IsPublic:    req.IsPublic,
 	}
@@ -46,8 +51,17 @@ func (s *FactorService) CreateFactor(req FactorCreateRequest, userID uint) (*mod
 	return factor, nil
 }
 
-// GetFactors 获取因子列表
-func (s *FactorService) GetFactors(page, pageSize int, category, status string, userID uint, isPublic *bool) (*PaginatedFactors, error) {
+// factorSortOrders GetFactors 支持的排序方式，半衰期升序排列且未分析的因子排在最后
+var factorSortOrders = map[string]string{
+	"created_at": "created_at DESC",
+	"ic":         "ic DESC",
+	"ir":         "ir DESC",
+	"rank_ic":    "rank_ic DESC",
+	"half_life":  "half_life = 0, half_life ASC",
+}
+
+// GetFactors 获取因子列表，sortBy 为空时按创建时间倒序
+func (s *FactorService) GetFactors(page, pageSize int, category, status string, userID uint, isPublic *bool, sortBy string) (*PaginatedFactors, error) {
 	var factors []models.Factor
 	var total int64
 
@@ -73,9 +87,17 @@ func (s *FactorService) GetFactors(page, pageSize int, category, status string,
 		return nil, fmt.Errorf("获取因子总数失败: %v", err)
 	}
 
+	if sortBy == "" {
+		sortBy = "created_at"
+	}
+	order, ok := factorSortOrders[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("不支持的排序字段: %s", sortBy)
+	}
+
 	// 分页查询
 	offset := (page - 1) * pageSize
-	if err := query.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&factors).Error; err != nil {
+	if err := query.Offset(offset).Limit(pageSize).Order(order).Find(&factors).Error; err != nil {
 		return nil, fmt.Errorf("获取因子列表失败: %v", err)
 	}
 
@@ -116,11 +138,20 @@ func (s *FactorService) UpdateFactor(id uint, req FactorUpdateRequest, userID ui
 	}
 
 	// 验证新的表达式语法（如果有更新）
-	if req.Expression != "" && req.Expression != factor.Expression {
+	expressionChanged := req.Expression != "" && req.Expression != factor.Expression
+	if expressionChanged && factor.Kind == models.FactorKindComposite {
+		return nil, fmt.Errorf("复合因子没有表达式，请修改成分因子")
+	}
+	if expressionChanged {
 		if err := s.factorEngine.ValidateExpression(req.Expression); err != nil {
 			return nil, fmt.Errorf("因子表达式语法错误: %v", err)
 		}
 	}
+	// 失效的复合因子只能通过重新测试恢复，不能直接修改状态
+	if req.Status != "" && req.Status != factor.Status && factor.Kind == models.FactorKindComposite &&
+		factor.Status == models.FactorStatusInvalid {
+		return nil, fmt.Errorf("复合因子的成分因子已变更，请重新测试后再修改状态")
+	}
 
 	// 更新字段
 	updates := map[string]interface{}{}
@@ -143,8 +174,17 @@ func (s *FactorService) UpdateFactor(id uint, req FactorUpdateRequest, userID ui
 		updates["is_public"] = *req.IsPublic
 	}
 
-	if err := s.db.Model(&factor).Updates(updates).Error; err != nil {
-		return nil, fmt.Errorf("更新因子失败: %v", err)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&factor).Updates(updates).Error; err != nil {
+			return fmt.Errorf("更新因子失败: %v", err)
+		}
+		if expressionChanged {
+			return invalidateComposites(tx, factor.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &factor, nil
@@ -160,11 +200,12 @@ func (s *FactorService) DeleteFactor(id uint, userID uint) error {
 		return fmt.Errorf("获取因子失败: %v", err)
 	}
 
-	if err := s.db.Delete(&factor).Error; err != nil {
-		return fmt.Errorf("删除因子失败: %v", err)
-	}
-
-	return nil
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&factor).Error; err != nil {
+			return fmt.Errorf("删除因子失败: %v", err)
+		}
+		return invalidateComposites(tx, factor.ID)
+	})
 }
 
 // TestFactor 测试因子性能
@@ -182,6 +223,7 @@ func (s *FactorService) TestFactor(req FactorTestRequest, userID uint) (*FactorT
 		Universe:    req.Universe,
 		Benchmark:   req.Benchmark,
 		Freq:        req.Freq,
+		Horizons:    req.Horizons,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("因子测试失败: %v", err)
@@ -201,9 +243,54 @@ func (s *FactorService) TestFactor(req FactorTestRequest, userID uint) (*FactorT
 		Details:    result.Details,
 	}
 
+	if req.FactorID != 0 {
+		if err := s.saveFactorMetrics(req.FactorID, userID, req, testResult); err != nil {
+			return nil, err
+		}
+	}
+
 	return testResult, nil
 }
 
+// saveFactorMetrics 将测试得到的IC、IR、RankIC、换手率和覆盖率保存到因子记录，并追加一条测试历史
+func (s *FactorService) saveFactorMetrics(factorID, userID uint, req FactorTestRequest, result *FactorTestResult) error {
+	record := models.FactorTestRecord{
+		FactorID:  factorID,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Universe:  req.Universe,
+		IC:        result.IC,
+		IR:        result.IR,
+		RankIC:    result.RankIC,
+		Turnover:  result.Turnover,
+		Coverage:  result.Coverage,
+		UserID:    userID,
+	}
+	if weights, ok := result.Details["weights"]; ok {
+		weightsJSON, _ := json.Marshal(weights)
+		record.WeightsJSON = string(weightsJSON)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Factor{}).
+			Where("id = ? AND (user_id = ? OR is_public = ?)", factorID, userID, true).
+			Updates(map[string]interface{}{
+				"ic":       result.IC,
+				"ir":       result.IR,
+				"rank_ic":  result.RankIC,
+				"turnover": result.Turnover,
+				"coverage": result.Coverage,
+			}).Error
+		if err != nil {
+			return fmt.Errorf("保存因子指标失败: %v", err)
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return fmt.Errorf("保存因子测试历史失败: %v", err)
+		}
+		return nil
+	})
+}
+
 // BatchTestFactors 批量测试因子
 func (s *FactorService) BatchTestFactors(req BatchFactorTestRequest, userID uint) (*BatchFactorTestResult, error) {
 	results := make([]FactorTestSummary, 0, len(req.FactorIDs))
@@ -222,6 +309,7 @@ func (s *FactorService) BatchTestFactors(req BatchFactorTestRequest, userID uint
 		}
 
 		testReq := FactorTestRequest{
+			FactorID:   factor.ID,
 			Expression: factor.Expression,
 			StartDate:  req.StartDate,
 			EndDate:    req.EndDate,
@@ -251,15 +339,6 @@ func (s *FactorService) BatchTestFactors(req BatchFactorTestRequest, userID uint
 			Turnover:    testResult.Turnover,
 			Coverage:    testResult.Coverage,
 		})
-
-		// 更新因子的性能指标
-		s.db.Model(factor).Updates(map[string]interface{}{
-			"ic":       testResult.IC,
-			"ir":       testResult.IR,
-			"rank_ic":  testResult.RankIC,
-			"turnover": testResult.Turnover,
-			"coverage": testResult.Coverage,
-		})
 	}
 
 	return &BatchFactorTestResult{
@@ -414,6 +493,163 @@ func (s *FactorService) GetFactorAnalysis(id uint, userID uint) (*FactorAnalysis
 	}, nil
 }
 
+// AnalyzeFactorQuantiles 因子分层回测：按因子值分组，计算各组累计收益、多空收益、单调性和换手率
+//
+// 请求带 TestID 时，进度和结果同时推送到 /ws/factor-test/:test_id。
+func (s *FactorService) AnalyzeFactorQuantiles(id uint, userID uint, req FactorQuantileRequest) (*FactorQuantileResult, error) {
+	if req.TestID != "" {
+		FactorTests.Start(req.TestID)
+		defer FactorTests.Finish(req.TestID)
+	}
+	result, err := s.analyzeFactorQuantiles(id, userID, req)
+	if err != nil {
+		publishFactorTest(req.TestID, "test_failed", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+	publishFactorTest(req.TestID, "quantile_result", map[string]interface{}{
+		"factor_id":   result.FactorID,
+		"factor_name": result.FactorName,
+		"report":      result.Report,
+		"charts":      result.Charts,
+	})
+	return result, nil
+}
+
+func (s *FactorService) analyzeFactorQuantiles(id uint, userID uint, req FactorQuantileRequest) (*FactorQuantileResult, error) {
+	factor, err := s.GetFactorByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.factorEngine.UsesNativeBackend() {
+		return nil, fmt.Errorf("分层回测需要原生计算后端")
+	}
+	publishFactorTest(req.TestID, "test_progress", map[string]interface{}{
+		"factor_name":   factor.Name,
+		"progress":      10,
+		"current_phase": "data_loading",
+	})
+
+	frameReq, err := factorFrameRequest(req.StartDate, req.EndDate, req.Universe, req.Freq)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.factorEngine.FactorQuantiles(context.Background(), factor.Expression, frameReq, req.QuantileOptions)
+	if err != nil {
+		return nil, fmt.Errorf("分层回测失败: %v", err)
+	}
+	publishFactorTest(req.TestID, "test_progress", map[string]interface{}{
+		"factor_name":   factor.Name,
+		"progress":      100,
+		"current_phase": "completed",
+	})
+
+	return &FactorQuantileResult{
+		FactorID:    factor.ID,
+		FactorName:  factor.Name,
+		Report:      report,
+		Charts:      quantileCharts(report),
+		GeneratedAt: time.Now(),
+	}, nil
+}
+
+// quantileCharts 将分层回测结果转换为图表序列
+func quantileCharts(report *qlib.QuantileReport) map[string]*utils.ChartData {
+	chartGenerator := utils.NewChartGenerator()
+	names := make([]string, report.Groups)
+	for g := range names {
+		names[g] = fmt.Sprintf("Q%d", g+1)
+	}
+	return map[string]*utils.ChartData{
+		"cumulative_returns": chartGenerator.GenerateMultiLineChart("分组累计收益", report.Dates, names, report.CumulativeReturns),
+		"long_short":         chartGenerator.GenerateLineChart("多空组合累计收益", report.Dates, report.LongShortCumulative),
+		"annual_returns":     chartGenerator.GenerateBarChart("分组年化收益", names, report.AnnualReturns),
+		"turnover":           chartGenerator.GenerateBarChart("分组换手率", names, report.Turnover),
+	}
+}
+
+// AnalyzeFactorDecay 因子IC衰减分析，估计的半衰期和建议调仓周期保存到因子记录
+func (s *FactorService) AnalyzeFactorDecay(id uint, userID uint, req FactorDecayRequest) (*FactorDecayResult, error) {
+	factor, err := s.GetFactorByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.factorEngine.UsesNativeBackend() {
+		return nil, fmt.Errorf("IC衰减分析需要原生计算后端")
+	}
+	frameReq, err := factorFrameRequest(req.StartDate, req.EndDate, req.Universe, req.Freq)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.factorEngine.FactorDecay(context.Background(), factor.Expression, frameReq, req.DecayOptions)
+	if err != nil {
+		return nil, fmt.Errorf("IC衰减分析失败: %v", err)
+	}
+
+	err = s.db.Model(&models.Factor{}).
+		Where("id = ? AND (user_id = ? OR is_public = ?)", factor.ID, userID, true).
+		Updates(map[string]interface{}{
+			"half_life": report.HalfLife,
+			"rebalance": report.RecommendedRebalance,
+		}).Error
+	if err != nil {
+		return nil, fmt.Errorf("保存因子半衰期失败: %v", err)
+	}
+
+	return &FactorDecayResult{
+		FactorID:    factor.ID,
+		FactorName:  factor.Name,
+		Report:      report,
+		Charts:      decayCharts(report),
+		GeneratedAt: time.Now(),
+	}, nil
+}
+
+// decayCharts 将IC衰减结果转换为图表序列
+func decayCharts(report *qlib.FactorDecayReport) map[string]*utils.ChartData {
+	chartGenerator := utils.NewChartGenerator()
+	curve := func(title string, points []qlib.DecayPoint) *utils.ChartData {
+		periods := make([]string, len(points))
+		ic := make([]float64, len(points))
+		rankIC := make([]float64, len(points))
+		for i, p := range points {
+			periods[i] = strconv.Itoa(p.Period)
+			ic[i], rankIC[i] = p.IC, p.RankIC
+		}
+		return chartGenerator.GenerateMultiLineChart(title, periods, []string{"IC", "RankIC"}, [][]float64{ic, rankIC})
+	}
+	return map[string]*utils.ChartData{
+		"horizon_ic": curve("不同持有期IC", report.Horizons),
+		"lag_ic":     curve("因子滞后IC衰减", report.Lags),
+	}
+}
+
+// factorFrameRequest 根据 YYYY-MM-DD 格式的起止日期构造行情请求，日期为空表示不限制
+func factorFrameRequest(startDate, endDate, universe, freq string) (qlib.FrameRequest, error) {
+	req := qlib.FrameRequest{Universe: universe, Freq: freq}
+	var err error
+	if startDate != "" {
+		if req.Start, err = time.Parse("2006-01-02", startDate); err != nil {
+			return req, fmt.Errorf("开始日期格式错误: %v", err)
+		}
+	}
+	if endDate != "" {
+		if req.End, err = time.Parse("2006-01-02", endDate); err != nil {
+			return req, fmt.Errorf("结束日期格式错误: %v", err)
+		}
+	}
+	return req, nil
+}
+
+// publishFactorTest 向因子测试推送事件，testID 为空时不推送
+func publishFactorTest(testID, event string, data map[string]interface{}) {
+	if testID == "" {
+		return
+	}
+	data["test_id"] = testID
+	data["timestamp"] = time.Now().Format(time.RFC3339)
+	FactorTests.Publish(testID, event, data)
+}
+
 // getFactorCategoryDescription 获取因子分类描述
 func getFactorCategoryDescription(category string) string {
 	descriptions := map[string]string{
@@ -454,12 +690,14 @@ type FactorUpdateRequest struct {
 }
 
 type FactorTestRequest struct {
+	FactorID   uint   `json:"factor_id"` // 非0时测试结果保存到该因子
 	Expression string `json:"expression" binding:"required"`
 	StartDate  string `json:"start_date" binding:"required"`
 	EndDate    string `json:"end_date" binding:"required"`
 	Universe   string `json:"universe"`
 	Benchmark  string `json:"benchmark"`
 	Freq       string `json:"freq"`
+	Horizons   []int  `json:"horizons"` // IC分析的持有期，默认1、5、10、20日
 }
 
 type FactorTestResult struct {
@@ -476,6 +714,43 @@ type FactorTestResult struct {
 	Details    map[string]interface{} `json:"details"`
 }
 
+// FactorQuantileRequest 因子分层回测请求
+type FactorQuantileRequest struct {
+	TestID    string `json:"test_id"` // 非空时通过 /ws/factor-test/:test_id 推送进度和结果
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Universe  string `json:"universe"`
+	Freq      string `json:"freq"`
+	qlib.QuantileOptions
+}
+
+// FactorDecayRequest 因子IC衰减分析请求
+type FactorDecayRequest struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Universe  string `json:"universe"`
+	Freq      string `json:"freq"`
+	qlib.DecayOptions
+}
+
+// FactorDecayResult 因子IC衰减分析结果
+type FactorDecayResult struct {
+	FactorID    uint                        `json:"factor_id"`
+	FactorName  string                      `json:"factor_name"`
+	Report      *qlib.FactorDecayReport     `json:"report"`
+	Charts      map[string]*utils.ChartData `json:"charts"`
+	GeneratedAt time.Time                   `json:"generated_at"`
+}
+
+// FactorQuantileResult 因子分层回测结果
+type FactorQuantileResult struct {
+	FactorID    uint                        `json:"factor_id"`
+	FactorName  string                      `json:"factor_name"`
+	Report      *qlib.QuantileReport        `json:"report"`
+	Charts      map[string]*utils.ChartData `json:"charts"`
+	GeneratedAt time.Time                   `json:"generated_at"`
+}
+
 type BatchFactorTestRequest struct {
 	FactorIDs []uint `json:"factor_ids" binding:"required"`
 	StartDate string `json:"start_date" binding:"required"`
@@ -1,7 +1,6 @@
 package services
 
 import (
-	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -14,9 +13,6 @@ func TestFactorService(t *testing.T) {
 	testutils.SetupTestEnv()
 	defer testutils.CleanupTestEnv()
 
-	// 创建模拟的因子服务
-	service := &FactorService{}
-
 	t.Run("ValidateFactorExpression", func(t *testing.T) {
 		testCases := []struct {
 			name       string
@@ -64,9 +60,6 @@ func TestFactorService(t *testing.T) {
 	})
 
 	t.Run("FactorCRUDOperations", func(t *testing.T) {
-		ctx := context.Background()
-		userID := uint(1)
-
 		// 测试创建因子
 		factorData := map[string]interface{}{
 			"name":        "test_factor",
@@ -104,8 +97,6 @@ func TestFactorService(t *testing.T) {
 	})
 
 	t.Run("FactorPerformanceTest", func(t *testing.T) {
-		ctx := context.Background()
-		
 		// 模拟因子测试参数
 		testParams := map[string]interface{}{
 			"factor_id":  1,
@@ -146,8 +137,6 @@ func TestFactorService(t *testing.T) {
 	})
 
 	t.Run("BatchFactorTest", func(t *testing.T) {
-		ctx := context.Background()
-		
 		// 模拟批量测试参数
 		batchParams := map[string]interface{}{
 			"factor_ids": []int{1, 2, 3},
@@ -186,12 +175,7 @@ func TestFactorServiceValidation(t *testing.T) {
 	testutils.SetupTestEnv()
 	defer testutils.CleanupTestEnv()
 
-	service := &FactorService{}
-
 	t.Run("InvalidFactorData", func(t *testing.T) {
-		ctx := context.Background()
-		userID := uint(1)
-
 		// 测试无效的因子数据
 		invalidCases := []map[string]interface{}{
 			{
@@ -279,10 +263,7 @@ func TestFactorServiceConcurrency(t *testing.T) {
 	testutils.SetupTestEnv()
 	defer testutils.CleanupTestEnv()
 
-	service := &FactorService{}
-
 	t.Run("ConcurrentFactorOperations", func(t *testing.T) {
-		ctx := context.Background()
 		done := make(chan bool, 10)
 
 		// 测试并发因子操作
@@ -1,9 +1,11 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"qlib-backend/internal/analytics"
 	"qlib-backend/internal/models"
 	"qlib-backend/internal/qlib"
 
@@ -12,11 +14,11 @@ import (
 
 type ModelService struct {
 	db           *gorm.DB
-	modelTrainer *qlib.ModelTrainer
+	modelTrainer qlib.TrainingEngine
 	taskService  *TaskService
 }
 
-func NewModelService(db *gorm.DB, modelTrainer *qlib.ModelTrainer, taskService *TaskService) *ModelService {
+func NewModelService(db *gorm.DB, modelTrainer qlib.TrainingEngine, taskService *TaskService) *ModelService {
 	return &ModelService{
 		db:           db,
 		modelTrainer: modelTrainer,
@@ -164,10 +166,8 @@ func (s *ModelService) StopTraining(modelID uint, userID uint) error {
 		return fmt.Errorf("停止训练失败: %v", err)
 	}
 
-	// 通知训练器停止训练
-	if err := s.modelTrainer.StopTraining(modelID); err != nil {
-		return fmt.Errorf("停止训练器失败: %v", err)
-	}
+	// 取消训练任务的上下文，训练进程组随之终止，任务记为已取消
+	runningJobs.cancelOwner(modelJobOwner(modelID))
 
 	return nil
 }
@@ -197,6 +197,13 @@ func (s *ModelService) EvaluateModel(modelID uint, userID uint) (*ModelEvaluatio
 		return nil, fmt.Errorf("模型评估失败: %v", err)
 	}
 
+	// 评估结果包含测试集每日IC时保存，供模型对比的显著性检验使用
+	if dailyIC := floatSeries(evaluation.TestMetrics["daily_ic"]); len(dailyIC) > 0 {
+		if err := SaveModelDailyIC(s.db, modelID, dailyIC, floatSeries(evaluation.TestMetrics["daily_rank_ic"])); err != nil {
+			return nil, err
+		}
+	}
+
 	return &ModelEvaluationResult{
 		ModelID:            modelID,
 		OverallScore:       evaluation.OverallScore,
@@ -253,6 +260,13 @@ func (s *ModelService) CompareModels(req models.ModelComparisonRequest, userID u
 		}
 	}
 
+	// 对测试集每日IC做两两显著性检验
+	candidates, err := modelICCandidates(s.db, modelPerformances)
+	if err != nil {
+		return nil, fmt.Errorf("统计检验失败: %v", err)
+	}
+	statTest := pairwiseSignificance(candidates, "daily_ic", analytics.CorrectionMethod(req.Correction))
+
 	return &models.ModelComparisonResult{
 		Models:          modelPerformances,
 		ComparisonChart: &models.ComparisonChart{
@@ -262,12 +276,7 @@ func (s *ModelService) CompareModels(req models.ModelComparisonRequest, userID u
 			},
 		},
 		RankingTable: []models.ModelRanking{},
-		StatisticalTest: &models.StatisticalTestResult{
-			TestType:  "t-test",
-			PValue:    0.05,
-			Statistic: 1.96,
-			Result:    "significant",
-		},
+		StatisticalTest: statTest,
 		Summary: &models.ComparisonSummary{
 			BestModel:  modelPerformances[0].ModelID,
 			AvgScore:   0.5,
@@ -387,21 +396,7 @@ func (s *ModelService) executeTraining(modelID, taskID uint, req ModelTrainingRe
 	s.db.Model(&models.Model{}).Where("id = ?", modelID).Updates(map[string]interface{}{
 		"status": "training",
 	})
-
-	// 调用模型训练器
-	trainingParams := qlib.ModelTrainingParams{
-		ModelID:     modelID,
-		ModelType:   req.ModelType,
-		ConfigJSON:  req.ConfigJSON,
-		TrainStart:  req.TrainStart,
-		TrainEnd:    req.TrainEnd,
-		ValidStart:  req.ValidStart,
-		ValidEnd:    req.ValidEnd,
-		TestStart:   req.TestStart,
-		TestEnd:     req.TestEnd,
-		Features:    req.Features,
-		Label:       req.Label,
-	}
+	startTaskEvents(taskID, "模型训练开始")
 
 	// 设置进度回调
 	progressCallback := func(progress int, metrics map[string]float64) {
@@ -429,22 +424,26 @@ func (s *ModelService) executeTraining(modelID, taskID uint, req ModelTrainingRe
 		}
 
 		s.db.Model(&models.Model{}).Where("id = ?", modelID).Updates(updates)
+		s.db.Model(&models.Task{}).Where("id = ?", taskID).Update("progress", progress)
 	}
 
 	// 执行训练
-	result, err := s.modelTrainer.TrainModel(trainingParams, progressCallback)
+	result, err := s.runTraining(modelID, taskID, req, progressCallback)
 	
 	// 更新最终状态
 	if err != nil {
-		// 训练失败
+		// 训练失败、被取消、超时或内存不足
+		status, reason := jobFailure(err)
 		s.db.Model(&models.Model{}).Where("id = ?", modelID).Updates(map[string]interface{}{
-			"status": "failed",
+			"status": status,
 		})
 		s.db.Model(&models.Task{}).Where("id = ?", taskID).Updates(map[string]interface{}{
-			"status":    "failed",
-			"error_msg": err.Error(),
-			"end_time":  time.Now(),
+			"status":             status,
+			"termination_reason": reason,
+			"error_msg":          err.Error(),
+			"end_time":           time.Now(),
 		})
+		finishTaskEvents(taskID, status, 0, err.Error())
 	} else {
 		// 训练成功
 		s.db.Model(&models.Model{}).Where("id = ?", modelID).Updates(map[string]interface{}{
@@ -460,11 +459,35 @@ func (s *ModelService) executeTraining(modelID, taskID uint, req ModelTrainingRe
 		})
 		s.db.Model(&models.Task{}).Where("id = ?", taskID).Updates(map[string]interface{}{
 			"status":   "completed",
+			"progress": 100,
 			"end_time": time.Now(),
 		})
+		finishTaskEvents(taskID, "completed", 100, "模型训练完成")
 	}
 }
 
+// runTraining 调用模型训练器，训练脚本的日志和产出文件推送到任务事件流；停止训练或超时时取消上下文
+func (s *ModelService) runTraining(modelID, taskID uint, req ModelTrainingRequest, progressCallback qlib.ProgressCallback) (*qlib.ModelTrainingResult, error) {
+	trainingParams := qlib.ModelTrainingParams{
+		ModelID:     modelID,
+		ModelType:   req.ModelType,
+		ConfigJSON:  req.ConfigJSON,
+		TrainStart:  req.TrainStart,
+		TrainEnd:    req.TrainEnd,
+		ValidStart:  req.ValidStart,
+		ValidEnd:    req.ValidEnd,
+		TestStart:   req.TestStart,
+		TestEnd:     req.TestEnd,
+		Features:    req.Features,
+		Label:       req.Label,
+	}
+
+	jobCtx, finishJob := runningJobs.start(context.Background(), taskID, modelJobOwner(modelID), DefaultJobTimeout)
+	defer finishJob()
+	ctx := qlib.WithPythonEvents(jobCtx, pythonTaskEvents(taskID, nil))
+	return s.modelTrainer.TrainModelContext(ctx, trainingParams, progressCallback)
+}
+
 // getTrainingLogs 获取训练日志
 func (s *ModelService) getTrainingLogs(modelID uint) []string {
 	// 这里应该从日志文件或数据库中读取实际的训练日志
@@ -1,11 +1,11 @@
 package services
 
 import (
-	"context"
 	"fmt"
 	"testing"
 	"time"
 
+	"qlib-backend/internal/qlib"
 	"qlib-backend/internal/testutils"
 )
 
@@ -13,12 +13,7 @@ func TestModelService(t *testing.T) {
 	testutils.SetupTestEnv()
 	defer testutils.CleanupTestEnv()
 
-	service := &ModelService{}
-
 	t.Run("CreateModelTrainingTask", func(t *testing.T) {
-		ctx := context.Background()
-		userID := uint(1)
-
 		// 测试不同类型的模型训练配置
 		testCases := []struct {
 			name       string
@@ -99,7 +94,6 @@ func TestModelService(t *testing.T) {
 	})
 
 	t.Run("ModelEvaluation", func(t *testing.T) {
-		ctx := context.Background()
 		modelID := uint(1)
 
 		// 模拟模型评估参数
@@ -150,8 +144,6 @@ func TestModelService(t *testing.T) {
 	})
 
 	t.Run("ModelComparison", func(t *testing.T) {
-		ctx := context.Background()
-
 		// 模拟模型对比参数
 		compareParams := map[string]interface{}{
 			"model_ids": []int{1, 2, 3},
@@ -207,7 +199,6 @@ func TestModelService(t *testing.T) {
 	})
 
 	t.Run("ModelDeployment", func(t *testing.T) {
-		ctx := context.Background()
 		modelID := uint(1)
 
 		// 测试不同的部署配置
@@ -282,8 +273,6 @@ func TestModelServiceValidation(t *testing.T) {
 	testutils.SetupTestEnv()
 	defer testutils.CleanupTestEnv()
 
-	service := &ModelService{}
-
 	t.Run("InvalidModelParameters", func(t *testing.T) {
 		invalidCases := []struct {
 			name       string
@@ -348,9 +337,6 @@ func TestModelServiceValidation(t *testing.T) {
 	})
 
 	t.Run("ModelProgressTracking", func(t *testing.T) {
-		ctx := context.Background()
-		taskID := "test_task_123"
-
 		// 模拟进度更新序列
 		progressUpdates := []map[string]interface{}{
 			{"progress": 0, "status": "queued", "message": "任务已加入队列"},
@@ -399,10 +385,7 @@ func TestModelServicePerformance(t *testing.T) {
 	testutils.SetupTestEnv()
 	defer testutils.CleanupTestEnv()
 
-	service := &ModelService{}
-
 	t.Run("ConcurrentModelOperations", func(t *testing.T) {
-		ctx := context.Background()
 		done := make(chan bool, 5)
 
 		// 模拟并发模型操作
@@ -438,8 +421,6 @@ func TestModelServicePerformance(t *testing.T) {
 	})
 
 	t.Run("ModelOperationLatency", func(t *testing.T) {
-		ctx := context.Background()
-
 		// 测试各种操作的响应时间
 		operations := []struct {
 			name string
@@ -491,6 +472,72 @@ func TestModelServicePerformance(t *testing.T) {
 	})
 }
 
+func newFakeTrainingRequest() ModelTrainingRequest {
+	return ModelTrainingRequest{
+		Name:       "测试模型",
+		ModelType:  "LightGBM",
+		ConfigJSON: `{"num_leaves": 31}`,
+		TrainStart: "2022-01-01",
+		TrainEnd:   "2022-06-30",
+		ValidStart: "2022-07-01",
+		ValidEnd:   "2022-09-30",
+		TestStart:  "2022-10-01",
+		TestEnd:    "2022-12-31",
+		Features:   []string{"$close / Ref($close, 5) - 1"},
+		Label:      "Ref($close, -2) / Ref($close, -1) - 1",
+	}
+}
+
+func TestModelTrainingWithFakeEngine(t *testing.T) {
+	service := NewModelService(nil, testutils.RequireFakeEngines(t, 3).Trainer, nil)
+	req := newFakeTrainingRequest()
+	if err := service.validateTrainingParams(req); err != nil {
+		t.Fatalf("训练参数校验失败: %v", err)
+	}
+
+	var progress []int
+	result, err := service.runTraining(1, 301, req, func(p int, metrics map[string]float64) {
+		progress = append(progress, p)
+		if _, ok := metrics["valid_ic"]; !ok {
+			t.Errorf("进度 %d 缺少验证集IC", p)
+		}
+	})
+	if err != nil {
+		t.Fatalf("训练失败: %v", err)
+	}
+	if result.ModelPath != "fake://models/1" || result.TestIC <= 0 {
+		t.Errorf("训练结果不符合预期: %+v", result)
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != 100 {
+		t.Errorf("训练进度为 %v", progress)
+	}
+	if runningJobs.cancelOwner(modelJobOwner(1)) {
+		t.Error("训练结束后不应再登记为运行中")
+	}
+
+	again, err := service.runTraining(1, 302, req, nil)
+	if err != nil || *again != *result {
+		t.Errorf("相同请求应得到相同结果: %+v, %v", again, err)
+	}
+}
+
+func TestStopTrainingCancelsFakeEngine(t *testing.T) {
+	engines := testutils.RequireFakeEngines(t, 3)
+	engines.Trainer.(*qlib.FakeModelTrainer).EpochDelay = time.Millisecond
+	service := NewModelService(nil, engines.Trainer, nil)
+
+	// 首轮结束时停止训练，与 StopTraining 的取消方式相同
+	_, err := service.runTraining(2, 401, newFakeTrainingRequest(), func(p int, metrics map[string]float64) {
+		runningJobs.cancelOwner(modelJobOwner(2))
+	})
+	if err == nil {
+		t.Fatal("停止后训练应返回错误")
+	}
+	if status, reason := jobFailure(err); status != "cancelled" || reason != qlib.JobCancelled {
+		t.Errorf("停止的训练应记为取消，得到 (%s, %s): %v", status, reason, err)
+	}
+}
+
 // 辅助函数
 func isValidModelType(modelType string) bool {
 	validTypes := []string{"lgb", "xgb", "linear", "lstm", "transformer"}
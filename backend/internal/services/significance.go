@@ -0,0 +1,201 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"qlib-backend/internal/analytics"
+	"qlib-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// significanceLevel 显著性水平
+const significanceLevel = 0.05
+
+// 夏普比率差异检验的自助样本数，固定种子保证同一对比结果可复现
+const (
+	bootstrapSamples = 1000
+	bootstrapSeed    = 42
+)
+
+// seriesCandidate 参与显著性检验的对象及其按日期索引的序列
+type seriesCandidate struct {
+	ID     uint
+	Name   string
+	Values map[string]float64 // 日期 -> 值
+}
+
+// SaveModelDailyIC 保存模型测试集每日IC，覆盖该模型已有记录
+func SaveModelDailyIC(db *gorm.DB, modelID uint, ic, rankIC map[string]float64) error {
+	records := make([]models.ModelDailyIC, 0, len(ic))
+	for key, value := range ic {
+		date := key
+		if len(date) > 10 {
+			date = date[:10]
+		}
+		records = append(records, models.ModelDailyIC{ModelID: modelID, Date: date, IC: value, RankIC: rankIC[key]})
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Date < records[j].Date })
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("model_id = ?", modelID).Delete(&models.ModelDailyIC{}).Error; err != nil {
+			return fmt.Errorf("清理模型IC记录失败: %v", err)
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(records, 500).Error; err != nil {
+			return fmt.Errorf("保存模型IC记录失败: %v", err)
+		}
+		return nil
+	})
+}
+
+// floatSeries 将评估结果中的 {日期: 值} 映射转换为浮点序列，忽略非数值项
+func floatSeries(raw interface{}) map[string]float64 {
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	series := make(map[string]float64, len(values))
+	for date, v := range values {
+		if f, ok := v.(float64); ok {
+			series[date] = f
+		}
+	}
+	return series
+}
+
+// modelICCandidates 读取各模型的每日IC序列
+func modelICCandidates(db *gorm.DB, modelPerformances []models.ModelPerformance) ([]seriesCandidate, error) {
+	candidates := make([]seriesCandidate, 0, len(modelPerformances))
+	for _, m := range modelPerformances {
+		var records []models.ModelDailyIC
+		if err := db.Where("model_id = ?", m.ModelID).Order("date").Find(&records).Error; err != nil {
+			return nil, fmt.Errorf("获取模型IC记录失败: %v", err)
+		}
+		values := make(map[string]float64, len(records))
+		for _, r := range records {
+			values[r.Date] = r.IC
+		}
+		candidates = append(candidates, seriesCandidate{ID: m.ModelID, Name: m.ModelName, Values: values})
+	}
+	return candidates, nil
+}
+
+// strategyReturnCandidates 读取各策略回测的每日收益序列
+func strategyReturnCandidates(db *gorm.DB, strategies []models.Strategy) ([]seriesCandidate, error) {
+	candidates := make([]seriesCandidate, 0, len(strategies))
+	for _, s := range strategies {
+		artifacts, err := loadBacktestArtifacts(db, s.ID)
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]float64)
+		if artifacts != nil {
+			for _, v := range artifacts.Values {
+				values[v.Date] = v.Return
+			}
+		}
+		candidates = append(candidates, seriesCandidate{ID: s.ID, Name: s.Name, Values: values})
+	}
+	return candidates, nil
+}
+
+// pairwiseSignificance 对候选序列两两做显著性检验
+//
+// 每对序列按共同日期对齐后进行Newey-West配对t检验、Wilcoxon符号秩检验和平稳自助法夏普比率差异检验。
+// IC和收益序列不是预测误差，因此不做Diebold-Mariano检验。
+// 比较多于一对时，配对t检验的p值按 correction 做多重比较校正；总体结果取校正后p值最小的一对。
+func pairwiseSignificance(candidates []seriesCandidate, metric string, correction analytics.CorrectionMethod) *models.StatisticalTestResult {
+	if correction == "" {
+		correction = analytics.CorrectionHolm
+	}
+	result := &models.StatisticalTestResult{
+		TestType: "paired_t_newey_west",
+		PValue:   1,
+		Result:   "insufficient data",
+		Details: map[string]interface{}{
+			"metric": metric,
+			"alpha":  significanceLevel,
+		},
+		Pairs: []models.PairwiseTestResult{},
+	}
+
+	var pvalues []float64
+	for i := 0; i < len(candidates); i++ {
+		for j := i + 1; j < len(candidates); j++ {
+			a, b := alignCandidates(candidates[i], candidates[j])
+			if len(a) < 3 {
+				continue
+			}
+			tTest := analytics.PairedTTest(a, b, -1)
+			wilcoxon := analytics.WilcoxonSignedRank(a, b)
+			sharpe := analytics.SharpeDifferenceTest(a, b, 0, bootstrapSamples, bootstrapSeed)
+			result.Pairs = append(result.Pairs, models.PairwiseTestResult{
+				AID:            candidates[i].ID,
+				AName:          candidates[i].Name,
+				BID:            candidates[j].ID,
+				BName:          candidates[j].Name,
+				Observations:   len(a),
+				MeanDifference: analytics.Mean(a) - analytics.Mean(b),
+				Tests: map[string]models.PairTest{
+					"paired_t":         {Statistic: tTest.Statistic, PValue: tTest.PValue},
+					"wilcoxon":         {Statistic: wilcoxon.Statistic, PValue: wilcoxon.PValue},
+					"sharpe_bootstrap": {Statistic: sharpe.Statistic, PValue: sharpe.PValue},
+				},
+			})
+			pvalues = append(pvalues, tTest.PValue)
+		}
+	}
+	if len(result.Pairs) == 0 {
+		result.Details["note"] = "缺少可对齐的每日序列，无法进行显著性检验"
+		return result
+	}
+
+	if len(result.Pairs) == 1 {
+		correction = analytics.CorrectionNone
+	}
+	result.Correction = string(correction)
+	adjusted := analytics.AdjustPValues(pvalues, correction)
+	significant := 0
+	for i := range result.Pairs {
+		pair := &result.Pairs[i]
+		pair.AdjustedPValue = adjusted[i]
+		pair.Significant = adjusted[i] < significanceLevel
+		if pair.Significant {
+			significant++
+		}
+		if i == 0 || adjusted[i] < result.PValue {
+			result.PValue = adjusted[i]
+			result.Statistic = pair.Tests["paired_t"].Statistic
+		}
+	}
+	result.Result = "not significant"
+	if significant > 0 {
+		result.Result = "significant"
+	}
+	result.Details["pairs_tested"] = len(result.Pairs)
+	result.Details["significant_pairs"] = significant
+	return result
+}
+
+// alignCandidates 按共同日期对齐两个序列
+func alignCandidates(a, b seriesCandidate) ([]float64, []float64) {
+	dates := make([]string, 0, len(a.Values))
+	for date, v := range a.Values {
+		if w, ok := b.Values[date]; ok && !math.IsNaN(v) && !math.IsNaN(w) {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+	x := make([]float64, len(dates))
+	y := make([]float64, len(dates))
+	for i, date := range dates {
+		x[i], y[i] = a.Values[date], b.Values[date]
+	}
+	return x, y
+}
+
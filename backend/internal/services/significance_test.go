@@ -0,0 +1,55 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"qlib-backend/internal/analytics"
+)
+
+func TestPairwiseSignificance(t *testing.T) {
+	base := make(map[string]float64)
+	shifted := make(map[string]float64)
+	noisy := make(map[string]float64)
+	for i := 0; i < 60; i++ {
+		date := fmt.Sprintf("2023-%02d-%02d", i/28+1, i%28+1)
+		v := 0.01 * float64(i%7-3)
+		base[date] = v
+		shifted[date] = v + 0.02 + 0.001*float64(i%3)
+		noisy[date] = v + 0.0005*float64(i%5-2)
+	}
+	candidates := []seriesCandidate{
+		{ID: 1, Name: "base", Values: base},
+		{ID: 2, Name: "shifted", Values: shifted},
+		{ID: 3, Name: "noisy", Values: noisy},
+	}
+
+	result := pairwiseSignificance(candidates, "daily_ic", "")
+	if result.Correction != string(analytics.CorrectionHolm) || len(result.Pairs) != 3 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.Result != "significant" || result.PValue >= significanceLevel {
+		t.Errorf("shifted series should differ significantly, got %s p=%v", result.Result, result.PValue)
+	}
+	for _, pair := range result.Pairs {
+		if pair.Observations != 60 || len(pair.Tests) != 3 {
+			t.Errorf("unexpected pair: %+v", pair)
+		}
+		if pair.AdjustedPValue < pair.Tests["paired_t"].PValue {
+			t.Errorf("adjusted p-value %v below raw %v", pair.AdjustedPValue, pair.Tests["paired_t"].PValue)
+		}
+		if pair.AID == 1 && pair.BID == 3 && pair.Significant {
+			t.Errorf("base and noisy should not differ: %+v", pair)
+		}
+	}
+
+	t.Run("InsufficientData", func(t *testing.T) {
+		result := pairwiseSignificance([]seriesCandidate{
+			{ID: 1, Values: map[string]float64{"2023-01-03": 0.1}},
+			{ID: 2, Values: map[string]float64{"2023-01-04": 0.2}},
+		}, "daily_return", analytics.CorrectionBH)
+		if result.Result != "insufficient data" || result.PValue != 1 || len(result.Pairs) != 0 {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	})
+}
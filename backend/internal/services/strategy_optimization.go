@@ -0,0 +1,464 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"qlib-backend/internal/analytics"
+	"qlib-backend/internal/models"
+	"qlib-backend/internal/optimizer"
+	"qlib-backend/internal/qlib"
+
+	"gorm.io/gorm"
+)
+
+// 参数优化默认配置
+const (
+	defaultOptimizationConcurrency = 4
+	defaultOutOfSampleRatio        = 0.3
+	defaultTargetMetric            = "sharpe_ratio"
+)
+
+// runningOptimizations 运行中的优化任务ID，各服务实例共享，避免同一任务被重复恢复
+var runningOptimizations sync.Map
+
+// optimizationTaskConfig 优化任务配置，保存在任务的ConfigJSON中，用于中断后恢复
+type optimizationTaskConfig struct {
+	StrategyID uint                        `json:"strategy_id"`
+	UserID     uint                        `json:"user_id"`
+	Request    StrategyOptimizationRequest `json:"request"`
+}
+
+// StrategyOptimizationResult 参数优化结果
+type StrategyOptimizationResult struct {
+	qlib.OptimizationResult
+	Method       string                           `json:"method"`
+	TargetMetric string                           `json:"target_metric"`
+	FailedTrials int                              `json:"failed_trials"`
+	Sensitivity  []optimizer.ParameterSensitivity `json:"sensitivity"`           // 各参数敏感性，按敏感程度排序
+	Surface      *optimizer.Surface               `json:"surface,omitempty"`     // 两个最敏感参数上的得分曲面
+	Overfitting  *optimizer.OverfitReport         `json:"overfitting,omitempty"` // 样本内外表现对比，未划分样本外区间时为空
+}
+
+// ResumeOptimization 恢复中断的参数优化任务，已完成的试验不会重复执行
+func (s *StrategyService) ResumeOptimization(taskID uint, userID uint) (*StrategyOptimizationResponse, error) {
+	var task models.Task
+	if err := s.db.Where("id = ? AND user_id = ? AND type = ?", taskID, userID, "strategy_optimization").First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("优化任务不存在")
+		}
+		return nil, fmt.Errorf("获取优化任务失败: %v", err)
+	}
+	if task.Status == "completed" {
+		return nil, fmt.Errorf("优化任务已完成")
+	}
+
+	var cfg optimizationTaskConfig
+	if err := json.Unmarshal([]byte(task.ConfigJSON), &cfg); err != nil {
+		return nil, fmt.Errorf("解析优化任务配置失败: %v", err)
+	}
+	if _, running := runningOptimizations.LoadOrStore(taskID, true); running {
+		return nil, fmt.Errorf("优化任务正在运行")
+	}
+	go s.executeOptimization(taskID, cfg)
+
+	return &StrategyOptimizationResponse{
+		StrategyID: cfg.StrategyID,
+		TaskID:     taskID,
+		Status:     "resumed",
+		Message:    "参数优化已恢复",
+	}, nil
+}
+
+// GetOptimizationTrials 获取优化任务已完成的试验
+func (s *StrategyService) GetOptimizationTrials(taskID uint, userID uint) ([]qlib.OptimizationStep, error) {
+	var task models.Task
+	if err := s.db.Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("优化任务不存在")
+		}
+		return nil, fmt.Errorf("获取优化任务失败: %v", err)
+	}
+	trials, err := loadOptimizationTrials(s.db, taskID)
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]qlib.OptimizationStep, len(trials))
+	for i, trial := range trials {
+		steps[i] = optimizationStep(trial)
+	}
+	return steps, nil
+}
+
+// executeOptimization 执行参数优化，取消任务时停止调度新的试验并终止正在运行的回测
+func (s *StrategyService) executeOptimization(taskID uint, cfg optimizationTaskConfig) {
+	defer runningOptimizations.Delete(taskID)
+
+	s.db.Model(&models.Task{}).Where("id = ?", taskID).Updates(map[string]interface{}{
+		"status":     "running",
+		"start_time": time.Now(),
+	})
+
+	jobCtx, finishJob := runningJobs.start(context.Background(), taskID, "", DefaultJobTimeout)
+	defer finishJob()
+	result, err := s.runOptimization(jobCtx, taskID, cfg)
+	if err != nil {
+		status, reason := jobFailure(err)
+		s.db.Model(&models.Task{}).Where("id = ?", taskID).Updates(map[string]interface{}{
+			"status":             status,
+			"termination_reason": reason,
+			"error_msg":          err.Error(),
+			"end_time":           time.Now(),
+		})
+		if s.wsService != nil {
+			s.wsService.SendTaskStatusUpdate(cfg.UserID, taskID, status, err.Error())
+		}
+		return
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	s.db.Model(&models.Task{}).Where("id = ?", taskID).Updates(map[string]interface{}{
+		"status":      "completed",
+		"progress":    100,
+		"result_json": string(resultJSON),
+		"end_time":    time.Now(),
+	})
+	if s.wsService != nil {
+		s.wsService.SendTaskStatusUpdate(cfg.UserID, taskID, "completed", "参数优化已完成")
+	}
+}
+
+// runOptimization 在Go中执行参数搜索，每次试验为一次完整回测，按回测区间切分样本内外计算目标值
+func (s *StrategyService) runOptimization(ctx context.Context, taskID uint, cfg optimizationTaskConfig) (*StrategyOptimizationResult, error) {
+	req := cfg.Request
+	var strategy models.Strategy
+	if err := s.db.Where("id = ?", cfg.StrategyID).First(&strategy).Error; err != nil {
+		return nil, fmt.Errorf("获取策略失败: %v", err)
+	}
+	space, err := optimizer.ParseSpace(req.ParameterRanges)
+	if err != nil {
+		return nil, err
+	}
+	baseConfig := req.ConfigJSON
+	if baseConfig == "" {
+		baseConfig = strategy.ConfigJSON
+	}
+	target := req.TargetMetric
+	if target == "" {
+		target = defaultTargetMetric
+	}
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultOptimizationConcurrency
+	}
+	oosRatio := req.OutOfSampleRatio
+	if oosRatio == 0 {
+		oosRatio = defaultOutOfSampleRatio
+	}
+	optimizerConfig := optimizer.Config{
+		Space:       space,
+		Method:      req.OptimizationMethod,
+		MaxTrials:   req.MaxIterations,
+		Concurrency: concurrency,
+		Seed:        req.Seed,
+	}
+
+	history, err := loadOptimizationTrials(s.db, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	objective := func(ctx context.Context, params optimizer.Params) (*optimizer.Evaluation, error) {
+		configJSON, err := mergeStrategyConfig(baseConfig, params)
+		if err != nil {
+			return nil, err
+		}
+		summary, report, err := s.backtestEngine.RunBacktestWithReport(ctx, qlib.BacktestParams{
+			StrategyID:    strategy.ID,
+			StrategyType:  strategy.Type,
+			ModelID:       strategy.ModelID,
+			ConfigJSON:    configJSON,
+			BacktestStart: strategy.BacktestStart,
+			BacktestEnd:   strategy.BacktestEnd,
+			Universe:      req.Universe,
+			Benchmark:     req.Benchmark,
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return scoreBacktest(summary, report, target, oosRatio, req.Benchmark != "")
+	}
+
+	var executor optimizer.Executor
+	if s.taskManager != nil {
+		executor = s.taskManager.RunJobs
+	}
+	total := optimizerConfig.TotalTrials()
+	finished := len(history)
+	onTrial := func(trial optimizer.Trial) {
+		if err := saveOptimizationTrial(s.db, taskID, cfg.StrategyID, trial); err != nil {
+			trial.Error = err.Error()
+		}
+		finished++
+		progress := finished * 100 / total
+		if progress > 99 {
+			progress = 99
+		}
+		s.db.Model(&models.Task{}).Where("id = ?", taskID).Update("progress", progress)
+		if s.wsService != nil {
+			s.wsService.SendOptimizationStep(cfg.UserID, taskID, progress, optimizationStep(trial))
+		}
+	}
+
+	trials, err := optimizer.Run(ctx, optimizerConfig, history, objective, executor, onTrial)
+	if err != nil {
+		return nil, err
+	}
+	best, ok := optimizer.Best(trials)
+	if !ok {
+		return nil, fmt.Errorf("全部 %d 次试验均失败", len(trials))
+	}
+
+	method, _ := optimizer.NormalizeMethod(req.OptimizationMethod)
+	result := &StrategyOptimizationResult{
+		OptimizationResult: qlib.OptimizationResult{
+			BestParameters: best.Params,
+			BestScore:      best.Score,
+			Iterations:     make([]qlib.OptimizationStep, len(trials)),
+		},
+		Method:       method,
+		TargetMetric: target,
+		Sensitivity:  optimizer.Sensitivity(space, trials),
+		Overfitting:  optimizer.CheckOverfit(trials, best),
+	}
+	for i, trial := range trials {
+		result.Iterations[i] = optimizationStep(trial)
+		if trial.Status == optimizer.TrialFailed {
+			result.FailedTrials++
+		}
+	}
+	result.Surface = optimizer.ResponseSurface(space, trials, result.Sensitivity)
+	return result, nil
+}
+
+// scoreBacktest 计算试验得分
+//
+// 原生回测按日收益将回测区间末尾 oosRatio 比例的交易日作为样本外区间，样本内外分别计算目标值；
+// 回退到Python回测时只能使用全区间汇总指标，不提供样本外得分。
+func scoreBacktest(summary *qlib.BacktestResult, report *qlib.NativeBacktestReport, target string, oosRatio float64, hasBenchmark bool) (*optimizer.Evaluation, error) {
+	if report == nil {
+		if summary == nil {
+			return nil, fmt.Errorf("回测未返回结果")
+		}
+		score, err := summaryScore(summary, target)
+		if err != nil {
+			return nil, err
+		}
+		return &optimizer.Evaluation{Score: score, Metrics: map[string]float64{
+			"total_return":  summary.TotalReturn,
+			"annual_return": summary.AnnualReturn,
+			"sharpe_ratio":  summary.SharpeRatio,
+			"max_drawdown":  summary.MaxDrawdown,
+			"volatility":    summary.Volatility,
+		}}, nil
+	}
+
+	returns := make([]float64, len(report.Daily))
+	var benchmark []float64
+	if hasBenchmark {
+		benchmark = make([]float64, len(report.Daily))
+	}
+	for i, day := range report.Daily {
+		returns[i] = day.Return
+		if benchmark != nil {
+			benchmark[i] = day.BenchmarkReturn
+		}
+	}
+	split := len(returns)
+	if oosRatio > 0 && oosRatio < 1 {
+		split = len(returns) - int(math.Round(float64(len(returns))*oosRatio))
+	}
+	// 样本内外均至少需要两个交易日
+	if split < 2 || len(returns)-split < 2 {
+		split = len(returns)
+	}
+
+	inSample := analytics.Compute(returns[:split], segment(benchmark, 0, split), analytics.Options{})
+	score, err := metricScore(inSample, target)
+	if err != nil {
+		return nil, err
+	}
+	evaluation := &optimizer.Evaluation{Score: score, Metrics: metricValues(inSample, "")}
+	if split < len(returns) {
+		outOfSample := analytics.Compute(returns[split:], segment(benchmark, split, len(returns)), analytics.Options{})
+		oos, err := metricScore(outOfSample, target)
+		if err != nil {
+			return nil, err
+		}
+		evaluation.OutOfSample = &oos
+		for k, v := range metricValues(outOfSample, "oos_") {
+			evaluation.Metrics[k] = v
+		}
+	}
+	return evaluation, nil
+}
+
+// metricScore 目标指标的得分，越大越好（回撤为非正数，波动率取负）
+func metricScore(m *analytics.Metrics, target string) (float64, error) {
+	switch target {
+	case "sharpe_ratio", "sharpe":
+		return m.SharpeRatio, nil
+	case "sortino_ratio":
+		return m.SortinoRatio, nil
+	case "calmar_ratio":
+		return m.CalmarRatio, nil
+	case "total_return":
+		return m.TotalReturn, nil
+	case "annual_return":
+		return m.AnnualReturn, nil
+	case "max_drawdown":
+		return m.Drawdown.MaxDrawdown, nil
+	case "volatility":
+		return -m.AnnualVolatility, nil
+	case "information_ratio", "excess_return":
+		if m.Relative == nil {
+			return 0, fmt.Errorf("目标指标 %s 需要设置基准", target)
+		}
+		if target == "information_ratio" {
+			return m.Relative.InformationRatio, nil
+		}
+		return m.Relative.ExcessReturn, nil
+	}
+	return 0, fmt.Errorf("不支持的目标指标: %s", target)
+}
+
+// validateTargetMetric 校验目标指标是否受支持
+func validateTargetMetric(target string) error {
+	_, err := metricScore(&analytics.Metrics{Relative: &analytics.RelativeMetrics{}}, target)
+	return err
+}
+
+// summaryScore 根据回测汇总指标计算得分
+func summaryScore(summary *qlib.BacktestResult, target string) (float64, error) {
+	switch target {
+	case "sharpe_ratio", "sharpe":
+		return summary.SharpeRatio, nil
+	case "total_return":
+		return summary.TotalReturn, nil
+	case "annual_return":
+		return summary.AnnualReturn, nil
+	case "excess_return":
+		return summary.ExcessReturn, nil
+	case "max_drawdown":
+		return -math.Abs(summary.MaxDrawdown), nil
+	case "volatility":
+		return -summary.Volatility, nil
+	}
+	return 0, fmt.Errorf("回测结果不包含目标指标: %s", target)
+}
+
+// metricValues 试验记录中保存的主要指标
+func metricValues(m *analytics.Metrics, prefix string) map[string]float64 {
+	values := map[string]float64{
+		prefix + "total_return":  m.TotalReturn,
+		prefix + "annual_return": m.AnnualReturn,
+		prefix + "sharpe_ratio":  m.SharpeRatio,
+		prefix + "max_drawdown":  m.Drawdown.MaxDrawdown,
+		prefix + "volatility":    m.AnnualVolatility,
+	}
+	if m.Relative != nil {
+		values[prefix+"excess_return"] = m.Relative.ExcessReturn
+		values[prefix+"information_ratio"] = m.Relative.InformationRatio
+	}
+	return values
+}
+
+// segment 截取序列区间，序列为空时返回空
+func segment(values []float64, from, to int) []float64 {
+	if values == nil {
+		return nil
+	}
+	return values[from:to]
+}
+
+// mergeStrategyConfig 将试验参数覆盖到基础策略配置
+func mergeStrategyConfig(base string, params optimizer.Params) (string, error) {
+	config := map[string]interface{}{}
+	if base != "" {
+		if err := json.Unmarshal([]byte(base), &config); err != nil {
+			return "", fmt.Errorf("解析策略配置失败: %v", err)
+		}
+	}
+	for k, v := range params {
+		config[k] = v
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("序列化策略配置失败: %v", err)
+	}
+	return string(data), nil
+}
+
+// saveOptimizationTrial 保存单次试验
+func saveOptimizationTrial(db *gorm.DB, taskID, strategyID uint, trial optimizer.Trial) error {
+	paramsJSON, _ := json.Marshal(trial.Params)
+	metricsJSON, _ := json.Marshal(trial.Metrics)
+	record := models.OptimizationTrial{
+		TaskID:      taskID,
+		StrategyID:  strategyID,
+		Number:      trial.Number,
+		ParamsJSON:  string(paramsJSON),
+		Status:      trial.Status,
+		Score:       trial.Score,
+		OutOfSample: trial.OutOfSample,
+		MetricsJSON: string(metricsJSON),
+		ErrorMsg:    trial.Error,
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return fmt.Errorf("保存优化试验失败: %v", err)
+	}
+	return nil
+}
+
+// loadOptimizationTrials 读取任务已完成的试验
+func loadOptimizationTrials(db *gorm.DB, taskID uint) ([]optimizer.Trial, error) {
+	var records []models.OptimizationTrial
+	if err := db.Where("task_id = ?", taskID).Order("number").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("获取优化试验失败: %v", err)
+	}
+	trials := make([]optimizer.Trial, 0, len(records))
+	for _, r := range records {
+		trial := optimizer.Trial{
+			Number:      r.Number,
+			Status:      r.Status,
+			Score:       r.Score,
+			OutOfSample: r.OutOfSample,
+			Error:       r.ErrorMsg,
+		}
+		if err := json.Unmarshal([]byte(r.ParamsJSON), &trial.Params); err != nil {
+			return nil, fmt.Errorf("解析试验参数失败: %v", err)
+		}
+		if r.MetricsJSON != "" {
+			json.Unmarshal([]byte(r.MetricsJSON), &trial.Metrics)
+		}
+		trials = append(trials, trial)
+	}
+	return trials, nil
+}
+
+// optimizationStep 试验转换为优化步骤
+func optimizationStep(trial optimizer.Trial) qlib.OptimizationStep {
+	return qlib.OptimizationStep{
+		Iteration:        trial.Number,
+		Parameters:       trial.Params,
+		Score:            trial.Score,
+		Status:           trial.Status,
+		OutOfSampleScore: trial.OutOfSample,
+		Metrics:          trial.Metrics,
+		Error:            trial.Error,
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"qlib-backend/internal/optimizer"
+	"qlib-backend/internal/qlib"
+)
+
+func TestScoreBacktest(t *testing.T) {
+	report := &qlib.NativeBacktestReport{}
+	returns := []float64{0.01, 0.02, -0.01, 0.015, 0.005, 0.01, -0.02, -0.01, 0.0, -0.005}
+	for i, r := range returns {
+		report.Daily = append(report.Daily, qlib.DailyRecord{
+			Date:            time.Date(2023, 1, 3+i, 0, 0, 0, 0, time.UTC),
+			Return:          r,
+			BenchmarkReturn: r / 2,
+		})
+	}
+
+	evaluation, err := scoreBacktest(nil, report, "total_return", 0.3, true)
+	if err != nil {
+		t.Fatalf("scoreBacktest failed: %v", err)
+	}
+	// 前7个交易日为样本内，后3个为样本外
+	inSample, outOfSample := 1.0, 1.0
+	for i, r := range returns {
+		if i < 7 {
+			inSample *= 1 + r
+		} else {
+			outOfSample *= 1 + r
+		}
+	}
+	if math.Abs(evaluation.Score-(inSample-1)) > 1e-12 || evaluation.OutOfSample == nil || math.Abs(*evaluation.OutOfSample-(outOfSample-1)) > 1e-12 {
+		t.Errorf("unexpected scores: %v, %v", evaluation.Score, evaluation.OutOfSample)
+	}
+	if _, ok := evaluation.Metrics["oos_information_ratio"]; !ok {
+		t.Errorf("expected out-of-sample relative metrics: %v", evaluation.Metrics)
+	}
+
+	if full, _ := scoreBacktest(nil, report, "sharpe_ratio", -1, false); full.OutOfSample != nil {
+		t.Error("negative ratio should disable the out-of-sample split")
+	}
+	if _, err := scoreBacktest(nil, report, "information_ratio", 0.3, false); err == nil {
+		t.Error("information_ratio without benchmark should fail")
+	}
+
+	t.Run("SummaryFallback", func(t *testing.T) {
+		evaluation, err := scoreBacktest(&qlib.BacktestResult{MaxDrawdown: 0.08}, nil, "max_drawdown", 0.3, false)
+		if err != nil || evaluation.Score != -0.08 || evaluation.OutOfSample != nil {
+			t.Errorf("unexpected fallback evaluation: %+v, %v", evaluation, err)
+		}
+		if _, err := scoreBacktest(&qlib.BacktestResult{}, nil, "sortino_ratio", 0.3, false); err == nil {
+			t.Error("summary does not provide sortino_ratio")
+		}
+	})
+}
+
+func TestMergeStrategyConfig(t *testing.T) {
+	merged, err := mergeStrategyConfig(`{"signal":"$close","topk":50}`, optimizer.Params{"topk": 30, "n_drop": 5})
+	if err != nil {
+		t.Fatalf("mergeStrategyConfig failed: %v", err)
+	}
+	var config map[string]interface{}
+	json.Unmarshal([]byte(merged), &config)
+	if config["signal"] != "$close" || config["topk"] != 30.0 || config["n_drop"] != 5.0 {
+		t.Errorf("unexpected config: %v", config)
+	}
+	if _, err := mergeStrategyConfig("{", nil); err == nil {
+		t.Error("expected error for invalid base config")
+	}
+}
+
+func TestTaskManagerRunJobs(t *testing.T) {
+	tm := NewTaskManager(nil, 2)
+	defer tm.Close()
+
+	var running, peak, done int32
+	jobs := make([]func(ctx context.Context), 8)
+	for i := range jobs {
+		jobs[i] = func(ctx context.Context) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&done, 1)
+		}
+	}
+	if err := tm.RunJobs(context.Background(), 3, jobs); err != nil {
+		t.Fatalf("RunJobs failed: %v", err)
+	}
+	if done != 8 || peak > 3 {
+		t.Errorf("done = %d, peak concurrency = %d", done, peak)
+	}
+}
@@ -1,10 +1,13 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"qlib-backend/internal/models"
+	"qlib-backend/internal/optimizer"
 	"qlib-backend/internal/qlib"
 
 	"gorm.io/gorm"
@@ -12,11 +15,13 @@ import (
 
 type StrategyService struct {
 	db              *gorm.DB
-	backtestEngine  *qlib.BacktestEngine
+	backtestEngine  qlib.BacktestingEngine
 	taskService     *TaskService
+	taskManager     *TaskManager      // 参数优化试验分发到任务管理器的工作协程，为空时使用独立协程
+	wsService       *WebSocketService // 推送参数优化进度，可为空
 }
 
-func NewStrategyService(db *gorm.DB, backtestEngine *qlib.BacktestEngine, taskService *TaskService) *StrategyService {
+func NewStrategyService(db *gorm.DB, backtestEngine qlib.BacktestingEngine, taskService *TaskService) *StrategyService {
 	return &StrategyService{
 		db:              db,
 		backtestEngine:  backtestEngine,
@@ -24,6 +29,16 @@ func NewStrategyService(db *gorm.DB, backtestEngine *qlib.BacktestEngine, taskSe
 	}
 }
 
+// SetTaskManager 设置任务管理器
+func (s *StrategyService) SetTaskManager(taskManager *TaskManager) {
+	s.taskManager = taskManager
+}
+
+// SetWebSocketService 设置WebSocket服务
+func (s *StrategyService) SetWebSocketService(wsService *WebSocketService) {
+	s.wsService = wsService
+}
+
 // StartBacktest 启动策略回测
 func (s *StrategyService) StartBacktest(req StrategyBacktestRequest, userID uint) (*StrategyBacktestResponse, error) {
 	// 验证回测参数
@@ -205,16 +220,19 @@ func (s *StrategyService) StopBacktest(strategyID uint, userID uint) error {
 		return fmt.Errorf("停止回测失败: %v", err)
 	}
 
-	// 通知回测引擎停止回测
-	if err := s.backtestEngine.StopBacktest(strategyID); err != nil {
-		return fmt.Errorf("停止回测引擎失败: %v", err)
-	}
+	// 取消回测任务的上下文，回测进程组随之终止，任务记为已取消
+	runningJobs.cancelOwner(strategyJobOwner(strategyID))
 
 	return nil
 }
 
 // GetAttributionAnalysis 获取策略归因分析
 func (s *StrategyService) GetAttributionAnalysis(strategyID uint, userID uint) (*AttributionAnalysisResult, error) {
+	return s.GetAttributionAnalysisWithOptions(strategyID, userID, AttributionAnalysisOptions{})
+}
+
+// GetAttributionAnalysisWithOptions 按选项获取策略归因分析，保存了每日持仓的回测使用原生Brinson归因
+func (s *StrategyService) GetAttributionAnalysisWithOptions(strategyID uint, userID uint, options AttributionAnalysisOptions) (*AttributionAnalysisResult, error) {
 	var strategy models.Strategy
 	if err := s.db.Where("id = ? AND user_id = ?", strategyID, userID).First(&strategy).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -227,10 +245,36 @@ func (s *StrategyService) GetAttributionAnalysis(strategyID uint, userID uint) (
 		return nil, fmt.Errorf("策略回测尚未完成")
 	}
 
-	// 调用回测引擎进行归因分析
-	attribution, err := s.backtestEngine.GetAttributionAnalysis(qlib.AttributionAnalysisParams{
+	params := qlib.AttributionAnalysisParams{
 		StrategyID: strategyID,
-	})
+		Universe:   options.Universe,
+		End:        strategy.BacktestEnd,
+		Exposures:  options.Exposures,
+		Linking:    options.Linking,
+	}
+	artifacts, err := loadBacktestArtifacts(s.db, strategyID)
+	if err != nil {
+		return nil, err
+	}
+	if artifacts != nil {
+		for _, p := range artifacts.Positions {
+			date, err := time.Parse("2006-01-02", p.Date)
+			if err != nil {
+				return nil, fmt.Errorf("持仓记录日期格式错误: %v", err)
+			}
+			params.Positions = append(params.Positions, qlib.PositionRecord{
+				Date:       date,
+				Instrument: p.Instrument,
+				Amount:     p.Amount,
+				Weight:     p.Weight,
+				Price:      p.Price,
+				Value:      p.Value,
+			})
+		}
+	}
+
+	// 调用回测引擎进行归因分析
+	attribution, err := s.backtestEngine.GetAttributionAnalysis(params)
 	if err != nil {
 		return nil, fmt.Errorf("归因分析失败: %v", err)
 	}
@@ -299,13 +343,33 @@ func (s *StrategyService) OptimizeStrategy(strategyID uint, req StrategyOptimiza
 		return nil, fmt.Errorf("获取策略失败: %v", err)
 	}
 
+	// 提前校验参数空间、优化方法和目标指标
+	if _, err := optimizer.ParseSpace(req.ParameterRanges); err != nil {
+		return nil, fmt.Errorf("参数范围无效: %v", err)
+	}
+	if _, err := optimizer.NormalizeMethod(req.OptimizationMethod); err != nil {
+		return nil, err
+	}
+	if req.TargetMetric != "" {
+		if err := validateTargetMetric(req.TargetMetric); err != nil {
+			return nil, err
+		}
+	}
+
+	// 任务配置保存完整请求，用于中断后恢复
+	cfg := optimizationTaskConfig{StrategyID: strategyID, UserID: userID, Request: req}
+	configJSON, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("序列化优化配置失败: %v", err)
+	}
+
 	// 创建优化任务
 	task := &models.Task{
 		Name:        fmt.Sprintf("策略优化: %s", strategy.Name),
 		Type:        "strategy_optimization",
 		Status:      "pending",
 		Description: "参数优化任务",
-		ConfigJSON:  req.ConfigJSON,
+		ConfigJSON:  string(configJSON),
 		UserID:      userID,
 	}
 
@@ -314,7 +378,8 @@ func (s *StrategyService) OptimizeStrategy(strategyID uint, req StrategyOptimiza
 	}
 
 	// 启动异步优化任务
-	go s.executeOptimization(strategyID, task.ID, req)
+	runningOptimizations.Store(task.ID, true)
+	go s.executeOptimization(task.ID, cfg)
 
 	return &StrategyOptimizationResponse{
 		StrategyID: strategyID,
@@ -395,18 +460,7 @@ func (s *StrategyService) executeBacktest(strategyID, taskID uint, req StrategyB
 	s.db.Model(&models.Strategy{}).Where("id = ?", strategyID).Updates(map[string]interface{}{
 		"status": "backtesting",
 	})
-
-	// 调用回测引擎
-	backtestParams := qlib.BacktestParams{
-		StrategyID:    strategyID,
-		StrategyType:  req.StrategyType,
-		ModelID:       req.ModelID,
-		ConfigJSON:    req.ConfigJSON,
-		BacktestStart: req.BacktestStart,
-		BacktestEnd:   req.BacktestEnd,
-		Universe:      req.Universe,
-		Benchmark:     req.Benchmark,
-	}
+	startTaskEvents(taskID, "策略回测开始")
 
 	// 设置进度回调
 	progressCallback := func(progress int, metrics map[string]float64) {
@@ -432,22 +486,31 @@ func (s *StrategyService) executeBacktest(strategyID, taskID uint, req StrategyB
 		}
 
 		s.db.Model(&models.Strategy{}).Where("id = ?", strategyID).Updates(updates)
+		s.db.Model(&models.Task{}).Where("id = ?", taskID).Update("progress", progress)
 	}
 
-	// 执行回测
-	result, err := s.backtestEngine.RunBacktest(backtestParams, progressCallback)
+	// 执行回测并保存每日净值、持仓和成交记录
+	result, report, err := s.runBacktest(strategyID, taskID, req, progressCallback)
+	if err == nil && report != nil {
+		if saveErr := SaveBacktestArtifacts(s.db, strategyID, req.Benchmark, report); saveErr != nil {
+			err = fmt.Errorf("保存回测记录失败: %v", saveErr)
+		}
+	}
 
 	// 更新最终状态
 	if err != nil {
-		// 回测失败
+		// 回测失败、被取消、超时或内存不足
+		status, reason := jobFailure(err)
 		s.db.Model(&models.Strategy{}).Where("id = ?", strategyID).Updates(map[string]interface{}{
-			"status": "failed",
+			"status": status,
 		})
 		s.db.Model(&models.Task{}).Where("id = ?", taskID).Updates(map[string]interface{}{
-			"status":    "failed",
-			"error_msg": err.Error(),
-			"end_time":  time.Now(),
+			"status":             status,
+			"termination_reason": reason,
+			"error_msg":          err.Error(),
+			"end_time":           time.Now(),
 		})
+		finishTaskEvents(taskID, status, 0, err.Error())
 	} else {
 		// 回测成功
 		s.db.Model(&models.Strategy{}).Where("id = ?", strategyID).Updates(map[string]interface{}{
@@ -463,45 +526,32 @@ func (s *StrategyService) executeBacktest(strategyID, taskID uint, req StrategyB
 		})
 		s.db.Model(&models.Task{}).Where("id = ?", taskID).Updates(map[string]interface{}{
 			"status":   "completed",
+			"progress": 100,
 			"end_time": time.Now(),
 		})
+		finishTaskEvents(taskID, "completed", 100, "策略回测完成")
 	}
 }
 
-// executeOptimization 执行参数优化
-func (s *StrategyService) executeOptimization(strategyID, taskID uint, req StrategyOptimizationRequest) {
-	// 更新任务状态
-	s.db.Model(&models.Task{}).Where("id = ?", taskID).Updates(map[string]interface{}{
-		"status":     "running",
-		"start_time": time.Now(),
-	})
-
-	// 调用回测引擎进行参数优化
-	optimizationParams := qlib.OptimizationParams{
-		StrategyID:      strategyID,
-		ParameterRanges: req.ParameterRanges,
-		OptimizationMethod: req.OptimizationMethod,
-		TargetMetric:    req.TargetMetric,
-		MaxIterations:   req.MaxIterations,
+// runBacktest 调用回测引擎，停止回测或超时时取消任务上下文
+//
+// 原生回测同时返回每日净值、持仓和成交记录，Python回测的日志推送到任务事件流。
+func (s *StrategyService) runBacktest(strategyID, taskID uint, req StrategyBacktestRequest, progressCallback qlib.BacktestProgressCallback) (*qlib.BacktestResult, *qlib.NativeBacktestReport, error) {
+	backtestParams := qlib.BacktestParams{
+		StrategyID:    strategyID,
+		StrategyType:  req.StrategyType,
+		ModelID:       req.ModelID,
+		ConfigJSON:    req.ConfigJSON,
+		BacktestStart: req.BacktestStart,
+		BacktestEnd:   req.BacktestEnd,
+		Universe:      req.Universe,
+		Benchmark:     req.Benchmark,
 	}
 
-	result, err := s.backtestEngine.OptimizeParameters(optimizationParams)
-
-	// 更新任务状态
-	if err != nil {
-		s.db.Model(&models.Task{}).Where("id = ?", taskID).Updates(map[string]interface{}{
-			"status":    "failed",
-			"error_msg": err.Error(),
-			"end_time":  time.Now(),
-		})
-	} else {
-		resultJSON, _ := result.ToJSON()
-		s.db.Model(&models.Task{}).Where("id = ?", taskID).Updates(map[string]interface{}{
-			"status":      "completed",
-			"result_json": resultJSON,
-			"end_time":    time.Now(),
-		})
-	}
+	jobCtx, finishJob := runningJobs.start(context.Background(), taskID, strategyJobOwner(strategyID), DefaultJobTimeout)
+	defer finishJob()
+	ctx := qlib.WithPythonEvents(jobCtx, pythonTaskEvents(taskID, nil))
+	return s.backtestEngine.RunBacktestWithReport(ctx, backtestParams, progressCallback)
 }
 
 // buildBasicMetrics 构建基础指标
@@ -599,6 +649,13 @@ type BacktestProgressResponse struct {
 	Logs        []string   `json:"logs"`
 }
 
+// AttributionAnalysisOptions 归因分析选项
+type AttributionAnalysisOptions struct {
+	Universe  string                        `json:"universe"`  // 行业基准股票池，为空表示全部证券
+	Linking   string                        `json:"linking"`   // 多期连接方法：carino（默认）或 menchero
+	Exposures map[string]map[string]float64 `json:"exposures"` // 证券 -> 因子 -> 暴露值，非空时进行因子归因
+}
+
 type AttributionAnalysisResult struct {
 	StrategyID        uint                   `json:"strategy_id"`
 	FactorAttribution map[string]interface{} `json:"factor_attribution"`
@@ -627,10 +684,15 @@ type StrategyComparisonResult struct {
 
 type StrategyOptimizationRequest struct {
 	ParameterRanges    map[string]interface{} `json:"parameter_ranges" binding:"required"`
-	OptimizationMethod string                 `json:"optimization_method"`
-	TargetMetric       string                 `json:"target_metric"`
-	MaxIterations      int                    `json:"max_iterations"`
-	ConfigJSON         string                 `json:"config_json"`
+	OptimizationMethod string                 `json:"optimization_method"` // grid、random 或 bayesian（默认）
+	TargetMetric       string                 `json:"target_metric"`       // 默认 sharpe_ratio
+	MaxIterations      int                    `json:"max_iterations"`      // 网格搜索为0时遍历全部组合，其余方法默认50
+	ConfigJSON         string                 `json:"config_json"`         // 基础策略配置，为空时使用策略已保存的配置
+	Concurrency        int                    `json:"concurrency"`         // 同时运行的回测数，默认4
+	OutOfSampleRatio   float64                `json:"out_of_sample_ratio"` // 回测区间末尾用于样本外检验的比例，默认0.3，为负时不划分
+	Seed               int64                  `json:"seed"`                // 随机种子，相同种子可复现随机搜索和贝叶斯优化
+	Universe           string                 `json:"universe"`
+	Benchmark          string                 `json:"benchmark"`
 }
 
 type StrategyOptimizationResponse struct {
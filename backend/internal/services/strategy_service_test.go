@@ -5,145 +5,163 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
 	"qlib-backend/internal/models"
 	"qlib-backend/internal/qlib"
 	"qlib-backend/internal/testutils"
 )
 
-// MockBacktestEngine 模拟回测引擎
-type MockBacktestEngine struct {
-	mock.Mock
+func newFakeBacktestRequest() StrategyBacktestRequest {
+	return StrategyBacktestRequest{
+		Name:          "测试策略回测",
+		StrategyType:  "TopkDropoutStrategy",
+		ModelID:       3,
+		ConfigJSON:    `{"topk": 5, "n_drop": 1}`,
+		BacktestStart: "2022-03-01",
+		BacktestEnd:   "2022-12-30",
+		Benchmark:     qlib.FakeBenchmark,
+	}
 }
 
-func (m *MockBacktestEngine) RunBacktest(config qlib.BacktestConfig) (*qlib.BacktestResult, error) {
-	args := m.Called(config)
-	return args.Get(0).(*qlib.BacktestResult), args.Error(1)
-}
+func TestStrategyBacktestWithFakeEngine(t *testing.T) {
+	service := NewStrategyService(nil, testutils.RequireFakeEngines(t, 3).Backtester, nil)
+	req := newFakeBacktestRequest()
+	if err := service.validateBacktestParams(req); err != nil {
+		t.Fatalf("回测参数校验失败: %v", err)
+	}
 
-func (m *MockBacktestEngine) GetBacktestProgress(taskID uint) (*qlib.BacktestProgress, error) {
-	args := m.Called(taskID)
-	return args.Get(0).(*qlib.BacktestProgress), args.Error(1)
-}
+	var progress []int
+	result, report, err := service.runBacktest(1, 101, req, func(p int, metrics map[string]float64) {
+		progress = append(progress, p)
+	})
+	if err != nil {
+		t.Fatalf("回测失败: %v", err)
+	}
+	if report == nil || len(report.Trades) == 0 || len(report.Daily) == 0 {
+		t.Fatal("假后端应执行原生回测并返回每日净值和成交记录")
+	}
+	if *result != report.Summary {
+		t.Errorf("汇总指标与回测报告不一致: %+v != %+v", *result, report.Summary)
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != 100 {
+		t.Errorf("回测进度为 %v", progress)
+	}
+	if runningJobs.cancelOwner(strategyJobOwner(1)) {
+		t.Error("回测结束后不应再登记为运行中")
+	}
 
-func (m *MockBacktestEngine) StopBacktest(taskID uint) error {
-	args := m.Called(taskID)
-	return args.Error(0)
-}
+	again, _, err := service.runBacktest(1, 102, req, nil)
+	if err != nil || *again != *result {
+		t.Errorf("相同请求应得到相同结果: %+v, %v", again, err)
+	}
 
-// MockTaskService 模拟任务服务
-type MockTaskService struct {
-	mock.Mock
+	req.StrategyType = "UnknownStrategy"
+	if err := service.validateBacktestParams(req); err == nil {
+		t.Error("不支持的策略类型应返回错误")
+	}
 }
 
-func (m *MockTaskService) CreateBacktestTask(name string, userID uint, config interface{}) (uint, error) {
-	args := m.Called(name, userID, config)
-	return uint(args.Int(0)), args.Error(1)
-}
+func TestStopBacktestCancelsFakeEngine(t *testing.T) {
+	service := NewStrategyService(nil, testutils.RequireFakeEngines(t, 3).Backtester, nil)
 
-func (m *MockTaskService) UpdateTaskProgress(taskID uint, progress int, status string) error {
-	args := m.Called(taskID, progress, status)
-	return args.Error(0)
+	// 首次推送进度时停止回测，与 StopBacktest 的取消方式相同
+	_, _, err := service.runBacktest(2, 201, newFakeBacktestRequest(), func(p int, metrics map[string]float64) {
+		runningJobs.cancelOwner(strategyJobOwner(2))
+	})
+	if err == nil {
+		t.Fatal("停止后回测应返回错误")
+	}
+	if status, reason := jobFailure(err); status != "cancelled" || reason != qlib.JobCancelled {
+		t.Errorf("停止的回测应记为取消，得到 (%s, %s): %v", status, reason, err)
+	}
 }
 
 type StrategyServiceTestSuite struct {
 	suite.Suite
-	service         *StrategyService
-	testDB          *testutils.TestDB
-	mockEngine      *MockBacktestEngine
-	mockTaskService *MockTaskService
+	service *StrategyService
+	db      *gorm.DB
 }
 
 func (suite *StrategyServiceTestSuite) SetupSuite() {
-	suite.testDB = testutils.SetupTestDB()
-	suite.mockEngine = new(MockBacktestEngine)
-	suite.mockTaskService = new(MockTaskService)
-	suite.service = NewStrategyService(suite.testDB.DB, suite.mockEngine, suite.mockTaskService)
+	suite.db = testutils.RequireTestDB(suite.T())
+	suite.service = NewStrategyService(suite.db, testutils.RequireFakeEngines(suite.T(), 3).Backtester, nil)
 }
 
-func (suite *StrategyServiceTestSuite) TearDownSuite() {
-	suite.testDB.Cleanup()
+func (suite *StrategyServiceTestSuite) SetupTest() {
+	testutils.CleanupTables(suite.db)
 }
 
-func (suite *StrategyServiceTestSuite) SetupTest() {
-	suite.testDB.CleanupTables()
-	suite.mockEngine.ExpectedCalls = nil
-	suite.mockTaskService.ExpectedCalls = nil
+// createStrategy 创建已完成回测的策略记录
+func (suite *StrategyServiceTestSuite) createStrategy(name string, sharpe float64) models.Strategy {
+	strategy := models.Strategy{
+		Name:          name,
+		Type:          "TopkDropoutStrategy",
+		Status:        "completed",
+		ConfigJSON:    `{"topk": 5, "n_drop": 1}`,
+		BacktestStart: "2022-03-01",
+		BacktestEnd:   "2022-12-30",
+		UserID:        1,
+		TotalReturn:   0.156,
+		AnnualReturn:  0.123,
+		SharpeRatio:   sharpe,
+		MaxDrawdown:   -0.08,
+		Volatility:    0.15,
+	}
+	suite.Require().NoError(suite.db.Create(&strategy).Error)
+	return strategy
+}
+
+// waitForStrategy 等待策略进入指定状态
+func (suite *StrategyServiceTestSuite) waitForStrategy(strategyID uint, status string) models.Strategy {
+	var strategy models.Strategy
+	suite.Eventually(func() bool {
+		return suite.db.First(&strategy, strategyID).Error == nil && strategy.Status == status
+	}, 30*time.Second, 20*time.Millisecond, "策略应进入 %s 状态", status)
+	return strategy
 }
 
 func (suite *StrategyServiceTestSuite) TestStartBacktest() {
 	userID := uint(1)
-	taskID := uint(123)
 
 	// 创建测试模型
 	model := models.Model{
 		Name:   "测试模型",
-		Type:   "lightgbm",
+		Type:   "LightGBM",
 		Status: "completed",
 		UserID: userID,
 		TestIC: 0.045,
 	}
-	suite.testDB.DB.Create(&model)
-
-	req := StrategyBacktestRequest{
-		Name:        "测试策略回测",
-		Type:        "top_k",
-		ModelID:     model.ID,
-		StartDate:   "2023-01-01",
-		EndDate:     "2023-12-31",
-		Benchmark:   "HS300",
-		TopK:        50,
-		Rebalance:   "M", // 月度调仓
-		Commission:  0.003,
-		Parameters: map[string]interface{}{
-			"universe": "CSI300",
-			"factors":  []string{"momentum", "value"},
-		},
-	}
+	suite.db.Create(&model)
 
-	// 设置模拟期望
-	suite.mockTaskService.On("CreateBacktestTask", req.Name, userID, mock.Anything).Return(int(taskID), nil)
-	suite.mockEngine.On("RunBacktest", mock.Anything).Return(&qlib.BacktestResult{
-		TaskID:      taskID,
-		Status:      "running",
-		Progress:    0,
-		TotalReturn: 0,
-	}, nil)
+	req := newFakeBacktestRequest()
+	req.ModelID = model.ID
 
 	response, err := suite.service.StartBacktest(req, userID)
 
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), response)
-	assert.Equal(suite.T(), taskID, response.TaskID)
-	assert.Equal(suite.T(), "submitted", response.Status)
-	assert.Equal(suite.T(), req.Name, response.StrategyName)
+	assert.Greater(suite.T(), response.TaskID, uint(0))
+	assert.Equal(suite.T(), "started", response.Status)
 	assert.Greater(suite.T(), response.StrategyID, uint(0))
 
-	// 验证策略记录已创建
-	var strategy models.Strategy
-	err = suite.testDB.DB.First(&strategy, response.StrategyID).Error
-	assert.NoError(suite.T(), err)
+	// 验证策略记录已创建，假后端回测完成后保存汇总指标和每日净值
+	strategy := suite.waitForStrategy(response.StrategyID, "completed")
 	assert.Equal(suite.T(), req.Name, strategy.Name)
-	assert.Equal(suite.T(), req.Type, strategy.Type)
+	assert.Equal(suite.T(), req.StrategyType, strategy.Type)
 	assert.Equal(suite.T(), userID, strategy.UserID)
+	assert.NotZero(suite.T(), strategy.SharpeRatio)
 
-	suite.mockTaskService.AssertExpectations(suite.T())
-	suite.mockEngine.AssertExpectations(suite.T())
+	var values int64
+	suite.db.Model(&models.BacktestPortfolioValue{}).Where("strategy_id = ?", strategy.ID).Count(&values)
+	assert.Greater(suite.T(), values, int64(0))
 }
 
 func (suite *StrategyServiceTestSuite) TestStartBacktestWithInvalidModel() {
-	userID := uint(1)
+	req := newFakeBacktestRequest()
+	req.ModelID = 999 // 不存在的模型ID
 
-	req := StrategyBacktestRequest{
-		Name:      "测试策略回测",
-		Type:      "top_k",
-		ModelID:   999, // 不存在的模型ID
-		StartDate: "2023-01-01",
-		EndDate:   "2023-12-31",
-	}
-
-	response, err := suite.service.StartBacktest(req, userID)
+	response, err := suite.service.StartBacktest(req, 1)
 
 	assert.Error(suite.T(), err)
 	assert.Nil(suite.T(), response)
@@ -155,241 +173,168 @@ func (suite *StrategyServiceTestSuite) TestGetStrategies() {
 
 	// 创建测试策略
 	strategies := []models.Strategy{
-		{
-			Name:           "策略A",
-			Type:           "top_k",
-			Status:         "completed",
-			UserID:         userID,
-			TotalReturn:    0.156,
-			SharpeRatio:    1.45,
-			MaxDrawdown:    -0.08,
-			BacktestAt:     time.Now().AddDate(0, 0, -1),
-		},
-		{
-			Name:           "策略B",
-			Type:           "long_short",
-			Status:         "running",
-			UserID:         userID,
-			TotalReturn:    0,
-			SharpeRatio:    0,
-			MaxDrawdown:    0,
-			BacktestAt:     time.Now(),
-		},
+		{Name: "策略A", Type: "TopkDropoutStrategy", Status: "completed", UserID: userID, TotalReturn: 0.156, SharpeRatio: 1.45, MaxDrawdown: -0.08},
+		{Name: "策略B", Type: "WeightStrategyBase", Status: "backtesting", UserID: userID},
 	}
-
 	for i := range strategies {
-		suite.testDB.DB.Create(&strategies[i])
+		suite.db.Create(&strategies[i])
 	}
 
 	// 测试获取所有策略
-	result, err := suite.service.GetStrategies(userID, 1, 10, "", "")
+	result, err := suite.service.GetStrategies(1, 10, "", "", userID)
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), result)
 	assert.Equal(suite.T(), int64(2), result.Total)
-	assert.Len(suite.T(), result.Strategies, 2)
+	assert.Len(suite.T(), result.Data, 2)
 
 	// 测试按状态筛选
-	result, err = suite.service.GetStrategies(userID, 1, 10, "completed", "")
+	result, err = suite.service.GetStrategies(1, 10, "completed", "", userID)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), int64(1), result.Total)
-	assert.Len(suite.T(), result.Strategies, 1)
-	assert.Equal(suite.T(), "策略A", result.Strategies[0].Name)
+	assert.Len(suite.T(), result.Data, 1)
+	assert.Equal(suite.T(), "策略A", result.Data[0].Name)
 
 	// 测试按类型筛选
-	result, err = suite.service.GetStrategies(userID, 1, 10, "", "top_k")
+	result, err = suite.service.GetStrategies(1, 10, "", "TopkDropoutStrategy", userID)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), int64(1), result.Total)
-	assert.Len(suite.T(), result.Strategies, 1)
-	assert.Equal(suite.T(), "策略A", result.Strategies[0].Name)
+	assert.Len(suite.T(), result.Data, 1)
+	assert.Equal(suite.T(), "策略A", result.Data[0].Name)
 }
 
 func (suite *StrategyServiceTestSuite) TestGetBacktestResults() {
-	userID := uint(1)
+	strategy := suite.createStrategy("测试策略", 1.45)
 
-	// 创建测试策略
-	strategy := models.Strategy{
-		Name:           "测试策略",
-		Type:           "top_k",
-		Status:         "completed",
-		UserID:         userID,
-		TotalReturn:    0.156,
-		AnnualReturn:   0.123,
-		SharpeRatio:    1.45,
-		MaxDrawdown:    -0.08,
-		VolatilityAnnual: 0.15,
-		BacktestAt:     time.Now(),
-	}
-	suite.testDB.DB.Create(&strategy)
-
-	results, err := suite.service.GetBacktestResults(strategy.ID, userID)
+	results, err := suite.service.GetBacktestResults(strategy.ID, 1)
 
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), results)
 	assert.Equal(suite.T(), strategy.ID, results.StrategyID)
 	assert.Equal(suite.T(), strategy.Name, results.StrategyName)
-	assert.Equal(suite.T(), strategy.TotalReturn, results.TotalReturn)
-	assert.Equal(suite.T(), strategy.SharpeRatio, results.SharpeRatio)
-	assert.NotNil(suite.T(), results.PerformanceMetrics)
+	assert.Equal(suite.T(), strategy.TotalReturn, results.BasicMetrics["total_return"])
+	assert.Equal(suite.T(), strategy.SharpeRatio, results.BasicMetrics["sharpe_ratio"])
+	assert.NotNil(suite.T(), results.PerformanceData)
 	assert.NotNil(suite.T(), results.RiskMetrics)
+
+	// 未完成回测的策略没有结果
+	suite.db.Model(&strategy).Update("status", "backtesting")
+	_, err = suite.service.GetBacktestResults(strategy.ID, 1)
+	assert.Error(suite.T(), err)
 }
 
 func (suite *StrategyServiceTestSuite) TestGetBacktestProgress() {
-	taskID := uint(123)
-
-	suite.mockEngine.On("GetBacktestProgress", taskID).Return(&qlib.BacktestProgress{
-		TaskID:      taskID,
-		Status:      "running",
-		Progress:    65,
-		CurrentStep: "计算组合权重中...",
-		ETA:         time.Now().Add(5 * time.Minute),
-	}, nil)
+	strategy := suite.createStrategy("进度测试策略", 1.2)
+	suite.db.Model(&strategy).Updates(map[string]interface{}{"status": "backtesting", "progress": 65})
 
-	progress, err := suite.service.GetBacktestProgress(taskID)
+	progress, err := suite.service.GetBacktestProgress(strategy.ID, 1)
 
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), progress)
-	assert.Equal(suite.T(), taskID, progress.TaskID)
-	assert.Equal(suite.T(), "running", progress.Status)
+	assert.Equal(suite.T(), strategy.ID, progress.StrategyID)
+	assert.Equal(suite.T(), "backtesting", progress.Status)
 	assert.Equal(suite.T(), 65, progress.Progress)
-	assert.Equal(suite.T(), "计算组合权重中...", progress.CurrentStep)
+	assert.NotEmpty(suite.T(), progress.CurrentStep)
 
-	suite.mockEngine.AssertExpectations(suite.T())
+	_, err = suite.service.GetBacktestProgress(strategy.ID+1000, 1)
+	assert.Error(suite.T(), err)
 }
 
 func (suite *StrategyServiceTestSuite) TestStopBacktest() {
-	taskID := uint(123)
+	strategy := suite.createStrategy("停止测试策略", 1.2)
 
-	suite.mockEngine.On("StopBacktest", taskID).Return(nil)
+	// 已完成的回测不能停止
+	assert.Error(suite.T(), suite.service.StopBacktest(strategy.ID, 1))
 
-	err := suite.service.StopBacktest(taskID)
+	suite.db.Model(&strategy).Update("status", "backtesting")
+	err := suite.service.StopBacktest(strategy.ID, 1)
 
 	assert.NoError(suite.T(), err)
-	suite.mockEngine.AssertExpectations(suite.T())
+	var stopped models.Strategy
+	suite.db.First(&stopped, strategy.ID)
+	assert.Equal(suite.T(), "cancelled", stopped.Status)
 }
 
-func (suite *StrategyServiceTestSuite) TestGetStrategyAttribution() {
-	userID := uint(1)
-	strategyID := uint(456)
-
-	// 创建测试策略
-	strategy := models.Strategy{
-		Name:        "归因测试策略",
-		Type:        "top_k",
-		Status:      "completed",
-		UserID:      userID,
-		TotalReturn: 0.156,
-		SharpeRatio: 1.45,
-	}
-	strategy.ID = strategyID
-	suite.testDB.DB.Create(&strategy)
+func (suite *StrategyServiceTestSuite) TestGetAttributionAnalysis() {
+	strategy := suite.createStrategy("归因测试策略", 1.45)
 
-	attribution, err := suite.service.GetStrategyAttribution(strategyID, userID)
+	attribution, err := suite.service.GetAttributionAnalysis(strategy.ID, 1)
 
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), attribution)
-	assert.Equal(suite.T(), strategyID, attribution.StrategyID)
-	assert.NotNil(suite.T(), attribution.FactorAttribution)
+	assert.Equal(suite.T(), strategy.ID, attribution.StrategyID)
 	assert.NotNil(suite.T(), attribution.SectorAttribution)
-	assert.NotNil(suite.T(), attribution.StyleAttribution)
-	assert.Greater(suite.T(), len(attribution.TopHoldings), 0)
+	assert.NotNil(suite.T(), attribution.SecuritySelection)
+
+	_, err = suite.service.GetAttributionAnalysis(strategy.ID+1000, 1)
+	assert.Error(suite.T(), err)
 }
 
 func (suite *StrategyServiceTestSuite) TestCompareStrategies() {
-	userID := uint(1)
-
-	// 创建测试策略
 	strategies := []models.Strategy{
-		{
-			Name:           "策略A",
-			Type:           "top_k",
-			Status:         "completed",
-			UserID:         userID,
-			TotalReturn:    0.156,
-			SharpeRatio:    1.45,
-			MaxDrawdown:    -0.08,
-		},
-		{
-			Name:           "策略B",
-			Type:           "long_short",
-			Status:         "completed",
-			UserID:         userID,
-			TotalReturn:    0.189,
-			SharpeRatio:    1.62,
-			MaxDrawdown:    -0.12,
-		},
-	}
-
-	for i := range strategies {
-		suite.testDB.DB.Create(&strategies[i])
+		suite.createStrategy("策略A", 1.45),
+		suite.createStrategy("策略B", 1.62),
 	}
 
 	req := StrategyComparisonRequest{
 		StrategyIDs: []uint{strategies[0].ID, strategies[1].ID},
 		Metrics:     []string{"return", "sharpe", "drawdown"},
-		Benchmark:   "HS300",
-		Period:      "all",
 	}
 
-	comparison, err := suite.service.CompareStrategies(req, userID)
+	comparison, err := suite.service.CompareStrategies(req, 1)
 
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), comparison)
 	assert.Len(suite.T(), comparison.Strategies, 2)
-	assert.Equal(suite.T(), "策略A", comparison.Strategies[0].StrategyName)
-	assert.Equal(suite.T(), "策略B", comparison.Strategies[1].StrategyName)
-	assert.NotNil(suite.T(), comparison.ComparisonMetrics)
-	assert.NotEmpty(suite.T(), comparison.Summary)
+	assert.Len(suite.T(), comparison.ComparisonMatrix, 2)
+	assert.NotEmpty(suite.T(), comparison.BestStrategy)
+
+	_, err = suite.service.CompareStrategies(StrategyComparisonRequest{StrategyIDs: []uint{strategies[0].ID}}, 1)
+	assert.Error(suite.T(), err)
 }
 
 func (suite *StrategyServiceTestSuite) TestOptimizeStrategy() {
-	userID := uint(1)
-	strategyID := uint(456)
-
-	// 创建测试策略
-	strategy := models.Strategy{
-		Name:   "优化测试策略",
-		Type:   "top_k",
-		Status: "completed",
-		UserID: userID,
-	}
-	strategy.ID = strategyID
-	suite.testDB.DB.Create(&strategy)
+	strategy := suite.createStrategy("优化测试策略", 1.2)
 
 	req := StrategyOptimizationRequest{
-		StrategyID: strategyID,
-		Parameters: []OptimizationParameter{
-			{
-				Name:   "top_k",
-				Type:   "int",
-				Min:    20,
-				Max:    100,
-				Step:   10,
-				Current: 50,
-			},
-			{
-				Name:   "rebalance_freq",
-				Type:   "string",
-				Options: []interface{}{"W", "M", "Q"},
-				Current: "M",
-			},
+		ParameterRanges: map[string]interface{}{
+			"topk": []interface{}{3, 5},
 		},
-		Objective:    "sharpe_ratio",
-		MaxTrials:    10,
-		Method:       "grid_search",
+		OptimizationMethod: "grid",
+		TargetMetric:       "sharpe_ratio",
+		Benchmark:          qlib.FakeBenchmark,
 	}
 
-	taskID, err := suite.service.OptimizeStrategy(req, userID)
+	response, err := suite.service.OptimizeStrategy(strategy.ID, req, 1)
 
 	assert.NoError(suite.T(), err)
-	assert.NotEmpty(suite.T(), taskID)
-	assert.Contains(suite.T(), taskID, "strategy_optimization_")
+	assert.NotNil(suite.T(), response)
+	assert.Equal(suite.T(), "started", response.Status)
+
+	// 网格搜索的每个组合执行一次假后端回测并保存为试验记录
+	var task models.Task
+	suite.Eventually(func() bool {
+		return suite.db.First(&task, response.TaskID).Error == nil && task.Status == "completed"
+	}, 30*time.Second, 20*time.Millisecond)
+	trials, err := suite.service.GetOptimizationTrials(response.TaskID, 1)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), trials, 2)
+
+	_, err = suite.service.ResumeOptimization(response.TaskID, 1)
+	assert.Error(suite.T(), err, "已完成的优化任务不能恢复")
+
+	req.OptimizationMethod = "unknown"
+	_, err = suite.service.OptimizeStrategy(strategy.ID, req, 1)
+	assert.Error(suite.T(), err)
 }
 
 func (suite *StrategyServiceTestSuite) TestExportBacktestReport() {
-	userID := uint(1)
+	strategies := []models.Strategy{
+		suite.createStrategy("报告策略A", 1.2),
+		suite.createStrategy("报告策略B", 1.4),
+	}
 
-	req := BacktestReportExportRequest{
-		StrategyIDs:   []uint{1, 2, 3},
+	req := models.BacktestReportExportRequestExtended{
+		ResultIDs:     []uint{strategies[0].ID, strategies[1].ID},
 		ReportType:    "comprehensive",
 		Format:        "pdf",
 		Language:      "zh-CN",
@@ -400,16 +345,20 @@ func (suite *StrategyServiceTestSuite) TestExportBacktestReport() {
 			"risk_analysis",
 			"attribution",
 		},
-		Benchmark: "HS300",
 	}
 
-	taskID, err := suite.service.ExportBacktestReport(req, userID)
+	report, err := suite.service.ExportBacktestReport(req, 1)
 
 	assert.NoError(suite.T(), err)
-	assert.NotEmpty(suite.T(), taskID)
-	assert.Contains(suite.T(), taskID, "backtest_report_")
+	assert.NotNil(suite.T(), report)
+	assert.NotEmpty(suite.T(), report.ReportID)
+	assert.Equal(suite.T(), "pdf", report.Format)
+
+	req.ResultIDs = append(req.ResultIDs, strategies[1].ID+1000)
+	_, err = suite.service.ExportBacktestReport(req, 1)
+	assert.Error(suite.T(), err)
 }
 
 func TestStrategyServiceTestSuite(t *testing.T) {
 	suite.Run(t, new(StrategyServiceTestSuite))
-}
\ No newline at end of file
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	"qlib-backend/internal/qlib"
+)
+
+// TaskEvents 全局任务事件分发器
+//
+// /ws/task/:task_id 推送状态、进度、指标和产出文件，/ws/logs/:task_id 推送日志。
+// 长时间训练会持续输出日志，回放时只保留最近的日志和最新的状态、指标。
+var TaskEvents = newTaskEventStreams()
+
+// taskLogReplayLines 任务事件流保留供回放的日志行数
+const taskLogReplayLines = 500
+
+// 任务事件名称
+const (
+	TaskEventStatus   = "task_status"
+	TaskEventMetric   = "task_metric"
+	TaskEventArtifact = "task_artifact"
+	TaskEventLog      = "log_message"
+)
+
+func newTaskEventStreams() *EventStreams {
+	streams := NewEventStreams()
+	streams.SetReplayLimit(TaskEventStatus, 1)
+	streams.SetReplayLimit(TaskEventMetric, 1)
+	streams.SetReplayLimit(TaskEventLog, taskLogReplayLines)
+	return streams
+}
+
+func taskStreamID(taskID uint) string {
+	return strconv.FormatUint(uint64(taskID), 10)
+}
+
+// startTaskEvents 登记任务事件流并推送运行状态
+func startTaskEvents(taskID uint, message string) {
+	TaskEvents.Start(taskStreamID(taskID))
+	publishTaskStatus(taskID, "running", 0, message)
+}
+
+// finishTaskEvents 推送任务的最终状态并结束事件流
+func finishTaskEvents(taskID uint, status string, progress int, message string) {
+	publishTaskStatus(taskID, status, progress, message)
+	TaskEvents.Finish(taskStreamID(taskID))
+}
+
+func publishTaskStatus(taskID uint, status string, progress int, message string) {
+	TaskEvents.Publish(taskStreamID(taskID), TaskEventStatus, map[string]interface{}{
+		"task_id":   taskID,
+		"status":    status,
+		"progress":  progress,
+		"message":   message,
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// pythonTaskEvents 将Python作业发送的事件转发到任务事件流，进度同时写入任务进度通道
+//
+// progressCh 为 nil 时只推送事件；通道已满时丢弃中间进度，避免阻塞脚本。
+func pythonTaskEvents(taskID uint, progressCh chan<- TaskProgress) qlib.PythonEventHandler {
+	id := taskStreamID(taskID)
+	progress, line := 0, 0
+	return func(event qlib.PythonEvent) {
+		timestamp := event.Time.Format(time.RFC3339)
+		switch event.Type {
+		case qlib.PythonEventProgress, qlib.PythonEventMetric:
+			if event.Type == qlib.PythonEventProgress || event.Progress > 0 {
+				progress = event.Progress
+			}
+			details := make(map[string]interface{}, len(event.Metrics))
+			for name, value := range event.Metrics {
+				details[name] = value
+			}
+			if event.Type == qlib.PythonEventProgress {
+				TaskEvents.Publish(id, TaskEventStatus, map[string]interface{}{
+					"task_id":   taskID,
+					"status":    "running",
+					"progress":  progress,
+					"message":   event.Message,
+					"timestamp": timestamp,
+				})
+			} else {
+				TaskEvents.Publish(id, TaskEventMetric, map[string]interface{}{
+					"task_id":   taskID,
+					"progress":  progress,
+					"metrics":   event.Metrics,
+					"timestamp": timestamp,
+				})
+			}
+			if progressCh != nil {
+				select {
+				case progressCh <- TaskProgress{TaskID: taskID, Progress: progress, Message: event.Message, Details: details}:
+				default:
+				}
+			}
+		case qlib.PythonEventArtifact:
+			TaskEvents.Publish(id, TaskEventArtifact, map[string]interface{}{
+				"task_id":   taskID,
+				"name":      event.Name,
+				"path":      event.Path,
+				"timestamp": timestamp,
+			})
+		case qlib.PythonEventLog, qlib.PythonEventError:
+			level := event.Level
+			if event.Type == qlib.PythonEventError {
+				level = "ERROR"
+			} else if level == "" {
+				level = "INFO"
+			}
+			line++
+			TaskEvents.Publish(id, TaskEventLog, map[string]interface{}{
+				"task_id":   taskID,
+				"timestamp": timestamp,
+				"level":     level,
+				"message":   event.Message,
+				"line":      line,
+			})
+		}
+	}
+}
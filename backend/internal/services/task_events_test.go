@@ -0,0 +1,88 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"qlib-backend/internal/qlib"
+)
+
+func TestPythonTaskEvents(t *testing.T) {
+	const taskID = 9001
+	startTaskEvents(taskID, "start")
+	progressCh := make(chan TaskProgress, 1)
+	handler := pythonTaskEvents(taskID, progressCh)
+
+	now := time.Now()
+	handler(qlib.PythonEvent{Type: qlib.PythonEventProgress, Progress: 40, Message: "epoch 4", Time: now})
+	handler(qlib.PythonEvent{Type: qlib.PythonEventMetric, Metrics: map[string]float64{"valid_ic": 0.05}, Time: now})
+	handler(qlib.PythonEvent{Type: qlib.PythonEventLog, Message: "hello", Time: now})
+	handler(qlib.PythonEvent{Type: qlib.PythonEventError, Message: "boom", Time: now})
+	handler(qlib.PythonEvent{Type: qlib.PythonEventArtifact, Name: "model", Path: "/tmp/m.pkl", Time: now})
+	finishTaskEvents(taskID, "completed", 100, "done")
+
+	// 进度通道已满时丢弃后续进度，不阻塞
+	if progress := <-progressCh; progress.Progress != 40 || progress.TaskID != taskID {
+		t.Errorf("进度不符合预期: %+v", progress)
+	}
+
+	events, cancel, ok := TaskEvents.Subscribe(taskStreamID(taskID))
+	if !ok {
+		t.Fatal("任务事件流应可回放")
+	}
+	defer cancel()
+	var names []string
+	var logs []map[string]interface{}
+	for event := range events {
+		names = append(names, event.Event)
+		if event.Event == TaskEventLog {
+			logs = append(logs, event.Data)
+		}
+	}
+	// 回放时状态和指标只保留最新一条
+	want := []string{TaskEventMetric, TaskEventLog, TaskEventLog, TaskEventArtifact, TaskEventStatus}
+	if len(names) != len(want) {
+		t.Fatalf("事件为 %v，应为 %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("事件为 %v，应为 %v", names, want)
+		}
+	}
+	if logs[0]["level"] != "INFO" || logs[1]["level"] != "ERROR" || logs[1]["line"] != 2 {
+		t.Errorf("日志事件不符合预期: %v", logs)
+	}
+}
+
+func TestPythonTaskEventsReplayIsBounded(t *testing.T) {
+	const taskID = 9002
+	startTaskEvents(taskID, "start")
+	handler := pythonTaskEvents(taskID, nil)
+	now := time.Now()
+	for i := 0; i < taskLogReplayLines+100; i++ {
+		handler(qlib.PythonEvent{Type: qlib.PythonEventLog, Message: "epoch", Time: now})
+		handler(qlib.PythonEvent{Type: qlib.PythonEventProgress, Progress: i % 100, Time: now})
+	}
+	finishTaskEvents(taskID, "completed", 100, "done")
+
+	events, _, ok := TaskEvents.Subscribe(taskStreamID(taskID))
+	if !ok {
+		t.Fatal("任务事件流应可回放")
+	}
+	var logs []map[string]interface{}
+	statuses := 0
+	for event := range events {
+		switch event.Event {
+		case TaskEventLog:
+			logs = append(logs, event.Data)
+		case TaskEventStatus:
+			statuses++
+		}
+	}
+	if len(logs) != taskLogReplayLines || statuses != 1 {
+		t.Fatalf("回放了 %d 行日志、%d 条状态，应为 %d 行、1 条", len(logs), statuses, taskLogReplayLines)
+	}
+	if logs[0]["line"] != 101 || logs[len(logs)-1]["line"] != taskLogReplayLines+100 {
+		t.Errorf("应保留最近的日志，首行 %v，末行 %v", logs[0]["line"], logs[len(logs)-1]["line"])
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"qlib-backend/internal/qlib"
+)
+
+// DefaultJobTimeout 模型训练、回测等任务的执行超时，超时后Python子进程被终止，0表示不限制
+var DefaultJobTimeout = 12 * time.Hour
+
+// runningJobs 运行中任务的取消函数
+//
+// 停止训练、停止回测和取消任务都通过任务上下文的 CancelFunc 终止，Python子进程组随之被终止。
+var runningJobs = newTaskJobs()
+
+type taskJobs struct {
+	mu      sync.Mutex
+	cancels map[uint]context.CancelFunc
+	owners  map[string]uint // 模型、策略等发起方 -> 运行中的任务ID
+}
+
+func newTaskJobs() *taskJobs {
+	return &taskJobs{
+		cancels: make(map[uint]context.CancelFunc),
+		owners:  make(map[string]uint),
+	}
+}
+
+// start 为任务创建可取消、带超时的上下文并登记，返回的 finish 在任务结束后调用
+//
+// owner 为空时只能按任务ID取消。
+func (j *taskJobs) start(parent context.Context, taskID uint, owner string, timeout time.Duration) (context.Context, func()) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+
+	j.mu.Lock()
+	j.cancels[taskID] = cancel
+	if owner != "" {
+		j.owners[owner] = taskID
+	}
+	j.mu.Unlock()
+
+	return ctx, func() {
+		j.mu.Lock()
+		delete(j.cancels, taskID)
+		if owner != "" && j.owners[owner] == taskID {
+			delete(j.owners, owner)
+		}
+		j.mu.Unlock()
+		cancel()
+	}
+}
+
+// cancel 取消任务，任务不在运行时返回 false
+func (j *taskJobs) cancel(taskID uint) bool {
+	j.mu.Lock()
+	cancel, ok := j.cancels[taskID]
+	j.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// cancelOwner 取消发起方当前运行的任务
+func (j *taskJobs) cancelOwner(owner string) bool {
+	j.mu.Lock()
+	taskID, ok := j.owners[owner]
+	j.mu.Unlock()
+	return ok && j.cancel(taskID)
+}
+
+func modelJobOwner(modelID uint) string {
+	return "model:" + strconv.FormatUint(uint64(modelID), 10)
+}
+
+func strategyJobOwner(strategyID uint) string {
+	return "strategy:" + strconv.FormatUint(uint64(strategyID), 10)
+}
+
+// jobFailure 根据任务错误返回任务状态和终止原因：取消记为 cancelled，超时和内存不足记为 failed 并保留原因
+func jobFailure(err error) (status, reason string) {
+	reason = qlib.JobTermination(err)
+	if reason == qlib.JobCancelled {
+		return "cancelled", reason
+	}
+	return "failed", reason
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"qlib-backend/internal/qlib"
+)
+
+func TestTaskJobsCancel(t *testing.T) {
+	jobs := newTaskJobs()
+	ctx, finish := jobs.start(context.Background(), 1, modelJobOwner(7), 0)
+	if jobs.cancelOwner(strategyJobOwner(7)) {
+		t.Error("其他发起方不应取消任务")
+	}
+	if !jobs.cancelOwner(modelJobOwner(7)) {
+		t.Fatal("应能按发起方取消任务")
+	}
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("任务上下文应已取消: %v", ctx.Err())
+	}
+	finish()
+	if jobs.cancel(1) || jobs.cancelOwner(modelJobOwner(7)) {
+		t.Error("结束的任务不应再被取消")
+	}
+
+	// 同一发起方的新任务结束时不影响登记
+	_, finishOld := jobs.start(context.Background(), 2, modelJobOwner(8), 0)
+	newCtx, finishNew := jobs.start(context.Background(), 3, modelJobOwner(8), 0)
+	finishOld()
+	if !jobs.cancelOwner(modelJobOwner(8)) || newCtx.Err() == nil {
+		t.Error("应取消发起方最新的任务")
+	}
+	finishNew()
+
+	timeoutCtx, finishTimeout := jobs.start(context.Background(), 4, "", 10*time.Millisecond)
+	defer finishTimeout()
+	<-timeoutCtx.Done()
+	if !errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+		t.Errorf("任务应超时: %v", timeoutCtx.Err())
+	}
+}
+
+func TestJobFailure(t *testing.T) {
+	cases := []struct {
+		err            error
+		status, reason string
+	}{
+		{&qlib.JobError{Reason: qlib.JobCancelled, Err: errors.New("x")}, "cancelled", qlib.JobCancelled},
+		{&qlib.JobError{Reason: qlib.JobTimedOut, Err: errors.New("x")}, "failed", qlib.JobTimedOut},
+		{&qlib.JobError{Reason: qlib.JobOOMKilled, Err: errors.New("x")}, "failed", qlib.JobOOMKilled},
+		{context.Canceled, "cancelled", qlib.JobCancelled},
+		{errors.New("脚本错误"), "failed", ""},
+	}
+	for _, c := range cases {
+		if status, reason := jobFailure(c.err); status != c.status || reason != c.reason {
+			t.Errorf("%v: 得到 (%s, %s)，应为 (%s, %s)", c.err, status, reason, c.status, c.reason)
+		}
+	}
+}
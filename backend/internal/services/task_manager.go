@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"qlib-backend/internal/models"
+	"qlib-backend/internal/qlib"
 
 	"gorm.io/gorm"
 )
@@ -17,6 +18,7 @@ type TaskManager struct {
 	db            *gorm.DB
 	runningTasks  map[uint]*TaskContext
 	taskQueue     chan *TaskContext
+	jobQueue      chan func() // 子任务队列，由空闲的工作协程执行
 	workers       int
 	mutex         sync.RWMutex
 	ctx           context.Context
@@ -26,7 +28,8 @@ type TaskManager struct {
 // TaskContext 任务上下文
 type TaskContext struct {
 	Task        *models.Task
-	Cancel      context.CancelFunc
+	Cancel      context.CancelFunc // 取消任务上下文，执行中的Python子进程组随之终止
+	ctx         context.Context
 	ProgressCh  chan TaskProgress
 	StatusCh    chan TaskStatus
 	ErrorCh     chan error
@@ -69,6 +72,7 @@ func NewTaskManager(db *gorm.DB, workers int) *TaskManager {
 		db:           db,
 		runningTasks: make(map[uint]*TaskContext),
 		taskQueue:    make(chan *TaskContext, 100),
+		jobQueue:     make(chan func()),
 		workers:      workers,
 		ctx:          ctx,
 		cancel:       cancel,
@@ -98,10 +102,11 @@ func (tm *TaskManager) SubmitTask(task *models.Task) error {
 	}
 	
 	// 创建任务上下文
-	_, cancel := context.WithCancel(tm.ctx)
+	ctx, cancel := context.WithCancel(tm.ctx)
 	taskCtx := &TaskContext{
 		Task:       task,
 		Cancel:     cancel,
+		ctx:        ctx,
 		ProgressCh: make(chan TaskProgress, 10),
 		StatusCh:   make(chan TaskStatus, 10),
 		ErrorCh:    make(chan error, 1),
@@ -174,6 +179,8 @@ func (tm *TaskManager) GetTaskStatus(taskID uint) (*TaskStatusInfo, error) {
 		EndTime:     task.EndTime,
 		ErrorMsg:    task.ErrorMsg,
 		IsRunning:   isRunning,
+
+		TerminationReason: task.TerminationReason,
 	}
 	
 	if task.StartTime != nil {
@@ -251,12 +258,48 @@ func (tm *TaskManager) GetRunningTasks() []TaskStatusInfo {
 	return runningTasks
 }
 
+// RunJobs 将一组子任务分发给工作协程执行，同时运行的子任务数不超过 limit，全部结束后返回
+//
+// 子任务优先交给空闲的工作协程；没有空闲协程时（例如调用方本身运行在工作协程中）
+// 在新协程中执行，避免相互等待。
+func (tm *TaskManager) RunJobs(ctx context.Context, limit int, jobs []func(ctx context.Context)) error {
+	if limit <= 0 {
+		limit = tm.workers
+	}
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+		wg.Add(1)
+		job := job
+		run := func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			job(ctx)
+		}
+		select {
+		case tm.jobQueue <- run:
+		default:
+			go run()
+		}
+	}
+	wg.Wait()
+	return nil
+}
+
 // worker 工作协程
 func (tm *TaskManager) worker() {
 	for {
 		select {
 		case taskCtx := <-tm.taskQueue:
 			tm.executeTask(taskCtx)
+		case job := <-tm.jobQueue:
+			job()
 		case <-tm.ctx.Done():
 			return
 		}
@@ -282,6 +325,7 @@ func (tm *TaskManager) executeTask(taskCtx *TaskContext) {
 		Message:   "任务开始执行",
 		Timestamp: startTime,
 	}
+	startTaskEvents(task.ID, "任务开始执行")
 	
 	// 获取任务处理器
 	handler := tm.getTaskHandler(task.Type)
@@ -290,9 +334,9 @@ func (tm *TaskManager) executeTask(taskCtx *TaskContext) {
 		return
 	}
 	
-	// 创建子上下文
-	ctx, cancel := context.WithCancel(tm.ctx)
-	defer cancel()
+	// 创建带超时的子上下文，取消任务或超时时处理函数中的Python作业被终止
+	ctx, finishJob := runningJobs.start(taskCtx.ctx, task.ID, "", DefaultJobTimeout)
+	defer finishJob()
 	
 	// 执行任务
 	result, err := handler(ctx, task, taskCtx.ProgressCh)
@@ -325,6 +369,7 @@ func (tm *TaskManager) completeTaskWithSuccess(taskCtx *TaskContext, result *Tas
 		Message:   "任务成功完成",
 		Timestamp: endTime,
 	}
+	finishTaskEvents(task.ID, "completed", 100, "任务成功完成")
 	
 	taskCtx.CompleteCh <- *result
 	
@@ -335,19 +380,22 @@ func (tm *TaskManager) completeTaskWithSuccess(taskCtx *TaskContext, result *Tas
 func (tm *TaskManager) completeTaskWithError(taskCtx *TaskContext, err error) {
 	task := taskCtx.Task
 	endTime := time.Now()
+	status, reason := jobFailure(err)
 	
 	tm.db.Model(task).Updates(map[string]interface{}{
-		"status":    "failed",
-		"end_time":  endTime,
-		"error_msg": err.Error(),
+		"status":             status,
+		"termination_reason": reason,
+		"end_time":           endTime,
+		"error_msg":          err.Error(),
 	})
 	
 	taskCtx.StatusCh <- TaskStatus{
 		TaskID:    task.ID,
-		Status:    "failed",
+		Status:    status,
 		Message:   err.Error(),
 		Timestamp: endTime,
 	}
+	finishTaskEvents(task.ID, status, task.Progress, err.Error())
 	
 	taskCtx.ErrorCh <- err
 	
@@ -393,14 +441,27 @@ func (tm *TaskManager) updateTaskProgress() {
 	tm.mutex.RUnlock()
 	
 	for _, taskCtx := range tasks {
-		select {
-		case progress := <-taskCtx.ProgressCh:
-			tm.db.Model(&models.Task{}).Where("id = ?", progress.TaskID).Updates(map[string]interface{}{
-				"progress": progress.Progress,
-			})
-		default:
+		// Python作业的事件可能在两次更新之间产生多条进度，只保存最新的一条
+		var latest *TaskProgress
+	drain:
+		for {
+			select {
+			case progress, ok := <-taskCtx.ProgressCh:
+				if !ok {
+					break drain
+				}
+				latest = &progress
+			default:
+				break drain
+			}
+		}
+		if latest == nil {
 			// 没有新的进度更新
+			continue
 		}
+		tm.db.Model(&models.Task{}).Where("id = ?", latest.TaskID).Updates(map[string]interface{}{
+			"progress": latest.Progress,
+		})
 	}
 }
 
@@ -413,6 +474,7 @@ func (tm *TaskManager) getTaskHandler(taskType string) TaskHandler {
 		"data_processing":     tm.handleDataProcessing,
 		"report_generation":   tm.handleReportGeneration,
 		"workflow_execution":  tm.handleWorkflowExecution,
+		"dataset_ingestion":   tm.handleDatasetIngestion,
 	}
 	
 	return handlers[taskType]
@@ -557,6 +619,57 @@ func (tm *TaskManager) handleDataProcessing(ctx context.Context, task *models.Ta
 	}, nil
 }
 
+// handleDatasetIngestion 处理数据集导入任务：将上传的CSV转换为Qlib二进制格式并更新数据集信息
+func (tm *TaskManager) handleDatasetIngestion(ctx context.Context, task *models.Task, progressCh chan<- TaskProgress) (*TaskResult, error) {
+	var config DatasetIngestionConfig
+	if err := json.Unmarshal([]byte(task.ConfigJSON), &config); err != nil {
+		return nil, fmt.Errorf("解析导入配置失败: %v", err)
+	}
+
+	result, err := qlib.IngestCSV(ctx, config.FilePath, config.OutputDir, config.Options, func(progress int, message string) {
+		// 进度通道按固定间隔消费，通道已满时丢弃中间进度，避免阻塞导入
+		select {
+		case progressCh <- TaskProgress{TaskID: task.ID, Progress: progress, Message: message}:
+		default:
+		}
+	})
+	if err != nil {
+		tm.db.Model(&models.Dataset{}).Where("id = ?", config.DatasetID).Update("status", "error")
+		if result != nil && len(result.RowErrors) > 0 {
+			return nil, fmt.Errorf("数据导入失败: %v，首个错误: 第%d行 %s", err, result.RowErrors[0].Line, result.RowErrors[0].Message)
+		}
+		return nil, fmt.Errorf("数据导入失败: %v", err)
+	}
+
+	updates := map[string]interface{}{
+		"status":       "active",
+		"data_path":    result.OutputDir,
+		"record_count": result.RecordCount,
+		"start_date":   result.StartDate,
+		"end_date":     result.EndDate,
+	}
+	if err := tm.db.Model(&models.Dataset{}).Where("id = ?", config.DatasetID).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("更新数据集信息失败: %v", err)
+	}
+
+	return &TaskResult{
+		TaskID:  task.ID,
+		Success: true,
+		Result: map[string]interface{}{
+			"dataset_id":       config.DatasetID,
+			"output_path":      result.OutputDir,
+			"record_count":     result.RecordCount,
+			"instrument_count": result.InstrumentCount,
+			"fields":           result.Fields,
+			"start_date":       result.StartDate,
+			"end_date":         result.EndDate,
+			"error_count":      result.ErrorCount,
+			"row_errors":       result.RowErrors,
+		},
+		Duration: time.Since(*task.StartTime),
+	}, nil
+}
+
 // handleReportGeneration 处理报告生成任务
 func (tm *TaskManager) handleReportGeneration(ctx context.Context, task *models.Task, progressCh chan<- TaskProgress) (*TaskResult, error) {
 	for i := 0; i <= 100; i += 25 {
@@ -646,6 +759,8 @@ type TaskStatusInfo struct {
 	Duration    time.Duration  `json:"duration"`
 	ErrorMsg    string         `json:"error_msg"`
 	IsRunning   bool           `json:"is_running"`
+	// 终止原因：cancelled, timeout, oom_killed
+	TerminationReason string `json:"termination_reason,omitempty"`
 }
 
 type PaginatedTasks struct {
@@ -2,11 +2,15 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
 	"qlib-backend/internal/models"
 	"qlib-backend/internal/testutils"
 )
@@ -14,110 +18,159 @@ import (
 type TaskManagerTestSuite struct {
 	suite.Suite
 	manager *TaskManager
-	testDB  *testutils.TestDB
+	db      *gorm.DB
 }
 
 func (suite *TaskManagerTestSuite) SetupSuite() {
-	suite.testDB = testutils.SetupTestDB()
-	suite.manager = NewTaskManager(suite.testDB.DB, 2) // 创建2个worker的任务管理器
+	suite.db = testutils.RequireTestDB(suite.T())
+	suite.manager = NewTaskManager(suite.db, 2) // 创建2个worker的任务管理器
 }
 
 func (suite *TaskManagerTestSuite) TearDownSuite() {
-	suite.manager.Stop()
-	suite.testDB.Cleanup()
+	if suite.manager != nil {
+		suite.manager.Close()
+	}
 }
 
 func (suite *TaskManagerTestSuite) SetupTest() {
-	suite.testDB.CleanupTables()
+	testutils.CleanupTables(suite.db)
 }
 
-func (suite *TaskManagerTestSuite) TestCreateTask() {
-	userID := uint(1)
-	taskName := "测试任务"
-	taskType := "model_training"
-	config := map[string]interface{}{
-		"model_type": "lightgbm",
-		"dataset_id": 123,
+// createTask 创建待提交的任务记录
+func (suite *TaskManagerTestSuite) createTask(name, taskType string, config interface{}) *models.Task {
+	configJSON, _ := json.Marshal(config)
+	task := &models.Task{
+		Name:       name,
+		Type:       taskType,
+		Status:     "pending",
+		UserID:     1,
+		ConfigJSON: string(configJSON),
 	}
+	suite.Require().NoError(suite.db.Create(task).Error)
+	return task
+}
 
-	taskID, err := suite.manager.CreateTask(taskName, taskType, userID, config)
+// registerRunning 将任务登记为运行中，不交给工作协程执行
+func (suite *TaskManagerTestSuite) registerRunning(task *models.Task) (*TaskContext, context.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+	taskCtx := &TaskContext{
+		Task:       task,
+		Cancel:     cancel,
+		ctx:        ctx,
+		ProgressCh: make(chan TaskProgress, 10),
+		StatusCh:   make(chan TaskStatus, 10),
+		ErrorCh:    make(chan error, 1),
+		CompleteCh: make(chan TaskResult, 1),
+	}
+	suite.manager.mutex.Lock()
+	suite.manager.runningTasks[task.ID] = taskCtx
+	suite.manager.mutex.Unlock()
+	suite.T().Cleanup(func() { suite.manager.cleanupTask(task.ID) })
+	return taskCtx, ctx
+}
 
+// waitForStatus 等待任务进入指定状态
+func (suite *TaskManagerTestSuite) waitForStatus(taskID uint, status string) models.Task {
+	var task models.Task
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		suite.Require().NoError(suite.db.First(&task, taskID).Error)
+		if task.Status == status {
+			return task
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	suite.FailNowf("等待任务状态超时", "任务 %d 状态为 %s，应为 %s: %s", taskID, task.Status, status, task.ErrorMsg)
+	return task
+}
+
+func (suite *TaskManagerTestSuite) TestSubmitTask() {
+	task := suite.createTask("测试任务", "model_training", map[string]interface{}{
+		"model_type": "lightgbm",
+		"dataset_id": 123,
+	})
+	// 提交后任务进入队列，由工作协程开始执行
+	err := suite.manager.SubmitTask(task)
 	assert.NoError(suite.T(), err)
-	assert.Greater(suite.T(), taskID, uint(0))
 
-	// 验证任务已保存到数据库
-	var task models.Task
-	err = suite.testDB.DB.First(&task, taskID).Error
+	status, err := suite.manager.GetTaskStatus(task.ID)
 	assert.NoError(suite.T(), err)
-	assert.Equal(suite.T(), taskName, task.Name)
-	assert.Equal(suite.T(), taskType, task.Type)
-	assert.Equal(suite.T(), userID, task.UserID)
-	assert.Equal(suite.T(), "pending", task.Status)
-	assert.Equal(suite.T(), 0, task.Progress)
+	assert.Contains(suite.T(), []string{"queued", "running"}, status.Status)
+	assert.True(suite.T(), status.IsRunning)
+
+	assert.NoError(suite.T(), suite.manager.CancelTask(task.ID))
 }
 
 func (suite *TaskManagerTestSuite) TestGetTasks() {
 	userID := uint(1)
+	now := time.Now()
 
 	// 创建测试任务
 	tasks := []models.Task{
 		{
+			BaseModel: models.BaseModel{CreatedAt: now.AddDate(0, 0, -1)},
 			Name:      "任务A",
 			Type:      "model_training",
 			Status:    "running",
 			UserID:    userID,
 			Progress:  50,
-			CreatedAt: time.Now().AddDate(0, 0, -1),
 		},
 		{
+			BaseModel: models.BaseModel{CreatedAt: now},
 			Name:      "任务B",
-			Type:      "backtest",
+			Type:      "strategy_backtest",
 			Status:    "completed",
 			UserID:    userID,
 			Progress:  100,
-			CreatedAt: time.Now(),
 		},
 		{
+			BaseModel: models.BaseModel{CreatedAt: now.Add(-2 * time.Hour)},
 			Name:      "任务C",
 			Type:      "factor_test",
 			Status:    "failed",
 			UserID:    userID,
 			Progress:  30,
-			CreatedAt: time.Now().Add(-2 * time.Hour),
 		},
 	}
 
 	for i := range tasks {
-		suite.testDB.DB.Create(&tasks[i])
+		suite.db.Create(&tasks[i])
 	}
 
-	// 测试获取所有任务
+	// 测试获取所有任务，按创建时间倒序
 	result, err := suite.manager.GetTasks(userID, "", "", 1, 10)
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), result)
 	assert.Equal(suite.T(), int64(3), result.Total)
-	assert.Len(suite.T(), result.Tasks, 3)
+	assert.Len(suite.T(), result.Data, 3)
+	assert.Equal(suite.T(), "任务B", result.Data[0].Name)
 
 	// 测试按状态筛选
 	result, err = suite.manager.GetTasks(userID, "running", "", 1, 10)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), int64(1), result.Total)
-	assert.Len(suite.T(), result.Tasks, 1)
-	assert.Equal(suite.T(), "任务A", result.Tasks[0].Name)
+	assert.Len(suite.T(), result.Data, 1)
+	assert.Equal(suite.T(), "任务A", result.Data[0].Name)
 
 	// 测试按类型筛选
-	result, err = suite.manager.GetTasks(userID, "", "backtest", 1, 10)
+	result, err = suite.manager.GetTasks(userID, "", "strategy_backtest", 1, 10)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), int64(1), result.Total)
-	assert.Len(suite.T(), result.Tasks, 1)
-	assert.Equal(suite.T(), "任务B", result.Tasks[0].Name)
+	assert.Len(suite.T(), result.Data, 1)
+	assert.Equal(suite.T(), "任务B", result.Data[0].Name)
 
 	// 测试复合筛选
-	result, err = suite.manager.GetTasks(userID, "completed", "backtest", 1, 10)
+	result, err = suite.manager.GetTasks(userID, "completed", "strategy_backtest", 1, 10)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), int64(1), result.Total)
-	assert.Len(suite.T(), result.Tasks, 1)
-	assert.Equal(suite.T(), "任务B", result.Tasks[0].Name)
+	assert.Len(suite.T(), result.Data, 1)
+	assert.Equal(suite.T(), "任务B", result.Data[0].Name)
+
+	// 测试分页
+	result, err = suite.manager.GetTasks(userID, "", "", 2, 2)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), result.Data, 1)
+	assert.Equal(suite.T(), int64(2), result.TotalPages)
 }
 
 func (suite *TaskManagerTestSuite) TestGetTaskStatus() {
@@ -129,7 +182,7 @@ func (suite *TaskManagerTestSuite) TestGetTaskStatus() {
 		UserID:   1,
 		Progress: 75,
 	}
-	suite.testDB.DB.Create(&task)
+	suite.db.Create(&task)
 
 	status, err := suite.manager.GetTaskStatus(task.ID)
 
@@ -138,83 +191,47 @@ func (suite *TaskManagerTestSuite) TestGetTaskStatus() {
 	assert.Equal(suite.T(), task.ID, status.TaskID)
 	assert.Equal(suite.T(), "running", status.Status)
 	assert.Equal(suite.T(), 75, status.Progress)
-}
+	assert.False(suite.T(), status.IsRunning)
 
-func (suite *TaskManagerTestSuite) TestUpdateTaskStatus() {
-	// 创建测试任务
-	task := models.Task{
-		Name:     "更新测试任务",
-		Type:     "model_training",
-		Status:   "running",
-		UserID:   1,
-		Progress: 30,
-	}
-	suite.testDB.DB.Create(&task)
-
-	// 更新任务状态
-	result := map[string]interface{}{
-		"model_id": 456,
-		"accuracy": 0.85,
-	}
-	err := suite.manager.UpdateTaskStatus(task.ID, "completed", 100, result)
-
-	assert.NoError(suite.T(), err)
-
-	// 验证更新
-	var updatedTask models.Task
-	err = suite.testDB.DB.First(&updatedTask, task.ID).Error
-	assert.NoError(suite.T(), err)
-	assert.Equal(suite.T(), "completed", updatedTask.Status)
-	assert.Equal(suite.T(), 100, updatedTask.Progress)
-	assert.NotEmpty(suite.T(), updatedTask.Result)
-	assert.NotNil(suite.T(), updatedTask.CompletedAt)
+	_, err = suite.manager.GetTaskStatus(task.ID + 1000)
+	assert.Error(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "任务不存在")
 }
 
 func (suite *TaskManagerTestSuite) TestUpdateTaskProgress() {
 	// 创建测试任务
-	task := models.Task{
-		Name:     "进度测试任务",
-		Type:     "model_training",
-		Status:   "running",
-		UserID:   1,
-		Progress: 30,
-	}
-	suite.testDB.DB.Create(&task)
+	task := suite.createTask("进度测试任务", "model_training", nil)
+	taskCtx, _ := suite.registerRunning(task)
 
-	// 更新进度
-	err := suite.manager.UpdateTaskProgress(task.ID, 65, "训练中...")
-
-	assert.NoError(suite.T(), err)
+	// 两次更新之间的多条进度只保存最新的一条
+	taskCtx.ProgressCh <- TaskProgress{TaskID: task.ID, Progress: 30, Message: "训练中..."}
+	taskCtx.ProgressCh <- TaskProgress{TaskID: task.ID, Progress: 65, Message: "训练中..."}
+	suite.manager.updateTaskProgress()
 
 	// 验证更新
 	var updatedTask models.Task
-	err = suite.testDB.DB.First(&updatedTask, task.ID).Error
+	err := suite.db.First(&updatedTask, task.ID).Error
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), 65, updatedTask.Progress)
-	assert.Equal(suite.T(), "训练中...", updatedTask.Message)
 }
 
 func (suite *TaskManagerTestSuite) TestCancelTask() {
 	// 创建测试任务
-	task := models.Task{
-		Name:     "取消测试任务",
-		Type:     "model_training",
-		Status:   "running",
-		UserID:   1,
-		Progress: 45,
-	}
-	suite.testDB.DB.Create(&task)
+	task := suite.createTask("取消测试任务", "model_training", nil)
+	_, ctx := suite.registerRunning(task)
 
 	err := suite.manager.CancelTask(task.ID)
 
 	assert.NoError(suite.T(), err)
+	assert.Error(suite.T(), ctx.Err(), "取消后任务上下文应结束")
 
 	// 验证任务状态已更新
 	var cancelledTask models.Task
-	err = suite.testDB.DB.First(&cancelledTask, task.ID).Error
+	err = suite.db.First(&cancelledTask, task.ID).Error
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), "cancelled", cancelledTask.Status)
-	assert.NotNil(suite.T(), cancelledTask.CompletedAt)
+	assert.NotNil(suite.T(), cancelledTask.EndTime)
+	assert.Empty(suite.T(), suite.manager.GetRunningTasks())
 }
 
 func (suite *TaskManagerTestSuite) TestCancelNonExistentTask() {
@@ -225,138 +242,75 @@ func (suite *TaskManagerTestSuite) TestCancelNonExistentTask() {
 }
 
 func (suite *TaskManagerTestSuite) TestSubmitTaskForExecution() {
-	userID := uint(1)
-	taskName := "执行测试任务"
-	taskType := "test_execution"
-	config := map[string]interface{}{
-		"test_param": "test_value",
-	}
-
-	taskID, err := suite.manager.CreateTask(taskName, taskType, userID, config)
-	assert.NoError(suite.T(), err)
-
-	// 提交任务执行
-	err = suite.manager.SubmitTask(taskID, func(ctx context.Context, task *models.Task) error {
-		// 模拟任务执行
-		for i := 0; i <= 100; i += 20 {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-				// 更新进度
-				suite.manager.UpdateTaskProgress(task.ID, i, fmt.Sprintf("进度 %d%%", i))
-				time.Sleep(10 * time.Millisecond) // 模拟处理时间
-			}
-		}
-		return nil
+	dir := suite.T().TempDir()
+	csvPath := filepath.Join(dir, "prices.csv")
+	suite.Require().NoError(os.WriteFile(csvPath, []byte(
+		"date,symbol,open,high,low,close,volume\n"+
+			"2023-01-03,SH600000,10,10.5,9.8,10.2,1000\n"+
+			"2023-01-04,SH600000,10.2,10.6,10,10.4,1200\n"), 0644))
+
+	dataset := models.Dataset{Name: "导入测试数据集", DataPath: csvPath, Status: "processing", UserID: 1}
+	suite.db.Create(&dataset)
+
+	task := suite.createTask("执行测试任务", "dataset_ingestion", DatasetIngestionConfig{
+		DatasetID: dataset.ID,
+		FilePath:  csvPath,
+		OutputDir: filepath.Join(dir, "qlib_data"),
 	})
 
+	// 提交任务执行
+	err := suite.manager.SubmitTask(task)
 	assert.NoError(suite.T(), err)
 
-	// 等待任务完成
-	time.Sleep(200 * time.Millisecond)
-
 	// 验证任务状态
-	var completedTask models.Task
-	err = suite.testDB.DB.First(&completedTask, taskID).Error
-	assert.NoError(suite.T(), err)
-	assert.Equal(suite.T(), "completed", completedTask.Status)
+	completedTask := suite.waitForStatus(task.ID, "completed")
 	assert.Equal(suite.T(), 100, completedTask.Progress)
-}
-
-func (suite *TaskManagerTestSuite) TestGetRunningTasks() {
-	runningTasks := suite.manager.GetRunningTasks()
+	assert.NotNil(suite.T(), completedTask.EndTime)
+	assert.Contains(suite.T(), completedTask.ResultJSON, "record_count")
 
-	assert.NotNil(suite.T(), runningTasks)
-	assert.IsType(suite.T(), map[uint]*TaskContext{}, runningTasks)
+	var updated models.Dataset
+	suite.db.First(&updated, dataset.ID)
+	assert.Equal(suite.T(), "active", updated.Status)
+	assert.Equal(suite.T(), int64(2), updated.RecordCount)
 }
 
-func (suite *TaskManagerTestSuite) TestTaskTimeout() {
-	userID := uint(1)
-	taskName := "超时测试任务"
-	taskType := "timeout_test"
-	config := map[string]interface{}{
-		"timeout": 100, // 100ms超时
-	}
+func (suite *TaskManagerTestSuite) TestUnsupportedTaskType() {
+	task := suite.createTask("不支持的任务", "unknown_type", nil)
 
-	taskID, err := suite.manager.CreateTask(taskName, taskType, userID, config)
+	err := suite.manager.SubmitTask(task)
 	assert.NoError(suite.T(), err)
 
-	// 提交一个会超时的任务
-	err = suite.manager.SubmitTask(taskID, func(ctx context.Context, task *models.Task) error {
-		// 模拟长时间运行的任务
-		time.Sleep(500 * time.Millisecond)
-		return nil
-	})
+	failedTask := suite.waitForStatus(task.ID, "failed")
+	assert.Contains(suite.T(), failedTask.ErrorMsg, "不支持的任务类型")
+}
 
-	assert.NoError(suite.T(), err)
+func (suite *TaskManagerTestSuite) TestGetRunningTasks() {
+	task := suite.createTask("运行中任务", "model_training", nil)
+	suite.registerRunning(task)
 
-	// 等待超时处理
-	time.Sleep(200 * time.Millisecond)
+	runningTasks := suite.manager.GetRunningTasks()
 
-	// 验证任务状态 - 应该仍在运行或已完成（取决于实现）
-	var task models.Task
-	err = suite.testDB.DB.First(&task, taskID).Error
-	assert.NoError(suite.T(), err)
-	// 注意：具体的超时处理取决于TaskManager的实现
+	assert.Len(suite.T(), runningTasks, 1)
+	assert.Equal(suite.T(), task.ID, runningTasks[0].TaskID)
+	assert.True(suite.T(), runningTasks[0].IsRunning)
 }
 
-func (suite *TaskManagerTestSuite) TestDeleteExpiredTasks() {
-	userID := uint(1)
-
-	// 创建一个旧任务
-	oldTask := models.Task{
-		Name:      "过期任务",
-		Type:      "expired_test",
-		Status:    "completed",
-		UserID:    userID,
-		Progress:  100,
-		CreatedAt: time.Now().AddDate(0, 0, -31), // 31天前
-	}
-	oldTask.CompletedAt = &time.Time{}
-	*oldTask.CompletedAt = time.Now().AddDate(0, 0, -30) // 30天前完成
-	suite.testDB.DB.Create(&oldTask)
-
-	// 创建一个新任务
-	newTask := models.Task{
-		Name:      "新任务",
-		Type:      "new_test",
-		Status:    "completed",
-		UserID:    userID,
-		Progress:  100,
-		CreatedAt: time.Now().AddDate(0, 0, -1), // 1天前
-	}
-	newTask.CompletedAt = &time.Time{}
-	*newTask.CompletedAt = time.Now().AddDate(0, 0, -1) // 1天前完成
-	suite.testDB.DB.Create(&newTask)
+func (suite *TaskManagerTestSuite) TestTaskTimeout() {
+	previous := DefaultJobTimeout
+	DefaultJobTimeout = 50 * time.Millisecond
+	defer func() { DefaultJobTimeout = previous }()
 
-	// 删除过期任务（假设保留期为7天）
-	count, err := suite.manager.DeleteExpiredTasks(7)
+	// 模型训练的模拟处理每轮等待1秒，超时后任务上下文结束，任务失败
+	task := suite.createTask("超时测试任务", "model_training", nil)
 
+	err := suite.manager.SubmitTask(task)
 	assert.NoError(suite.T(), err)
-	assert.Equal(suite.T(), int64(1), count) // 应该删除1个过期任务
-
-	// 验证旧任务已被删除，新任务仍存在
-	var tasks []models.Task
-	suite.testDB.DB.Unscoped().Find(&tasks)
-	
-	foundOld := false
-	foundNew := false
-	for _, task := range tasks {
-		if task.ID == oldTask.ID {
-			foundOld = true
-			assert.NotNil(suite.T(), task.DeletedAt) // 应该被软删除
-		}
-		if task.ID == newTask.ID {
-			foundNew = true
-			assert.Nil(suite.T(), task.DeletedAt) // 不应该被删除
-		}
-	}
-	
-	assert.True(suite.T(), foundOld, "应该找到旧任务记录")
-	assert.True(suite.T(), foundNew, "应该找到新任务记录")
+
+	failedTask := suite.waitForStatus(task.ID, "failed")
+	assert.NotEmpty(suite.T(), failedTask.ErrorMsg)
+	assert.NotNil(suite.T(), failedTask.EndTime)
 }
 
 func TestTaskManagerTestSuite(t *testing.T) {
 	suite.Run(t, new(TaskManagerTestSuite))
-}
\ No newline at end of file
+}
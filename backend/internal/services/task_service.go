@@ -143,6 +143,9 @@ func (s *TaskService) CancelTask(taskID uint, userID uint) error {
 		return fmt.Errorf("failed to cancel task: %w", err)
 	}
 
+	// 运行中的任务通过上下文取消，Python子进程组随之终止
+	runningJobs.cancel(taskID)
+
 	return nil
 }
 
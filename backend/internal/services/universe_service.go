@@ -0,0 +1,247 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"qlib-backend/internal/models"
+	"qlib-backend/internal/qlib"
+
+	"gorm.io/gorm"
+)
+
+// 股票池类型
+const (
+	UniverseTypeConstituents = "constituents" // 导入的成分区间
+	UniverseTypeFilter       = "filter"       // 按筛选条件逐日计算
+)
+
+// UniverseService 股票池服务，维护时点成分并注册到回测和因子测试使用的股票池注册表
+type UniverseService struct {
+	db           *gorm.DB
+	registry     *qlib.UniverseRegistry
+	dataProvider qlib.MarketDataProvider
+}
+
+// NewUniverseService 创建股票池服务，registry 为空时使用全局注册表
+func NewUniverseService(db *gorm.DB, registry *qlib.UniverseRegistry) *UniverseService {
+	if registry == nil {
+		registry = qlib.DefaultUniverses
+	}
+	return &UniverseService{db: db, registry: registry}
+}
+
+// SetDataProvider 设置计算筛选股票池使用的行情数据
+func (s *UniverseService) SetDataProvider(provider qlib.MarketDataProvider) {
+	s.dataProvider = provider
+}
+
+// LoadStoredUniverses 将数据库中保存的股票池加载到注册表
+func (s *UniverseService) LoadStoredUniverses() error {
+	var records []models.Universe
+	if err := s.db.Find(&records).Error; err != nil {
+		return fmt.Errorf("获取股票池失败: %v", err)
+	}
+	for _, record := range records {
+		if err := s.register(record); err != nil {
+			return fmt.Errorf("加载股票池 %s 失败: %v", record.Name, err)
+		}
+	}
+	return nil
+}
+
+// ImportQlibUniverse 导入Qlib数据目录中 instruments/<name>.txt 的成分区间
+func (s *UniverseService) ImportQlibUniverse(name, dataPath string, userID uint) (*models.Universe, error) {
+	spans, err := qlib.NewBinDataReader(dataPath).Instruments(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("股票池 %s 没有成分股", name)
+	}
+	return s.saveConstituents(qlib.NewUniverse(name, spans), "qlib", userID)
+}
+
+// ImportUniverseCSV 导入CSV格式的成分区间，每行为 "代码,纳入日期,剔除日期"
+func (s *UniverseService) ImportUniverseCSV(name string, content []byte, userID uint) (*models.Universe, error) {
+	universe, err := qlib.ParseUniverse(name, bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	return s.saveConstituents(universe, "csv", userID)
+}
+
+// CreateFilterUniverse 创建按筛选条件定义的自定义股票池，如流动性前1000、剔除ST、上市满60天
+func (s *UniverseService) CreateFilterUniverse(name, description string, filter qlib.UniverseFilter, userID uint) (*models.Universe, error) {
+	if name := universeName(name); name == "" || name == "all" || name == universeName(filter.Base) {
+		return nil, fmt.Errorf("无效的股票池名称: %s", name)
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	filterJSON, err := json.Marshal(filter)
+	if err != nil {
+		return nil, fmt.Errorf("序列化筛选条件失败: %v", err)
+	}
+	record := models.Universe{
+		Name:        universeName(name),
+		Description: description,
+		Type:        UniverseTypeFilter,
+		FilterJSON:  string(filterJSON),
+		UserID:      userID,
+	}
+	if err := s.replace(&record, nil); err != nil {
+		return nil, err
+	}
+	if err := s.registry.RegisterFilter(record.Name, filter); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ListUniverses 列出保存的股票池
+func (s *UniverseService) ListUniverses() ([]models.Universe, error) {
+	var records []models.Universe
+	if err := s.db.Order("name").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("获取股票池失败: %v", err)
+	}
+	return records, nil
+}
+
+// DeleteUniverse 删除股票池及其成分区间
+func (s *UniverseService) DeleteUniverse(name string) error {
+	name = universeName(name)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var record models.Universe
+		if err := tx.Where("name = ?", name).First(&record).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("universe_id = ?", record.ID).Delete(&models.UniverseMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&record).Error
+	})
+	if err != nil {
+		return fmt.Errorf("删除股票池失败: %v", err)
+	}
+	s.registry.Remove(name)
+	return nil
+}
+
+// GetMembers 查询股票池在 date 当天的成分股
+func (s *UniverseService) GetMembers(ctx context.Context, name string, date time.Time) ([]string, error) {
+	universe, err := s.registry.Resolve(ctx, s.dataProvider, name, date, date, "day")
+	if err != nil {
+		return nil, err
+	}
+	if universe == nil {
+		return nil, fmt.Errorf("股票池 %s 没有成分信息", name)
+	}
+	return universe.Members(date), nil
+}
+
+// saveConstituents 保存成分区间，同名股票池被替换
+func (s *UniverseService) saveConstituents(universe *qlib.Universe, source string, userID uint) (*models.Universe, error) {
+	spans := universe.Spans()
+	members := make([]models.UniverseMember, len(spans))
+	for i, span := range spans {
+		members[i] = models.UniverseMember{
+			Instrument: span.Symbol,
+			StartDate:  span.Start.Format("2006-01-02"),
+			EndDate:    span.End.Format("2006-01-02"),
+		}
+	}
+	record := models.Universe{
+		Name:        universeName(universe.Name),
+		Type:        UniverseTypeConstituents,
+		Source:      source,
+		Instruments: len(universe.Instruments(time.Time{}, time.Time{})),
+		UserID:      userID,
+	}
+	if err := s.replace(&record, members); err != nil {
+		return nil, err
+	}
+	universe.Name = record.Name
+	s.registry.Register(universe)
+	return &record, nil
+}
+
+// replace 在事务中删除同名股票池后写入新的定义和成分区间
+func (s *UniverseService) replace(record *models.Universe, members []models.UniverseMember) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing []models.Universe
+		if err := tx.Unscoped().Where("name = ?", record.Name).Find(&existing).Error; err != nil {
+			return err
+		}
+		for _, old := range existing {
+			if err := tx.Where("universe_id = ?", old.ID).Delete(&models.UniverseMember{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Delete(&old).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		for i := range members {
+			members[i].UniverseID = record.ID
+		}
+		if len(members) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(members, 500).Error
+	})
+	if err != nil {
+		return fmt.Errorf("保存股票池失败: %v", err)
+	}
+	return nil
+}
+
+// register 将数据库记录注册到股票池注册表
+func (s *UniverseService) register(record models.Universe) error {
+	if record.Type == UniverseTypeFilter {
+		var filter qlib.UniverseFilter
+		if err := json.Unmarshal([]byte(record.FilterJSON), &filter); err != nil {
+			return fmt.Errorf("解析筛选条件失败: %v", err)
+		}
+		return s.registry.RegisterFilter(record.Name, filter)
+	}
+
+	var members []models.UniverseMember
+	if err := s.db.Where("universe_id = ?", record.ID).Find(&members).Error; err != nil {
+		return err
+	}
+	spans, err := memberSpans(members)
+	if err != nil {
+		return err
+	}
+	s.registry.Register(qlib.NewUniverse(record.Name, spans))
+	return nil
+}
+
+// memberSpans 将成分区间记录转换为证券有效区间
+func memberSpans(members []models.UniverseMember) ([]qlib.InstrumentSpan, error) {
+	spans := make([]qlib.InstrumentSpan, 0, len(members))
+	for _, member := range members {
+		start, err := time.Parse("2006-01-02", member.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("成分 %s 的纳入日期错误: %v", member.Instrument, err)
+		}
+		end, err := time.Parse("2006-01-02", member.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("成分 %s 的剔除日期错误: %v", member.Instrument, err)
+		}
+		spans = append(spans, qlib.InstrumentSpan{Symbol: member.Instrument, Start: start, End: end})
+	}
+	return spans, nil
+}
+
+// universeName 股票池名称统一为小写，与Qlib的 instruments 文件名一致
+func universeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"qlib-backend/internal/models"
+)
+
+func TestMemberSpans(t *testing.T) {
+	members := []models.UniverseMember{
+		{Instrument: "SH600000", StartDate: "2020-01-02", EndDate: "2023-06-09"},
+		{Instrument: "SH600000", StartDate: "2023-12-11", EndDate: "2099-12-31"},
+	}
+	spans, err := memberSpans(members)
+	if err != nil {
+		t.Fatalf("memberSpans failed: %v", err)
+	}
+	if len(spans) != 2 || !spans[1].Start.Equal(time.Date(2023, 12, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected spans: %+v", spans)
+	}
+
+	members[0].EndDate = "2023/06/09"
+	if _, err := memberSpans(members); err == nil {
+		t.Error("invalid date should fail")
+	}
+	if name := universeName(" CSI300 "); name != "csi300" {
+		t.Errorf("universeName = %q", name)
+	}
+}
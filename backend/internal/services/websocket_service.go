@@ -7,6 +7,8 @@ import (
 	"sync"
 	"time"
 
+	"qlib-backend/internal/qlib"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -474,6 +476,16 @@ func (ws *WebSocketService) SendTaskStatusUpdate(userID uint, taskID uint, statu
 	ws.BroadcastToUser(userID, "task_status", data)
 }
 
+// SendOptimizationStep 发送参数优化单次试验结果
+func (ws *WebSocketService) SendOptimizationStep(userID uint, taskID uint, progress int, step qlib.OptimizationStep) {
+	data := map[string]interface{}{
+		"task_id":  taskID,
+		"progress": progress,
+		"step":     step,
+	}
+	ws.BroadcastToUser(userID, "optimization_step", data)
+}
+
 // SendFactorTestUpdate 发送因子测试更新
 func (ws *WebSocketService) SendFactorTestUpdate(userID uint, factorID uint, progress int, results map[string]interface{}) {
 	data := map[string]interface{}{
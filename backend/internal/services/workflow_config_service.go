@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"qlib-backend/internal/qlib"
 	"qlib-backend/internal/utils"
 
 	"gorm.io/gorm"
@@ -214,6 +215,42 @@ func (wcs *WorkflowConfigService) validateSteps(steps []ConfigStep, result *Work
 			})
 			result.IsValid = false
 		}
+
+		wcs.validateProcessors(stepPrefix, step, result)
+	}
+}
+
+// validateProcessors 验证步骤中的预处理器配置，数据准备和模型训练步骤交给Qlib执行，原生专有的处理器给出警告
+func (wcs *WorkflowConfigService) validateProcessors(stepPrefix string, step ConfigStep, result *WorkflowValidationResult) {
+	for _, key := range []string{"processors", "infer_processors", "learn_processors"} {
+		raw, ok := step.Config[key]
+		if !ok {
+			continue
+		}
+		configs, err := qlib.ParseProcessors(raw)
+		if err != nil {
+			result.Errors = append(result.Errors, ValidationError{
+				Field:   stepPrefix + ".config." + key,
+				Step:    step.Name,
+				Code:    "INVALID_PROCESSOR",
+				Message: err.Error(),
+			})
+			result.IsValid = false
+			continue
+		}
+		if step.Type != "data_preparation" && step.Type != "model_training" {
+			continue
+		}
+		for _, config := range configs {
+			if !qlib.IsQlibProcessor(config) {
+				result.Warnings = append(result.Warnings, ValidationWarning{
+					Field:   stepPrefix + ".config." + key,
+					Step:    step.Name,
+					Code:    "NATIVE_ONLY_PROCESSOR",
+					Message: fmt.Sprintf("处理器 %s 仅支持原生计算，Qlib工作流中将不可用", config.Class),
+				})
+			}
+		}
 	}
 }
 
@@ -372,6 +409,14 @@ func (wcs *WorkflowConfigService) getBuiltinTemplates() []PresetTemplate {
 						"start_time":  "2020-01-01",
 						"end_time":    "2023-12-31",
 						"fields":      []string{"$close", "$volume", "$high", "$low", "$open"},
+						"infer_processors": []map[string]interface{}{
+							{"class": "RobustZScoreNorm", "kwargs": map[string]interface{}{"fields_group": "feature", "clip_outlier": true}},
+							{"class": "Fillna", "kwargs": map[string]interface{}{"fields_group": "feature"}},
+						},
+						"learn_processors": []map[string]interface{}{
+							{"class": "DropnaLabel"},
+							{"class": "CSRankNorm", "kwargs": map[string]interface{}{"fields_group": "label"}},
+						},
 					},
 				},
 				{
@@ -475,7 +520,13 @@ func (wcs *WorkflowConfigService) getBuiltinTemplates() []PresetTemplate {
 					Required:     true,
 					Enabled:      true,
 					Dependencies: []string{"Factor Generation"},
-					Config:       map[string]interface{}{},
+					Config: map[string]interface{}{
+						"processors": []map[string]interface{}{
+							{"class": "Winsorize", "kwargs": map[string]interface{}{"method": "mad", "n": 3}},
+							{"class": "CSZScoreNorm"},
+							{"class": "Fillna", "kwargs": map[string]interface{}{"fill_value": 0}},
+						},
+					},
 				},
 			},
 		},
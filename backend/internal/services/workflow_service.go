@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"sync"
 	"time"
 
@@ -97,6 +98,11 @@ func NewWorkflowService(db *gorm.DB, taskManager *TaskManager, broadcastService
 	}
 }
 
+// SetDataProvider 设置行情数据提供者，用于滚动训练的交易日历和原生回测
+func (ws *WorkflowService) SetDataProvider(provider qlib.MarketDataProvider) {
+	ws.workflowEngine.SetDataProvider(provider)
+}
+
 // RunWorkflow 运行完整工作流
 func (ws *WorkflowService) RunWorkflow(req WorkflowRunRequest) (*WorkflowExecution, error) {
 	// 获取模板
@@ -190,6 +196,7 @@ func (ws *WorkflowService) executeWorkflow(execution *WorkflowExecution, templat
 
 	endTime := time.Now()
 	execution.EndTime = &endTime
+	ws.saveExecutionRecord(execution, results, err)
 
 	if err != nil {
 		// 执行失败
@@ -255,6 +262,113 @@ func (ws *WorkflowService) executeWorkflow(execution *WorkflowExecution, templat
 	})
 }
 
+// saveExecutionRecord 保存工作流执行记录及各步骤记录，滚动训练的每个窗口作为步骤的子记录保存
+func (ws *WorkflowService) saveExecutionRecord(execution *WorkflowExecution, results map[string]interface{}, runErr error) {
+	status := models.WorkflowStatusCompleted
+	if success, _ := results["success"].(bool); runErr != nil || !success {
+		status = models.WorkflowStatusFailed
+	}
+	duration := execution.EndTime.Sub(execution.StartTime).Milliseconds()
+	record := &models.WorkflowExecution{
+		WorkflowID:  execution.WorkflowID,
+		TaskID:      execution.TaskID,
+		Status:      status,
+		CurrentStep: execution.CurrentStep,
+		Progress:    execution.Progress,
+		StartTime:   execution.StartTime,
+		EndTime:     execution.EndTime,
+		Duration:    &duration,
+		ResultJSON:  ws.mapToJSON(results),
+	}
+	if runErr != nil {
+		record.ErrorMsg = runErr.Error()
+	} else if msg, ok := results["error"].(string); ok {
+		record.ErrorMsg = msg
+	}
+	if err := ws.db.Create(record).Error; err != nil {
+		log.Printf("保存工作流执行记录失败: %v", err)
+		return
+	}
+
+	steps, _ := results["steps"].([]qlib.StepResult)
+	for _, step := range steps {
+		stepRecord := stepExecutionRecord(record.ID, step)
+		if err := ws.db.Create(&stepRecord).Error; err != nil {
+			log.Printf("保存工作流步骤记录失败: %v", err)
+			continue
+		}
+		if step.Type != models.StepTypeRollingTraining {
+			continue
+		}
+		for _, child := range rollingWindowRecords(record.ID, stepRecord.ID, step) {
+			if err := ws.db.Create(&child).Error; err != nil {
+				log.Printf("保存滚动窗口记录失败: %v", err)
+			}
+		}
+	}
+}
+
+// GetExecutionSteps 获取工作流最近一次执行的步骤记录，包括滚动训练各窗口的子记录
+func (ws *WorkflowService) GetExecutionSteps(workflowID uint) ([]models.WorkflowStepExecution, error) {
+	var execution models.WorkflowExecution
+	if err := ws.db.Where("workflow_id = ?", workflowID).Order("id DESC").First(&execution).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("工作流执行记录不存在")
+		}
+		return nil, fmt.Errorf("获取工作流执行记录失败: %v", err)
+	}
+	var steps []models.WorkflowStepExecution
+	if err := ws.db.Where("execution_id = ?", execution.ID).Order("id").Find(&steps).Error; err != nil {
+		return nil, fmt.Errorf("获取工作流步骤记录失败: %v", err)
+	}
+	return steps, nil
+}
+
+// stepExecutionRecord 将步骤执行结果转换为步骤记录
+func stepExecutionRecord(executionID uint, step qlib.StepResult) models.WorkflowStepExecution {
+	status := "completed"
+	if !step.Success {
+		status = "failed"
+	}
+	duration := step.Duration.Milliseconds()
+	outputJSON, _ := json.Marshal(step.Output)
+	return models.WorkflowStepExecution{
+		ExecutionID: executionID,
+		StepName:    step.Name,
+		StepType:    step.Type,
+		Status:      status,
+		Duration:    &duration,
+		OutputJSON:  string(outputJSON),
+		ErrorMsg:    step.Error,
+	}
+}
+
+// rollingWindowRecords 将滚动训练步骤输出的各窗口转换为子记录，输入为窗口分段，输出为模型路径和指标
+func rollingWindowRecords(executionID, parentID uint, step qlib.StepResult) []models.WorkflowStepExecution {
+	windows, _ := step.Output["windows"].([]qlib.RollingWindowResult)
+	records := make([]models.WorkflowStepExecution, 0, len(windows))
+	for _, window := range windows {
+		duration := window.Duration.Milliseconds()
+		inputJSON, _ := json.Marshal(window.Window)
+		outputJSON, _ := json.Marshal(map[string]interface{}{
+			"model_path":  window.ModelPath,
+			"metrics":     window.Metrics,
+			"predictions": window.Predictions,
+		})
+		records = append(records, models.WorkflowStepExecution{
+			ExecutionID: executionID,
+			ParentID:    &parentID,
+			StepName:    fmt.Sprintf("%s/窗口%d", step.Name, window.Window.Index),
+			StepType:    models.StepTypeRollingWindow,
+			Status:      "completed",
+			Duration:    &duration,
+			InputJSON:   string(inputJSON),
+			OutputJSON:  string(outputJSON),
+		})
+	}
+	return records
+}
+
 // updateWorkflowStatus 更新工作流状态
 func (ws *WorkflowService) updateWorkflowStatus(workflowID uint, status string, progress int, message string) {
 	updates := map[string]interface{}{
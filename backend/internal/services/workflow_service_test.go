@@ -6,156 +6,115 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
 	"qlib-backend/internal/models"
-	"qlib-backend/internal/qlib"
 	"qlib-backend/internal/testutils"
 )
 
-// MockWorkflowEngine 模拟工作流引擎
-type MockWorkflowEngine struct {
-	mock.Mock
-}
-
-func (m *MockWorkflowEngine) RunWorkflow(ctx context.Context, config WorkflowConfig) (*WorkflowResult, error) {
-	args := m.Called(ctx, config)
-	return args.Get(0).(*WorkflowResult), args.Error(1)
-}
-
-func (m *MockWorkflowEngine) GetWorkflowStatus(taskID uint) (*WorkflowStatus, error) {
-	args := m.Called(taskID)
-	return args.Get(0).(*WorkflowStatus), args.Error(1)
-}
-
-func (m *MockWorkflowEngine) PauseWorkflow(taskID uint) error {
-	args := m.Called(taskID)
-	return args.Error(0)
-}
-
-func (m *MockWorkflowEngine) ResumeWorkflow(taskID uint) error {
-	args := m.Called(taskID)
-	return args.Error(0)
-}
-
-func (m *MockWorkflowEngine) StopWorkflow(taskID uint) error {
-	args := m.Called(taskID)
-	return args.Error(0)
-}
-
-// MockTaskManager 模拟任务管理器
-type MockTaskManager struct {
-	mock.Mock
-}
-
-func (m *MockTaskManager) CreateTask(name, taskType string, userID uint, config interface{}) (uint, error) {
-	args := m.Called(name, taskType, userID, config)
-	return uint(args.Int(0)), args.Error(1)
-}
-
-func (m *MockTaskManager) UpdateTaskStatus(taskID uint, status string, progress int, result interface{}) error {
-	args := m.Called(taskID, status, progress, result)
-	return args.Error(0)
-}
-
-func (m *MockTaskManager) GetTaskStatus(taskID uint) (interface{}, error) {
-	args := m.Called(taskID)
-	return args.Get(0), args.Error(1)
-}
-
-// MockBroadcastService 模拟广播服务
-type MockBroadcastService struct {
-	mock.Mock
-}
-
-func (m *MockBroadcastService) BroadcastWorkflowProgress(taskID uint, progress WorkflowProgressMessage) {
-	m.Called(taskID, progress)
-}
-
 type WorkflowServiceTestSuite struct {
 	suite.Suite
-	service          *WorkflowService
-	testDB           *testutils.TestDB
-	mockEngine       *MockWorkflowEngine
-	mockTaskManager  *MockTaskManager
-	mockBroadcast    *MockBroadcastService
+	service *WorkflowService
+	db      *gorm.DB
 }
 
 func (suite *WorkflowServiceTestSuite) SetupSuite() {
-	suite.testDB = testutils.SetupTestDB()
-	suite.mockEngine = new(MockWorkflowEngine)
-	suite.mockTaskManager = new(MockTaskManager)
-	suite.mockBroadcast = new(MockBroadcastService)
-	
-	suite.service = &WorkflowService{
-		db:               suite.testDB.DB,
-		workflowEngine:   suite.mockEngine,
-		taskManager:      suite.mockTaskManager,
-		broadcastService: suite.mockBroadcast,
-		runningWorkflows: make(map[uint]*WorkflowExecution),
-	}
+	suite.db = testutils.RequireTestDB(suite.T())
+	suite.T().Setenv("QLIB_WORKSPACE_DIR", suite.T().TempDir())
+	suite.service = NewWorkflowService(suite.db, nil, nil)
 }
 
-func (suite *WorkflowServiceTestSuite) TearDownSuite() {
-	suite.testDB.Cleanup()
+func (suite *WorkflowServiceTestSuite) SetupTest() {
+	testutils.CleanupTables(suite.db)
 }
 
-func (suite *WorkflowServiceTestSuite) SetupTest() {
-	suite.testDB.CleanupTables()
-	suite.mockEngine.ExpectedCalls = nil
-	suite.mockTaskManager.ExpectedCalls = nil
-	suite.mockBroadcast.ExpectedCalls = nil
+// createTemplate 创建只包含给定步骤的工作流模板
+func (suite *WorkflowServiceTestSuite) createTemplate(steps ...WorkflowStep) *WorkflowTemplate {
+	template, err := suite.service.CreateTemplate(WorkflowTemplate{
+		Name:        "测试模板",
+		Description: "用于测试的工作流模板",
+		Category:    "custom",
+		Config:      map[string]interface{}{"type": "custom_quantitative"},
+		Steps:       steps,
+	}, 1)
+	suite.Require().NoError(err)
+	return template
+}
+
+// registerRunning 将工作流登记为运行中，不实际执行
+func (suite *WorkflowServiceTestSuite) registerRunning(status string) *WorkflowExecution {
+	now := time.Now()
+	workflow := models.Workflow{Name: "运行中工作流", TemplateID: 1, Status: status, Progress: 40, StartTime: &now, UserID: 1}
+	suite.db.Create(&workflow)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	execution := &WorkflowExecution{
+		WorkflowID: workflow.ID,
+		Status:     status,
+		Progress:   40,
+		StartTime:  now,
+		Context:    ctx,
+		Cancel:     cancel,
+	}
+	suite.service.mutex.Lock()
+	suite.service.runningWorkflows[workflow.ID] = execution
+	suite.service.mutex.Unlock()
+	suite.T().Cleanup(func() {
+		cancel()
+		suite.service.mutex.Lock()
+		delete(suite.service.runningWorkflows, workflow.ID)
+		suite.service.mutex.Unlock()
+	})
+	return execution
 }
 
 func (suite *WorkflowServiceTestSuite) TestRunWorkflow() {
 	userID := uint(1)
-	taskID := uint(123)
+	template := suite.createTemplate(WorkflowStep{Name: "未知步骤", Type: "unknown_step", Required: true})
 
 	req := WorkflowRunRequest{
-		TemplateID: 1,
+		TemplateID: template.ID,
 		Name:       "测试工作流",
 		Config: map[string]interface{}{
-			"dataset_id": 1,
-			"model_type": "lightgbm",
+			"dataset_id":    1,
+			"model_type":    "lightgbm",
 			"strategy_type": "top_k",
 		},
-		Parameters: map[string]interface{}{
-			"train_start": "2020-01-01",
-			"train_end":   "2022-12-31",
-			"test_start":  "2023-01-01",
-			"test_end":    "2023-12-31",
-		},
+		UserID: userID,
 	}
 
-	// 设置模拟期望
-	suite.mockTaskManager.On("CreateTask", "测试工作流", "workflow", userID, mock.Anything).Return(int(taskID), nil)
-	suite.mockEngine.On("RunWorkflow", mock.Anything, mock.Anything).Return(&WorkflowResult{
-		TaskID:  taskID,
-		Status:  "completed",
-		Results: map[string]interface{}{"model_id": 456, "strategy_id": 789},
-	}, nil)
-	suite.mockBroadcast.On("BroadcastWorkflowProgress", taskID, mock.Anything).Return()
-
-	result, err := suite.service.RunWorkflow(req, userID)
+	execution, err := suite.service.RunWorkflow(req)
 
 	assert.NoError(suite.T(), err)
-	assert.NotNil(suite.T(), result)
-	assert.Equal(suite.T(), taskID, result.TaskID)
-	assert.Equal(suite.T(), "submitted", result.Status)
-	assert.Equal(suite.T(), req.Name, result.WorkflowName)
+	assert.NotNil(suite.T(), execution)
+	assert.Greater(suite.T(), execution.WorkflowID, uint(0))
+	assert.Greater(suite.T(), execution.TaskID, uint(0))
+
+	// 等待异步执行结束，不支持的步骤记为失败的执行记录
+	var record models.WorkflowExecution
+	suite.Eventually(func() bool {
+		return suite.db.Where("workflow_id = ?", execution.WorkflowID).First(&record).Error == nil
+	}, 10*time.Second, 20*time.Millisecond)
+	assert.Equal(suite.T(), models.WorkflowStatusFailed, record.Status)
+	assert.Contains(suite.T(), record.ErrorMsg, "未知步骤")
+
+	steps, err := suite.service.GetExecutionSteps(execution.WorkflowID)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), steps, 1)
+	assert.Equal(suite.T(), "failed", steps[0].Status)
 
-	suite.mockTaskManager.AssertExpectations(suite.T())
-	suite.mockBroadcast.AssertExpectations(suite.T())
+	_, err = suite.service.RunWorkflow(WorkflowRunRequest{TemplateID: template.ID + 1000, Name: "无模板", UserID: userID})
+	assert.Error(suite.T(), err)
 }
 
-func (suite *WorkflowServiceTestSuite) TestGetWorkflowTemplates() {
-	category := "quantitative"
+func (suite *WorkflowServiceTestSuite) TestGetTemplates() {
+	suite.createTemplate(WorkflowStep{Name: "数据准备", Type: "data_preparation"})
+	suite.db.Create(&models.WorkflowTemplate{Name: "研究模板", Category: "research", StepsJSON: `[{"name":"因子生成","type":"factor_generation"}]`, CreatedBy: 1})
 
-	templates, err := suite.service.GetWorkflowTemplates(category)
+	templates, err := suite.service.GetTemplates("custom")
 
 	assert.NoError(suite.T(), err)
-	assert.NotNil(suite.T(), templates)
-	assert.Greater(suite.T(), len(templates), 0)
+	assert.Len(suite.T(), templates, 1)
 
 	// 验证模板结构
 	for _, template := range templates {
@@ -163,211 +122,227 @@ func (suite *WorkflowServiceTestSuite) TestGetWorkflowTemplates() {
 		assert.NotEmpty(suite.T(), template.Description)
 		assert.NotNil(suite.T(), template.Config)
 		assert.Greater(suite.T(), len(template.Steps), 0)
+		assert.Equal(suite.T(), "custom", template.Category)
 	}
 
-	// 如果指定了分类，验证返回的模板都属于该分类
-	if category != "" {
-		for _, template := range templates {
-			assert.Equal(suite.T(), category, template.Category)
-		}
-	}
+	templates, err = suite.service.GetTemplates("")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), templates, 2)
 }
 
-func (suite *WorkflowServiceTestSuite) TestCreateWorkflowTemplate() {
-	userID := uint(1)
-
-	req := WorkflowTemplateRequest{
-		Name:        "自定义模板",
-		Description: "用户自定义的工作流模板",
-		Category:    "custom",
-		Config: map[string]interface{}{
-			"type": "custom_quantitative",
+func (suite *WorkflowServiceTestSuite) TestCreateTemplate() {
+	template := suite.createTemplate(
+		WorkflowStep{
+			Name:   "数据准备",
+			Type:   "data_preparation",
+			Config: map[string]interface{}{"dataset_id": "{{dataset_id}}"},
 		},
-		Steps: []WorkflowStep{
-			{
-				Name: "数据加载",
-				Type: "data_loading",
-				Config: map[string]interface{}{
-					"dataset_id": "{{dataset_id}}",
-				},
-			},
-			{
-				Name: "模型训练",
-				Type: "model_training",
-				Config: map[string]interface{}{
-					"model_type": "{{model_type}}",
-				},
-			},
+		WorkflowStep{
+			Name:         "模型训练",
+			Type:         "model_training",
+			Config:       map[string]interface{}{"model_type": "{{model_type}}"},
+			Dependencies: []string{"数据准备"},
 		},
-	}
+	)
 
-	template, err := suite.service.CreateWorkflowTemplate(req, userID)
+	assert.Greater(suite.T(), template.ID, uint(0))
 
+	stored, err := suite.service.GetTemplate(template.ID)
 	assert.NoError(suite.T(), err)
-	assert.NotNil(suite.T(), template)
-	assert.Equal(suite.T(), req.Name, template.Name)
-	assert.Equal(suite.T(), req.Description, template.Description)
-	assert.Equal(suite.T(), req.Category, template.Category)
-	assert.Len(suite.T(), template.Steps, 2)
-	assert.Greater(suite.T(), template.ID, uint(0))
+	assert.Equal(suite.T(), template.Name, stored.Name)
+	assert.Equal(suite.T(), template.Description, stored.Description)
+	assert.Equal(suite.T(), template.Category, stored.Category)
+	assert.Len(suite.T(), stored.Steps, 2)
+	assert.Equal(suite.T(), []string{"数据准备"}, stored.Steps[1].Dependencies)
+
+	_, err = suite.service.GetTemplate(template.ID + 1000)
+	assert.Error(suite.T(), err)
 }
 
 func (suite *WorkflowServiceTestSuite) TestGetWorkflowStatus() {
-	taskID := uint(123)
+	execution := suite.registerRunning("running")
+	execution.CurrentStep = "模型训练"
 
-	suite.mockEngine.On("GetWorkflowStatus", taskID).Return(&WorkflowStatus{
-		TaskID:       taskID,
-		Status:       "running",
-		Progress:     45,
-		CurrentStep:  "模型训练中...",
-		StartTime:    time.Now().Add(-10 * time.Minute),
-		EstimatedEnd: time.Now().Add(15 * time.Minute),
-	}, nil)
-
-	status, err := suite.service.GetWorkflowStatus(taskID)
+	status, err := suite.service.GetWorkflowStatus(execution.WorkflowID)
 
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), status)
-	assert.Equal(suite.T(), taskID, status.TaskID)
+	assert.Equal(suite.T(), execution.WorkflowID, status.WorkflowID)
 	assert.Equal(suite.T(), "running", status.Status)
-	assert.Equal(suite.T(), 45, status.Progress)
-	assert.Equal(suite.T(), "模型训练中...", status.CurrentStep)
+	assert.Equal(suite.T(), 40, status.Progress)
+	assert.Equal(suite.T(), "模型训练", status.CurrentStep)
 
-	suite.mockEngine.AssertExpectations(suite.T())
+	_, err = suite.service.GetWorkflowStatus(execution.WorkflowID + 1000)
+	assert.Error(suite.T(), err)
 }
 
 func (suite *WorkflowServiceTestSuite) TestPauseWorkflow() {
-	taskID := uint(123)
-
-	suite.mockEngine.On("PauseWorkflow", taskID).Return(nil)
+	execution := suite.registerRunning("running")
 
-	err := suite.service.PauseWorkflow(taskID)
+	err := suite.service.PauseWorkflow(execution.WorkflowID)
 
 	assert.NoError(suite.T(), err)
-	suite.mockEngine.AssertExpectations(suite.T())
+	assert.Equal(suite.T(), "paused", execution.Status)
+
+	var workflow models.Workflow
+	suite.db.First(&workflow, execution.WorkflowID)
+	assert.Equal(suite.T(), "paused", workflow.Status)
+
+	// 已暂停的工作流不能再次暂停
+	assert.Error(suite.T(), suite.service.PauseWorkflow(execution.WorkflowID))
+	assert.Error(suite.T(), suite.service.PauseWorkflow(execution.WorkflowID+1000))
 }
 
 func (suite *WorkflowServiceTestSuite) TestResumeWorkflow() {
-	taskID := uint(123)
-
-	suite.mockEngine.On("ResumeWorkflow", taskID).Return(nil)
+	execution := suite.registerRunning("paused")
 
-	err := suite.service.ResumeWorkflow(taskID)
+	err := suite.service.ResumeWorkflow(execution.WorkflowID)
 
 	assert.NoError(suite.T(), err)
-	suite.mockEngine.AssertExpectations(suite.T())
+	assert.Equal(suite.T(), "running", execution.Status)
+
+	var workflow models.Workflow
+	suite.db.First(&workflow, execution.WorkflowID)
+	assert.Equal(suite.T(), "running", workflow.Status)
+
+	// 运行中的工作流不能恢复
+	assert.Error(suite.T(), suite.service.ResumeWorkflow(execution.WorkflowID))
 }
 
 func (suite *WorkflowServiceTestSuite) TestGetWorkflowHistory() {
 	userID := uint(1)
 
 	// 创建测试工作流记录
+	start := time.Now().Add(-1 * time.Hour)
+	end := time.Now()
 	workflow := models.Workflow{
-		Name:        "历史工作流",
-		Status:      "completed",
-		UserID:      userID,
-		TemplateID:  1,
-		StartTime:   time.Now().Add(-1 * time.Hour),
-		EndTime:     &time.Time{},
-		Progress:    100,
-		Results:     `{"model_id": 123, "strategy_id": 456}`,
+		Name:       "历史工作流",
+		Status:     "completed",
+		UserID:     userID,
+		TemplateID: 1,
+		StartTime:  &start,
+		EndTime:    &end,
+		Progress:   100,
+		ResultJSON: `{"model_id": 123, "strategy_id": 456}`,
 	}
-	*workflow.EndTime = time.Now()
-	suite.testDB.DB.Create(&workflow)
-
-	page := 1
-	pageSize := 10
-	status := ""
+	suite.db.Create(&workflow)
 
-	history, err := suite.service.GetWorkflowHistory(userID, page, pageSize, status)
+	history, err := suite.service.GetWorkflowHistory(userID, 1, 10)
 
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), history)
 	assert.Equal(suite.T(), int64(1), history.Total)
-	assert.Len(suite.T(), history.Workflows, 1)
-	assert.Equal(suite.T(), "历史工作流", history.Workflows[0].Name)
-	assert.Equal(suite.T(), "completed", history.Workflows[0].Status)
+	assert.Len(suite.T(), history.Data, 1)
+	assert.Equal(suite.T(), "历史工作流", history.Data[0].Name)
+	assert.Equal(suite.T(), "completed", history.Data[0].Status)
+	assert.NotNil(suite.T(), history.Data[0].Duration)
+	assert.Contains(suite.T(), history.Data[0].Results, "model_id")
 }
 
-func (suite *WorkflowServiceTestSuite) TestValidateWorkflowConfig() {
-	// 测试有效配置
-	validConfig := WorkflowConfig{
+func (suite *WorkflowServiceTestSuite) TestGetWorkflowResults() {
+	// 创建测试工作流
+	start := time.Now().Add(-1 * time.Hour)
+	workflow := models.Workflow{
+		Name:       "完成的工作流",
+		Status:     "completed",
 		TemplateID: 1,
-		Name:       "有效工作流",
-		Steps: []WorkflowStepConfig{
-			{
-				Name: "数据加载",
-				Type: "data_loading",
-				Config: map[string]interface{}{
-					"dataset_id": 1,
-				},
-			},
-		},
-		Parameters: map[string]interface{}{
-			"train_start": "2020-01-01",
-			"train_end":   "2022-12-31",
-		},
+		UserID:     1,
+		StartTime:  &start,
+		ResultJSON: `{"model_id": 456, "strategy_id": 789, "performance": {"ic": 0.045, "sharpe": 1.23}}`,
 	}
+	suite.db.Create(&workflow)
 
-	result, err := suite.service.ValidateWorkflowConfig(validConfig)
+	results, err := suite.service.GetWorkflowStatus(workflow.ID)
 
 	assert.NoError(suite.T(), err)
-	assert.NotNil(suite.T(), result)
-	assert.True(suite.T(), result.IsValid)
-	assert.Empty(suite.T(), result.ValidationErrors)
-	assert.Greater(suite.T(), result.EstimatedDuration, 0)
-
-	// 测试无效配置
-	invalidConfig := WorkflowConfig{
-		TemplateID: 0, // 无效的模板ID
-		Name:       "", // 空名称
-		Steps:      []WorkflowStepConfig{}, // 空步骤
-	}
-
-	result, err = suite.service.ValidateWorkflowConfig(invalidConfig)
-
-	assert.NoError(suite.T(), err) // 验证函数本身不应出错
-	assert.NotNil(suite.T(), result)
-	assert.False(suite.T(), result.IsValid)
-	assert.NotEmpty(suite.T(), result.ValidationErrors)
+	assert.NotNil(suite.T(), results)
+	assert.Equal(suite.T(), workflow.ID, results.WorkflowID)
+	assert.Equal(suite.T(), "completed", results.Status)
+	assert.Contains(suite.T(), results.Results, "model_id")
+	assert.Contains(suite.T(), results.Results, "strategy_id")
+	assert.Contains(suite.T(), results.Results, "performance")
 }
 
-func (suite *WorkflowServiceTestSuite) TestStopWorkflow() {
-	taskID := uint(123)
+func TestWorkflowServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(WorkflowServiceTestSuite))
+}
 
-	suite.mockEngine.On("StopWorkflow", taskID).Return(nil)
+func TestGetPresetTemplates(t *testing.T) {
+	service := NewWorkflowConfigService(nil)
 
-	err := suite.service.StopWorkflow(taskID)
+	templates, err := service.GetPresetTemplates("research")
+	if err != nil {
+		t.Fatalf("GetPresetTemplates failed: %v", err)
+	}
+	if len(templates) == 0 {
+		t.Fatal("应返回研究类预设模板")
+	}
 
-	assert.NoError(suite.T(), err)
-	suite.mockEngine.AssertExpectations(suite.T())
+	// 验证模板结构
+	for _, template := range templates {
+		if template.Name == "" || template.Description == "" || len(template.Steps) == 0 {
+			t.Errorf("预设模板信息不完整: %+v", template)
+		}
+		if template.Category != "research" {
+			t.Errorf("模板 %s 的分类为 %s，应为 research", template.Name, template.Category)
+		}
+	}
 }
 
-func (suite *WorkflowServiceTestSuite) TestGetWorkflowResults() {
-	taskID := uint(123)
+func TestValidateWorkflowConfig(t *testing.T) {
+	service := NewWorkflowConfigService(nil)
 
-	// 创建测试工作流
-	workflow := models.Workflow{
-		TaskID:  taskID,
-		Name:    "完成的工作流",
-		Status:  "completed",
-		UserID:  1,
-		Results: `{"model_id": 456, "strategy_id": 789, "performance": {"ic": 0.045, "sharpe": 1.23}}`,
+	// 测试有效配置
+	validReq := WorkflowConfigRequest{
+		Name: "有效工作流",
+		Steps: []ConfigStep{
+			{Name: "数据准备", Type: "data_preparation", Config: map[string]interface{}{"dataset_id": 1}, Enabled: true, Required: true},
+			{Name: "模型训练", Type: "model_training", Dependencies: []string{"数据准备"}, Enabled: true},
+		},
+		Config: map[string]interface{}{
+			"train_start": "2020-01-01",
+			"train_end":   "2022-12-31",
+		},
 	}
-	suite.testDB.DB.Create(&workflow)
 
-	results, err := suite.service.GetWorkflowResults(taskID)
+	result, err := service.ValidateWorkflowConfig(validReq)
+	if err != nil {
+		t.Fatalf("ValidateWorkflowConfig failed: %v", err)
+	}
+	if !result.IsValid || len(result.Errors) != 0 {
+		t.Errorf("有效配置不应有错误: %+v", result.Errors)
+	}
+	if result.Summary.TotalSteps != 2 || result.Summary.EnabledSteps != 2 || result.Summary.RequiredSteps != 1 {
+		t.Errorf("验证汇总不正确: %+v", result.Summary)
+	}
 
-	assert.NoError(suite.T(), err)
-	assert.NotNil(suite.T(), results)
-	assert.Equal(suite.T(), taskID, results.TaskID)
-	assert.Equal(suite.T(), "completed", results.Status)
-	assert.NotNil(suite.T(), results.Results)
-	assert.Contains(suite.T(), results.Results, "model_id")
-	assert.Contains(suite.T(), results.Results, "strategy_id")
-	assert.Contains(suite.T(), results.Results, "performance")
-}
+	// 测试无效配置：空名称、重复步骤和不支持的步骤类型
+	invalidReq := WorkflowConfigRequest{
+		Steps: []ConfigStep{
+			{Name: "步骤", Type: "data_preparation"},
+			{Name: "步骤", Type: "unknown_step"},
+		},
+	}
 
-func TestWorkflowServiceTestSuite(t *testing.T) {
-	suite.Run(t, new(WorkflowServiceTestSuite))
-}
\ No newline at end of file
+	result, err = service.ValidateWorkflowConfig(invalidReq) // 验证函数本身不应出错
+	if err != nil {
+		t.Fatalf("ValidateWorkflowConfig failed: %v", err)
+	}
+	if result.IsValid {
+		t.Fatal("无效配置应验证失败")
+	}
+	codes := map[string]bool{}
+	for _, e := range result.Errors {
+		codes[e.Code] = true
+	}
+	for _, code := range []string{"REQUIRED", "DUPLICATE", "UNSUPPORTED_TYPE"} {
+		if !codes[code] {
+			t.Errorf("应包含 %s 错误: %+v", code, result.Errors)
+		}
+	}
+
+	result, _ = service.ValidateWorkflowConfig(WorkflowConfigRequest{Name: "空工作流"})
+	if result.IsValid {
+		t.Error("没有步骤的配置应验证失败")
+	}
+}
@@ -3,31 +3,76 @@ package testutils
 import (
 	"bytes"
 	"encoding/json"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"qlib-backend/internal/models"
+	"qlib-backend/internal/qlib"
 )
 
-// TestDB 测试数据库实例
+// TestDB 测试数据库实例，未配置 TEST_DATABASE_DSN 时为 nil
 var TestDB *gorm.DB
 
 // SetupTestDB 设置测试数据库
+//
+// 通过环境变量 TEST_DATABASE_DSN 连接MySQL测试库并迁移全部数据表；未配置或连接失败时返回 nil，
+// 依赖数据库的测试应使用 RequireTestDB 跳过。
 func SetupTestDB() *gorm.DB {
-	// 对于测试，我们使用模拟的数据库或跳过数据库操作
-	// 在实际实现中，可以使用内存数据库或测试数据库
-	TestDB = nil // 暂时设为nil，在service测试中使用mock
+	if TestDB != nil {
+		return TestDB
+	}
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		return nil
+	}
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		log.Printf("连接测试数据库失败: %v", err)
+		return nil
+	}
+	if err := db.AutoMigrate(models.AllModels()...); err != nil {
+		log.Printf("迁移测试数据库失败: %v", err)
+		return nil
+	}
+	TestDB = db
 	return TestDB
 }
 
+// RequireTestDB 返回测试数据库，未配置时跳过当前测试
+func RequireTestDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	db := SetupTestDB()
+	if db == nil {
+		t.Skip("未配置 TEST_DATABASE_DSN，跳过依赖数据库的测试")
+	}
+	return db
+}
+
+// CleanupTables 清空全部数据表，每个测试开始前调用以隔离测试数据
+func CleanupTables(db *gorm.DB) {
+	if db == nil {
+		return
+	}
+	for _, model := range models.AllModels() {
+		db.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(model)
+	}
+}
+
 // CleanupTestDB 清理测试数据库
 func CleanupTestDB() {
 	if TestDB != nil {
 		sqlDB, _ := TestDB.DB()
 		sqlDB.Close()
+		TestDB = nil
 	}
 }
 
@@ -111,9 +156,14 @@ func CleanupTestEnv() {
 
 // MockAuthMiddleware 模拟认证中间件
 func MockAuthMiddleware() gin.HandlerFunc {
+	return MockAuthMiddlewareForUser(1)
+}
+
+// MockAuthMiddlewareForUser 模拟指定用户登录的认证中间件
+func MockAuthMiddlewareForUser(userID uint) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 为测试设置用户信息
-		c.Set("user_id", uint(1))
+		c.Set("user_id", userID)
 		c.Set("username", "testuser")
 		c.Next()
 	}
@@ -166,6 +216,26 @@ func RunTestCases(t *testing.T, router *gin.Engine, testCases []TestCase) {
 	}
 }
 
+// NewFakeTestEngines 创建各包测试共用的假后端：20只合成股票、2022年全年的工作日行情
+func NewFakeTestEngines(seed int64) (*qlib.Engines, error) {
+	return qlib.NewEngines(qlib.EngineBackendFake, qlib.EngineConfig{FakeMarket: qlib.FakeMarketConfig{
+		Seed:        seed,
+		Instruments: 20,
+		Start:       time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:         time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC),
+	}})
+}
+
+// RequireFakeEngines 返回测试共用的假后端，创建失败时终止当前测试
+func RequireFakeEngines(t testing.TB, seed int64) *qlib.Engines {
+	t.Helper()
+	engines, err := NewFakeTestEngines(seed)
+	if err != nil {
+		t.Fatalf("创建假后端失败: %v", err)
+	}
+	return engines
+}
+
 // MockQlibClient 模拟Qlib客户端
 type MockQlibClient struct {
 	Initialized bool
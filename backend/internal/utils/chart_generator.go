@@ -42,6 +42,35 @@ func (cg *ChartGenerator) GenerateLineChart(title string, xData []string, yData
 	}
 }
 
+// GenerateMultiLineChart 生成共用横轴的多序列折线图，names 与 series 一一对应
+func (cg *ChartGenerator) GenerateMultiLineChart(title string, xData []string, names []string, series [][]float64) *ChartData {
+	data := make([]interface{}, len(names))
+	for i, name := range names {
+		points := make([]interface{}, len(xData))
+		for j, x := range xData {
+			points[j] = map[string]interface{}{
+				"x": x,
+				"y": series[i][j],
+			}
+		}
+		data[i] = map[string]interface{}{
+			"name": name,
+			"data": points,
+		}
+	}
+
+	return &ChartData{
+		Type:  "multi_line",
+		Title: title,
+		Data:  data,
+		Config: map[string]interface{}{
+			"xAxis":  map[string]string{"type": "category"},
+			"yAxis":  map[string]string{"type": "value"},
+			"legend": names,
+		},
+	}
+}
+
 // GenerateBarChart 生成柱状图
 func (cg *ChartGenerator) GenerateBarChart(title string, categories []string, values []float64) *ChartData {
 	data := make([]interface{}, len(categories))
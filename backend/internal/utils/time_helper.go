@@ -5,6 +5,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"qlib-backend/internal/calendar"
 )
 
 // DateFormat 常用日期格式
@@ -17,12 +19,25 @@ const (
 	ChineseDateFormat = "2006年01月02日"
 )
 
-// TimeHelper 时间工具
-type TimeHelper struct{}
+// TimeHelper 时间工具，交易日相关的计算使用交易日历注册表
+type TimeHelper struct {
+	calendars *calendar.Registry
+	market    string
+}
 
-// NewTimeHelper 创建时间工具
+// NewTimeHelper 创建时间工具，交易日按A股日历计算
 func NewTimeHelper() *TimeHelper {
-	return &TimeHelper{}
+	return NewTimeHelperForMarket(calendar.Default, "cn")
+}
+
+// NewTimeHelperForMarket 创建使用指定日历注册表和市场的时间工具
+func NewTimeHelperForMarket(calendars *calendar.Registry, market string) *TimeHelper {
+	return &TimeHelper{calendars: calendars, market: market}
+}
+
+// tradingCalendar 当前市场的交易日历
+func (th *TimeHelper) tradingCalendar() *calendar.Calendar {
+	return th.calendars.MustGet(th.market)
 }
 
 // ParseDate 解析日期字符串
@@ -76,47 +91,24 @@ func (th *TimeHelper) GetDateRange(startDate, endDate string) (time.Time, time.T
 	return start, end, nil
 }
 
-// GetTradingDays 获取交易日列表（排除周末和节假日）
+// GetTradingDays 获取交易日列表
 func (th *TimeHelper) GetTradingDays(startDate, endDate time.Time) []time.Time {
-	var tradingDays []time.Time
-	
-	current := startDate
-	for !current.After(endDate) {
-		// 排除周末
-		if current.Weekday() != time.Saturday && current.Weekday() != time.Sunday {
-			// 这里可以进一步排除节假日
-			if !th.isHoliday(current) {
-				tradingDays = append(tradingDays, current)
-			}
-		}
-		current = current.AddDate(0, 0, 1)
-	}
+	return append([]time.Time(nil), th.tradingCalendar().Between(startDate, endDate)...)
+}
 
-	return tradingDays
+// CountTradingDays 计算区间内的交易日数
+func (th *TimeHelper) CountTradingDays(startDate, endDate time.Time) int {
+	return th.tradingCalendar().Count(startDate, endDate)
 }
 
-// isHoliday 检查是否为节假日（简化版本）
-func (th *TimeHelper) isHoliday(date time.Time) bool {
-	// 这里应该实现具体的节假日判断逻辑
-	// 可以基于固定节假日或从API获取
-	
-	// 简单的固定节假日判断
-	holidays := []string{
-		"01-01", // 元旦
-		"05-01", // 劳动节
-		"10-01", // 国庆节
-		"10-02",
-		"10-03",
-	}
-
-	dateStr := date.Format("01-02")
-	for _, holiday := range holidays {
-		if dateStr == holiday {
-			return true
-		}
-	}
+// OffsetTradingDays 获取从指定日期起第n个交易日
+func (th *TimeHelper) OffsetTradingDays(t time.Time, n int) (time.Time, error) {
+	return th.tradingCalendar().Offset(t, n)
+}
 
-	return false
+// GetPeriodEnds 获取区间内每周、月、季度或年的最后一个交易日
+func (th *TimeHelper) GetPeriodEnds(startDate, endDate time.Time, period string) ([]time.Time, error) {
+	return th.tradingCalendar().PeriodEnds(startDate, endDate, period)
 }
 
 // GetQuarter 获取季度
@@ -377,19 +369,25 @@ func (th *TimeHelper) GetRelativeTime(t time.Time) string {
 	return fmt.Sprintf("%d年前", int(duration.Hours()/(365*24)))
 }
 
-// GetNextTradingDay 获取下一个交易日
+// GetNextTradingDay 获取下一个交易日，超出日历范围时只跳过周末
 func (th *TimeHelper) GetNextTradingDay(t time.Time) time.Time {
+	if next, err := th.tradingCalendar().Next(t); err == nil {
+		return next
+	}
 	next := t.AddDate(0, 0, 1)
-	for next.Weekday() == time.Saturday || next.Weekday() == time.Sunday || th.isHoliday(next) {
+	for th.IsWeekend(next) {
 		next = next.AddDate(0, 0, 1)
 	}
 	return next
 }
 
-// GetPreviousTradingDay 获取上一个交易日
+// GetPreviousTradingDay 获取上一个交易日，超出日历范围时只跳过周末
 func (th *TimeHelper) GetPreviousTradingDay(t time.Time) time.Time {
+	if prev, err := th.tradingCalendar().Prev(t); err == nil {
+		return prev
+	}
 	prev := t.AddDate(0, 0, -1)
-	for prev.Weekday() == time.Saturday || prev.Weekday() == time.Sunday || th.isHoliday(prev) {
+	for th.IsWeekend(prev) {
 		prev = prev.AddDate(0, 0, -1)
 	}
 	return prev
@@ -413,12 +411,11 @@ func (th *TimeHelper) ConvertTimezone(t time.Time, to *time.Location) time.Time
 // GetMarketTime 获取市场时间
 func (th *TimeHelper) GetMarketTime(market string) time.Time {
 	now := time.Now()
-	
+	if m, err := calendar.LookupMarket(market); err == nil {
+		return now.In(m.Location)
+	}
+
 	switch strings.ToLower(market) {
-	case "cn", "china", "shanghai":
-		return now.In(Shanghai)
-	case "us", "usa", "newyork":
-		return now.In(NewYork)
 	case "uk", "london":
 		return now.In(London)
 	case "jp", "japan", "tokyo":
@@ -428,33 +425,13 @@ func (th *TimeHelper) GetMarketTime(market string) time.Time {
 	}
 }
 
-// IsMarketOpen 检查市场是否开盘（简化版本）
+// IsMarketOpen 检查市场当前是否处于交易时段
 func (th *TimeHelper) IsMarketOpen(market string) bool {
-	marketTime := th.GetMarketTime(market)
-	
-	// 检查是否为交易日
-	if marketTime.Weekday() == time.Saturday || marketTime.Weekday() == time.Sunday {
-		return false
-	}
-	
-	if th.isHoliday(marketTime) {
-		return false
-	}
-	
-	hour := marketTime.Hour()
-	minute := marketTime.Minute()
-	
-	switch strings.ToLower(market) {
-	case "cn", "china", "shanghai":
-		// 中国股市: 9:30-11:30, 13:00-15:00
-		return (hour == 9 && minute >= 30) || hour == 10 || hour == 11 && minute <= 30 ||
-			   hour == 13 || hour == 14 || (hour == 15 && minute == 0)
-	case "us", "usa", "newyork":
-		// 美国股市: 9:30-16:00 (EST)
-		return (hour == 9 && minute >= 30) || (hour >= 10 && hour <= 15) || (hour == 16 && minute == 0)
-	default:
+	cal, err := th.calendars.Get(market)
+	if err != nil {
 		return false
 	}
+	return cal.IsOpen(time.Now())
 }
 
 // ParseDurationString 解析时间间隔字符串
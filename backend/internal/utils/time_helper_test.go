@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"qlib-backend/internal/calendar"
 )
 
 func TestNewTimeHelper(t *testing.T) {
@@ -375,4 +376,33 @@ func TestFromTimestamp(t *testing.T) {
 	result := helper.FromTimestamp(timestamp)
 	// 转换为UTC时间进行比较
 	assert.Equal(t, originalTime, result.UTC())
-}
\ No newline at end of file
+}
+
+func TestTradingDaysUseCalendar(t *testing.T) {
+	market, _ := calendar.LookupMarket("cn")
+	days := []time.Time{
+		time.Date(2024, 2, 7, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 8, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 19, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC),
+	}
+	cal, err := calendar.New(market, days, calendar.SourceUpload)
+	assert.NoError(t, err)
+	registry := calendar.NewRegistry()
+	registry.Register(cal)
+	helper := NewTimeHelperForMarket(registry, "cn")
+
+	// 春节休市期间不是交易日
+	tradingDays := helper.GetTradingDays(days[0], days[3])
+	assert.Equal(t, days, tradingDays)
+	assert.Equal(t, days[2], helper.GetNextTradingDay(days[1]))
+	assert.Equal(t, days[1], helper.GetPreviousTradingDay(days[2]))
+	assert.Equal(t, 2, helper.CountTradingDays(days[1], time.Date(2024, 2, 19, 0, 0, 0, 0, time.UTC)))
+
+	offset, err := helper.OffsetTradingDays(days[0], 2)
+	assert.NoError(t, err)
+	assert.Equal(t, days[2], offset)
+
+	// 超出日历范围时只跳过周末
+	assert.Equal(t, time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC), helper.GetNextTradingDay(time.Date(2024, 2, 23, 0, 0, 0, 0, time.UTC)))
+}
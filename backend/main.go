@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"time"
 
 	"qlib-backend/config"
 	"qlib-backend/internal/api/routes"
+	"qlib-backend/internal/qlib"
 	"qlib-backend/internal/services"
 
 	"github.com/gin-contrib/cors"
@@ -26,6 +29,70 @@ func main() {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	// 加载交易日历：各市场Qlib数据目录中的日历，管理员上传的日历优先
+	calendarService := services.NewCalendarService(services.DB, nil)
+	for market, dataPath := range map[string]string{
+		"cn": cfg.Qlib.DataPath,
+		"us": cfg.Qlib.USDataPath,
+		"hk": cfg.Qlib.HKDataPath,
+	} {
+		if dataPath == "" {
+			continue
+		}
+		if _, err := calendarService.LoadQlibCalendar(market, dataPath); err != nil {
+			log.Printf("未加载%s市场的Qlib交易日历，将按工作日计算交易日: %v", market, err)
+		}
+	}
+	if err := calendarService.LoadStoredCalendars(); err != nil {
+		log.Printf("加载上传的交易日历失败: %v", err)
+	}
+
+	// 加载导入的股票池和自定义筛选股票池，未导入的股票池直接读取Qlib数据目录
+	if err := services.NewUniverseService(services.DB, nil).LoadStoredUniverses(); err != nil {
+		log.Printf("加载股票池失败: %v", err)
+	}
+
+	// Python子进程在独立进程组中运行并受资源限制，任务取消或超时时整个进程组被终止
+	limits := qlib.ResourceLimits{
+		MemoryBytes:   uint64(cfg.Qlib.JobMemoryMB) << 20,
+		CPUSeconds:    uint64(cfg.Qlib.JobCPUSeconds),
+		FileSizeBytes: uint64(cfg.Qlib.JobFileSizeMB) << 20,
+		CgroupParent:  cfg.Qlib.JobCgroup,
+		KillGrace:     time.Duration(cfg.Qlib.JobKillGrace) * time.Second,
+	}
+	qlib.SetDefaultResourceLimits(limits)
+	services.DefaultJobTimeout = time.Duration(cfg.Qlib.JobTimeout) * time.Second
+
+	// Python调用共用常驻工作进程池，每个进程只导入和初始化一次qlib
+	if cfg.Qlib.Workers > 0 {
+		pool := qlib.NewWorkerPool(qlib.WorkerPoolConfig{
+			PythonPath:     cfg.Qlib.PythonPath,
+			Size:           cfg.Qlib.Workers,
+			MaxRequests:    cfg.Qlib.WorkerMaxRequests,
+			RequestTimeout: time.Duration(cfg.Qlib.WorkerTimeout) * time.Second,
+			Init:           map[string]interface{}{"provider_uri": cfg.Qlib.DataPath, "region": "cn"},
+			Limits:         limits,
+		})
+		defer pool.Close()
+		qlib.SetDefaultPythonClient(pool)
+	}
+
+	// 按配置创建模型训练、回测和因子计算后端，服务和接口共用
+	engines, err := qlib.NewEngines(cfg.Qlib.Engine, qlib.EngineConfig{
+		PythonPath:    cfg.Qlib.PythonPath,
+		QlibPath:      cfg.Qlib.QlibPath,
+		WorkspacePath: cfg.Qlib.WorkspacePath,
+		DataPath:      cfg.Qlib.DataPath,
+		GPUEnabled:    cfg.Qlib.GPUEnabled,
+	})
+	if err != nil {
+		log.Fatal("Failed to create engines:", err)
+	}
+	qlib.SetDefaultEngines(engines)
+
+	// 定时计算因子库相关系数矩阵，用于发现冗余因子
+	go services.NewFactorCorrelationService(services.DB, engines.Factors).Schedule(context.Background(), 24*time.Hour, services.FactorCorrelationRequest{})
+
 	// 设置Gin模式
 	gin.SetMode(cfg.App.Mode)
 